@@ -57,6 +57,12 @@ type EtcdConfig struct {
 	EtcdCertFile     string `json:"etcdCertFile" envconfig:"ETCD_CERT_FILE"`
 	EtcdCACertFile   string `json:"etcdCACertFile" envconfig:"ETCD_CA_CERT_FILE"`
 
+	// EtcdPoolSize is the number of connections used by the etcdv3 backend.  If greater than one,
+	// each connection is to a single endpoint (the endpoints are assigned to the connections in
+	// turn) and the requests are spread across the connections to healthy endpoints.  Otherwise a
+	// single connection to all of the endpoints is used.
+	EtcdPoolSize int `json:"etcdPoolSize" envconfig:"ETCD_POOL_SIZE"`
+
 	// These config file parameters are to support inline certificates, keys and CA / Trusted certificate.
 	// There are no corresponding environment variables to avoid accidental exposure.
 	EtcdKey    string `json:"etcdKey" ignored:"true"`
@@ -83,6 +83,22 @@ type BGPPeerSpec struct {
 	// this BGPPeer resource.  Default value "UseNodeIP" means to configure the node IP as the
 	// source address.  "None" means not to configure a source address.
 	SourceAddress SourceAddress `json:"sourceAddress,omitempty" validate:"omitempty,sourceAddress"`
+	// The number of times the local AS number is prepended to the AS path of routes advertised
+	// to the peerings generated by this BGPPeer resource.  Must be in the range 0-10.  Default
+	// is 0, meaning the AS path is not prepended.
+	// +optional
+	ASPathPrepend int `json:"asPathPrepend,omitempty" validate:"omitempty,gte=0,lte=10"`
+	// Time between the BGP keepalive messages sent on the peerings generated by this BGPPeer
+	// resource.  Must be a whole number of seconds, at least 1s and at most one third of the hold
+	// time.  If not set, the BGP daemon default is used.
+	// +optional
+	KeepaliveTime *metav1.Duration `json:"keepaliveTime,omitempty"`
+	// Time after which the peerings generated by this BGPPeer resource are closed if no keepalive
+	// or update message is received.  Must be a whole number of seconds, and either 0 (meaning
+	// keepalives are not used) or in the range 3s-65535s.  If not set, the BGP daemon default is
+	// used.
+	// +optional
+	HoldTime *metav1.Duration `json:"holdTime,omitempty"`
 }
 
 type SourceAddress string
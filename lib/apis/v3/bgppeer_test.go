@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3_test
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/projectcalico/libcalico-go/lib/apis/v3"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BGPPeerSpec asPathPrepend", func() {
+	It("should round-trip the AS path prepend count", func() {
+		peer := NewBGPPeer()
+		peer.Name = "peer1"
+		peer.Spec = BGPPeerSpec{
+			PeerIP:        "10.0.0.1",
+			ASPathPrepend: 3,
+		}
+		b, err := json.Marshal(peer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"asPathPrepend":3`))
+
+		out := &BGPPeer{}
+		Expect(json.Unmarshal(b, out)).To(Succeed())
+		Expect(out.Spec).To(Equal(peer.Spec))
+	})
+
+	It("should omit the AS path prepend count when not set", func() {
+		b, err := json.Marshal(BGPPeerSpec{PeerIP: "10.0.0.1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).NotTo(ContainSubstring("asPathPrepend"))
+	})
+})
+
+var _ = Describe("BGPPeerSpec timers", func() {
+	It("should round-trip the keepalive and hold times", func() {
+		peer := NewBGPPeer()
+		peer.Name = "peer1"
+		peer.Spec = BGPPeerSpec{
+			PeerIP:        "10.0.0.1",
+			KeepaliveTime: &metav1.Duration{Duration: 10 * time.Second},
+			HoldTime:      &metav1.Duration{Duration: 30 * time.Second},
+		}
+		b, err := json.Marshal(peer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"keepaliveTime":"10s"`))
+		Expect(string(b)).To(ContainSubstring(`"holdTime":"30s"`))
+
+		out := &BGPPeer{}
+		Expect(json.Unmarshal(b, out)).To(Succeed())
+		Expect(out.Spec).To(Equal(peer.Spec))
+		Expect(out.DeepCopy().Spec).To(Equal(peer.Spec))
+	})
+
+	It("should omit the timers when not set", func() {
+		b, err := json.Marshal(BGPPeerSpec{PeerIP: "10.0.0.1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).NotTo(ContainSubstring("keepaliveTime"))
+		Expect(string(b)).NotTo(ContainSubstring("holdTime"))
+	})
+})
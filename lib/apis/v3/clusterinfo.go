@@ -39,8 +39,9 @@ type ClusterInformation struct {
 type ClusterInformationSpec struct {
 	// ClusterGUID is the GUID of the cluster
 	ClusterGUID string `json:"clusterGUID,omitempty" validate:"omitempty"`
-	// ClusterType describes the type of the cluster
-	ClusterType string `json:"clusterType,omitempty" validate:"omitempty"`
+	// ClusterType describes the type of the cluster, as a comma separated list of cluster
+	// types (e.g. "k8s,bgp").
+	ClusterType string `json:"clusterType,omitempty" validate:"omitempty,clusterType"`
 	// CalicoVersion is the version of Calico that the cluster is running
 	CalicoVersion string `json:"calicoVersion,omitempty" validate:"omitempty"`
 	// DatastoreReady is used during significant datastore migrations to signal to components
@@ -40,6 +40,14 @@ const (
 	// orchestrator.
 	LabelOrchestrator = "projectcalico.org/orchestrator"
 
+	// Annotation used to mark a Node as being decommissioned.  When set to "true", controllers may
+	// release the IPAM block affinities of the Node in preparation for its removal.
+	AnnotationNodeDecommissioning = "projectcalico.org/decommissioning"
+
+	// Annotation used to override the Felix log level on a Node.  The value is one of the log
+	// levels accepted by the FelixConfiguration LogSeverityScreen field.
+	AnnotationNodeLogLevel = "projectcalico.org/log-level"
+
 	// Known orchestrators.  Orchestrators are not limited to this list.
 	OrchestratorKubernetes = "k8s"
 	OrchestratorCNI        = "cni"
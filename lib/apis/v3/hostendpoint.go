@@ -67,7 +67,7 @@ type HostEndpointSpec struct {
 	// 	endpoints, the ExpectedIPs field is used for that purpose. (If only the interface
 	// 	name is specified, Calico does not learn the IPs of the interface for use in match
 	// 	criteria.)
-	ExpectedIPs []string `json:"expectedIPs,omitempty" validate:"omitempty,dive,ip"`
+	ExpectedIPs []string `json:"expectedIPs,omitempty" validate:"omitempty,dive,hostip"`
 	// A list of identifiers of security Profile objects that apply to this endpoint. Each
 	// profile is applied in the order that they appear in this list.  Profile rules are applied
 	// after the selector-based security policy.
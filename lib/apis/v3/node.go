@@ -28,6 +28,32 @@ const (
 	ExternalIP   = "ExternalIP"
 )
 
+// TaintEffect is the effect of a NodeTaint on the workloads that do not tolerate the taint.
+type TaintEffect string
+
+const (
+	TaintEffectNoSchedule       TaintEffect = "NoSchedule"
+	TaintEffectPreferNoSchedule TaintEffect = "PreferNoSchedule"
+	TaintEffectNoExecute        TaintEffect = "NoExecute"
+)
+
+// NodeConditionType is the type of a NodeCondition.
+type NodeConditionType string
+
+const (
+	// NodeConditionReady indicates whether the node is healthy and ready to accept workloads.
+	NodeConditionReady NodeConditionType = "Ready"
+)
+
+// ConditionStatus is the status of a NodeCondition.
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -59,6 +85,16 @@ type NodeSpec struct {
 	// VXLANTunnelMACV6Addr is the MAC address of the VXLAN tunnel.
 	VXLANTunnelMACV6Addr string `json:"vxlanTunnelMACV6Addr,omitempty" validate:"omitempty,mac"`
 
+	// VXLANVNI is the VXLAN Network Identifier used by this node, overriding the VNI in the Felix
+	// configuration.  This allows nodes attached to different networks to use distinct VNIs.
+	// If not specified, the VNI from the Felix configuration is used.
+	VXLANVNI *int `json:"vxlanVNI,omitempty" validate:"omitempty,gte=0,lte=16777215"`
+
+	// VXLANTunnelAddrs are the addresses of additional VXLAN tunnels on this node, for example
+	// separate tunnels for the traffic of different IP pools.  Each tunnel is identified by a
+	// unique name.
+	VXLANTunnelAddrs []NodeVXLANTunnelAddr `json:"vxlanTunnelAddrs,omitempty" validate:"omitempty,dive"`
+
 	// OrchRefs for this node.
 	OrchRefs []OrchRef `json:"orchRefs,omitempty" validate:"omitempty"`
 
@@ -67,6 +103,9 @@ type NodeSpec struct {
 
 	// Addresses list address that a client can reach the node at.
 	Addresses []NodeAddress `json:"addresses,omitempty" validate:"omitempty"`
+
+	// Taints applied to this node.  These mirror the Kubernetes node taints.
+	Taints []NodeTaint `json:"taints,omitempty" validate:"omitempty,dive"`
 }
 
 // NodeAddress represents an address assigned to a node.
@@ -78,6 +117,28 @@ type NodeAddress struct {
 	Type string `json:"type,omitempty" validate:"omitempty,ipType"`
 }
 
+// NodeVXLANTunnelAddr is the address of an additional VXLAN tunnel on a node.
+type NodeVXLANTunnelAddr struct {
+	// Name identifies the tunnel, for example by the name of the IP pool that it is used for.
+	Name string `json:"name" validate:"name"`
+
+	// Address is the IPv4 or IPv6 address of the tunnel.
+	Address string `json:"address" validate:"ip"`
+}
+
+// NodeTaint represents a taint applied to a node.
+type NodeTaint struct {
+	// Key is the taint key.
+	Key string `json:"key"`
+
+	// Value is the taint value.
+	Value string `json:"value,omitempty"`
+
+	// Effect is the effect of the taint on workloads that do not tolerate the taint.
+	// Valid effects are NoSchedule, PreferNoSchedule and NoExecute.
+	Effect TaintEffect `json:"effect" validate:"taintEffect"`
+}
+
 type NodeStatus struct {
 	// WireguardPublicKey is the Wireguard public-key for this node.
 	// wireguardPublicKey validates if the string is a valid base64 encoded key.
@@ -85,6 +146,21 @@ type NodeStatus struct {
 
 	// PodCIDR is a reflection of the Kubernetes node's spec.PodCIDRs field.
 	PodCIDRs []string `json:"podCIDRs,omitempty" validate:"omitempty"`
+
+	// Conditions is a reflection of the Kubernetes node's status.conditions field.
+	Conditions []NodeCondition `json:"conditions,omitempty" validate:"omitempty"`
+}
+
+// NodeCondition describes an aspect of the current state of a node.
+type NodeCondition struct {
+	// Type is the type of the condition, for example Ready.
+	Type NodeConditionType `json:"type"`
+
+	// Status is the status of the condition: True, False or Unknown.
+	Status ConditionStatus `json:"status"`
+
+	// Reason is a brief reason for the last transition of the condition.
+	Reason string `json:"reason,omitempty"`
 }
 
 // OrchRef is used to correlate a Calico node to its corresponding representation in a given orchestrator
@@ -111,6 +187,9 @@ type NodeBGPSpec struct {
 	// RouteReflectorClusterID enables this node as a route reflector within the given
 	// cluster.
 	RouteReflectorClusterID string `json:"routeReflectorClusterID,omitempty" validate:"omitempty,ipv4"`
+	// ServiceClusterIPs are the service ClusterIP CIDR blocks advertised by this node, in
+	// addition to any blocks in the BGPConfiguration.
+	ServiceClusterIPs []ServiceClusterIPBlock `json:"serviceClusterIPs,omitempty" validate:"omitempty,dive"`
 }
 
 // NodeWireguardSpec contains the specification for the Node wireguard configuration.
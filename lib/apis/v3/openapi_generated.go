@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 // Copyright (c) 2016-2021 Tigera, Inc. All rights reserved.
@@ -129,10 +130,13 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.Node":                               schema_libcalico_go_lib_apis_v3_Node(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeAddress":                        schema_libcalico_go_lib_apis_v3_NodeAddress(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeBGPSpec":                        schema_libcalico_go_lib_apis_v3_NodeBGPSpec(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeCondition":                      schema_libcalico_go_lib_apis_v3_NodeCondition(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeControllerConfig":               schema_libcalico_go_lib_apis_v3_NodeControllerConfig(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeList":                           schema_libcalico_go_lib_apis_v3_NodeList(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeSpec":                           schema_libcalico_go_lib_apis_v3_NodeSpec(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeStatus":                         schema_libcalico_go_lib_apis_v3_NodeStatus(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeTaint":                          schema_libcalico_go_lib_apis_v3_NodeTaint(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeVXLANTunnelAddr":                schema_libcalico_go_lib_apis_v3_NodeVXLANTunnelAddr(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeWireguardSpec":                  schema_libcalico_go_lib_apis_v3_NodeWireguardSpec(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.OrchRef":                            schema_libcalico_go_lib_apis_v3_OrchRef(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.PolicyControllerConfig":             schema_libcalico_go_lib_apis_v3_PolicyControllerConfig(ref),
@@ -149,6 +153,12 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.ServiceClusterIPBlock":              schema_libcalico_go_lib_apis_v3_ServiceClusterIPBlock(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.ServiceExternalIPBlock":             schema_libcalico_go_lib_apis_v3_ServiceExternalIPBlock(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.ServiceLoadBalancerIPBlock":         schema_libcalico_go_lib_apis_v3_ServiceLoadBalancerIPBlock(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedGlobalNetworkPolicy":          schema_libcalico_go_lib_apis_v3_StagedGlobalNetworkPolicy(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedGlobalNetworkPolicyList":      schema_libcalico_go_lib_apis_v3_StagedGlobalNetworkPolicyList(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedGlobalNetworkPolicySpec":      schema_libcalico_go_lib_apis_v3_StagedGlobalNetworkPolicySpec(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedNetworkPolicy":                schema_libcalico_go_lib_apis_v3_StagedNetworkPolicy(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedNetworkPolicyList":            schema_libcalico_go_lib_apis_v3_StagedNetworkPolicyList(ref),
+		"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedNetworkPolicySpec":            schema_libcalico_go_lib_apis_v3_StagedNetworkPolicySpec(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.WorkloadEndpoint":                   schema_libcalico_go_lib_apis_v3_WorkloadEndpoint(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.WorkloadEndpointControllerConfig":   schema_libcalico_go_lib_apis_v3_WorkloadEndpointControllerConfig(ref),
 		"github.com/projectcalico/libcalico-go/lib/apis/v3.WorkloadEndpointList":               schema_libcalico_go_lib_apis_v3_WorkloadEndpointList(ref),
@@ -2429,11 +2439,30 @@ func schema_libcalico_go_lib_apis_v3_BGPPeerSpec(ref common.ReferenceCallback) c
 							Format:      "",
 						},
 					},
+					"asPathPrepend": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The number of times the local AS number is prepended to the AS path of routes advertised to the peerings generated by this BGPPeer resource.  Must be in the range 0-10.  Default is 0, meaning the AS path is not prepended.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"keepaliveTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Time between the BGP keepalive messages sent on the peerings generated by this BGPPeer resource.  Must be a whole number of seconds, at least 1s and at most one third of the hold time.  If not set, the BGP daemon default is used.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"holdTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Time after which the peerings generated by this BGPPeer resource are closed if no keepalive or update message is received.  Must be a whole number of seconds, and either 0 (meaning keepalives are not used) or in the range 3s-65535s.  If not set, the BGP daemon default is used.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/projectcalico/libcalico-go/lib/apis/v3.BGPPassword"},
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.BGPPassword", "k8s.io/apimachinery/pkg/apis/meta/v1.Duration"},
 	}
 }
 
@@ -2680,7 +2709,7 @@ func schema_libcalico_go_lib_apis_v3_ClusterInformationSpec(ref common.Reference
 					},
 					"clusterType": {
 						SchemaProps: spec.SchemaProps{
-							Description: "ClusterType describes the type of the cluster",
+							Description: "ClusterType describes the type of the cluster, as a comma separated list of cluster types (e.g. \"k8s,bgp\").",
 							Type:        []string{"string"},
 							Format:      "",
 						},
@@ -5508,9 +5537,62 @@ func schema_libcalico_go_lib_apis_v3_NodeBGPSpec(ref common.ReferenceCallback) c
 							Format:      "",
 						},
 					},
+					"serviceClusterIPs": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ServiceClusterIPs are the service ClusterIP CIDR blocks advertised by this node, in addition to any blocks in the BGPConfiguration.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.ServiceClusterIPBlock"),
+									},
+								},
+							},
+						},
+					},
 				},
 			},
 		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.ServiceClusterIPBlock"},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_NodeCondition(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NodeCondition describes an aspect of the current state of a node.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"type": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Type is the type of the condition, for example Ready.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Status is the status of the condition: True, False or Unknown.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"reason": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Reason is a brief reason for the last transition of the condition.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"type", "status"},
+			},
+		},
 	}
 }
 
@@ -5638,6 +5720,27 @@ func schema_libcalico_go_lib_apis_v3_NodeSpec(ref common.ReferenceCallback) comm
 							Format:      "",
 						},
 					},
+					"vxlanVNI": {
+						SchemaProps: spec.SchemaProps{
+							Description: "VXLANVNI is the VXLAN Network Identifier used by this node, overriding the VNI in the Felix configuration.  This allows nodes attached to different networks to use distinct VNIs. If not specified, the VNI from the Felix configuration is used.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"vxlanTunnelAddrs": {
+						SchemaProps: spec.SchemaProps{
+							Description: "VXLANTunnelAddrs are the addresses of additional VXLAN tunnels on this node, for example separate tunnels for the traffic of different IP pools.  Each tunnel is identified by a unique name.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.NodeVXLANTunnelAddr"),
+									},
+								},
+							},
+						},
+					},
 					"orchRefs": {
 						SchemaProps: spec.SchemaProps{
 							Description: "OrchRefs for this node.",
@@ -5672,11 +5775,25 @@ func schema_libcalico_go_lib_apis_v3_NodeSpec(ref common.ReferenceCallback) comm
 							},
 						},
 					},
+					"taints": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Taints applied to this node.  These mirror the Kubernetes node taints.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.NodeTaint"),
+									},
+								},
+							},
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeAddress", "github.com/projectcalico/libcalico-go/lib/apis/v3.NodeBGPSpec", "github.com/projectcalico/libcalico-go/lib/apis/v3.NodeWireguardSpec", "github.com/projectcalico/libcalico-go/lib/apis/v3.OrchRef"},
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeAddress", "github.com/projectcalico/libcalico-go/lib/apis/v3.NodeBGPSpec", "github.com/projectcalico/libcalico-go/lib/apis/v3.NodeTaint", "github.com/projectcalico/libcalico-go/lib/apis/v3.NodeVXLANTunnelAddr", "github.com/projectcalico/libcalico-go/lib/apis/v3.NodeWireguardSpec", "github.com/projectcalico/libcalico-go/lib/apis/v3.OrchRef"},
 	}
 }
 
@@ -5708,7 +5825,90 @@ func schema_libcalico_go_lib_apis_v3_NodeStatus(ref common.ReferenceCallback) co
 							},
 						},
 					},
+					"conditions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Conditions is a reflection of the Kubernetes node's status.conditions field.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.NodeCondition"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.NodeCondition"},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_NodeTaint(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NodeTaint represents a taint applied to a node.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"key": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Key is the taint key.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"value": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Value is the taint value.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"effect": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Effect is the effect of the taint on workloads that do not tolerate the taint. Valid effects are NoSchedule, PreferNoSchedule and NoExecute.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"key", "effect"},
+			},
+		},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_NodeVXLANTunnelAddr(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NodeVXLANTunnelAddr is the address of an additional VXLAN tunnel on a node.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name identifies the tunnel, for example by the name of the IP pool that it is used for.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"address": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Address is the IPv4 or IPv6 address of the tunnel.",
+							Default:     "",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
+				Required: []string{"name", "address"},
 			},
 		},
 	}
@@ -6252,6 +6452,390 @@ func schema_libcalico_go_lib_apis_v3_ServiceLoadBalancerIPBlock(ref common.Refer
 	}
 }
 
+func schema_libcalico_go_lib_apis_v3_StagedGlobalNetworkPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StagedGlobalNetworkPolicy is a staged GlobalNetworkPolicy.  A staged policy is evaluated by the data plane in the same way as the equivalent enforced policy, but its verdict is only recorded, not enforced.  This allows the effect of a policy change to be previewed before it is made.\n\nStagedGlobalNetworkPolicy is globally-scoped (i.e. not Namespaced).",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Standard object's metadata.",
+							Default:     map[string]interface{}{},
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Specification of the Policy.",
+							Default:     map[string]interface{}{},
+							Ref:         ref("github.com/projectcalico/libcalico-go/lib/apis/v3.StagedGlobalNetworkPolicySpec"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedGlobalNetworkPolicySpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_StagedGlobalNetworkPolicyList(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StagedGlobalNetworkPolicyList contains a list of StagedGlobalNetworkPolicy resources.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"),
+						},
+					},
+					"items": {
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.StagedGlobalNetworkPolicy"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"metadata", "items"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedGlobalNetworkPolicy", "k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_StagedGlobalNetworkPolicySpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StagedGlobalNetworkPolicySpec contains the staged action and the fields of the staged GlobalNetworkPolicySpec.  See GlobalNetworkPolicySpec for details of the policy fields.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"stagedAction": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The staged action.  If this is omitted, the default is Set.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"order": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Order is an optional field that specifies the order in which the policy is applied.",
+							Type:        []string{"number"},
+							Format:      "double",
+						},
+					},
+					"ingress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The ordered set of ingress rules.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.Rule"),
+									},
+								},
+							},
+						},
+					},
+					"egress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The ordered set of egress rules.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.Rule"),
+									},
+								},
+							},
+						},
+					},
+					"selector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The selector is an expression used to pick pick out the endpoints that the policy should be applied to.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"types": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Types indicates whether this policy applies to ingress, or to egress, or to both.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"doNotTrack": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DoNotTrack indicates whether packets matched by the rules in this policy should go through the data plane's connection tracking, such as Linux conntrack.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"preDNAT": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PreDNAT indicates to apply the rules in this policy before any DNAT.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"applyOnForward": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ApplyOnForward indicates to apply the rules in this policy on forward traffic.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"serviceAccountSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ServiceAccountSelector is an optional field for an expression used to select a pod based on service accounts.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespaceSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NamespaceSelector is an optional field for an expression used to select a pod based on namespaces.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.Rule"},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_StagedNetworkPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StagedNetworkPolicy is the Namespaced-equivalent of the StagedGlobalNetworkPolicy.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Standard object's metadata.",
+							Default:     map[string]interface{}{},
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Specification of the Policy.",
+							Default:     map[string]interface{}{},
+							Ref:         ref("github.com/projectcalico/libcalico-go/lib/apis/v3.StagedNetworkPolicySpec"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedNetworkPolicySpec", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_StagedNetworkPolicyList(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StagedNetworkPolicyList contains a list of StagedNetworkPolicy resources.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Default: map[string]interface{}{},
+							Ref:     ref("k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"),
+						},
+					},
+					"items": {
+						SchemaProps: spec.SchemaProps{
+							Type: []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.StagedNetworkPolicy"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"metadata", "items"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.StagedNetworkPolicy", "k8s.io/apimachinery/pkg/apis/meta/v1.ListMeta"},
+	}
+}
+
+func schema_libcalico_go_lib_apis_v3_StagedNetworkPolicySpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "StagedNetworkPolicySpec contains the staged action and the fields of the staged NetworkPolicySpec.  See NetworkPolicySpec for details of the policy fields.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"stagedAction": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The staged action.  If this is omitted, the default is Set.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"order": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Order is an optional field that specifies the order in which the policy is applied.",
+							Type:        []string{"number"},
+							Format:      "double",
+						},
+					},
+					"ingress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The ordered set of ingress rules.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.Rule"),
+									},
+								},
+							},
+						},
+					},
+					"egress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The ordered set of egress rules.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: map[string]interface{}{},
+										Ref:     ref("github.com/projectcalico/libcalico-go/lib/apis/v3.Rule"),
+									},
+								},
+							},
+						},
+					},
+					"selector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The selector is an expression used to pick pick out the endpoints that the policy should be applied to.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"types": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Types indicates whether this policy applies to ingress, or to egress, or to both.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Default: "",
+										Type:    []string{"string"},
+										Format:  "",
+									},
+								},
+							},
+						},
+					},
+					"serviceAccountSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ServiceAccountSelector is an optional field for an expression used to select a pod based on service accounts.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/projectcalico/libcalico-go/lib/apis/v3.Rule"},
+	}
+}
+
 func schema_libcalico_go_lib_apis_v3_WorkloadEndpoint(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -0,0 +1,144 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	KindStagedGlobalNetworkPolicy     = "StagedGlobalNetworkPolicy"
+	KindStagedGlobalNetworkPolicyList = "StagedGlobalNetworkPolicyList"
+)
+
+// StagedAction is the change that a staged policy would make to the enforced policy of the same
+// name if the staged policy were enforced.
+type StagedAction string
+
+const (
+	// StagedActionSet stages the creation or update of the enforced policy of the same name.
+	// This is the default if no action is specified.
+	StagedActionSet StagedAction = "Set"
+	// StagedActionDelete stages the deletion of the enforced policy of the same name.  The
+	// policy rules are ignored.
+	StagedActionDelete StagedAction = "Delete"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StagedGlobalNetworkPolicy is a staged GlobalNetworkPolicy.  A staged policy is evaluated by the
+// data plane in the same way as the equivalent enforced policy, but its verdict is only
+// recorded, not enforced.  This allows the effect of a policy change to be previewed before it
+// is made.
+//
+// StagedGlobalNetworkPolicy is globally-scoped (i.e. not Namespaced).
+type StagedGlobalNetworkPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Specification of the Policy.
+	Spec StagedGlobalNetworkPolicySpec `json:"spec,omitempty"`
+}
+
+// StagedGlobalNetworkPolicySpec contains the staged action and the fields of the staged
+// GlobalNetworkPolicySpec.  See GlobalNetworkPolicySpec for details of the policy fields.
+type StagedGlobalNetworkPolicySpec struct {
+	// The staged action.  If this is omitted, the default is Set.
+	StagedAction StagedAction `json:"stagedAction,omitempty" validate:"omitempty,stagedAction"`
+
+	// Order is an optional field that specifies the order in which the policy is applied.
+	Order *float64 `json:"order,omitempty"`
+	// The ordered set of ingress rules.
+	Ingress []Rule `json:"ingress,omitempty" validate:"omitempty,dive"`
+	// The ordered set of egress rules.
+	Egress []Rule `json:"egress,omitempty" validate:"omitempty,dive"`
+	// The selector is an expression used to pick pick out the endpoints that the policy should
+	// be applied to.
+	Selector string `json:"selector,omitempty" validate:"selector"`
+	// Types indicates whether this policy applies to ingress, or to egress, or to both.
+	Types []PolicyType `json:"types,omitempty" validate:"omitempty,dive,policyType"`
+
+	// DoNotTrack indicates whether packets matched by the rules in this policy should go through
+	// the data plane's connection tracking, such as Linux conntrack.
+	DoNotTrack bool `json:"doNotTrack,omitempty"`
+	// PreDNAT indicates to apply the rules in this policy before any DNAT.
+	PreDNAT bool `json:"preDNAT,omitempty"`
+	// ApplyOnForward indicates to apply the rules in this policy on forward traffic.
+	ApplyOnForward bool `json:"applyOnForward,omitempty"`
+
+	// ServiceAccountSelector is an optional field for an expression used to select a pod based on service accounts.
+	ServiceAccountSelector string `json:"serviceAccountSelector,omitempty" validate:"selector"`
+
+	// NamespaceSelector is an optional field for an expression used to select a pod based on namespaces.
+	NamespaceSelector string `json:"namespaceSelector,omitempty" validate:"selector"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StagedGlobalNetworkPolicyList contains a list of StagedGlobalNetworkPolicy resources.
+type StagedGlobalNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []StagedGlobalNetworkPolicy `json:"items"`
+}
+
+// NewStagedGlobalNetworkPolicy creates a new (zeroed) StagedGlobalNetworkPolicy struct with the TypeMetadata
+// initialised to the current version.
+func NewStagedGlobalNetworkPolicy() *StagedGlobalNetworkPolicy {
+	return &StagedGlobalNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       KindStagedGlobalNetworkPolicy,
+			APIVersion: GroupVersionCurrent,
+		},
+	}
+}
+
+// NewStagedGlobalNetworkPolicyList creates a new (zeroed) StagedGlobalNetworkPolicyList struct with the TypeMetadata
+// initialised to the current version.
+func NewStagedGlobalNetworkPolicyList() *StagedGlobalNetworkPolicyList {
+	return &StagedGlobalNetworkPolicyList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       KindStagedGlobalNetworkPolicyList,
+			APIVersion: GroupVersionCurrent,
+		},
+	}
+}
+
+// ConvertStagedGlobalPolicyToEnforced returns the staged action and the GlobalNetworkPolicy that
+// would be enforced by the staged policy.  The returned policy has the same metadata as the
+// staged policy.  The staged action is defaulted to StagedActionSet if not specified.
+func ConvertStagedGlobalPolicyToEnforced(staged *StagedGlobalNetworkPolicy) (StagedAction, *GlobalNetworkPolicy) {
+	enforced := NewGlobalNetworkPolicy()
+	staged.ObjectMeta.DeepCopyInto(&enforced.ObjectMeta)
+	spec := staged.Spec.DeepCopy()
+	enforced.Spec = GlobalNetworkPolicySpec{
+		Order:                  spec.Order,
+		Ingress:                spec.Ingress,
+		Egress:                 spec.Egress,
+		Selector:               spec.Selector,
+		Types:                  spec.Types,
+		DoNotTrack:             spec.DoNotTrack,
+		PreDNAT:                spec.PreDNAT,
+		ApplyOnForward:         spec.ApplyOnForward,
+		ServiceAccountSelector: spec.ServiceAccountSelector,
+		NamespaceSelector:      spec.NamespaceSelector,
+	}
+	action := spec.StagedAction
+	if action == "" {
+		action = StagedActionSet
+	}
+	return action, enforced
+}
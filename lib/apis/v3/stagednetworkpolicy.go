@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	KindStagedNetworkPolicy     = "StagedNetworkPolicy"
+	KindStagedNetworkPolicyList = "StagedNetworkPolicyList"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StagedNetworkPolicy is the Namespaced-equivalent of the StagedGlobalNetworkPolicy.
+type StagedNetworkPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Specification of the Policy.
+	Spec StagedNetworkPolicySpec `json:"spec,omitempty"`
+}
+
+// StagedNetworkPolicySpec contains the staged action and the fields of the staged
+// NetworkPolicySpec.  See NetworkPolicySpec for details of the policy fields.
+type StagedNetworkPolicySpec struct {
+	// The staged action.  If this is omitted, the default is Set.
+	StagedAction StagedAction `json:"stagedAction,omitempty" validate:"omitempty,stagedAction"`
+
+	// Order is an optional field that specifies the order in which the policy is applied.
+	Order *float64 `json:"order,omitempty"`
+	// The ordered set of ingress rules.
+	Ingress []Rule `json:"ingress,omitempty" validate:"omitempty,dive"`
+	// The ordered set of egress rules.
+	Egress []Rule `json:"egress,omitempty" validate:"omitempty,dive"`
+	// The selector is an expression used to pick pick out the endpoints that the policy should
+	// be applied to.
+	Selector string `json:"selector,omitempty" validate:"selector"`
+	// Types indicates whether this policy applies to ingress, or to egress, or to both.
+	Types []PolicyType `json:"types,omitempty" validate:"omitempty,dive,policyType"`
+
+	// ServiceAccountSelector is an optional field for an expression used to select a pod based on service accounts.
+	ServiceAccountSelector string `json:"serviceAccountSelector,omitempty" validate:"selector"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StagedNetworkPolicyList contains a list of StagedNetworkPolicy resources.
+type StagedNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []StagedNetworkPolicy `json:"items"`
+}
+
+// NewStagedNetworkPolicy creates a new (zeroed) StagedNetworkPolicy struct with the TypeMetadata initialised to
+// the current version.
+func NewStagedNetworkPolicy() *StagedNetworkPolicy {
+	return &StagedNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       KindStagedNetworkPolicy,
+			APIVersion: GroupVersionCurrent,
+		},
+	}
+}
+
+// NewStagedNetworkPolicyList creates a new (zeroed) StagedNetworkPolicyList struct with the TypeMetadata
+// initialised to the current version.
+func NewStagedNetworkPolicyList() *StagedNetworkPolicyList {
+	return &StagedNetworkPolicyList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       KindStagedNetworkPolicyList,
+			APIVersion: GroupVersionCurrent,
+		},
+	}
+}
+
+// ConvertStagedPolicyToEnforced returns the staged action and the NetworkPolicy that would be
+// enforced by the staged policy.  The returned policy has the same metadata as the staged
+// policy.  The staged action is defaulted to StagedActionSet if not specified.
+func ConvertStagedPolicyToEnforced(staged *StagedNetworkPolicy) (StagedAction, *NetworkPolicy) {
+	enforced := NewNetworkPolicy()
+	staged.ObjectMeta.DeepCopyInto(&enforced.ObjectMeta)
+	spec := staged.Spec.DeepCopy()
+	enforced.Spec = NetworkPolicySpec{
+		Order:                  spec.Order,
+		Ingress:                spec.Ingress,
+		Egress:                 spec.Egress,
+		Selector:               spec.Selector,
+		Types:                  spec.Types,
+		ServiceAccountSelector: spec.ServiceAccountSelector,
+	}
+	action := spec.StagedAction
+	if action == "" {
+		action = StagedActionSet
+	}
+	return action, enforced
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3_test
+
+import (
+	. "github.com/projectcalico/libcalico-go/lib/apis/v3"
+
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// These tests verify that the staged policy specs are kept in sync with the enforced policy specs.
+var _ = DescribeTable("Staged policy specs should match the enforced policy specs",
+	func(staged, enforced interface{}) {
+		stagedFieldsByName := fieldsByName(staged)
+		enforcedFieldsByName := fieldsByName(enforced)
+
+		Expect(stagedFieldsByName).To(HaveKey("StagedAction"))
+		delete(stagedFieldsByName, "StagedAction")
+		Expect(stagedFieldsByName).To(HaveLen(len(enforcedFieldsByName)))
+		for n, f := range stagedFieldsByName {
+			Expect(enforcedFieldsByName).To(HaveKey(n))
+			Expect(f.Tag).To(Equal(enforcedFieldsByName[n].Tag), "Field "+n+" had different tag")
+			Expect(f.Type).To(Equal(enforcedFieldsByName[n].Type), "Field "+n+" had different type")
+		}
+	},
+	Entry("StagedGlobalNetworkPolicySpec", StagedGlobalNetworkPolicySpec{}, GlobalNetworkPolicySpec{}),
+	Entry("StagedNetworkPolicySpec", StagedNetworkPolicySpec{}, NetworkPolicySpec{}),
+)
+
+var _ = Describe("Staged policy conversion", func() {
+	order := 10.0
+
+	It("should convert a StagedGlobalNetworkPolicy to the enforced policy", func() {
+		staged := NewStagedGlobalNetworkPolicy()
+		staged.Name = "policy"
+		staged.Labels = map[string]string{"a": "b"}
+		staged.Spec.Order = &order
+		staged.Spec.Selector = "all()"
+		staged.Spec.PreDNAT = true
+		staged.Spec.ApplyOnForward = true
+
+		action, enforced := ConvertStagedGlobalPolicyToEnforced(staged)
+		Expect(action).To(Equal(StagedActionSet))
+		Expect(enforced.TypeMeta).To(Equal(NewGlobalNetworkPolicy().TypeMeta))
+		Expect(enforced.ObjectMeta).To(Equal(staged.ObjectMeta))
+
+		By("checking that the staged spec JSON matches the enforced spec JSON")
+		stagedJSON, err := json.Marshal(staged.Spec)
+		Expect(err).NotTo(HaveOccurred())
+		enforcedJSON, err := json.Marshal(enforced.Spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stagedJSON).To(MatchJSON(enforcedJSON))
+
+		By("checking that the enforced policy does not share data with the staged policy")
+		*enforced.Spec.Order = 20
+		Expect(*staged.Spec.Order).To(Equal(10.0))
+	})
+
+	It("should convert a StagedNetworkPolicy with a staged action to the enforced policy", func() {
+		staged := NewStagedNetworkPolicy()
+		staged.Name = "policy"
+		staged.Namespace = "namespace1"
+		staged.Spec.StagedAction = StagedActionDelete
+		staged.Spec.Types = []PolicyType{PolicyTypeIngress}
+
+		action, enforced := ConvertStagedPolicyToEnforced(staged)
+		Expect(action).To(Equal(StagedActionDelete))
+		Expect(enforced.Namespace).To(Equal("namespace1"))
+		Expect(enforced.Spec.Types).To(Equal([]PolicyType{PolicyTypeIngress}))
+
+		By("round-tripping the staged policy through JSON")
+		data, err := json.Marshal(staged)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"stagedAction":"Delete"`))
+		var decoded StagedNetworkPolicy
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(&decoded).To(Equal(staged))
+	})
+})
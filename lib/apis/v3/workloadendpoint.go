@@ -80,9 +80,9 @@ type WorkloadEndpointSpec struct {
 type IPNAT struct {
 	// The internal IP address which must be associated with the owning endpoint via the
 	// configured IPNetworks for the endpoint.
-	InternalIP string `json:"internalIP" validate:"omitempty,ip"`
+	InternalIP string `json:"internalIP" validate:"omitempty,hostip"`
 	// The external IP address.
-	ExternalIP string `json:"externalIP" validate:"omitempty,ip"`
+	ExternalIP string `json:"externalIP" validate:"omitempty,hostip"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -273,6 +273,16 @@ func (in *BGPPeerSpec) DeepCopyInto(out *BGPPeerSpec) {
 		*out = new(BGPPassword)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.KeepaliveTime != nil {
+		in, out := &in.KeepaliveTime, &out.KeepaliveTime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HoldTime != nil {
+		in, out := &in.HoldTime, &out.HoldTime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -2036,6 +2046,11 @@ func (in *NodeBGPSpec) DeepCopyInto(out *NodeBGPSpec) {
 		*out = new(numorstring.ASNumber)
 		**out = **in
 	}
+	if in.ServiceClusterIPs != nil {
+		in, out := &in.ServiceClusterIPs, &out.ServiceClusterIPs
+		*out = make([]ServiceClusterIPBlock, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -2049,6 +2064,22 @@ func (in *NodeBGPSpec) DeepCopy() *NodeBGPSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCondition) DeepCopyInto(out *NodeCondition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCondition.
+func (in *NodeCondition) DeepCopy() *NodeCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeControllerConfig) DeepCopyInto(out *NodeControllerConfig) {
 	*out = *in
@@ -2116,6 +2147,16 @@ func (in *NodeSpec) DeepCopyInto(out *NodeSpec) {
 		*out = new(NodeBGPSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.VXLANVNI != nil {
+		in, out := &in.VXLANVNI, &out.VXLANVNI
+		*out = new(int)
+		**out = **in
+	}
+	if in.VXLANTunnelAddrs != nil {
+		in, out := &in.VXLANTunnelAddrs, &out.VXLANTunnelAddrs
+		*out = make([]NodeVXLANTunnelAddr, len(*in))
+		copy(*out, *in)
+	}
 	if in.OrchRefs != nil {
 		in, out := &in.OrchRefs, &out.OrchRefs
 		*out = make([]OrchRef, len(*in))
@@ -2131,6 +2172,11 @@ func (in *NodeSpec) DeepCopyInto(out *NodeSpec) {
 		*out = make([]NodeAddress, len(*in))
 		copy(*out, *in)
 	}
+	if in.Taints != nil {
+		in, out := &in.Taints, &out.Taints
+		*out = make([]NodeTaint, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -2152,6 +2198,11 @@ func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]NodeCondition, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -2165,6 +2216,38 @@ func (in *NodeStatus) DeepCopy() *NodeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeTaint) DeepCopyInto(out *NodeTaint) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeTaint.
+func (in *NodeTaint) DeepCopy() *NodeTaint {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeTaint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeVXLANTunnelAddr) DeepCopyInto(out *NodeVXLANTunnelAddr) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeVXLANTunnelAddr.
+func (in *NodeVXLANTunnelAddr) DeepCopy() *NodeVXLANTunnelAddr {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeVXLANTunnelAddr)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeWireguardSpec) DeepCopyInto(out *NodeWireguardSpec) {
 	*out = *in
@@ -2534,6 +2617,206 @@ func (in *ServiceLoadBalancerIPBlock) DeepCopy() *ServiceLoadBalancerIPBlock {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagedGlobalNetworkPolicy) DeepCopyInto(out *StagedGlobalNetworkPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagedGlobalNetworkPolicy.
+func (in *StagedGlobalNetworkPolicy) DeepCopy() *StagedGlobalNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(StagedGlobalNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StagedGlobalNetworkPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagedGlobalNetworkPolicyList) DeepCopyInto(out *StagedGlobalNetworkPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StagedGlobalNetworkPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagedGlobalNetworkPolicyList.
+func (in *StagedGlobalNetworkPolicyList) DeepCopy() *StagedGlobalNetworkPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(StagedGlobalNetworkPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StagedGlobalNetworkPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagedGlobalNetworkPolicySpec) DeepCopyInto(out *StagedGlobalNetworkPolicySpec) {
+	*out = *in
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = new(float64)
+		**out = **in
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = make([]Rule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Egress != nil {
+		in, out := &in.Egress, &out.Egress
+		*out = make([]Rule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]PolicyType, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagedGlobalNetworkPolicySpec.
+func (in *StagedGlobalNetworkPolicySpec) DeepCopy() *StagedGlobalNetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StagedGlobalNetworkPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagedNetworkPolicy) DeepCopyInto(out *StagedNetworkPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagedNetworkPolicy.
+func (in *StagedNetworkPolicy) DeepCopy() *StagedNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(StagedNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StagedNetworkPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagedNetworkPolicyList) DeepCopyInto(out *StagedNetworkPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StagedNetworkPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagedNetworkPolicyList.
+func (in *StagedNetworkPolicyList) DeepCopy() *StagedNetworkPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(StagedNetworkPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StagedNetworkPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagedNetworkPolicySpec) DeepCopyInto(out *StagedNetworkPolicySpec) {
+	*out = *in
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = new(float64)
+		**out = **in
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = make([]Rule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Egress != nil {
+		in, out := &in.Egress, &out.Egress
+		*out = make([]Rule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]PolicyType, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagedNetworkPolicySpec.
+func (in *StagedNetworkPolicySpec) DeepCopy() *StagedNetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StagedNetworkPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadEndpoint) DeepCopyInto(out *WorkloadEndpoint) {
 	*out = *in
@@ -20,6 +20,8 @@ import (
 
 	"context"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 )
 
@@ -124,6 +126,33 @@ type Client interface {
 	//Close()
 }
 
+// ConsistentReader is an optional interface that may be implemented by a Client.  Datastores
+// that support it are able to perform a read that is guaranteed to reflect the most recently
+// committed state of the datastore, rather than a potentially stale cached copy.
+type ConsistentReader interface {
+	// GetConsistent returns the object identified by the given key as a KVPair with
+	// revision information, using a strongly consistent read.
+	GetConsistent(ctx context.Context, key model.Key) (*model.KVPair, error)
+}
+
+// PropagatingDeleter is an optional interface that may be implemented by a Client.  Datastores
+// that support it are able to control the garbage collection of the dependents of a deleted
+// resource.
+type PropagatingDeleter interface {
+	// DeleteKVPWithPropagation is as DeleteKVP, but deletes the dependents of the resource
+	// according to the supplied propagation policy.
+	DeleteKVPWithPropagation(ctx context.Context, object *model.KVPair, policy metav1.DeletionPropagation) (*model.KVPair, error)
+}
+
+// BulkDeleter is an optional interface that may be implemented by a Client.  Datastores that
+// support it are able to delete a number of objects in a single operation.
+type BulkDeleter interface {
+	// DeleteKVPs removes each of the objects specified by the KVPairs, as DeleteKVP.  Returns
+	// the deleted KVPairs and the error for each object, in the order of the supplied KVPairs;
+	// the KVPair is nil and the error non-nil for an object that could not be deleted.
+	DeleteKVPs(ctx context.Context, objects []*model.KVPair) ([]*model.KVPair, []error)
+}
+
 type Syncer interface {
 	// Starts the Syncer.  May start a background goroutine.
 	Start()
@@ -155,6 +184,32 @@ type SyncerParseFailCallbacks interface {
 	ParseFailed(rawKey string, rawValue string)
 }
 
+// SyncerTypeSyncedCallbacks is an optional interface that can be implemented
+// by a Syncer callback.  Syncers that support it report when each resource type
+// has completed its initial sync, allowing processing of that type to start
+// before all types are in-sync.
+type SyncerTypeSyncedCallbacks interface {
+	// OnTypeSynced is called once for each resource type when it has completed its
+	// initial sync.  All updates for the initial sync of the resource type are sent
+	// before this call.  The kind is the resource kind for v3 resource types, or the
+	// default path root of the list options for other types.
+	OnTypeSynced(kind string)
+}
+
+// SyncerTypeResyncCallbacks is an optional interface that can be implemented by a
+// Syncer callback.  Syncers that support resuming from previously exported revisions
+// use it to report that a resumed resource type could not be resumed, and is being
+// re-listed in full.
+type SyncerTypeResyncCallbacks interface {
+	// OnTypeResync is called when a resource type that was resumed from a previous
+	// revision falls back to a full list, for example because the revision has been
+	// compacted.  The consumer should discard any data of the resource type that it
+	// retained from before the syncer started, since deletions made since the revision
+	// cannot be reported.  The syncer then sends the current data of the resource type.
+	// The kind is as for OnTypeSynced.
+	OnTypeResync(kind string)
+}
+
 // Update from the Syncer.  A KV pair plus extra metadata.
 type Update struct {
 	model.KVPair
@@ -195,6 +250,7 @@ const (
 	WatchModified WatchEventType = "MODIFIED"
 	WatchDeleted  WatchEventType = "DELETED"
 	WatchError    WatchEventType = "ERROR"
+	WatchBookmark WatchEventType = "BOOKMARK"
 )
 
 // Event represents a single event to a watched resource.
@@ -202,10 +258,13 @@ type WatchEvent struct {
 	Type WatchEventType
 
 	// Old is:
-	// * If Type is Added or Error: nil
+	// * If Type is Added, Error or Bookmark: nil
 	// * If Type is Modified or Deleted: the previous state of the object
 	// New is:
 	//  * If Type is Added or Modified: the new state of the object.
+	//  * If Type is Bookmark: a KVPair with only the Revision set.  A bookmark indicates
+	//    that the watch has progressed to the revision, and may be resumed from it,
+	//    without any change to the watched objects.
 	//  * If Type is Deleted or Error: nil
 	Old *model.KVPair
 	New *model.KVPair
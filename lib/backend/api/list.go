@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+)
+
+// PagedLister is an optional interface that may be implemented by a Client.  Datastores that
+// support it are able to return a large list in pages, so that the list does not need to be
+// held in memory all at once.
+type PagedLister interface {
+	// ListPaged returns a page of at most pageSize KVPairs matching the input list options,
+	// starting from the page identified by the continue token, or from the first page if the
+	// token is empty.  Also returns the continue token for the next page, which is empty if
+	// this is the last page.  All of the pages of a list reflect the datastore at the same
+	// revision, which is returned as the Revision of each page.  A pageSize of zero or less
+	// returns the full list as a single page.
+	ListPaged(ctx context.Context, list model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error)
+}
+
+// ListPaged returns a page of the KVPairs matching the input list options.  If the client
+// implements PagedLister the list is paged natively, otherwise the full list is returned as a
+// single page with an empty continue token.
+func ListPaged(ctx context.Context, c Client, list model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error) {
+	if pl, ok := c.(PagedLister); ok {
+		return pl.ListPaged(ctx, list, pageSize, token)
+	}
+	l, err := c.List(ctx, list, "")
+	return l, "", err
+}
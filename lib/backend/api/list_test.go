@@ -0,0 +1,102 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_test
+
+import (
+	"context"
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+)
+
+var _ = Describe("Backend paged lists", func() {
+	ctx := context.Background()
+	list := model.GlobalConfigListOptions{}
+
+	It("should return the full list as a single page if the client does not support paging", func() {
+		client := &listClient{memClient: newMemClient()}
+		for _, k := range []model.Key{keyA, keyB} {
+			_, err := client.Create(ctx, &model.KVPair{Key: k, Value: "v"})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		l, token, err := api.ListPaged(ctx, client, list, 1, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(BeEmpty())
+		Expect(l.KVPairs).To(HaveLen(2))
+	})
+
+	It("should page natively if the client supports paging", func() {
+		client := &pagedListClient{listClient{memClient: newMemClient()}}
+		for _, k := range []model.Key{keyA, keyB} {
+			_, err := client.Create(ctx, &model.KVPair{Key: k, Value: "v"})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		l, token, err := api.ListPaged(ctx, client, list, 1, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.KVPairs).To(HaveLen(1))
+		Expect(l.KVPairs[0].Key).To(Equal(keyA))
+		Expect(token).NotTo(BeEmpty())
+
+		l, token, err = api.ListPaged(ctx, client, list, 1, token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.KVPairs).To(HaveLen(1))
+		Expect(l.KVPairs[0].Key).To(Equal(keyB))
+		Expect(token).To(BeEmpty())
+	})
+})
+
+// listClient is a memClient that supports List.
+type listClient struct {
+	*memClient
+}
+
+func (c *listClient) List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+	l := &model.KVPairList{}
+	for _, kvp := range c.kvps {
+		out := *kvp
+		l.KVPairs = append(l.KVPairs, &out)
+	}
+	sort.Slice(l.KVPairs, func(i, j int) bool {
+		return l.KVPairs[i].Key.String() < l.KVPairs[j].Key.String()
+	})
+	return l, nil
+}
+
+// pagedListClient is a listClient that implements the PagedLister interface, using the index
+// of the next entry as the continue token.
+type pagedListClient struct {
+	listClient
+}
+
+func (c *pagedListClient) ListPaged(ctx context.Context, list model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error) {
+	l, _ := c.List(ctx, list, "")
+	start := 0
+	if token != "" {
+		start = int(token[0] - '0')
+	}
+	end := start + int(pageSize)
+	if end >= len(l.KVPairs) {
+		l.KVPairs = l.KVPairs[start:]
+		return l, "", nil
+	}
+	l.KVPairs = l.KVPairs[start:end]
+	return l, string(rune('0' + end)), nil
+}
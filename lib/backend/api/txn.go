@@ -0,0 +1,210 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+// TxnOpType is the type of a single operation within a transaction.
+type TxnOpType uint8
+
+const (
+	// TxnCreate creates the KVPair, which must not already exist.
+	TxnCreate TxnOpType = iota
+	// TxnUpdate updates the KVPair, which must exist.  If the KVPair contains revision
+	// information then the update only succeeds if the revision is still current.
+	TxnUpdate
+	// TxnDelete deletes the KVPair.  If the KVPair contains revision information then
+	// the delete only succeeds if the revision is still current.
+	TxnDelete
+)
+
+func (t TxnOpType) String() string {
+	switch t {
+	case TxnCreate:
+		return "create"
+	case TxnUpdate:
+		return "update"
+	case TxnDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("TxnOpType(%d)", uint8(t))
+	}
+}
+
+// TxnOp is a single operation within a transaction.
+type TxnOp struct {
+	Type   TxnOpType
+	KVPair *model.KVPair
+}
+
+// Transactor is an optional interface that may be implemented by a Client.  Datastores
+// that support it are able to apply a set of operations atomically: either all of the
+// operations succeed, or none of them are applied.
+type Transactor interface {
+	// Txn applies the supplied operations atomically.  Each key may appear in at most
+	// one operation.  On success, returns a KVPair for each operation, in the same order
+	// as the operations, with revision information filled-in.  For a delete, the
+	// returned KVPair is the object that was deleted.
+	Txn(ctx context.Context, ops []TxnOp) ([]*model.KVPair, error)
+}
+
+// Txn applies the supplied operations as a single transaction.  If the client implements
+// Transactor the operations are applied natively, otherwise they are applied using
+// ApplyTxnWithRollback.
+func Txn(ctx context.Context, c Client, ops []TxnOp) ([]*model.KVPair, error) {
+	if t, ok := c.(Transactor); ok {
+		return t.Txn(ctx, ops)
+	}
+	return ApplyTxnWithRollback(ctx, c, ops)
+}
+
+// ApplyTxnWithRollback applies the supplied operations using optimistic concurrency for
+// datastores that cannot apply multiple operations atomically.
+//
+// The current state of each key is read up front, and each operation is then applied
+// with revision checking against that state, so a concurrent modification of any key
+// results in an update conflict.  If an operation fails, the operations that were
+// already applied are reverted in reverse order and the original error is returned;
+// callers may then re-read and retry.  If the revert itself fails, an
+// ErrorPartialFailure is returned.
+func ApplyTxnWithRollback(ctx context.Context, c Client, ops []TxnOp) ([]*model.KVPair, error) {
+	if err := ValidateTxnOps(ops); err != nil {
+		return nil, err
+	}
+
+	// Read the current state of each key and check that each operation can be applied.
+	// Update and delete operations without revision information are pinned to the
+	// revision we read so that a concurrent modification is detected.
+	pinned := make([]TxnOp, len(ops))
+	current := make([]*model.KVPair, len(ops))
+	for i, op := range ops {
+		key := op.KVPair.Key
+		kvp, err := c.Get(ctx, key, "")
+		if err != nil {
+			if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok || op.Type != TxnCreate {
+				return nil, err
+			}
+		}
+		current[i] = kvp
+		pinnedKVP := *op.KVPair
+		switch op.Type {
+		case TxnCreate:
+			if kvp != nil {
+				return nil, cerrors.ErrorResourceAlreadyExists{Identifier: key}
+			}
+		case TxnUpdate, TxnDelete:
+			if pinnedKVP.Revision == "" {
+				pinnedKVP.Revision = kvp.Revision
+			} else if pinnedKVP.Revision != kvp.Revision {
+				return nil, cerrors.ErrorResourceUpdateConflict{Identifier: key}
+			}
+		}
+		pinned[i] = TxnOp{Type: op.Type, KVPair: &pinnedKVP}
+	}
+
+	// Apply the operations in order.  On failure, the values read above are used to
+	// restore any updated keys.
+	results := make([]*model.KVPair, 0, len(pinned))
+	for _, op := range pinned {
+		var result *model.KVPair
+		var err error
+		switch op.Type {
+		case TxnCreate:
+			result, err = c.Create(ctx, op.KVPair)
+		case TxnUpdate:
+			result, err = c.Update(ctx, op.KVPair)
+		case TxnDelete:
+			result, err = c.DeleteKVP(ctx, op.KVPair)
+		}
+		if err != nil {
+			log.WithError(err).WithField("key", op.KVPair.Key).Debug("Transaction operation failed, rolling back")
+			if rbErr := rollbackTxn(ctx, c, pinned[:len(results)], results, current); rbErr != nil {
+				return nil, cerrors.ErrorPartialFailure{
+					Err: fmt.Errorf("%v; rollback failed: %v", err, rbErr),
+				}
+			}
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// rollbackTxn reverts the applied operations in reverse order.
+func rollbackTxn(ctx context.Context, c Client, ops []TxnOp, results, previous []*model.KVPair) error {
+	for i := len(ops) - 1; i >= 0; i-- {
+		var err error
+		switch ops[i].Type {
+		case TxnCreate:
+			_, err = c.DeleteKVP(ctx, results[i])
+		case TxnUpdate:
+			restore := *previous[i]
+			restore.Revision = results[i].Revision
+			_, err = c.Update(ctx, &restore)
+		case TxnDelete:
+			restore := *results[i]
+			restore.Revision = ""
+			_, err = c.Create(ctx, &restore)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to revert %s of %v: %v", ops[i].Type, ops[i].KVPair.Key, err)
+		}
+	}
+	return nil
+}
+
+// ValidateTxnOps checks that the operations are well formed and that each key appears
+// in at most one operation.
+func ValidateTxnOps(ops []TxnOp) error {
+	seen := make(map[string]bool, len(ops))
+	for i, op := range ops {
+		if op.KVPair == nil || op.KVPair.Key == nil {
+			return cerrors.ErrorValidation{
+				ErroredFields: []cerrors.ErroredField{{
+					Name:   fmt.Sprintf("ops[%d]", i),
+					Reason: "operation has no key",
+				}},
+			}
+		}
+		if op.Type > TxnDelete {
+			return cerrors.ErrorValidation{
+				ErroredFields: []cerrors.ErroredField{{
+					Name:   fmt.Sprintf("ops[%d]", i),
+					Reason: fmt.Sprintf("unknown operation %s", op.Type),
+				}},
+			}
+		}
+		ks := op.KVPair.Key.String()
+		if seen[ks] {
+			return cerrors.ErrorValidation{
+				ErroredFields: []cerrors.ErroredField{{
+					Name:   fmt.Sprintf("ops[%d]", i),
+					Reason: "key appears in more than one operation",
+					Value:  ks,
+				}},
+			}
+		}
+		seen[ks] = true
+	}
+	return nil
+}
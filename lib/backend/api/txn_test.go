@@ -0,0 +1,273 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+var (
+	keyA = model.GlobalConfigKey{Name: "A"}
+	keyB = model.GlobalConfigKey{Name: "B"}
+)
+
+var _ = Describe("Backend transactions", func() {
+	var client *memClient
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = newMemClient()
+	})
+
+	value := func(k model.Key) interface{} {
+		kvp, err := client.Get(ctx, k, "")
+		if err != nil {
+			return nil
+		}
+		return kvp.Value
+	}
+
+	It("should commit a two-key transaction", func() {
+		_, err := client.Create(ctx, &model.KVPair{Key: keyA, Value: "a1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		kvps, err := api.Txn(ctx, client, []api.TxnOp{
+			{Type: api.TxnUpdate, KVPair: &model.KVPair{Key: keyA, Value: "a2"}},
+			{Type: api.TxnCreate, KVPair: &model.KVPair{Key: keyB, Value: "b1"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(2))
+		Expect(kvps[0].Value).To(Equal("a2"))
+		Expect(kvps[1].Value).To(Equal("b1"))
+		Expect(value(keyA)).To(Equal("a2"))
+		Expect(value(keyB)).To(Equal("b1"))
+	})
+
+	It("should return the deleted object for a delete operation", func() {
+		_, err := client.Create(ctx, &model.KVPair{Key: keyA, Value: "a1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		kvps, err := api.Txn(ctx, client, []api.TxnOp{
+			{Type: api.TxnDelete, KVPair: &model.KVPair{Key: keyA}},
+			{Type: api.TxnCreate, KVPair: &model.KVPair{Key: keyB, Value: "b1"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps[0].Value).To(Equal("a1"))
+		Expect(value(keyA)).To(BeNil())
+		Expect(value(keyB)).To(Equal("b1"))
+	})
+
+	It("should make no changes if a precondition does not hold", func() {
+		a, err := client.Create(ctx, &model.KVPair{Key: keyA, Value: "a1"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Create(ctx, &model.KVPair{Key: keyB, Value: "b1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = api.Txn(ctx, client, []api.TxnOp{
+			{Type: api.TxnUpdate, KVPair: &model.KVPair{Key: keyA, Value: "a2", Revision: a.Revision}},
+			{Type: api.TxnCreate, KVPair: &model.KVPair{Key: keyB, Value: "b2"}},
+		})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceAlreadyExists{}))
+		Expect(client.writes).To(Equal(2))
+		Expect(value(keyA)).To(Equal("a1"))
+		Expect(value(keyB)).To(Equal("b1"))
+	})
+
+	It("should roll back the first key if the second key conflicts", func() {
+		_, err := client.Create(ctx, &model.KVPair{Key: keyA, Value: "a1"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Create(ctx, &model.KVPair{Key: keyB, Value: "b1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		// Simulate a concurrent writer modifying B after the transaction has read it.
+		client.beforeWrite = func(k model.Key) {
+			if k == keyB {
+				client.beforeWrite = nil
+				_, err := client.Apply(ctx, &model.KVPair{Key: keyB, Value: "concurrent"})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+
+		_, err = api.Txn(ctx, client, []api.TxnOp{
+			{Type: api.TxnUpdate, KVPair: &model.KVPair{Key: keyA, Value: "a2"}},
+			{Type: api.TxnUpdate, KVPair: &model.KVPair{Key: keyB, Value: "b2"}},
+		})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+		Expect(value(keyA)).To(Equal("a1"))
+		Expect(value(keyB)).To(Equal("concurrent"))
+	})
+
+	It("should roll back a created key if a later operation fails", func() {
+		client.beforeWrite = func(k model.Key) {
+			if k == keyB {
+				client.beforeWrite = nil
+				_, err := client.Create(ctx, &model.KVPair{Key: keyB, Value: "concurrent"})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+
+		_, err := api.Txn(ctx, client, []api.TxnOp{
+			{Type: api.TxnCreate, KVPair: &model.KVPair{Key: keyA, Value: "a1"}},
+			{Type: api.TxnCreate, KVPair: &model.KVPair{Key: keyB, Value: "b1"}},
+		})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceAlreadyExists{}))
+		Expect(value(keyA)).To(BeNil())
+		Expect(value(keyB)).To(Equal("concurrent"))
+	})
+
+	It("should return a partial failure if the rollback fails", func() {
+		_, err := client.Create(ctx, &model.KVPair{Key: keyA, Value: "a1"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Create(ctx, &model.KVPair{Key: keyB, Value: "b1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		// Modify both keys after the first has been written so that neither the second
+		// operation nor the rollback of the first can succeed.
+		client.beforeWrite = func(k model.Key) {
+			if k == keyB {
+				client.beforeWrite = nil
+				_, err := client.Apply(ctx, &model.KVPair{Key: keyA, Value: "concurrent"})
+				Expect(err).NotTo(HaveOccurred())
+				_, err = client.Apply(ctx, &model.KVPair{Key: keyB, Value: "concurrent"})
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+
+		_, err = api.Txn(ctx, client, []api.TxnOp{
+			{Type: api.TxnUpdate, KVPair: &model.KVPair{Key: keyA, Value: "a2"}},
+			{Type: api.TxnUpdate, KVPair: &model.KVPair{Key: keyB, Value: "b2"}},
+		})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorPartialFailure{}))
+	})
+
+	It("should reject a transaction that uses the same key twice", func() {
+		_, err := api.Txn(ctx, client, []api.TxnOp{
+			{Type: api.TxnCreate, KVPair: &model.KVPair{Key: keyA, Value: "a1"}},
+			{Type: api.TxnUpdate, KVPair: &model.KVPair{Key: keyA, Value: "a2"}},
+		})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+		Expect(client.writes).To(BeZero())
+	})
+
+	It("should use the native transaction if the client supports it", func() {
+		tc := &txnClient{memClient: client}
+		_, err := api.Txn(ctx, tc, []api.TxnOp{
+			{Type: api.TxnCreate, KVPair: &model.KVPair{Key: keyA, Value: "a1"}},
+		})
+		Expect(err).To(Equal(errNativeTxn))
+		Expect(client.writes).To(BeZero())
+	})
+})
+
+var errNativeTxn = errors.New("native transaction")
+
+// txnClient is a memClient that implements the Transactor interface.
+type txnClient struct {
+	*memClient
+}
+
+func (c *txnClient) Txn(ctx context.Context, ops []api.TxnOp) ([]*model.KVPair, error) {
+	return nil, errNativeTxn
+}
+
+// memClient is a minimal in-memory implementation of the revision handling of a backend
+// client.
+type memClient struct {
+	api.Client
+	kvps        map[model.Key]*model.KVPair
+	rev         int
+	writes      int
+	beforeWrite func(k model.Key)
+}
+
+func newMemClient() *memClient {
+	return &memClient{kvps: map[model.Key]*model.KVPair{}}
+}
+
+func (c *memClient) store(kvp *model.KVPair) *model.KVPair {
+	c.rev++
+	c.writes++
+	stored := &model.KVPair{Key: kvp.Key, Value: kvp.Value, Revision: strconv.Itoa(c.rev)}
+	c.kvps[kvp.Key] = stored
+	out := *stored
+	return &out
+}
+
+func (c *memClient) hook(k model.Key) {
+	if c.beforeWrite != nil {
+		c.beforeWrite(k)
+	}
+}
+
+func (c *memClient) Create(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	c.hook(kvp.Key)
+	if _, ok := c.kvps[kvp.Key]; ok {
+		return nil, cerrors.ErrorResourceAlreadyExists{Identifier: kvp.Key}
+	}
+	return c.store(kvp), nil
+}
+
+func (c *memClient) Update(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	c.hook(kvp.Key)
+	current, ok := c.kvps[kvp.Key]
+	if !ok {
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: kvp.Key}
+	}
+	if kvp.Revision != "" && kvp.Revision != current.Revision {
+		return nil, cerrors.ErrorResourceUpdateConflict{Identifier: kvp.Key}
+	}
+	return c.store(kvp), nil
+}
+
+func (c *memClient) Apply(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	return c.store(kvp), nil
+}
+
+func (c *memClient) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	return c.Delete(ctx, kvp.Key, kvp.Revision)
+}
+
+func (c *memClient) Delete(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
+	c.hook(k)
+	current, ok := c.kvps[k]
+	if !ok {
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: k}
+	}
+	if revision != "" && revision != current.Revision {
+		return nil, cerrors.ErrorResourceUpdateConflict{Identifier: k}
+	}
+	c.writes++
+	delete(c.kvps, k)
+	return current, nil
+}
+
+func (c *memClient) Get(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
+	current, ok := c.kvps[k]
+	if !ok {
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: k}
+	}
+	out := *current
+	return &out, nil
+}
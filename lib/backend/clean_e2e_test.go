@@ -25,7 +25,9 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/apiconfig"
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	"github.com/projectcalico/libcalico-go/lib/testutils"
 )
 
@@ -125,4 +127,48 @@ var _ = testutils.E2eDatastoreDescribe("Backend API tests", testutils.DatastoreE
 			}, "10s", "1s").Should(HaveOccurred())
 		})
 	})
+
+	Describe("Test DeleteKVPs()", func() {
+		It("should delete each KVPair, reporting the KVPairs that could not be deleted", func() {
+			c, err := backend.NewClient(config)
+			Expect(err).NotTo(HaveOccurred())
+			c.Clean()
+
+			bd, ok := c.(api.BulkDeleter)
+			Expect(ok).To(BeTrue())
+
+			By("Creating some pools")
+			var kvps []*model.KVPair
+			for _, name := range []string{"ippool-1", "ippool-2", "ippool-3"} {
+				kvp, err := c.Create(ctx, &model.KVPair{
+					Key: model.ResourceKey{Kind: apiv3.KindIPPool, Name: name},
+					Value: &apiv3.IPPool{
+						ObjectMeta: metav1.ObjectMeta{Name: name},
+						Spec:       apiv3.IPPoolSpec{CIDR: "1.2.3.0/24"},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				kvps = append(kvps, kvp)
+			}
+
+			By("Updating one of the pools so that its listed revision is stale")
+			_, err = c.Update(ctx, kvps[1])
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Deleting the pools")
+			deleted, errs := bd.DeleteKVPs(ctx, kvps)
+			Expect(errs[0]).NotTo(HaveOccurred())
+			Expect(errs[1]).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+			Expect(errs[2]).NotTo(HaveOccurred())
+			Expect(deleted[0].Key).To(Equal(kvps[0].Key))
+			Expect(deleted[1]).To(BeNil())
+			Expect(deleted[2].Key).To(Equal(kvps[2].Key))
+
+			By("Checking only the updated pool remains")
+			list, err := c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindIPPool}, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(list.KVPairs).To(HaveLen(1))
+			Expect(list.KVPairs[0].Key).To(Equal(kvps[1].Key))
+		})
+	})
 })
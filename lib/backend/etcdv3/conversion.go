@@ -46,7 +46,39 @@ func convertListResponse(ekv *mvccpb.KeyValue, l model.ListInterface) *model.KVP
 // event did not correspond to an event that we are interested in.
 func convertWatchEvent(e *clientv3.Event, l model.ListInterface) (*api.WatchEvent, error) {
 	log.WithField("etcdv3-etcdKey", string(e.Kv.Key)).Debug("Processing etcdv3 event")
+	ae, ok, err := decodeWatchEvent(e, l, true)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return &ae, nil
+}
+
+// convertWatchEvents converts the events of an etcdv3 watch response in a single pass, appending
+// the api.WatchEvents to buf and returning the extended slice.  The events are converted in the
+// same way as convertWatchEvent, except that an event that fails to convert is returned in its
+// place as a WatchError event, so that the errors remain ordered with respect to the other
+// events.  Since the api.WatchEvents are held by value, the caller may reuse buf (truncated to
+// zero length) for the next response once the events have been sent.
+func convertWatchEvents(events []*clientv3.Event, l model.ListInterface, buf []api.WatchEvent) []api.WatchEvent {
+	debug := log.GetLevel() >= log.DebugLevel
+	for _, e := range events {
+		if debug {
+			log.WithField("etcdv3-etcdKey", string(e.Kv.Key)).Debug("Processing etcdv3 event")
+		}
+		if ae, ok, err := decodeWatchEvent(e, l, debug); err != nil {
+			buf = append(buf, api.WatchEvent{Type: api.WatchError, Error: err})
+		} else if ok {
+			buf = append(buf, ae)
+		}
+	}
+	return buf
+}
 
+// decodeWatchEvent converts an etcdv3 watch event to an api.WatchEvent.  The returned bool is
+// false if the event did not correspond to an event that we are interested in.  The per-event
+// debug logs are only emitted if debug is set, since building the log fields allocates even when
+// debug logging is disabled.
+func decodeWatchEvent(e *clientv3.Event, l model.ListInterface, debug bool) (api.WatchEvent, bool, error) {
 	var eventType api.WatchEventType
 	switch {
 	case e.Type == clientv3.EventTypeDelete:
@@ -59,34 +91,38 @@ func convertWatchEvent(e *clientv3.Event, l model.ListInterface) (*api.WatchEven
 
 	var oldKV, newKV *model.KVPair
 	var err error
-	if k := l.KeyFromDefaultPath(string(e.Kv.Key)); k != nil {
+	k := l.KeyFromDefaultPath(string(e.Kv.Key))
+	if k == nil {
+		if debug {
+			log.WithField("key", string(e.Kv.Key)).Debug("key filtered")
+		}
+		return api.WatchEvent{}, false, nil
+	}
+	if debug {
 		log.WithField("model-etcdKey", k).Debug("Key is valid and converted to model-etcdKey")
+	}
 
-		if eventType != api.WatchDeleted {
-			// Add or modify, parse the new value.
-			if newKV, err = etcdToKVPair(k, e.Kv); err != nil {
-				return nil, err
-			}
+	if eventType != api.WatchDeleted {
+		// Add or modify, parse the new value.
+		if newKV, err = etcdToKVPair(k, e.Kv); err != nil {
+			return api.WatchEvent{}, false, err
 		}
-		if eventType != api.WatchAdded {
-			// Delete or modify, parse the old value.
-			if oldKV, err = etcdToKVPair(k, e.PrevKv); err != nil {
-				if eventType == api.WatchDeleted || err != ErrMissingValue {
-					// Ignore missing value for modified events, but we need them for deletion.
-					return nil, err
-				}
+	}
+	if eventType != api.WatchAdded {
+		// Delete or modify, parse the old value.
+		if oldKV, err = etcdToKVPair(k, e.PrevKv); err != nil {
+			if eventType == api.WatchDeleted || err != ErrMissingValue {
+				// Ignore missing value for modified events, but we need them for deletion.
+				return api.WatchEvent{}, false, err
 			}
 		}
-	} else {
-		log.WithField("key", string(e.Kv.Key)).Debug("key filtered")
-		return nil, nil
 	}
 
-	return &api.WatchEvent{
+	return api.WatchEvent{
 		Old:  oldKV,
 		New:  newKV,
 		Type: eventType,
-	}, nil
+	}, true, nil
 }
 
 var (
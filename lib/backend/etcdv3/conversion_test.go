@@ -0,0 +1,152 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdv3
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+const nodesPrefix = "/calico/resources/v3/projectcalico.org/nodes/"
+
+// nodeKV returns an etcd KeyValue holding a Node with the given name and revisions.
+func nodeKV(name string, createRev, modRev int64) *mvccpb.KeyValue {
+	return &mvccpb.KeyValue{
+		Key:            []byte(nodesPrefix + name),
+		Value:          []byte(fmt.Sprintf(`{"kind":"Node","apiVersion":"projectcalico.org/v3","metadata":{"name":%q}}`, name)),
+		CreateRevision: createRev,
+		ModRevision:    modRev,
+	}
+}
+
+// mixedWatchEvents returns a stream of etcd watch events including adds, modifies and deletes, an
+// event for a key that is filtered out, and events that fail to convert.
+func mixedWatchEvents() []*clientv3.Event {
+	badKV := nodeKV("bad", 6, 6)
+	badKV.Value = []byte("{not json")
+	return []*clientv3.Event{
+		{Type: clientv3.EventTypePut, Kv: nodeKV("node1", 1, 1)},
+		{Type: clientv3.EventTypePut, Kv: nodeKV("node1", 1, 2), PrevKv: nodeKV("node1", 1, 1)},
+		{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte("/calico/other/key"), CreateRevision: 3, ModRevision: 3}},
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(nodesPrefix + "node2"), ModRevision: 4}, PrevKv: nodeKV("node2", 2, 2)},
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(nodesPrefix + "node3"), ModRevision: 5}},
+		{Type: clientv3.EventTypePut, Kv: badKV},
+		{Type: clientv3.EventTypePut, Kv: nodeKV("node4", 7, 7)},
+	}
+}
+
+var _ = Describe("Batched watch event conversion", func() {
+	list := model.ResourceListOptions{Kind: apiv3.KindNode}
+
+	It("should convert a stream of mixed events in the same way as convertWatchEvent", func() {
+		events := mixedWatchEvents()
+
+		var expected []api.WatchEvent
+		for _, e := range events {
+			if ae, err := convertWatchEvent(e, list); ae != nil {
+				expected = append(expected, *ae)
+			} else if err != nil {
+				expected = append(expected, api.WatchEvent{Type: api.WatchError, Error: err})
+			}
+		}
+
+		converted := convertWatchEvents(events, list, nil)
+		Expect(converted).To(Equal(expected))
+
+		Expect(converted).To(HaveLen(6))
+		Expect(converted[0].Type).To(Equal(api.WatchAdded))
+		Expect(converted[0].New.Key).To(Equal(model.ResourceKey{Kind: apiv3.KindNode, Name: "node1"}))
+		Expect(converted[0].New.Revision).To(Equal("1"))
+		Expect(converted[1].Type).To(Equal(api.WatchModified))
+		Expect(converted[1].Old.Revision).To(Equal("1"))
+		Expect(converted[1].New.Revision).To(Equal("2"))
+		Expect(converted[2].Type).To(Equal(api.WatchDeleted))
+		Expect(converted[2].Old.Key).To(Equal(model.ResourceKey{Kind: apiv3.KindNode, Name: "node2"}))
+		Expect(converted[2].New).To(BeNil())
+		Expect(converted[3].Type).To(Equal(api.WatchError))
+		Expect(converted[3].Error).To(Equal(ErrMissingValue))
+		Expect(converted[4].Type).To(Equal(api.WatchError))
+		Expect(converted[4].Error).To(BeAssignableToTypeOf(errors.ErrorParsingDatastoreEntry{}))
+		Expect(converted[5].Type).To(Equal(api.WatchAdded))
+		Expect(converted[5].New.Key).To(Equal(model.ResourceKey{Kind: apiv3.KindNode, Name: "node4"}))
+	})
+
+	It("should append to the supplied buffer", func() {
+		buf := make([]api.WatchEvent, 0, 10)
+		converted := convertWatchEvents(mixedWatchEvents()[:2], list, buf)
+		Expect(converted).To(HaveLen(2))
+		Expect(&converted[0]).To(BeIdenticalTo(&buf[:1][0]))
+
+		converted = convertWatchEvents(mixedWatchEvents()[3:4], list, converted[:0])
+		Expect(converted).To(HaveLen(1))
+		Expect(converted[0].Type).To(Equal(api.WatchDeleted))
+	})
+})
+
+func benchmarkWatchEvents(n int) []*clientv3.Event {
+	events := make([]*clientv3.Event, n)
+	for i := range events {
+		name := fmt.Sprintf("node%d", i)
+		events[i] = &clientv3.Event{
+			Type:   clientv3.EventTypePut,
+			Kv:     nodeKV(name, 1, int64(i+2)),
+			PrevKv: nodeKV(name, 1, 1),
+		}
+	}
+	return events
+}
+
+var watchEventsResult []api.WatchEvent
+
+func BenchmarkConvertWatchEventsSingly(b *testing.B) {
+	list := model.ResourceListOptions{Kind: apiv3.KindNode}
+	events := benchmarkWatchEvents(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var r []api.WatchEvent
+	for n := 0; n < b.N; n++ {
+		r = r[:0]
+		for _, e := range events {
+			if ae, _ := convertWatchEvent(e, list); ae != nil {
+				r = append(r, *ae)
+			}
+		}
+	}
+	watchEventsResult = r
+}
+
+func BenchmarkConvertWatchEventsBatched(b *testing.B) {
+	list := model.ResourceListOptions{Kind: apiv3.KindNode}
+	events := benchmarkWatchEvents(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var r []api.WatchEvent
+	for n := 0; n < b.N; n++ {
+		r = convertWatchEvents(events, list, r[:0])
+	}
+	watchEventsResult = r
+}
@@ -16,6 +16,7 @@ package etcdv3
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"strconv"
@@ -50,7 +51,7 @@ const (
 )
 
 type etcdV3Client struct {
-	etcdClient *clientv3.Client
+	pool *connectionPool
 }
 
 func NewEtcdV3Client(config *apiconfig.EtcdConfig) (api.Client, error) {
@@ -127,12 +128,12 @@ func NewEtcdV3Client(config *apiconfig.EtcdConfig) (api.Client, error) {
 		cfg.Password = config.EtcdPassword
 	}
 
-	client, err := clientv3.New(cfg)
+	pool, err := newEtcdConnectionPool(cfg, config.EtcdPoolSize)
 	if err != nil {
 		return nil, err
 	}
 
-	return &etcdV3Client{etcdClient: client}, nil
+	return &etcdV3Client{pool: pool}, nil
 }
 
 // Create an entry in the datastore.  If the entry already exists, this will return
@@ -155,7 +156,7 @@ func (c *etcdV3Client) Create(ctx context.Context, d *model.KVPair) (*model.KVPa
 	// Checking for 0 version of the etcdKey, which means it doesn't exists yet,
 	// and if it does, get the current value.
 	logCxt.Debug("Performing etcdv3 transaction for Create request")
-	txnResp, err := c.etcdClient.Txn(ctx).If(
+	txnResp, err := c.pool.get().Txn(ctx).If(
 		clientv3.Compare(clientv3.Version(key), "=", 0),
 	).Then(
 		clientv3.OpPut(key, value, putOpts...),
@@ -214,7 +215,7 @@ func (c *etcdV3Client) Update(ctx context.Context, d *model.KVPair) (*model.KVPa
 	conds := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(key), "=", rev)}
 
 	logCxt.Debug("Performing etcdv3 transaction for Update request")
-	txnResp, err := c.etcdClient.Txn(ctx).If(
+	txnResp, err := c.pool.get().Txn(ctx).If(
 		conds...,
 	).Then(
 		clientv3.OpPut(key, value, opts...),
@@ -269,7 +270,7 @@ func (c *etcdV3Client) Apply(ctx context.Context, d *model.KVPair) (*model.KVPai
 	}
 
 	logCxt.Debug("Performing etcdv3 Put for Apply request")
-	resp, err := c.etcdClient.Put(ctx, key, value, putOpts...)
+	resp, err := c.pool.get().Put(ctx, key, value, putOpts...)
 	if err != nil {
 		logCxt.WithError(err).Warning("Apply failed")
 		return nil, cerrors.ErrorDatastoreError{Err: err}
@@ -308,7 +309,7 @@ func (c *etcdV3Client) Delete(ctx context.Context, k model.Key, revision string)
 
 	// Perform the delete transaction - note that this is an exact delete, not a prefix delete.
 	logCxt.Debug("Performing etcdv3 transaction for Delete request")
-	txnResp, err := c.etcdClient.Txn(ctx).If(
+	txnResp, err := c.pool.get().Txn(ctx).If(
 		conds...,
 	).Then(
 		clientv3.OpDelete(key, clientv3.WithPrevKV()),
@@ -378,7 +379,7 @@ func (c *etcdV3Client) Get(ctx context.Context, k model.Key, revision string) (*
 	}
 
 	logCxt.Debug("Calling Get on etcdv3 client")
-	resp, err := c.etcdClient.Get(ctx, key, ops...)
+	resp, err := c.pool.get().Get(ctx, key, ops...)
 	if err != nil {
 		logCxt.WithError(err).Debug("Error returned from etcdv3 client")
 		return nil, cerrors.ErrorDatastoreError{Err: err}
@@ -391,6 +392,12 @@ func (c *etcdV3Client) Get(ctx context.Context, k model.Key, revision string) (*
 	return etcdToKVPair(k, resp.Kvs[0])
 }
 
+// GetConsistent gets an entry from the datastore using a linearizable read.  etcd reads are
+// linearizable by default, so this is simply a Get at the latest revision.
+func (c *etcdV3Client) GetConsistent(ctx context.Context, k model.Key) (*model.KVPair, error) {
+	return c.Get(ctx, k, "")
+}
+
 // List entries in the datastore.  This may return an empty list of there are
 // no entries matching the request in the ListInterface.
 func (c *etcdV3Client) List(ctx context.Context, l model.ListInterface, revision string) (*model.KVPairList, error) {
@@ -411,7 +418,7 @@ func (c *etcdV3Client) List(ctx context.Context, l model.ListInterface, revision
 	}
 
 	logCxt.Debug("Calling Get on etcdv3 client")
-	resp, err := c.etcdClient.Get(ctx, key, ops...)
+	resp, err := c.pool.get().Get(ctx, key, ops...)
 	if err != nil {
 		logCxt.WithError(err).Debug("Error returned from etcdv3 client")
 		return nil, cerrors.ErrorDatastoreError{Err: err}
@@ -439,6 +446,75 @@ func (c *etcdV3Client) List(ctx context.Context, l model.ListInterface, revision
 	}, nil
 }
 
+// ListPaged returns a page of entries in the datastore, see api.PagedLister.  The pages are read at
+// the revision of the first page, so a list may fail part way through if that revision is
+// compacted.  Since entries that do not convert are filtered out, a page may hold fewer than
+// pageSize entries even if it is not the last page.
+func (c *etcdV3Client) ListPaged(ctx context.Context, l model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error) {
+	logCxt := log.WithFields(log.Fields{"list-interface": l, "pageSize": pageSize, "token": token})
+	logCxt.Debug("Processing paged List request")
+
+	key, ops := calculateListKeyAndOptions(logCxt, l)
+	if pageSize <= 0 || len(ops) == 0 {
+		// Either paging is not requested, or the list is for a single fully qualified key, so
+		// the list is returned as a single page.
+		list, err := c.List(ctx, l, "")
+		return list, "", err
+	}
+
+	// Each page is a range Get from the start key to the end of the prefix, at the revision of
+	// the first page.
+	start, rev := key, int64(0)
+	if len(token) != 0 {
+		var err error
+		if rev, start, err = parseListToken(token); err != nil || !strings.HasPrefix(start, key) {
+			logCxt.Debug("Invalid continue token")
+			return nil, "", cerrors.ErrorValidation{
+				ErroredFields: []cerrors.ErroredField{{Name: "ContinueToken", Value: token}},
+			}
+		}
+	}
+	ops = []clientv3.OpOption{clientv3.WithRange(clientv3.GetPrefixRangeEnd(key)), clientv3.WithLimit(pageSize)}
+	if rev != 0 {
+		ops = append(ops, clientv3.WithRev(rev))
+	}
+
+	logCxt.Debug("Calling Get on etcdv3 client")
+	resp, err := c.pool.get().Get(ctx, start, ops...)
+	if err != nil {
+		logCxt.WithError(err).Debug("Error returned from etcdv3 client")
+		return nil, "", cerrors.ErrorDatastoreError{Err: err}
+	}
+	if rev == 0 {
+		rev = resp.Header.Revision
+	}
+	logCxt.WithField("numResults", len(resp.Kvs)).Debug("Processing response from etcdv3")
+
+	list := []*model.KVPair{}
+	for _, p := range resp.Kvs {
+		if kv := convertListResponse(p, l); kv != nil {
+			list = append(list, kv)
+		}
+	}
+
+	// The next page starts immediately after the last key of this page.
+	var next string
+	if resp.More && len(resp.Kvs) > 0 {
+		next = formatListToken(rev, string(resp.Kvs[len(resp.Kvs)-1].Key)+"\x00")
+	}
+
+	// As for List, we always include the default-allow profile when listing profiles.  It is
+	// included in the last page.
+	if next == "" && (key == profilesKey || key == defaultAllowProfileKey) {
+		list = append(list, resources.DefaultAllowProfile())
+	}
+
+	return &model.KVPairList{
+		KVPairs:  list,
+		Revision: strconv.FormatInt(rev, 10),
+	}, next, nil
+}
+
 func calculateListKeyAndOptions(logCxt *log.Entry, l model.ListInterface) (string, []clientv3.OpOption) {
 	// -  If the final name segment of the name is itself a prefix, then just perform a prefix Get
 	//    using the constructed key.
@@ -475,10 +551,16 @@ func (c *etcdV3Client) EnsureInitialized() error {
 	return nil
 }
 
+// Close closes the connections to etcd.  This is not part of the exposed API, but is public to
+// allow direct consumers of the backend API to release the connections.
+func (c *etcdV3Client) Close() error {
+	return c.pool.Close()
+}
+
 // Clean removes all of the Calico data from the datastore.
 func (c *etcdV3Client) Clean() error {
 	log.Warning("Cleaning etcdv3 datastore of all Calico data")
-	_, err := c.etcdClient.Txn(context.Background()).If().Then(
+	_, err := c.pool.get().Txn(context.Background()).If().Then(
 		clientv3.OpDelete("/calico/", clientv3.WithPrefix()),
 	).Commit()
 
@@ -493,7 +575,7 @@ func (c *etcdV3Client) Clean() error {
 // direct consumers of the backend API to access this.
 func (c *etcdV3Client) IsClean() (bool, error) {
 	log.Debug("Calling Get on etcdv3 client")
-	resp, err := c.etcdClient.Get(context.Background(), "/calico/", clientv3.WithPrefix())
+	resp, err := c.pool.get().Get(context.Background(), "/calico/", clientv3.WithPrefix())
 	if err != nil {
 		log.WithError(err).Debug("Error returned from etcdv3 client")
 		return false, cerrors.ErrorDatastoreError{Err: err}
@@ -508,7 +590,7 @@ func (c *etcdV3Client) getTTLOption(ctx context.Context, d *model.KVPair) ([]cli
 	putOpts := []clientv3.OpOption{}
 
 	if d.TTL != 0 {
-		resp, err := c.etcdClient.Lease.Grant(ctx, int64(d.TTL.Seconds()))
+		resp, err := c.pool.get().Grant(ctx, int64(d.TTL.Seconds()))
 		if err != nil {
 			log.WithError(err).Error("Failed to grant a lease")
 			return nil, cerrors.ErrorDatastoreError{Err: err}
@@ -544,6 +626,29 @@ func getKeyValueStrings(d *model.KVPair) (string, string, error) {
 	return key, string(bytes), nil
 }
 
+// formatListToken returns the continue token for a paged list, identifying the revision of the
+// list and the key that the next page starts from.
+func formatListToken(rev int64, start string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(rev, 10) + ":" + start))
+}
+
+// parseListToken parses a continue token returned by formatListToken.
+func parseListToken(token string) (int64, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, "", err
+	}
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("malformed continue token")
+	}
+	rev, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return rev, parts[1], nil
+}
+
 // parseRevision parses the model.KVPair revision string and converts to the
 // equivalent etcdv3 int64 value.
 func parseRevision(revs string) (int64, error) {
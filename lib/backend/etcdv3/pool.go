@@ -0,0 +1,239 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdv3
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+)
+
+var (
+	healthCheckInterval = 10 * time.Second
+	healthCheckTimeout  = 5 * time.Second
+)
+
+// etcdConnection is the subset of the etcd client used by the etcdv3 backend.
+type etcdConnection interface {
+	clientv3.KV
+	clientv3.Lease
+	clientv3.Watcher
+}
+
+// healthCheckFunc checks the health of the endpoint of a connection, returning an error if the
+// endpoint is not healthy.
+type healthCheckFunc func(ctx context.Context, conn etcdConnection) error
+
+// poolConnection is a connection in a connectionPool.
+type poolConnection struct {
+	etcdConnection
+
+	// The endpoints of the connection, used for logging.
+	endpoint string
+
+	// Non-zero if the last health check of the connection failed.
+	unhealthy int32
+}
+
+func (c *poolConnection) healthy() bool {
+	return atomic.LoadInt32(&c.unhealthy) == 0
+}
+
+// connectionPool spreads the requests of the etcdv3 backend across a set of connections, each
+// to a single etcd endpoint.  The connections are health checked periodically, and requests are
+// only routed to the healthy connections (unless none are healthy).  The watches using a
+// connection are cancelled when it becomes unhealthy, so that they are restarted using another
+// connection.
+type connectionPool struct {
+	conns       []*poolConnection
+	next        uint32
+	healthCheck healthCheckFunc
+
+	// The cancel functions of the watches using each connection, indexed by an ID
+	// allocated when the watch starts.
+	lock        sync.Mutex
+	watches     map[*poolConnection]map[uint64]context.CancelFunc
+	nextWatchID uint64
+
+	// Closed to stop the health checks when the pool is closed.
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// newConnectionPool creates a pool of the given connections.  The health check may be nil, in
+// which case every connection is treated as healthy.
+func newConnectionPool(conns []*poolConnection, healthCheck healthCheckFunc) *connectionPool {
+	return &connectionPool{
+		conns:       conns,
+		healthCheck: healthCheck,
+		watches:     map[*poolConnection]map[uint64]context.CancelFunc{},
+		stop:        make(chan struct{}),
+	}
+}
+
+// newEtcdConnectionPool creates a pool of size connections, assigning the endpoints in the
+// config to the connections in turn.  If the size is not greater than one, the pool has a single
+// connection that uses all of the endpoints, and is not health checked.
+func newEtcdConnectionPool(cfg clientv3.Config, size int) (*connectionPool, error) {
+	if size <= 1 {
+		client, err := clientv3.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newConnectionPool([]*poolConnection{{etcdConnection: client}}, nil), nil
+	}
+
+	var clients []*clientv3.Client
+	var conns []*poolConnection
+	for i := 0; i < size; i++ {
+		endpoint := cfg.Endpoints[i%len(cfg.Endpoints)]
+		connCfg := cfg
+		connCfg.Endpoints = []string{endpoint}
+		client, err := clientv3.New(connCfg)
+		if err != nil {
+			for _, c := range clients {
+				_ = c.Close()
+			}
+			return nil, err
+		}
+		clients = append(clients, client)
+		conns = append(conns, &poolConnection{etcdConnection: client, endpoint: endpoint})
+	}
+	log.WithFields(log.Fields{"size": size, "endpoints": cfg.Endpoints}).Info("Created etcdv3 connection pool")
+
+	p := newConnectionPool(conns, checkEndpointHealth)
+	go p.run(healthCheckInterval)
+	return p, nil
+}
+
+// checkEndpointHealth checks the health of an endpoint in the same way as "etcdctl endpoint
+// health", by performing a linearizable read.  A permission denied error shows that the endpoint
+// is serving requests, so is treated as healthy.
+func checkEndpointHealth(ctx context.Context, conn etcdConnection) error {
+	_, err := conn.Get(ctx, "health")
+	if err == nil || err == rpctypes.ErrPermissionDenied {
+		return nil
+	}
+	return err
+}
+
+// get returns the connection to use for a request.  The healthy connections are used in turn;
+// if no connection is healthy, all of the connections are used in turn.
+func (p *connectionPool) get() *poolConnection {
+	n := uint32(len(p.conns))
+	for i := uint32(0); i < n; i++ {
+		if c := p.conns[atomic.AddUint32(&p.next, 1)%n]; c.healthy() {
+			return c
+		}
+	}
+	return p.conns[atomic.AddUint32(&p.next, 1)%n]
+}
+
+// watchContext returns the connection to use for a watch, along with a context derived from
+// ctx that is cancelled if the connection becomes unhealthy.  The returned release function
+// must be called once the watch has finished.
+func (p *connectionPool) watchContext(ctx context.Context) (context.Context, *poolConnection, func()) {
+	conn := p.get()
+	wctx, cancel := context.WithCancel(ctx)
+
+	p.lock.Lock()
+	id := p.nextWatchID
+	p.nextWatchID++
+	if p.watches[conn] == nil {
+		p.watches[conn] = map[uint64]context.CancelFunc{}
+	}
+	p.watches[conn][id] = cancel
+	p.lock.Unlock()
+
+	release := func() {
+		p.lock.Lock()
+		delete(p.watches[conn], id)
+		p.lock.Unlock()
+		cancel()
+	}
+	return wctx, conn, release
+}
+
+// run health checks the connections at the given interval until the pool is closed.
+func (p *connectionPool) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth(context.Background())
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Close stops the health checks and closes each connection in the pool.  It returns the first
+// error encountered closing a connection.
+func (p *connectionPool) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		for _, c := range p.conns {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	})
+	return err
+}
+
+// checkHealth health checks each connection, updating whether the connection is used.
+func (p *connectionPool) checkHealth(ctx context.Context) {
+	if p.healthCheck == nil {
+		return
+	}
+	for _, c := range p.conns {
+		hctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := p.healthCheck(hctx, c.etcdConnection)
+		cancel()
+		p.setHealthy(c, err)
+	}
+}
+
+// setHealthy records the result of a health check of a connection.  When a connection becomes
+// unhealthy, the watches using it are cancelled.
+func (p *connectionPool) setHealthy(c *poolConnection, err error) {
+	logCxt := log.WithField("endpoint", c.endpoint)
+	if err == nil {
+		if atomic.CompareAndSwapInt32(&c.unhealthy, 1, 0) {
+			logCxt.Info("etcd endpoint is healthy again")
+		}
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&c.unhealthy, 0, 1) {
+		logCxt.WithError(err).Debug("etcd endpoint is still unhealthy")
+		return
+	}
+	logCxt.WithError(err).Warn("etcd endpoint is unhealthy, moving requests to other endpoints")
+
+	p.lock.Lock()
+	watches := p.watches[c]
+	delete(p.watches, c)
+	p.lock.Unlock()
+	for _, cancel := range watches {
+		cancel()
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+)
+
+// fakeWatch is a watch started on a fakeConnection.
+type fakeWatch struct {
+	rev    int64
+	events chan clientv3.WatchResponse
+}
+
+// fakeConnection is an etcdConnection that counts the Get requests and records the watches
+// made using it.  The other methods are not implemented.
+type fakeConnection struct {
+	clientv3.KV
+	clientv3.Lease
+	clientv3.Watcher
+
+	gets    int32
+	closes  int32
+	watches chan *fakeWatch
+}
+
+func newFakeConnection() *fakeConnection {
+	return &fakeConnection{watches: make(chan *fakeWatch, 10)}
+}
+
+func (f *fakeConnection) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	atomic.AddInt32(&f.gets, 1)
+	return &clientv3.GetResponse{}, nil
+}
+
+// Close is implemented explicitly since both the Lease and the Watcher have a Close method.
+func (f *fakeConnection) Close() error {
+	atomic.AddInt32(&f.closes, 1)
+	return nil
+}
+
+func (f *fakeConnection) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	w := &fakeWatch{
+		rev:    clientv3.OpGet(key, opts...).Rev(),
+		events: make(chan clientv3.WatchResponse),
+	}
+	go func() {
+		<-ctx.Done()
+		close(w.events)
+	}()
+	f.watches <- w
+	return w.events
+}
+
+var _ = Describe("etcdv3 connection pool", func() {
+	var conns []*fakeConnection
+	var down map[etcdConnection]bool
+	var pool *connectionPool
+	var client *etcdV3Client
+
+	BeforeEach(func() {
+		conns = []*fakeConnection{newFakeConnection(), newFakeConnection(), newFakeConnection()}
+		down = map[etcdConnection]bool{}
+		var pcs []*poolConnection
+		for _, c := range conns {
+			pcs = append(pcs, &poolConnection{etcdConnection: c})
+		}
+		pool = newConnectionPool(pcs, func(ctx context.Context, conn etcdConnection) error {
+			if down[conn] {
+				return errors.New("endpoint is unavailable")
+			}
+			return nil
+		})
+		client = &etcdV3Client{pool: pool}
+	})
+
+	// sendRequests sends the given number of Get requests through the pool.
+	sendRequests := func(n int) {
+		for i := 0; i < n; i++ {
+			_, err := client.IsClean()
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+
+	gets := func() []int32 {
+		var counts []int32
+		for _, c := range conns {
+			counts = append(counts, atomic.LoadInt32(&c.gets))
+		}
+		return counts
+	}
+
+	// nextWatch returns the index of the connection used for the next watch, and the watch.
+	nextWatch := func() (int, *fakeWatch) {
+		var idx int
+		EventuallyWithOffset(1, func() bool {
+			for i, c := range conns {
+				if len(c.watches) > 0 {
+					idx = i
+					return true
+				}
+			}
+			return false
+		}).Should(BeTrue())
+		return idx, <-conns[idx].watches
+	}
+
+	It("should spread requests across the connections", func() {
+		sendRequests(30)
+		Expect(gets()).To(Equal([]int32{10, 10, 10}))
+	})
+
+	It("should route requests away from an unhealthy endpoint", func() {
+		down[conns[1]] = true
+		pool.checkHealth(context.Background())
+		sendRequests(30)
+		Expect(gets()).To(Equal([]int32{15, 0, 15}))
+
+		By("the endpoint becoming healthy again")
+		down[conns[1]] = false
+		pool.checkHealth(context.Background())
+		sendRequests(30)
+		Expect(gets()).To(Equal([]int32{25, 10, 25}))
+	})
+
+	It("should use every connection if no endpoint is healthy", func() {
+		for _, c := range conns {
+			down[c] = true
+		}
+		pool.checkHealth(context.Background())
+		sendRequests(30)
+		Expect(gets()).To(Equal([]int32{10, 10, 10}))
+	})
+
+	It("should restart watches on another connection when an endpoint becomes unhealthy", func() {
+		w, err := client.Watch(context.Background(), model.ResourceListOptions{Kind: apiv3.KindNode}, "10")
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Stop()
+
+		first, fw := nextWatch()
+		Expect(fw.rev).To(Equal(int64(11)))
+
+		By("receiving an event from the first watch")
+		fw.events <- clientv3.WatchResponse{Events: []*clientv3.Event{
+			{Type: mvccpb.PUT, Kv: nodeKV("node1", 12, 12)},
+		}}
+		Eventually(w.ResultChan()).Should(Receive())
+
+		By("marking the endpoint of the first watch as unhealthy")
+		down[conns[first]] = true
+		pool.checkHealth(context.Background())
+
+		second, sw := nextWatch()
+		Expect(second).NotTo(Equal(first))
+		Expect(sw.rev).To(Equal(int64(13)))
+		Expect(w.(*watcher).HasTerminated()).To(BeFalse())
+	})
+
+	It("should stop the health checks and close the connections when closed", func() {
+		checks := int32(0)
+		pool.healthCheck = func(ctx context.Context, conn etcdConnection) error {
+			atomic.AddInt32(&checks, 1)
+			return nil
+		}
+		done := make(chan struct{})
+		go func() {
+			pool.run(10 * time.Millisecond)
+			close(done)
+		}()
+		Eventually(func() int32 { return atomic.LoadInt32(&checks) }).Should(BeNumerically(">", 0))
+
+		Expect(pool.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		for _, c := range conns {
+			Expect(atomic.LoadInt32(&c.closes)).To(Equal(int32(1)))
+		}
+
+		By("closing the pool again")
+		Expect(pool.Close()).To(Succeed())
+		for _, c := range conns {
+			Expect(atomic.LoadInt32(&c.closes)).To(Equal(int32(1)))
+		}
+	})
+
+	It("should not restart watches that are stopped", func() {
+		w, err := client.Watch(context.Background(), model.ResourceListOptions{Kind: apiv3.KindNode}, "10")
+		Expect(err).NotTo(HaveOccurred())
+		nextWatch()
+
+		w.Stop()
+		Eventually(w.(*watcher).HasTerminated).Should(BeTrue())
+		Consistently(func() int {
+			n := 0
+			for _, c := range conns {
+				n += len(c.watches)
+			}
+			return n
+		}, "100ms").Should(Equal(0))
+	})
+})
@@ -0,0 +1,175 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdv3
+
+import (
+	"context"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+// maxBulkDeleteOps is the maximum number of deletes that DeleteKVPs performs in a single
+// transaction.  This matches the default limit on the number of operations in an etcd
+// transaction.
+const maxBulkDeleteOps = 128
+
+// Txn applies the supplied operations in a single etcdv3 transaction.  The transaction
+// only succeeds if the precondition of every operation holds; if any does not, no
+// changes are made and the error for the first failing operation is returned.
+func (c *etcdV3Client) Txn(ctx context.Context, ops []api.TxnOp) ([]*model.KVPair, error) {
+	logCxt := log.WithField("numOps", len(ops))
+	logCxt.Debug("Processing Txn request")
+	if err := api.ValidateTxnOps(ops); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(ops))
+	values := make([]string, len(ops))
+	revs := make([]int64, len(ops))
+	var conds []clientv3.Cmp
+	var thenOps, elseOps []clientv3.Op
+	for i, op := range ops {
+		d := op.KVPair
+		var err error
+		if op.Type == api.TxnDelete {
+			keys[i], err = model.KeyToDefaultDeletePath(d.Key)
+		} else {
+			keys[i], values[i], err = getKeyValueStrings(d)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if op.Type != api.TxnCreate && len(d.Revision) != 0 {
+			if revs[i], err = parseRevision(d.Revision); err != nil {
+				return nil, err
+			}
+		}
+
+		switch op.Type {
+		case api.TxnCreate:
+			conds = append(conds, clientv3.Compare(clientv3.Version(keys[i]), "=", 0))
+		case api.TxnUpdate, api.TxnDelete:
+			if revs[i] != 0 {
+				conds = append(conds, clientv3.Compare(clientv3.ModRevision(keys[i]), "=", revs[i]))
+			} else {
+				conds = append(conds, clientv3.Compare(clientv3.Version(keys[i]), ">", 0))
+			}
+		}
+
+		if op.Type == api.TxnDelete {
+			thenOps = append(thenOps, clientv3.OpDelete(keys[i], clientv3.WithPrevKV()))
+		} else {
+			putOpts, err := c.getTTLOption(ctx, d)
+			if err != nil {
+				return nil, err
+			}
+			thenOps = append(thenOps, clientv3.OpPut(keys[i], values[i], putOpts...))
+		}
+		elseOps = append(elseOps, clientv3.OpGet(keys[i]))
+	}
+
+	logCxt.Debug("Performing etcdv3 transaction for Txn request")
+	txnResp, err := c.pool.get().Txn(ctx).If(conds...).Then(thenOps...).Else(elseOps...).Commit()
+	if err != nil {
+		logCxt.WithError(err).Warning("Txn failed")
+		return nil, cerrors.ErrorDatastoreError{Err: err}
+	}
+
+	if !txnResp.Succeeded {
+		// Find the first operation whose precondition failed so that we can return the
+		// appropriate error.
+		for i, op := range ops {
+			getResp := txnResp.Responses[i].GetResponseRange()
+			exists := len(getResp.Kvs) != 0
+			switch {
+			case op.Type == api.TxnCreate && exists:
+				logCxt.Debug("Txn failed due to resource already existing")
+				return nil, cerrors.ErrorResourceAlreadyExists{Identifier: op.KVPair.Key}
+			case op.Type != api.TxnCreate && !exists:
+				logCxt.Debug("Txn failed due to resource not existing")
+				return nil, cerrors.ErrorResourceDoesNotExist{Identifier: op.KVPair.Key}
+			case op.Type != api.TxnCreate && revs[i] != 0 && getResp.Kvs[0].ModRevision != revs[i]:
+				logCxt.Debug("Txn failed due to resource update conflict")
+				return nil, cerrors.ErrorResourceUpdateConflict{Identifier: op.KVPair.Key}
+			}
+		}
+		// The conditions failed at commit time but the state has since changed.  Treat
+		// this as a conflict on the transaction as a whole.
+		return nil, cerrors.ErrorResourceUpdateConflict{Identifier: ops[0].KVPair.Key}
+	}
+
+	revision := strconv.FormatInt(txnResp.Header.Revision, 10)
+	results := make([]*model.KVPair, len(ops))
+	for i, op := range ops {
+		if op.Type == api.TxnDelete {
+			// Don't propagate a parsing error since the delete did succeed.
+			delResp := txnResp.Responses[i].GetResponseDeleteRange()
+			if len(delResp.PrevKvs) != 0 {
+				results[i], _ = etcdToKVPair(op.KVPair.Key, delResp.PrevKvs[0])
+			}
+			continue
+		}
+		v, err := model.ParseValue(op.KVPair.Key, []byte(values[i]))
+		if err != nil {
+			return nil, cerrors.ErrorPartialFailure{Err: err}
+		}
+		results[i] = &model.KVPair{
+			Key:      op.KVPair.Key,
+			Value:    v,
+			Revision: revision,
+			TTL:      op.KVPair.TTL,
+		}
+	}
+	return results, nil
+}
+
+// DeleteKVPs removes each of the supplied KVPairs.  The deletes are performed in transactions of
+// up to maxBulkDeleteOps KVPairs.  If a transaction fails then each of its KVPairs is deleted
+// individually, so that the error for each KVPair can be reported.
+func (c *etcdV3Client) DeleteKVPs(ctx context.Context, kvps []*model.KVPair) ([]*model.KVPair, []error) {
+	results := make([]*model.KVPair, len(kvps))
+	errs := make([]error, len(kvps))
+	for start := 0; start < len(kvps); start += maxBulkDeleteOps {
+		end := start + maxBulkDeleteOps
+		if end > len(kvps) {
+			end = len(kvps)
+		}
+		ops := make([]api.TxnOp, 0, end-start)
+		for _, kvp := range kvps[start:end] {
+			ops = append(ops, api.TxnOp{Type: api.TxnDelete, KVPair: kvp})
+		}
+		deleted, err := c.Txn(ctx, ops)
+		if err == nil {
+			copy(results[start:end], deleted)
+			continue
+		}
+
+		log.WithError(err).WithField("numKVPs", end-start).Debug("Bulk delete failed, deleting individually")
+		for i := start; i < end; i++ {
+			if kvp, err := c.DeleteKVP(ctx, kvps[i]); err != nil {
+				errs[i] = err
+			} else {
+				results[i] = kvp
+			}
+		}
+	}
+	return results, errs
+}
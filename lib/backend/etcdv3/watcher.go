@@ -114,35 +114,70 @@ func (wc *watcher) watchLoop() {
 		wc.sendAddedEvents(kvps)
 	}
 
-	opts = append(opts, clientv3.WithRev(wc.initialRev+1), clientv3.WithPrevKV())
+	for {
+		// Start the watch using a connection from the pool.  If the endpoint of the connection
+		// becomes unhealthy the watch context is cancelled, in which case the watch is restarted
+		// from the last revision received using another connection.
+		ctx, conn, release := wc.client.pool.watchContext(wc.ctx)
+		err := wc.watch(ctx, conn, logCxt, key, opts)
+		rebalance := ctx.Err() != nil && wc.ctx.Err() == nil
+		release()
+		if err != nil {
+			wc.sendError(err)
+			return
+		}
+		if !rebalance {
+			break
+		}
+		logCxt.WithField("endpoint", conn.endpoint).Info("Restarting etcdv3 watch away from unhealthy endpoint")
+	}
+
+	// If we exit the loop, it means the watcher has closed for some reason.
+	log.Warn("etcdv3 watch channel closed")
+}
+
+// watch runs a single etcdv3 watch from the revision following the last revision received, and
+// sends the events until the watch channel is closed.  It returns an error if the watch fails,
+// unless the failure is due to the watch context being cancelled because the connection has
+// become unhealthy.
+func (wc *watcher) watch(ctx context.Context, conn *poolConnection, logCxt *log.Entry, key string, opts []clientv3.OpOption) error {
+	opts = append(opts[:len(opts):len(opts)], clientv3.WithRev(wc.initialRev+1), clientv3.WithPrevKV())
 	logCxt = logCxt.WithFields(log.Fields{
 		"etcdv3-etcdKey": key,
 		"rev":            wc.initialRev,
+		"endpoint":       conn.endpoint,
 	})
 	logCxt.Debug("Starting etcdv3 watch")
-	wch := wc.client.etcdClient.Watch(wc.ctx, key, opts...)
+	wch := conn.Watch(ctx, key, opts...)
+
+	// The events of each watch response are decoded in a single pass into a buffer that is
+	// reused across responses.  This is safe since the events are sent by value.
+	var events []api.WatchEvent
 	for wres := range wch {
 		if wres.Err() != nil {
+			if ctx.Err() != nil && wc.ctx.Err() == nil {
+				// The watch was cancelled to move it to another connection.
+				return nil
+			}
 			// A watch channel error is a terminating event, so exit the loop.
 			err := wres.Err()
 			log.WithError(err).Error("Watch channel error")
-			wc.sendError(err)
-			return
+			return err
 		}
-		for _, e := range wres.Events {
-			// Convert the etcdv3 event to the equivalent Watcher event.  An error
-			// parsing the event is returned as an error, but don't exit the watcher as
-			// restarting the watcher is unlikely to fix the conversion error.
-			if ae, err := convertWatchEvent(e, wc.list); ae != nil {
-				wc.sendEvent(ae)
-			} else if err != nil {
-				wc.sendError(err)
-			}
+		// Convert the etcdv3 events to the equivalent Watcher events.  An error parsing an
+		// event is returned as an error event, but don't exit the watcher as restarting the
+		// watcher is unlikely to fix the conversion error.
+		events = convertWatchEvents(wres.Events, wc.list, events[:0])
+		for i := range events {
+			wc.sendEvent(&events[i])
 		}
-	}
 
-	// If we exit the loop, it means the watcher has closed for some reason.
-	log.Warn("etcdv3 watch channel closed")
+		// Track the revision received, so that the watch may be restarted from it.
+		if n := len(wres.Events); n > 0 {
+			wc.initialRev = wres.Events[n-1].Kv.ModRevision
+		}
+	}
+	return nil
 }
 
 // listCurrent retrieves the existing entries.
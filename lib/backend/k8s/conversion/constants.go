@@ -14,11 +14,13 @@
 
 package conversion
 
+import "github.com/projectcalico/libcalico-go/lib/selector"
+
 const (
-	NamespaceLabelPrefix            = "pcns."
+	NamespaceLabelPrefix            = selector.NamespaceLabelPrefix
 	NamespaceProfileNamePrefix      = "kns."
 	K8sNetworkPolicyNamePrefix      = "knp.default."
-	ServiceAccountLabelPrefix       = "pcsa."
+	ServiceAccountLabelPrefix       = selector.ServiceAccountLabelPrefix
 	ServiceAccountProfileNamePrefix = "ksa."
 
 	// AnnotationPodIP is an annotation we apply to pods when assigning them an IP.  It
@@ -32,6 +34,12 @@ const (
 	// AnnotationPodIPs is similar for the plural PodIPs field.
 	AnnotationPodIPs = "cni.projectcalico.org/podIPs"
 
+	// AnnotationIngressBandwidth and AnnotationEgressBandwidth are the standard Kubernetes
+	// annotations used to request bandwidth limits for a pod, for example "10M".  They are
+	// copied from the pod to the WorkloadEndpoint.
+	AnnotationIngressBandwidth = "kubernetes.io/ingress-bandwidth"
+	AnnotationEgressBandwidth  = "kubernetes.io/egress-bandwidth"
+
 	// NameLabel is a label that can be used to match a serviceaccount or namespace
 	// name exactly.
 	NameLabel = "projectcalico.org/name"
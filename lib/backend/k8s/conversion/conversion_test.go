@@ -477,6 +477,33 @@ var _ = Describe("Test Pod conversion", func() {
 
 	})
 
+	It("should copy only the bandwidth annotations to the workload endpoint", func() {
+		pod := kapiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "podA",
+				Namespace: "default",
+				Annotations: map[string]string{
+					"arbitrary":                       "annotation",
+					"cni.projectcalico.org/podIP":     "192.168.0.1",
+					"kubernetes.io/ingress-bandwidth": "10M",
+					"kubernetes.io/egress-bandwidth":  "20M",
+				},
+				ResourceVersion: "1234",
+			},
+			Spec: kapiv1.PodSpec{
+				NodeName:   "nodeA",
+				Containers: []kapiv1.Container{},
+			},
+		}
+
+		wep, err := podToWorkloadEndpoint(c, &pod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wep.Value.(*apiv3.WorkloadEndpoint).Annotations).To(Equal(map[string]string{
+			"kubernetes.io/ingress-bandwidth": "10M",
+			"kubernetes.io/egress-bandwidth":  "20M",
+		}))
+	})
+
 	It("should find the right address family target for dual stack floating IPs", func() {
 		pod := kapiv1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
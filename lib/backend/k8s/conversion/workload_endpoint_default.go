@@ -210,6 +210,17 @@ func (wc defaultWorkloadEndpointConverter) podToDefaultWorkloadEndpoint(pod *kap
 		}
 	}
 
+	// Copy the bandwidth annotations, these are converted to QoS controls by the syncer.
+	var annotations map[string]string
+	for _, a := range []string{AnnotationIngressBandwidth, AnnotationEgressBandwidth} {
+		if v, ok := pod.Annotations[a]; ok {
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[a] = v
+		}
+	}
+
 	// Create the workload endpoint.
 	wep := apiv3.NewWorkloadEndpoint()
 	wep.ObjectMeta = metav1.ObjectMeta{
@@ -218,6 +229,7 @@ func (wc defaultWorkloadEndpointConverter) podToDefaultWorkloadEndpoint(pod *kap
 		CreationTimestamp: pod.CreationTimestamp,
 		UID:               pod.UID,
 		Labels:            labels,
+		Annotations:       annotations,
 		GenerateName:      pod.GenerateName,
 	}
 	wep.Spec = apiv3.WorkloadEndpointSpec{
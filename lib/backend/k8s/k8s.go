@@ -45,6 +45,12 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+const (
+	// txnMaxRetries is the number of times a transaction is attempted when it conflicts
+	// with a concurrent update.
+	txnMaxRetries = 5
+)
+
 var (
 	resourceKeyType  = reflect.TypeOf(model.ResourceKey{})
 	resourceListType = reflect.TypeOf(model.ResourceListOptions{})
@@ -118,6 +124,18 @@ func NewKubeClient(ca *apiconfig.CalicoAPIConfigSpec) (api.Client, error) {
 		apiv3.KindNetworkPolicy,
 		resources.NewNetworkPolicyClient(cs, crdClientV1),
 	)
+	kubeClient.registerResourceClient(
+		reflect.TypeOf(model.ResourceKey{}),
+		reflect.TypeOf(model.ResourceListOptions{}),
+		apiv3.KindStagedGlobalNetworkPolicy,
+		resources.NewStagedGlobalNetworkPolicyClient(cs, crdClientV1),
+	)
+	kubeClient.registerResourceClient(
+		reflect.TypeOf(model.ResourceKey{}),
+		reflect.TypeOf(model.ResourceListOptions{}),
+		apiv3.KindStagedNetworkPolicy,
+		resources.NewStagedNetworkPolicyClient(cs, crdClientV1),
+	)
 	kubeClient.registerResourceClient(
 		reflect.TypeOf(model.ResourceKey{}),
 		reflect.TypeOf(model.ResourceListOptions{}),
@@ -372,6 +390,8 @@ func (c *KubeClient) Clean() error {
 		apiv3.KindFelixConfiguration,
 		apiv3.KindGlobalNetworkPolicy,
 		apiv3.KindNetworkPolicy,
+		apiv3.KindStagedGlobalNetworkPolicy,
+		apiv3.KindStagedNetworkPolicy,
 		apiv3.KindGlobalNetworkSet,
 		apiv3.KindNetworkSet,
 		apiv3.KindIPPool,
@@ -480,6 +500,10 @@ func buildCRDClientV1(cfg rest.Config) (*rest.RESTClient, error) {
 					&apiv3.GlobalNetworkPolicyList{},
 					&apiv3.NetworkPolicy{},
 					&apiv3.NetworkPolicyList{},
+					&apiv3.StagedGlobalNetworkPolicy{},
+					&apiv3.StagedGlobalNetworkPolicyList{},
+					&apiv3.StagedNetworkPolicy{},
+					&apiv3.StagedNetworkPolicyList{},
 					&apiv3.NetworkSet{},
 					&apiv3.NetworkSetList{},
 					&apiv3.HostEndpoint{},
@@ -582,6 +606,47 @@ func (c *KubeClient) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.K
 	return client.DeleteKVP(ctx, kvp)
 }
 
+// DeleteKVPWithPropagation deletes an entry in the datastore, deleting its dependents according
+// to the supplied propagation policy.
+func (c *KubeClient) DeleteKVPWithPropagation(ctx context.Context, kvp *model.KVPair, policy metav1.DeletionPropagation) (*model.KVPair, error) {
+	log.Debugf("Performing 'DeleteKVPWithPropagation' for %+v", kvp.Key)
+	client, ok := c.getResourceClientFromKey(kvp.Key).(resources.PropagatingDeleter)
+	if !ok {
+		log.Debug("Attempt to 'DeleteKVPWithPropagation' using kubernetes backend is not supported.")
+		return nil, cerrors.ErrorOperationNotSupported{
+			Identifier: kvp.Key,
+			Operation:  "DeleteWithPropagation",
+		}
+	}
+	return client.DeleteKVPWithPropagation(ctx, kvp, policy)
+}
+
+// Txn applies the supplied operations as a single transaction.  The Kubernetes API has no
+// multi-object transactions, so the operations are applied optimistically and reverted on
+// failure.  If none of the operations specify a revision, a conflict caused by a concurrent
+// update is retried against the latest state.
+func (c *KubeClient) Txn(ctx context.Context, ops []api.TxnOp) ([]*model.KVPair, error) {
+	log.Debugf("Performing 'Txn' for %d operations", len(ops))
+	retry := true
+	for _, op := range ops {
+		if op.KVPair != nil && op.KVPair.Revision != "" {
+			retry = false
+			break
+		}
+	}
+
+	var err error
+	for i := 0; i < txnMaxRetries; i++ {
+		var kvps []*model.KVPair
+		kvps, err = api.ApplyTxnWithRollback(ctx, c, ops)
+		if _, ok := err.(cerrors.ErrorResourceUpdateConflict); !ok || !retry {
+			return kvps, err
+		}
+		log.WithError(err).Debug("Transaction conflicted with a concurrent update, retrying")
+	}
+	return nil, err
+}
+
 // Delete an entry in the datastore by key.
 func (c *KubeClient) Delete(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
 	log.Debugf("Performing 'Delete' for %+v", k)
@@ -610,6 +675,13 @@ func (c *KubeClient) Get(ctx context.Context, k model.Key, revision string) (*mo
 	return client.Get(ctx, k, revision)
 }
 
+// GetConsistent gets an entry from the datastore using a quorum read.  The Kubernetes API server
+// serves a Get with no resource version from etcd rather than from its watch cache.
+func (c *KubeClient) GetConsistent(ctx context.Context, k model.Key) (*model.KVPair, error) {
+	log.Debugf("Performing consistent 'Get' for %+v", k)
+	return c.Get(ctx, k, "")
+}
+
 // List entries in the datastore.  This may return an empty list if there are
 // no entries matching the request in the ListInterface.
 func (c *KubeClient) List(ctx context.Context, l model.ListInterface, revision string) (*model.KVPairList, error) {
@@ -625,6 +697,25 @@ func (c *KubeClient) List(ctx context.Context, l model.ListInterface, revision s
 	return client.List(ctx, l, revision)
 }
 
+// ListPaged returns a page of entries in the datastore, see api.PagedLister.  Resource types
+// that do not support paging return the full list as a single page.
+func (c *KubeClient) ListPaged(ctx context.Context, l model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error) {
+	log.Debugf("Performing 'ListPaged' for %+v %v", l, reflect.TypeOf(l))
+	client := c.getResourceClientFromList(l)
+	if client == nil {
+		log.Info("Attempt to 'ListPaged' using kubernetes backend is not supported.")
+		return nil, "", cerrors.ErrorOperationNotSupported{
+			Identifier: l,
+			Operation:  "List",
+		}
+	}
+	if pl, ok := client.(resources.PagedLister); ok {
+		return pl.ListPaged(ctx, l, pageSize, token)
+	}
+	list, err := client.List(ctx, l, "")
+	return list, "", err
+}
+
 // List entries in the datastore.  This may return an empty list if there are
 // no entries matching the request in the ListInterface.
 func (c *KubeClient) Watch(ctx context.Context, l model.ListInterface, revision string) (api.WatchInterface, error) {
@@ -18,6 +18,7 @@ import (
 	"context"
 
 	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/projectcalico/libcalico-go/lib/backend/api"
@@ -71,6 +72,22 @@ type K8sResourceClient interface {
 	EnsureInitialized() error
 }
 
+// PropagatingDeleter is implemented by the K8sResourceClients that support deleting the
+// dependents of a resource according to a deletion propagation policy.
+type PropagatingDeleter interface {
+	// DeleteKVPWithPropagation removes the object specified by the KVPair, and deletes
+	// its dependents according to the supplied propagation policy.
+	DeleteKVPWithPropagation(ctx context.Context, object *model.KVPair, policy metav1.DeletionPropagation) (*model.KVPair, error)
+}
+
+// PagedLister is implemented by the K8sResourceClients that support listing resources a page at
+// a time.  See api.PagedLister.
+type PagedLister interface {
+	// ListPaged returns a page of at most pageSize KVPairs matching the input list options,
+	// and the continue token for the next page.
+	ListPaged(ctx context.Context, list model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error)
+}
+
 // K8sNodeResourceClient extends the K8sResourceClient to add a helper method to
 // extract resources from the supplied K8s Node.  This convenience interface is
 // expected to be removed in a future libcalico-go release.
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 
 	log "github.com/sirupsen/logrus"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -150,8 +151,30 @@ func (c *customK8sResourceClient) DeleteKVP(ctx context.Context, kvp *model.KVPa
 	return c.Delete(ctx, kvp.Key, kvp.Revision, kvp.UID)
 }
 
+// DeleteKVPWithPropagation deletes an existing Custom K8s Resource instance in the k8s API using
+// the supplied KVPair, deleting its dependents according to the supplied propagation policy.
+func (c *customK8sResourceClient) DeleteKVPWithPropagation(ctx context.Context, kvp *model.KVPair, policy metav1.DeletionPropagation) (*model.KVPair, error) {
+	return c.delete(ctx, kvp.Key, kvp.Revision, deleteOptions(kvp.UID, &policy))
+}
+
 // Delete deletes an existing Custom K8s Resource instance in the k8s API using the supplied KVPair.
 func (c *customK8sResourceClient) Delete(ctx context.Context, k model.Key, revision string, uid *types.UID) (*model.KVPair, error) {
+	return c.delete(ctx, k, revision, deleteOptions(uid, nil))
+}
+
+// deleteOptions returns the k8s delete options for the UID precondition and the propagation
+// policy.  Either may be nil, in which case the corresponding option is not set.
+func deleteOptions(uid *types.UID, policy *metav1.DeletionPropagation) *metav1.DeleteOptions {
+	opts := &metav1.DeleteOptions{PropagationPolicy: policy}
+	if uid != nil {
+		opts.Preconditions = &metav1.Preconditions{UID: uid}
+	}
+	return opts
+}
+
+// delete deletes an existing Custom K8s Resource instance in the k8s API using the supplied
+// k8s delete options.
+func (c *customK8sResourceClient) delete(ctx context.Context, k model.Key, revision string, opts *metav1.DeleteOptions) (*model.KVPair, error) {
 	logContext := log.WithFields(log.Fields{
 		"Key":      k,
 		"Resource": c.resource,
@@ -172,11 +195,6 @@ func (c *customK8sResourceClient) Delete(ctx context.Context, k model.Key, revis
 
 	namespace := k.(model.ResourceKey).Namespace
 
-	opts := &metav1.DeleteOptions{}
-	if uid != nil {
-		opts.Preconditions = &metav1.Preconditions{UID: uid}
-	}
-
 	// Delete the resource using the name.
 	logContext = logContext.WithField("Name", name)
 	logContext.Debug("Send delete request by name")
@@ -308,9 +326,62 @@ func (c *customK8sResourceClient) List(ctx context.Context, list model.ListInter
 	}, nil
 }
 
+// ListPaged returns a page of the Custom K8s Resources matching the list options, using the
+// Kubernetes limit and continue parameters.  Kubernetes serves all of the pages of a list from the
+// same snapshot, and returns an expired error if the snapshot has been compacted.
+func (c *customK8sResourceClient) ListPaged(ctx context.Context, list model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error) {
+	if pageSize <= 0 || c.listInterfaceToKey(list) != nil {
+		// Either paging is not requested, or the list is for a single fully qualified resource,
+		// so the list is returned as a single page.
+		l, err := c.List(ctx, list, "")
+		return l, "", err
+	}
+	logContext := log.WithFields(log.Fields{
+		"ListInterface": list,
+		"Resource":      c.resource,
+		"PageSize":      pageSize,
+		"Continue":      token,
+	})
+	logContext.Debug("List page of Custom K8s Resources")
+
+	reslOut := reflect.New(c.k8sListType).Interface().(ResourceList)
+	namespace := list.(model.ResourceListOptions).Namespace
+	req := c.restClient.Get().
+		NamespaceIfScoped(namespace, c.namespaced).
+		Resource(c.resource).
+		Param("limit", strconv.FormatInt(pageSize, 10))
+	if token != "" {
+		req = req.Param("continue", token)
+	}
+	if err := req.Do(ctx).Into(reslOut); err != nil {
+		// As for List, "not found" means there are no matching resources.
+		if !kerrors.IsNotFound(err) {
+			logContext.WithError(err).Debug("Error listing resources")
+			return nil, "", K8sErrorToCalico(err, list)
+		}
+		return &model.KVPairList{KVPairs: []*model.KVPair{}}, "", nil
+	}
+
+	kvps := []*model.KVPair{}
+	elem := reflect.ValueOf(reslOut).Elem()
+	items := reflect.ValueOf(elem.FieldByName("Items").Interface())
+	for idx := 0; idx < items.Len(); idx++ {
+		res := items.Index(idx).Addr().Interface().(Resource)
+		if kvp, err := c.convertResourceToKVPair(res); err == nil {
+			kvps = append(kvps, kvp)
+		} else {
+			logContext.WithError(err).WithField("Item", res).Warning("unable to process resource, skipping")
+		}
+	}
+	return &model.KVPairList{
+		KVPairs:  kvps,
+		Revision: reslOut.GetListMeta().GetResourceVersion(),
+	}, reslOut.GetListMeta().GetContinue(), nil
+}
+
 func (c *customK8sResourceClient) Watch(ctx context.Context, list model.ListInterface, revision string) (api.WatchInterface, error) {
 	// Build watch options to pass to k8s.
-	opts := metav1.ListOptions{ResourceVersion: revision, Watch: true}
+	opts := metav1.ListOptions{ResourceVersion: revision, Watch: true, AllowWatchBookmarks: true}
 	rlo, ok := list.(model.ResourceListOptions)
 	if !ok {
 		return nil, fmt.Errorf("ListInterface is not a ResourceListOptions: %s", list)
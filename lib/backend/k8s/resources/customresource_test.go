@@ -20,6 +20,7 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/net"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -132,3 +133,18 @@ var _ = Describe("Custom resource conversion methods (tested using BGPPeer)", fu
 		Expect(kvp.Value).To(Equal(kvp1.Value))
 	})
 })
+
+var _ = Describe("Custom resource delete options", func() {
+	It("should not set any options by default", func() {
+		Expect(deleteOptions(nil, nil)).To(Equal(&metav1.DeleteOptions{}))
+	})
+
+	It("should set the UID precondition and propagation policy", func() {
+		uid := types.UID("abcd")
+		policy := metav1.DeletePropagationOrphan
+		Expect(deleteOptions(&uid, &policy)).To(Equal(&metav1.DeleteOptions{
+			Preconditions:     &metav1.Preconditions{UID: &uid},
+			PropagationPolicy: &policy,
+		}))
+	})
+})
@@ -252,7 +252,7 @@ func (c *ipamBlockClient) List(ctx context.Context, list model.ListInterface, re
 func (c *ipamBlockClient) Watch(ctx context.Context, list model.ListInterface, revision string) (api.WatchInterface, error) {
 	resl := model.ResourceListOptions{Kind: apiv3.KindIPAMBlock}
 	k8sWatchClient := cache.NewListWatchFromClient(c.rc.restClient, c.rc.resource, "", fields.Everything())
-	k8sWatch, err := k8sWatchClient.WatchFunc(metav1.ListOptions{ResourceVersion: revision})
+	k8sWatch, err := k8sWatchClient.WatchFunc(metav1.ListOptions{ResourceVersion: revision, AllowWatchBookmarks: true})
 	if err != nil {
 		return nil, K8sErrorToCalico(err, list)
 	}
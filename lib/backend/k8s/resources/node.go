@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	kapiv1 "k8s.io/api/core/v1"
@@ -46,6 +47,7 @@ const (
 	nodeBgpIpv6VXLANTunnelAddrAnnotation  = "projectcalico.org/IPv6VXLANTunnelAddr"
 	nodeBgpAsnAnnotation                  = "projectcalico.org/ASNumber"
 	nodeBgpCIDAnnotation                  = "projectcalico.org/RouteReflectorClusterID"
+	nodeBgpServiceClusterIPsAnnotation    = "projectcalico.org/ServiceClusterIPs"
 	nodeK8sLabelAnnotation                = "projectcalico.org/kube-labels"
 	nodeWireguardIpv4IfaceAddrAnnotation  = "projectcalico.org/IPv4WireguardInterfaceAddr"
 	nodeWireguardPublicKeyAnnotation      = "projectcalico.org/WireguardPublicKey"
@@ -181,7 +183,7 @@ func (c *nodeClient) EnsureInitialized() error {
 
 func (c *nodeClient) Watch(ctx context.Context, list model.ListInterface, revision string) (api.WatchInterface, error) {
 	// Build watch options to pass to k8s.
-	opts := metav1.ListOptions{ResourceVersion: revision, Watch: true}
+	opts := metav1.ListOptions{ResourceVersion: revision, Watch: true, AllowWatchBookmarks: true}
 	rlo, ok := list.(model.ResourceListOptions)
 	if !ok {
 		return nil, fmt.Errorf("ListInterface is not a ResourceListOptions: %s", list)
@@ -226,6 +228,11 @@ func K8sNodeToCalico(k8sNode *kapiv1.Node, usePodCIDR bool) (*model.KVPair, erro
 	bgpSpec.IPv4Address = annotations[nodeBgpIpv4AddrAnnotation]
 	bgpSpec.IPv6Address = annotations[nodeBgpIpv6AddrAnnotation]
 	bgpSpec.RouteReflectorClusterID = annotations[nodeBgpCIDAnnotation]
+	for _, cidr := range strings.Split(annotations[nodeBgpServiceClusterIPsAnnotation], ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			bgpSpec.ServiceClusterIPs = append(bgpSpec.ServiceClusterIPs, apiv3.ServiceClusterIPBlock{CIDR: cidr})
+		}
+	}
 	asnString, ok := annotations[nodeBgpAsnAnnotation]
 	if ok {
 		asn, err := numorstring.ASNumberFromString(asnString)
@@ -292,6 +299,24 @@ func K8sNodeToCalico(k8sNode *kapiv1.Node, usePodCIDR bool) (*model.KVPair, erro
 		}
 	}
 
+	// Fill in the conditions from the Kubernetes node.
+	for _, c := range k8sNode.Status.Conditions {
+		calicoNode.Status.Conditions = append(calicoNode.Status.Conditions, apiv3.NodeCondition{
+			Type:   apiv3.NodeConditionType(c.Type),
+			Status: apiv3.ConditionStatus(c.Status),
+			Reason: c.Reason,
+		})
+	}
+
+	// Fill in the taints from the Kubernetes node.
+	for _, t := range k8sNode.Spec.Taints {
+		calicoNode.Spec.Taints = append(calicoNode.Spec.Taints, apiv3.NodeTaint{
+			Key:    t.Key,
+			Value:  t.Value,
+			Effect: apiv3.TaintEffect(t.Effect),
+		})
+	}
+
 	// Fill the list of all addresses from the calico Node
 	fillAllAddresses(calicoNode, k8sNode)
 
@@ -377,6 +402,7 @@ func mergeCalicoNodeIntoK8sNode(calicoNode *apiv3.Node, k8sNode *kapiv1.Node) (*
 		delete(k8sNode.Annotations, nodeBgpIpv6AddrAnnotation)
 		delete(k8sNode.Annotations, nodeBgpAsnAnnotation)
 		delete(k8sNode.Annotations, nodeBgpCIDAnnotation)
+		delete(k8sNode.Annotations, nodeBgpServiceClusterIPsAnnotation)
 	} else {
 		// If the BGP spec is not nil, then handle each field within the BGP spec individually.
 		if calicoNode.Spec.BGP.IPv4Address != "" {
@@ -408,6 +434,16 @@ func mergeCalicoNodeIntoK8sNode(calicoNode *apiv3.Node, k8sNode *kapiv1.Node) (*
 		} else {
 			delete(k8sNode.Annotations, nodeBgpCIDAnnotation)
 		}
+
+		if len(calicoNode.Spec.BGP.ServiceClusterIPs) != 0 {
+			var cidrs []string
+			for _, b := range calicoNode.Spec.BGP.ServiceClusterIPs {
+				cidrs = append(cidrs, b.CIDR)
+			}
+			k8sNode.Annotations[nodeBgpServiceClusterIPsAnnotation] = strings.Join(cidrs, ",")
+		} else {
+			delete(k8sNode.Annotations, nodeBgpServiceClusterIPsAnnotation)
+		}
 	}
 
 	if calicoNode.Spec.Wireguard == nil {
@@ -192,6 +192,79 @@ var _ = Describe("Test Node conversion", func() {
 		Expect(n.Value.(*apiv3.Node).Spec.BGP).To(BeNil())
 	})
 
+	It("should parse a k8s Node with taints to a Calico Node", func() {
+		node := k8sapi.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "TestNode",
+				ResourceVersion: "1234",
+			},
+			Spec: k8sapi.NodeSpec{
+				Taints: []k8sapi.Taint{
+					{Key: "node-role.kubernetes.io/master", Effect: k8sapi.TaintEffectNoSchedule},
+					{Key: "dedicated", Value: "gpu", Effect: k8sapi.TaintEffectNoExecute},
+				},
+			},
+		}
+
+		n, err := K8sNodeToCalico(&node, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Value.(*apiv3.Node).Spec.Taints).To(Equal([]apiv3.NodeTaint{
+			{Key: "node-role.kubernetes.io/master", Effect: apiv3.TaintEffectNoSchedule},
+			{Key: "dedicated", Value: "gpu", Effect: apiv3.TaintEffectNoExecute},
+		}))
+	})
+
+	It("should parse a k8s Node with conditions to a Calico Node", func() {
+		node := k8sapi.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "TestNode",
+				ResourceVersion: "1234",
+			},
+			Status: k8sapi.NodeStatus{
+				Conditions: []k8sapi.NodeCondition{
+					{Type: k8sapi.NodeMemoryPressure, Status: k8sapi.ConditionFalse, Reason: "KubeletHasSufficientMemory"},
+					{Type: k8sapi.NodeReady, Status: k8sapi.ConditionTrue, Reason: "KubeletReady", Message: "kubelet is posting ready status"},
+				},
+			},
+		}
+
+		n, err := K8sNodeToCalico(&node, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Value.(*apiv3.Node).Status.Conditions).To(Equal([]apiv3.NodeCondition{
+			{Type: "MemoryPressure", Status: apiv3.ConditionFalse, Reason: "KubeletHasSufficientMemory"},
+			{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionTrue, Reason: "KubeletReady"},
+		}))
+	})
+
+	It("should round trip the BGP service ClusterIPs through the k8s Node annotations", func() {
+		k8sNode := &k8sapi.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "TestNode",
+				ResourceVersion: "1234",
+				Annotations:     make(map[string]string),
+			},
+		}
+		calicoNode := apiv3.NewNode()
+		calicoNode.Name = "TestNode"
+		calicoNode.Spec.BGP = &apiv3.NodeBGPSpec{
+			ServiceClusterIPs: []apiv3.ServiceClusterIPBlock{{CIDR: "10.96.0.0/12"}, {CIDR: "fd00:96::/112"}},
+		}
+
+		newK8sNode, err := mergeCalicoNodeIntoK8sNode(calicoNode, k8sNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newK8sNode.Annotations).To(HaveKeyWithValue(nodeBgpServiceClusterIPsAnnotation, "10.96.0.0/12,fd00:96::/112"))
+
+		n, err := K8sNodeToCalico(newK8sNode, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Value.(*apiv3.Node).Spec.BGP).To(Equal(calicoNode.Spec.BGP))
+
+		By("removing the service ClusterIPs")
+		calicoNode.Spec.BGP = nil
+		newK8sNode, err = mergeCalicoNodeIntoK8sNode(calicoNode, newK8sNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newK8sNode.Annotations).NotTo(HaveKey(nodeBgpServiceClusterIPsAnnotation))
+	})
+
 	It("Should parse and remove BGP info when given Calico Node with empty BGP spec", func() {
 		l := map[string]string{"net.beta.kubernetes.io/role": "master"}
 		k8sNode := &k8sapi.Node{
@@ -21,6 +21,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	kwatch "k8s.io/apimachinery/pkg/watch"
 
 	"github.com/projectcalico/libcalico-go/lib/backend/api"
@@ -167,6 +168,19 @@ func (crw *k8sWatcherConverter) convertEvent(kevent kwatch.Event) []*api.WatchEv
 
 		return crw.buildEventsFromKVPs(kvps, kevent.Type)
 
+	case kwatch.Bookmark:
+		// A bookmark contains an object of the watched type with only the resource version
+		// set.  The resource version is passed on so that the watch may be resumed from it.
+		m, err := meta.Accessor(kevent.Object)
+		if err != nil {
+			crw.logCxt.WithError(err).Debug("Ignoring bookmark without object metadata")
+			return nil
+		}
+		return []*api.WatchEvent{{
+			Type: api.WatchBookmark,
+			New:  &model.KVPair{Revision: m.GetResourceVersion()},
+		}}
+
 	default:
 		return []*api.WatchEvent{{
 			Type:  api.WatchError,
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+// Conformance tests for the paged list support of each backend.
+var _ = testutils.E2eDatastoreDescribe("Backend paged list tests", testutils.DatastoreAll, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	list := model.ResourceListOptions{Kind: apiv3.KindIPPool}
+	const numPools = 5
+
+	var c bapi.Client
+
+	BeforeEach(func() {
+		var err error
+		c, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		c.Clean()
+
+		for i := 0; i < numPools; i++ {
+			name := fmt.Sprintf("ippool-%d", i)
+			_, err = c.Create(ctx, &model.KVPair{
+				Key: model.ResourceKey{Kind: apiv3.KindIPPool, Name: name},
+				Value: &apiv3.IPPool{
+					TypeMeta:   metav1.TypeMeta{Kind: apiv3.KindIPPool, APIVersion: apiv3.GroupVersionCurrent},
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec:       apiv3.IPPoolSpec{CIDR: fmt.Sprintf("10.%d.0.0/16", i)},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	AfterEach(func() {
+		c.Clean()
+	})
+
+	// listAll lists all of the IP pools a page at a time, checking that each page is within the
+	// page size and that every page has the same revision.
+	listAll := func(pageSize int64) ([]string, int) {
+		var names []string
+		var token, revision string
+		pages := 0
+		for {
+			l, next, err := bapi.ListPaged(ctx, c, list, pageSize, token)
+			Expect(err).NotTo(HaveOccurred())
+			pages++
+			if pageSize > 0 {
+				Expect(len(l.KVPairs)).To(BeNumerically("<=", pageSize))
+			}
+			if revision == "" {
+				revision = l.Revision
+			}
+			Expect(l.Revision).To(Equal(revision))
+			for _, kvp := range l.KVPairs {
+				names = append(names, kvp.Key.(model.ResourceKey).Name)
+			}
+			if next == "" {
+				return names, pages
+			}
+			token = next
+		}
+	}
+
+	It("should support paged lists", func() {
+		_, ok := c.(bapi.PagedLister)
+		Expect(ok).To(BeTrue())
+	})
+
+	It("should list every entry exactly once across the pages", func() {
+		names, pages := listAll(2)
+		Expect(names).To(ConsistOf("ippool-0", "ippool-1", "ippool-2", "ippool-3", "ippool-4"))
+		Expect(pages).To(Equal(3))
+	})
+
+	It("should return the full list in a single page if the page size is not set", func() {
+		names, pages := listAll(0)
+		Expect(names).To(HaveLen(numPools))
+		Expect(pages).To(Equal(1))
+	})
+
+	It("should list the pages at the revision of the first page", func() {
+		first, token, err := bapi.ListPaged(ctx, c, list, 2, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).NotTo(BeEmpty())
+
+		_, err = c.Delete(ctx, model.ResourceKey{Kind: apiv3.KindIPPool, Name: "ippool-4"}, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		names := []string{}
+		for _, kvp := range first.KVPairs {
+			names = append(names, kvp.Key.(model.ResourceKey).Name)
+		}
+		for token != "" {
+			var l *model.KVPairList
+			l, token, err = bapi.ListPaged(ctx, c, list, 2, token)
+			Expect(err).NotTo(HaveOccurred())
+			for _, kvp := range l.KVPairs {
+				names = append(names, kvp.Key.(model.ResourceKey).Name)
+			}
+		}
+		Expect(names).To(ContainElement("ippool-4"))
+	})
+})
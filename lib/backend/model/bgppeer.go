@@ -204,6 +204,15 @@ type BGPPeer struct {
 	// converts large uints to float e notation which breaks the BIRD
 	// configuration.
 	ASNum numorstring.ASNumber `json:"as_num,string"`
+
+	// Password is the BGP password for the peering, if one is configured.  This is a pointer
+	// so that the password is not included when the value is logged.
+	Password *string `json:"password,omitempty"`
+
+	// KeepaliveTime and HoldTime are the BGP timers of the peering in seconds, or nil if the
+	// BGP daemon default is used.  A HoldTime of 0 means that keepalives are not used.
+	KeepaliveTime *uint16 `json:"keepalive_time,omitempty"`
+	HoldTime      *uint16 `json:"hold_time,omitempty"`
 }
 
 func extractIPAndPort(ipPort string) ([]byte, uint16) {
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "reflect"
+
+// DiffKVPairs compares two sets of KVPairs, matching the KVPairs by the default path of their
+// keys, and returns the KVPairs that were added, updated and deleted in the new set.  A KVPair
+// is updated if its value differs from the value of the old KVPair with the same key; a change
+// to the revision alone is not treated as an update.  The added and updated KVPairs are taken
+// from the new set, in the order of that set, and the deleted KVPairs are taken from the old set,
+// in the order of that set.
+func DiffKVPairs(old, new []*KVPair) (added, updated, deleted []*KVPair) {
+	oldByPath := make(map[string]*KVPair, len(old))
+	for _, kvp := range old {
+		oldByPath[kvPairPath(kvp)] = kvp
+	}
+	newPaths := make(map[string]bool, len(new))
+	for _, kvp := range new {
+		path := kvPairPath(kvp)
+		newPaths[path] = true
+		o, ok := oldByPath[path]
+		switch {
+		case !ok:
+			added = append(added, kvp)
+		case !valuesEqual(o.Value, kvp.Value):
+			updated = append(updated, kvp)
+		}
+	}
+	for _, kvp := range old {
+		if !newPaths[kvPairPath(kvp)] {
+			deleted = append(deleted, kvp)
+		}
+	}
+	return
+}
+
+// valuesEqual returns true if the KVPair values are equal.  Values that define their own notion
+// of equality are compared using it, otherwise the values are compared deeply.
+func valuesEqual(a, b interface{}) bool {
+	if wa, ok := a.(*Wireguard); ok {
+		if wb, ok := b.(*Wireguard); ok {
+			return wa.Equal(wb)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// kvPairPath returns the default path of the KVPair key, falling back to the string form of
+// the key if it does not have a default path.
+func kvPairPath(kvp *KVPair) string {
+	if path, err := KeyToDefaultPath(kvp.Key); err == nil {
+		return path
+	}
+	return kvp.Key.String()
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("DiffKVPairs", func() {
+	kvp := func(name string, value interface{}, revision string) *KVPair {
+		return &KVPair{Key: GlobalConfigKey{Name: name}, Value: value, Revision: revision}
+	}
+
+	It("should return the added, updated and deleted KVPairs", func() {
+		unchanged := kvp("unchanged", "a", "1")
+		revisionOnly := kvp("revision-only", map[string]string{"a": "b"}, "2")
+		modified := kvp("modified", "a", "3")
+		deleted := kvp("deleted", "a", "4")
+
+		newRevisionOnly := kvp("revision-only", map[string]string{"a": "b"}, "5")
+		newModified := kvp("modified", "b", "6")
+		added := kvp("added", "a", "7")
+
+		a, u, d := DiffKVPairs(
+			[]*KVPair{unchanged, revisionOnly, modified, deleted},
+			[]*KVPair{added, newModified, newRevisionOnly, unchanged},
+		)
+		Expect(a).To(Equal([]*KVPair{added}))
+		Expect(u).To(Equal([]*KVPair{newModified}))
+		Expect(d).To(Equal([]*KVPair{deleted}))
+	})
+
+	It("should treat every KVPair as added or deleted when the other set is empty", func() {
+		kvps := []*KVPair{kvp("one", "a", "1"), kvp("two", "b", "2")}
+
+		a, u, d := DiffKVPairs(nil, kvps)
+		Expect(a).To(Equal(kvps))
+		Expect(u).To(BeEmpty())
+		Expect(d).To(BeEmpty())
+
+		a, u, d = DiffKVPairs(kvps, nil)
+		Expect(a).To(BeEmpty())
+		Expect(u).To(BeEmpty())
+		Expect(d).To(Equal(kvps))
+	})
+
+	It("should compare Wireguard values using their own equality", func() {
+		ip := net.ParseIP("192.168.0.1")
+		old := &KVPair{Key: WireguardKey{NodeName: "node1"}, Value: &Wireguard{InterfaceIPv4Addr: ip}, Revision: "1"}
+		same := &KVPair{Key: WireguardKey{NodeName: "node1"}, Value: &Wireguard{InterfaceIPv4Addr: &net.IP{IP: ip.To16()}}, Revision: "2"}
+
+		a, u, d := DiffKVPairs([]*KVPair{old}, []*KVPair{same})
+		Expect(a).To(BeEmpty())
+		Expect(u).To(BeEmpty())
+		Expect(d).To(BeEmpty())
+
+		changed := &KVPair{Key: WireguardKey{NodeName: "node1"}, Value: &Wireguard{InterfaceIPv4Addr: ip, Port: 51821}, Revision: "3"}
+		_, u, _ = DiffKVPairs([]*KVPair{old}, []*KVPair{changed})
+		Expect(u).To(Equal([]*KVPair{changed}))
+	})
+})
@@ -0,0 +1,57 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// FormatKVPair returns a concise, human readable rendering of a KVPair for use in debug logs.
+// The key is rendered as its default path (or its string form if it has no default path) and
+// the value as a summary: strings are quoted, values that implement fmt.Stringer use their
+// String method, and other values are rendered with their type and fields.  A nil value, which
+// indicates a deletion, is rendered as "<delete>".
+func FormatKVPair(kvp *KVPair) string {
+	if kvp == nil {
+		return "<nil>"
+	}
+	s := fmt.Sprintf("%s = %s", kvPairPath(kvp), formatValue(kvp.Value))
+	if kvp.Revision != "" {
+		s += fmt.Sprintf(" (rev=%s)", kvp.Revision)
+	}
+	return s
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "<delete>"
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "<delete>"
+	}
+	switch t := v.(type) {
+	case string:
+		return strconv.Quote(t)
+	case fmt.Stringer:
+		return t.String()
+	}
+	if rv.Kind() == reflect.Ptr {
+		v = rv.Elem().Interface()
+	}
+	return fmt.Sprintf("%T%+v", v, v)
+}
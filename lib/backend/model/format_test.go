@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("FormatKVPair", func() {
+	It("should render a HostIPKey with the IP", func() {
+		ip := net.MustParseIP("10.0.0.1")
+		kvp := &KVPair{Key: HostIPKey{Hostname: "node1"}, Value: &ip, Revision: "1234"}
+		Expect(FormatKVPair(kvp)).To(Equal("/calico/v1/host/node1/bird_ip = 10.0.0.1 (rev=1234)"))
+	})
+
+	It("should render a HostConfigKey with a quoted value", func() {
+		kvp := &KVPair{Key: HostConfigKey{Hostname: "node1", Name: "IpInIpTunnelAddr"}, Value: "192.168.0.1"}
+		Expect(FormatKVPair(kvp)).To(Equal(`/calico/v1/host/node1/config/IpInIpTunnelAddr = "192.168.0.1"`))
+	})
+
+	It("should render a delete", func() {
+		kvp := &KVPair{Key: HostConfigKey{Hostname: "node1", Name: "IpInIpTunnelAddr"}, Revision: "1234"}
+		Expect(FormatKVPair(kvp)).To(Equal("/calico/v1/host/node1/config/IpInIpTunnelAddr = <delete> (rev=1234)"))
+	})
+
+	It("should render a typed nil value as a delete", func() {
+		var ip *net.IP
+		kvp := &KVPair{Key: HostIPKey{Hostname: "node1"}, Value: ip}
+		Expect(FormatKVPair(kvp)).To(Equal("/calico/v1/host/node1/bird_ip = <delete>"))
+	})
+
+	It("should render other values with their type and fields", func() {
+		kvp := &KVPair{Key: GlobalConfigKey{Name: "foo"}, Value: &HostEndpointStatus{Status: "up"}}
+		Expect(FormatKVPair(kvp)).To(Equal("/calico/v1/config/foo = model.HostEndpointStatus{Status:up}"))
+	})
+})
@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+)
+
+// HostnameFromKey returns the hostname of a host-scoped key: the name of a v3 Node ResourceKey,
+// or the hostname of one of the v1 keys for a host (such as a HostIPKey, HostConfigKey or
+// WireguardKey).  It returns false if the key is not scoped to a host.
+func HostnameFromKey(k Key) (string, bool) {
+	switch key := k.(type) {
+	case ResourceKey:
+		if key.Kind == apiv3.KindNode {
+			return key.Name, true
+		}
+	case NodeKey:
+		return key.Hostname, true
+	case HostIPKey:
+		return key.Hostname, true
+	case HostMetadataKey:
+		return key.Hostname, true
+	case HostConfigKey:
+		return key.Hostname, true
+	case OrchRefKey:
+		return key.Hostname, true
+	case WireguardKey:
+		return key.NodeName, true
+	case HostEndpointKey:
+		return key.Hostname, true
+	case WorkloadEndpointKey:
+		return key.Hostname, true
+	case BGPNodeKey:
+		return key.Host, true
+	case NodeBGPConfigKey:
+		return key.Nodename, true
+	case NodeBGPPeerKey:
+		return key.Nodename, true
+	case BlockAffinityKey:
+		return key.Host, true
+	case IPAMHostKey:
+		return key.Host, true
+	}
+	return "", false
+}
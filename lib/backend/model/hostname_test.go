@@ -0,0 +1,40 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = DescribeTable("HostnameFromKey",
+	func(key Key, expectedHostname string, expectedOK bool) {
+		hostname, ok := HostnameFromKey(key)
+		Expect(ok).To(Equal(expectedOK))
+		Expect(hostname).To(Equal(expectedHostname))
+	},
+	Entry("Node ResourceKey", ResourceKey{Kind: apiv3.KindNode, Name: "node1"}, "node1", true),
+	Entry("HostIPKey", HostIPKey{Hostname: "node1"}, "node1", true),
+	Entry("HostConfigKey", HostConfigKey{Hostname: "node1", Name: "IpInIpTunnelAddr"}, "node1", true),
+	Entry("WireguardKey", WireguardKey{NodeName: "node1"}, "node1", true),
+	Entry("BlockAffinityKey", BlockAffinityKey{Host: "node1", CIDR: net.MustParseCIDR("10.0.0.0/26")}, "node1", true),
+	Entry("ResourceKey of another kind", ResourceKey{Kind: apiv3.KindIPPool, Name: "pool1"}, "", false),
+	Entry("GlobalConfigKey", GlobalConfigKey{Name: "LogSeverityScreen"}, "", false),
+	Entry("nil key", nil, "", false),
+)
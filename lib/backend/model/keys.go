@@ -217,6 +217,11 @@ func KeyFromDefaultPath(path string) Key {
 		return PolicyKey{
 			Name: unescapeName(m[2]),
 		}
+	} else if m := matchStagedPolicy.FindStringSubmatch(path); m != nil {
+		log.Debugf("Path is a staged policy: %v", path)
+		return StagedPolicyKey{
+			Name: unescapeName(m[2]),
+		}
 	} else if m := matchProfile.FindStringSubmatch(path); m != nil {
 		log.Debugf("Path is a profile: %v (%v)", path, m[2])
 		pk := ProfileKey{unescapeName(m[1])}
@@ -297,6 +302,13 @@ func KeyFromDefaultPath(path string) Key {
 // PolicyKey as the first parameter, it will try to parse rawData into a
 // Policy struct.
 func ParseValue(key Key, rawData []byte) (interface{}, error) {
+	if rk, ok := key.(ResourceKey); ok {
+		// Resources are unmarshalled by the function registered for the kind, which allows
+		// custom kinds to be deserialized.
+		if unmarshal, ok := ResourceValueUnmarshaller(rk.Kind); ok {
+			return unmarshal(rawData)
+		}
+	}
 	valueType, err := key.valueType()
 	if err != nil {
 		return nil, err
@@ -371,3 +383,42 @@ func SerializeValue(d *KVPair) ([]byte, error) {
 	}
 	return json.Marshal(d.Value)
 }
+
+// ValueEqualer is implemented by model values that define their own value equality, for values
+// that may be equal even though their canonical serializations differ (for example, because the
+// order of a list is not significant).
+type ValueEqualer interface {
+	// EqualValue returns true if the value is equal to the other value, which may be of any type.
+	EqualValue(other interface{}) bool
+}
+
+// SerializeValueCanonical serializes a value in the model to a canonical []byte form, in which
+// JSON object keys are sorted and insignificant whitespace is removed.  A value serializes to
+// identical bytes regardless of how it was constructed or which backend it was read from, so the
+// result may be used for a byte-level comparison of values.  Values that are semantically equal
+// but serialize differently, such as lists in a different order, are not identified as equal; see
+// ValueEqualer.
+func SerializeValueCanonical(d *KVPair) ([]byte, error) {
+	valueType, err := d.Key.valueType()
+	if err != nil {
+		return nil, err
+	}
+	if d.Value != nil && (valueType == rawStringType || valueType == rawBoolType || valueType == rawIPType) {
+		// Raw values are not JSON and are already in their canonical form.
+		return []byte(fmt.Sprint(d.Value)), nil
+	}
+	data, err := json.Marshal(d.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round trip through a generic representation.  The encoder sorts the keys of maps, which
+	// re-orders any objects produced by custom marshalers.  Numbers are preserved verbatim.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
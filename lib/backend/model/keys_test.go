@@ -15,6 +15,8 @@
 package model_test
 
 import (
+	"encoding/json"
+
 	. "github.com/projectcalico/libcalico-go/lib/backend/model"
 
 	. "github.com/onsi/ginkgo"
@@ -152,6 +154,12 @@ var _ = DescribeTable(
 		PolicyKey{Name: "biff/bop"},
 		false,
 	),
+	Entry(
+		"staged policy with a /",
+		"/calico/v1/policy/staged/tier/default/policy/biff%2fbop",
+		StagedPolicyKey{Name: "biff/bop"},
+		false,
+	),
 	Entry(
 		"workload with a /",
 		"/calico/v1/host/foobar/workload/open%2fstack/work%2fload/endpoint/end%2fpoint",
@@ -288,6 +296,46 @@ var _ = DescribeTable(
 	),
 )
 
+var _ = DescribeTable(
+	"SerializeValueCanonical",
+	func(key Key, value1, value2 interface{}, expected string) {
+		serialized1, err := SerializeValueCanonical(&KVPair{Key: key, Value: value1})
+		Expect(err).NotTo(HaveOccurred())
+		serialized2, err := SerializeValueCanonical(&KVPair{Key: key, Value: value2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(serialized1)).To(Equal(expected))
+		Expect(string(serialized2)).To(Equal(expected))
+	},
+	Entry(
+		"objects with reordered keys",
+		ResourceKey{Kind: "NetworkPolicy", Namespace: "ns1", Name: "policy1"},
+		json.RawMessage(`{"spec": {"selector": "all()", "order": 10}, "metadata": {"name": "policy1"}}`),
+		json.RawMessage(`{"metadata":{"name":"policy1"},"spec":{"order":10,"selector":"all()"}}`),
+		`{"metadata":{"name":"policy1"},"spec":{"order":10,"selector":"all()"}}`,
+	),
+	Entry(
+		"maps populated in a different order",
+		ProfileLabelsKey{ProfileKey: ProfileKey{Name: "prof1"}},
+		map[string]string{"a": "1", "b": "2", "c": "3"},
+		map[string]string{"c": "3", "b": "2", "a": "1"},
+		`{"a":"1","b":"2","c":"3"}`,
+	),
+	Entry(
+		"numbers are preserved",
+		ResourceKey{Kind: "NetworkPolicy", Namespace: "ns1", Name: "policy1"},
+		json.RawMessage(`{"b": 1.50, "a": 12345678901234567890}`),
+		json.RawMessage(`{"a":12345678901234567890,"b":1.50}`),
+		`{"a":12345678901234567890,"b":1.50}`,
+	),
+	Entry(
+		"raw string values",
+		HostConfigKey{Hostname: "host1", Name: "LogSeverityScreen"},
+		"Debug",
+		"Debug",
+		`Debug`,
+	),
+)
+
 func mustParseCIDR(s string) net.IPNet {
 	_, ipNet, err := net.ParseCIDR(s)
 	if err != nil {
@@ -61,8 +61,65 @@ type OrchRef struct {
 }
 
 type Wireguard struct {
-	InterfaceIPv4Addr *net.IP `json:"interfaceIPv4Addr,omitempty"`
-	PublicKey         string  `json:"publicKey,omitempty"`
+	InterfaceIPv4Addr *net.IP             `json:"interfaceIPv4Addr,omitempty"`
+	InterfaceIPv6Addr *net.IP             `json:"interfaceIPv6Addr,omitempty"`
+	PublicKey         *WireguardPublicKey `json:"publicKey,omitempty"`
+	PublicKeyV6       *WireguardPublicKey `json:"publicKeyV6,omitempty"`
+
+	// Port is the Wireguard listening port of the node.  Zero indicates the port configured
+	// in the FelixConfiguration.
+	Port int `json:"port,omitempty"`
+
+	// AllowedIPs is the set of CIDRs advertised by the node that should be routed to the node
+	// over Wireguard.  This is derived from the node pod CIDRs and tunnel addresses.
+	AllowedIPs []net.IPNet `json:"allowedIPs,omitempty"`
+}
+
+// Equal returns true if the Wireguard configuration is the same as the other configuration.
+// Addresses are compared by value, so the 4 and 16 byte forms of an IPv4 address are equal,
+// and the order of the AllowedIPs is not significant.  Either configuration may be nil.
+func (w *Wireguard) Equal(other *Wireguard) bool {
+	if w == nil || other == nil {
+		return w == other
+	}
+	if !wireguardPublicKeysEqual(w.PublicKey, other.PublicKey) ||
+		!wireguardPublicKeysEqual(w.PublicKeyV6, other.PublicKeyV6) ||
+		w.Port != other.Port {
+		return false
+	}
+	if !ipsEqual(w.InterfaceIPv4Addr, other.InterfaceIPv4Addr) || !ipsEqual(w.InterfaceIPv6Addr, other.InterfaceIPv6Addr) {
+		return false
+	}
+	if len(w.AllowedIPs) != len(other.AllowedIPs) {
+		return false
+	}
+	allowed := map[string]int{}
+	for i := range w.AllowedIPs {
+		allowed[w.AllowedIPs[i].String()]++
+	}
+	for i := range other.AllowedIPs {
+		s := other.AllowedIPs[i].String()
+		if allowed[s] == 0 {
+			return false
+		}
+		allowed[s]--
+	}
+	return true
+}
+
+// EqualValue implements the ValueEqualer interface, so that the syncer does not send updates for
+// Wireguard configuration that has not changed.
+func (w *Wireguard) EqualValue(other interface{}) bool {
+	o, ok := other.(*Wireguard)
+	return ok && w.Equal(o)
+}
+
+// ipsEqual returns true if the IP addresses are equal, or are both nil.
+func ipsEqual(a, b *net.IP) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b.IP)
 }
 
 type NodeKey struct {
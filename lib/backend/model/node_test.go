@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("Wireguard", func() {
+	wireguard := func() *Wireguard {
+		return &Wireguard{
+			InterfaceIPv4Addr: net.ParseIP("192.168.0.1"),
+			InterfaceIPv6Addr: net.ParseIP("fd00::1"),
+			PublicKey:         MustParseWireguardPublicKey("jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="),
+			PublicKeyV6:       MustParseWireguardPublicKey("hTnWXE5mh5Rz9jpMBVN6KHOKyDPkavTnDfNm6wCyU14="),
+			Port:              51820,
+			AllowedIPs:        []net.IPNet{net.MustParseCIDR("10.0.0.0/26"), net.MustParseCIDR("fd10::/122")},
+		}
+	}
+
+	DescribeTable("Equal",
+		func(modify func(w *Wireguard), expected bool) {
+			w := wireguard()
+			other := wireguard()
+			modify(other)
+			Expect(w.Equal(other)).To(Equal(expected))
+			Expect(other.Equal(w)).To(Equal(expected))
+		},
+		Entry("identical", func(w *Wireguard) {}, true),
+		Entry("IPv4 address in 16 byte form", func(w *Wireguard) {
+			w.InterfaceIPv4Addr = &net.IP{IP: w.InterfaceIPv4Addr.To16()}
+		}, true),
+		Entry("different IPv4 interface address", func(w *Wireguard) {
+			w.InterfaceIPv4Addr = net.ParseIP("192.168.0.2")
+		}, false),
+		Entry("missing IPv4 interface address", func(w *Wireguard) { w.InterfaceIPv4Addr = nil }, false),
+		Entry("different IPv6 interface address", func(w *Wireguard) {
+			w.InterfaceIPv6Addr = net.ParseIP("fd00::2")
+		}, false),
+		Entry("missing IPv6 interface address", func(w *Wireguard) { w.InterfaceIPv6Addr = nil }, false),
+		Entry("different IPv4 public key", func(w *Wireguard) { w.PublicKey = w.PublicKeyV6 }, false),
+		Entry("missing IPv4 public key", func(w *Wireguard) { w.PublicKey = nil }, false),
+		Entry("different IPv6 public key", func(w *Wireguard) { w.PublicKeyV6 = w.PublicKey }, false),
+		Entry("missing IPv6 public key", func(w *Wireguard) { w.PublicKeyV6 = nil }, false),
+		Entry("equal public key in a different instance", func(w *Wireguard) {
+			w.PublicKey = MustParseWireguardPublicKey(w.PublicKey.String())
+		}, true),
+		Entry("different port", func(w *Wireguard) { w.Port = 51821 }, false),
+		Entry("different allowed IPs", func(w *Wireguard) {
+			w.AllowedIPs[1] = net.MustParseCIDR("fd10::40/122")
+		}, false),
+		Entry("reordered allowed IPs", func(w *Wireguard) {
+			w.AllowedIPs[0], w.AllowedIPs[1] = w.AllowedIPs[1], w.AllowedIPs[0]
+		}, true),
+		Entry("duplicated allowed IPs", func(w *Wireguard) {
+			w.AllowedIPs[1] = w.AllowedIPs[0]
+		}, false),
+		Entry("additional allowed IPs", func(w *Wireguard) {
+			w.AllowedIPs = append(w.AllowedIPs, net.MustParseCIDR("10.0.1.0/26"))
+		}, false),
+	)
+
+	It("should compare values using Equal", func() {
+		other := wireguard()
+		other.AllowedIPs[0], other.AllowedIPs[1] = other.AllowedIPs[1], other.AllowedIPs[0]
+		Expect(wireguard().EqualValue(other)).To(BeTrue())
+		other.Port = 51821
+		Expect(wireguard().EqualValue(other)).To(BeFalse())
+		Expect(wireguard().EqualValue(&Node{})).To(BeFalse())
+		Expect(wireguard().EqualValue(nil)).To(BeFalse())
+	})
+
+	It("should handle nil configurations", func() {
+		var nilWireguard *Wireguard
+		Expect(nilWireguard.Equal(nil)).To(BeTrue())
+		Expect(nilWireguard.Equal(wireguard())).To(BeFalse())
+		Expect(wireguard().Equal(nil)).To(BeFalse())
+	})
+})
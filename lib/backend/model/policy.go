@@ -100,6 +100,11 @@ type Policy struct {
 	PreDNAT        bool              `json:"pre_dnat,omitempty"`
 	ApplyOnForward bool              `json:"apply_on_forward,omitempty"`
 	Types          []string          `json:"types,omitempty"`
+
+	// StagedAction is only set for the value of a StagedPolicyKey.  It is the change that the
+	// staged policy would make to the enforced policy of the same name: "Set" or "Delete".  A
+	// staged deletion has no rules.
+	StagedAction string `json:"staged_action,omitempty"`
 }
 
 func (p Policy) String() string {
@@ -122,5 +127,8 @@ func (p Policy) String() string {
 	parts = append(parts, fmt.Sprintf("pre_dnat:%v", p.PreDNAT))
 	parts = append(parts, fmt.Sprintf("apply_on_forward:%v", p.ApplyOnForward))
 	parts = append(parts, fmt.Sprintf("types:%v", strings.Join(p.Types, ";")))
+	if p.StagedAction != "" {
+		parts = append(parts, fmt.Sprintf("staged_action:%v", p.StagedAction))
+	}
 	return strings.Join(parts, ",")
 }
@@ -23,14 +23,16 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	"github.com/projectcalico/libcalico-go/lib/namespace"
 )
 
 // Name/type information about a single resource.
 type resourceInfo struct {
-	typeOf reflect.Type
-	plural string
-	kind   string
+	typeOf    reflect.Type
+	plural    string
+	kind      string
+	unmarshal ValueUnmarshalFunc
 }
 
 var (
@@ -38,15 +40,23 @@ var (
 	matchNamespacedResource = regexp.MustCompile("^/calico/resources/v3/projectcalico[.]org/([^/]+)/([^/]+)/([^/]+)$")
 	resourceInfoByKind      = make(map[string]resourceInfo)
 	resourceInfoByPlural    = make(map[string]resourceInfo)
+	knownKinds              = make(map[string]bool)
 )
 
-func registerResourceInfo(kind string, plural string, typeOf reflect.Type) {
+// registerResourceInfo registers a resource kind.  If the unmarshal function is nil, values are
+// unmarshalled from JSON into the supplied type.
+func registerResourceInfo(kind string, plural string, typeOf reflect.Type, unmarshal ValueUnmarshalFunc) {
+	knownKinds[kind] = true
 	kind = strings.ToLower(kind)
 	plural = strings.ToLower(plural)
+	if unmarshal == nil {
+		unmarshal = jsonValueUnmarshaller(typeOf)
+	}
 	ri := resourceInfo{
-		typeOf: typeOf,
-		kind:   kind,
-		plural: plural,
+		typeOf:    typeOf,
+		kind:      kind,
+		plural:    plural,
+		unmarshal: unmarshal,
 	}
 	resourceInfoByKind[kind] = ri
 	resourceInfoByPlural[plural] = ri
@@ -57,76 +67,104 @@ func init() {
 		apiv3.KindBGPPeer,
 		"bgppeers",
 		reflect.TypeOf(apiv3.BGPPeer{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindBGPConfiguration,
 		"bgpconfigurations",
 		reflect.TypeOf(apiv3.BGPConfiguration{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindClusterInformation,
 		"clusterinformations",
 		reflect.TypeOf(apiv3.ClusterInformation{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindFelixConfiguration,
 		"felixconfigurations",
 		reflect.TypeOf(apiv3.FelixConfiguration{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindGlobalNetworkPolicy,
 		"globalnetworkpolicies",
 		reflect.TypeOf(apiv3.GlobalNetworkPolicy{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindHostEndpoint,
 		"hostendpoints",
 		reflect.TypeOf(apiv3.HostEndpoint{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindGlobalNetworkSet,
 		"globalnetworksets",
 		reflect.TypeOf(apiv3.GlobalNetworkSet{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindIPPool,
 		"ippools",
 		reflect.TypeOf(apiv3.IPPool{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindNetworkPolicy,
 		"networkpolicies",
 		reflect.TypeOf(apiv3.NetworkPolicy{}),
+		nil,
+	)
+	registerResourceInfo(
+		apiv3.KindStagedGlobalNetworkPolicy,
+		"stagedglobalnetworkpolicies",
+		reflect.TypeOf(apiv3.StagedGlobalNetworkPolicy{}),
+		nil,
+	)
+	registerResourceInfo(
+		apiv3.KindStagedNetworkPolicy,
+		"stagednetworkpolicies",
+		reflect.TypeOf(apiv3.StagedNetworkPolicy{}),
+		nil,
 	)
 	registerResourceInfo(
 		KindKubernetesNetworkPolicy,
 		"kubernetesnetworkpolicies",
 		reflect.TypeOf(apiv3.NetworkPolicy{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindNetworkSet,
 		"networksets",
 		reflect.TypeOf(apiv3.NetworkSet{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindNode,
 		"nodes",
 		reflect.TypeOf(apiv3.Node{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindProfile,
 		"profiles",
 		reflect.TypeOf(apiv3.Profile{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindWorkloadEndpoint,
 		"workloadendpoints",
 		reflect.TypeOf(apiv3.WorkloadEndpoint{}),
+		nil,
 	)
 	registerResourceInfo(
 		apiv3.KindKubeControllersConfiguration,
 		"kubecontrollersconfigurations",
-		reflect.TypeOf(apiv3.KubeControllersConfiguration{}))
+		reflect.TypeOf(apiv3.KubeControllersConfiguration{}),
+		nil,
+	)
 }
 
 type ResourceKey struct {
@@ -138,6 +176,36 @@ type ResourceKey struct {
 	Kind string
 }
 
+// NewResourceKey returns a ResourceKey for the resource, validating that the kind is one of the
+// registered resource kinds (the match is case sensitive) and that a namespace is supplied if
+// and only if the kind is namespaced.  A ResourceKey may still be constructed directly, but this
+// function should be preferred where the kind is not a constant.
+func NewResourceKey(kind, ns, name string) (ResourceKey, error) {
+	var fields []cerrors.ErroredField
+	if !knownKinds[kind] {
+		reason := "unknown resource kind"
+		if ri, ok := resourceInfoByKind[strings.ToLower(kind)]; ok {
+			for k := range knownKinds {
+				if strings.ToLower(k) == ri.kind {
+					reason = fmt.Sprintf("unknown resource kind, did you mean %s?", k)
+				}
+			}
+		}
+		fields = append(fields, cerrors.ErroredField{Name: "Kind", Value: kind, Reason: reason})
+	} else if namespace.IsNamespaced(kind) && ns == "" {
+		fields = append(fields, cerrors.ErroredField{Name: "Namespace", Reason: "namespace must be specified for kind " + kind})
+	} else if !namespace.IsNamespaced(kind) && ns != "" {
+		fields = append(fields, cerrors.ErroredField{Name: "Namespace", Value: ns, Reason: "kind " + kind + " is not namespaced"})
+	}
+	if name == "" {
+		fields = append(fields, cerrors.ErroredField{Name: "Name", Reason: "name must be specified"})
+	}
+	if len(fields) > 0 {
+		return ResourceKey{}, cerrors.ErrorValidation{ErroredFields: fields}
+	}
+	return ResourceKey{Kind: kind, Namespace: ns, Name: name}, nil
+}
+
 func (key ResourceKey) defaultPath() (string, error) {
 	return key.defaultDeletePath()
 }
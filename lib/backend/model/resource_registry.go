@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ValueUnmarshalFunc unmarshals the serialized value of a resource into the value stored in a
+// KVPair, which is typically a pointer to the resource struct.
+type ValueUnmarshalFunc func(data []byte) (interface{}, error)
+
+// anyValueType is the value type of a resource kind registered with RegisterResourceKind, for
+// which the type of the value is determined by the unmarshal function.
+var anyValueType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// RegisterResourceKind registers a custom resource kind, so that ResourceKeys of the kind may be
+// constructed with NewResourceKey and their values deserialized (see ParseValue) by the backends
+// and the syncers.  The plural is used in the default datastore path of the resource, and the
+// unmarshal function converts the serialized resource into its value.  Values are serialized as
+// JSON.  Custom kinds are not namespaced.
+//
+// The built-in kinds are registered when the package is initialized.  An error is returned if the
+// kind or plural is empty or is already registered (the comparison is not case sensitive), or if
+// the unmarshal function is nil.  Kinds should be registered during initialization, since the
+// registry is not safe for use concurrently with registration.
+func RegisterResourceKind(kind, plural string, unmarshal ValueUnmarshalFunc) error {
+	if kind == "" || plural == "" {
+		return fmt.Errorf("kind and plural must be specified to register a resource kind")
+	}
+	if unmarshal == nil {
+		return fmt.Errorf("an unmarshal function must be specified to register resource kind %s", kind)
+	}
+	if _, ok := resourceInfoByKind[strings.ToLower(kind)]; ok {
+		return fmt.Errorf("resource kind %s is already registered", kind)
+	}
+	if _, ok := resourceInfoByPlural[strings.ToLower(plural)]; ok {
+		return fmt.Errorf("resource plural %s is already registered", plural)
+	}
+	registerResourceInfo(kind, plural, anyValueType, unmarshal)
+	return nil
+}
+
+// ResourceValueUnmarshaller returns the function used to unmarshal the values of the resource
+// kind, and whether the kind is registered.  The kind is not case sensitive.
+func ResourceValueUnmarshaller(kind string) (ValueUnmarshalFunc, bool) {
+	ri, ok := resourceInfoByKind[strings.ToLower(kind)]
+	if !ok {
+		return nil, false
+	}
+	return ri.unmarshal, true
+}
+
+// jsonValueUnmarshaller returns a ValueUnmarshalFunc that unmarshals JSON into a new value of the
+// supplied struct type, returning a pointer to the value.
+func jsonValueUnmarshaller(typeOf reflect.Type) ValueUnmarshalFunc {
+	return func(data []byte) (interface{}, error) {
+		value := reflect.New(typeOf).Interface()
+		if err := json.Unmarshal(data, value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
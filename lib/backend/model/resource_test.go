@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+var _ = Describe("NewResourceKey", func() {
+	DescribeTable("should construct keys for known kinds",
+		func(kind, ns, name string) {
+			key, err := NewResourceKey(kind, ns, name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(Equal(ResourceKey{Kind: kind, Namespace: ns, Name: name}))
+		},
+		Entry("a global kind", apiv3.KindNode, "", "node1"),
+		Entry("a namespaced kind", apiv3.KindNetworkPolicy, "ns1", "policy1"),
+		Entry("the Kubernetes NetworkPolicy kind", KindKubernetesNetworkPolicy, "ns1", "knp.default.policy1"),
+	)
+
+	DescribeTable("should reject invalid keys",
+		func(kind, ns, name, field, reason string) {
+			_, err := NewResourceKey(kind, ns, name)
+			Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+			fields := err.(cerrors.ErrorValidation).ErroredFields
+			Expect(fields).To(HaveLen(1))
+			Expect(fields[0].Name).To(Equal(field))
+			Expect(fields[0].Reason).To(ContainSubstring(reason))
+		},
+		Entry("a bogus kind", "Nodez", "", "node1", "Kind", "unknown resource kind"),
+		Entry("a kind with the wrong case", "node", "", "node1", "Kind", "did you mean Node?"),
+		Entry("an empty kind", "", "", "node1", "Kind", "unknown resource kind"),
+		Entry("a namespaced kind without a namespace", apiv3.KindNetworkPolicy, "", "policy1", "Namespace", "must be specified"),
+		Entry("a global kind with a namespace", apiv3.KindNode, "ns1", "node1", "Namespace", "is not namespaced"),
+		Entry("an empty name", apiv3.KindNode, "", "", "Name", "must be specified"),
+	)
+})
+
+// customResource is a resource of a custom kind registered with RegisterResourceKind.
+type customResource struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+var _ = Describe("RegisterResourceKind", func() {
+	It("should register a custom kind and round-trip its value", func() {
+		err := RegisterResourceKind("CustomResource", "customresources", func(data []byte) (interface{}, error) {
+			r := &customResource{}
+			if err := json.Unmarshal(data, r); err != nil {
+				return nil, err
+			}
+			return r, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		key, err := NewResourceKey("CustomResource", "", "custom1")
+		Expect(err).NotTo(HaveOccurred())
+		path, err := KeyToDefaultPath(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal("/calico/resources/v3/projectcalico.org/customresources/custom1"))
+
+		data, err := SerializeValue(&KVPair{Key: key, Value: &customResource{Name: "custom1", Value: 10}})
+		Expect(err).NotTo(HaveOccurred())
+		value, err := ParseValue(key, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(&customResource{Name: "custom1", Value: 10}))
+
+		By("rejecting a duplicate registration")
+		Expect(RegisterResourceKind("customresource", "others", func([]byte) (interface{}, error) { return nil, nil })).To(HaveOccurred())
+		Expect(RegisterResourceKind("Other", "CustomResources", func([]byte) (interface{}, error) { return nil, nil })).To(HaveOccurred())
+	})
+
+	It("should reject an incomplete registration", func() {
+		Expect(RegisterResourceKind("", "empties", func([]byte) (interface{}, error) { return nil, nil })).To(HaveOccurred())
+		Expect(RegisterResourceKind("NoUnmarshal", "nounmarshals", nil)).To(HaveOccurred())
+	})
+
+	It("should register the built-in kinds", func() {
+		unmarshal, ok := ResourceValueUnmarshaller(apiv3.KindNode)
+		Expect(ok).To(BeTrue())
+		value, err := unmarshal([]byte(`{"metadata":{"name":"node1"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(BeAssignableToTypeOf(&apiv3.Node{}))
+		Expect(value.(*apiv3.Node).Name).To(Equal("node1"))
+
+		_, ok = ResourceValueUnmarshaller("NotAKind")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+var (
+	matchStagedPolicy = regexp.MustCompile("^/?calico/v1/policy/staged/tier/([^/]+)/policy/([^/]+)$")
+)
+
+// StagedPolicyKey is the key of a staged policy.  A staged policy is evaluated but its verdict
+// is not enforced.  The name is the same as the name of the PolicyKey of the enforced policy
+// that the staged policy would change.  The value is a Policy with StagedAction set.
+type StagedPolicyKey struct {
+	Name string `json:"-" validate:"required,name"`
+}
+
+func (key StagedPolicyKey) defaultPath() (string, error) {
+	if key.Name == "" {
+		return "", errors.ErrorInsufficientIdentifiers{Name: "name"}
+	}
+	e := fmt.Sprintf("/calico/v1/policy/staged/tier/default/policy/%s",
+		escapeName(key.Name))
+	return e, nil
+}
+
+func (key StagedPolicyKey) defaultDeletePath() (string, error) {
+	return key.defaultPath()
+}
+
+func (key StagedPolicyKey) defaultDeleteParentPaths() ([]string, error) {
+	return nil, nil
+}
+
+func (key StagedPolicyKey) valueType() (reflect.Type, error) {
+	return typePolicy, nil
+}
+
+func (key StagedPolicyKey) String() string {
+	return fmt.Sprintf("StagedPolicy(name=%s)", key.Name)
+}
+
+type StagedPolicyListOptions struct {
+	Name string
+}
+
+func (options StagedPolicyListOptions) defaultPathRoot() string {
+	k := "/calico/v1/policy/staged/tier/default/policy"
+	if options.Name == "" {
+		return k
+	}
+	k = k + fmt.Sprintf("/%s", escapeName(options.Name))
+	return k
+}
+
+func (options StagedPolicyListOptions) KeyFromDefaultPath(path string) Key {
+	log.Debugf("Get StagedPolicy key from %s", path)
+	r := matchStagedPolicy.FindAllStringSubmatch(path, -1)
+	if len(r) != 1 {
+		log.Debugf("Didn't match regex")
+		return nil
+	}
+	name := unescapeName(r[0][2])
+	if options.Name != "" && name != options.Name {
+		log.Debugf("Didn't match name %s != %s", options.Name, name)
+		return nil
+	}
+	return StagedPolicyKey{Name: name}
+}
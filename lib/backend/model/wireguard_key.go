@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	wgtypes "golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// WireguardPublicKey is a Wireguard public key.  The key is validated when it is parsed, so a
+// WireguardPublicKey is always a valid key.  It is serialized in the same base64 encoded form
+// that is used for the key in the Node resource.
+type WireguardPublicKey wgtypes.Key
+
+// ParseWireguardPublicKey parses a base64 encoded Wireguard public key.
+func ParseWireguardPublicKey(s string) (*WireguardPublicKey, error) {
+	k, err := wgtypes.ParseKey(s)
+	if err != nil {
+		return nil, err
+	}
+	key := WireguardPublicKey(k)
+	return &key, nil
+}
+
+// MustParseWireguardPublicKey parses a base64 encoded Wireguard public key, and panics if the
+// key is not valid.
+func MustParseWireguardPublicKey(s string) *WireguardPublicKey {
+	k, err := ParseWireguardPublicKey(s)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// String returns the base64 encoded form of the key.
+func (k WireguardPublicKey) String() string {
+	return wgtypes.Key(k).String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (k WireguardPublicKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (k *WireguardPublicKey) UnmarshalText(text []byte) error {
+	parsed, err := wgtypes.ParseKey(string(text))
+	if err != nil {
+		return err
+	}
+	*k = WireguardPublicKey(parsed)
+	return nil
+}
+
+// wireguardPublicKeysEqual returns true if the keys are equal, or are both nil.
+func wireguardPublicKeysEqual(a, b *WireguardPublicKey) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("WireguardPublicKey", func() {
+	const key = "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="
+
+	It("should parse and format a valid key", func() {
+		k, err := ParseWireguardPublicKey(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k.String()).To(Equal(key))
+	})
+
+	DescribeTable("should reject invalid keys",
+		func(s string) {
+			k, err := ParseWireguardPublicKey(s)
+			Expect(err).To(HaveOccurred())
+			Expect(k).To(BeNil())
+			Expect(func() { MustParseWireguardPublicKey(s) }).To(Panic())
+
+			var unmarshalled WireguardPublicKey
+			Expect(json.Unmarshal([]byte(`"`+s+`"`), &unmarshalled)).NotTo(Succeed())
+		},
+		Entry("empty", ""),
+		Entry("not base64", "not-a-valid-key"),
+		Entry("too short", "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvS"),
+	)
+
+	It("should round-trip a Wireguard value using the string form of the keys", func() {
+		wg := &Wireguard{
+			InterfaceIPv4Addr: net.ParseIP("192.168.0.1"),
+			PublicKey:         MustParseWireguardPublicKey(key),
+		}
+		data, err := json.Marshal(wg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"interfaceIPv4Addr":"192.168.0.1","publicKey":"` + key + `"}`))
+
+		var decoded Wireguard
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded.Equal(wg)).To(BeTrue())
+		Expect(decoded.PublicKeyV6).To(BeNil())
+	})
+
+	It("should reject a Wireguard value with an invalid key", func() {
+		var decoded Wireguard
+		Expect(json.Unmarshal([]byte(`{"publicKey":"not-a-valid-key"}`), &decoded)).NotTo(Succeed())
+	})
+})
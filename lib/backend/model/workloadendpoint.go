@@ -163,6 +163,14 @@ type WorkloadEndpoint struct {
 	IPv6Gateway      *net.IP           `json:"ipv6_gateway,omitempty" validate:"omitempty,ipv6"`
 	Ports            []EndpointPort    `json:"ports,omitempty" validate:"dive"`
 	GenerateName     string            `json:"generate_name,omitempty"`
+	QoSControls      *QoSControls      `json:"qos_controls,omitempty"`
+}
+
+// QoSControls contains the QoS controls to apply to a workload endpoint.  The bandwidths are in
+// bits per second, and a zero value means no limit.
+type QoSControls struct {
+	IngressBandwidth int64 `json:"ingress_bandwidth,omitempty"`
+	EgressBandwidth  int64 `json:"egress_bandwidth,omitempty"`
 }
 
 type EndpointPort struct {
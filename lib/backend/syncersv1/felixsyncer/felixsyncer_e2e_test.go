@@ -233,7 +233,7 @@ var _ = testutils.E2eDatastoreDescribe("Felix syncer tests", testutils.Datastore
 				})
 				syncTester.ExpectData(model.KVPair{
 					Key:   model.WireguardKey{NodeName: "127.0.0.1"},
-					Value: &model.Wireguard{InterfaceIPv4Addr: &wip, PublicKey: "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="},
+					Value: &model.Wireguard{InterfaceIPv4Addr: &wip, PublicKey: model.MustParseWireguardPublicKey("jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY=")},
 				})
 				expectedCacheSize += 3
 			} else {
@@ -285,7 +285,7 @@ var _ = testutils.E2eDatastoreDescribe("Felix syncer tests", testutils.Datastore
 				})
 				syncTester.ExpectData(model.KVPair{
 					Key:   model.WireguardKey{NodeName: "127.0.0.1"},
-					Value: &model.Wireguard{InterfaceIPv4Addr: &wip, PublicKey: "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="},
+					Value: &model.Wireguard{InterfaceIPv4Addr: &wip, PublicKey: model.MustParseWireguardPublicKey("jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY=")},
 				})
 				//add one for the node resource
 				expectedCacheSize += 6
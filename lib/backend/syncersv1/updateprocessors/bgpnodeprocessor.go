@@ -16,6 +16,7 @@ package updateprocessors
 
 import (
 	"errors"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -51,7 +52,7 @@ func (c *bgpNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, er
 
 	// Extract the separate bits of BGP config - these are stored as separate keys in the
 	// v1 model.  For a delete these will all be nil.
-	var asNum, ipv4, netv4, ipv6, netv6, rrClusterID interface{}
+	var asNum, ipv4, netv4, ipv6, netv6, rrClusterID, svcClusterIPs interface{}
 	var node *apiv3.Node
 	var ok bool
 	if kvp.Value != nil {
@@ -91,6 +92,24 @@ func (c *bgpNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, er
 				asNum = bgp.ASNumber.String()
 			}
 			rrClusterID = bgp.RouteReflectorClusterID
+
+			// The service ClusterIP CIDRs advertised by this node are sent as a single
+			// comma-separated list of normalized CIDRs.  Invalid CIDRs are omitted.
+			var cidrs []string
+			for _, b := range bgp.ServiceClusterIPs {
+				_, cidr, perr := cnet.ParseCIDR(b.CIDR)
+				if perr != nil {
+					log.WithError(perr).WithField("CIDR", b.CIDR).Warn("Failed to parse Node service ClusterIP CIDR")
+					if err == nil {
+						err = perr
+					}
+					continue
+				}
+				cidrs = append(cidrs, cidr.String())
+			}
+			if len(cidrs) != 0 {
+				svcClusterIPs = strings.Join(cidrs, ",")
+			}
 		}
 	}
 
@@ -143,6 +162,14 @@ func (c *bgpNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, er
 			Value:    rrClusterID,
 			Revision: kvp.Revision,
 		},
+		{
+			Key: model.NodeBGPConfigKey{
+				Nodename: name,
+				Name:     "svc_cluster_ips",
+			},
+			Value:    svcClusterIPs,
+			Revision: kvp.Revision,
+		},
 	}
 
 	if c.usePodCIDR {
@@ -192,6 +219,29 @@ func (c *bgpNodeUpdateProcessor) OnSyncerStarting() {
 	log.Debug("Sync starting called on BGP node update processor")
 }
 
+// Shutdown implements the SyncerUpdateProcessorShutdown interface.  It returns deletes for the
+// block affinities of all PodCIDRs tracked by the processor, and clears the tracker.
+func (c *bgpNodeUpdateProcessor) Shutdown() []*model.KVPair {
+	var kvps []*model.KVPair
+	tracked := c.nodeCIDRTracker.RemoveAll()
+	for _, name := range sortedNodeNames(tracked) {
+		for _, c := range tracked[name] {
+			_, cidr, err := cnet.ParseCIDR(c)
+			if err != nil {
+				log.WithError(err).WithField("CIDR", c).Warn("Failed to parse Node PodCIDR")
+				continue
+			}
+			kvps = append(kvps, &model.KVPair{Key: model.BlockAffinityKey{Host: name, CIDR: *cidr}})
+		}
+	}
+	return kvps
+}
+
+// ProducedKeyTypes implements the SyncerUpdateProcessorKeyTypes interface.
+func (c *bgpNodeUpdateProcessor) ProducedKeyTypes() []string {
+	return keyTypeNames(model.NodeBGPConfigKey{}, model.BlockAffinityKey{})
+}
+
 func (c *bgpNodeUpdateProcessor) extractName(k model.Key) (string, error) {
 	rk, ok := k.(model.ResourceKey)
 	if !ok || rk.Kind != apiv3.KindNode {
@@ -24,6 +24,7 @@ import (
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
 	"github.com/projectcalico/libcalico-go/lib/net"
 	"github.com/projectcalico/libcalico-go/lib/numorstring"
 )
@@ -33,7 +34,7 @@ var _ = Describe("Test the (BGP) Node update processor", func() {
 		Kind: apiv3.KindNode,
 		Name: "bgpnode1",
 	}
-	numBgpConfigs := 6
+	numBgpConfigs := 7
 	up := updateprocessors.NewBGPNodeUpdateProcessor(false)
 
 	BeforeEach(func() {
@@ -274,6 +275,71 @@ var _ = Describe("Test the (BGP) Node update processor", func() {
 			expected,
 		)
 	})
+
+	It("should handle the service ClusterIPs field", func() {
+		res := apiv3.NewNode()
+		res.Name = "bgpnode1"
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address: "172.17.0.2/24",
+			ServiceClusterIPs: []apiv3.ServiceClusterIPBlock{
+				{CIDR: "10.96.0.0/12"},
+				{CIDR: "fd00:96::1/112"},
+			},
+		}
+		expected := map[string]interface{}{
+			"ip_addr_v4":      "172.17.0.2",
+			"ip_addr_v6":      "",
+			"network_v4":      "172.17.0.0/24",
+			"network_v6":      nil,
+			"as_num":          nil,
+			"rr_cluster_id":   "",
+			"svc_cluster_ips": "10.96.0.0/12,fd00:96::/112",
+		}
+		kvps, err := up.Process(&model.KVPair{
+			Key:   v3NodeKey1,
+			Value: res,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		checkExpectedConfigs(
+			kvps,
+			isNodeBgpConfig,
+			numBgpConfigs,
+			expected,
+		)
+
+		By("converting a Node with an invalid service ClusterIP CIDR")
+		res.Spec.BGP.ServiceClusterIPs = []apiv3.ServiceClusterIPBlock{
+			{CIDR: "10.96.0.0/33"},
+			{CIDR: "10.100.0.0/16"},
+		}
+		expected["svc_cluster_ips"] = "10.100.0.0/16"
+		kvps, err = up.Process(&model.KVPair{
+			Key:   v3NodeKey1,
+			Value: res,
+		})
+		Expect(err).To(HaveOccurred())
+		checkExpectedConfigs(
+			kvps,
+			isNodeBgpConfig,
+			numBgpConfigs,
+			expected,
+		)
+
+		By("removing the service ClusterIPs")
+		res.Spec.BGP.ServiceClusterIPs = nil
+		delete(expected, "svc_cluster_ips")
+		kvps, err = up.Process(&model.KVPair{
+			Key:   v3NodeKey1,
+			Value: res,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		checkExpectedConfigs(
+			kvps,
+			isNodeBgpConfig,
+			numBgpConfigs,
+			expected,
+		)
+	})
 })
 
 var _ = Describe("Test the (BGP) Node update processor with USE_POD_CIDR=true", func() {
@@ -330,6 +396,24 @@ var _ = Describe("Test the (BGP) Node update processor with USE_POD_CIDR=true",
 		// And a remove for block affinity 2.
 		assertBlockAffinityUpdate(kvps, &model.KVPair{Key: model.BlockAffinityKey{CIDR: c2, Host: "mynode"}, Value: nil})
 	})
+
+	It("should return deletes for all tracked block affinities on shutdown", func() {
+		up := updateprocessors.NewBGPNodeUpdateProcessor(true)
+		res := apiv3.NewNode()
+		res.Name = "mynode"
+		res.Status.PodCIDRs = []string{"192.168.1.0/24", "192.168.2.0/24"}
+		_, err := up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+
+		sp := up.(watchersyncer.SyncerUpdateProcessorShutdown)
+		Expect(sp.Shutdown()).To(Equal([]*model.KVPair{
+			{Key: model.BlockAffinityKey{CIDR: net.MustParseCIDR("192.168.1.0/24"), Host: "mynode"}},
+			{Key: model.BlockAffinityKey{CIDR: net.MustParseCIDR("192.168.2.0/24"), Host: "mynode"}},
+		}))
+
+		By("checking no deletes are returned once the tracker is cleared")
+		Expect(sp.Shutdown()).To(BeEmpty())
+	})
 })
 
 func assertBlockAffinityUpdate(kvps []*model.KVPair, expected *model.KVPair) {
@@ -0,0 +1,186 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// SecretReader is used to read the values of secrets referenced by resources.
+type SecretReader interface {
+	// GetSecretValue returns the value of the key in the named secret.  If either the secret or
+	// the key does not exist, a cerrors.ErrorResourceDoesNotExist is returned.
+	GetSecretValue(name, key string) (string, error)
+}
+
+// Create a new SyncerUpdateProcessor to sync BGPPeer data in v1 format for consumption by the
+// BGP daemon.  Only peers with an explicit PeerIP can be represented in the v1 model: a peer
+// with a Node is converted to a NodeBGPPeerKey and a peer with neither a Node nor a NodeSelector
+// is converted to a GlobalBGPPeerKey.  Other peers are treated as deleted.
+//
+// If the peer references a BGP password secret, the password is read using the supplied
+// SecretReader and included in the v1 value.  A peer whose password cannot be resolved is
+// treated as deleted rather than being programmed without its password.
+func NewBGPPeerUpdateProcessor(secrets SecretReader) watchersyncer.SyncerUpdateProcessor {
+	c := &bgpPeerConverter{secrets: secrets}
+	return NewConflictResolvingCacheUpdateProcessor(apiv3.KindBGPPeer, c.convertBGPPeerV2ToV1)
+}
+
+type bgpPeerConverter struct {
+	secrets SecretReader
+}
+
+// Convert v3 KVPair to the equivalent v1 KVPair.
+func (c *bgpPeerConverter) convertBGPPeerV2ToV1(kvp *model.KVPair) (*model.KVPair, error) {
+	// Validate against incorrect key/value kinds.  This indicates a code bug rather
+	// than a user error.
+	v3key, ok := kvp.Key.(model.ResourceKey)
+	if !ok || v3key.Kind != apiv3.KindBGPPeer {
+		return nil, errors.New("Key is not a valid BGPPeer resource key")
+	}
+	v3res, ok := kvp.Value.(*apiv3.BGPPeer)
+	if !ok {
+		return nil, errors.New("Value is not a valid BGPPeer resource value")
+	}
+
+	spec := v3res.Spec
+	if spec.PeerIP == "" || spec.PeerSelector != "" || spec.NodeSelector != "" {
+		return nil, fmt.Errorf("BGPPeer %s cannot be represented in the v1 model", v3key.Name)
+	}
+	peerIP, port, err := parsePeerIPAndPort(spec.PeerIP)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := c.resolvePassword(v3key.Name, spec.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	var v1key model.Key
+	if spec.Node != "" {
+		v1key = model.NodeBGPPeerKey{Nodename: spec.Node, PeerIP: peerIP, Port: port}
+	} else {
+		v1key = model.GlobalBGPPeerKey{PeerIP: peerIP, Port: port}
+	}
+
+	return &model.KVPair{
+		Key: v1key,
+		Value: &model.BGPPeer{
+			PeerIP:        peerIP,
+			ASNum:         spec.ASNumber,
+			Password:      password,
+			KeepaliveTime: bgpTimerSeconds(spec.KeepaliveTime),
+			HoldTime:      bgpTimerSeconds(spec.HoldTime),
+		},
+		Revision: kvp.Revision,
+	}, nil
+}
+
+// resolvePassword validates the BGP password secret reference and returns the referenced
+// password, or nil if no password is configured.  The password itself is never logged.
+func (c *bgpPeerConverter) resolvePassword(name string, pw *apiv3.BGPPassword) (*string, error) {
+	if pw == nil {
+		return nil, nil
+	}
+	ref := pw.SecretKeyRef
+	if ref == nil {
+		return nil, cerrors.ErrorValidation{ErroredFields: []cerrors.ErroredField{{
+			Name:   "BGPPeerSpec.Password.SecretKeyRef",
+			Reason: "a secret key reference must be specified",
+		}}}
+	}
+	var fields []cerrors.ErroredField
+	if ref.Name == "" {
+		fields = append(fields, cerrors.ErroredField{
+			Name:   "BGPPeerSpec.Password.SecretKeyRef.Name",
+			Reason: "the secret name must be specified",
+		})
+	}
+	if ref.Key == "" {
+		fields = append(fields, cerrors.ErroredField{
+			Name:   "BGPPeerSpec.Password.SecretKeyRef.Key",
+			Reason: "the secret key must be specified",
+		})
+	}
+	if len(fields) > 0 {
+		return nil, cerrors.ErrorValidation{ErroredFields: fields}
+	}
+
+	logCxt := log.WithFields(log.Fields{"BGPPeer": name, "Secret": ref.Name, "SecretKey": ref.Key})
+	if c.secrets == nil {
+		return nil, fmt.Errorf("unable to read BGP password for BGPPeer %s: no secret reader configured", name)
+	}
+	password, err := c.secrets.GetSecretValue(ref.Name, ref.Key)
+	if err != nil {
+		if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok && ref.Optional != nil && *ref.Optional {
+			logCxt.Debug("Optional BGP password secret does not exist")
+			return nil, nil
+		}
+		logCxt.WithError(err).Warning("Unable to read BGP password secret")
+		return nil, err
+	}
+	logCxt.Debug("Read BGP password secret")
+	return &password, nil
+}
+
+// bgpTimerSeconds converts a v3 BGP timer to the whole number of seconds used in the v1 model,
+// or nil if the timer is not set.  The timer is validated to be within the range of a BGP timer,
+// but is clamped to that range here in case the resource has not been validated.
+func bgpTimerSeconds(d *metav1.Duration) *uint16 {
+	if d == nil {
+		return nil
+	}
+	secs := d.Duration / time.Second
+	if secs < 0 {
+		secs = 0
+	} else if secs > math.MaxUint16 {
+		secs = math.MaxUint16
+	}
+	s := uint16(secs)
+	return &s
+}
+
+// parsePeerIPAndPort parses a v3 PeerIP, which is either an IP address or an IP address and
+// port in the form <IPv4>:<port> or [<IPv6>]:<port>.  A port of 0 indicates the default port.
+func parsePeerIPAndPort(peerIP string) (cnet.IP, uint16, error) {
+	ipStr, port := peerIP, uint16(0)
+	if host, portStr, err := net.SplitHostPort(peerIP); err == nil {
+		p, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return cnet.IP{}, 0, fmt.Errorf("invalid port in PeerIP %s", peerIP)
+		}
+		ipStr, port = host, uint16(p)
+	}
+	ip := cnet.ParseIP(ipStr)
+	if ip == nil {
+		return cnet.IP{}, 0, fmt.Errorf("invalid PeerIP %s", peerIP)
+	}
+	return *ip, port, nil
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	k8sv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+// fakeSecretReader is a SecretReader backed by a map of secret name to secret data.
+type fakeSecretReader map[string]map[string]string
+
+func (f fakeSecretReader) GetSecretValue(name, key string) (string, error) {
+	if v, ok := f[name][key]; ok {
+		return v, nil
+	}
+	return "", cerrors.ErrorResourceDoesNotExist{Identifier: fmt.Sprintf("%s/%s", name, key)}
+}
+
+var _ = Describe("Test the BGPPeer update processor", func() {
+	v3PeerKey := model.ResourceKey{
+		Kind: apiv3.KindBGPPeer,
+		Name: "peer1",
+	}
+	peerIP := net.MustParseIP("10.0.0.1")
+	secrets := fakeSecretReader{
+		"bgp-secrets": {"peer1": "very-secret"},
+	}
+	var up watchersyncer.SyncerUpdateProcessor
+
+	// peer returns a BGPPeer with the supplied password secret reference.
+	peer := func(secret, key string) *apiv3.BGPPeer {
+		res := apiv3.NewBGPPeer()
+		res.Name = v3PeerKey.Name
+		res.Spec.PeerIP = "10.0.0.1"
+		res.Spec.ASNumber = numorstring.ASNumber(64512)
+		if secret != "" || key != "" {
+			res.Spec.Password = &apiv3.BGPPassword{
+				SecretKeyRef: &k8sv1.SecretKeySelector{
+					LocalObjectReference: k8sv1.LocalObjectReference{Name: secret},
+					Key:                  key,
+				},
+			}
+		}
+		return res
+	}
+
+	BeforeEach(func() {
+		up = updateprocessors.NewBGPPeerUpdateProcessor(secrets)
+	})
+
+	It("should convert a global peer without a password", func() {
+		kvps, err := up.Process(&model.KVPair{Key: v3PeerKey, Value: peer("", ""), Revision: "abcde"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{
+			Key:      model.GlobalBGPPeerKey{PeerIP: peerIP},
+			Value:    &model.BGPPeer{PeerIP: peerIP, ASNum: numorstring.ASNumber(64512)},
+			Revision: "abcde",
+		}}))
+	})
+
+	It("should convert a node peer with a port and a password read from the secret", func() {
+		res := peer("bgp-secrets", "peer1")
+		res.Spec.Node = "node1"
+		res.Spec.PeerIP = "10.0.0.1:1179"
+		kvps, err := up.Process(&model.KVPair{Key: v3PeerKey, Value: res, Revision: "abcde"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		Expect(kvps[0].Key).To(Equal(model.NodeBGPPeerKey{Nodename: "node1", PeerIP: peerIP, Port: 1179}))
+
+		v1 := kvps[0].Value.(*model.BGPPeer)
+		Expect(v1.Password).NotTo(BeNil())
+		Expect(*v1.Password).To(Equal("very-secret"))
+
+		By("checking the password is not included when the value is formatted")
+		Expect(fmt.Sprintf("%+v", *v1)).NotTo(ContainSubstring("very-secret"))
+	})
+
+	It("should convert the BGP timers to seconds", func() {
+		res := peer("", "")
+		res.Spec.KeepaliveTime = &metav1.Duration{Duration: 10 * time.Second}
+		res.Spec.HoldTime = &metav1.Duration{Duration: 30 * time.Second}
+		kvps, err := up.Process(&model.KVPair{Key: v3PeerKey, Value: res, Revision: "abcde"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+
+		v1 := kvps[0].Value.(*model.BGPPeer)
+		Expect(v1.KeepaliveTime).NotTo(BeNil())
+		Expect(*v1.KeepaliveTime).To(Equal(uint16(10)))
+		Expect(v1.HoldTime).NotTo(BeNil())
+		Expect(*v1.HoldTime).To(Equal(uint16(30)))
+
+		By("round-tripping the v1 value through the backend serialization")
+		data, err := model.SerializeValue(kvps[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"keepalive_time":10`))
+		Expect(string(data)).To(ContainSubstring(`"hold_time":30`))
+		parsed, err := model.ParseValue(kvps[0].Key, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed).To(Equal(v1))
+
+		By("converting a hold time of 0")
+		res.Spec.KeepaliveTime = nil
+		res.Spec.HoldTime = &metav1.Duration{}
+		kvps, err = up.Process(&model.KVPair{Key: v3PeerKey, Value: res, Revision: "abcdf"})
+		Expect(err).NotTo(HaveOccurred())
+		v1 = kvps[0].Value.(*model.BGPPeer)
+		Expect(v1.KeepaliveTime).To(BeNil())
+		Expect(v1.HoldTime).NotTo(BeNil())
+		Expect(*v1.HoldTime).To(BeZero())
+	})
+
+	It("should treat a peer with a missing secret as deleted", func() {
+		kvps, err := up.Process(&model.KVPair{Key: v3PeerKey, Value: peer("bgp-secrets", "peer1"), Revision: "abcde"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+
+		By("updating the peer to reference a missing secret key")
+		kvps, err = up.Process(&model.KVPair{Key: v3PeerKey, Value: peer("bgp-secrets", "missing"), Revision: "abcdf"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		Expect(kvps).To(Equal([]*model.KVPair{{Key: model.GlobalBGPPeerKey{PeerIP: peerIP}}}))
+
+		By("adding a peer that references a missing secret")
+		_, err = up.Process(&model.KVPair{Key: v3PeerKey, Value: peer("other-secrets", "peer1"), Revision: "abcdg"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+
+	It("should convert a peer with a missing optional secret without a password", func() {
+		res := peer("other-secrets", "peer1")
+		optional := true
+		res.Spec.Password.SecretKeyRef.Optional = &optional
+		kvps, err := up.Process(&model.KVPair{Key: v3PeerKey, Value: res, Revision: "abcde"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		Expect(kvps[0].Value.(*model.BGPPeer).Password).To(BeNil())
+	})
+
+	It("should validate the secret reference", func() {
+		res := peer("", "")
+		res.Spec.Password = &apiv3.BGPPassword{}
+		_, err := up.Process(&model.KVPair{Key: v3PeerKey, Value: res, Revision: "abcde"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+		Expect(err.(cerrors.ErrorValidation).ErroredFields[0].Name).To(Equal("BGPPeerSpec.Password.SecretKeyRef"))
+
+		_, err = up.Process(&model.KVPair{Key: v3PeerKey, Value: peer("bgp-secrets", ""), Revision: "abcde"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+		Expect(err.(cerrors.ErrorValidation).ErroredFields[0].Name).To(Equal("BGPPeerSpec.Password.SecretKeyRef.Key"))
+	})
+
+	It("should not convert a peer that selects its peers", func() {
+		res := peer("", "")
+		res.Spec.PeerIP = ""
+		res.Spec.PeerSelector = "all()"
+		_, err := up.Process(&model.KVPair{Key: v3PeerKey, Value: res, Revision: "abcde"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -16,6 +16,7 @@ package updateprocessors
 
 import (
 	"reflect"
+	"sync"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
@@ -48,3 +49,77 @@ func NewClusterInfoUpdateProcessor() watchersyncer.SyncerUpdateProcessor {
 func datastoreReadyToBool(value interface{}) interface{} {
 	return value.(bool)
 }
+
+// DatastoreReadyGate tracks the DatastoreReady flag of the ClusterInformation, so that other
+// processors and consumers can check whether the datastore is ready before converting or acting
+// on updates.  The gate is updated by a ClusterInformation update processor created with
+// NewClusterInfoUpdateProcessorWithGate.  It is safe for concurrent use.
+type DatastoreReadyGate struct {
+	lock  sync.RWMutex
+	ready bool
+}
+
+// NewDatastoreReadyGate creates a new DatastoreReadyGate.  The gate is not ready until a
+// ClusterInformation with DatastoreReady set to true has been processed.
+func NewDatastoreReadyGate() *DatastoreReadyGate {
+	return &DatastoreReadyGate{}
+}
+
+// Ready returns true if the last ClusterInformation processed had DatastoreReady set to true.
+// It returns false if the ClusterInformation has not been processed, has been deleted, or does
+// not set DatastoreReady.
+func (g *DatastoreReadyGate) Ready() bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.ready
+}
+
+func (g *DatastoreReadyGate) setReady(ready bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.ready = ready
+}
+
+// NewClusterInfoUpdateProcessorWithGate creates a new ClusterInformation update processor that
+// also updates the supplied gate with the DatastoreReady flag of each ClusterInformation that
+// it processes.  The conversion is otherwise identical to NewClusterInfoUpdateProcessor.
+func NewClusterInfoUpdateProcessorWithGate(gate *DatastoreReadyGate) watchersyncer.SyncerUpdateProcessor {
+	return &clusterInfoGateUpdateProcessor{
+		SyncerUpdateProcessor: NewClusterInfoUpdateProcessor(),
+		gate:                  gate,
+	}
+}
+
+// clusterInfoGateUpdateProcessor wraps the ClusterInformation update processor to track the
+// ready flag in a DatastoreReadyGate.
+type clusterInfoGateUpdateProcessor struct {
+	watchersyncer.SyncerUpdateProcessor
+	gate *DatastoreReadyGate
+}
+
+func (c *clusterInfoGateUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, error) {
+	kvps, err := c.SyncerUpdateProcessor.Process(kvp)
+	for _, converted := range kvps {
+		if _, ok := converted.Key.(model.ReadyFlagKey); ok {
+			ready, _ := converted.Value.(bool)
+			c.gate.setReady(ready)
+		}
+	}
+	return kvps, err
+}
+
+// OnSyncerStarting clears the gate since a ClusterInformation that was deleted while the syncer
+// was not watching is not passed to the processor.  The gate is set again as the
+// ClusterInformation is re-listed.
+func (c *clusterInfoGateUpdateProcessor) OnSyncerStarting() {
+	c.SyncerUpdateProcessor.OnSyncerStarting()
+	c.gate.setReady(false)
+}
+
+// ProducedKeyTypes implements the watchersyncer.SyncerUpdateProcessorKeyTypes interface.
+func (c *clusterInfoGateUpdateProcessor) ProducedKeyTypes() []string {
+	if kt, ok := c.SyncerUpdateProcessor.(watchersyncer.SyncerUpdateProcessorKeyTypes); ok {
+		return kt.ProducedKeyTypes()
+	}
+	return nil
+}
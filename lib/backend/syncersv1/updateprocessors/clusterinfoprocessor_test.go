@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+)
+
+var _ = Describe("Test the ClusterInformation update processor", func() {
+	clusterKey := model.ResourceKey{
+		Kind: apiv3.KindClusterInformation,
+		Name: "default",
+	}
+
+	// valuesByKey returns the values of the converted KVPairs keyed off the v1 key.
+	valuesByKey := func(kvps []*model.KVPair) map[model.Key]interface{} {
+		values := make(map[model.Key]interface{}, len(kvps))
+		for _, kvp := range kvps {
+			values[kvp.Key] = kvp.Value
+		}
+		return values
+	}
+
+	It("should convert the ClusterInformation fields to global config and the ready flag", func() {
+		up := updateprocessors.NewClusterInfoUpdateProcessor()
+		ready := true
+		res := apiv3.NewClusterInformation()
+		res.Name = "default"
+		res.Spec = apiv3.ClusterInformationSpec{
+			ClusterGUID:    "abcdef",
+			ClusterType:    "k8s,bgp",
+			CalicoVersion:  "v3.19.0",
+			DatastoreReady: &ready,
+			Variant:        "Calico",
+		}
+		kvps, err := up.Process(&model.KVPair{Key: clusterKey, Value: res, Revision: "1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(valuesByKey(kvps)).To(Equal(map[model.Key]interface{}{
+			model.GlobalConfigKey{Name: "ClusterGUID"}:   "abcdef",
+			model.GlobalConfigKey{Name: "ClusterType"}:   "k8s,bgp",
+			model.GlobalConfigKey{Name: "CalicoVersion"}: "v3.19.0",
+			model.GlobalConfigKey{Name: "Variant"}:       "Calico",
+			model.ReadyFlagKey{}:                         true,
+		}))
+
+		By("deleting the ClusterInformation")
+		kvps, err = up.Process(&model.KVPair{Key: clusterKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(valuesByKey(kvps)).To(Equal(map[model.Key]interface{}{
+			model.GlobalConfigKey{Name: "ClusterGUID"}:   nil,
+			model.GlobalConfigKey{Name: "ClusterType"}:   nil,
+			model.GlobalConfigKey{Name: "CalicoVersion"}: nil,
+			model.GlobalConfigKey{Name: "Variant"}:       nil,
+			model.ReadyFlagKey{}:                         nil,
+		}))
+	})
+
+	It("should track the DatastoreReady flag in the gate", func() {
+		gate := updateprocessors.NewDatastoreReadyGate()
+		up := updateprocessors.NewClusterInfoUpdateProcessorWithGate(gate)
+		Expect(gate.Ready()).To(BeFalse())
+
+		process := func(ready *bool) {
+			res := apiv3.NewClusterInformation()
+			res.Name = "default"
+			res.Spec.DatastoreReady = ready
+			_, err := up.Process(&model.KVPair{Key: clusterKey, Value: res, Revision: "1"})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		ready, notReady := true, false
+
+		By("processing a ready ClusterInformation")
+		process(&ready)
+		Expect(gate.Ready()).To(BeTrue())
+
+		By("processing a ClusterInformation that is not ready")
+		process(&notReady)
+		Expect(gate.Ready()).To(BeFalse())
+
+		By("processing a ClusterInformation without the ready flag")
+		process(&ready)
+		process(nil)
+		Expect(gate.Ready()).To(BeFalse())
+
+		By("deleting the ClusterInformation")
+		process(&ready)
+		_, err := up.Process(&model.KVPair{Key: clusterKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gate.Ready()).To(BeFalse())
+
+		By("restarting the syncer")
+		process(&ready)
+		up.OnSyncerStarting()
+		Expect(gate.Ready()).To(BeFalse())
+	})
+
+	It("should convert the same KVPairs with and without a gate", func() {
+		ready := true
+		res := apiv3.NewClusterInformation()
+		res.Name = "default"
+		res.Spec.ClusterGUID = "abcdef"
+		res.Spec.DatastoreReady = &ready
+		kvp := &model.KVPair{Key: clusterKey, Value: res, Revision: "1"}
+
+		expected, err := updateprocessors.NewClusterInfoUpdateProcessor().Process(kvp)
+		Expect(err).NotTo(HaveOccurred())
+		up := updateprocessors.NewClusterInfoUpdateProcessorWithGate(updateprocessors.NewDatastoreReadyGate())
+		kvps, err := up.Process(kvp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(ConsistOf(expected))
+
+		kt, ok := up.(watchersyncer.SyncerUpdateProcessorKeyTypes)
+		Expect(ok).To(BeTrue())
+		Expect(kt.ProducedKeyTypes()).To(ContainElement("ReadyFlagKey"))
+	})
+})
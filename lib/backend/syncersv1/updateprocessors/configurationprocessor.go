@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -298,6 +299,22 @@ func (c *configUpdateProcessor) OnSyncerStarting() {
 	c.additionalNames = map[string]struct{}{}
 }
 
+// ProducedKeyTypes implements the SyncerUpdateProcessorKeyTypes interface.  The key types
+// are determined from the key functions for each of the known config names.
+func (c *configUpdateProcessor) ProducedKeyTypes() []string {
+	names := make([]string, 0, len(c.names))
+	for name := range c.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var keys []model.Key
+	for _, name := range names {
+		keys = append(keys, c.globalConfigKeyFn(name), c.nodeConfigKeyFn("", name))
+	}
+	return keyTypeNames(keys...)
+}
+
 // extractAnnotations extracts the config override annotations from the
 // configuration resource.
 func (c *configUpdateProcessor) extractAnnotations(kvp *model.KVPair) (map[string]string, error) {
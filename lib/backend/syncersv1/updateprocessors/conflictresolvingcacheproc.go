@@ -255,3 +255,8 @@ func (c *conflictResolvingCache) OnSyncerStarting() {
 	c.kvpsByName = make(map[string]*model.KVPair)
 	c.orderedNamesByV1Key = make(map[string][]string)
 }
+
+// ProducedKeyTypes implements the SyncerUpdateProcessorKeyTypes interface.
+func (c *conflictResolvingCache) ProducedKeyTypes() []string {
+	return producedKeyTypesForKind(c.v3Kind)
+}
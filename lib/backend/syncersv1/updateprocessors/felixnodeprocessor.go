@@ -15,8 +15,15 @@
 package updateprocessors
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -25,69 +32,704 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
 	cresources "github.com/projectcalico/libcalico-go/lib/resources"
 
-	wg "golang.zx2c4.com/wireguard/wgctrl/wgtypes"
-
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
 )
 
+// The names of the HostConfigKeys emitted for the tunnel configuration of a Node.  Each key is
+// emitted for every Node update, with a nil value if the corresponding field is not set or is
+// not valid.  The keys for each address family are derived independently:
+//
+//	Node field                   HostConfigKey         Address family
+//	Spec.BGP.IPv4IPIPTunnelAddr  IpInIpTunnelAddr      IPv4 (IPIP is only supported for IPv4)
+//	Spec.IPv4VXLANTunnelAddr     IPv4VXLANTunnelAddr   IPv4
+//	Spec.VXLANTunnelMACV4Addr    VXLANTunnelMACV4Addr  -
+//	Spec.IPv6VXLANTunnelAddr     IPv6VXLANTunnelAddr   IPv6
+//	Spec.VXLANTunnelMACV6Addr    VXLANTunnelMACV6Addr  -
+//
+// A tunnel address of the wrong address family is treated as invalid.  The name of the IPIP
+// tunnel address key may be overridden with WithIPIPTunnelAddrKeyName.
+const (
+	IPv4VXLANTunnelAddrKeyName  = "IPv4VXLANTunnelAddr"
+	VXLANTunnelMACV4AddrKeyName = "VXLANTunnelMACV4Addr"
+	IPv6VXLANTunnelAddrKeyName  = "IPv6VXLANTunnelAddr"
+	VXLANTunnelMACV6AddrKeyName = "VXLANTunnelMACV6Addr"
+)
+
+// hostConfigVXLANVNI is the name of the HostConfigKey emitted for the VXLAN VNI of a Node
+// (Spec.VXLANVNI).  Unlike the tunnel keys, it is only emitted for a Node that sets a valid VNI,
+// or with a nil value to remove a VNI that was previously emitted, so that a Node using the
+// default VNI does not override the VNI in the Felix configuration.
+const hostConfigVXLANVNI = "VXLANVNI"
+
+// maxVXLANVNI is the largest VXLAN VNI, which is a 24-bit value.
+const maxVXLANVNI = 1<<24 - 1
+
+// VXLANTunnelAddrKeyPrefix is the prefix of the names of the HostConfigKeys emitted for the
+// additional VXLAN tunnel addresses of a Node (Spec.VXLANTunnelAddrs).  The name of each key is
+// the prefix followed by the name of the tunnel.  The key for a tunnel has a nil value if the
+// address is not valid, and is sent with a nil value when the tunnel is removed from the Node.
+const VXLANTunnelAddrKeyPrefix = "VXLANTunnelAddr-"
+
+// DefaultIPIPTunnelAddrKeyName is the default name of the HostConfigKey emitted for the IPIP
+// tunnel address of a Node.  The mixed casing is historical.
+const DefaultIPIPTunnelAddrKeyName = "IpInIpTunnelAddr"
+
+// ASNumberKeyName is the name of the HostConfigKey emitted for the AS number of a Node.  Like the
+// VXLAN VNI key, it is only emitted for a Node that specifies a valid AS number, or with a nil value
+// to remove an AS number that was previously emitted, so that a Node inheriting the global AS
+// number has no key.
+const ASNumberKeyName = "AsNumber"
+
+// hostConfigNodeTaints is the name of the HostConfigKey emitted for the taints of a Node when the
+// processor is configured with EmitNodeTaints.
+const hostConfigNodeTaints = "NodeTaints"
+
+// hostConfigNodeAddresses is the name of the HostConfigKey emitted for the set of all addresses of
+// a Node when the processor is configured with EmitNodeAddresses.
+const hostConfigNodeAddresses = "NodeAddresses"
+
+// The names of the HostConfigKeys emitted for the operating system and architecture of a Node when
+// the processor is configured with EmitNodePlatform.
+const (
+	hostConfigNodeOS   = "NodeOS"
+	hostConfigNodeArch = "NodeArch"
+)
+
+// hostConfigNodeEncapsulation is the name of the HostConfigKey emitted for the encapsulation
+// in use on a Node when the processor is configured with EmitNodeEncapsulation.
+const hostConfigNodeEncapsulation = "NodeEncapsulation"
+
+// The values of the NodeEncapsulation HostConfigKey.  A Node that has both an IPIP and a VXLAN
+// tunnel address (for example, while migrating between IP pools with different encapsulation)
+// has the value NodeEncapsulationIPIPAndVXLAN.
+const (
+	NodeEncapsulationIPIP         = "IPIP"
+	NodeEncapsulationVXLAN        = "VXLAN"
+	NodeEncapsulationIPIPAndVXLAN = "IPIP,VXLAN"
+)
+
+// hostConfigNodeDecommissioning is the name of the HostConfigKey emitted to mark a Node that is
+// being decommissioned when the processor is configured with EmitNodeDecommissioning.
+const hostConfigNodeDecommissioning = "NodeDecommissioning"
+
+// hostConfigNodeLogLevel is the name of the HostConfigKey emitted for the log level override of a
+// Node when the processor is configured with EmitNodeLogLevel.
+const hostConfigNodeLogLevel = "NodeLogLevel"
+
+// hostConfigHostIPv6 is the name of the HostConfigKey emitted for the IPv6 address of a Node when
+// the processor is configured with EmitHostIPv6.
+const hostConfigHostIPv6 = "HostIPv6"
+
+// hostConfigNodeReadiness is the name of the HostConfigKey emitted for the readiness of a Node
+// when the processor is configured with EmitNodeReadiness.
+const hostConfigNodeReadiness = "NodeReadiness"
+
+// The log levels that may be set with the apiv3.AnnotationNodeLogLevel annotation, keyed by their
+// lowercased form.  These match the levels accepted by the FelixConfiguration LogSeverityScreen.
+var nodeLogLevels = map[string]string{
+	"debug":   "Debug",
+	"info":    "Info",
+	"warning": "Warning",
+	"error":   "Error",
+	"fatal":   "Fatal",
+}
+
+// The Node labels holding the operating system and architecture of a Node, in order of preference.
+var (
+	nodeOSLabels   = []string{"kubernetes.io/os", "beta.kubernetes.io/os"}
+	nodeArchLabels = []string{"kubernetes.io/arch", "beta.kubernetes.io/arch"}
+)
+
+// The known operating systems and architectures of a Node.
+var (
+	knownNodeOSes   = map[string]bool{"linux": true, "windows": true}
+	knownNodeArches = map[string]bool{
+		"amd64": true, "arm64": true, "arm": true, "386": true, "ppc64le": true, "s390x": true,
+	}
+)
+
+// The names of the HostConfigKeys emitted for the node-status addresses of a Node when the
+// processor is configured with EmitNodeStatusAddresses.  The first address of each type and
+// address family is emitted, with a nil value if the Node has no such address.
+var nodeStatusAddressKeys = []struct {
+	name    string
+	ipType  string
+	version int
+}{
+	{"NodeInternalIPv4Addr", apiv3.InternalIP, 4},
+	{"NodeExternalIPv4Addr", apiv3.ExternalIP, 4},
+	{"NodeInternalIPv6Addr", apiv3.InternalIP, 6},
+	{"NodeExternalIPv6Addr", apiv3.ExternalIP, 6},
+}
+
+// Sentinel errors identifying the category of a Node conversion failure.  The errors returned by
+// the FelixNodeUpdateProcessor for an invalid Node field wrap one of these, so the failures may be
+// classified using errors.Is.
+var (
+	ErrNodeInvalidIPv4Address            = errors.New("invalid Node IPv4 address")
+	ErrNodeInvalidIPv6Address            = errors.New("invalid Node IPv6 address")
+	ErrNodeInvalidIPIPTunnelAddr         = errors.New("invalid Node IPIP tunnel address")
+	ErrNodeInvalidVXLANTunnelAddr        = errors.New("invalid Node VXLAN tunnel address")
+	ErrNodeInvalidVXLANTunnelMAC         = errors.New("invalid Node VXLAN tunnel MAC address")
+	ErrNodeInvalidVXLANVNI               = errors.New("invalid Node VXLAN VNI")
+	ErrNodeInvalidWireguardInterfaceAddr = errors.New("invalid Node Wireguard interface address")
+	ErrNodeInvalidWireguardPublicKey     = errors.New("invalid Node Wireguard public key")
+	ErrNodeInvalidPodCIDR                = errors.New("invalid Node pod CIDR")
+	ErrNodeInvalidTaint                  = errors.New("invalid Node taint")
+	ErrNodeInvalidASNumber               = errors.New("invalid Node AS number")
+	ErrNodeInvalidPlatform               = errors.New("invalid Node operating system or architecture")
+	ErrNodeInvalidDecommissioning        = errors.New("invalid Node decommissioning annotation")
+	ErrNodeUnknownSpecField              = errors.New("unknown Node spec field")
+	ErrNodeInvalidLogLevel               = errors.New("invalid Node log level annotation")
+	ErrNodeAddressMismatch               = errors.New("Node BGP address does not match the Node address")
+)
+
+// DropReason is the reason that a Node field was dropped during conversion.  Unlike the ErrNode*
+// sentinel errors, which identify the field, the reason identifies what was wrong with the value,
+// so that failures may be aggregated across fields.
+type DropReason int
+
+const (
+	// DropReasonInvalidIP indicates a value that is not a valid IP address or CIDR.
+	DropReasonInvalidIP DropReason = iota + 1
+	// DropReasonInvalidMAC indicates a value that is not a valid MAC address.
+	DropReasonInvalidMAC
+	// DropReasonInvalidKey indicates a value that is not a valid key, such as a Wireguard
+	// public key or a taint key.
+	DropReasonInvalidKey
+	// DropReasonWrongFamily indicates a valid IP address of the wrong address family.
+	DropReasonWrongFamily
+	// DropReasonOutOfRange indicates a value outside of the permitted values.
+	DropReasonOutOfRange
+	// DropReasonUnknownField indicates a field that the processor does not handle.
+	DropReasonUnknownField
+	// DropReasonMismatch indicates a value that disagrees with another field of the Node.
+	DropReasonMismatch
+)
+
+func (r DropReason) String() string {
+	switch r {
+	case DropReasonInvalidIP:
+		return "InvalidIP"
+	case DropReasonInvalidMAC:
+		return "InvalidMAC"
+	case DropReasonInvalidKey:
+		return "InvalidKey"
+	case DropReasonWrongFamily:
+		return "WrongFamily"
+	case DropReasonOutOfRange:
+		return "OutOfRange"
+	case DropReasonUnknownField:
+		return "UnknownField"
+	case DropReasonMismatch:
+		return "Mismatch"
+	}
+	return "Unknown"
+}
+
+// NodeConversionErrorHandler is called by the FelixNodeUpdateProcessor for each Node field that is
+// dropped from the conversion.  The field is the path of the field within the Node resource (for
+// example "Spec.BGP.IPv4Address"), and the error wraps one of the ErrNode* sentinel errors.
+type NodeConversionErrorHandler func(node, field string, reason DropReason, err error)
+
+// NodeField identifies a Node field counted by a NodeConversionCounter.
+type NodeField string
+
+const (
+	NodeFieldIPv4       NodeField = "IPv4"
+	NodeFieldIPv6       NodeField = "IPv6"
+	NodeFieldIPIP       NodeField = "IPIP"
+	NodeFieldVXLANV4    NodeField = "VXLANV4"
+	NodeFieldVXLANV6    NodeField = "VXLANV6"
+	NodeFieldVXLANMACV4 NodeField = "VXLANMACV4"
+	NodeFieldVXLANMACV6 NodeField = "VXLANMACV6"
+	NodeFieldWireguard  NodeField = "Wireguard"
+)
+
+// NodeConversionCounter counts the outcome of converting each Node field, for example to
+// maintain metrics of the health of the Node configuration across the cluster.  For each Node
+// update, a field that is set is counted as either parsed or dropped; a field that is not set is
+// not counted.  The Wireguard field counts the interface address and the public key separately.
+// The counter is called synchronously from Process.
+type NodeConversionCounter interface {
+	IncParsed(field NodeField)
+	IncDropped(field NodeField)
+}
+
+// nodeConversionError is an error converting a Node field.  The category is one of the ErrNode*
+// sentinel errors.
+type nodeConversionError struct {
+	category error
+	field    string
+	reason   DropReason
+	msg      string
+}
+
+func newNodeConversionError(category error, field string, reason DropReason, format string, args ...interface{}) error {
+	return nodeConversionError{category: category, field: field, reason: reason, msg: fmt.Sprintf(format, args...)}
+}
+
+func (e nodeConversionError) Error() string {
+	return e.msg
+}
+
+func (e nodeConversionError) Unwrap() error {
+	return e.category
+}
+
+// FelixNodeUpdateProcessorOption is an option that modifies the behavior of the
+// FelixNodeUpdateProcessor.
+type FelixNodeUpdateProcessorOption func(*FelixNodeUpdateProcessor)
+
+// WithholdResourceOnError configures the processor to omit the Node resource KVPair
+// from its updates whenever any part of the conversion fails.  Consumers of the resource
+// will then retain the previous (fully converted) state of the Node rather than seeing a
+// Node whose derived keys have been partially deleted.
+func WithholdResourceOnError() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.withholdResourceOnError = true
+	}
+}
+
+// StrictIPParsing configures the processor to reject BGP addresses that are in an ambiguous
+// form, such as dotted-decimal octets with leading zeros.  See cnet.ParseIPStrict.
+func StrictIPParsing() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.strictIPParsing = true
+	}
+}
+
+// DeriveVXLANTunnelMAC configures the processor to synthesize the IPv4 VXLAN tunnel MAC
+// address from the IPv4 VXLAN tunnel address when the Node does not specify a MAC address.
+// See deriveVXLANTunnelMAC for details of the derivation.
+func DeriveVXLANTunnelMAC() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.deriveVXLANTunnelMAC = true
+	}
+}
+
+// EmitNodeTaints configures the processor to emit the taints of the Node as a NodeTaints
+// HostConfigKey.  The value is a comma-separated list of the taints in the kubectl form
+// "key=value:Effect" (or "key:Effect" for a taint with no value), and is nil if the Node has no
+// valid taints.
+func EmitNodeTaints() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodeTaints = true
+	}
+}
+
+// EmitNodeStatusAddresses configures the processor to emit the node-status addresses of the
+// Node (see nodeStatusAddressKeys) as HostConfigKeys.  The addresses are emitted even if the
+// Node has a BGP address, in which case the HostIPKey contains the BGP address and the
+// node-status addresses are for reference only.
+func EmitNodeStatusAddresses() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodeStatusAddresses = true
+	}
+}
+
+// StrictNodeSpec configures the processor to report each field set in the Node spec that the
+// processor does not handle, such as a field added to a newer version of the Node API.  Each
+// unknown field is logged and reported as a conversion error wrapping ErrNodeUnknownSpecField; the
+// rest of the Node is converted as normal.  The fields are identified by their JSON names (see
+// knownNodeSpecFields).
+func StrictNodeSpec() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.strictNodeSpec = true
+	}
+}
+
+// WithIPIPTunnelAddrKeyName configures the processor to emit the IPIP tunnel address of the Node
+// with the supplied HostConfigKey name, rather than DefaultIPIPTunnelAddrKeyName.  This is for
+// compatibility with consumers that expect a different casing of the name.  An empty name is
+// ignored.
+func WithIPIPTunnelAddrKeyName(name string) FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		if name != "" {
+			c.ipipTunnelAddrKeyName = name
+		}
+	}
+}
+
+// WithConversionErrorHandler configures the processor to call the supplied handler for each Node
+// field that is dropped from the conversion.  The handler is called synchronously from Process.
+func WithConversionErrorHandler(fn NodeConversionErrorHandler) FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.conversionErrorHandler = fn
+	}
+}
+
+// WithConversionCounter configures the processor to count the outcome of converting each Node
+// field with the supplied counter.  A nil counter is ignored.
+func WithConversionCounter(counter NodeConversionCounter) FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.conversionCounter = counter
+	}
+}
+
+// EmitNodeAddresses configures the processor to emit the set of all InternalIP and ExternalIP
+// addresses of the Node as a NodeAddresses HostConfigKey.  The value is a comma-separated list of
+// the unique addresses, with the IPv4 addresses before the IPv6 addresses and each in ascending
+// order, and is nil if the Node has no valid addresses.  Addresses given in CIDR form are emitted
+// without the prefix length.
+func EmitNodeAddresses() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodeAddresses = true
+	}
+}
+
+// EmitNodePlatform configures the processor to emit the operating system and architecture of the
+// Node, taken from the kubernetes.io/os and kubernetes.io/arch labels (or their deprecated beta
+// equivalents), as the NodeOS and NodeArch HostConfigKeys.  The values are lowercased and must be
+// a known operating system (linux or windows) or architecture; a value is nil if the label is not
+// set or is not known.
+func EmitNodePlatform() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodePlatform = true
+	}
+}
+
+// EmitNodeEncapsulation configures the processor to emit a summary of the encapsulation in use on
+// the Node as a NodeEncapsulation HostConfigKey.  The encapsulation is derived from the tunnel
+// addresses of the Node: NodeEncapsulationIPIP if the Node has a valid IPIP tunnel address,
+// NodeEncapsulationVXLAN if it has a valid IPv4 or IPv6 VXLAN tunnel address, and
+// NodeEncapsulationIPIPAndVXLAN if it has both.  The value is nil if the Node has no valid tunnel
+// address.
+func EmitNodeEncapsulation() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodeEncapsulation = true
+	}
+}
+
+// EmitNodeDecommissioning configures the processor to emit a NodeDecommissioning HostConfigKey
+// for a Node that is being decommissioned, as indicated by the apiv3.AnnotationNodeDecommissioning
+// annotation.  The value is "true" while the annotation is set to true, and is nil (clearing the
+// marker) otherwise.  Controllers may watch for the marker to release the block affinities of the
+// Node, for example with the IPAM ReleaseHostAffinities method.
+func EmitNodeDecommissioning() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodeDecommissioning = true
+	}
+}
+
+// EmitNodeLogLevel configures the processor to emit the log level override of a Node, as set by the
+// apiv3.AnnotationNodeLogLevel annotation, as a NodeLogLevel HostConfigKey so that Felix may adjust
+// its log level on that Node.  The level is matched case-insensitively and emitted in the form used
+// by the FelixConfiguration (for example "Debug").  The value is nil if the annotation is not set
+// or is not a valid log level.
+func EmitNodeLogLevel() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodeLogLevel = true
+	}
+}
+
+// EmitHostIPv6 configures the processor to emit the IPv6 address of a Node as a HostIPv6
+// HostConfigKey, the IPv6 equivalent of the HostIPKey.  The address is taken from the BGP
+// IPv6Address if set, and otherwise from the first IPv6 InternalIP or ExternalIP node-status
+// address.  The value is nil if the Node has no IPv6 address.
+func EmitHostIPv6() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitHostIPv6 = true
+	}
+}
+
+// MissingPodCIDRsHandler is called by the FelixNodeUpdateProcessor for a Node that has reported
+// no PodCIDRs for longer than the grace period configured with WarnOnMissingPodCIDRs.  The
+// duration is the time since the processor first saw the Node without PodCIDRs.
+type MissingPodCIDRsHandler func(node string, missingFor time.Duration)
+
+// WarnOnMissingPodCIDRs configures the processor, when usePodCIDR is enabled, to warn about a Node
+// that still reports no PodCIDRs once the grace period has passed since the processor first saw
+// the Node without any.  A newly joined Node is not expected to have PodCIDRs until they have been
+// allocated, whereas a Node that is never allocated PodCIDRs cannot host any pods.  The check is
+// made as each update for the Node is processed, and the warning is logged (and passed to the
+// handler, if not nil) once until the Node reports PodCIDRs or is deleted.
+func WarnOnMissingPodCIDRs(gracePeriod time.Duration, fn MissingPodCIDRsHandler) FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.warnOnMissingPodCIDRs = true
+		c.missingPodCIDRsGracePeriod = gracePeriod
+		c.missingPodCIDRsHandler = fn
+	}
+}
+
+// IPPoolsFunc returns the CIDRs of the IP pools currently known to the caller, for example from a
+// cache of the IPPool resources.
+type IPPoolsFunc func() []cnet.IPNet
+
+// WithPodCIDRPools configures the processor, when usePodCIDR is enabled, to only send Blocks for
+// the PodCIDRs of a Node that fall within an IP pool, so that the host-local Blocks cannot
+// conflict with addresses allocated by Calico IPAM outside of the pools.  The pools are those
+// returned by the supplied function (which may be nil) along with the additional host-local
+// pools, which are the ranges from which the PodCIDRs are allocated but that are not configured
+// as IPPools.  A PodCIDR that is not contained within any pool is skipped with a warning, and a
+// Block previously sent for the PodCIDR is deleted.
+func WithPodCIDRPools(pools IPPoolsFunc, hostLocalPools ...cnet.IPNet) FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.checkPodCIDRPools = true
+		c.podCIDRPools = pools
+		c.hostLocalPools = hostLocalPools
+	}
+}
+
+// EmitNodeReadiness configures the processor to emit the readiness of a Node, taken from its Ready
+// condition, as a NodeReadiness HostConfigKey so that Felix may adjust the programming of the
+// endpoints on a Node that is not ready.  The value is one of cresources.NodeReady,
+// cresources.NodeNotReady or cresources.NodeReadinessUnknown (see cresources.FindNodeReadiness),
+// and is nil if the Node has no Ready condition.
+func EmitNodeReadiness() FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.emitNodeReadiness = true
+	}
+}
+
+// AddressMismatchPolicy determines how the FelixNodeUpdateProcessor handles a Node whose BGP
+// address differs from the InternalIP address in the Node spec addresses.
+type AddressMismatchPolicy int
+
+const (
+	// AddressMismatchIgnore uses the BGP address without reporting the mismatch.  This is the
+	// default.
+	AddressMismatchIgnore AddressMismatchPolicy = iota
+	// AddressMismatchWarn logs a warning, and uses the BGP address.
+	AddressMismatchWarn
+	// AddressMismatchError drops the BGP address with a conversion error wrapping
+	// ErrNodeAddressMismatch.  As for an invalid BGP address, the InternalIP address is then used.
+	AddressMismatchError
+)
+
+// WithAddressMismatchPolicy configures how the processor handles a Node whose BGP IPv4Address or
+// IPv6Address differs from the first InternalIP address of the same family in the Node spec
+// addresses.  The addresses are compared without their prefix lengths, and there is no mismatch
+// if the Node has no InternalIP address of the family.
+func WithAddressMismatchPolicy(policy AddressMismatchPolicy) FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.addressMismatchPolicy = policy
+	}
+}
+
+// HostnameNormalizer converts a Node name into the hostname used in the v1 keys, for example
+// by lowercasing the name or by stripping a domain suffix.
+type HostnameNormalizer func(name string) string
+
+// WithHostnameNormalizer configures the processor to normalize the Node name before using it
+// as the hostname in the v1 keys (the HostIPKey, HostConfigKeys, WireguardKey and the affinity
+// of PodCIDR blocks).  The v3 Node ResourceKey is sent unchanged.  By default the Node name is
+// used as is.
+func WithHostnameNormalizer(fn HostnameNormalizer) FelixNodeUpdateProcessorOption {
+	return func(c *FelixNodeUpdateProcessor) {
+		c.normalizeHostname = fn
+	}
+}
+
 // Create a new SyncerUpdateProcessor to sync Node data in v1 format for
 // consumption by Felix.
-func NewFelixNodeUpdateProcessor(usePodCIDR bool) watchersyncer.SyncerUpdateProcessor {
-	return &FelixNodeUpdateProcessor{
-		usePodCIDR:      usePodCIDR,
-		nodeCIDRTracker: newNodeCIDRTracker(),
+func NewFelixNodeUpdateProcessor(usePodCIDR bool, opts ...FelixNodeUpdateProcessorOption) watchersyncer.SyncerUpdateProcessor {
+	c := &FelixNodeUpdateProcessor{
+		usePodCIDR:            usePodCIDR,
+		ipipTunnelAddrKeyName: DefaultIPIPTunnelAddrKeyName,
+		nodeCIDRTracker:       newNodeCIDRTracker(),
+		vxlanTunnelTracker:    newNodeCIDRTracker(),
+		vxlanVNINodes:         map[string]bool{},
+		asNumberNodes:         map[string]bool{},
+		missingPodCIDRsSince:  map[string]time.Time{},
+		missingPodCIDRsWarned: map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // FelixNodeUpdateProcessor implements the SyncerUpdateProcessor interface.
 // This converts the v3 node configuration into the v1 data types consumed by confd.
 type FelixNodeUpdateProcessor struct {
-	usePodCIDR      bool
-	nodeCIDRTracker nodeCIDRTracker
+	usePodCIDR              bool
+	withholdResourceOnError bool
+	strictIPParsing         bool
+	strictNodeSpec          bool
+	deriveVXLANTunnelMAC    bool
+	emitNodeTaints          bool
+	emitNodeStatusAddresses bool
+	emitNodeAddresses       bool
+	emitNodePlatform        bool
+	emitNodeEncapsulation   bool
+	emitNodeDecommissioning bool
+	emitNodeLogLevel        bool
+	emitHostIPv6            bool
+	emitNodeReadiness       bool
+	addressMismatchPolicy   AddressMismatchPolicy
+	ipipTunnelAddrKeyName   string
+	normalizeHostname       HostnameNormalizer
+	conversionErrorHandler  NodeConversionErrorHandler
+	conversionCounter       NodeConversionCounter
+	nodeCIDRTracker         nodeCIDRTracker
+
+	// The names of the additional VXLAN tunnels emitted for each Node.  The nodeCIDRTracker
+	// tracks an arbitrary set of strings for each Node.
+	vxlanTunnelTracker nodeCIDRTracker
+
+	// The Nodes for which a VXLAN VNI has been emitted.
+	vxlanVNINodes map[string]bool
+
+	// The Nodes for which an AS number has been emitted.
+	asNumberNodes map[string]bool
+
+	// The Nodes that have been seen without any PodCIDRs, with the time that each was first seen
+	// without them, and the Nodes that have been warned about.
+	warnOnMissingPodCIDRs      bool
+	missingPodCIDRsGracePeriod time.Duration
+	missingPodCIDRsHandler     MissingPodCIDRsHandler
+	missingPodCIDRsSince       map[string]time.Time
+	missingPodCIDRsWarned      map[string]bool
+
+	// The pools containing the PodCIDRs for which Blocks are sent.
+	checkPodCIDRPools bool
+	podCIDRPools      IPPoolsFunc
+	hostLocalPools    []cnet.IPNet
 }
 
 func (c *FelixNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, error) {
-	// Extract the name.
+	name, node, kvps, err := c.convert(kvp)
+	if name == "" {
+		return nil, err
+	}
+	if c.usePodCIDR {
+		kvps = append(kvps, c.podCIDRUpdates(name, nodePodCIDRs(node), kvp.Revision)...)
+		c.checkMissingPodCIDRs(name, node)
+	}
+	if log.GetLevel() >= log.DebugLevel {
+		for _, u := range kvps {
+			log.WithField("node", name).Debugf("Node update: %s", model.FormatKVPair(u))
+		}
+	}
+	return kvps, err
+}
+
+// ProcessBatch implements the SyncerUpdateProcessorBatch interface.  The updates are converted
+// in order, but the PodCIDR tracker is only updated once for each Node in the batch, using the
+// PodCIDRs from the last update for that Node.  The Block updates for each Node follow the
+// converted updates.  Updates for intermediate PodCIDRs of a Node are therefore not sent,
+// but the resulting state is the same as processing the updates sequentially.
+func (c *FelixNodeUpdateProcessor) ProcessBatch(kvps []*model.KVPair) ([]*model.KVPair, error) {
+	type podCIDRs struct {
+		node     *apiv3.Node
+		cidrs    []string
+		revision string
+	}
+	var names []string
+	cidrsByName := map[string]podCIDRs{}
+	var updates []*model.KVPair
+	var firstErr error
+	for _, kvp := range kvps {
+		name, node, converted, err := c.convert(kvp)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if name == "" {
+			continue
+		}
+		updates = append(updates, converted...)
+		if _, ok := cidrsByName[name]; !ok {
+			names = append(names, name)
+		}
+		cidrsByName[name] = podCIDRs{node: node, cidrs: nodePodCIDRs(node), revision: kvp.Revision}
+	}
+	if c.usePodCIDR {
+		for _, name := range names {
+			pc := cidrsByName[name]
+			updates = append(updates, c.podCIDRUpdates(name, pc.cidrs, pc.revision)...)
+			c.checkMissingPodCIDRs(name, pc.node)
+		}
+	}
+	return updates, firstErr
+}
+
+// convert converts the Node update, excluding any Block updates for the Node PodCIDRs.  It
+// returns the (normalized) hostname of the Node and the Node resource (nil for a delete) along
+// with the converted updates.  If the update is not for a Node, the returned hostname is empty.
+func (c *FelixNodeUpdateProcessor) convert(kvp *model.KVPair) (string, *apiv3.Node, []*model.KVPair, error) {
+	// Extract the name, and the hostname used in the v1 keys.
 	name, err := c.extractName(kvp.Key)
 	if err != nil {
-		return nil, err
+		return "", nil, nil, err
+	}
+	hostname := name
+	if c.normalizeHostname != nil {
+		hostname = c.normalizeHostname(name)
 	}
 
 	// Extract the separate bits of BGP config - these are stored as separate keys in the
 	// v1 model.  For a delete these will all be nil.  If we fail to convert any value then
 	// just treat that as a delete on the underlying key and return the error alongside
 	// the updates.
-	var ipv4, ipv6, ipv4Tunl, vxlanTunlIpv4, vxlanTunlIpv6, vxlanTunlMacV4, vxlanTunlMacV6, wgConfig interface{}
+	var ipv4, ipv6, ipv4Tunl, vxlanTunlIpv4, vxlanTunlIpv6, vxlanTunlMacV4, vxlanTunlMacV6, wgConfig, taints, asNumber interface{}
+	statusAddrs := make([]interface{}, len(nodeStatusAddressKeys))
+	var nodeAddrs, nodeOS, nodeArch, encap, decommissioning, logLevel, readiness interface{}
+	var vxlanTunlAddrs map[string]interface{}
+	var vxlanVNI interface{}
 	var node *apiv3.Node
 	var ok bool
+
+	// Each dropped field is recorded as the conversion error, and reported to the conversion
+	// error handler, if any.  The last error is returned.
+	drop := func(e error) {
+		err = e
+		if ce, ok := e.(nodeConversionError); ok && c.conversionErrorHandler != nil {
+			c.conversionErrorHandler(name, ce.field, ce.reason, e)
+		}
+	}
 	if kvp.Value != nil {
 		node, ok = kvp.Value.(*apiv3.Node)
 		if !ok {
-			return nil, errors.New("Incorrect value type - expecting resource of kind Node")
+			return "", nil, nil, errors.New("Incorrect value type - expecting resource of kind Node")
 		}
 
 		if bgp := node.Spec.BGP; bgp != nil {
 			var ip *cnet.IP
 			var cidr *cnet.IPNet
+			var parseErr, mismatchErr error
 
 			// Parse the IPv4 address, Felix expects this as a HostIPKey.  If we fail to parse then
 			// treat as a delete (i.e. leave ipv4 as nil).
 			if len(bgp.IPv4Address) != 0 {
-				ip, cidr, err = cnet.ParseCIDROrIP(bgp.IPv4Address)
-				if err == nil {
+				ip, cidr, parseErr = c.parseCIDROrIP(bgp.IPv4Address)
+				if parseErr == nil {
+					mismatchErr = c.addressMismatchError(node, ip, "Spec.BGP.IPv4Address")
+				}
+				if mismatchErr != nil {
+					drop(mismatchErr)
+					c.countField(NodeFieldIPv4, false)
+				} else if parseErr == nil {
 					log.WithFields(log.Fields{"ip": ip, "cidr": cidr}).Debug("Parsed IPv4 address")
 					ipv4 = ip
+					c.countField(NodeFieldIPv4, true)
 				} else {
-					log.WithError(err).WithField("IPv4Address", bgp.IPv4Address).Warn("Failed to parse IPv4Address")
+					log.WithError(parseErr).WithField("IPv4Address", bgp.IPv4Address).Warn("Failed to parse IPv4Address")
+					drop(newNodeConversionError(ErrNodeInvalidIPv4Address, "Spec.BGP.IPv4Address", DropReasonInvalidIP, "failed to parse IPv4Address: %v", parseErr))
+					c.countField(NodeFieldIPv4, false)
 				}
 			}
 			if len(bgp.IPv6Address) != 0 {
-				ip, cidr, err = cnet.ParseCIDROrIP(bgp.IPv6Address)
-				if err == nil {
+				ip, cidr, parseErr = c.parseCIDROrIP(bgp.IPv6Address)
+				mismatchErr = nil
+				if parseErr == nil && ip.Version() == 6 {
+					mismatchErr = c.addressMismatchError(node, ip, "Spec.BGP.IPv6Address")
+				}
+				if parseErr == nil && ip.Version() != 6 {
+					log.WithField("IPv6Address", bgp.IPv6Address).Warn("IPv6Address is not an IPv6 address")
+					drop(newNodeConversionError(ErrNodeInvalidIPv6Address, "Spec.BGP.IPv6Address", DropReasonWrongFamily, "IPv6Address is not an IPv6 address"))
+					c.countField(NodeFieldIPv6, false)
+				} else if mismatchErr != nil {
+					drop(mismatchErr)
+					c.countField(NodeFieldIPv6, false)
+				} else if parseErr == nil {
 					log.WithFields(log.Fields{"ip": ip, "cidr": cidr}).Debug("Parsed IPv6 address")
-					ipv4 = ip
+					ipv6 = ip
+					c.countField(NodeFieldIPv6, true)
 				} else {
-					log.WithError(err).WithField("IPv6Address", bgp.IPv6Address).Warn("Failed to parse IPv6Address")
+					log.WithError(parseErr).WithField("IPv6Address", bgp.IPv6Address).Warn("Failed to parse IPv6Address")
+					drop(newNodeConversionError(ErrNodeInvalidIPv6Address, "Spec.BGP.IPv6Address", DropReasonInvalidIP, "failed to parse IPv6Address: %v", parseErr))
+					c.countField(NodeFieldIPv6, false)
 				}
 			}
 
@@ -95,12 +737,30 @@ func (c *FelixNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair,
 			// treat as a delete (i.e. leave ipv4Tunl as nil).
 			if len(bgp.IPv4IPIPTunnelAddr) != 0 {
 				ip := cnet.ParseIP(bgp.IPv4IPIPTunnelAddr)
-				if ip != nil {
+				if ip != nil && ip.Version() != 4 {
+					log.WithField("IPv4IPIPTunnelAddr", bgp.IPv4IPIPTunnelAddr).Warn("IPv4IPIPTunnelAddr is not an IPv4 address")
+					drop(newNodeConversionError(ErrNodeInvalidIPIPTunnelAddr, "Spec.BGP.IPv4IPIPTunnelAddr", DropReasonWrongFamily, "IPv4IPIPTunnelAddr is not an IPv4 address, IPIP is only supported for IPv4"))
+					c.countField(NodeFieldIPIP, false)
+				} else if ip != nil {
 					log.WithField("ip", ip).Debug("Parsed IPIP tunnel address")
 					ipv4Tunl = ip.String()
+					c.countField(NodeFieldIPIP, true)
 				} else {
 					log.WithField("IPv4IPIPTunnelAddr", bgp.IPv4IPIPTunnelAddr).Warn("Failed to parse IPv4IPIPTunnelAddr")
-					err = fmt.Errorf("failed to parsed IPv4IPIPTunnelAddr as an IP address")
+					drop(newNodeConversionError(ErrNodeInvalidIPIPTunnelAddr, "Spec.BGP.IPv4IPIPTunnelAddr", DropReasonInvalidIP, "failed to parsed IPv4IPIPTunnelAddr as an IP address"))
+					c.countField(NodeFieldIPIP, false)
+				}
+			}
+
+			// The AS number is a 4-byte value, but 0 is reserved and may not be used.  If the
+			// AS number is not set then the node uses the global AS number, so leave asNumber
+			// as nil.
+			if bgp.ASNumber != nil {
+				if *bgp.ASNumber == 0 {
+					log.WithField("ASNumber", *bgp.ASNumber).Warn("Invalid ASNumber")
+					drop(newNodeConversionError(ErrNodeInvalidASNumber, "Spec.BGP.ASNumber", DropReasonOutOfRange, "ASNumber 0 is reserved"))
+				} else {
+					asNumber = bgp.ASNumber.String()
 				}
 			}
 		}
@@ -118,13 +778,13 @@ func (c *FelixNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair,
 			}
 		}
 		if ipv6 == nil {
-			ip, _ := cresources.FindNodeAddress(node, apiv3.InternalIP)
+			ip, _ := cresources.FindNodeIPv6Address(node, apiv3.InternalIP)
 			if ip != nil {
 				ipv6 = ip
 			}
 		}
 		if ipv6 == nil {
-			ip, _ := cresources.FindNodeAddress(node, apiv3.ExternalIP)
+			ip, _ := cresources.FindNodeIPv6Address(node, apiv3.ExternalIP)
 			if ip != nil {
 				ipv6 = ip
 			}
@@ -132,14 +792,22 @@ func (c *FelixNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair,
 
 		// Parse the IPv4 VXLAN tunnel address, Felix expects this as a HostConfigKey.  If we fail to parse then
 		// treat as a delete (i.e. leave ipv4Tunl as nil).
+		var vxlanTunlIPv4Addr *cnet.IP
 		if len(node.Spec.IPv4VXLANTunnelAddr) != 0 {
 			ip := cnet.ParseIP(node.Spec.IPv4VXLANTunnelAddr)
-			if ip != nil {
+			if ip != nil && ip.Version() != 4 {
+				log.WithField("IPv4VXLANTunnelAddr", node.Spec.IPv4VXLANTunnelAddr).Warn("IPv4VXLANTunnelAddr is not an IPv4 address")
+				drop(newNodeConversionError(ErrNodeInvalidVXLANTunnelAddr, "Spec.IPv4VXLANTunnelAddr", DropReasonWrongFamily, "IPv4VXLANTunnelAddr is not an IPv4 address"))
+				c.countField(NodeFieldVXLANV4, false)
+			} else if ip != nil {
 				log.WithField("ip", ip).Debug("Parsed VXLAN tunnel IPv4 address")
 				vxlanTunlIpv4 = ip.String()
+				vxlanTunlIPv4Addr = ip
+				c.countField(NodeFieldVXLANV4, true)
 			} else {
 				log.WithField("IPv4VXLANTunnelAddr", node.Spec.IPv4VXLANTunnelAddr).Warn("Failed to parse IPv4VXLANTunnelAddr")
-				err = fmt.Errorf("failed to parsed IPv4VXLANTunnelAddr as an IP address")
+				drop(newNodeConversionError(ErrNodeInvalidVXLANTunnelAddr, "Spec.IPv4VXLANTunnelAddr", DropReasonInvalidIP, "failed to parsed IPv4VXLANTunnelAddr as an IP address"))
+				c.countField(NodeFieldVXLANV4, false)
 			}
 		}
 
@@ -147,73 +815,222 @@ func (c *FelixNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair,
 		// treat as a delete (i.e. leave ipv4Tunl as nil).
 		if len(node.Spec.IPv6VXLANTunnelAddr) != 0 {
 			ip := cnet.ParseIP(node.Spec.IPv6VXLANTunnelAddr)
-			if ip != nil {
+			if ip != nil && ip.Version() != 6 {
+				log.WithField("IPv6VXLANTunnelAddr", node.Spec.IPv6VXLANTunnelAddr).Warn("IPv6VXLANTunnelAddr is not an IPv6 address")
+				drop(newNodeConversionError(ErrNodeInvalidVXLANTunnelAddr, "Spec.IPv6VXLANTunnelAddr", DropReasonWrongFamily, "IPv6VXLANTunnelAddr is not an IPv6 address"))
+				c.countField(NodeFieldVXLANV6, false)
+			} else if ip != nil {
 				log.WithField("ip", ip).Debug("Parsed VXLAN tunnel address")
 				vxlanTunlIpv6 = ip.String()
+				c.countField(NodeFieldVXLANV6, true)
 			} else {
 				log.WithField("IPv6VXLANTunnelAddr", node.Spec.IPv6VXLANTunnelAddr).Warn("Failed to parse IPv6VXLANTunnelAddr")
-				err = fmt.Errorf("failed to parsed IPv6VXLANTunnelAddr as an IP address")
+				drop(newNodeConversionError(ErrNodeInvalidVXLANTunnelAddr, "Spec.IPv6VXLANTunnelAddr", DropReasonInvalidIP, "failed to parsed IPv6VXLANTunnelAddr as an IP address"))
+				c.countField(NodeFieldVXLANV6, false)
 			}
 		}
 
-		// Parse the VXLAN tunnel MAC address, Felix expects this as a HostConfigKey.  If we fail to parse then
-		// treat as a delete (i.e. leave ipv4Tunl as nil).
+		// Parse the VXLAN tunnel MAC address, Felix expects this as a HostConfigKey in the canonical colon-separated
+		// form.  If we fail to parse then treat as a delete (i.e. leave ipv4Tunl as nil).
 		if len(node.Spec.VXLANTunnelMACV4Addr) != 0 {
 			macV4 := node.Spec.VXLANTunnelMACV4Addr
-			if macV4 != "" {
-				log.WithField("mac v4 addr", macV4).Debug("Parsed VXLAN tunnel MAC V4 address")
-				vxlanTunlMacV4 = macV4
+			if mac, parseErr := cresources.CanonicalizeMAC(macV4); parseErr == nil {
+				log.WithField("mac v4 addr", mac).Debug("Parsed VXLAN tunnel MAC V4 address")
+				vxlanTunlMacV4 = mac
+				c.countField(NodeFieldVXLANMACV4, true)
 			} else {
-				log.WithField("VXLANTunnelMACV4Addr", node.Spec.VXLANTunnelMACV4Addr).Warn("VXLANTunnelMACV4Addr not populated")
-				err = fmt.Errorf("failed to update VXLANTunnelMACAddr")
+				log.WithField("VXLANTunnelMACV4Addr", node.Spec.VXLANTunnelMACV4Addr).Warn("Failed to parse VXLANTunnelMACV4Addr")
+				drop(newNodeConversionError(ErrNodeInvalidVXLANTunnelMAC, "Spec.VXLANTunnelMACV4Addr", DropReasonInvalidMAC, "failed to parse VXLANTunnelMACV4Addr as a MAC address"))
+				c.countField(NodeFieldVXLANMACV4, false)
+			}
+		} else if c.deriveVXLANTunnelMAC && vxlanTunlIPv4Addr != nil {
+			if mac := deriveVXLANTunnelMAC(*vxlanTunlIPv4Addr); mac != nil {
+				log.WithField("mac v4 addr", mac).Debug("Derived VXLAN tunnel MAC V4 address")
+				vxlanTunlMacV4 = mac.String()
 			}
 		}
 
 		if len(node.Spec.VXLANTunnelMACV6Addr) != 0 {
 			macV6 := node.Spec.VXLANTunnelMACV6Addr
-			if macV6 != "" {
-				log.WithField("mac v6 addr", macV6).Debug("Parsed VXLAN tunnel MAC V6 address")
-				vxlanTunlMacV6 = macV6
+			if mac, parseErr := cresources.CanonicalizeMAC(macV6); parseErr == nil {
+				log.WithField("mac v6 addr", mac).Debug("Parsed VXLAN tunnel MAC V6 address")
+				vxlanTunlMacV6 = mac
+				c.countField(NodeFieldVXLANMACV6, true)
+			} else {
+				log.WithField("VXLANTunnelMACV6Addr", node.Spec.VXLANTunnelMACV6Addr).Warn("Failed to parse VXLANTunnelMACV6Addr")
+				drop(newNodeConversionError(ErrNodeInvalidVXLANTunnelMAC, "Spec.VXLANTunnelMACV6Addr", DropReasonInvalidMAC, "failed to parse VXLANTunnelMACV6Addr as a MAC address"))
+				c.countField(NodeFieldVXLANMACV6, false)
+			}
+		}
+
+		// Parse the additional VXLAN tunnel addresses, Felix expects each as a HostConfigKey.  If we
+		// fail to parse an address then treat as a delete of the key for that tunnel.
+		if len(node.Spec.VXLANTunnelAddrs) != 0 {
+			vxlanTunlAddrs = make(map[string]interface{}, len(node.Spec.VXLANTunnelAddrs))
+			for i, t := range node.Spec.VXLANTunnelAddrs {
+				if ip := cnet.ParseIP(t.Address); ip != nil {
+					log.WithFields(log.Fields{"name": t.Name, "ip": ip}).Debug("Parsed VXLAN tunnel address")
+					vxlanTunlAddrs[t.Name] = ip.String()
+				} else {
+					field := fmt.Sprintf("Spec.VXLANTunnelAddrs[%d].Address", i)
+					log.WithField(field, t.Address).Warn("Failed to parse VXLAN tunnel address")
+					drop(newNodeConversionError(ErrNodeInvalidVXLANTunnelAddr, field, DropReasonInvalidIP, "failed to parse %s as an IP address", field))
+					vxlanTunlAddrs[t.Name] = nil
+				}
+			}
+		}
+
+		// Validate the VXLAN VNI.  If it is out of range then treat as a delete of the key, so
+		// that Felix falls back to the VNI in its configuration.
+		if node.Spec.VXLANVNI != nil {
+			if vni := *node.Spec.VXLANVNI; vni < 0 || vni > maxVXLANVNI {
+				log.WithField("VXLANVNI", vni).Warn("VXLANVNI is out of range")
+				drop(newNodeConversionError(ErrNodeInvalidVXLANVNI, "Spec.VXLANVNI", DropReasonOutOfRange, "VXLANVNI %d is not in the range 0-%d", vni, maxVXLANVNI))
 			} else {
-				log.WithField("VXLANTunnelMACV6Addr", node.Spec.VXLANTunnelMACV6Addr).Warn("VXLANTunnelMACV6Addr not populated")
-				err = fmt.Errorf("failed to update VXLANTunnelMACV6Addr")
+				vxlanVNI = strconv.Itoa(vni)
 			}
 		}
 
 		var wgIfaceIpv4Addr *cnet.IP
-		var wgPubKey string
+		var wgPubKey *model.WireguardPublicKey
 		if wgSpec := node.Spec.Wireguard; wgSpec != nil {
 			if len(wgSpec.InterfaceIPv4Address) != 0 {
 				wgIfaceIpv4Addr = cnet.ParseIP(wgSpec.InterfaceIPv4Address)
 				if wgIfaceIpv4Addr != nil {
 					log.WithField("InterfaceIPv4Addr", wgIfaceIpv4Addr).Debug("Parsed Wireguard interface address")
+					c.countField(NodeFieldWireguard, true)
 				} else {
 					log.WithField("InterfaceIPv4Addr", wgSpec.InterfaceIPv4Address).Warn("Failed to parse InterfaceIPv4Address")
-					err = fmt.Errorf("failed to parse InterfaceIPv4Address as an IP address")
+					drop(newNodeConversionError(ErrNodeInvalidWireguardInterfaceAddr, "Spec.Wireguard.InterfaceIPv4Address", DropReasonInvalidIP, "failed to parse InterfaceIPv4Address as an IP address"))
+					c.countField(NodeFieldWireguard, false)
 				}
 			}
 		}
-		if wgPubKey = node.Status.WireguardPublicKey; wgPubKey != "" {
-			_, err := wg.ParseKey(wgPubKey)
-			if err == nil {
-				log.WithField("public-key", wgPubKey).Debug("Parsed Wireguard public-key")
+		if key := node.Status.WireguardPublicKey; key != "" {
+			if parsed, parseErr := model.ParseWireguardPublicKey(key); parseErr == nil {
+				log.WithField("public-key", parsed).Debug("Parsed Wireguard public-key")
+				c.countField(NodeFieldWireguard, true)
+				wgPubKey = parsed
 			} else {
-				log.WithField("WireguardPublicKey", wgPubKey).Warn("Failed to parse Wireguard public-key")
-				err = fmt.Errorf("failed to parse PublicKey as Wireguard public-key")
+				log.WithField("WireguardPublicKey", key).Warn("Failed to parse Wireguard public-key")
+				drop(newNodeConversionError(ErrNodeInvalidWireguardPublicKey, "Status.WireguardPublicKey", DropReasonInvalidKey, "failed to parse PublicKey as Wireguard public-key"))
+				c.countField(NodeFieldWireguard, false)
 			}
 		}
 
 		// If either of interface address or public-key is set, set the WireguardKey value.
 		// If we failed to parse both the values, leave the WireguardKey value empty.
-		if wgIfaceIpv4Addr != nil || wgPubKey != "" {
-			wgConfig = &model.Wireguard{InterfaceIPv4Addr: wgIfaceIpv4Addr, PublicKey: wgPubKey}
+		if wgIfaceIpv4Addr != nil || wgPubKey != nil {
+			allowedIPs, allowedIPsErrs := wireguardAllowedIPs(node)
+			for _, e := range allowedIPsErrs {
+				drop(e)
+			}
+			wgConfig = &model.Wireguard{InterfaceIPv4Addr: wgIfaceIpv4Addr, PublicKey: wgPubKey, AllowedIPs: allowedIPs}
+		}
+
+		if c.emitNodeStatusAddresses {
+			for i, k := range nodeStatusAddressKeys {
+				var ip *cnet.IP
+				if k.version == 4 {
+					ip, _ = cresources.FindNodeIPv4Address(node, k.ipType)
+				} else {
+					ip, _ = cresources.FindNodeIPv6Address(node, k.ipType)
+				}
+				if ip != nil {
+					statusAddrs[i] = ip.String()
+				}
+			}
+		}
+
+		if c.emitNodeAddresses {
+			if addrs := formatNodeAddresses(node); addrs != "" {
+				nodeAddrs = addrs
+			}
+		}
+
+		if c.emitNodePlatform {
+			if osName, ok := nodePlatformLabel(node, nodeOSLabels); ok {
+				if knownNodeOSes[osName] {
+					nodeOS = osName
+				} else {
+					log.WithField("os", osName).Warn("Unknown Node operating system")
+					drop(newNodeConversionError(ErrNodeInvalidPlatform, "Labels", DropReasonOutOfRange, "unknown operating system %q", osName))
+				}
+			}
+			if arch, ok := nodePlatformLabel(node, nodeArchLabels); ok {
+				if knownNodeArches[arch] {
+					nodeArch = arch
+				} else {
+					log.WithField("arch", arch).Warn("Unknown Node architecture")
+					drop(newNodeConversionError(ErrNodeInvalidPlatform, "Labels", DropReasonOutOfRange, "unknown architecture %q", arch))
+				}
+			}
+		}
+
+		if c.emitNodeEncapsulation {
+			if e := nodeEncapsulation(ipv4Tunl != nil, vxlanTunlIpv4 != nil || vxlanTunlIpv6 != nil); e != "" {
+				encap = e
+			}
+		}
+
+		if c.emitNodeDecommissioning {
+			if v, ok := node.Annotations[apiv3.AnnotationNodeDecommissioning]; ok {
+				if d, parseErr := strconv.ParseBool(v); parseErr != nil {
+					log.WithField("decommissioning", v).Warn("Failed to parse Node decommissioning annotation")
+					drop(newNodeConversionError(ErrNodeInvalidDecommissioning, "Annotations", DropReasonOutOfRange, "failed to parse %s annotation %q as a boolean", apiv3.AnnotationNodeDecommissioning, v))
+				} else if d {
+					decommissioning = "true"
+				}
+			}
+		}
+
+		if c.emitNodeLogLevel {
+			if v, ok := node.Annotations[apiv3.AnnotationNodeLogLevel]; ok {
+				if level, known := nodeLogLevels[strings.ToLower(v)]; known {
+					logLevel = level
+				} else {
+					log.WithField("logLevel", v).Warn("Invalid Node log level annotation")
+					drop(newNodeConversionError(ErrNodeInvalidLogLevel, "Annotations", DropReasonOutOfRange, "%s annotation %q is not a valid log level", apiv3.AnnotationNodeLogLevel, v))
+				}
+			}
+		}
+
+		if c.emitNodeReadiness {
+			if r := cresources.FindNodeReadiness(node); r != "" {
+				if r != cresources.NodeReady {
+					log.WithFields(log.Fields{"node": name, "readiness": r}).Debug("Node is not ready")
+				}
+				readiness = r
+			}
+		}
+
+		if c.strictNodeSpec {
+			if data, marshalErr := json.Marshal(node.Spec); marshalErr == nil {
+				for _, field := range unknownNodeSpecFields(data) {
+					log.WithField("field", field).Warn("Node spec field is not handled")
+					drop(newNodeConversionError(ErrNodeUnknownSpecField, field, DropReasonUnknownField, "%s is not handled by the Node update processor", field))
+				}
+			} else {
+				log.WithError(marshalErr).Warn("Failed to serialize Node spec")
+			}
+		}
+
+		if c.emitNodeTaints && len(node.Spec.Taints) != 0 {
+			nodeTaints, taintsErrs := formatNodeTaints(node.Spec.Taints)
+			for _, e := range taintsErrs {
+				log.WithError(e).WithField("Taints", node.Spec.Taints).Warn("Failed to convert Node taint")
+				drop(e)
+			}
+			if nodeTaints != "" {
+				taints = nodeTaints
+			}
 		}
 	}
 
 	kvps := []*model.KVPair{
 		{
 			Key: model.HostIPKey{
-				Hostname: name,
+				Hostname: hostname,
 			},
 			Value:    ipv4,
 			Revision: kvp.Revision,
@@ -225,50 +1042,59 @@ func (c *FelixNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair,
 		//	Value:    ipv6,
 		//	Revision: kvp.Revision,
 		//},
+
+		// The HostConfigKeys are emitted in a stable order: the IPv4 keys and then the IPv6 keys,
+		// with the tunnel address of each family followed by the tunnel MAC address, and finally
+		// the AS number (if any).
 		{
 			Key: model.HostConfigKey{
-				Hostname: name,
-				Name:     "IpInIpTunnelAddr",
+				Hostname: hostname,
+				Name:     c.ipipTunnelAddrKeyName,
 			},
 			Value:    ipv4Tunl,
 			Revision: kvp.Revision,
 		},
 		{
 			Key: model.HostConfigKey{
-				Hostname: name,
-				Name:     "IPv4VXLANTunnelAddr",
+				Hostname: hostname,
+				Name:     IPv4VXLANTunnelAddrKeyName,
 			},
 			Value:    vxlanTunlIpv4,
 			Revision: kvp.Revision,
 		},
 		{
 			Key: model.HostConfigKey{
-				Hostname: name,
-				Name:     "IPv6VXLANTunnelAddr",
+				Hostname: hostname,
+				Name:     VXLANTunnelMACV4AddrKeyName,
 			},
-			Value:    vxlanTunlIpv6,
+			Value:    vxlanTunlMacV4,
 			Revision: kvp.Revision,
 		},
 		{
 			Key: model.HostConfigKey{
-				Hostname: name,
-				Name:     "VXLANTunnelMACV6Addr",
+				Hostname: hostname,
+				Name:     IPv6VXLANTunnelAddrKeyName,
 			},
-			Value:    vxlanTunlMacV6,
+			Value:    vxlanTunlIpv6,
 			Revision: kvp.Revision,
 		},
 		{
 			Key: model.HostConfigKey{
-				Hostname: name,
-				Name:     "VXLANTunnelMACV4Addr",
+				Hostname: hostname,
+				Name:     VXLANTunnelMACV6AddrKeyName,
 			},
-			Value:    vxlanTunlMacV4,
+			Value:    vxlanTunlMacV6,
 			Revision: kvp.Revision,
 		},
+	}
+	if u := c.asNumberUpdate(hostname, asNumber, kvp.Revision); u != nil {
+		kvps = append(kvps, u)
+	}
+	kvps = append(kvps, []*model.KVPair{
 		{
 			// Include the original node KVP info as a separate update. Note we do not use the node value here because
 			// a nil interface is different to a nil pointer. Felix and other code assumes a nil Value is a delete, so
-			// preserve that relationship here.
+			// preserve that relationship here.  The resource key is not normalized since it must match the resource.
 			Key: model.ResourceKey{
 				Name: name,
 				Kind: apiv3.KindNode,
@@ -278,62 +1104,686 @@ func (c *FelixNodeUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair,
 		},
 		{
 			Key: model.WireguardKey{
-				NodeName: name,
+				NodeName: hostname,
 			},
 			Value:    wgConfig,
 			Revision: kvp.Revision,
 		},
+	}...)
+
+	if c.emitNodeTaints {
+		kvps = append(kvps, &model.KVPair{
+			Key: model.HostConfigKey{
+				Hostname: hostname,
+				Name:     hostConfigNodeTaints,
+			},
+			Value:    taints,
+			Revision: kvp.Revision,
+		})
 	}
 
-	if c.usePodCIDR {
-		// If we're using host-local IPAM based off the Kubernetes node PodCIDR, then
-		// we need to send Blocks based on the CIDRs to felix.
-		log.Debug("Using pod cidr")
-		var currentPodCIDRs []string
-		if node != nil {
-			currentPodCIDRs = node.Status.PodCIDRs
-		}
-		toRemove := c.nodeCIDRTracker.SetNodeCIDRs(name, currentPodCIDRs)
-		log.Debugf("Current CIDRS: %s", currentPodCIDRs)
-		log.Debugf("Old CIDRS: %s", toRemove)
-
-		// Send deletes for any CIDRs which are no longer present.
-		for _, c := range toRemove {
-			_, cidr, err := cnet.ParseCIDR(c)
-			if err != nil {
-				log.WithError(err).WithField("CIDR", c).Warn("Failed to parse Node PodCIDR")
-				continue
-			}
+	if c.emitNodeStatusAddresses {
+		for i, k := range nodeStatusAddressKeys {
 			kvps = append(kvps, &model.KVPair{
-				Key:      model.BlockKey{CIDR: *cidr},
-				Value:    nil,
+				Key: model.HostConfigKey{
+					Hostname: hostname,
+					Name:     k.name,
+				},
+				Value:    statusAddrs[i],
 				Revision: kvp.Revision,
 			})
 		}
+	}
+
+	if c.emitNodeAddresses {
+		kvps = append(kvps, &model.KVPair{
+			Key: model.HostConfigKey{
+				Hostname: hostname,
+				Name:     hostConfigNodeAddresses,
+			},
+			Value:    nodeAddrs,
+			Revision: kvp.Revision,
+		})
+	}
+
+	if c.emitNodePlatform {
+		kvps = append(kvps,
+			&model.KVPair{
+				Key: model.HostConfigKey{
+					Hostname: hostname,
+					Name:     hostConfigNodeOS,
+				},
+				Value:    nodeOS,
+				Revision: kvp.Revision,
+			},
+			&model.KVPair{
+				Key: model.HostConfigKey{
+					Hostname: hostname,
+					Name:     hostConfigNodeArch,
+				},
+				Value:    nodeArch,
+				Revision: kvp.Revision,
+			},
+		)
+	}
+
+	if c.emitNodeEncapsulation {
+		kvps = append(kvps, &model.KVPair{
+			Key: model.HostConfigKey{
+				Hostname: hostname,
+				Name:     hostConfigNodeEncapsulation,
+			},
+			Value:    encap,
+			Revision: kvp.Revision,
+		})
+	}
+
+	if c.emitNodeDecommissioning {
+		kvps = append(kvps, &model.KVPair{
+			Key: model.HostConfigKey{
+				Hostname: hostname,
+				Name:     hostConfigNodeDecommissioning,
+			},
+			Value:    decommissioning,
+			Revision: kvp.Revision,
+		})
+	}
+
+	if c.emitNodeLogLevel {
+		kvps = append(kvps, &model.KVPair{
+			Key: model.HostConfigKey{
+				Hostname: hostname,
+				Name:     hostConfigNodeLogLevel,
+			},
+			Value:    logLevel,
+			Revision: kvp.Revision,
+		})
+	}
+
+	if c.emitHostIPv6 {
+		var hostIPv6 interface{}
+		if ip, ok := ipv6.(*cnet.IP); ok {
+			hostIPv6 = ip.String()
+		}
+		kvps = append(kvps, &model.KVPair{
+			Key: model.HostConfigKey{
+				Hostname: hostname,
+				Name:     hostConfigHostIPv6,
+			},
+			Value:    hostIPv6,
+			Revision: kvp.Revision,
+		})
+	}
+
+	if c.emitNodeReadiness {
+		kvps = append(kvps, &model.KVPair{
+			Key: model.HostConfigKey{
+				Hostname: hostname,
+				Name:     hostConfigNodeReadiness,
+			},
+			Value:    readiness,
+			Revision: kvp.Revision,
+		})
+	}
+
+	if u := c.vxlanVNIUpdate(hostname, vxlanVNI, kvp.Revision); u != nil {
+		kvps = append(kvps, u)
+	}
+
+	kvps = append(kvps, c.vxlanTunnelAddrUpdates(hostname, vxlanTunlAddrs, kvp.Revision)...)
+
+	if err != nil && c.withholdResourceOnError {
+		// The conversion failed part way through, so do not send the resource update.  This leaves
+		// the previous version of the resource in place downstream.
+		log.WithError(err).WithField("node", name).Info("Withholding Node resource update due to conversion error")
+		kvps = removeResourceKVPair(kvps)
+	}
+
+	return hostname, node, kvps, err
+}
+
+// The JSON names of the Node spec fields known to the processor, including fields that are known
+// but are not converted (such as the OrchRefs).  The fields of the BGP spec are listed separately.
+var (
+	knownNodeSpecFields = map[string]bool{
+		"bgp":                  true,
+		"ipv4VXLANTunnelAddr":  true,
+		"ipv6VXLANTunnelAddr":  true,
+		"vxlanTunnelMACV4Addr": true,
+		"vxlanTunnelMACV6Addr": true,
+		"vxlanTunnelAddrs":     true,
+		"vxlanVNI":             true,
+		"orchRefs":             true,
+		"wireguard":            true,
+		"addresses":            true,
+		"taints":               true,
+	}
+	knownNodeBGPSpecFields = map[string]bool{
+		"asNumber":                true,
+		"ipv4Address":             true,
+		"ipv6Address":             true,
+		"ipv4IPIPTunnelAddr":      true,
+		"routeReflectorClusterID": true,
+		"serviceClusterIPs":       true,
+	}
+)
+
+// unknownNodeSpecFields returns the paths of the fields set in the JSON serialization of a Node
+// spec that are not known to the processor, in sorted order.
+func unknownNodeSpecFields(data []byte) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		log.WithError(err).Warn("Failed to parse Node spec")
+		return nil
+	}
+	var unknown []string
+	for name, value := range fields {
+		if !knownNodeSpecFields[name] {
+			unknown = append(unknown, "Spec."+name)
+			continue
+		}
+		if name != "bgp" {
+			continue
+		}
+		var bgpFields map[string]json.RawMessage
+		if err := json.Unmarshal(value, &bgpFields); err != nil {
+			continue
+		}
+		for bgpName := range bgpFields {
+			if !knownNodeBGPSpecFields[bgpName] {
+				unknown = append(unknown, "Spec.bgp."+bgpName)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// vxlanVNIUpdate returns the HostConfigKey update for the VXLAN VNI of a Node, or nil if the Node
+// has no valid VNI and no VNI was previously emitted for it.
+func (c *FelixNodeUpdateProcessor) vxlanVNIUpdate(hostname string, vni interface{}, revision string) *model.KVPair {
+	if vni == nil {
+		if !c.vxlanVNINodes[hostname] {
+			return nil
+		}
+		delete(c.vxlanVNINodes, hostname)
+	} else {
+		c.vxlanVNINodes[hostname] = true
+	}
+	return &model.KVPair{
+		Key:      model.HostConfigKey{Hostname: hostname, Name: hostConfigVXLANVNI},
+		Value:    vni,
+		Revision: revision,
+	}
+}
+
+// asNumberUpdate returns the HostConfigKey update for the AS number of a Node, or nil if the Node
+// has no valid AS number and no AS number was previously emitted for it.
+func (c *FelixNodeUpdateProcessor) asNumberUpdate(hostname string, asNumber interface{}, revision string) *model.KVPair {
+	if asNumber == nil {
+		if !c.asNumberNodes[hostname] {
+			return nil
+		}
+		delete(c.asNumberNodes, hostname)
+	} else {
+		c.asNumberNodes[hostname] = true
+	}
+	return &model.KVPair{
+		Key:      model.HostConfigKey{Hostname: hostname, Name: ASNumberKeyName},
+		Value:    asNumber,
+		Revision: revision,
+	}
+}
+
+// vxlanTunnelAddrUpdates returns the HostConfigKey updates for the additional VXLAN tunnel addresses
+// of a Node, followed by deletes for the tunnels that have been removed since the last update.
+// The addrs map the name of each tunnel to its address, or to nil if the address is not valid.
+func (c *FelixNodeUpdateProcessor) vxlanTunnelAddrUpdates(hostname string, addrs map[string]interface{}, revision string) []*model.KVPair {
+	names := make([]string, 0, len(addrs))
+	for name := range addrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	removed := c.vxlanTunnelTracker.SetNodeCIDRs(hostname, names)
+
+	var kvps []*model.KVPair
+	for _, name := range names {
+		kvps = append(kvps, &model.KVPair{
+			Key:      model.HostConfigKey{Hostname: hostname, Name: VXLANTunnelAddrKeyPrefix + name},
+			Value:    addrs[name],
+			Revision: revision,
+		})
+	}
+	for _, name := range removed {
+		kvps = append(kvps, &model.KVPair{
+			Key:      model.HostConfigKey{Hostname: hostname, Name: VXLANTunnelAddrKeyPrefix + name},
+			Revision: revision,
+		})
+	}
+	return kvps
+}
+
+// nodeEncapsulation returns the NodeEncapsulation value for a Node with the given tunnel
+// addresses, or an empty string if the Node has neither.
+func nodeEncapsulation(ipip, vxlan bool) string {
+	switch {
+	case ipip && vxlan:
+		return NodeEncapsulationIPIPAndVXLAN
+	case ipip:
+		return NodeEncapsulationIPIP
+	case vxlan:
+		return NodeEncapsulationVXLAN
+	}
+	return ""
+}
+
+// formatNodeTaints returns the taints formatted as a comma-separated list of "key=value:Effect"
+// entries.  Taints with an unknown effect or an empty key are omitted and an error is returned
+// for each of them alongside the valid entries.
+func formatNodeTaints(taints []apiv3.NodeTaint) (string, []error) {
+	var entries []string
+	var errs []error
+	for _, t := range taints {
+		switch t.Effect {
+		case apiv3.TaintEffectNoSchedule, apiv3.TaintEffectPreferNoSchedule, apiv3.TaintEffectNoExecute:
+		default:
+			errs = append(errs, newNodeConversionError(ErrNodeInvalidTaint, "Spec.Taints", DropReasonOutOfRange, "taint %s has an invalid effect %q", t.Key, t.Effect))
+			continue
+		}
+		if t.Key == "" {
+			errs = append(errs, newNodeConversionError(ErrNodeInvalidTaint, "Spec.Taints", DropReasonInvalidKey, "taint with effect %s has no key", t.Effect))
+			continue
+		}
+		entry := t.Key
+		if t.Value != "" {
+			entry += "=" + t.Value
+		}
+		entries = append(entries, entry+":"+string(t.Effect))
+	}
+	return strings.Join(entries, ","), errs
+}
+
+// nodePlatformLabel returns the lowercased value of the first of the supplied labels that is set on
+// the Node.
+func nodePlatformLabel(node *apiv3.Node, labels []string) (string, bool) {
+	for _, l := range labels {
+		if v, ok := node.Labels[l]; ok && v != "" {
+			return strings.ToLower(v), true
+		}
+	}
+	return "", false
+}
+
+// formatNodeAddresses returns the unique InternalIP and ExternalIP addresses of the Node as a
+// sorted, comma-separated list.  Addresses that cannot be parsed are omitted.
+func formatNodeAddresses(node *apiv3.Node) string {
+	seen := map[string]bool{}
+	var ips []*cnet.IP
+	for _, addr := range node.Spec.Addresses {
+		if addr.Type != apiv3.InternalIP && addr.Type != apiv3.ExternalIP {
+			continue
+		}
+		ip, _, parseErr := cnet.ParseCIDROrIP(addr.Address)
+		if parseErr != nil {
+			log.WithError(parseErr).WithField("Address", addr.Address).Warn("Failed to parse Node address")
+			continue
+		}
+		if seen[ip.String()] {
+			continue
+		}
+		seen[ip.String()] = true
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		if ips[i].Version() != ips[j].Version() {
+			return ips[i].Version() < ips[j].Version()
+		}
+		return bytes.Compare(ips[i].To16(), ips[j].To16()) < 0
+	})
+	strs := make([]string, len(ips))
+	for i, ip := range ips {
+		strs[i] = ip.String()
+	}
+	return strings.Join(strs, ",")
+}
+
+// nodePodCIDRs returns the PodCIDRs of the Node, or nil if the Node is nil.
+func nodePodCIDRs(node *apiv3.Node) []string {
+	if node == nil {
+		return nil
+	}
+	return node.Status.PodCIDRs
+}
+
+// podCIDRUpdates updates the PodCIDR tracker with the current PodCIDRs of the Node and returns
+// the corresponding Block updates.
+func (c *FelixNodeUpdateProcessor) podCIDRUpdates(name string, currentPodCIDRs []string, revision string) []*model.KVPair {
+	// If we're using host-local IPAM based off the Kubernetes node PodCIDR, then
+	// we need to send Blocks based on the CIDRs to felix.
+	log.Debug("Using pod cidr")
+	var kvps []*model.KVPair
+	currentPodCIDRs = dedupePodCIDRs(name, currentPodCIDRs)
+	if c.checkPodCIDRPools {
+		currentPodCIDRs = c.pooledPodCIDRs(name, currentPodCIDRs)
+	}
+	toRemove := c.nodeCIDRTracker.SetNodeCIDRs(name, currentPodCIDRs)
+	log.Debugf("Current CIDRS: %s", currentPodCIDRs)
+	log.Debugf("Old CIDRS: %s", toRemove)
+
+	// A Node without PodCIDRs has either not been allocated any yet, in which case there is
+	// nothing to send, or has had them all removed, in which case the Blocks are deleted below.
+	if len(currentPodCIDRs) == 0 {
+		if len(toRemove) == 0 {
+			log.WithField("node", name).Debug("Node has no PodCIDRs yet")
+		} else {
+			log.WithFields(log.Fields{"node": name, "CIDRs": toRemove}).Info("Node PodCIDRs removed, deleting Blocks")
+		}
+	}
+
+	// Send deletes for any CIDRs which are no longer present.
+	for _, c := range toRemove {
+		_, cidr, err := cnet.ParseCIDR(c)
+		if err != nil {
+			log.WithError(err).WithField("CIDR", c).Warn("Failed to parse Node PodCIDR")
+			continue
+		}
+		kvps = append(kvps, &model.KVPair{
+			Key:      model.BlockKey{CIDR: *cidr},
+			Value:    nil,
+			Revision: revision,
+		})
+	}
+
+	// Send updates for any CIDRs which are still present.
+	for _, c := range currentPodCIDRs {
+		_, cidr, err := cnet.ParseCIDR(c)
+		if err != nil {
+			log.WithError(err).WithField("CIDR", c).Warn("Failed to parse Node PodCIDR")
+			continue
+		}
 
-		// Send updates for any CIDRs which are still present.
-		for _, c := range currentPodCIDRs {
+		aff := fmt.Sprintf("host:%s", name)
+		kvps = append(kvps, &model.KVPair{
+			Key:      model.BlockKey{CIDR: *cidr},
+			Value:    &model.AllocationBlock{CIDR: *cidr, Affinity: &aff},
+			Revision: revision,
+		})
+	}
+
+	return kvps
+}
+
+// pooledPodCIDRs returns the PodCIDRs of the Node that are contained within an IP pool or one of
+// the host-local pools (see WithPodCIDRPools).  A CIDR outside of all the pools is skipped with a
+// warning.  CIDRs that cannot be parsed are passed through unchanged.
+func (c *FelixNodeUpdateProcessor) pooledPodCIDRs(name string, podCIDRs []string) []string {
+	var pools []cnet.IPNet
+	if c.podCIDRPools != nil {
+		pools = c.podCIDRPools()
+	}
+	pools = append(pools[:len(pools):len(pools)], c.hostLocalPools...)
+
+	var pooled []string
+	for _, pc := range podCIDRs {
+		_, cidr, err := cnet.ParseCIDR(pc)
+		if err != nil || cidrInPools(*cidr, pools) {
+			pooled = append(pooled, pc)
+			continue
+		}
+		log.WithFields(log.Fields{"node": name, "CIDR": pc}).Warn("Node PodCIDR is not within any IP pool, skipping")
+	}
+	return pooled
+}
+
+// cidrInPools returns whether the CIDR is contained within any of the pools.
+func cidrInPools(cidr cnet.IPNet, pools []cnet.IPNet) bool {
+	ones, _ := cidr.Mask.Size()
+	for _, pool := range pools {
+		poolOnes, _ := pool.Mask.Size()
+		if pool.Version() == cidr.Version() && poolOnes <= ones && pool.Contains(cidr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMissingPodCIDRs tracks how long the Node has been without PodCIDRs and, if configured with
+// WarnOnMissingPodCIDRs, warns once the grace period has passed.  A nil Node is a delete, which
+// clears the tracking for the Node.
+func (c *FelixNodeUpdateProcessor) checkMissingPodCIDRs(name string, node *apiv3.Node) {
+	if !c.warnOnMissingPodCIDRs {
+		return
+	}
+	if node == nil || len(node.Status.PodCIDRs) != 0 {
+		delete(c.missingPodCIDRsSince, name)
+		delete(c.missingPodCIDRsWarned, name)
+		return
+	}
+	since, ok := c.missingPodCIDRsSince[name]
+	if !ok {
+		since = time.Now()
+		c.missingPodCIDRsSince[name] = since
+	}
+	missingFor := time.Since(since)
+	if c.missingPodCIDRsWarned[name] || missingFor < c.missingPodCIDRsGracePeriod {
+		return
+	}
+	c.missingPodCIDRsWarned[name] = true
+	log.WithFields(log.Fields{"node": name, "missingFor": missingFor}).Warn("Node has no PodCIDRs but usePodCIDR is enabled")
+	if c.missingPodCIDRsHandler != nil {
+		c.missingPodCIDRsHandler(name, missingFor)
+	}
+}
+
+// dedupePodCIDRs returns the PodCIDRs of the Node with duplicate and overlapping CIDRs removed,
+// so that no two Block updates for the Node cover the same addresses.  Exact duplicates are
+// dropped silently; a CIDR that overlaps an earlier CIDR is skipped with a warning.  CIDRs that
+// cannot be parsed are passed through unchanged.
+func dedupePodCIDRs(name string, podCIDRs []string) []string {
+	var deduped []string
+	var accepted []*cnet.IPNet
+	seen := map[string]bool{}
+	for _, c := range podCIDRs {
+		_, cidr, err := cnet.ParseCIDR(c)
+		if err != nil {
+			deduped = append(deduped, c)
+			continue
+		}
+		if seen[cidr.String()] {
+			log.WithFields(log.Fields{"node": name, "CIDR": c}).Debug("Ignoring duplicate Node PodCIDR")
+			continue
+		}
+		overlaps := false
+		for _, a := range accepted {
+			if a.Contains(cidr.IP) || cidr.Contains(a.IP) {
+				log.WithFields(log.Fields{"node": name, "CIDR": c, "overlaps": a.String()}).Warn(
+					"Ignoring Node PodCIDR that overlaps another PodCIDR")
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		seen[cidr.String()] = true
+		accepted = append(accepted, cidr)
+		deduped = append(deduped, c)
+	}
+	return deduped
+}
+
+// countField counts the outcome of converting a Node field with the conversion counter, if any.
+func (c *FelixNodeUpdateProcessor) countField(field NodeField, parsed bool) {
+	if c.conversionCounter == nil {
+		return
+	}
+	if parsed {
+		c.conversionCounter.IncParsed(field)
+	} else {
+		c.conversionCounter.IncDropped(field)
+	}
+}
+
+// parseCIDROrIP parses a BGP address, using strict parsing if configured.
+func (c *FelixNodeUpdateProcessor) parseCIDROrIP(addr string) (*cnet.IP, *cnet.IPNet, error) {
+	if c.strictIPParsing {
+		return cnet.ParseCIDROrIPStrict(addr)
+	}
+	return cnet.ParseCIDROrIP(addr)
+}
+
+// addressMismatchError compares the BGP address of the Node with the first InternalIP address of
+// the same family in the Node spec addresses, and handles a mismatch according to the address
+// mismatch policy (see WithAddressMismatchPolicy).  Returns a conversion error for the BGP address
+// field if the address should be dropped.
+func (c *FelixNodeUpdateProcessor) addressMismatchError(node *apiv3.Node, bgpIP *cnet.IP, field string) error {
+	if c.addressMismatchPolicy == AddressMismatchIgnore {
+		return nil
+	}
+	var internalIP *cnet.IP
+	if bgpIP.Version() == 4 {
+		internalIP, _ = cresources.FindNodeIPv4Address(node, apiv3.InternalIP)
+	} else {
+		internalIP, _ = cresources.FindNodeIPv6Address(node, apiv3.InternalIP)
+	}
+	if internalIP == nil || internalIP.Equal(bgpIP.IP) {
+		return nil
+	}
+	log.WithFields(log.Fields{
+		"node":       node.Name,
+		field:        bgpIP.String(),
+		"InternalIP": internalIP.String(),
+	}).Warn("Node BGP address does not match the Node InternalIP address")
+	if c.addressMismatchPolicy != AddressMismatchError {
+		return nil
+	}
+	return newNodeConversionError(ErrNodeAddressMismatch, field, DropReasonMismatch, "%s %s does not match the InternalIP address %s", field, bgpIP, internalIP)
+}
+
+// wireguardAllowedIPs returns the CIDRs that should be routed to the node over Wireguard.  These
+// are the node pod CIDRs and the IPIP and VXLAN tunnel addresses.  Entries that cannot be parsed
+// are omitted and an error is returned for each of them alongside the valid entries.
+func wireguardAllowedIPs(node *apiv3.Node) ([]cnet.IPNet, []error) {
+	var allowedIPs []cnet.IPNet
+	var errs []error
+	for _, c := range node.Status.PodCIDRs {
+		_, cidr, parseErr := cnet.ParseCIDR(c)
+		if parseErr != nil {
+			log.WithError(parseErr).WithField("CIDR", c).Warn("Failed to parse Node PodCIDR for Wireguard allowed IPs")
+			errs = append(errs, newNodeConversionError(ErrNodeInvalidPodCIDR, "Status.PodCIDRs", DropReasonInvalidIP, "failed to parse PodCIDR %s as a CIDR", c))
+			continue
+		}
+		allowedIPs = append(allowedIPs, *cidr)
+	}
+
+	var tunnelAddrs []string
+	if node.Spec.BGP != nil {
+		tunnelAddrs = append(tunnelAddrs, node.Spec.BGP.IPv4IPIPTunnelAddr)
+	}
+	tunnelAddrs = append(tunnelAddrs, node.Spec.IPv4VXLANTunnelAddr, node.Spec.IPv6VXLANTunnelAddr)
+	for _, addr := range tunnelAddrs {
+		if addr == "" {
+			continue
+		}
+		// Parse failures for the tunnel addresses are already reported by the main conversion.
+		if ip := cnet.ParseIP(addr); ip != nil {
+			allowedIPs = append(allowedIPs, *ip.Network())
+		}
+	}
+	return allowedIPs, errs
+}
+
+// Sync is restarting - nothing to do for this processor.
+func (c *FelixNodeUpdateProcessor) OnSyncerStarting() {
+	log.Debug("Sync starting called on Felix node update processor")
+}
+
+// Shutdown implements the SyncerUpdateProcessorShutdown interface.  It returns deletes for the
+// Blocks of all PodCIDRs tracked by the processor, followed by deletes for the AS numbers, the VXLAN
+// VNIs and the additional VXLAN tunnel addresses emitted for each Node, and clears the tracked
+// state.
+func (c *FelixNodeUpdateProcessor) Shutdown() []*model.KVPair {
+	var kvps []*model.KVPair
+	tracked := c.nodeCIDRTracker.RemoveAll()
+	for _, name := range sortedNodeNames(tracked) {
+		for _, c := range tracked[name] {
 			_, cidr, err := cnet.ParseCIDR(c)
 			if err != nil {
 				log.WithError(err).WithField("CIDR", c).Warn("Failed to parse Node PodCIDR")
 				continue
 			}
+			kvps = append(kvps, &model.KVPair{Key: model.BlockKey{CIDR: *cidr}})
+		}
+	}
 
-			aff := fmt.Sprintf("host:%s", name)
-			kvps = append(kvps, &model.KVPair{
-				Key:      model.BlockKey{CIDR: *cidr},
-				Value:    &model.AllocationBlock{CIDR: *cidr, Affinity: &aff},
-				Revision: kvp.Revision,
-			})
+	asNumberNodes := make([]string, 0, len(c.asNumberNodes))
+	for name := range c.asNumberNodes {
+		asNumberNodes = append(asNumberNodes, name)
+	}
+	sort.Strings(asNumberNodes)
+	for _, name := range asNumberNodes {
+		kvps = append(kvps, &model.KVPair{Key: model.HostConfigKey{Hostname: name, Name: ASNumberKeyName}})
+	}
+	c.asNumberNodes = map[string]bool{}
+
+	vniNodes := make([]string, 0, len(c.vxlanVNINodes))
+	for name := range c.vxlanVNINodes {
+		vniNodes = append(vniNodes, name)
+	}
+	sort.Strings(vniNodes)
+	for _, name := range vniNodes {
+		kvps = append(kvps, &model.KVPair{Key: model.HostConfigKey{Hostname: name, Name: hostConfigVXLANVNI}})
+	}
+	c.vxlanVNINodes = map[string]bool{}
+
+	tunnels := c.vxlanTunnelTracker.RemoveAll()
+	for _, name := range sortedNodeNames(tunnels) {
+		for _, tunnel := range tunnels[name] {
+			kvps = append(kvps, &model.KVPair{Key: model.HostConfigKey{Hostname: name, Name: VXLANTunnelAddrKeyPrefix + tunnel}})
 		}
 	}
+	return kvps
+}
 
-	return kvps, err
+// ProducedKeyTypes implements the SyncerUpdateProcessorKeyTypes interface.
+func (c *FelixNodeUpdateProcessor) ProducedKeyTypes() []string {
+	return keyTypeNames(
+		model.HostIPKey{},
+		model.HostConfigKey{},
+		model.WireguardKey{},
+		model.BlockKey{},
+		model.ResourceKey{},
+	)
 }
 
-// Sync is restarting - nothing to do for this processor.
-func (c *FelixNodeUpdateProcessor) OnSyncerStarting() {
-	log.Debug("Sync starting called on Felix node update processor")
+// vxlanTunnelMACPrefix is the prefix used for VXLAN tunnel MAC addresses derived from the
+// tunnel IP.  The first octet has the locally administered bit set and the multicast bit
+// clear, so derived addresses cannot clash with vendor assigned or multicast addresses.
+var vxlanTunnelMACPrefix = []byte{0x76, 0x78}
+
+// deriveVXLANTunnelMAC returns the MAC address derived from an IPv4 VXLAN tunnel address, or
+// nil if the address is not IPv4.  The MAC address is the fixed vxlanTunnelMACPrefix followed
+// by the four octets of the IP address.  This is stable, and since the IP address is embedded
+// in its entirety, distinct tunnel addresses always result in distinct MAC addresses.
+func deriveVXLANTunnelMAC(ip cnet.IP) net.HardwareAddr {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil
+	}
+	mac := make(net.HardwareAddr, 0, 6)
+	mac = append(mac, vxlanTunnelMACPrefix...)
+	return append(mac, ip4...)
+}
+
+// removeResourceKVPair returns the supplied KVPairs with any resource KVPairs removed.
+func removeResourceKVPair(kvps []*model.KVPair) []*model.KVPair {
+	filtered := kvps[:0]
+	for _, kvp := range kvps {
+		if _, ok := kvp.Key.(model.ResourceKey); !ok {
+			filtered = append(filtered, kvp)
+		}
+	}
+	return filtered
 }
 
 func (c *FelixNodeUpdateProcessor) extractName(k model.Key) (string, error) {
@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+var _ = Describe("Test the (Felix) Node update processor strict mode", func() {
+	It("should return the unknown fields of a Node spec", func() {
+		data := []byte(`{
+			"bgp": {"ipv4Address": "172.0.0.1/24", "futureBGPField": "a"},
+			"ipv4VXLANTunnelAddr": "192.168.0.1",
+			"futureField": {"a": "b"},
+			"anotherFutureField": true
+		}`)
+		Expect(unknownNodeSpecFields(data)).To(Equal([]string{
+			"Spec.anotherFutureField",
+			"Spec.bgp.futureBGPField",
+			"Spec.futureField",
+		}))
+	})
+
+	It("should know every field of the current Node spec", func() {
+		asn := numorstring.ASNumber(64512)
+		spec := apiv3.NodeSpec{
+			BGP: &apiv3.NodeBGPSpec{
+				ASNumber:                &asn,
+				IPv4Address:             "172.0.0.1/24",
+				IPv6Address:             "fd00::1/64",
+				IPv4IPIPTunnelAddr:      "192.168.0.1",
+				RouteReflectorClusterID: "255.0.0.1",
+				ServiceClusterIPs:       []apiv3.ServiceClusterIPBlock{{CIDR: "10.96.0.0/12"}},
+			},
+			IPv4VXLANTunnelAddr:  "192.168.1.1",
+			IPv6VXLANTunnelAddr:  "fd01::1",
+			VXLANTunnelMACV4Addr: "00:0a:74:9d:68:16",
+			VXLANTunnelMACV6Addr: "00:0a:74:9d:68:17",
+			VXLANTunnelAddrs:     []apiv3.NodeVXLANTunnelAddr{{Name: "pool1", Address: "192.168.3.1"}},
+			OrchRefs:             []apiv3.OrchRef{{Orchestrator: "k8s", NodeName: "mynode"}},
+			Wireguard:            &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "192.168.2.1"},
+			Addresses:            []apiv3.NodeAddress{{Address: "172.0.0.1"}},
+			Taints:               []apiv3.NodeTaint{{Key: "a", Effect: apiv3.TaintEffectNoSchedule}},
+		}
+		data, err := json.Marshal(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unknownNodeSpecFields(data)).To(BeEmpty())
+
+		By("converting the Node in strict mode without error")
+		node := apiv3.NewNode()
+		node.Name = "mynode"
+		node.Spec = spec
+		up := NewFelixNodeUpdateProcessor(false, StrictNodeSpec())
+		_, err = up.Process(&model.KVPair{Key: model.ResourceKey{Kind: apiv3.KindNode, Name: "mynode"}, Value: node})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should list the JSON name of every field of the Node spec types", func() {
+		for _, t := range []struct {
+			spec  interface{}
+			known map[string]bool
+		}{
+			{apiv3.NodeSpec{}, knownNodeSpecFields},
+			{apiv3.NodeBGPSpec{}, knownNodeBGPSpecFields},
+		} {
+			st := reflect.TypeOf(t.spec)
+			for i := 0; i < st.NumField(); i++ {
+				name := strings.Split(st.Field(i).Tag.Get("json"), ",")[0]
+				Expect(t.known).To(HaveKey(name), "%s.%s is not a known field", st.Name(), st.Field(i).Name)
+			}
+		}
+	})
+
+	It("should ignore a spec that cannot be parsed", func() {
+		Expect(unknownNodeSpecFields([]byte(`not-json`))).To(BeEmpty())
+	})
+})
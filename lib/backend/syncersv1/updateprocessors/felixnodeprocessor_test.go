@@ -15,18 +15,66 @@
 package updateprocessors_test
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
 	"github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
 )
 
+// testNodeKey is the key of the Node used by the tests of the individual processor options.
+var testNodeKey = model.ResourceKey{
+	Kind: apiv3.KindNode,
+	Name: "mynode",
+}
+
+// newTestNode returns a new Node with the name of testNodeKey.
+func newTestNode() *apiv3.Node {
+	res := apiv3.NewNode()
+	res.Name = testNodeKey.Name
+	return res
+}
+
+// processTestNode processes an update for the supplied Node, which must have been created with
+// newTestNode, with revision "abcde".
+func processTestNode(up watchersyncer.SyncerUpdateProcessor, res *apiv3.Node) ([]*model.KVPair, error) {
+	return up.Process(&model.KVPair{Key: testNodeKey, Value: res, Revision: "abcde"})
+}
+
+// findHostConfig returns the update for the named HostConfigKey of the test Node, or nil if there
+// is none.
+func findHostConfig(kvps []*model.KVPair, name string) *model.KVPair {
+	key := model.HostConfigKey{Hostname: testNodeKey.Name, Name: name}
+	for _, kvp := range kvps {
+		if kvp.Key == key {
+			return kvp
+		}
+	}
+	return nil
+}
+
+// hostConfigValues returns the values of the HostConfigKey updates keyed by name.
+func hostConfigValues(kvps []*model.KVPair) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, kvp := range kvps {
+		if k, ok := kvp.Key.(model.HostConfigKey); ok {
+			values[k.Name] = kvp.Value
+		}
+	}
+	return values
+}
+
 var _ = Describe("Test the (Felix) Node update processor", func() {
 	v3NodeKey1 := model.ResourceKey{
 		Kind: apiv3.KindNode,
@@ -160,7 +208,7 @@ var _ = Describe("Test the (Felix) Node update processor", func() {
 		expected = map[string]interface{}{
 			nodeMarker: res,
 			wireguardMarker: &model.Wireguard{
-				PublicKey: key,
+				PublicKey: model.MustParseWireguardPublicKey(key),
 			},
 		}
 		kvps, err = up.Process(&model.KVPair{
@@ -188,7 +236,7 @@ var _ = Describe("Test the (Felix) Node update processor", func() {
 			nodeMarker: res,
 			wireguardMarker: &model.Wireguard{
 				InterfaceIPv4Addr: &ip,
-				PublicKey:         key,
+				PublicKey:         model.MustParseWireguardPublicKey(key),
 			},
 		}
 		kvps, err = up.Process(&model.KVPair{
@@ -253,6 +301,124 @@ var _ = Describe("Test the (Felix) Node update processor", func() {
 		)
 	})
 
+	It("should populate the Wireguard allowed IPs", func() {
+		// wireguardValue returns the value of the WireguardKey update.
+		wireguardValue := func(kvps []*model.KVPair) interface{} {
+			for _, kvp := range kvps {
+				if _, ok := kvp.Key.(model.WireguardKey); ok {
+					return kvp.Value
+				}
+			}
+			Fail("No WireguardKey update")
+			return nil
+		}
+		key := "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="
+
+		By("converting a Node with Wireguard, pod CIDRs and tunnel addresses")
+		res := apiv3.NewNode()
+		res.Name = "mynode"
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4IPIPTunnelAddr: "192.100.100.100",
+		}
+		res.Spec.IPv4VXLANTunnelAddr = "192.200.200.200"
+		res.Spec.IPv6VXLANTunnelAddr = "fd00::1"
+		res.Status.WireguardPublicKey = key
+		res.Status.PodCIDRs = []string{"10.0.0.0/24", "fd10::/120"}
+		kvps, err := up.Process(&model.KVPair{
+			Key:   v3NodeKey1,
+			Value: res,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wireguardValue(kvps)).To(Equal(&model.Wireguard{
+			PublicKey: model.MustParseWireguardPublicKey(key),
+			AllowedIPs: []net.IPNet{
+				net.MustParseCIDR("10.0.0.0/24"),
+				net.MustParseCIDR("fd10::/120"),
+				net.MustParseCIDR("192.100.100.100/32"),
+				net.MustParseCIDR("192.200.200.200/32"),
+				net.MustParseCIDR("fd00::1/128"),
+			},
+		}))
+
+		By("converting a Node with an invalid pod CIDR")
+		res.Status.PodCIDRs = []string{"10.0.0.0/240", "fd10::/120"}
+		res.Spec.BGP = nil
+		res.Spec.IPv4VXLANTunnelAddr = ""
+		res.Spec.IPv6VXLANTunnelAddr = ""
+		kvps, err = up.Process(&model.KVPair{
+			Key:   v3NodeKey1,
+			Value: res,
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(wireguardValue(kvps)).To(Equal(&model.Wireguard{
+			PublicKey:  model.MustParseWireguardPublicKey(key),
+			AllowedIPs: []net.IPNet{net.MustParseCIDR("fd10::/120")},
+		}))
+
+		By("deleting the Node")
+		kvps, err = up.Process(&model.KVPair{
+			Key: v3NodeKey1,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wireguardValue(kvps)).To(BeNil())
+	})
+
+	It("should delete the WireguardKey when the Wireguard fields are cleared", func() {
+		// wireguardUpdate returns the WireguardKey update.
+		wireguardUpdate := func(kvps []*model.KVPair) *model.KVPair {
+			for _, kvp := range kvps {
+				if _, ok := kvp.Key.(model.WireguardKey); ok {
+					return kvp
+				}
+			}
+			Fail("No WireguardKey update")
+			return nil
+		}
+		key := "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="
+		process := func(res *apiv3.Node) *model.KVPair {
+			kvps, _ := up.Process(&model.KVPair{
+				Key:      v3NodeKey1,
+				Value:    res,
+				Revision: "1",
+			})
+			return wireguardUpdate(kvps)
+		}
+
+		By("converting a Node with Wireguard configured")
+		res := apiv3.NewNode()
+		res.Name = "mynode"
+		res.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "1.2.3.4"}
+		res.Status.WireguardPublicKey = key
+		Expect(process(res).Value).NotTo(BeNil())
+
+		By("clearing the interface address and public key")
+		res.Spec.Wireguard.InterfaceIPv4Address = ""
+		res.Status.WireguardPublicKey = ""
+		Expect(process(res)).To(Equal(&model.KVPair{
+			Key:      model.WireguardKey{NodeName: "mynode"},
+			Revision: "1",
+		}))
+
+		By("reconfiguring and then removing the Wireguard spec")
+		res.Spec.Wireguard.InterfaceIPv4Address = "1.2.3.4"
+		Expect(process(res).Value).NotTo(BeNil())
+		res.Spec.Wireguard = nil
+		Expect(process(res).Value).To(BeNil())
+
+		By("reconfiguring and then replacing the public key with an invalid key")
+		res.Status.WireguardPublicKey = key
+		Expect(process(res).Value).NotTo(BeNil())
+		res.Status.WireguardPublicKey = "not-a-valid-key"
+		Expect(process(res).Value).To(BeNil())
+
+		By("reconfiguring and then replacing the interface address with an invalid address")
+		res.Status.WireguardPublicKey = ""
+		res.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "1.2.3.4"}
+		Expect(process(res).Value).NotTo(BeNil())
+		res.Spec.Wireguard.InterfaceIPv4Address = "foo.bar"
+		Expect(process(res).Value).To(BeNil())
+	})
+
 	It("should fail to convert an invalid resource", func() {
 		By("trying to convert with the wrong key type")
 		res := apiv3.NewNode()
@@ -346,6 +512,220 @@ var _ = Describe("Test the (Felix) Node update processor", func() {
 			expected,
 		)
 	})
+
+	It("should report the key types it produces", func() {
+		kt, ok := up.(watchersyncer.SyncerUpdateProcessorKeyTypes)
+		Expect(ok).To(BeTrue())
+		Expect(kt.ProducedKeyTypes()).To(Equal([]string{
+			"HostIPKey", "HostConfigKey", "WireguardKey", "BlockKey", "ResourceKey",
+		}))
+	})
+	It("should emit the HostConfigKeys in a stable order", func() {
+		res := newTestNode()
+		asn := numorstring.ASNumber(64512)
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address:        "172.0.0.1/24",
+			IPv4IPIPTunnelAddr: "192.100.100.100",
+			ASNumber:           &asn,
+		}
+		res.Spec.IPv4VXLANTunnelAddr = "192.200.200.200"
+		res.Spec.VXLANTunnelMACV4Addr = "00:11:22:33:44:55"
+		res.Spec.IPv6VXLANTunnelAddr = "fd00::1"
+		res.Spec.VXLANTunnelMACV6Addr = "00:11:22:33:44:66"
+
+		for _, value := range []interface{}{res, nil} {
+			kvps, err := up.Process(&model.KVPair{Key: v3NodeKey1, Value: value})
+			Expect(err).NotTo(HaveOccurred())
+
+			var names []string
+			for _, kvp := range kvps {
+				if k, ok := kvp.Key.(model.HostConfigKey); ok {
+					names = append(names, k.Name)
+				}
+			}
+			Expect(names).To(Equal([]string{
+				"IpInIpTunnelAddr",
+				"IPv4VXLANTunnelAddr",
+				"VXLANTunnelMACV4Addr",
+				"IPv6VXLANTunnelAddr",
+				"VXLANTunnelMACV6Addr",
+				"AsNumber",
+			}))
+		}
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor with WithholdResourceOnError", func() {
+	up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.WithholdResourceOnError())
+
+	BeforeEach(func() {
+		up.OnSyncerStarting()
+	})
+
+	hasResourceKey := func(kvps []*model.KVPair) bool {
+		for _, kvp := range kvps {
+			if _, ok := kvp.Key.(model.ResourceKey); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	It("should include the resource when conversion succeeds", func() {
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address:        "1.2.3.4/24",
+			IPv4IPIPTunnelAddr: "192.100.100.100",
+		}
+		kvps, err := processTestNode(up, res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasResourceKey(kvps)).To(BeTrue())
+	})
+
+	It("should include the resource delete", func() {
+		kvps, err := up.Process(&model.KVPair{
+			Key:   testNodeKey,
+			Value: nil,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hasResourceKey(kvps)).To(BeTrue())
+	})
+
+	It("should omit the resource when a tunnel address fails to convert", func() {
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address:        "1.2.3.4/24",
+			IPv4IPIPTunnelAddr: "192.100.100.100/24",
+		}
+		kvps, err := processTestNode(up, res)
+		Expect(err).To(HaveOccurred())
+		Expect(hasResourceKey(kvps)).To(BeFalse())
+
+		// The remaining keys are still sent, with the failed field treated as a delete.
+		ip := net.MustParseIP("1.2.3.4")
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key:      model.HostIPKey{Hostname: "mynode"},
+			Value:    &ip,
+			Revision: "abcde",
+		}))
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key:      model.HostConfigKey{Hostname: "mynode", Name: "IpInIpTunnelAddr"},
+			Revision: "abcde",
+		}))
+	})
+
+	It("should omit the resource when the Wireguard interface address fails to convert", func() {
+		res := newTestNode()
+		res.Spec.Wireguard = &apiv3.NodeWireguardSpec{
+			InterfaceIPv4Address: "1.2.3.4/240",
+		}
+		kvps, err := processTestNode(up, res)
+		Expect(err).To(HaveOccurred())
+		Expect(hasResourceKey(kvps)).To(BeFalse())
+	})
+
+	It("should omit the resource when the Wireguard public key fails to parse", func() {
+		res := newTestNode()
+		res.Status.WireguardPublicKey = "not-a-valid-key"
+		kvps, err := processTestNode(up, res)
+		Expect(err).To(HaveOccurred())
+		Expect(hasResourceKey(kvps)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor with StrictIPParsing", func() {
+	up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.StrictIPParsing())
+
+	BeforeEach(func() {
+		up.OnSyncerStarting()
+	})
+
+	hostIPValue := func(kvps []*model.KVPair) interface{} {
+		for _, kvp := range kvps {
+			if _, ok := kvp.Key.(model.HostIPKey); ok {
+				return kvp.Value
+			}
+		}
+		Fail("No HostIPKey update")
+		return nil
+	}
+
+	It("should convert an unambiguous BGP address", func() {
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address: "10.0.0.1/24",
+		}
+		kvps, err := processTestNode(up, res)
+		Expect(err).NotTo(HaveOccurred())
+		ip := net.MustParseIP("10.0.0.1")
+		Expect(hostIPValue(kvps)).To(Equal(&ip))
+	})
+
+	It("should reject a BGP address with leading zeros", func() {
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address: "010.0.0.1/24",
+		}
+		kvps, err := processTestNode(up, res)
+		Expect(err).To(HaveOccurred())
+		Expect(hostIPValue(kvps)).To(BeNil())
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor with DeriveVXLANTunnelMAC", func() {
+	up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.DeriveVXLANTunnelMAC())
+
+	BeforeEach(func() {
+		up.OnSyncerStarting()
+	})
+
+	// processMAC processes a Node with the supplied VXLAN tunnel address and MAC and returns the
+	// value of the MAC HostConfigKey.
+	processMAC := func(up watchersyncer.SyncerUpdateProcessor, tunnelAddr, mac string) interface{} {
+		res := newTestNode()
+		res.Spec.IPv4VXLANTunnelAddr = tunnelAddr
+		res.Spec.VXLANTunnelMACV4Addr = mac
+		kvps, err := processTestNode(up, res)
+		Expect(err).NotTo(HaveOccurred())
+		kvp := findHostConfig(kvps, "VXLANTunnelMACV4Addr")
+		Expect(kvp).NotTo(BeNil(), "No VXLANTunnelMACV4Addr update")
+		return kvp.Value
+	}
+
+	It("should derive the MAC from the tunnel address", func() {
+		Expect(processMAC(up, "192.168.1.2", "")).To(Equal("76:78:c0:a8:01:02"))
+	})
+
+	It("should derive the same MAC for the same tunnel address", func() {
+		mac := processMAC(up, "10.0.0.1", "")
+		Expect(processMAC(up, "10.0.0.1", "")).To(Equal(mac))
+
+		By("deriving from a new processor")
+		other := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.DeriveVXLANTunnelMAC())
+		Expect(processMAC(other, "10.0.0.1", "")).To(Equal(mac))
+	})
+
+	It("should derive different MACs for different tunnel addresses", func() {
+		Expect(processMAC(up, "10.0.0.1", "")).NotTo(Equal(processMAC(up, "10.0.1.0", "")))
+	})
+
+	It("should use the configured MAC in preference to the derived MAC", func() {
+		Expect(processMAC(up, "10.0.0.1", "ee:ee:ee:ee:ee:ee")).To(Equal("ee:ee:ee:ee:ee:ee"))
+	})
+
+	It("should canonicalize the configured MAC", func() {
+		Expect(processMAC(up, "10.0.0.1", "EE-EE-EE-00-11-22")).To(Equal("ee:ee:ee:00:11:22"))
+		Expect(processMAC(up, "10.0.0.1", "eeee.ee00.1122")).To(Equal("ee:ee:ee:00:11:22"))
+		Expect(processMAC(up, "10.0.0.1", "eeeeee001122")).To(Equal("ee:ee:ee:00:11:22"))
+	})
+
+	It("should not derive a MAC when there is no tunnel address", func() {
+		Expect(processMAC(up, "", "")).To(BeNil())
+	})
+
+	It("should not derive a MAC unless configured to", func() {
+		Expect(processMAC(updateprocessors.NewFelixNodeUpdateProcessor(false), "10.0.0.1", "")).To(BeNil())
+	})
 })
 
 var _ = Describe("Test the (Felix) Node update processor with USE_POD_CIDR=true", func() {
@@ -415,6 +795,1325 @@ var _ = Describe("Test the (Felix) Node update processor with USE_POD_CIDR=true"
 		// And a remove for block 2.
 		assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: c2}, Value: nil})
 	})
+
+	// blockKeys returns the BlockKeys in the updates, in order.
+	blockKeys := func(kvps []*model.KVPair) []model.BlockKey {
+		var keys []model.BlockKey
+		for _, kvp := range kvps {
+			if k, ok := kvp.Key.(model.BlockKey); ok {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+
+	It("should only send one block for duplicate PodCIDRs", func() {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(true)
+		res := newTestNode()
+		res.Status.PodCIDRs = []string{"192.168.1.0/24", "192.168.1.0/24", "192.168.1.1/24"}
+		kvps, err := up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{{CIDR: net.MustParseCIDR("192.168.1.0/24")}}))
+
+		By("removing the duplicate")
+		res.Status.PodCIDRs = []string{"192.168.1.0/24"}
+		kvps, err = up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{{CIDR: net.MustParseCIDR("192.168.1.0/24")}}))
+	})
+
+	It("should delete the blocks of a node whose PodCIDRs are all removed", func() {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(true)
+		res := newTestNode()
+
+		By("sending no blocks for a node without PodCIDRs")
+		kvps, err := up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(BeEmpty())
+
+		By("sending blocks once the node has PodCIDRs")
+		res.Status.PodCIDRs = []string{"192.168.1.0/24", "fd10::/120"}
+		kvps, err = up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(HaveLen(2))
+
+		By("deleting the blocks when the PodCIDRs are removed")
+		res.Status.PodCIDRs = nil
+		kvps, err = up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{
+			{CIDR: net.MustParseCIDR("192.168.1.0/24")},
+			{CIDR: net.MustParseCIDR("fd10::/120")},
+		}))
+		assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: net.MustParseCIDR("192.168.1.0/24")}, Value: nil})
+		assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: net.MustParseCIDR("fd10::/120")}, Value: nil})
+
+		By("sending nothing further once the blocks are deleted")
+		kvps, err = up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(BeEmpty())
+	})
+
+	Describe("with WithPodCIDRPools", func() {
+		var pools []net.IPNet
+		poolsFn := func() []net.IPNet {
+			return pools
+		}
+		BeforeEach(func() {
+			pools = []net.IPNet{net.MustParseCIDR("192.168.0.0/16")}
+		})
+
+		process := func(up watchersyncer.SyncerUpdateProcessor, podCIDRs ...string) []*model.KVPair {
+			res := newTestNode()
+			res.Status.PodCIDRs = podCIDRs
+			kvps, err := up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+			Expect(err).NotTo(HaveOccurred())
+			return kvps
+		}
+
+		It("should send blocks for PodCIDRs within an IP pool", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WithPodCIDRPools(poolsFn))
+			kvps := process(up, "192.168.1.0/24")
+			aff := "host:mynode"
+			c := net.MustParseCIDR("192.168.1.0/24")
+			assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: c}, Value: &model.AllocationBlock{CIDR: c, Affinity: &aff}})
+		})
+
+		It("should skip PodCIDRs outside of every pool", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WithPodCIDRPools(poolsFn))
+			kvps := process(up, "10.0.1.0/24", "192.168.1.0/24", "192.0.0.0/8", "fd10::/120")
+			Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{{CIDR: net.MustParseCIDR("192.168.1.0/24")}}))
+		})
+
+		It("should accept PodCIDRs within a host-local pool", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WithPodCIDRPools(nil, net.MustParseCIDR("10.0.0.0/16")))
+			kvps := process(up, "10.0.1.0/24", "192.168.1.0/24")
+			Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{{CIDR: net.MustParseCIDR("10.0.1.0/24")}}))
+		})
+
+		It("should delete the block of a PodCIDR whose pool is removed", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WithPodCIDRPools(poolsFn))
+			process(up, "192.168.1.0/24")
+			pools = nil
+			kvps := process(up, "192.168.1.0/24")
+			Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{{CIDR: net.MustParseCIDR("192.168.1.0/24")}}))
+			assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: net.MustParseCIDR("192.168.1.0/24")}, Value: nil})
+		})
+	})
+
+	Describe("with WarnOnMissingPodCIDRs", func() {
+		var warned []string
+		handler := func(node string, missingFor time.Duration) {
+			warned = append(warned, node)
+		}
+		BeforeEach(func() {
+			warned = nil
+		})
+
+		process := func(up watchersyncer.SyncerUpdateProcessor, podCIDRs ...string) {
+			res := newTestNode()
+			res.Status.PodCIDRs = podCIDRs
+			_, err := up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("should not warn within the grace period", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WarnOnMissingPodCIDRs(time.Hour, handler))
+			process(up)
+			process(up)
+			Expect(warned).To(BeEmpty())
+		})
+
+		It("should warn once after the grace period", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WarnOnMissingPodCIDRs(10*time.Millisecond, handler))
+			process(up)
+			Expect(warned).To(BeEmpty())
+			time.Sleep(20 * time.Millisecond)
+			process(up)
+			process(up)
+			Expect(warned).To(Equal([]string{"mynode"}))
+
+			By("resetting once the node has PodCIDRs")
+			process(up, "192.168.1.0/24")
+			process(up)
+			Expect(warned).To(Equal([]string{"mynode"}))
+			time.Sleep(20 * time.Millisecond)
+			process(up)
+			Expect(warned).To(Equal([]string{"mynode", "mynode"}))
+		})
+
+		It("should not warn when usePodCIDR is disabled", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.WarnOnMissingPodCIDRs(0, handler))
+			process(up)
+			Expect(warned).To(BeEmpty())
+		})
+
+		It("should stop tracking a deleted node", func() {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WarnOnMissingPodCIDRs(10*time.Millisecond, handler))
+			process(up)
+			_, err := up.Process(&model.KVPair{Key: v3NodeKey1})
+			Expect(err).NotTo(HaveOccurred())
+			time.Sleep(20 * time.Millisecond)
+			process(up)
+			Expect(warned).To(BeEmpty())
+		})
+	})
+
+	It("should return deletes for all tracked blocks on shutdown", func() {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(true)
+		for _, name := range []string{"node2", "node1"} {
+			res := apiv3.NewNode()
+			res.Name = name
+			if name == "node1" {
+				res.Status.PodCIDRs = []string{"192.168.1.0/24", "fd10::/120"}
+			} else {
+				res.Status.PodCIDRs = []string{"192.168.2.0/24"}
+			}
+			_, err := up.Process(&model.KVPair{Key: model.ResourceKey{Kind: apiv3.KindNode, Name: name}, Value: res})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		sp := up.(watchersyncer.SyncerUpdateProcessorShutdown)
+		Expect(sp.Shutdown()).To(Equal([]*model.KVPair{
+			{Key: model.BlockKey{CIDR: net.MustParseCIDR("192.168.1.0/24")}},
+			{Key: model.BlockKey{CIDR: net.MustParseCIDR("fd10::/120")}},
+			{Key: model.BlockKey{CIDR: net.MustParseCIDR("192.168.2.0/24")}},
+		}))
+
+		By("checking the tracker is cleared")
+		Expect(sp.Shutdown()).To(BeEmpty())
+	})
+
+	It("should skip a PodCIDR contained within another PodCIDR", func() {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(true)
+		res := newTestNode()
+		res.Status.PodCIDRs = []string{"192.168.0.0/16", "192.168.1.0/24", "fd10::/120"}
+		kvps, err := up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{
+			{CIDR: net.MustParseCIDR("192.168.0.0/16")},
+			{CIDR: net.MustParseCIDR("fd10::/120")},
+		}))
+
+		By("skipping a PodCIDR that contains an earlier PodCIDR")
+		res.Status.PodCIDRs = []string{"192.168.1.0/24", "192.168.0.0/16"}
+		kvps, err = up.Process(&model.KVPair{Key: v3NodeKey1, Value: res})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blockKeys(kvps)).To(Equal([]model.BlockKey{
+			{CIDR: net.MustParseCIDR("192.168.0.0/16")},
+			{CIDR: net.MustParseCIDR("fd10::/120")},
+			{CIDR: net.MustParseCIDR("192.168.1.0/24")},
+		}))
+		assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: net.MustParseCIDR("192.168.0.0/16")}, Value: nil})
+		assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: net.MustParseCIDR("fd10::/120")}, Value: nil})
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor with WithIPIPTunnelAddrKeyName", func() {
+	// processTunnelAddr processes a Node with an IPIP tunnel address and returns the
+	// HostConfigKey updates keyed by name.
+	processTunnelAddr := func(up watchersyncer.SyncerUpdateProcessor) map[string]interface{} {
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address:        "172.0.0.1/24",
+			IPv4IPIPTunnelAddr: "192.100.100.100",
+		}
+		kvps, err := processTestNode(up, res)
+		Expect(err).NotTo(HaveOccurred())
+		return hostConfigValues(kvps)
+	}
+
+	It("should use the historical key name by default", func() {
+		Expect(updateprocessors.DefaultIPIPTunnelAddrKeyName).To(Equal("IpInIpTunnelAddr"))
+		values := processTunnelAddr(updateprocessors.NewFelixNodeUpdateProcessor(false))
+		Expect(values).To(HaveKeyWithValue("IpInIpTunnelAddr", "192.100.100.100"))
+	})
+
+	It("should use the configured key name", func() {
+		values := processTunnelAddr(updateprocessors.NewFelixNodeUpdateProcessor(false,
+			updateprocessors.WithIPIPTunnelAddrKeyName("IPIPTunnelAddr")))
+		Expect(values).To(HaveKeyWithValue("IPIPTunnelAddr", "192.100.100.100"))
+		Expect(values).NotTo(HaveKey("IpInIpTunnelAddr"))
+	})
+
+	It("should ignore an empty key name", func() {
+		values := processTunnelAddr(updateprocessors.NewFelixNodeUpdateProcessor(false,
+			updateprocessors.WithIPIPTunnelAddrKeyName("")))
+		Expect(values).To(HaveKeyWithValue("IpInIpTunnelAddr", "192.100.100.100"))
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor with WithHostnameNormalizer", func() {
+	v3NodeKey := model.ResourceKey{
+		Kind: apiv3.KindNode,
+		Name: "MyNode",
+	}
+	up := updateprocessors.NewFelixNodeUpdateProcessor(true, updateprocessors.WithHostnameNormalizer(strings.ToLower))
+
+	BeforeEach(func() {
+		up.OnSyncerStarting()
+	})
+
+	It("should use the normalized hostname in the v1 keys", func() {
+		res := apiv3.NewNode()
+		res.Name = "MyNode"
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address:        "1.2.3.4/24",
+			IPv4IPIPTunnelAddr: "192.100.100.100",
+		}
+		res.Status.PodCIDRs = []string{"192.168.1.0/24"}
+		kvps, err := up.Process(&model.KVPair{
+			Key:   v3NodeKey,
+			Value: res,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		ip := net.MustParseIP("1.2.3.4")
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key:   model.HostIPKey{Hostname: "mynode"},
+			Value: &ip,
+		}))
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key:   model.HostConfigKey{Hostname: "mynode", Name: "IpInIpTunnelAddr"},
+			Value: "192.100.100.100",
+		}))
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key: model.WireguardKey{NodeName: "mynode"},
+		}))
+		c := net.MustParseCIDR("192.168.1.0/24")
+		aff := "host:mynode"
+		assertBlockUpdate(kvps, &model.KVPair{Key: model.BlockKey{CIDR: c}, Value: &model.AllocationBlock{CIDR: c, Affinity: &aff}})
+
+		By("checking the resource key is not normalized")
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key:   v3NodeKey,
+			Value: res,
+		}))
+	})
+
+	It("should use the normalized hostname for a delete", func() {
+		kvps, err := up.Process(&model.KVPair{
+			Key: v3NodeKey,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key: model.HostIPKey{Hostname: "mynode"},
+		}))
+		Expect(kvps).To(ContainElement(&model.KVPair{
+			Key: v3NodeKey,
+		}))
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor tunnel keys", func() {
+	up := updateprocessors.NewFelixNodeUpdateProcessor(false)
+
+	// tunnelKeys returns the values of the HostConfigKey updates that are not deletes.
+	tunnelKeys := func(kvps []*model.KVPair) map[string]interface{} {
+		values := map[string]interface{}{}
+		for _, kvp := range kvps {
+			if k, ok := kvp.Key.(model.HostConfigKey); ok && kvp.Value != nil {
+				values[k.Name] = kvp.Value
+			}
+		}
+		return values
+	}
+
+	DescribeTable("should emit the tunnel keys for each field independently",
+		func(setField func(*apiv3.Node), expectErr bool, expected map[string]interface{}) {
+			res := newTestNode()
+			setField(res)
+			kvps, err := processTestNode(up, res)
+			if expectErr {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(tunnelKeys(kvps)).To(Equal(expected))
+		},
+		Entry("no tunnel fields", func(n *apiv3.Node) {}, false, map[string]interface{}{}),
+		Entry("IPIP IPv4 only", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "192.100.100.100"}
+		}, false, map[string]interface{}{"IpInIpTunnelAddr": "192.100.100.100"}),
+		Entry("VXLAN IPv4 only", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "192.200.200.200"
+		}, false, map[string]interface{}{"IPv4VXLANTunnelAddr": "192.200.200.200"}),
+		Entry("VXLAN IPv6 only", func(n *apiv3.Node) {
+			n.Spec.IPv6VXLANTunnelAddr = "fd00::1"
+		}, false, map[string]interface{}{"IPv6VXLANTunnelAddr": "fd00::1"}),
+		Entry("VXLAN MAC v4 only", func(n *apiv3.Node) {
+			n.Spec.VXLANTunnelMACV4Addr = "00:11:22:33:44:55"
+		}, false, map[string]interface{}{"VXLANTunnelMACV4Addr": "00:11:22:33:44:55"}),
+		Entry("VXLAN MAC v6 only", func(n *apiv3.Node) {
+			n.Spec.VXLANTunnelMACV6Addr = "00:11:22:33:44:66"
+		}, false, map[string]interface{}{"VXLANTunnelMACV6Addr": "00:11:22:33:44:66"}),
+		Entry("IPIP with an IPv6 address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "fd00::1"}
+		}, true, map[string]interface{}{}),
+		Entry("IPv4 VXLAN with an IPv6 address", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "fd00::1"
+		}, true, map[string]interface{}{}),
+		Entry("IPv6 VXLAN with an IPv4 address", func(n *apiv3.Node) {
+			n.Spec.IPv6VXLANTunnelAddr = "192.200.200.200"
+		}, true, map[string]interface{}{}),
+		Entry("IPv6 VXLAN with an IPv4 IPIP address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "192.100.100.100"}
+			n.Spec.IPv6VXLANTunnelAddr = "fd00::1"
+		}, false, map[string]interface{}{
+			"IpInIpTunnelAddr":    "192.100.100.100",
+			"IPv6VXLANTunnelAddr": "fd00::1",
+		}),
+	)
+})
+
+var _ = Describe("Test the (Felix) Node update processor optional HostConfigKeys", func() {
+	// Functions to set the fields of the test Node that are read by the options.
+	withTaints := func(taints ...apiv3.NodeTaint) func(*apiv3.Node) {
+		return func(n *apiv3.Node) { n.Spec.Taints = taints }
+	}
+	withAddresses := func(addrs ...apiv3.NodeAddress) func(*apiv3.Node) {
+		return func(n *apiv3.Node) { n.Spec.Addresses = addrs }
+	}
+	withBGPAndAddresses := func(bgp apiv3.NodeBGPSpec, addrs ...apiv3.NodeAddress) func(*apiv3.Node) {
+		return func(n *apiv3.Node) {
+			n.Spec.BGP = &bgp
+			n.Spec.Addresses = addrs
+		}
+	}
+	withConditions := func(conditions ...apiv3.NodeCondition) func(*apiv3.Node) {
+		return func(n *apiv3.Node) { n.Status.Conditions = conditions }
+	}
+	withLabels := func(labels map[string]string) func(*apiv3.Node) {
+		return func(n *apiv3.Node) { n.Labels = labels }
+	}
+	withTunnels := func(ipip, vxlanV4, vxlanV6 string) func(*apiv3.Node) {
+		return func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24", IPv4IPIPTunnelAddr: ipip}
+			n.Spec.IPv4VXLANTunnelAddr = vxlanV4
+			n.Spec.IPv6VXLANTunnelAddr = vxlanV6
+		}
+	}
+	withAnnotations := func(annotations map[string]string) func(*apiv3.Node) {
+		return func(n *apiv3.Node) { n.Annotations = annotations }
+	}
+	withAnnotation := func(key, value string) func(*apiv3.Node) {
+		return withAnnotations(map[string]string{key: value})
+	}
+
+	statusAddresses := withBGPAndAddresses(apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24"},
+		apiv3.NodeAddress{Address: "10.0.0.1", Type: apiv3.InternalIP},
+		apiv3.NodeAddress{Address: "fd00::1", Type: apiv3.InternalIP},
+		apiv3.NodeAddress{Address: "172.16.0.1/16", Type: apiv3.ExternalIP},
+	)
+	linuxLabels := withLabels(map[string]string{"kubernetes.io/os": "linux", "kubernetes.io/arch": "arm64"})
+	windowsBetaLabels := withLabels(map[string]string{"beta.kubernetes.io/os": "Windows", "beta.kubernetes.io/arch": "amd64"})
+	unknownOSLabels := withLabels(map[string]string{"kubernetes.io/os": "plan9", "kubernetes.io/arch": "amd64"})
+
+	// Each entry processes the test Node, modified by setField, with a processor configured with
+	// the option, and checks the named HostConfigKey update and the returned error.  A nil setField
+	// processes a delete of the Node instead, and a nil option checks that the key is not emitted
+	// by a processor that is not configured with the option.
+	DescribeTable("should emit the HostConfigKey for the option",
+		func(opt updateprocessors.FelixNodeUpdateProcessorOption, name string, setField func(*apiv3.Node), expected interface{}, expectedErr error) {
+			up := updateprocessors.NewFelixNodeUpdateProcessor(false)
+			if opt != nil {
+				up = updateprocessors.NewFelixNodeUpdateProcessor(false, opt)
+			}
+			kvp := &model.KVPair{Key: testNodeKey, Revision: "abcde"}
+			if setField != nil {
+				res := newTestNode()
+				setField(res)
+				kvp.Value = res
+			}
+			kvps, err := up.Process(kvp)
+			if expectedErr == nil {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(errors.Is(err, expectedErr)).To(BeTrue(), fmt.Sprintf("expected %v to be classified as %v", err, expectedErr))
+			}
+			if opt == nil {
+				Expect(findHostConfig(kvps, name)).To(BeNil())
+				return
+			}
+			Expect(findHostConfig(kvps, name)).To(Equal(&model.KVPair{
+				Key:      model.HostConfigKey{Hostname: testNodeKey.Name, Name: name},
+				Value:    expected,
+				Revision: "abcde",
+			}))
+		},
+
+		Entry("EmitNodeTaints: NoSchedule and NoExecute taints", updateprocessors.EmitNodeTaints(), "NodeTaints",
+			withTaints(
+				apiv3.NodeTaint{Key: "node-role.kubernetes.io/master", Effect: apiv3.TaintEffectNoSchedule},
+				apiv3.NodeTaint{Key: "dedicated", Value: "gpu", Effect: apiv3.TaintEffectNoExecute},
+			), "node-role.kubernetes.io/master:NoSchedule,dedicated=gpu:NoExecute", nil),
+		Entry("EmitNodeTaints: no taints", updateprocessors.EmitNodeTaints(), "NodeTaints", withTaints(), nil, nil),
+		Entry("EmitNodeTaints: invalid effect", updateprocessors.EmitNodeTaints(), "NodeTaints",
+			withTaints(
+				apiv3.NodeTaint{Key: "dedicated", Effect: "NoRun"},
+				apiv3.NodeTaint{Key: "node-role.kubernetes.io/master", Effect: apiv3.TaintEffectNoSchedule},
+			), "node-role.kubernetes.io/master:NoSchedule", updateprocessors.ErrNodeInvalidTaint),
+		Entry("EmitNodeTaints: delete", updateprocessors.EmitNodeTaints(), "NodeTaints", nil, nil, nil),
+		Entry("EmitNodeTaints: not configured", nil, "NodeTaints",
+			withTaints(apiv3.NodeTaint{Key: "dedicated", Value: "gpu", Effect: apiv3.TaintEffectNoExecute}), nil, nil),
+
+		Entry("EmitHostIPv6: IPv6 Internal address", updateprocessors.EmitHostIPv6(), "HostIPv6",
+			withAddresses(
+				apiv3.NodeAddress{Address: "10.0.0.1", Type: apiv3.InternalIP},
+				apiv3.NodeAddress{Address: "fd00::2", Type: apiv3.ExternalIP},
+				apiv3.NodeAddress{Address: "fd00::1/64", Type: apiv3.InternalIP},
+			), "fd00::1", nil),
+		Entry("EmitHostIPv6: IPv4 only Internal address", updateprocessors.EmitHostIPv6(), "HostIPv6",
+			withAddresses(apiv3.NodeAddress{Address: "10.0.0.1", Type: apiv3.InternalIP}), nil, nil),
+		Entry("EmitHostIPv6: BGP IPv6 address", updateprocessors.EmitHostIPv6(), "HostIPv6",
+			withBGPAndAddresses(apiv3.NodeBGPSpec{IPv6Address: "fd00::10/64"},
+				apiv3.NodeAddress{Address: "fd00::1", Type: apiv3.InternalIP},
+			), "fd00::10", nil),
+		Entry("EmitHostIPv6: BGP IPv6 address that is an IPv4 address", updateprocessors.EmitHostIPv6(), "HostIPv6",
+			withBGPAndAddresses(apiv3.NodeBGPSpec{IPv6Address: "10.0.0.10/24"}), nil, updateprocessors.ErrNodeInvalidIPv6Address),
+		Entry("EmitHostIPv6: delete", updateprocessors.EmitHostIPv6(), "HostIPv6", nil, nil, nil),
+		Entry("EmitHostIPv6: not configured", nil, "HostIPv6",
+			withAddresses(apiv3.NodeAddress{Address: "fd00::1", Type: apiv3.InternalIP}), nil, nil),
+
+		Entry("EmitNodeReadiness: Ready", updateprocessors.EmitNodeReadiness(), "NodeReadiness",
+			withConditions(
+				apiv3.NodeCondition{Type: "MemoryPressure", Status: apiv3.ConditionFalse},
+				apiv3.NodeCondition{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionTrue},
+			), "Ready", nil),
+		Entry("EmitNodeReadiness: NotReady", updateprocessors.EmitNodeReadiness(), "NodeReadiness",
+			withConditions(apiv3.NodeCondition{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionFalse, Reason: "KubeletNotReady"}),
+			"NotReady", nil),
+		Entry("EmitNodeReadiness: Unknown", updateprocessors.EmitNodeReadiness(), "NodeReadiness",
+			withConditions(apiv3.NodeCondition{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionUnknown, Reason: "NodeStatusUnknown"}),
+			"Unknown", nil),
+		Entry("EmitNodeReadiness: no Ready condition", updateprocessors.EmitNodeReadiness(), "NodeReadiness", withConditions(), nil, nil),
+		Entry("EmitNodeReadiness: delete", updateprocessors.EmitNodeReadiness(), "NodeReadiness", nil, nil, nil),
+		Entry("EmitNodeReadiness: not configured", nil, "NodeReadiness",
+			withConditions(apiv3.NodeCondition{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionTrue}), nil, nil),
+
+		Entry("EmitNodeStatusAddresses: Internal IPv4", updateprocessors.EmitNodeStatusAddresses(), "NodeInternalIPv4Addr", statusAddresses, "10.0.0.1", nil),
+		Entry("EmitNodeStatusAddresses: External IPv4", updateprocessors.EmitNodeStatusAddresses(), "NodeExternalIPv4Addr", statusAddresses, "172.16.0.1", nil),
+		Entry("EmitNodeStatusAddresses: Internal IPv6", updateprocessors.EmitNodeStatusAddresses(), "NodeInternalIPv6Addr", statusAddresses, "fd00::1", nil),
+		Entry("EmitNodeStatusAddresses: no External IPv6", updateprocessors.EmitNodeStatusAddresses(), "NodeExternalIPv6Addr", statusAddresses, nil, nil),
+		Entry("EmitNodeStatusAddresses: delete", updateprocessors.EmitNodeStatusAddresses(), "NodeInternalIPv4Addr", nil, nil, nil),
+		Entry("EmitNodeStatusAddresses: not configured", nil, "NodeInternalIPv4Addr", statusAddresses, nil, nil),
+
+		Entry("EmitNodeAddresses: sorted, deduplicated set", updateprocessors.EmitNodeAddresses(), "NodeAddresses",
+			withBGPAndAddresses(apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24"},
+				apiv3.NodeAddress{Address: "10.0.0.10", Type: apiv3.InternalIP},
+				apiv3.NodeAddress{Address: "fd00::2", Type: apiv3.InternalIP},
+				apiv3.NodeAddress{Address: "10.0.0.9/24", Type: apiv3.InternalIP},
+				apiv3.NodeAddress{Address: "172.16.0.1", Type: apiv3.ExternalIP},
+				apiv3.NodeAddress{Address: "fd00::1", Type: apiv3.ExternalIP},
+				apiv3.NodeAddress{Address: "10.0.0.10/32", Type: apiv3.ExternalIP},
+				apiv3.NodeAddress{Address: "not-an-ip", Type: apiv3.ExternalIP},
+				apiv3.NodeAddress{Address: "192.168.0.1", Type: "Other"},
+			), "10.0.0.9,10.0.0.10,172.16.0.1,fd00::1,fd00::2", nil),
+		Entry("EmitNodeAddresses: no addresses", updateprocessors.EmitNodeAddresses(), "NodeAddresses",
+			withBGPAndAddresses(apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24"}), nil, nil),
+		Entry("EmitNodeAddresses: delete", updateprocessors.EmitNodeAddresses(), "NodeAddresses", nil, nil, nil),
+		Entry("EmitNodeAddresses: not configured", nil, "NodeAddresses",
+			withAddresses(apiv3.NodeAddress{Address: "10.0.0.1", Type: apiv3.InternalIP}), nil, nil),
+
+		Entry("EmitNodePlatform: linux OS", updateprocessors.EmitNodePlatform(), "NodeOS", linuxLabels, "linux", nil),
+		Entry("EmitNodePlatform: linux architecture", updateprocessors.EmitNodePlatform(), "NodeArch", linuxLabels, "arm64", nil),
+		Entry("EmitNodePlatform: windows OS from the beta label", updateprocessors.EmitNodePlatform(), "NodeOS", windowsBetaLabels, "windows", nil),
+		Entry("EmitNodePlatform: architecture from the beta label", updateprocessors.EmitNodePlatform(), "NodeArch", windowsBetaLabels, "amd64", nil),
+		Entry("EmitNodePlatform: no OS label", updateprocessors.EmitNodePlatform(), "NodeOS", withLabels(nil), nil, nil),
+		Entry("EmitNodePlatform: no architecture label", updateprocessors.EmitNodePlatform(), "NodeArch", withLabels(nil), nil, nil),
+		Entry("EmitNodePlatform: unknown OS", updateprocessors.EmitNodePlatform(), "NodeOS", unknownOSLabels, nil, updateprocessors.ErrNodeInvalidPlatform),
+		Entry("EmitNodePlatform: architecture with unknown OS", updateprocessors.EmitNodePlatform(), "NodeArch", unknownOSLabels, "amd64", updateprocessors.ErrNodeInvalidPlatform),
+		Entry("EmitNodePlatform: delete", updateprocessors.EmitNodePlatform(), "NodeOS", nil, nil, nil),
+		Entry("EmitNodePlatform: not configured", nil, "NodeOS", linuxLabels, nil, nil),
+
+		Entry("EmitNodeEncapsulation: no tunnel addresses", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("", "", ""), nil, nil),
+		Entry("EmitNodeEncapsulation: IPIP only", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("192.168.0.1", "", ""), updateprocessors.NodeEncapsulationIPIP, nil),
+		Entry("EmitNodeEncapsulation: IPv4 VXLAN only", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("", "192.168.1.1", ""), updateprocessors.NodeEncapsulationVXLAN, nil),
+		Entry("EmitNodeEncapsulation: IPv6 VXLAN only", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("", "", "fd00::1"), updateprocessors.NodeEncapsulationVXLAN, nil),
+		Entry("EmitNodeEncapsulation: IPIP and VXLAN", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("192.168.0.1", "192.168.1.1", ""), updateprocessors.NodeEncapsulationIPIPAndVXLAN, nil),
+		Entry("EmitNodeEncapsulation: IPIP and IPv6 VXLAN", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("192.168.0.1", "", "fd00::1"), updateprocessors.NodeEncapsulationIPIPAndVXLAN, nil),
+		Entry("EmitNodeEncapsulation: invalid IPIP and valid VXLAN", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("not-an-ip", "192.168.1.1", ""), updateprocessors.NodeEncapsulationVXLAN, updateprocessors.ErrNodeInvalidIPIPTunnelAddr),
+		Entry("EmitNodeEncapsulation: invalid tunnel addresses", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation",
+			withTunnels("not-an-ip", "fd00::1", "192.168.1.1"), nil, updateprocessors.ErrNodeInvalidVXLANTunnelAddr),
+		Entry("EmitNodeEncapsulation: delete", updateprocessors.EmitNodeEncapsulation(), "NodeEncapsulation", nil, nil, nil),
+		Entry("EmitNodeEncapsulation: not configured", nil, "NodeEncapsulation",
+			withTunnels("192.168.0.1", "192.168.1.1", ""), nil, nil),
+
+		Entry("EmitNodeDecommissioning: decommissioning", updateprocessors.EmitNodeDecommissioning(), "NodeDecommissioning",
+			withAnnotation(apiv3.AnnotationNodeDecommissioning, "true"), "true", nil),
+		Entry("EmitNodeDecommissioning: not decommissioning", updateprocessors.EmitNodeDecommissioning(), "NodeDecommissioning",
+			withAnnotation(apiv3.AnnotationNodeDecommissioning, "false"), nil, nil),
+		Entry("EmitNodeDecommissioning: no annotation", updateprocessors.EmitNodeDecommissioning(), "NodeDecommissioning",
+			withAnnotation("other", "annotation"), nil, nil),
+		Entry("EmitNodeDecommissioning: invalid annotation", updateprocessors.EmitNodeDecommissioning(), "NodeDecommissioning",
+			withAnnotation(apiv3.AnnotationNodeDecommissioning, "soon"), nil, updateprocessors.ErrNodeInvalidDecommissioning),
+		Entry("EmitNodeDecommissioning: delete", updateprocessors.EmitNodeDecommissioning(), "NodeDecommissioning", nil, nil, nil),
+		Entry("EmitNodeDecommissioning: not configured", nil, "NodeDecommissioning",
+			withAnnotation(apiv3.AnnotationNodeDecommissioning, "true"), nil, nil),
+
+		Entry("EmitNodeLogLevel: DEBUG", updateprocessors.EmitNodeLogLevel(), "NodeLogLevel",
+			withAnnotation(apiv3.AnnotationNodeLogLevel, "DEBUG"), "Debug", nil),
+		Entry("EmitNodeLogLevel: Warning", updateprocessors.EmitNodeLogLevel(), "NodeLogLevel",
+			withAnnotation(apiv3.AnnotationNodeLogLevel, "Warning"), "Warning", nil),
+		Entry("EmitNodeLogLevel: no annotation", updateprocessors.EmitNodeLogLevel(), "NodeLogLevel", withAnnotations(nil), nil, nil),
+		Entry("EmitNodeLogLevel: invalid level", updateprocessors.EmitNodeLogLevel(), "NodeLogLevel",
+			withAnnotation(apiv3.AnnotationNodeLogLevel, "verbose"), nil, updateprocessors.ErrNodeInvalidLogLevel),
+		Entry("EmitNodeLogLevel: delete", updateprocessors.EmitNodeLogLevel(), "NodeLogLevel", nil, nil, nil),
+		Entry("EmitNodeLogLevel: not configured", nil, "NodeLogLevel",
+			withAnnotation(apiv3.AnnotationNodeLogLevel, "Debug"), nil, nil),
+	)
+
+	It("should not treat the BGP IPv6 address as the IPv4 address with EmitHostIPv6", func() {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.EmitHostIPv6())
+		res := newTestNode()
+		withBGPAndAddresses(apiv3.NodeBGPSpec{IPv6Address: "fd00::10/64"})(res)
+		kvps, err := processTestNode(up, res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(ContainElement(&model.KVPair{Key: model.HostIPKey{Hostname: "mynode"}, Revision: "abcde"}))
+	})
+
+	It("should emit the BGP address alongside the node-status addresses with EmitNodeStatusAddresses", func() {
+		ip := net.MustParseIP("1.2.3.4")
+		for _, up := range []watchersyncer.SyncerUpdateProcessor{
+			updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.EmitNodeStatusAddresses()),
+			updateprocessors.NewFelixNodeUpdateProcessor(false),
+		} {
+			res := newTestNode()
+			statusAddresses(res)
+			kvps, err := processTestNode(up, res)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(ContainElement(&model.KVPair{Key: model.HostIPKey{Hostname: "mynode"}, Value: &ip, Revision: "abcde"}))
+		}
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor with WithAddressMismatchPolicy", func() {
+	hostIPKey := model.HostIPKey{Hostname: "mynode"}
+
+	// processNode processes a Node with the supplied BGP and InternalIP addresses, and returns
+	// the HostIPKey update.
+	processNode := func(policy updateprocessors.AddressMismatchPolicy, bgpAddr, internalAddr string) (*model.KVPair, error) {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.WithAddressMismatchPolicy(policy))
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{IPv4Address: bgpAddr}
+		res.Spec.Addresses = []apiv3.NodeAddress{
+			{Address: "fd00::1", Type: apiv3.InternalIP},
+			{Address: internalAddr, Type: apiv3.InternalIP},
+		}
+		kvps, err := processTestNode(up, res)
+		for _, kvp := range kvps {
+			if kvp.Key == hostIPKey {
+				return kvp, err
+			}
+		}
+		return nil, err
+	}
+	hostIP := func(addr string) *model.KVPair {
+		ip := net.ParseIP(addr)
+		return &model.KVPair{Key: hostIPKey, Value: ip, Revision: "abcde"}
+	}
+
+	DescribeTable("should use a BGP address that matches the InternalIP address",
+		func(policy updateprocessors.AddressMismatchPolicy) {
+			kvp, err := processNode(policy, "10.0.0.1/24", "10.0.0.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp).To(Equal(hostIP("10.0.0.1")))
+		},
+		Entry("ignore", updateprocessors.AddressMismatchIgnore),
+		Entry("warn", updateprocessors.AddressMismatchWarn),
+		Entry("error", updateprocessors.AddressMismatchError),
+	)
+
+	DescribeTable("should use a mismatched BGP address unless the policy is to fail",
+		func(policy updateprocessors.AddressMismatchPolicy) {
+			kvp, err := processNode(policy, "10.0.0.1/24", "10.0.0.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp).To(Equal(hostIP("10.0.0.1")))
+		},
+		Entry("ignore", updateprocessors.AddressMismatchIgnore),
+		Entry("warn", updateprocessors.AddressMismatchWarn),
+	)
+
+	It("should drop a mismatched BGP address if the policy is to fail", func() {
+		var fields []string
+		var reasons []updateprocessors.DropReason
+		up := updateprocessors.NewFelixNodeUpdateProcessor(false,
+			updateprocessors.WithAddressMismatchPolicy(updateprocessors.AddressMismatchError),
+			updateprocessors.WithConversionErrorHandler(func(node, field string, reason updateprocessors.DropReason, err error) {
+				fields = append(fields, field)
+				reasons = append(reasons, reason)
+			}),
+		)
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{IPv4Address: "10.0.0.1/24", IPv6Address: "fd00::10/64"}
+		res.Spec.Addresses = []apiv3.NodeAddress{
+			{Address: "fd00::1", Type: apiv3.InternalIP},
+			{Address: "10.0.0.2", Type: apiv3.InternalIP},
+		}
+		kvps, err := processTestNode(up, res)
+		Expect(errors.Is(err, updateprocessors.ErrNodeAddressMismatch)).To(BeTrue())
+		Expect(fields).To(Equal([]string{"Spec.BGP.IPv4Address", "Spec.BGP.IPv6Address"}))
+		Expect(reasons).To(Equal([]updateprocessors.DropReason{updateprocessors.DropReasonMismatch, updateprocessors.DropReasonMismatch}))
+
+		By("falling back to the InternalIP address")
+		Expect(kvps).To(ContainElement(hostIP("10.0.0.2")))
+	})
+
+	It("should not treat a Node without an InternalIP address of the family as mismatched", func() {
+		kvp, err := processNode(updateprocessors.AddressMismatchError, "10.0.0.1/24", "fd00::2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp).To(Equal(hostIP("10.0.0.1")))
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor AS number", func() {
+	asNumberKey := model.HostConfigKey{Hostname: "mynode", Name: "AsNumber"}
+	var up watchersyncer.SyncerUpdateProcessor
+
+	BeforeEach(func() {
+		up = updateprocessors.NewFelixNodeUpdateProcessor(false)
+	})
+
+	// processBGP processes a Node with the supplied BGP spec and returns the AsNumber update, if any.
+	processBGP := func(bgp *apiv3.NodeBGPSpec) (*model.KVPair, error) {
+		res := newTestNode()
+		res.Spec.BGP = bgp
+		kvps, err := processTestNode(up, res)
+		return findHostConfig(kvps, asNumberKey.Name), err
+	}
+	explicitASN := func() *apiv3.NodeBGPSpec {
+		asn := numorstring.ASNumber(4200000001)
+		return &apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24", ASNumber: &asn}
+	}
+
+	It("should emit the AS number of a Node with an explicit AS number", func() {
+		kvp, err := processBGP(explicitASN())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp).To(Equal(&model.KVPair{
+			Key:      asNumberKey,
+			Value:    "4200000001",
+			Revision: "abcde",
+		}))
+	})
+
+	It("should not emit a key for a Node inheriting the global AS number", func() {
+		kvp, err := processBGP(&apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp).To(BeNil())
+
+		kvp, err = processBGP(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp).To(BeNil())
+	})
+
+	It("should emit a delete only when a previously emitted AS number is cleared", func() {
+		_, err := processBGP(explicitASN())
+		Expect(err).NotTo(HaveOccurred())
+
+		kvp, err := processBGP(&apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp).To(Equal(&model.KVPair{Key: asNumberKey, Revision: "abcde"}))
+
+		kvp, err = processBGP(&apiv3.NodeBGPSpec{IPv4Address: "1.2.3.4/24"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp).To(BeNil())
+	})
+
+	It("should emit a delete when a Node with an AS number is deleted", func() {
+		_, err := processBGP(explicitASN())
+		Expect(err).NotTo(HaveOccurred())
+
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(findHostConfig(kvps, asNumberKey.Name)).To(Equal(&model.KVPair{Key: asNumberKey}))
+	})
+
+	It("should emit a delete for each emitted AS number on shutdown", func() {
+		_, err := processBGP(explicitASN())
+		Expect(err).NotTo(HaveOccurred())
+
+		sp := up.(watchersyncer.SyncerUpdateProcessorShutdown)
+		Expect(sp.Shutdown()).To(ContainElement(&model.KVPair{Key: asNumberKey}))
+		Expect(sp.Shutdown()).To(BeEmpty())
+	})
+
+	It("should treat the reserved AS number 0 as unset", func() {
+		asn := numorstring.ASNumber(0)
+		kvp, err := processBGP(&apiv3.NodeBGPSpec{ASNumber: &asn})
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidASNumber)).To(BeTrue())
+		Expect(kvp).To(BeNil())
+
+		_, err = processBGP(explicitASN())
+		Expect(err).NotTo(HaveOccurred())
+		kvp, err = processBGP(&apiv3.NodeBGPSpec{ASNumber: &asn})
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidASNumber)).To(BeTrue())
+		Expect(kvp).To(Equal(&model.KVPair{Key: asNumberKey, Revision: "abcde"}))
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor error classification", func() {
+	up := updateprocessors.NewFelixNodeUpdateProcessor(false)
+
+	DescribeTable("should classify each malformed field with the matching sentinel error",
+		func(setField func(*apiv3.Node), expected error) {
+			res := newTestNode()
+			setField(res)
+			_, err := processTestNode(up, res)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, expected)).To(BeTrue(), fmt.Sprintf("expected %v to be classified as %v", err, expected))
+		},
+		Entry("IPv4Address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4Address: "not-an-ip"}
+		}, updateprocessors.ErrNodeInvalidIPv4Address),
+		Entry("IPv6Address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv6Address: "not-an-ip"}
+		}, updateprocessors.ErrNodeInvalidIPv6Address),
+		Entry("IPv4IPIPTunnelAddr", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "not-an-ip"}
+		}, updateprocessors.ErrNodeInvalidIPIPTunnelAddr),
+		Entry("IPv4IPIPTunnelAddr with an IPv6 address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "fd00::1"}
+		}, updateprocessors.ErrNodeInvalidIPIPTunnelAddr),
+		Entry("IPv4VXLANTunnelAddr", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "not-an-ip"
+		}, updateprocessors.ErrNodeInvalidVXLANTunnelAddr),
+		Entry("IPv6VXLANTunnelAddr", func(n *apiv3.Node) {
+			n.Spec.IPv6VXLANTunnelAddr = "192.200.200.200"
+		}, updateprocessors.ErrNodeInvalidVXLANTunnelAddr),
+		Entry("VXLANTunnelMACV4Addr", func(n *apiv3.Node) {
+			n.Spec.VXLANTunnelMACV4Addr = "not-a-mac"
+		}, updateprocessors.ErrNodeInvalidVXLANTunnelMAC),
+		Entry("VXLANTunnelMACV6Addr", func(n *apiv3.Node) {
+			n.Spec.VXLANTunnelMACV6Addr = "not-a-mac"
+		}, updateprocessors.ErrNodeInvalidVXLANTunnelMAC),
+		Entry("Wireguard InterfaceIPv4Address", func(n *apiv3.Node) {
+			n.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "not-an-ip"}
+		}, updateprocessors.ErrNodeInvalidWireguardInterfaceAddr),
+		Entry("WireguardPublicKey", func(n *apiv3.Node) {
+			n.Status.WireguardPublicKey = "not-a-key"
+		}, updateprocessors.ErrNodeInvalidWireguardPublicKey),
+		Entry("PodCIDRs", func(n *apiv3.Node) {
+			n.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "192.168.0.1"}
+			n.Status.PodCIDRs = []string{"not-a-cidr"}
+		}, updateprocessors.ErrNodeInvalidPodCIDR),
+		Entry("ASNumber", func(n *apiv3.Node) {
+			asn := numorstring.ASNumber(0)
+			n.Spec.BGP = &apiv3.NodeBGPSpec{ASNumber: &asn}
+		}, updateprocessors.ErrNodeInvalidASNumber),
+	)
+
+	It("should not classify the error under any other category", func() {
+		res := newTestNode()
+		res.Spec.IPv4VXLANTunnelAddr = "not-an-ip"
+		_, err := processTestNode(up, res)
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidVXLANTunnelAddr)).To(BeTrue())
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidIPIPTunnelAddr)).To(BeFalse())
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidVXLANTunnelMAC)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor drop reasons", func() {
+	type drop struct {
+		node   string
+		field  string
+		reason updateprocessors.DropReason
+	}
+	var drops []drop
+	up := updateprocessors.NewFelixNodeUpdateProcessor(false,
+		updateprocessors.EmitNodeTaints(),
+		updateprocessors.WithConversionErrorHandler(func(node, field string, reason updateprocessors.DropReason, err error) {
+			Expect(err).To(HaveOccurred())
+			drops = append(drops, drop{node: node, field: field, reason: reason})
+		}),
+	)
+
+	BeforeEach(func() {
+		drops = nil
+	})
+
+	DescribeTable("should report each malformed field with the matching reason",
+		func(setField func(*apiv3.Node), field string, reason updateprocessors.DropReason) {
+			res := newTestNode()
+			setField(res)
+			_, err := processTestNode(up, res)
+			Expect(err).To(HaveOccurred())
+			Expect(drops).To(Equal([]drop{{node: "mynode", field: field, reason: reason}}))
+		},
+		Entry("IPv4Address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4Address: "not-an-ip"}
+		}, "Spec.BGP.IPv4Address", updateprocessors.DropReasonInvalidIP),
+		Entry("IPv6Address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv6Address: "not-an-ip"}
+		}, "Spec.BGP.IPv6Address", updateprocessors.DropReasonInvalidIP),
+		Entry("IPv4IPIPTunnelAddr", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "not-an-ip"}
+		}, "Spec.BGP.IPv4IPIPTunnelAddr", updateprocessors.DropReasonInvalidIP),
+		Entry("IPv4IPIPTunnelAddr with an IPv6 address", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "fd00::1"}
+		}, "Spec.BGP.IPv4IPIPTunnelAddr", updateprocessors.DropReasonWrongFamily),
+		Entry("ASNumber", func(n *apiv3.Node) {
+			asn := numorstring.ASNumber(0)
+			n.Spec.BGP = &apiv3.NodeBGPSpec{ASNumber: &asn}
+		}, "Spec.BGP.ASNumber", updateprocessors.DropReasonOutOfRange),
+		Entry("IPv4VXLANTunnelAddr", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "not-an-ip"
+		}, "Spec.IPv4VXLANTunnelAddr", updateprocessors.DropReasonInvalidIP),
+		Entry("IPv4VXLANTunnelAddr with an IPv6 address", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "fd00::1"
+		}, "Spec.IPv4VXLANTunnelAddr", updateprocessors.DropReasonWrongFamily),
+		Entry("IPv6VXLANTunnelAddr", func(n *apiv3.Node) {
+			n.Spec.IPv6VXLANTunnelAddr = "not-an-ip"
+		}, "Spec.IPv6VXLANTunnelAddr", updateprocessors.DropReasonInvalidIP),
+		Entry("IPv6VXLANTunnelAddr with an IPv4 address", func(n *apiv3.Node) {
+			n.Spec.IPv6VXLANTunnelAddr = "192.200.200.200"
+		}, "Spec.IPv6VXLANTunnelAddr", updateprocessors.DropReasonWrongFamily),
+		Entry("VXLANTunnelMACV4Addr", func(n *apiv3.Node) {
+			n.Spec.VXLANTunnelMACV4Addr = "not-a-mac"
+		}, "Spec.VXLANTunnelMACV4Addr", updateprocessors.DropReasonInvalidMAC),
+		Entry("VXLANTunnelMACV6Addr", func(n *apiv3.Node) {
+			n.Spec.VXLANTunnelMACV6Addr = "not-a-mac"
+		}, "Spec.VXLANTunnelMACV6Addr", updateprocessors.DropReasonInvalidMAC),
+		Entry("Wireguard InterfaceIPv4Address", func(n *apiv3.Node) {
+			n.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "not-an-ip"}
+		}, "Spec.Wireguard.InterfaceIPv4Address", updateprocessors.DropReasonInvalidIP),
+		Entry("WireguardPublicKey", func(n *apiv3.Node) {
+			n.Status.WireguardPublicKey = "not-a-key"
+		}, "Status.WireguardPublicKey", updateprocessors.DropReasonInvalidKey),
+		Entry("PodCIDRs", func(n *apiv3.Node) {
+			n.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "192.168.0.1"}
+			n.Status.PodCIDRs = []string{"not-a-cidr"}
+		}, "Status.PodCIDRs", updateprocessors.DropReasonInvalidIP),
+		Entry("taint with an invalid effect", func(n *apiv3.Node) {
+			n.Spec.Taints = []apiv3.NodeTaint{{Key: "dedicated", Effect: "NoRun"}}
+		}, "Spec.Taints", updateprocessors.DropReasonOutOfRange),
+		Entry("taint with no key", func(n *apiv3.Node) {
+			n.Spec.Taints = []apiv3.NodeTaint{{Effect: apiv3.TaintEffectNoSchedule}}
+		}, "Spec.Taints", updateprocessors.DropReasonInvalidKey),
+	)
+
+	It("should report every dropped field, not just the returned error", func() {
+		res := newTestNode()
+		res.Spec.IPv4VXLANTunnelAddr = "not-an-ip"
+		res.Spec.VXLANTunnelMACV4Addr = "not-a-mac"
+		_, err := processTestNode(up, res)
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidVXLANTunnelMAC)).To(BeTrue())
+		Expect(drops).To(Equal([]drop{
+			{node: "mynode", field: "Spec.IPv4VXLANTunnelAddr", reason: updateprocessors.DropReasonInvalidIP},
+			{node: "mynode", field: "Spec.VXLANTunnelMACV4Addr", reason: updateprocessors.DropReasonInvalidMAC},
+		}))
+	})
+
+	It("should not report anything for a valid Node", func() {
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{IPv4Address: "172.16.1.1/24"}
+		_, err := processTestNode(up, res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drops).To(BeEmpty())
+	})
+
+	It("should format the reasons", func() {
+		Expect(updateprocessors.DropReasonWrongFamily.String()).To(Equal("WrongFamily"))
+		Expect(updateprocessors.DropReason(0).String()).To(Equal("Unknown"))
+	})
+})
+
+// fieldCounter is a NodeConversionCounter that records the counts in maps.
+type fieldCounter struct {
+	parsed  map[updateprocessors.NodeField]int
+	dropped map[updateprocessors.NodeField]int
+}
+
+func (f *fieldCounter) IncParsed(field updateprocessors.NodeField) {
+	f.parsed[field]++
+}
+
+func (f *fieldCounter) IncDropped(field updateprocessors.NodeField) {
+	f.dropped[field]++
+}
+
+var _ = Describe("Test the (Felix) Node update processor conversion counters", func() {
+	mixedNode := func() *apiv3.Node {
+		res := newTestNode()
+		res.Spec.BGP = &apiv3.NodeBGPSpec{
+			IPv4Address:        "172.0.0.1/24",
+			IPv6Address:        "not-an-ip",
+			IPv4IPIPTunnelAddr: "192.100.100.100",
+		}
+		res.Spec.IPv4VXLANTunnelAddr = "fd00::1"
+		res.Spec.IPv6VXLANTunnelAddr = "fd00::2"
+		res.Spec.VXLANTunnelMACV4Addr = "00:0a:74:9d:68:16"
+		res.Spec.VXLANTunnelMACV6Addr = "not-a-mac"
+		res.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "192.168.0.1"}
+		res.Status.WireguardPublicKey = "not-a-key"
+		return res
+	}
+
+	It("should count the parsed and dropped fields of a mixed-validity node", func() {
+		counter := &fieldCounter{
+			parsed:  map[updateprocessors.NodeField]int{},
+			dropped: map[updateprocessors.NodeField]int{},
+		}
+		up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.WithConversionCounter(counter))
+
+		_, err := up.Process(&model.KVPair{Key: testNodeKey, Value: mixedNode()})
+		Expect(err).To(HaveOccurred())
+		Expect(counter.parsed).To(Equal(map[updateprocessors.NodeField]int{
+			updateprocessors.NodeFieldIPv4:       1,
+			updateprocessors.NodeFieldIPIP:       1,
+			updateprocessors.NodeFieldVXLANV6:    1,
+			updateprocessors.NodeFieldVXLANMACV4: 1,
+			updateprocessors.NodeFieldWireguard:  1,
+		}))
+		Expect(counter.dropped).To(Equal(map[updateprocessors.NodeField]int{
+			updateprocessors.NodeFieldIPv6:       1,
+			updateprocessors.NodeFieldVXLANV4:    1,
+			updateprocessors.NodeFieldVXLANMACV6: 1,
+			updateprocessors.NodeFieldWireguard:  1,
+		}))
+
+		By("counting again for a second update")
+		_, _ = up.Process(&model.KVPair{Key: testNodeKey, Value: mixedNode()})
+		Expect(counter.parsed[updateprocessors.NodeFieldIPv4]).To(Equal(2))
+		Expect(counter.dropped[updateprocessors.NodeFieldIPv6]).To(Equal(2))
+
+		By("not counting fields for a delete")
+		_, err = up.Process(&model.KVPair{Key: testNodeKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(counter.parsed[updateprocessors.NodeFieldIPv4]).To(Equal(2))
+	})
+
+	It("should convert the node without a counter", func() {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(false, updateprocessors.WithConversionCounter(nil))
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: mixedNode()})
+		Expect(err).To(HaveOccurred())
+		Expect(kvps).NotTo(BeEmpty())
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor additional VXLAN tunnel addresses", func() {
+	var up watchersyncer.SyncerUpdateProcessor
+
+	BeforeEach(func() {
+		up = updateprocessors.NewFelixNodeUpdateProcessor(false)
+	})
+
+	// tunnelAddrs returns the values of the additional VXLAN tunnel address keys in the updates,
+	// keyed by tunnel name.
+	tunnelAddrs := func(kvps []*model.KVPair) map[string]interface{} {
+		addrs := map[string]interface{}{}
+		for _, kvp := range kvps {
+			if k, ok := kvp.Key.(model.HostConfigKey); ok && strings.HasPrefix(k.Name, updateprocessors.VXLANTunnelAddrKeyPrefix) {
+				Expect(k.Hostname).To(Equal("mynode"))
+				addrs[strings.TrimPrefix(k.Name, updateprocessors.VXLANTunnelAddrKeyPrefix)] = kvp.Value
+			}
+		}
+		return addrs
+	}
+
+	node := func(tunnels ...apiv3.NodeVXLANTunnelAddr) *apiv3.Node {
+		res := newTestNode()
+		res.Spec.IPv4VXLANTunnelAddr = "192.168.0.1"
+		res.Spec.VXLANTunnelAddrs = tunnels
+		return res
+	}
+
+	It("should emit a key for each tunnel and delete the keys of removed tunnels", func() {
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(
+			apiv3.NodeVXLANTunnelAddr{Name: "pool1", Address: "192.168.1.1"},
+			apiv3.NodeVXLANTunnelAddr{Name: "pool2", Address: "fd00::1"},
+		)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnelAddrs(kvps)).To(Equal(map[string]interface{}{
+			"pool1": "192.168.1.1",
+			"pool2": "fd00::1",
+		}))
+
+		By("removing a tunnel")
+		kvps, err = up.Process(&model.KVPair{Key: testNodeKey, Value: node(
+			apiv3.NodeVXLANTunnelAddr{Name: "pool2", Address: "fd00::2"},
+		)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnelAddrs(kvps)).To(Equal(map[string]interface{}{
+			"pool1": nil,
+			"pool2": "fd00::2",
+		}))
+
+		By("removing all tunnels")
+		kvps, err = up.Process(&model.KVPair{Key: testNodeKey, Value: node()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnelAddrs(kvps)).To(Equal(map[string]interface{}{"pool2": nil}))
+
+		By("updating a Node without tunnels")
+		kvps, err = up.Process(&model.KVPair{Key: testNodeKey, Value: node()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnelAddrs(kvps)).To(BeEmpty())
+	})
+
+	It("should delete the keys of all tunnels when the Node is deleted", func() {
+		_, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(
+			apiv3.NodeVXLANTunnelAddr{Name: "pool1", Address: "192.168.1.1"},
+			apiv3.NodeVXLANTunnelAddr{Name: "pool2", Address: "192.168.2.1"},
+		)})
+		Expect(err).NotTo(HaveOccurred())
+
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnelAddrs(kvps)).To(Equal(map[string]interface{}{
+			"pool1": nil,
+			"pool2": nil,
+		}))
+	})
+
+	It("should return deletes for the keys of all tunnels on shutdown", func() {
+		_, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(
+			apiv3.NodeVXLANTunnelAddr{Name: "pool1", Address: "192.168.1.1"},
+			apiv3.NodeVXLANTunnelAddr{Name: "pool2", Address: "192.168.2.1"},
+		)})
+		Expect(err).NotTo(HaveOccurred())
+
+		sp := up.(watchersyncer.SyncerUpdateProcessorShutdown)
+		Expect(sp.Shutdown()).To(Equal([]*model.KVPair{
+			{Key: model.HostConfigKey{Hostname: "mynode", Name: updateprocessors.VXLANTunnelAddrKeyPrefix + "pool1"}},
+			{Key: model.HostConfigKey{Hostname: "mynode", Name: updateprocessors.VXLANTunnelAddrKeyPrefix + "pool2"}},
+		}))
+
+		By("checking the tunnels are cleared")
+		Expect(sp.Shutdown()).To(BeEmpty())
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tunnelAddrs(kvps)).To(BeEmpty())
+	})
+
+	It("should treat an invalid tunnel address as a delete", func() {
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(
+			apiv3.NodeVXLANTunnelAddr{Name: "pool1", Address: "192.168.1.1"},
+			apiv3.NodeVXLANTunnelAddr{Name: "pool2", Address: "not-an-ip"},
+		)})
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidVXLANTunnelAddr)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("Spec.VXLANTunnelAddrs[1].Address"))
+		Expect(tunnelAddrs(kvps)).To(Equal(map[string]interface{}{
+			"pool1": "192.168.1.1",
+			"pool2": nil,
+		}))
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor VXLAN VNI", func() {
+	var up watchersyncer.SyncerUpdateProcessor
+
+	BeforeEach(func() {
+		up = updateprocessors.NewFelixNodeUpdateProcessor(false)
+	})
+
+	// vniUpdates returns the VXLANVNI key updates.
+	vniUpdates := func(kvps []*model.KVPair) []*model.KVPair {
+		var updates []*model.KVPair
+		for _, kvp := range kvps {
+			if k, ok := kvp.Key.(model.HostConfigKey); ok && k.Name == "VXLANVNI" {
+				Expect(k.Hostname).To(Equal("mynode"))
+				updates = append(updates, kvp)
+			}
+		}
+		return updates
+	}
+
+	node := func(vni *int) *apiv3.Node {
+		res := newTestNode()
+		res.Spec.IPv4VXLANTunnelAddr = "192.168.0.1"
+		res.Spec.VXLANVNI = vni
+		return res
+	}
+	vni := func(v int) *int {
+		return &v
+	}
+
+	It("should not emit a key when the VNI is not set", func() {
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(nil)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vniUpdates(kvps)).To(BeEmpty())
+
+		By("deleting the Node")
+		kvps, err = up.Process(&model.KVPair{Key: testNodeKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vniUpdates(kvps)).To(BeEmpty())
+	})
+
+	It("should emit a custom VNI and delete it when it is unset", func() {
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(vni(5000)), Revision: "1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vniUpdates(kvps)).To(Equal([]*model.KVPair{{
+			Key:      model.HostConfigKey{Hostname: "mynode", Name: "VXLANVNI"},
+			Value:    "5000",
+			Revision: "1",
+		}}))
+
+		By("unsetting the VNI")
+		kvps, err = up.Process(&model.KVPair{Key: testNodeKey, Value: node(nil), Revision: "2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vniUpdates(kvps)).To(Equal([]*model.KVPair{{
+			Key:      model.HostConfigKey{Hostname: "mynode", Name: "VXLANVNI"},
+			Revision: "2",
+		}}))
+
+		By("updating the Node again without a VNI")
+		kvps, err = up.Process(&model.KVPair{Key: testNodeKey, Value: node(nil), Revision: "3"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vniUpdates(kvps)).To(BeEmpty())
+	})
+
+	It("should delete the VNI when the Node is deleted", func() {
+		_, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(vni(0xffffff))})
+		Expect(err).NotTo(HaveOccurred())
+
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey})
+		Expect(err).NotTo(HaveOccurred())
+		updates := vniUpdates(kvps)
+		Expect(updates).To(HaveLen(1))
+		Expect(updates[0].Value).To(BeNil())
+	})
+
+	It("should return deletes for the emitted VNIs on shutdown", func() {
+		_, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(vni(5000))})
+		Expect(err).NotTo(HaveOccurred())
+
+		sp := up.(watchersyncer.SyncerUpdateProcessorShutdown)
+		Expect(sp.Shutdown()).To(Equal([]*model.KVPair{
+			{Key: model.HostConfigKey{Hostname: "mynode", Name: "VXLANVNI"}},
+		}))
+
+		By("checking the VNIs are cleared")
+		Expect(sp.Shutdown()).To(BeEmpty())
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(nil)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vniUpdates(kvps)).To(BeEmpty())
+	})
+
+	It("should drop out of range VNIs", func() {
+		for _, v := range []int{-1, 0x1000000} {
+			kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(vni(v))})
+			Expect(errors.Is(err, updateprocessors.ErrNodeInvalidVXLANVNI)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("is not in the range 0-16777215"))
+			Expect(vniUpdates(kvps)).To(BeEmpty())
+		}
+
+		By("replacing a valid VNI with an out of range VNI")
+		_, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(vni(4096))})
+		Expect(err).NotTo(HaveOccurred())
+		kvps, err := up.Process(&model.KVPair{Key: testNodeKey, Value: node(vni(0x1000000))})
+		Expect(errors.Is(err, updateprocessors.ErrNodeInvalidVXLANVNI)).To(BeTrue())
+		updates := vniUpdates(kvps)
+		Expect(updates).To(HaveLen(1))
+		Expect(updates[0].Value).To(BeNil())
+	})
+})
+
+var _ = Describe("Test the (Felix) Node update processor batch processing", func() {
+	var sequential, batch watchersyncer.SyncerUpdateProcessor
+
+	BeforeEach(func() {
+		sequential = updateprocessors.NewFelixNodeUpdateProcessor(true)
+		batch = updateprocessors.NewFelixNodeUpdateProcessor(true)
+	})
+
+	// nodeKVP returns an update for a Node with the supplied PodCIDRs.
+	nodeKVP := func(name, revision string, podCIDRs ...string) *model.KVPair {
+		res := apiv3.NewNode()
+		res.Name = name
+		res.Spec.BGP = &apiv3.NodeBGPSpec{IPv4Address: "10.0.0.1/24"}
+		res.Status.PodCIDRs = podCIDRs
+		return &model.KVPair{
+			Key:      model.ResourceKey{Kind: apiv3.KindNode, Name: name},
+			Value:    res,
+			Revision: revision,
+		}
+	}
+
+	// processSequentially processes each update in turn.
+	processSequentially := func(kvps []*model.KVPair) []*model.KVPair {
+		var updates []*model.KVPair
+		for _, kvp := range kvps {
+			converted, err := sequential.Process(kvp)
+			Expect(err).NotTo(HaveOccurred())
+			updates = append(updates, converted...)
+		}
+		return updates
+	}
+
+	// finalState returns the state resulting from applying the updates, ignoring revisions.
+	finalState := func(kvps []*model.KVPair) map[string]interface{} {
+		state := map[string]interface{}{}
+		for _, kvp := range kvps {
+			if kvp.Value == nil {
+				delete(state, kvp.Key.String())
+			} else {
+				state[kvp.Key.String()] = kvp.Value
+			}
+		}
+		return state
+	}
+
+	It("should implement the batch interface", func() {
+		_, ok := batch.(watchersyncer.SyncerUpdateProcessorBatch)
+		Expect(ok).To(BeTrue())
+	})
+
+	It("should produce the same updates as sequential processing for distinct nodes", func() {
+		kvps := []*model.KVPair{
+			nodeKVP("node1", "1", "192.168.1.0/24", "192.168.2.0/24"),
+			nodeKVP("node2", "2", "192.168.3.0/24"),
+			nodeKVP("node3", "3"),
+		}
+		updates, err := watchersyncer.ProcessBatch(batch, kvps)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updates).To(ConsistOf(processSequentially(kvps)))
+	})
+
+	It("should produce the same state as sequential processing for repeated nodes", func() {
+		initial := []*model.KVPair{nodeKVP("node1", "1", "192.168.1.0/24", "192.168.2.0/24")}
+		kvps := []*model.KVPair{
+			nodeKVP("node1", "2", "192.168.2.0/24", "192.168.3.0/24"),
+			nodeKVP("node2", "3", "192.168.4.0/24"),
+			nodeKVP("node1", "4", "192.168.3.0/24", "192.168.5.0/24"),
+			{Key: model.ResourceKey{Kind: apiv3.KindNode, Name: "node2"}, Revision: "5"},
+		}
+
+		expected := processSequentially(append(initial, kvps...))
+		updates, err := watchersyncer.ProcessBatch(batch, initial)
+		Expect(err).NotTo(HaveOccurred())
+		batched, err := watchersyncer.ProcessBatch(batch, kvps)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(finalState(append(updates, batched...))).To(Equal(finalState(expected)))
+
+		By("checking subsequent sequential processing is unaffected by the batch")
+		next := nodeKVP("node1", "6", "192.168.5.0/24")
+		expectedNext, err := sequential.Process(next)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batch.Process(next)).To(ConsistOf(expectedNext))
+	})
+
+	It("should return the first error alongside the converted updates", func() {
+		bad := nodeKVP("node2", "2")
+		bad.Value.(*apiv3.Node).Spec.IPv4VXLANTunnelAddr = "not-an-ip"
+		kvps := []*model.KVPair{
+			nodeKVP("node1", "1", "192.168.1.0/24"),
+			bad,
+			{Key: model.GlobalConfigKey{Name: "foo"}},
+		}
+		updates, err := watchersyncer.ProcessBatch(batch, kvps)
+		Expect(err).To(MatchError("failed to parsed IPv4VXLANTunnelAddr as an IP address"))
+
+		expected := processSequentially(kvps[:1])
+		converted, err := sequential.Process(bad)
+		Expect(err).To(HaveOccurred())
+		Expect(updates).To(ConsistOf(append(expected, converted...)))
+	})
 })
 
 func assertBlockUpdate(kvps []*model.KVPair, expected *model.KVPair) {
@@ -22,6 +22,7 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
 )
 
 // Create a new SyncerUpdateProcessor to sync GlobalNetworkPolicy data in v1 format for
@@ -47,6 +48,12 @@ func convertGlobalNetworkPolicyV2ToV1Value(val interface{}) (interface{}, error)
 	}
 
 	spec := v3res.Spec
+	if err := validator.ValidateGlobalNetworkPolicyFlags(&spec); err != nil {
+		return nil, err
+	}
+	if err := ValidatePolicyRuleSelectors("GlobalNetworkPolicySpec", spec.Ingress, spec.Egress); err != nil {
+		return nil, err
+	}
 	selector := spec.Selector
 
 	nsSelector := spec.NamespaceSelector
@@ -16,12 +16,15 @@ package updateprocessors_test
 
 import (
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
 )
 
 var _ = Describe("Test the GlobalNetworkPolicy update processor", func() {
@@ -245,4 +248,76 @@ var _ = Describe("Test the GlobalNetworkPolicy update processor", func() {
 				Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key, Value: &policy, Revision: testRev}}))
 			})
 	})
+
+	Context("test processing of the GlobalNetworkPolicy forwarding flags", func() {
+		up := updateprocessors.NewGlobalNetworkPolicyUpdateProcessor()
+		flagsKey := model.ResourceKey{Kind: apiv3.KindGlobalNetworkPolicy, Name: "flags"}
+		v1Key := model.PolicyKey{Name: "flags"}
+
+		// flagsGNP returns an ingress-only GlobalNetworkPolicy with the supplied flags.
+		flagsGNP := func(doNotTrack, preDNAT, applyOnForward bool) *apiv3.GlobalNetworkPolicy {
+			gnp := apiv3.NewGlobalNetworkPolicy()
+			gnp.Spec.Ingress = []apiv3.Rule{v3TestIngressRule}
+			gnp.Spec.Types = []apiv3.PolicyType{apiv3.PolicyTypeIngress}
+			gnp.Spec.DoNotTrack = doNotTrack
+			gnp.Spec.PreDNAT = preDNAT
+			gnp.Spec.ApplyOnForward = applyOnForward
+			return gnp
+		}
+
+		DescribeTable("should propagate valid flag combinations into the v1 Policy",
+			func(doNotTrack, preDNAT, applyOnForward bool) {
+				gnp := flagsGNP(doNotTrack, preDNAT, applyOnForward)
+				Expect(validator.ValidateGlobalNetworkPolicyFlags(&gnp.Spec)).NotTo(HaveOccurred())
+
+				kvps, err := up.Process(&model.KVPair{Key: flagsKey, Value: gnp, Revision: testRev})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(kvps).To(Equal([]*model.KVPair{{
+					Key: v1Key,
+					Value: &model.Policy{
+						InboundRules:   []model.Rule{v1TestIngressRule},
+						Types:          []string{"ingress"},
+						DoNotTrack:     doNotTrack,
+						PreDNAT:        preDNAT,
+						ApplyOnForward: applyOnForward,
+					},
+					Revision: testRev,
+				}}))
+			},
+			Entry("no flags", false, false, false),
+			Entry("ApplyOnForward only", false, false, true),
+			Entry("DoNotTrack with ApplyOnForward", true, false, true),
+			Entry("PreDNAT with ApplyOnForward", false, true, true),
+		)
+
+		DescribeTable("should reject invalid flag combinations and treat the policy as deleted",
+			func(gnp *apiv3.GlobalNetworkPolicy, fields ...string) {
+				err := validator.ValidateGlobalNetworkPolicyFlags(&gnp.Spec)
+				Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+				var names []string
+				for _, f := range err.(cerrors.ErrorValidation).ErroredFields {
+					names = append(names, f.Name)
+				}
+				Expect(names).To(Equal(fields))
+
+				kvps, err := up.Process(&model.KVPair{Key: flagsKey, Value: gnp, Revision: testRev})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key}}))
+			},
+			Entry("PreDNAT without ApplyOnForward",
+				flagsGNP(false, true, false), "PolicySpec.ApplyOnForward"),
+			Entry("DoNotTrack without ApplyOnForward",
+				flagsGNP(true, false, false), "PolicySpec.ApplyOnForward"),
+			Entry("DoNotTrack and PreDNAT",
+				flagsGNP(true, true, true), "PolicySpec.PreDNAT"),
+			Entry("DoNotTrack and PreDNAT without ApplyOnForward",
+				flagsGNP(true, true, false), "PolicySpec.PreDNAT", "PolicySpec.ApplyOnForward"),
+			Entry("PreDNAT with egress rules and types", func() *apiv3.GlobalNetworkPolicy {
+				gnp := flagsGNP(false, true, true)
+				gnp.Spec.Egress = []apiv3.Rule{v3TestEgressRule}
+				gnp.Spec.Types = []apiv3.PolicyType{apiv3.PolicyTypeIngress, apiv3.PolicyTypeEgress}
+				return gnp
+			}(), "PolicySpec.Egress", "PolicySpec.Types"),
+		)
+	})
 })
@@ -17,6 +17,8 @@ package updateprocessors
 import (
 	"errors"
 
+	log "github.com/sirupsen/logrus"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
@@ -50,13 +52,16 @@ func convertHostEndpointV2ToV1(kvp *model.KVPair) (*model.KVPair, error) {
 	var ipv4Addrs []cnet.IP
 	var ipv6Addrs []cnet.IP
 	for _, ipString := range v3res.Spec.ExpectedIPs {
-		ip := cnet.ParseIP(ipString)
-		if ip != nil {
-			if ip.Version() == 4 {
-				ipv4Addrs = append(ipv4Addrs, *ip)
-			} else {
-				ipv6Addrs = append(ipv6Addrs, *ip)
-			}
+		// The expected IPs may be given as IP addresses or as host CIDRs.
+		ip, err := cnet.ParseHostIP(ipString)
+		if err != nil {
+			log.WithError(err).WithField("ExpectedIP", ipString).Warn("Ignoring invalid HostEndpoint expected IP")
+			continue
+		}
+		if ip.Version() == 4 {
+			ipv4Addrs = append(ipv4Addrs, *ip)
+		} else {
+			ipv6Addrs = append(ipv6Addrs, *ip)
 		}
 	}
 
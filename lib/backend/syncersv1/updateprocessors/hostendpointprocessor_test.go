@@ -134,6 +134,24 @@ var _ = Describe("Test the HostEndpoint update processor", func() {
 		Expect(err).To(HaveOccurred())
 	})
 
+	It("should accept expected IPs given as addresses or host CIDRs", func() {
+		up := updateprocessors.NewHostEndpointUpdateProcessor()
+		res := apiv3.NewHostEndpoint()
+		res.Name = v3HostEndpointKey1.Name
+		res.Spec.Node = hn1
+		res.Spec.ExpectedIPs = []string{"10.100.10.1", "10.100.10.2/32", "aa:bb::1/128", "10.100.10.0/24"}
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3HostEndpointKey1,
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		hep := kvps[0].Value.(*model.HostEndpoint)
+		Expect(hep.ExpectedIPv4Addrs).To(Equal([]net.IP{*net.ParseIP("10.100.10.1"), *net.ParseIP("10.100.10.2")}))
+		Expect(hep.ExpectedIPv6Addrs).To(Equal([]net.IP{*net.ParseIP("aa:bb::1")}))
+	})
+
 	It("should fail to convert an invalid resource", func() {
 		up := updateprocessors.NewHostEndpointUpdateProcessor()
 
@@ -22,6 +22,7 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	cresources "github.com/projectcalico/libcalico-go/lib/resources"
 )
 
 // Create a new SyncerUpdateProcessor to sync IPPool data in v1 format for
@@ -43,6 +44,10 @@ func convertIPPoolV2ToV1(kvp *model.KVPair) (*model.KVPair, error) {
 		return nil, errors.New("Value is not a valid IPPool resource value")
 	}
 
+	// Apply the defaults to a copy of the resource, since the resource may be shared.
+	v3res = v3res.DeepCopy()
+	cresources.Default(v3res)
+
 	// Correct data types.  Handle the conversion.
 	_, cidr, err := cnet.ParseCIDR(v3res.Spec.CIDR)
 	if err != nil {
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors
+
+import (
+	"reflect"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+)
+
+// v1KeyTypesByKind is the v1 key type produced for each v3 resource kind handled by the
+// generic (simple and conflict resolving) update processors.  These processors are only
+// given conversion functions, so the key type cannot be determined from the processor
+// itself.
+var v1KeyTypesByKind = map[string][]string{
+	apiv3.KindBGPPeer:                   keyTypeNames(model.GlobalBGPPeerKey{}, model.NodeBGPPeerKey{}),
+	apiv3.KindGlobalNetworkPolicy:       keyTypeNames(model.PolicyKey{}),
+	apiv3.KindGlobalNetworkSet:          keyTypeNames(model.NetworkSetKey{}),
+	apiv3.KindHostEndpoint:              keyTypeNames(model.HostEndpointKey{}),
+	apiv3.KindIPPool:                    keyTypeNames(model.IPPoolKey{}),
+	apiv3.KindNetworkPolicy:             keyTypeNames(model.PolicyKey{}),
+	apiv3.KindNetworkSet:                keyTypeNames(model.NetworkSetKey{}),
+	apiv3.KindStagedGlobalNetworkPolicy: keyTypeNames(model.StagedPolicyKey{}),
+	apiv3.KindStagedNetworkPolicy:       keyTypeNames(model.StagedPolicyKey{}),
+	apiv3.KindWorkloadEndpoint:          keyTypeNames(model.WorkloadEndpointKey{}),
+}
+
+// producedKeyTypesForKind returns a copy of the v1 key types produced for the v3 kind, or
+// nil if the kind is not known.
+func producedKeyTypesForKind(kind string) []string {
+	types, ok := v1KeyTypesByKind[kind]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), types...)
+}
+
+// keyTypeNames returns the type names of the supplied keys, with duplicates and nil keys
+// removed.  The order of the keys is maintained.
+func keyTypeNames(keys ...model.Key) []string {
+	names := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k == nil {
+			continue
+		}
+		name := reflect.TypeOf(k).Name()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -47,6 +47,9 @@ func convertNetworkPolicyV2ToV1Value(val interface{}) (interface{}, error) {
 	}
 
 	spec := v3res.Spec
+	if err := ValidatePolicyRuleSelectors("NetworkPolicySpec", spec.Ingress, spec.Egress); err != nil {
+		return nil, err
+	}
 	selector := spec.Selector
 
 	if v3res.Namespace != "" {
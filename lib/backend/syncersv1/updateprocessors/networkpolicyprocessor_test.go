@@ -148,6 +148,44 @@ var _ = Describe("Test the NetworkPolicy update processor", func() {
 		})
 
 	})
+
+	Context("test processing of rules with negated selectors", func() {
+		up := updateprocessors.NewNetworkPolicyUpdateProcessor()
+		negatedKey := model.ResourceKey{Kind: apiv3.KindNetworkPolicy, Name: "negated", Namespace: ns1}
+		v1Key := model.PolicyKey{Name: ns1 + "/negated"}
+
+		// negatedNP returns a NetworkPolicy with an ingress rule with the supplied source selectors.
+		negatedNP := func(sel, notSel string) *apiv3.NetworkPolicy {
+			np := apiv3.NewNetworkPolicy()
+			np.Name = "negated"
+			np.Namespace = ns1
+			np.Spec.Ingress = []apiv3.Rule{{
+				Action: apiv3.Allow,
+				Source: apiv3.EntityRule{Selector: sel, NotSelector: notSel},
+			}}
+			return np
+		}
+
+		It("should emit both the selector and the negated selector", func() {
+			kvps, err := up.Process(&model.KVPair{Key: negatedKey, Value: negatedNP("role == 'db'", "has(quarantine)"), Revision: testRev})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			Expect(kvps[0].Key).To(Equal(v1Key))
+
+			rules := kvps[0].Value.(*model.Policy).InboundRules
+			Expect(rules).To(HaveLen(1))
+			Expect(rules[0].SrcSelector).To(Equal("(projectcalico.org/namespace == 'namespace1') && (role == 'db')"))
+			Expect(rules[0].NotSrcSelector).To(Equal("has(quarantine)"))
+			Expect(rules[0].OriginalSrcSelector).To(Equal("role == 'db'"))
+			Expect(rules[0].OriginalNotSrcSelector).To(Equal("has(quarantine)"))
+		})
+
+		It("should treat a NetworkPolicy with a malformed negated selector as deleted", func() {
+			kvps, err := up.Process(&model.KVPair{Key: negatedKey, Value: negatedNP("role == 'db'", "has(quarantine"), Revision: testRev})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key}}))
+		})
+	})
 })
 
 // Define network policies and the corresponding expected v1 KVPairs.
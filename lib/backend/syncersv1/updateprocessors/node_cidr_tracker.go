@@ -14,6 +14,8 @@
 
 package updateprocessors
 
+import "sort"
+
 // nodeCIDRTracker can be used to keep track of CIDRs associated with each node,
 // and to check when they have changed.
 type nodeCIDRTracker struct {
@@ -42,6 +44,23 @@ func (c *nodeCIDRTracker) SetNodeCIDRs(node string, cidrs []string) []string {
 	return outdated
 }
 
+// RemoveAll clears the tracker, and returns the CIDRs that were tracked for each node.
+func (c *nodeCIDRTracker) RemoveAll() map[string][]string {
+	seen := c.seenNodeCIDRs
+	c.seenNodeCIDRs = map[string][]string{}
+	return seen
+}
+
+// sortedNodeNames returns the names of the nodes in the supplied CIDRs map, in order.
+func sortedNodeNames(cidrs map[string][]string) []string {
+	names := make([]string, 0, len(cidrs))
+	for name := range cidrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (c *nodeCIDRTracker) findOutdatedCIDRs(node string, currentCIDRs []string) []string {
 	// Any that are in the old set of CIDRs but not the current set should be removed.
 	toRemove := []string{}
@@ -80,7 +80,7 @@ func (sup *simpleUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, e
 		v1value, err = sup.valueConverter(kvp.Value)
 		if err != nil {
 			// Currently treat any values that fail to convert properly as a deletion event.
-			log.WithField("Resource", kvp.Key).Warn("Unable to process resource data - treating as deleted")
+			log.WithError(err).WithField("Resource", kvp.Key).Warn("Unable to process resource data - treating as deleted")
 			return []*model.KVPair{{Key: v1key}}, nil
 		}
 		if v1value == nil {
@@ -102,3 +102,8 @@ func (sup *simpleUpdateProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, e
 func (sup *simpleUpdateProcessor) OnSyncerStarting() {
 	// Do nothing
 }
+
+// ProducedKeyTypes implements the SyncerUpdateProcessorKeyTypes interface.
+func (sup *simpleUpdateProcessor) ProducedKeyTypes() []string {
+	return producedKeyTypesForKind(sup.v3Kind)
+}
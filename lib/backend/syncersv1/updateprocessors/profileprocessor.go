@@ -101,6 +101,11 @@ func (pup *profileUpdateProcessor) OnSyncerStarting() {
 	// Do nothing
 }
 
+// ProducedKeyTypes implements the SyncerUpdateProcessorKeyTypes interface.
+func (pup *profileUpdateProcessor) ProducedKeyTypes() []string {
+	return keyTypeNames(model.ProfileLabelsKey{}, model.ProfileRulesKey{}, model.ResourceKey{})
+}
+
 func convertProfileV2ToV1Value(val interface{}) (*model.Profile, error) {
 	v3res, ok := val.(*apiv3.Profile)
 	if !ok {
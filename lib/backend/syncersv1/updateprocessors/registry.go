@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors
+
+import (
+	"sync"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+)
+
+// ProcessorFactory creates a new SyncerUpdateProcessor.  Update processors may hold state for the
+// resources that they have processed, so a new processor is created for each use.
+type ProcessorFactory func() watchersyncer.SyncerUpdateProcessor
+
+// The registry of the update processor for each v3 kind.  It is populated with the standard
+// processors used by the Felix syncer, and may be modified with RegisterForKind.  Kinds whose
+// processor requires configuration (such as the BGPPeer processor, which requires a secret
+// reader) are not registered by default.
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]ProcessorFactory{
+		apiv3.KindClusterInformation:        NewClusterInfoUpdateProcessor,
+		apiv3.KindFelixConfiguration:        NewFelixConfigUpdateProcessor,
+		apiv3.KindGlobalNetworkPolicy:       NewGlobalNetworkPolicyUpdateProcessor,
+		apiv3.KindGlobalNetworkSet:          NewGlobalNetworkSetUpdateProcessor,
+		apiv3.KindHostEndpoint:              NewHostEndpointUpdateProcessor,
+		apiv3.KindIPPool:                    NewIPPoolUpdateProcessor,
+		apiv3.KindNetworkPolicy:             NewNetworkPolicyUpdateProcessor,
+		apiv3.KindNetworkSet:                NewNetworkSetUpdateProcessor,
+		apiv3.KindProfile:                   NewProfileUpdateProcessor,
+		apiv3.KindStagedGlobalNetworkPolicy: NewStagedGlobalNetworkPolicyUpdateProcessor,
+		apiv3.KindStagedNetworkPolicy:       NewStagedNetworkPolicyUpdateProcessor,
+		apiv3.KindWorkloadEndpoint:          NewWorkloadEndpointUpdateProcessor,
+		apiv3.KindNode: func() watchersyncer.SyncerUpdateProcessor {
+			return NewFelixNodeUpdateProcessor(false)
+		},
+	}
+)
+
+// ForKind returns a new instance of the update processor registered for the v3 kind, or false if
+// no processor is registered for the kind.
+func ForKind(kind string) (watchersyncer.SyncerUpdateProcessor, bool) {
+	registryLock.RLock()
+	factory, ok := registry[kind]
+	registryLock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// RegisterForKind registers the factory for the update processor of the v3 kind, replacing any
+// processor already registered for the kind.  A nil factory removes the registration.
+func RegisterForKind(kind string, factory ProcessorFactory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if factory == nil {
+		delete(registry, kind)
+		return
+	}
+	registry[kind] = factory
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+)
+
+var _ = Describe("Test the update processor registry", func() {
+	It("should return the standard processors for known kinds", func() {
+		for _, kind := range []string{
+			apiv3.KindClusterInformation,
+			apiv3.KindFelixConfiguration,
+			apiv3.KindGlobalNetworkPolicy,
+			apiv3.KindGlobalNetworkSet,
+			apiv3.KindHostEndpoint,
+			apiv3.KindIPPool,
+			apiv3.KindNetworkPolicy,
+			apiv3.KindNetworkSet,
+			apiv3.KindProfile,
+			apiv3.KindStagedGlobalNetworkPolicy,
+			apiv3.KindStagedNetworkPolicy,
+			apiv3.KindWorkloadEndpoint,
+			apiv3.KindNode,
+		} {
+			up, ok := updateprocessors.ForKind(kind)
+			Expect(ok).To(BeTrue(), kind)
+			Expect(up).NotTo(BeNil(), kind)
+		}
+
+		up, ok := updateprocessors.ForKind(apiv3.KindNode)
+		Expect(ok).To(BeTrue())
+		Expect(up).To(BeAssignableToTypeOf(&updateprocessors.FelixNodeUpdateProcessor{}))
+	})
+
+	It("should return a new processor on each lookup", func() {
+		up1, _ := updateprocessors.ForKind(apiv3.KindNode)
+		up2, _ := updateprocessors.ForKind(apiv3.KindNode)
+		Expect(up1).NotTo(BeIdenticalTo(up2))
+	})
+
+	It("should return false for unknown kinds", func() {
+		up, ok := updateprocessors.ForKind("NotAKind")
+		Expect(ok).To(BeFalse())
+		Expect(up).To(BeNil())
+	})
+
+	It("should allow processors to be registered and overridden", func() {
+		defer updateprocessors.RegisterForKind(apiv3.KindNode, func() watchersyncer.SyncerUpdateProcessor {
+			return updateprocessors.NewFelixNodeUpdateProcessor(false)
+		})
+		defer updateprocessors.RegisterForKind(apiv3.KindBGPPeer, nil)
+
+		updateprocessors.RegisterForKind(apiv3.KindBGPPeer, func() watchersyncer.SyncerUpdateProcessor {
+			return updateprocessors.NewBGPPeerUpdateProcessor(nil)
+		})
+		_, ok := updateprocessors.ForKind(apiv3.KindBGPPeer)
+		Expect(ok).To(BeTrue())
+
+		updateprocessors.RegisterForKind(apiv3.KindNode, updateprocessors.NewIPPoolUpdateProcessor)
+		up, ok := updateprocessors.ForKind(apiv3.KindNode)
+		Expect(ok).To(BeTrue())
+		Expect(up).NotTo(BeAssignableToTypeOf(&updateprocessors.FelixNodeUpdateProcessor{}))
+
+		updateprocessors.RegisterForKind(apiv3.KindBGPPeer, nil)
+		_, ok = updateprocessors.ForKind(apiv3.KindBGPPeer)
+		Expect(ok).To(BeFalse())
+	})
+})
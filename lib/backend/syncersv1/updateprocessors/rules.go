@@ -23,8 +23,10 @@ import (
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
 	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	"github.com/projectcalico/libcalico-go/lib/selector"
 	"github.com/projectcalico/libcalico-go/lib/selector/parser"
 )
 
@@ -40,6 +42,50 @@ func RulesAPIV2ToBackend(ars []apiv3.Rule, ns string) []model.Rule {
 	return brs
 }
 
+// ValidatePolicyRuleSelectors checks that the Selector and NotSelector of the source and
+// destination of each ingress and egress rule are valid selector expressions.  The specName
+// (e.g. "NetworkPolicySpec") is used to name the errored fields.  A cerrors.ErrorValidation is
+// returned describing each invalid selector.
+func ValidatePolicyRuleSelectors(specName string, ingress, egress []apiv3.Rule) error {
+	var fields []cerrors.ErroredField
+	for i := range ingress {
+		fields = appendRuleSelectorErrors(fields, fmt.Sprintf("%s.Ingress[%d]", specName, i), &ingress[i])
+	}
+	for i := range egress {
+		fields = appendRuleSelectorErrors(fields, fmt.Sprintf("%s.Egress[%d]", specName, i), &egress[i])
+	}
+	if len(fields) > 0 {
+		return cerrors.ErrorValidation{ErroredFields: fields}
+	}
+	return nil
+}
+
+// appendRuleSelectorErrors appends an ErroredField for each selector in the rule that does not
+// parse.
+func appendRuleSelectorErrors(fields []cerrors.ErroredField, name string, r *apiv3.Rule) []cerrors.ErroredField {
+	for _, f := range []struct {
+		name string
+		sel  string
+	}{
+		{name + ".Source.Selector", r.Source.Selector},
+		{name + ".Source.NotSelector", r.Source.NotSelector},
+		{name + ".Destination.Selector", r.Destination.Selector},
+		{name + ".Destination.NotSelector", r.Destination.NotSelector},
+	} {
+		if f.sel == "" {
+			continue
+		}
+		if _, err := selector.Parse(f.sel); err != nil {
+			fields = append(fields, cerrors.ErroredField{
+				Name:   f.name,
+				Value:  f.sel,
+				Reason: fmt.Sprintf("invalid selector: %v", err),
+			})
+		}
+	}
+	return fields
+}
+
 // Form and return a single selector expression for all the endpoints that an EntityRule should
 // match.  The returned expression incorporates the semantics of:
 // - the EntityRule's Selector, NamespaceSelector and ServiceAccounts fields
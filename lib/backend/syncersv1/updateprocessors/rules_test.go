@@ -23,6 +23,7 @@ import (
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
 	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
 	"github.com/projectcalico/libcalico-go/lib/numorstring"
 )
@@ -505,4 +506,32 @@ var _ = Describe("Test the Rules Conversion Functions", func() {
 		Expect(outRules[1].DstSelector).To(Equal("(has(projectcalico.org/namespace)) && (has(label2))"))
 		Expect(outRules[2].DstSelector).To(Equal("(!has(projectcalico.org/namespace)) && (has(label3))"))
 	})
+
+	It("should validate rules with both a selector and a negated selector", func() {
+		rules := []apiv3.Rule{{
+			Action:      apiv3.Allow,
+			Source:      apiv3.EntityRule{Selector: "role == 'db'", NotSelector: "has(quarantine)"},
+			Destination: apiv3.EntityRule{Selector: "all()", NotSelector: "role in {'a', 'b'}"},
+		}}
+		Expect(updateprocessors.ValidatePolicyRuleSelectors("NetworkPolicySpec", rules, rules)).NotTo(HaveOccurred())
+	})
+
+	It("should return a field-specific error for a malformed selector", func() {
+		ingress := []apiv3.Rule{
+			{Action: apiv3.Allow, Source: apiv3.EntityRule{Selector: "role == 'db'", NotSelector: "has(quarantine)"}},
+			{Action: apiv3.Allow, Source: apiv3.EntityRule{Selector: "role == 'db'", NotSelector: "has(quarantine"}},
+		}
+		egress := []apiv3.Rule{
+			{Action: apiv3.Deny, Destination: apiv3.EntityRule{Selector: "role == db"}},
+		}
+		err := updateprocessors.ValidatePolicyRuleSelectors("GlobalNetworkPolicySpec", ingress, egress)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		fields := err.(cerrors.ErrorValidation).ErroredFields
+		Expect(fields).To(HaveLen(2))
+		Expect(fields[0].Name).To(Equal("GlobalNetworkPolicySpec.Ingress[1].Source.NotSelector"))
+		Expect(fields[0].Value).To(Equal("has(quarantine"))
+		Expect(fields[1].Name).To(Equal("GlobalNetworkPolicySpec.Egress[0].Destination.Selector"))
+		Expect(fields[1].Value).To(Equal("role == db"))
+	})
 })
@@ -10,8 +10,8 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/numorstring"
 )
 
-var srcSelector string = "mylabel == selector1"
-var dstSelector string = "mylabel == selector2"
+var srcSelector string = "mylabel == 'selector1'"
+var dstSelector string = "mylabel == 'selector2'"
 var notSrcSelector string = "has(label1)"
 var notDstSelector string = "has(label2)"
 
@@ -39,10 +39,10 @@ var v1TestIngressRule = model.Rule{
 	NotICMPCode: &incode,
 
 	SrcNets:     up.ConvertStringsToNets([]string{"10.100.10.1"}),
-	SrcSelector: "mylabel == selector1",
+	SrcSelector: "mylabel == 'selector1'",
 	SrcPorts:    []numorstring.Port{port80},
 	DstNets:     up.NormalizeIPNets([]string{"10.100.1.1"}),
-	DstSelector: "mylabel == selector2",
+	DstSelector: "mylabel == 'selector2'",
 	DstPorts:    []numorstring.Port{Port443},
 
 	NotSrcNets:     up.ConvertStringsToNets([]string{"192.168.40.1"}),
@@ -52,8 +52,8 @@ var v1TestIngressRule = model.Rule{
 	NotDstSelector: "has(label2)",
 	NotDstPorts:    []numorstring.Port{port80},
 
-	OriginalSrcSelector:    "mylabel == selector1",
-	OriginalDstSelector:    "mylabel == selector2",
+	OriginalSrcSelector:    "mylabel == 'selector1'",
+	OriginalDstSelector:    "mylabel == 'selector2'",
 	OriginalNotSrcSelector: "has(label1)",
 	OriginalNotDstSelector: "has(label2)",
 }
@@ -67,7 +67,7 @@ var v3TestIngressRule = apiv3.Rule{
 	NotICMP:     &apiv3.ICMPFields{Type: &intype, Code: &incode},
 	Source: apiv3.EntityRule{
 		Nets:        []string{"10.100.10.1"},
-		Selector:    "mylabel == selector1",
+		Selector:    "mylabel == 'selector1'",
 		Ports:       []numorstring.Port{port80},
 		NotNets:     []string{"192.168.40.1"},
 		NotSelector: "has(label1)",
@@ -75,7 +75,7 @@ var v3TestIngressRule = apiv3.Rule{
 	},
 	Destination: apiv3.EntityRule{
 		Nets:        []string{"10.100.1.1"},
-		Selector:    "mylabel == selector2",
+		Selector:    "mylabel == 'selector2'",
 		Ports:       []numorstring.Port{Port443},
 		NotNets:     []string{"192.168.80.1"},
 		NotSelector: "has(label2)",
@@ -102,10 +102,10 @@ var v1TestEgressRule = model.Rule{
 	NotICMPType: &entype,
 
 	SrcNets:     up.ConvertStringsToNets([]string{"10.100.1.1"}),
-	SrcSelector: "mylabel == selector2",
+	SrcSelector: "mylabel == 'selector2'",
 	SrcPorts:    []numorstring.Port{Port443},
 	DstNets:     up.NormalizeIPNets([]string{"10.100.10.1"}),
-	DstSelector: "mylabel == selector1",
+	DstSelector: "mylabel == 'selector1'",
 	DstPorts:    []numorstring.Port{port80},
 
 	NotSrcNets:     up.ConvertStringsToNets([]string{"192.168.80.1"}),
@@ -115,8 +115,8 @@ var v1TestEgressRule = model.Rule{
 	NotDstSelector: "has(label1)",
 	NotDstPorts:    []numorstring.Port{Port443},
 
-	OriginalSrcSelector:    "mylabel == selector2",
-	OriginalDstSelector:    "mylabel == selector1",
+	OriginalSrcSelector:    "mylabel == 'selector2'",
+	OriginalDstSelector:    "mylabel == 'selector1'",
 	OriginalNotSrcSelector: "has(label2)",
 	OriginalNotDstSelector: "has(label1)",
 }
@@ -136,7 +136,7 @@ var v3TestEgressRule = apiv3.Rule{
 	},
 	Source: apiv3.EntityRule{
 		Nets:        []string{"10.100.1.1"},
-		Selector:    "mylabel == selector2",
+		Selector:    "mylabel == 'selector2'",
 		Ports:       []numorstring.Port{Port443},
 		NotNets:     []string{"192.168.80.1"},
 		NotSelector: "has(label2)",
@@ -144,7 +144,7 @@ var v3TestEgressRule = apiv3.Rule{
 	},
 	Destination: apiv3.EntityRule{
 		Nets:        []string{"10.100.10.1"},
-		Selector:    "mylabel == selector1",
+		Selector:    "mylabel == 'selector1'",
 		Ports:       []numorstring.Port{port80},
 		NotNets:     []string{"192.168.40.1"},
 		NotSelector: "has(label1)",
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors
+
+import (
+	"errors"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+)
+
+// Create a new SyncerUpdateProcessor to sync StagedGlobalNetworkPolicy data in v1 format for
+// consumption by Felix.  The v1 policy has a StagedPolicyKey, so that Felix evaluates the policy
+// without enforcing it, and records the staged action.
+func NewStagedGlobalNetworkPolicyUpdateProcessor() watchersyncer.SyncerUpdateProcessor {
+	return NewSimpleUpdateProcessor(apiv3.KindStagedGlobalNetworkPolicy, convertStagedGlobalNetworkPolicyV3ToV1Key, convertStagedGlobalNetworkPolicyV3ToV1Value)
+}
+
+// Create a new SyncerUpdateProcessor to sync StagedNetworkPolicy data in v1 format for
+// consumption by Felix.  The v1 policy has a StagedPolicyKey, so that Felix evaluates the policy
+// without enforcing it, and records the staged action.
+func NewStagedNetworkPolicyUpdateProcessor() watchersyncer.SyncerUpdateProcessor {
+	return NewSimpleUpdateProcessor(apiv3.KindStagedNetworkPolicy, convertStagedNetworkPolicyV3ToV1Key, convertStagedNetworkPolicyV3ToV1Value)
+}
+
+func convertStagedGlobalNetworkPolicyV3ToV1Key(v3key model.ResourceKey) (model.Key, error) {
+	if v3key.Name == "" {
+		return model.StagedPolicyKey{}, errors.New("Missing Name field to create a v1 StagedGlobalNetworkPolicy Key")
+	}
+	return model.StagedPolicyKey{
+		Name: v3key.Name,
+	}, nil
+}
+
+func convertStagedGlobalNetworkPolicyV3ToV1Value(val interface{}) (interface{}, error) {
+	staged, ok := val.(*apiv3.StagedGlobalNetworkPolicy)
+	if !ok {
+		return nil, errors.New("Value is not a valid StagedGlobalNetworkPolicy resource value")
+	}
+	action, enforced := apiv3.ConvertStagedGlobalPolicyToEnforced(staged)
+	if action == apiv3.StagedActionDelete {
+		// The rules of a staged deletion are ignored.
+		enforced.Spec = apiv3.GlobalNetworkPolicySpec{}
+	}
+	v1value, err := convertGlobalNetworkPolicyV2ToV1Value(enforced)
+	if err != nil {
+		return nil, err
+	}
+	v1value.(*model.Policy).StagedAction = string(action)
+	return v1value, nil
+}
+
+func convertStagedNetworkPolicyV3ToV1Key(v3key model.ResourceKey) (model.Key, error) {
+	if v3key.Name == "" || v3key.Namespace == "" {
+		return model.StagedPolicyKey{}, errors.New("Missing Name or Namespace field to create a v1 StagedNetworkPolicy Key")
+	}
+	return model.StagedPolicyKey{
+		Name: v3key.Namespace + "/" + v3key.Name,
+	}, nil
+}
+
+func convertStagedNetworkPolicyV3ToV1Value(val interface{}) (interface{}, error) {
+	staged, ok := val.(*apiv3.StagedNetworkPolicy)
+	if !ok {
+		return nil, errors.New("Value is not a valid StagedNetworkPolicy resource value")
+	}
+	action, enforced := apiv3.ConvertStagedPolicyToEnforced(staged)
+	if action == apiv3.StagedActionDelete {
+		// The rules of a staged deletion are ignored.
+		enforced.Spec = apiv3.NetworkPolicySpec{}
+	}
+	v1value, err := convertNetworkPolicyV2ToV1Value(enforced)
+	if err != nil {
+		return nil, err
+	}
+	v1value.(*model.Policy).StagedAction = string(action)
+	return v1value, nil
+}
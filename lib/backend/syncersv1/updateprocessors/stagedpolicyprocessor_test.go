@@ -0,0 +1,165 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+)
+
+var _ = Describe("Test the staged policy update processors", func() {
+	selector := `mylabel == 'selectme'`
+
+	// stagedGNP returns a StagedGlobalNetworkPolicy with the same fields as the full v3 GNP.
+	stagedGNP := func(action apiv3.StagedAction) *apiv3.StagedGlobalNetworkPolicy {
+		gnp := fullGNPv3("", selector)
+		staged := apiv3.NewStagedGlobalNetworkPolicy()
+		staged.Name = "policy"
+		staged.Spec = apiv3.StagedGlobalNetworkPolicySpec{
+			StagedAction:   action,
+			Order:          gnp.Spec.Order,
+			Ingress:        gnp.Spec.Ingress,
+			Egress:         gnp.Spec.Egress,
+			Selector:       gnp.Spec.Selector,
+			Types:          gnp.Spec.Types,
+			DoNotTrack:     gnp.Spec.DoNotTrack,
+			PreDNAT:        gnp.Spec.PreDNAT,
+			ApplyOnForward: gnp.Spec.ApplyOnForward,
+		}
+		return staged
+	}
+
+	// stagedNP returns a StagedNetworkPolicy with the same fields as the full v3 NP.
+	stagedNP := func(action apiv3.StagedAction) *apiv3.StagedNetworkPolicy {
+		np := fullNPv3("policy", "namespace1", selector)
+		staged := apiv3.NewStagedNetworkPolicy()
+		staged.Name = np.Name
+		staged.Namespace = np.Namespace
+		staged.Spec = apiv3.StagedNetworkPolicySpec{
+			StagedAction: action,
+			Order:        np.Spec.Order,
+			Ingress:      np.Spec.Ingress,
+			Egress:       np.Spec.Egress,
+			Selector:     np.Spec.Selector,
+			Types:        np.Spec.Types,
+		}
+		return staged
+	}
+
+	Context("StagedGlobalNetworkPolicy", func() {
+		stagedKey := model.ResourceKey{Kind: apiv3.KindStagedGlobalNetworkPolicy, Name: "policy"}
+		enforcedKey := model.ResourceKey{Kind: apiv3.KindGlobalNetworkPolicy, Name: "policy"}
+		v1StagedKey := model.StagedPolicyKey{Name: "policy"}
+
+		It("should convert to a staged v1 policy that differs from the enforced policy only by the key and action", func() {
+			kvps, err := updateprocessors.NewStagedGlobalNetworkPolicyUpdateProcessor().Process(
+				&model.KVPair{Key: stagedKey, Value: stagedGNP(""), Revision: testRev},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			Expect(kvps[0].Key).To(Equal(v1StagedKey))
+
+			enforced, err := updateprocessors.NewGlobalNetworkPolicyUpdateProcessor().Process(
+				&model.KVPair{Key: enforcedKey, Value: fullGNPv3("", selector), Revision: testRev},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enforced).To(HaveLen(1))
+			Expect(enforced[0].Key).To(Equal(model.PolicyKey{Name: "policy"}))
+			Expect(enforced[0].Value.(*model.Policy).StagedAction).To(BeEmpty())
+
+			expected := *enforced[0].Value.(*model.Policy)
+			expected.StagedAction = "Set"
+			Expect(kvps[0].Value).To(Equal(&expected))
+		})
+
+		It("should convert an explicit Set action in the same way as the default", func() {
+			up := updateprocessors.NewStagedGlobalNetworkPolicyUpdateProcessor()
+			defaulted, err := up.Process(&model.KVPair{Key: stagedKey, Value: stagedGNP(""), Revision: testRev})
+			Expect(err).NotTo(HaveOccurred())
+			set, err := up.Process(&model.KVPair{Key: stagedKey, Value: stagedGNP(apiv3.StagedActionSet), Revision: testRev})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(set).To(Equal(defaulted))
+		})
+
+		It("should convert a staged deletion to a staged v1 policy with the Delete action and no rules", func() {
+			kvps, err := updateprocessors.NewStagedGlobalNetworkPolicyUpdateProcessor().Process(
+				&model.KVPair{Key: stagedKey, Value: stagedGNP(apiv3.StagedActionDelete), Revision: testRev},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			Expect(kvps[0].Key).To(Equal(v1StagedKey))
+			Expect(kvps[0].Value).To(Equal(&model.Policy{StagedAction: "Delete"}))
+		})
+
+		It("should convert a delete to a delete of the staged v1 policy", func() {
+			kvps, err := updateprocessors.NewStagedGlobalNetworkPolicyUpdateProcessor().Process(&model.KVPair{Key: stagedKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(Equal([]*model.KVPair{{Key: v1StagedKey}}))
+		})
+	})
+
+	Context("StagedNetworkPolicy", func() {
+		stagedKey := model.ResourceKey{Kind: apiv3.KindStagedNetworkPolicy, Name: "policy", Namespace: "namespace1"}
+		enforcedKey := model.ResourceKey{Kind: apiv3.KindNetworkPolicy, Name: "policy", Namespace: "namespace1"}
+		v1StagedKey := model.StagedPolicyKey{Name: "namespace1/policy"}
+
+		It("should convert to a staged v1 policy that differs from the enforced policy only by the key and action", func() {
+			kvps, err := updateprocessors.NewStagedNetworkPolicyUpdateProcessor().Process(
+				&model.KVPair{Key: stagedKey, Value: stagedNP(""), Revision: testRev},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			Expect(kvps[0].Key).To(Equal(v1StagedKey))
+
+			enforced, err := updateprocessors.NewNetworkPolicyUpdateProcessor().Process(
+				&model.KVPair{Key: enforcedKey, Value: fullNPv3("policy", "namespace1", selector), Revision: testRev},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enforced).To(HaveLen(1))
+			Expect(enforced[0].Key).To(Equal(model.PolicyKey{Name: "namespace1/policy"}))
+			Expect(enforced[0].Value.(*model.Policy).StagedAction).To(BeEmpty())
+
+			expected := *enforced[0].Value.(*model.Policy)
+			expected.StagedAction = "Set"
+			Expect(kvps[0].Value).To(Equal(&expected))
+		})
+
+		It("should convert a staged deletion to a staged v1 policy with the Delete action and no rules", func() {
+			kvps, err := updateprocessors.NewStagedNetworkPolicyUpdateProcessor().Process(
+				&model.KVPair{Key: stagedKey, Value: stagedNP(apiv3.StagedActionDelete), Revision: testRev},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			Expect(kvps[0].Key).To(Equal(v1StagedKey))
+			Expect(kvps[0].Value).To(Equal(&model.Policy{
+				Namespace:      "namespace1",
+				Selector:       "projectcalico.org/namespace == 'namespace1'",
+				ApplyOnForward: true,
+				StagedAction:   "Delete",
+			}))
+		})
+
+		It("should reject a StagedNetworkPolicy key without a namespace", func() {
+			_, err := updateprocessors.NewStagedNetworkPolicyUpdateProcessor().Process(
+				&model.KVPair{Key: model.ResourceKey{Kind: apiv3.KindStagedNetworkPolicy, Name: "policy"}},
+			)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -16,10 +16,13 @@ package updateprocessors
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
@@ -35,6 +38,37 @@ func NewWorkloadEndpointUpdateProcessor() watchersyncer.SyncerUpdateProcessor {
 	return NewSimpleUpdateProcessor(apiv3.KindWorkloadEndpoint, convertWorkloadEndpointV2ToV1Key, convertWorkloadEndpointV2ToV1Value)
 }
 
+// NamespaceLabelsFunc returns the labels of the named namespace, and whether the namespace is
+// known.
+type NamespaceLabelsFunc func(namespace string) (map[string]string, bool)
+
+// NewWorkloadEndpointUpdateProcessorWithNamespaceLabels creates a WorkloadEndpoint update processor
+// that also merges the labels of each WorkloadEndpoint's namespace into the v1 labels, prefixed
+// with the namespace label prefix (pcns.), along with a label for the namespace name.  This allows
+// policy to select on namespace labels using the endpoint labels alone.
+//
+// Labels already on the endpoint take precedence over the namespace labels.  Since any labels on
+// the endpoint that use the namespace label prefix are removed, the only such label is the
+// namespace name label, which therefore cannot be overridden by a namespace label of the same
+// name.  Namespace labels that do not form a valid label key once prefixed are ignored.
+//
+// No namespace labels are merged for an endpoint whose namespace is not known.  The processor
+// does not track namespace updates - the endpoints in a namespace must be re-processed for
+// namespace label changes to take effect.
+func NewWorkloadEndpointUpdateProcessorWithNamespaceLabels(nsLabels NamespaceLabelsFunc) watchersyncer.SyncerUpdateProcessor {
+	return NewSimpleUpdateProcessor(apiv3.KindWorkloadEndpoint, convertWorkloadEndpointV2ToV1Key, func(val interface{}) (interface{}, error) {
+		v1value, err := convertWorkloadEndpointV2ToV1Value(val)
+		if err != nil || v1value == nil {
+			return v1value, err
+		}
+		v3res := val.(*apiv3.WorkloadEndpoint)
+		if labels, ok := nsLabels(v3res.Namespace); ok {
+			mergeNamespaceLabels(v3res, v1value.(*model.WorkloadEndpoint).Labels, labels)
+		}
+		return v1value, nil
+	})
+}
+
 func convertWorkloadEndpointV2ToV1Key(v3key model.ResourceKey) (model.Key, error) {
 	parts := names.ExtractDashSeparatedParms(v3key.Name, 4)
 	if len(parts) != 4 || v3key.Namespace == "" {
@@ -118,9 +152,21 @@ func convertWorkloadEndpointV2ToV1Value(val interface{}) (interface{}, error) {
 		cmac = &cnet.MAC{HardwareAddr: mac}
 	}
 
-	// Convert the EndpointPort type from the API pkg to the v1 model equivalent type
+	// Convert the EndpointPort type from the API pkg to the v1 model equivalent type.  Felix
+	// programs a named port ipset per protocol, so skip any port whose protocol does not
+	// support ports (or has no port number) rather than handing Felix an unusable entry.
 	ports := []model.EndpointPort{}
 	for _, port := range v3res.Spec.Ports {
+		if !port.Protocol.SupportsPorts() || port.Port == 0 {
+			log.WithFields(log.Fields{
+				"name":      v3res.Name,
+				"namespace": v3res.Namespace,
+				"port":      port.Name,
+				"protocol":  port.Protocol.String(),
+				"number":    port.Port,
+			}).Warn("Ignoring WEP port with invalid protocol and port combination")
+			continue
+		}
 		ports = append(ports, model.EndpointPort{
 			Name:     port.Name,
 			Protocol: port.Protocol.ToV1(),
@@ -139,6 +185,13 @@ func convertWorkloadEndpointV2ToV1Value(val interface{}) (interface{}, error) {
 		}
 	}
 
+	// Synthesize the namespace and orchestrator labels from the WEP itself.  These are normally
+	// set when the WEP is stored (or converted from a Pod), but we do not rely on that, and we
+	// override any value that does not match so that policy selecting on these labels
+	// cannot be spoofed.
+	synthesizeLabel(v3res, labels, apiv3.LabelNamespace, v3res.Namespace)
+	synthesizeLabel(v3res, labels, apiv3.LabelOrchestrator, v3res.Spec.Orchestrator)
+
 	// Add a label for the WEP's serviceaccount if present. We do this in the syncer rather than on the
 	// workload endpoint when we create it, because it is possible that a serviceaccount name is longer than
 	// the allowable character limit for a label.
@@ -164,15 +217,108 @@ func convertWorkloadEndpointV2ToV1Value(val interface{}) (interface{}, error) {
 		IPv6Gateway:  ipv6Gateway,
 		Ports:        ports,
 		GenerateName: v3res.GenerateName,
+		QoSControls:  convertBandwidthAnnotations(v3res),
 	}
 
 	return v1value, nil
 }
 
+// The range of bandwidths accepted in the bandwidth annotations.  This matches the range
+// accepted by Kubernetes.
+var (
+	minBandwidth = resource.MustParse("1k")
+	maxBandwidth = resource.MustParse("1P")
+)
+
+// convertBandwidthAnnotations converts the bandwidth annotations of the WEP into QoS controls.
+// Malformed bandwidths are ignored.  Returns nil if there are no valid bandwidths.
+func convertBandwidthAnnotations(v3res *apiv3.WorkloadEndpoint) *model.QoSControls {
+	ingress := parseBandwidthAnnotation(v3res, conversion.AnnotationIngressBandwidth)
+	egress := parseBandwidthAnnotation(v3res, conversion.AnnotationEgressBandwidth)
+	if ingress == 0 && egress == 0 {
+		return nil
+	}
+	return &model.QoSControls{
+		IngressBandwidth: ingress,
+		EgressBandwidth:  egress,
+	}
+}
+
+// parseBandwidthAnnotation returns the bandwidth in bits per second from the named annotation,
+// or 0 if the annotation is not set or is not valid.
+func parseBandwidthAnnotation(v3res *apiv3.WorkloadEndpoint, annotation string) int64 {
+	value, ok := v3res.Annotations[annotation]
+	if !ok {
+		return 0
+	}
+	q, err := resource.ParseQuantity(value)
+	if err == nil && (q.Cmp(minBandwidth) < 0 || q.Cmp(maxBandwidth) > 0) {
+		err = fmt.Errorf("bandwidth must be between %s and %s", minBandwidth.String(), maxBandwidth.String())
+	}
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"name":       v3res.Name,
+			"namespace":  v3res.Namespace,
+			"annotation": annotation,
+			"value":      value,
+		}).Warn("Ignoring WEP bandwidth annotation with invalid value")
+		return 0
+	}
+	return q.Value()
+}
+
+// mergeNamespaceLabels adds the supplied namespace labels to the WEP labels, using the namespace
+// label prefix.  Existing WEP labels take precedence, and the namespace name label is added first
+// so that it cannot be overridden.
+func mergeNamespaceLabels(v3res *apiv3.WorkloadEndpoint, labels, nsLabels map[string]string) {
+	synthesizeLabel(v3res, labels, conversion.NamespaceLabelPrefix+conversion.NameLabel, v3res.Namespace)
+	for k, v := range nsLabels {
+		key := conversion.NamespaceLabelPrefix + k
+		if errs := k8svalidation.IsQualifiedName(key); len(errs) != 0 {
+			log.WithFields(log.Fields{
+				"name":      v3res.Name,
+				"namespace": v3res.Namespace,
+				"label":     key,
+				"reason":    strings.Join(errs, "; "),
+			}).Warn("Ignoring namespace label that is not a valid WEP label")
+			continue
+		}
+		if _, ok := labels[key]; ok {
+			log.WithFields(log.Fields{
+				"name":      v3res.Name,
+				"namespace": v3res.Namespace,
+				"label":     key,
+			}).Debug("WEP label takes precedence over namespace label")
+			continue
+		}
+		labels[key] = v
+	}
+}
+
+// synthesizeLabel sets the named label to the supplied value, logging if the WEP had a
+// conflicting value for the label.  No label is added if the value is empty.
+func synthesizeLabel(v3res *apiv3.WorkloadEndpoint, labels map[string]string, name, value string) {
+	if value == "" {
+		return
+	}
+	if current, ok := labels[name]; ok && current != value {
+		log.WithFields(log.Fields{
+			"name":      v3res.Name,
+			"namespace": v3res.Namespace,
+			"label":     name,
+			"value":     current,
+			"expected":  value,
+		}).Warn("Overriding WEP label that does not match the WEP")
+	}
+	labels[name] = value
+}
+
+// ConvertV2ToV1IPNAT converts the IPNAT to the v1 model, or returns nil if either address is
+// invalid.  The addresses may be given as IP addresses or as host CIDRs.
 func ConvertV2ToV1IPNAT(ipnat apiv3.IPNAT) *model.IPNAT {
-	internalip := cnet.ParseIP(ipnat.InternalIP)
-	externalip := cnet.ParseIP(ipnat.ExternalIP)
-	if internalip != nil && externalip != nil {
+	internalip, ierr := cnet.ParseHostIP(ipnat.InternalIP)
+	externalip, eerr := cnet.ParseHostIP(ipnat.ExternalIP)
+	if ierr == nil && eerr == nil {
 		return &model.IPNAT{
 			IntIP: *internalip,
 			ExtIP: *externalip,
@@ -16,6 +16,7 @@ package updateprocessors_test
 
 import (
 	"net"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -147,7 +148,7 @@ var _ = Describe("Test the WorkloadEndpoint update processor", func() {
 		res.Spec.Ports = []apiv3.EndpointPort{
 			{
 				Name:     "portname",
-				Protocol: numorstring.ProtocolFromInt(uint8(30)),
+				Protocol: numorstring.ProtocolFromInt(uint8(6)),
 				Port:     uint16(8080),
 			},
 		}
@@ -178,7 +179,7 @@ var _ = Describe("Test the WorkloadEndpoint update processor", func() {
 					Ports: []model.EndpointPort{
 						{
 							Name:     "portname",
-							Protocol: numorstring.ProtocolFromInt(uint8(30)),
+							Protocol: numorstring.ProtocolFromInt(uint8(6)),
 							Port:     uint16(8080),
 						},
 					},
@@ -201,6 +202,108 @@ var _ = Describe("Test the WorkloadEndpoint update processor", func() {
 		}))
 	})
 
+	It("should round-trip TCP and UDP named ports", func() {
+		up := updateprocessors.NewWorkloadEndpointUpdateProcessor()
+
+		res := apiv3.NewWorkloadEndpoint()
+		res.Namespace = ns1
+		res.Spec.Node = hn1
+		res.Spec.Orchestrator = oid1
+		res.Spec.Workload = wid1
+		res.Spec.Endpoint = eid1
+		res.Spec.InterfaceName = iface1
+		res.Spec.IPNetworks = []string{"10.100.10.1"}
+		res.Spec.Ports = []apiv3.EndpointPort{
+			{
+				Name:     "http",
+				Protocol: numorstring.ProtocolFromString("TCP"),
+				Port:     uint16(80),
+			},
+			{
+				Name:     "dns",
+				Protocol: numorstring.ProtocolFromString("UDP"),
+				Port:     uint16(53),
+			},
+		}
+
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3WorkloadEndpointKey1,
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		wep := kvps[0].Value.(*model.WorkloadEndpoint)
+		Expect(wep.Ports).To(Equal([]model.EndpointPort{
+			{
+				Name:     "http",
+				Protocol: numorstring.ProtocolFromStringV1("tcp"),
+				Port:     uint16(80),
+			},
+			{
+				Name:     "dns",
+				Protocol: numorstring.ProtocolFromStringV1("udp"),
+				Port:     uint16(53),
+			},
+		}))
+
+		By("converting the v1 protocols back to v3")
+		for i, port := range wep.Ports {
+			Expect(port.Name).To(Equal(res.Spec.Ports[i].Name))
+			Expect(port.Port).To(Equal(res.Spec.Ports[i].Port))
+			Expect(numorstring.ProtocolV3FromProtocolV1(port.Protocol)).To(Equal(res.Spec.Ports[i].Protocol))
+		}
+	})
+
+	It("should drop ports with an invalid protocol and port combination", func() {
+		up := updateprocessors.NewWorkloadEndpointUpdateProcessor()
+
+		res := apiv3.NewWorkloadEndpoint()
+		res.Namespace = ns1
+		res.Spec.Node = hn1
+		res.Spec.Orchestrator = oid1
+		res.Spec.Workload = wid1
+		res.Spec.Endpoint = eid1
+		res.Spec.InterfaceName = iface1
+		res.Spec.IPNetworks = []string{"10.100.10.1"}
+		res.Spec.Ports = []apiv3.EndpointPort{
+			{
+				Name:     "icmp",
+				Protocol: numorstring.ProtocolFromString("ICMP"),
+				Port:     uint16(80),
+			},
+			{
+				Name:     "proto30",
+				Protocol: numorstring.ProtocolFromInt(uint8(30)),
+				Port:     uint16(8080),
+			},
+			{
+				Name:     "noport",
+				Protocol: numorstring.ProtocolFromString("TCP"),
+			},
+			{
+				Name:     "sctp",
+				Protocol: numorstring.ProtocolFromString("SCTP"),
+				Port:     uint16(9000),
+			},
+		}
+
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3WorkloadEndpointKey1,
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		Expect(kvps[0].Value.(*model.WorkloadEndpoint).Ports).To(Equal([]model.EndpointPort{
+			{
+				Name:     "sctp",
+				Protocol: numorstring.ProtocolFromStringV1("sctp"),
+				Port:     uint16(9000),
+			},
+		}))
+	})
+
 	It("should fail to convert an invalid resource", func() {
 		up := updateprocessors.NewWorkloadEndpointUpdateProcessor()
 
@@ -368,4 +471,200 @@ var _ = Describe("Test the WorkloadEndpoint update processor", func() {
 			Revision: "abcde",
 		}))
 	})
+
+	It("should synthesize the namespace and orchestrator labels", func() {
+		up := updateprocessors.NewWorkloadEndpointUpdateProcessor()
+
+		res := apiv3.NewWorkloadEndpoint()
+		res.Namespace = ns1
+		res.Labels = map[string]string{
+			"k1": "v1",
+		}
+		res.Spec.Node = hn1
+		res.Spec.Orchestrator = oid1
+		res.Spec.Workload = wid1
+		res.Spec.Endpoint = eid1
+		res.Spec.InterfaceName = iface1
+		res.Spec.IPNetworks = []string{"10.100.10.1"}
+		res.Spec.ServiceAccountName = "sa1"
+
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3WorkloadEndpointKey1,
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		Expect(kvps[0].Value.(*model.WorkloadEndpoint).Labels).To(Equal(map[string]string{
+			"projectcalico.org/namespace":      ns1,
+			"projectcalico.org/orchestrator":   oid1,
+			"projectcalico.org/serviceaccount": "sa1",
+			"k1":                               "v1",
+		}))
+	})
+
+	It("should override synthesized labels that do not match the WEP", func() {
+		up := updateprocessors.NewWorkloadEndpointUpdateProcessor()
+
+		res := apiv3.NewWorkloadEndpoint()
+		res.Namespace = ns1
+		res.Labels = map[string]string{
+			"projectcalico.org/namespace":      ns2,
+			"projectcalico.org/orchestrator":   oid2,
+			"projectcalico.org/serviceaccount": "other",
+		}
+		res.Spec.Node = hn1
+		res.Spec.Orchestrator = oid1
+		res.Spec.Workload = wid1
+		res.Spec.Endpoint = eid1
+		res.Spec.InterfaceName = iface1
+		res.Spec.IPNetworks = []string{"10.100.10.1"}
+		res.Spec.ServiceAccountName = "sa1"
+
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3WorkloadEndpointKey1,
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		Expect(kvps[0].Value.(*model.WorkloadEndpoint).Labels).To(Equal(map[string]string{
+			"projectcalico.org/namespace":      ns1,
+			"projectcalico.org/orchestrator":   oid1,
+			"projectcalico.org/serviceaccount": "sa1",
+		}))
+	})
+
+	It("should convert the bandwidth annotations to QoS controls", func() {
+		up := updateprocessors.NewWorkloadEndpointUpdateProcessor()
+
+		res := apiv3.NewWorkloadEndpoint()
+		res.Namespace = ns1
+		res.Annotations = map[string]string{
+			conversion.AnnotationIngressBandwidth: "10M",
+			conversion.AnnotationEgressBandwidth:  "1Gi",
+		}
+		res.Spec.Node = hn1
+		res.Spec.Orchestrator = oid1
+		res.Spec.Workload = wid1
+		res.Spec.Endpoint = eid1
+		res.Spec.InterfaceName = iface1
+		res.Spec.IPNetworks = []string{"10.100.10.1"}
+
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3WorkloadEndpointKey1,
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(1))
+		Expect(kvps[0].Value.(*model.WorkloadEndpoint).QoSControls).To(Equal(&model.QoSControls{
+			IngressBandwidth: 10000000,
+			EgressBandwidth:  1073741824,
+		}))
+	})
+
+	It("should drop malformed bandwidth annotations", func() {
+		up := updateprocessors.NewWorkloadEndpointUpdateProcessor()
+
+		res := apiv3.NewWorkloadEndpoint()
+		res.Namespace = ns1
+		res.Spec.Node = hn1
+		res.Spec.Orchestrator = oid1
+		res.Spec.Workload = wid1
+		res.Spec.Endpoint = eid1
+		res.Spec.InterfaceName = iface1
+		res.Spec.IPNetworks = []string{"10.100.10.1"}
+
+		process := func(annotations map[string]string) *model.QoSControls {
+			res.Annotations = annotations
+			kvps, err := up.Process(&model.KVPair{
+				Key:      v3WorkloadEndpointKey1,
+				Value:    res,
+				Revision: "abcde",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			return kvps[0].Value.(*model.WorkloadEndpoint).QoSControls
+		}
+
+		By("dropping a malformed ingress bandwidth but keeping a valid egress bandwidth")
+		Expect(process(map[string]string{
+			conversion.AnnotationIngressBandwidth: "ten megabits",
+			conversion.AnnotationEgressBandwidth:  "5M",
+		})).To(Equal(&model.QoSControls{EgressBandwidth: 5000000}))
+
+		By("dropping bandwidths outside of the valid range")
+		Expect(process(map[string]string{
+			conversion.AnnotationIngressBandwidth: "10",
+			conversion.AnnotationEgressBandwidth:  "2P",
+		})).To(BeNil())
+
+		By("dropping a negative bandwidth")
+		Expect(process(map[string]string{
+			conversion.AnnotationIngressBandwidth: "-10M",
+		})).To(BeNil())
+	})
+
+	Describe("with namespace labels", func() {
+		nsLabels := map[string]map[string]string{
+			ns1: {
+				"team":                   "a",
+				"testLabel":              "namespace",
+				"projectcalico.org/name": "spoofed",
+				strings.Repeat("x", 60):  "too-long-once-prefixed",
+			},
+		}
+		up := updateprocessors.NewWorkloadEndpointUpdateProcessorWithNamespaceLabels(func(namespace string) (map[string]string, bool) {
+			labels, ok := nsLabels[namespace]
+			return labels, ok
+		})
+
+		newWEP := func(key model.ResourceKey) *apiv3.WorkloadEndpoint {
+			res := apiv3.NewWorkloadEndpoint()
+			res.Name = key.Name
+			res.Namespace = key.Namespace
+			res.Labels = map[string]string{
+				"testLabel": "pod",
+				"pcns.team": "spoofed",
+			}
+			res.Spec.Orchestrator = oid1
+			res.Spec.InterfaceName = iface1
+			res.Spec.IPNetworks = []string{"10.100.10.1"}
+			return res
+		}
+
+		It("should merge the namespace labels, favoring the WEP labels", func() {
+			kvps, err := up.Process(&model.KVPair{
+				Key:      v3WorkloadEndpointKey1,
+				Value:    newWEP(v3WorkloadEndpointKey1),
+				Revision: "abcde",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			Expect(kvps[0].Value.(*model.WorkloadEndpoint).Labels).To(Equal(map[string]string{
+				"testLabel":                      "pod",
+				"projectcalico.org/namespace":    ns1,
+				"projectcalico.org/orchestrator": oid1,
+				"pcns.team":                      "a",
+				"pcns.testLabel":                 "namespace",
+				"pcns.projectcalico.org/name":    ns1,
+			}))
+		})
+
+		It("should not merge labels for an unknown namespace", func() {
+			kvps, err := up.Process(&model.KVPair{
+				Key:      v3WorkloadEndpointKey2,
+				Value:    newWEP(v3WorkloadEndpointKey2),
+				Revision: "abcde",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps).To(HaveLen(1))
+			Expect(kvps[0].Value.(*model.WorkloadEndpoint).Labels).To(Equal(map[string]string{
+				"testLabel":                      "pod",
+				"projectcalico.org/namespace":    ns2,
+				"projectcalico.org/orchestrator": oid1,
+			}))
+		})
+	})
 })
@@ -16,6 +16,8 @@ package watchersyncer
 
 import (
 	"context"
+	"crypto/sha256"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -33,7 +35,8 @@ import (
 // types:
 // -  An error
 // -  An api.Update
-// -  A api.SyncStatus (only for the very first InSync notification)
+// -  A cacheSynced (only for the very first InSync notification)
+// -  A cacheResync (only when falling back to a full list after resuming from a revision)
 type watcherCache struct {
 	logger               *logrus.Entry
 	client               api.Client
@@ -44,21 +47,44 @@ type watcherCache struct {
 	hasSynced            bool
 	resourceType         ResourceType
 	currentWatchRevision string
+	connected            bool
+	revisionLock         sync.Mutex
+	resumed              bool
 }
 
 var (
 	ListRetryInterval     = 1000 * time.Millisecond
 	WatchPollInterval     = 5000 * time.Millisecond
 	DefaultErrorThreshold = 15
+
+	// ListPageSize is the maximum number of resources requested in each page of the list
+	// performed by a resync, for datastores that support paged lists (see api.PagedLister).
+	// Listing a page at a time bounds the memory required to resync large resource types.
+	ListPageSize int64 = 500
 )
 
 // cacheEntry is an entry in our cache.  It groups the a key with the last known
 // revision that we processed.  We store the revision so that we can determine
 // if an entry has been updated (and therefore whether we need to send an update
-// event in the syncer callback).
+// event in the syncer callback).  We also store a hash of the canonical serialization
+// of the value so that updates that do not change the value can be suppressed.  The
+// hash is a byte-level comparison, so for values that define their own equality we
+// store the value instead.
 type cacheEntry struct {
 	revision string
 	key      model.Key
+	hash     *[sha256.Size]byte
+	value    model.ValueEqualer
+}
+
+// sameValue returns true if the value is the same as the value of the cache entry.  If the value
+// of the entry implements model.ValueEqualer then the values are compared using EqualValue,
+// otherwise the hashes of their canonical serializations are compared.
+func (e cacheEntry) sameValue(value interface{}, hash *[sha256.Size]byte) bool {
+	if e.value != nil {
+		return e.value.EqualValue(value)
+	}
+	return e.hash != nil && hash != nil && *e.hash == *hash
 }
 
 // Create a new watcherCache.
@@ -113,13 +139,21 @@ mainLoop:
 				}
 				kvp.Value = nil
 				wc.handleWatchListEvent(kvp)
+			case api.WatchBookmark:
+				// A bookmark only advances the revision that we resume watching from, there
+				// are no updates to send.
+				if event.New != nil && event.New.Revision != "" {
+					wc.logger.WithField("Revision", event.New.Revision).Debug("Bookmark received")
+					wc.setWatchRevision(event.New.Revision)
+				}
 			case api.WatchError:
 				// Handle a WatchError. This error triggered from upstream, all type
 				// of WatchError are treated equally,log the Error and trigger a full resync. We only log at info
 				// because errors may occur due to compaction causing revisions to no longer be valid - in this case
 				// we simply need to do a full resync.
 				wc.logger.WithError(event.Error).Infof("Watch error received from Upstream")
-				wc.currentWatchRevision = ""
+				wc.setConnected(false)
+				wc.setWatchRevision("")
 				wc.resyncAndCreateWatcher(ctx)
 			default:
 				// Unknown event type - not much we can do other than log.
@@ -173,17 +207,29 @@ func (wc *watcherCache) resyncAndCreateWatcher(ctx context.Context) {
 		if performFullResync {
 			wc.logger.Info("Full resync is required")
 
+			// If we resumed from a revision then the consumer may hold resources that we have not
+			// sent and that have since been deleted, for which the list cannot send deletes.
+			// Notify the main WatcherSyncer so that the consumer can discard them.  From now on
+			// the consumer only holds the resources that we have sent.
+			if wc.resumed {
+				wc.logger.Info("Resumed revision is no longer valid, notifying full resync")
+				wc.results <- cacheResync{kind: wc.kind()}
+				wc.resumed = false
+			}
+
 			// Notify the converter that we are resyncing.
 			if wc.resourceType.UpdateProcessor != nil {
 				wc.logger.Debug("Trigger converter resync notification")
 				wc.resourceType.UpdateProcessor.OnSyncerStarting()
 			}
 
-			// Start the sync by Listing the current resources.
-			l, err := wc.client.List(ctx, wc.resourceType.ListInterface, "")
+			// Start the sync by Listing the current resources.  Updates are sent for each page of
+			// the list as it is received.
+			revision, err := wc.listCurrent(ctx)
 			if err != nil {
 				// Failed to perform the list.  Pause briefly (so we don't tight loop) and retry.
 				wc.logger.WithError(err).Info("Failed to perform list of current data during resync")
+				wc.setConnected(false)
 				select {
 				case <-time.After(ListRetryInterval):
 					continue
@@ -194,24 +240,13 @@ func (wc *watcherCache) resyncAndCreateWatcher(ctx context.Context) {
 				}
 			}
 
-			// Once this point is reached, it's important not to drop out if the context is cancelled.
-			// Move the current resources over to the oldResources
-			wc.oldResources = wc.resources
-			wc.resources = make(map[string]cacheEntry, 0)
-
-			// Send updates for each of the resources we listed - this will revalidate entries in
-			// the oldResources map.
-			for _, kvp := range l.KVPairs {
-				wc.handleWatchListEvent(kvp)
-			}
-
 			// We've listed the current settings.  Complete the sync by notifying the main WatcherSyncer
 			// go routine (if we haven't already) and by sending deletes for the old resources that were
 			// not acknowledged by the List.  The oldResources will be empty after this call.
 			wc.finishResync()
 
 			// Store the current watch revision.  This gets updated on any new add/modified event.
-			wc.currentWatchRevision = l.Revision
+			wc.setWatchRevision(revision)
 		}
 
 		// And now start watching from the revision returned by the List, or from a previous watch event
@@ -241,6 +276,7 @@ func (wc *watcherCache) resyncAndCreateWatcher(ctx context.Context) {
 
 			// We hit an error creating the Watch.  Trigger a full resync.
 			wc.logger.WithError(err).WithField("performFullResync", performFullResync).Info("Failed to create watcher")
+			wc.setConnected(false)
 			performFullResync = true
 			continue
 		}
@@ -248,10 +284,112 @@ func (wc *watcherCache) resyncAndCreateWatcher(ctx context.Context) {
 		// Store the watcher and exit back to the main event loop.
 		wc.logger.Debug("Resync completed, now watching for change events")
 		wc.watch = w
+		wc.setConnected(true)
+
+		// If we resumed from a previous revision then no list was performed.  The watch was
+		// accepted, so we are now in-sync.
+		if !wc.hasSynced {
+			wc.logger.Info("Resumed watching from previous revision")
+			wc.finishResync()
+		}
 		return
 	}
 }
 
+// listCurrent lists the current resources a page at a time, sending updates for the resources in
+// each page, and returns the revision of the list.  The resources that are known before the list are
+// moved into the oldResources so that they are revalidated by the list.  If the list fails part way
+// through then the resources that were not revalidated are restored, so that the cache still holds
+// every resource for which an update has been sent.
+func (wc *watcherCache) listCurrent(ctx context.Context) (string, error) {
+	var token string
+	for page := 0; ; page++ {
+		l, next, err := api.ListPaged(ctx, wc.client, wc.resourceType.ListInterface, ListPageSize, token)
+		if err != nil {
+			if page > 0 {
+				wc.logger.WithField("Pages", page).Info("List failed part way through, restoring unvalidated resources")
+				for k, v := range wc.oldResources {
+					wc.resources[k] = v
+				}
+				wc.oldResources = nil
+			}
+			return "", err
+		}
+
+		if page == 0 {
+			// The datastore is reachable, even if the watch below turns out not to be supported.
+			wc.setConnected(true)
+
+			// Once this point is reached, it's important not to drop out if the context is cancelled.
+			// Move the current resources over to the oldResources
+			wc.oldResources = wc.resources
+			wc.resources = make(map[string]cacheEntry, 0)
+		}
+
+		// Send updates for each of the resources we listed - this will revalidate entries in
+		// the oldResources map.
+		wc.handleListEvents(l.KVPairs)
+
+		if next == "" {
+			return l.Revision, nil
+		}
+		wc.logger.WithFields(logrus.Fields{"Page": page, "NumEntries": len(l.KVPairs)}).Debug("Listing next page")
+		token = next
+	}
+}
+
+// name returns the name used to identify the resource type of this cache.
+func (wc *watcherCache) name() string {
+	return model.ListOptionsToDefaultPathRoot(wc.resourceType.ListInterface)
+}
+
+// kind returns the kind reported in the per-type in-sync callback.  This is the resource kind
+// for v3 resources, or the name of the resource type otherwise.
+func (wc *watcherCache) kind() string {
+	if ro, ok := wc.resourceType.ListInterface.(model.ResourceListOptions); ok {
+		return ro.Kind
+	}
+	return wc.name()
+}
+
+// startFromRevision configures the cache to resume watching from the supplied revision.
+// This must be called before the cache is started.
+func (wc *watcherCache) startFromRevision(revision string) {
+	wc.logger.WithField("Revision", revision).Info("Configuring cache to resume from revision")
+	wc.setWatchRevision(revision)
+	wc.resumed = true
+}
+
+// setWatchRevision stores the current watch revision.
+func (wc *watcherCache) setWatchRevision(revision string) {
+	wc.revisionLock.Lock()
+	defer wc.revisionLock.Unlock()
+	wc.currentWatchRevision = revision
+}
+
+// watchRevision returns the current watch revision.  This may be called from outside of the
+// cache processing goroutine.
+func (wc *watcherCache) watchRevision() string {
+	wc.revisionLock.Lock()
+	defer wc.revisionLock.Unlock()
+	return wc.currentWatchRevision
+}
+
+// setConnected stores whether the cache is currently able to list or watch the datastore.
+func (wc *watcherCache) setConnected(connected bool) {
+	wc.revisionLock.Lock()
+	defer wc.revisionLock.Unlock()
+	wc.connected = connected
+}
+
+// isConnected returns whether the cache is currently able to list or watch the datastore.
+// This may be called from outside of the cache goroutine.
+func (wc *watcherCache) isConnected() bool {
+	wc.revisionLock.Lock()
+	defer wc.revisionLock.Unlock()
+	return wc.connected
+}
+
 func (wc *watcherCache) cleanExistingWatcher() {
 	if wc.watch != nil {
 		wc.logger.Debug("Stopping previous watcher")
@@ -269,7 +407,7 @@ func (wc *watcherCache) finishResync() {
 	// event when it has received synced events from each cache. Once in-sync the cache remains in-sync.
 	if !wc.hasSynced {
 		wc.logger.Info("Sending synced update")
-		wc.results <- api.InSync
+		wc.results <- cacheSynced{kind: wc.kind()}
 		wc.hasSynced = true
 	}
 
@@ -294,11 +432,40 @@ func (wc *watcherCache) finishResync() {
 	wc.oldResources = nil
 }
 
+// handleListEvents handles the events from a list.  If the update processor supports batch
+// processing then the events are converted as a single batch, otherwise each event is handled
+// in turn by handleWatchListEvent.
+func (wc *watcherCache) handleListEvents(kvps []*model.KVPair) {
+	bp, ok := wc.resourceType.UpdateProcessor.(SyncerUpdateProcessorBatch)
+	if !ok {
+		for _, kvp := range kvps {
+			wc.handleWatchListEvent(kvp)
+		}
+		return
+	}
+
+	// Track the resource version from the last of the list events.
+	if len(kvps) > 0 {
+		wc.setWatchRevision(kvps[len(kvps)-1].Revision)
+	}
+
+	// Convert the batch of events.
+	converted, err := bp.ProcessBatch(kvps)
+	for _, kvp := range converted {
+		wc.handleConvertedWatchEvent(kvp)
+	}
+
+	// If we hit a conversion error, notify the main syncer.
+	if err != nil {
+		wc.results <- err
+	}
+}
+
 // handleWatchListEvent handles a watch event converting it if required and passing to
 // handleConvertedWatchEvent to send the appropriate update types.
 func (wc *watcherCache) handleWatchListEvent(kvp *model.KVPair) {
 	// Track the resource version from this watch/list event.
-	wc.currentWatchRevision = kvp.Revision
+	wc.setWatchRevision(kvp.Revision)
 
 	if wc.resourceType.UpdateProcessor == nil {
 		// No update processor - handle immediately.
@@ -337,14 +504,23 @@ func (wc *watcherCache) handleAddedOrModifiedUpdate(kvp *model.KVPair) {
 	thisRevision := kvp.Revision
 	wc.markAsValid(thisKeyString)
 
+	thisHash := valueHash(kvp)
+
 	// If the resource is already in our map, then this is a modified event.  Check the
-	// revision to see if we actually need to send an update.
+	// revision and the value to see if we actually need to send an update.
 	if resource, ok := wc.resources[thisKeyString]; ok {
 		if resource.revision == thisRevision {
 			// No update to revision, so no event to send.
 			wc.logger.WithField("Key", thisKeyString).Debug("Swallowing event update from datastore because entry is same as cached entry")
 			return
 		}
+		if wc.resourceType.UpdateMode != UpdateModeFull && resource.sameValue(kvp.Value, thisHash) {
+			// No update to the value, so no event to send.  Store the latest revision.
+			wc.logger.WithField("Key", thisKeyString).Debug("Swallowing event update from datastore because value is same as cached entry")
+			resource.revision = thisRevision
+			wc.resources[thisKeyString] = resource
+			return
+		}
 		// Resource is modified, send an update event and store the latest revision.
 		wc.logger.WithField("Key", thisKeyString).Debug("Datastore entry modified, sending syncer update")
 		wc.results <- []api.Update{{
@@ -352,6 +528,8 @@ func (wc *watcherCache) handleAddedOrModifiedUpdate(kvp *model.KVPair) {
 			KVPair:     *kvp,
 		}}
 		resource.revision = thisRevision
+		resource.hash = thisHash
+		resource.value = valueEqualer(kvp)
 		wc.resources[thisKeyString] = resource
 		return
 	}
@@ -366,7 +544,28 @@ func (wc *watcherCache) handleAddedOrModifiedUpdate(kvp *model.KVPair) {
 	wc.resources[thisKeyString] = cacheEntry{
 		revision: thisRevision,
 		key:      thisKey,
+		hash:     thisHash,
+		value:    valueEqualer(kvp),
+	}
+}
+
+// valueEqualer returns the value in the KVPair if it defines its own equality, or nil otherwise.
+func valueEqualer(kvp *model.KVPair) model.ValueEqualer {
+	if ve, ok := kvp.Value.(model.ValueEqualer); ok {
+		return ve
+	}
+	return nil
+}
+
+// valueHash returns a hash of the canonical serialization of the value in the KVPair, or nil
+// if the value cannot be serialized.
+func valueHash(kvp *model.KVPair) *[sha256.Size]byte {
+	data, err := model.SerializeValueCanonical(kvp)
+	if err != nil {
+		return nil
 	}
+	hash := sha256.Sum256(data)
+	return &hash
 }
 
 // handleDeletedWatchEvent sends a deleted event and removes the resource key from our cache.
@@ -375,8 +574,9 @@ func (wc *watcherCache) handleDeletedUpdate(key model.Key) {
 	wc.markAsValid(thisKeyString)
 
 	// If we have seen an added event for this key then send a deleted event and remove
-	// from the cache.
-	if _, ok := wc.resources[thisKeyString]; ok {
+	// from the cache.  If we resumed from a previous revision then our cache may not contain
+	// the key, so always send the deleted event.
+	if _, ok := wc.resources[thisKeyString]; ok || wc.resumed {
 		wc.logger.WithField("Key", thisKeyString).Debug("Datastore entry deleted, sending syncer update")
 		wc.results <- []api.Update{{
 			UpdateType: api.UpdateTypeKVDeleted,
@@ -18,7 +18,9 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/projectcalico/libcalico-go/lib/backend/api"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
@@ -38,8 +40,28 @@ type ResourceType struct {
 	// UpdateProcessor converts the raw KVPairs returned from the datastore into the appropriate
 	// KVPairs required for the syncer.  This is optional.
 	UpdateProcessor SyncerUpdateProcessor
+
+	// UpdateMode controls which of the KVPairs converted from a datastore update are sent to the
+	// syncer callbacks.  This is optional and defaults to UpdateModeDeltas.
+	UpdateMode UpdateMode
 }
 
+// UpdateMode controls which of the KVPairs converted from a datastore update are sent to the
+// syncer callbacks.
+type UpdateMode int
+
+const (
+	// UpdateModeDeltas sends only the KVPairs whose value has changed.  A KVPair that is
+	// re-emitted with a new revision but the same value is swallowed, which reduces the volume of
+	// updates for resources that convert to many KVPairs, such as Nodes.
+	UpdateModeDeltas UpdateMode = iota
+
+	// UpdateModeFull sends every KVPair converted from a new revision of a resource, even if its
+	// value has not changed, so that the consumer receives the full current value of the
+	// resource on every change.
+	UpdateModeFull
+)
+
 // SyncerUpdateProcessor is used to convert a Watch update into one or more additional
 // Syncer updates.
 type SyncerUpdateProcessor interface {
@@ -60,7 +82,156 @@ type SyncerUpdateProcessor interface {
 	OnSyncerStarting()
 }
 
-// New creates a new multiple Watcher-backed api.Syncer.
+// SyncerUpdateProcessorKeyTypes is an optional interface that can be implemented by a
+// SyncerUpdateProcessor.  It allows the set of key types emitted by the processor to be
+// determined without processing any updates, for example to route updates by key type.
+type SyncerUpdateProcessorKeyTypes interface {
+	// ProducedKeyTypes returns the type names of the model.Key types (e.g. "HostIPKey")
+	// that the processor may emit from Process.
+	ProducedKeyTypes() []string
+}
+
+// SyncerUpdateProcessorBatch is an optional interface that can be implemented by a
+// SyncerUpdateProcessor that is able to process multiple updates more efficiently than by
+// processing each update in turn.
+type SyncerUpdateProcessorBatch interface {
+	// ProcessBatch processes the supplied watch updates in order.  The resulting updates must
+	// be equivalent to those obtained by calling Process for each update, although updates
+	// that are superseded within the batch may be omitted.  If any update fails to convert,
+	// the first error is returned alongside the converted updates.
+	ProcessBatch([]*model.KVPair) ([]*model.KVPair, error)
+}
+
+// SyncerUpdateProcessorShutdown is an optional interface that can be implemented by a
+// SyncerUpdateProcessor that holds state derived from the updates it has processed.
+type SyncerUpdateProcessorShutdown interface {
+	// Shutdown returns deletes for all of the state held by the processor, so that a clean
+	// teardown can propagate the removals, and clears the state.  For example, a processor
+	// that tracks the Blocks emitted for each Node returns a delete for each tracked Block.
+	Shutdown() []*model.KVPair
+}
+
+// ProcessBatch processes the supplied watch updates using the update processor.  If the
+// processor implements SyncerUpdateProcessorBatch then the updates are processed as a batch,
+// otherwise each update is processed in turn using Process.  If any update fails to convert,
+// the first error is returned alongside the converted updates.
+func ProcessBatch(p SyncerUpdateProcessor, kvps []*model.KVPair) ([]*model.KVPair, error) {
+	if bp, ok := p.(SyncerUpdateProcessorBatch); ok {
+		return bp.ProcessBatch(kvps)
+	}
+	var updates []*model.KVPair
+	var firstErr error
+	for _, kvp := range kvps {
+		converted, err := p.Process(kvp)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		updates = append(updates, converted...)
+	}
+	return updates, firstErr
+}
+
+// RevisionTracker is implemented by the syncer returned by New.  It allows a process to persist
+// the last revision seen for each resource type and to resume from those revisions after a
+// restart, without performing a full re-list of the datastore.
+type RevisionTracker interface {
+	// ExportRevisions returns, for each resource type, the last revision received from the
+	// datastore, keyed off the default path root of the resource type ListInterface.  Resource
+	// types that have neither been listed nor resumed from a revision are not included.
+	//
+	// While the syncer is running, the updates for the returned revisions may not yet have been
+	// sent to the callbacks, so a consumer that persists them may miss those updates when it
+	// resumes.  Revisions exported after Stop has returned are consistent with the updates sent.
+	ExportRevisions() map[string]string
+
+	// StartFromRevisions configures the syncer to resume watching from the supplied revisions
+	// rather than performing an initial list.  This must be called before Start.  The revisions
+	// should be as returned from ExportRevisions.  Resource types with no supplied revision, or
+	// for which the datastore rejects the revision, fall back to a full list.
+	//
+	// Since no list is performed, the syncer cannot send updates for resources that have not
+	// changed since the supplied revision - the consumer should therefore maintain its own
+	// persisted copy of the data.  For the same reason, until a resource type has been re-listed
+	// the syncer sends deleted updates for keys that it has not itself sent, so the consumer must
+	// tolerate deletes for keys it does not hold.
+	//
+	// If a resumed resource type falls back to a full list, the syncer cannot send deletes for
+	// the persisted resources that were deleted after the supplied revision.  The callbacks are
+	// therefore notified using api.SyncerTypeResyncCallbacks before the list, and the consumer
+	// must discard its persisted copy of the resource type; a consumer that does not implement
+	// that interface may otherwise retain deleted resources.
+	StartFromRevisions(revisions map[string]string)
+}
+
+// SyncerHealth is a point in time report of the health of a syncer, suitable for use by
+// liveness and readiness probes.
+type SyncerHealth struct {
+	// Status is the last status sent to the syncer callbacks.
+	Status api.SyncStatus
+
+	// Connected is true if every resource type is currently able to list or watch the
+	// datastore.
+	Connected bool
+
+	// InSync is true once every resource type has completed its initial sync.
+	InSync bool
+
+	// LastSyncTime is the time at which the syncer became in-sync, or the zero time if it
+	// has not yet done so.
+	LastSyncTime time.Time
+
+	// Revisions is the last revision processed for each resource type, as returned by
+	// RevisionTracker.ExportRevisions.
+	Revisions map[string]string
+}
+
+// HealthReporter is implemented by the syncer returned by New.
+type HealthReporter interface {
+	// HealthReport returns the current health of the syncer.  This is safe to call
+	// concurrently with the syncer processing.
+	HealthReport() SyncerHealth
+}
+
+// ListenerRegistry is implemented by the syncer returned by New.  It allows additional consumers
+// to attach to a syncer that is already running.
+type ListenerRegistry interface {
+	// EnableListeners configures the syncer to maintain a copy of its current state so that it
+	// can be replayed to listeners added with AddListener.  This must be called before Start.
+	// The state is not maintained unless listeners are enabled, since it duplicates the data
+	// held by the consumers.
+	EnableListeners()
+
+	// AddListener attaches an additional set of callbacks to the syncer.  The listener first
+	// receives a replay of the current state of the syncer: the current status and, once the
+	// syncer has started its resync, a ResyncInProgress status followed by a single update
+	// containing an add for every entry currently known to the syncer, any per-type in-sync
+	// notifications, and an InSync status if the syncer is in-sync.  The listener then receives
+	// the same subsequent updates as the existing listeners, which are not affected.
+	//
+	// This is safe to call concurrently with the syncer processing, but must not be called after
+	// Stop.  Listeners must have been enabled using EnableListeners.
+	AddListener(callbacks api.SyncerCallbacks)
+}
+
+// addListener is sent to the main syncer loop to attach a new listener.
+type addListener struct {
+	callbacks api.SyncerCallbacks
+}
+
+// cacheSynced is sent by a watcherCache when it has completed its initial sync.
+type cacheSynced struct {
+	kind string
+}
+
+// cacheResync is sent by a watcherCache that was resumed from a revision when it falls back to a
+// full list.
+type cacheResync struct {
+	kind string
+}
+
+// New creates a new multiple Watcher-backed api.Syncer.  If the callbacks implement
+// api.SyncerTypeSyncedCallbacks, then they are notified as each resource type completes
+// its initial sync.
 func New(client api.Client, resourceTypes []ResourceType, callbacks api.SyncerCallbacks) api.Syncer {
 	rs := &watcherSyncer{
 		watcherCaches: make([]*watcherCache, len(resourceTypes)),
@@ -76,13 +247,75 @@ func New(client api.Client, resourceTypes []ResourceType, callbacks api.SyncerCa
 // watcherSyncer implements the api.Syncer interface.
 type watcherSyncer struct {
 	status        api.SyncStatus
+	lastSyncTime  time.Time
+	statusLock    sync.Mutex
 	watcherCaches []*watcherCache
 	results       chan interface{}
 	numSynced     int
+	syncedKinds   []string
 	callbacks     api.SyncerCallbacks
+	listeners     []api.SyncerCallbacks
 	wgwc          *sync.WaitGroup
 	wgws          *sync.WaitGroup
 	cancel        context.CancelFunc
+
+	// The state replayed to new listeners.  This is nil unless listeners are enabled.
+	state map[string]model.KVPair
+}
+
+// ExportRevisions implements the RevisionTracker interface.
+func (ws *watcherSyncer) ExportRevisions() map[string]string {
+	revisions := make(map[string]string)
+	for _, wc := range ws.watcherCaches {
+		if rev := wc.watchRevision(); rev != "" {
+			revisions[wc.name()] = rev
+		}
+	}
+	return revisions
+}
+
+// HealthReport implements the HealthReporter interface.
+func (ws *watcherSyncer) HealthReport() SyncerHealth {
+	ws.statusLock.Lock()
+	h := SyncerHealth{
+		Status:       ws.status,
+		InSync:       ws.status == api.InSync,
+		LastSyncTime: ws.lastSyncTime,
+	}
+	ws.statusLock.Unlock()
+
+	h.Connected = true
+	for _, wc := range ws.watcherCaches {
+		if !wc.isConnected() {
+			h.Connected = false
+			break
+		}
+	}
+	h.Revisions = ws.ExportRevisions()
+	return h
+}
+
+// EnableListeners implements the ListenerRegistry interface.
+func (ws *watcherSyncer) EnableListeners() {
+	ws.state = make(map[string]model.KVPair)
+}
+
+// AddListener implements the ListenerRegistry interface.  The listener is attached by the main
+// syncer loop, so that the replay is consistent with the updates sent to the existing listeners.
+func (ws *watcherSyncer) AddListener(callbacks api.SyncerCallbacks) {
+	if ws.state == nil {
+		log.Panic("AddListener called on a syncer without listeners enabled")
+	}
+	ws.results <- addListener{callbacks: callbacks}
+}
+
+// StartFromRevisions implements the RevisionTracker interface.
+func (ws *watcherSyncer) StartFromRevisions(revisions map[string]string) {
+	for _, wc := range ws.watcherCaches {
+		if rev := revisions[wc.name()]; rev != "" {
+			wc.startFromRevision(rev)
+		}
+	}
 }
 
 func (ws *watcherSyncer) Start() {
@@ -122,11 +355,25 @@ func (ws *watcherSyncer) Stop() {
 
 }
 
-// Send a status update and store the status.
+// Send a status update and store the status.  The status is stored first so that the health
+// report reflects the status by the time the callbacks are notified.
 func (ws *watcherSyncer) sendStatusUpdate(status api.SyncStatus) {
 	log.WithField("Status", status).Info("Sending status update")
-	ws.callbacks.OnStatusUpdated(status)
+	ws.statusLock.Lock()
 	ws.status = status
+	if status == api.InSync {
+		ws.lastSyncTime = time.Now()
+	}
+	ws.statusLock.Unlock()
+	for _, cb := range ws.allCallbacks() {
+		cb.OnStatusUpdated(status)
+	}
+}
+
+// allCallbacks returns the callbacks supplied to New followed by any listeners added with
+// AddListener.  This is only accessed from the main syncer loop.
+func (ws *watcherSyncer) allCallbacks() []api.SyncerCallbacks {
+	return append([]api.SyncerCallbacks{ws.callbacks}, ws.listeners...)
 }
 
 // run implements the main syncer loop that loops forever receiving watch events and translating
@@ -194,46 +441,120 @@ func (ws *watcherSyncer) processResult(updates []api.Update, result interface{})
 		// If this is a parsing error, and if the callbacks support
 		// it, then send the error update.
 		log.WithError(r).Debug("Error received in main syncer event processing loop")
-		if ec, ok := ws.callbacks.(api.SyncerParseFailCallbacks); ok {
-			log.Debug("syncer receiver can receive parse failed callbacks")
-			if pe, ok := r.(cerrors.ErrorParsingDatastoreEntry); ok {
-				ec.ParseFailed(pe.RawKey, pe.RawValue)
+		for _, cb := range ws.allCallbacks() {
+			if ec, ok := cb.(api.SyncerParseFailCallbacks); ok {
+				log.Debug("syncer receiver can receive parse failed callbacks")
+				if pe, ok := r.(cerrors.ErrorParsingDatastoreEntry); ok {
+					ec.ParseFailed(pe.RawKey, pe.RawValue)
+				}
 			}
 		}
 
-	case api.SyncStatus:
+	case cacheSynced:
 		// Received a synced event.  If we are still waiting for datastore, send a
 		// ResyncInProgress since at least one watcher has connected.
-		log.WithField("SyncUpdate", r).Debug("Received sync status event from watcher")
-		if r == api.InSync {
-			log.Info("Received InSync event from one of the watcher caches")
+		log.WithField("Kind", r.kind).Info("Received InSync event from one of the watcher caches")
+		if ws.status == api.WaitForDatastore {
+			ws.sendStatusUpdate(api.ResyncInProgress)
+		}
 
-			if ws.status == api.WaitForDatastore {
-				ws.sendStatusUpdate(api.ResyncInProgress)
+		// If the callbacks support it, send any updates that we have grouped and then notify
+		// that this resource type is in-sync.
+		for _, cb := range ws.allCallbacks() {
+			if tc, ok := cb.(api.SyncerTypeSyncedCallbacks); ok {
+				updates = ws.sendUpdates(updates)
+				tc.OnTypeSynced(r.kind)
 			}
+		}
 
-			// Increment the count of synced events.
-			ws.numSynced++
+		// Increment the count of synced events, and store the kind for replaying to new
+		// listeners.
+		ws.numSynced++
+		ws.syncedKinds = append(ws.syncedKinds, r.kind)
+
+		// If we have now received synced events from all of our watchers then we are in
+		// sync.  If we have any updates, send them first and then send the status update.
+		if ws.numSynced == len(ws.watcherCaches) {
+			log.Info("All watchers have sync'd data - sending data and final sync")
+			updates = ws.sendUpdates(updates)
+			ws.sendStatusUpdate(api.InSync)
+		}
 
-			// If we have now received synced events from all of our watchers then we are in
-			// sync.  If we have any updates, send them first and then send the status update.
-			if ws.numSynced == len(ws.watcherCaches) {
-				log.Info("All watchers have sync'd data - sending data and final sync")
-				updates = ws.sendUpdates(updates)
-				ws.sendStatusUpdate(api.InSync)
+	case cacheResync:
+		// Received a resync event from a resumed watcher cache.  Send any updates that we have
+		// grouped and then notify the callbacks that support it, so that they can discard
+		// their persisted data before the list updates are sent.
+		log.WithField("Kind", r.kind).Info("Resumed watcher cache is performing a full resync")
+		updates = ws.sendUpdates(updates)
+		for _, cb := range ws.allCallbacks() {
+			if rc, ok := cb.(api.SyncerTypeResyncCallbacks); ok {
+				rc.OnTypeResync(r.kind)
 			}
 		}
+
+	case addListener:
+		// Send any updates that we have grouped so that the state is current, and then replay
+		// the state to the new listener before attaching it.
+		updates = ws.sendUpdates(updates)
+		ws.replayState(r.callbacks)
+		ws.listeners = append(ws.listeners, r.callbacks)
 	}
 
 	// Return the accumulated or processed updated.
 	return updates
 }
 
-// sendUpdates is used to send the consolidated set of updates.  Returns nil.
+// replayState sends the current state of the syncer to a new listener.  See
+// ListenerRegistry.AddListener.
+func (ws *watcherSyncer) replayState(callbacks api.SyncerCallbacks) {
+	log.WithField("NumEntries", len(ws.state)).Info("Replaying syncer state to new listener")
+	callbacks.OnStatusUpdated(api.WaitForDatastore)
+	if ws.status == api.WaitForDatastore {
+		return
+	}
+	callbacks.OnStatusUpdated(api.ResyncInProgress)
+
+	// Send the adds in a deterministic order.
+	keys := make([]string, 0, len(ws.state))
+	for k := range ws.state {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		updates := make([]api.Update, 0, len(keys))
+		for _, k := range keys {
+			updates = append(updates, api.Update{KVPair: ws.state[k], UpdateType: api.UpdateTypeKVNew})
+		}
+		callbacks.OnUpdates(updates)
+	}
+
+	if tc, ok := callbacks.(api.SyncerTypeSyncedCallbacks); ok {
+		for _, kind := range ws.syncedKinds {
+			tc.OnTypeSynced(kind)
+		}
+	}
+	if ws.status == api.InSync {
+		callbacks.OnStatusUpdated(api.InSync)
+	}
+}
+
+// sendUpdates is used to send the consolidated set of updates.  If listeners are enabled, the
+// updates are also applied to the state that is replayed to new listeners.  Returns nil.
 func (ws *watcherSyncer) sendUpdates(updates []api.Update) []api.Update {
 	log.WithField("NumUpdates", len(updates)).Debug("Sending syncer updates (if any to send)")
 	if len(updates) > 0 {
-		ws.callbacks.OnUpdates(updates)
+		if ws.state != nil {
+			for _, u := range updates {
+				if u.Value == nil {
+					delete(ws.state, u.Key.String())
+				} else {
+					ws.state[u.Key.String()] = u.KVPair
+				}
+			}
+		}
+		for _, cb := range ws.allCallbacks() {
+			cb.OnUpdates(updates)
+		}
 	}
 	return nil
 }
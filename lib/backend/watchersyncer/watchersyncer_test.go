@@ -17,7 +17,9 @@ package watchersyncer_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -99,6 +101,35 @@ var _ = Describe("Test the backend datastore multi-watch syncer", func() {
 		rs.ExpectStatusUpdate(api.InSync)
 	})
 
+	It("should send per-type in-sync notifications in list completion order", func() {
+		r3Name := model.ListOptionsToDefaultPathRoot(r3.ListInterface)
+		eventL2Added1 := addEvent(l2Key1)
+		rs := newWatcherSyncerTesterWithTypeSynced([]watchersyncer.ResourceType{r1, r2, r3})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+
+		By("Completing the list for resource 2 and expecting its updates before the notification")
+		rs.clientListResponse(r2, &model.KVPairList{
+			Revision: "abcdef",
+			KVPairs:  []*model.KVPair{eventL2Added1.New},
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		Eventually(rs.typeSynced.syncedKinds).Should(Equal([]string{apiv3.KindIPPool}))
+		rs.ExpectOnUpdates([][]api.Update{{
+			{
+				KVPair:     *eventL2Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}})
+
+		By("Completing the lists for resources 3 and 1")
+		rs.clientListResponse(r3, emptyList)
+		Eventually(rs.typeSynced.syncedKinds).Should(Equal([]string{apiv3.KindIPPool, r3Name}))
+		rs.ExpectStatusUnchanged()
+		rs.clientListResponse(r1, emptyList)
+		rs.ExpectStatusUpdate(api.InSync)
+		Expect(rs.typeSynced.syncedKinds()).To(Equal([]string{apiv3.KindIPPool, r3Name, apiv3.KindNetworkPolicy}))
+	})
+
 	It("should not change status if watch returns multiple ErrorOperationNotSupported errors", func() {
 		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1})
 		rs.ExpectStatusUpdate(api.WaitForDatastore)
@@ -463,6 +494,94 @@ var _ = Describe("Test the backend datastore multi-watch syncer", func() {
 		}, true)
 	})
 
+	It("should send the updates for each page of a paged list", func() {
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r2})
+		eventL2Added1 := addEvent(l2Key1)
+		eventL2Added2 := addEvent(l2Key2)
+
+		By("returning the first page of the list")
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r2, listPage{
+			list: &model.KVPairList{Revision: "12345", KVPairs: []*model.KVPair{eventL2Added1.New}},
+			next: "page-2",
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectOnUpdates([][]api.Update{{
+			{
+				KVPair:     *eventL2Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}})
+		rs.ExpectStatusUnchanged()
+
+		By("returning the last page of the list")
+		rs.clientListResponse(r2, &model.KVPairList{Revision: "12345", KVPairs: []*model.KVPair{eventL2Added2.New}})
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.ExpectOnUpdates([][]api.Update{{
+			{
+				KVPair:     *eventL2Added2.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}})
+
+		By("watching from the revision of the list")
+		rs.clientWatchResponse(r2, nil)
+		Eventually(rs.lws[model.ListOptionsToDefaultPathRoot(r2.ListInterface)].getWatchRevision).Should(Equal("12345"))
+	})
+
+	It("should delete stale entries after a paged list that failed part way through is retried", func() {
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r2})
+		eventL2Added1 := addEvent(l2Key1)
+		eventL2Added2 := addEvent(l2Key2)
+
+		// Temporarily reduce the watch and list poll interval to make the tests faster.
+		defer setWatchIntervals(watchersyncer.ListRetryInterval, watchersyncer.WatchPollInterval)
+		setWatchIntervals(100*time.Millisecond, 500*time.Millisecond)
+
+		By("syncing two entries and then failing the watch")
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r2, &model.KVPairList{
+			Revision: "12345",
+			KVPairs:  []*model.KVPair{eventL2Added1.New, eventL2Added2.New},
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r2, genError)
+
+		By("failing the resync list after its first page")
+		rs.clientListResponse(r2, listPage{
+			list: &model.KVPairList{Revision: "12346", KVPairs: []*model.KVPair{eventL2Added1.New}},
+			next: "page-2",
+		})
+		rs.clientListResponse(r2, genError)
+
+		By("retrying the list without the second entry")
+		rs.clientListResponse(r2, &model.KVPairList{
+			Revision: "12347",
+			KVPairs:  []*model.KVPair{eventL2Added1.New},
+		})
+		rs.clientWatchResponse(r2, nil)
+
+		By("expecting the second entry to be deleted")
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL2Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+			{
+				KVPair:     *eventL2Added2.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+			{
+				KVPair: model.KVPair{
+					Key: eventL2Added2.New.Key,
+				},
+				UpdateType: api.UpdateTypeKVDeleted,
+			},
+		}, true)
+		rs.ExpectStatusUnchanged()
+	})
+
 	It("Should accumulate updates into a single update when the handler thread is blocked", func() {
 		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1, r2})
 		eventL1Added1 := addEvent(l1Key1)
@@ -582,6 +701,502 @@ var _ = Describe("Test the backend datastore multi-watch syncer", func() {
 
 	})
 
+	It("should export revisions and resume from them without a re-list", func() {
+		r1Name := model.ListOptionsToDefaultPathRoot(r1.ListInterface)
+		r2Name := model.ListOptionsToDefaultPathRoot(r2.ListInterface)
+		eventL1Added1 := addEvent(l1Key1)
+
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1, r2})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r1, emptyList)
+		rs.clientListResponse(r2, emptyList)
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+		rs.clientWatchResponse(r2, nil)
+		rs.sendEvent(r1, eventL1Added1)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+
+		By("Exporting the revisions")
+		revisions := rs.watcherSyncer.(watchersyncer.RevisionTracker).ExportRevisions()
+		Expect(revisions).To(Equal(map[string]string{
+			r1Name: eventL1Added1.New.Revision,
+			r2Name: emptyList.Revision,
+		}))
+
+		By("Starting a new syncer from the exported revisions, retaining the previously received data")
+		rs = newWatcherSyncerTesterResuming([]watchersyncer.ResourceType{r1, r2}, revisions, rs.SyncerTester)
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientWatchResponse(r1, nil)
+		rs.clientWatchResponse(r2, nil)
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.expectAllEventsHandled()
+		Expect(rs.lws[r1Name].getWatchRevision()).To(Equal(eventL1Added1.New.Revision))
+		Expect(rs.lws[r2Name].getWatchRevision()).To(Equal(emptyList.Revision))
+
+		By("Sending a delete for a resource that was received from the previous syncer")
+		rs.sendEvent(r1, deleteEvent(l1Key1))
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     model.KVPair{Key: l1Key1},
+				UpdateType: api.UpdateTypeKVDeleted,
+			},
+		}, false)
+		rs.ExpectCacheSize(0)
+	})
+
+	It("should fall back to a full list if the watch revision is rejected", func() {
+		r1Name := model.ListOptionsToDefaultPathRoot(r1.ListInterface)
+		rs := newWatcherSyncerTesterFromRevisions([]watchersyncer.ResourceType{r1}, map[string]string{
+			r1Name: "compacted",
+		})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientWatchResponse(r1, genError)
+		rs.ExpectStatusUnchanged()
+		rs.clientListResponse(r1, emptyList)
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+		rs.expectAllEventsHandled()
+		Expect(rs.lws[r1Name].getWatchRevision()).To(Equal(emptyList.Revision))
+	})
+
+	It("should fall back to a full list if the watch terminates due to an invalid revision", func() {
+		r1Name := model.ListOptionsToDefaultPathRoot(r1.ListInterface)
+		eventL1Added1 := addEvent(l1Key1)
+		rs := newWatcherSyncerTesterFromRevisions([]watchersyncer.ResourceType{r1}, map[string]string{
+			r1Name: "compacted",
+		})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientWatchResponse(r1, nil)
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		Expect(rs.lws[r1Name].getWatchRevision()).To(Equal("compacted"))
+
+		rs.sendEvent(r1, api.WatchEvent{
+			Type:  api.WatchError,
+			Error: dsError,
+		})
+		rs.clientListResponse(r1, &model.KVPairList{
+			KVPairs:  []*model.KVPair{eventL1Added1.New},
+			Revision: "listrevision",
+		})
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+		rs.clientWatchResponse(r1, nil)
+		rs.ExpectStatusUnchanged()
+		rs.expectAllEventsHandled()
+		Expect(rs.lws[r1Name].getWatchRevision()).To(Equal("listrevision"))
+		Expect(rs.watcherSyncer.(watchersyncer.RevisionTracker).ExportRevisions()).To(Equal(map[string]string{
+			r1Name: "listrevision",
+		}))
+	})
+
+	It("should notify a resync when a resumed resource type falls back to a full list", func() {
+		r1Name := model.ListOptionsToDefaultPathRoot(r1.ListInterface)
+		r2Name := model.ListOptionsToDefaultPathRoot(r2.ListInterface)
+		eventL1Added1 := addEvent(l1Key1)
+		rs := newWatcherSyncerTesterWithOptions([]watchersyncer.ResourceType{r1, r2}, watcherSyncerTesterOptions{
+			revisions:        map[string]string{r1Name: "compacted", r2Name: "valid"},
+			recordTypeSynced: true,
+		})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientWatchResponse(r2, nil)
+		rs.clientWatchResponse(r1, genError)
+		rs.clientListResponse(r1, &model.KVPairList{
+			KVPairs:  []*model.KVPair{eventL1Added1.New},
+			Revision: "listrevision",
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+		rs.expectAllEventsHandled()
+		Expect(rs.typeSynced.resyncedKinds()).To(Equal([]string{apiv3.KindNetworkPolicy}))
+
+		By("Failing the watch again, which does not notify a resync since the data has been re-listed")
+		rs.sendEvent(r1, api.WatchEvent{
+			Type:  api.WatchError,
+			Error: dsError,
+		})
+		rs.clientListResponse(r1, &model.KVPairList{
+			KVPairs:  []*model.KVPair{eventL1Added1.New},
+			Revision: "listrevision2",
+		})
+		rs.clientWatchResponse(r1, nil)
+		rs.expectAllEventsHandled()
+		Expect(rs.typeSynced.resyncedKinds()).To(Equal([]string{apiv3.KindNetworkPolicy}))
+	})
+
+	It("should not send updates that do not change the value", func() {
+		eventL1Added1 := addEvent(l1Key1)
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r1, &model.KVPairList{
+			KVPairs:  []*model.KVPair{eventL1Added1.New},
+			Revision: "listrevision",
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+
+		By("Sending a modified event with a new revision but the same value")
+		rs.sendEvent(r1, api.WatchEvent{
+			Type: api.WatchModified,
+			New: &model.KVPair{
+				Key:      l1Key1,
+				Value:    eventL1Added1.New.Value,
+				Revision: "newrevision",
+			},
+		})
+
+		By("Sending a modified event that changes the value and expecting a single update")
+		eventL1Modified1 := modifiedEvent(l1Key1)
+		rs.sendEvent(r1, eventL1Modified1)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Modified1.New,
+				UpdateType: api.UpdateTypeKVUpdated,
+			},
+		}, false)
+	})
+
+	It("should compare values that define their own equality using that equality", func() {
+		added := &model.KVPair{Key: l1Key1, Value: &caseInsensitiveValue{Name: "abc"}, Revision: "1"}
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r1, &model.KVPairList{
+			KVPairs:  []*model.KVPair{added},
+			Revision: "listrevision",
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *added,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+
+		By("Sending a modified event with a value that serializes differently but is equal")
+		rs.sendEvent(r1, api.WatchEvent{
+			Type: api.WatchModified,
+			New:  &model.KVPair{Key: l1Key1, Value: &caseInsensitiveValue{Name: "ABC"}, Revision: "2"},
+		})
+
+		By("Sending a modified event that changes the value and expecting a single update")
+		modified := &model.KVPair{Key: l1Key1, Value: &caseInsensitiveValue{Name: "def"}, Revision: "3"}
+		rs.sendEvent(r1, api.WatchEvent{
+			Type: api.WatchModified,
+			New:  modified,
+		})
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *modified,
+				UpdateType: api.UpdateTypeKVUpdated,
+			},
+		}, false)
+	})
+
+	It("should not send updates for Wireguard configuration that has not changed", func() {
+		wireguard := func(allowedIPs ...string) *model.Wireguard {
+			w := &model.Wireguard{InterfaceIPv4Addr: cnet.ParseIP("192.168.0.1"), Port: 51820}
+			for _, cidr := range allowedIPs {
+				w.AllowedIPs = append(w.AllowedIPs, cnet.MustParseCIDR(cidr))
+			}
+			return w
+		}
+		added := &model.KVPair{Key: l1Key1, Value: wireguard("10.0.0.0/26", "10.0.1.0/26"), Revision: "1"}
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r1, &model.KVPairList{
+			KVPairs:  []*model.KVPair{added},
+			Revision: "listrevision",
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *added,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+
+		By("Sending a modified event with the allowed IPs reordered")
+		rs.sendEvent(r1, api.WatchEvent{
+			Type: api.WatchModified,
+			New:  &model.KVPair{Key: l1Key1, Value: wireguard("10.0.1.0/26", "10.0.0.0/26"), Revision: "2"},
+		})
+
+		By("Sending a modified event that changes the allowed IPs and expecting a single update")
+		modified := &model.KVPair{Key: l1Key1, Value: wireguard("10.0.1.0/26"), Revision: "3"}
+		rs.sendEvent(r1, api.WatchEvent{
+			Type: api.WatchModified,
+			New:  modified,
+		})
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *modified,
+				UpdateType: api.UpdateTypeKVUpdated,
+			},
+		}, false)
+	})
+
+	It("should send updates that do not change the value in full update mode", func() {
+		rf := watchersyncer.ResourceType{
+			ListInterface: r1.ListInterface,
+			UpdateMode:    watchersyncer.UpdateModeFull,
+		}
+		eventL1Added1 := addEvent(l1Key1)
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{rf})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(rf, &model.KVPairList{
+			KVPairs:  []*model.KVPair{eventL1Added1.New},
+			Revision: "listrevision",
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(rf, nil)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+
+		By("Sending a modified event with a new revision but the same value and expecting an update")
+		unchanged := &model.KVPair{
+			Key:      l1Key1,
+			Value:    eventL1Added1.New.Value,
+			Revision: "newrevision",
+		}
+		rs.sendEvent(rf, api.WatchEvent{
+			Type: api.WatchModified,
+			New:  unchanged,
+		})
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *unchanged,
+				UpdateType: api.UpdateTypeKVUpdated,
+			},
+		}, false)
+
+		By("Sending a modified event with the same revision and expecting no update")
+		rs.sendEvent(rf, api.WatchEvent{
+			Type: api.WatchModified,
+			New:  unchanged,
+		})
+
+		By("Sending a modified event that changes the value and expecting a single update")
+		eventL1Modified1 := modifiedEvent(l1Key1)
+		rs.sendEvent(rf, eventL1Modified1)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Modified1.New,
+				UpdateType: api.UpdateTypeKVUpdated,
+			},
+		}, false)
+	})
+
+	It("should advance the watch revision on a bookmark without processing an update", func() {
+		r1Name := model.ListOptionsToDefaultPathRoot(r1.ListInterface)
+		cp := &countingProcessor{}
+		rc1 := watchersyncer.ResourceType{
+			UpdateProcessor: cp,
+			ListInterface:   r1.ListInterface,
+		}
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{rc1})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r1, emptyList)
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+
+		By("Sending a bookmark and checking the tracked revision advances")
+		rs.sendEvent(r1, api.WatchEvent{
+			Type: api.WatchBookmark,
+			New:  &model.KVPair{Revision: "bookmarkrevision"},
+		})
+		Eventually(func() map[string]string {
+			return rs.watcherSyncer.(watchersyncer.RevisionTracker).ExportRevisions()
+		}).Should(Equal(map[string]string{r1Name: "bookmarkrevision"}))
+		rs.ExpectStatusUnchanged()
+		rs.expectAllEventsHandled()
+		Expect(cp.numCalls()).To(BeZero())
+
+		By("Sending an update after the bookmark and checking it is processed")
+		eventL1Added1 := addEvent(l1Key1)
+		rs.sendEvent(r1, eventL1Added1)
+		rs.ExpectUpdates([]api.Update{
+			{
+				KVPair:     *eventL1Added1.New,
+				UpdateType: api.UpdateTypeKVNew,
+			},
+		}, false)
+		Expect(cp.numCalls()).To(Equal(1))
+	})
+
+	It("should report the connection and sync state in the health report", func() {
+		r1Name := model.ListOptionsToDefaultPathRoot(r1.ListInterface)
+		r2Name := model.ListOptionsToDefaultPathRoot(r2.ListInterface)
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1, r2})
+		hr := rs.watcherSyncer.(watchersyncer.HealthReporter)
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		h := hr.HealthReport()
+		Expect(h.Status).To(Equal(api.WaitForDatastore))
+		Expect(h.Connected).To(BeFalse())
+		Expect(h.InSync).To(BeFalse())
+		Expect(h.LastSyncTime.IsZero()).To(BeTrue())
+		Expect(h.Revisions).To(BeEmpty())
+
+		By("Completing the sync for one resource type")
+		rs.clientListResponse(r1, emptyList)
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.clientWatchResponse(r1, nil)
+		rs.expectAllEventsHandled()
+		h = hr.HealthReport()
+		Expect(h.Status).To(Equal(api.ResyncInProgress))
+		Expect(h.Connected).To(BeFalse())
+		Expect(h.InSync).To(BeFalse())
+
+		By("Completing the sync for the other resource type")
+		before := time.Now()
+		rs.clientListResponse(r2, emptyList)
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r2, nil)
+		rs.expectAllEventsHandled()
+		Eventually(func() bool { return hr.HealthReport().Connected }).Should(BeTrue())
+		h = hr.HealthReport()
+		Expect(h.Status).To(Equal(api.InSync))
+		Expect(h.InSync).To(BeTrue())
+		Expect(h.LastSyncTime).To(BeTemporally(">=", before))
+		Expect(h.Revisions).To(Equal(map[string]string{
+			r1Name: emptyList.Revision,
+			r2Name: emptyList.Revision,
+		}))
+
+		By("Failing the watch and the subsequent list for one resource type")
+		rs.sendEvent(r1, api.WatchEvent{
+			Type:  api.WatchError,
+			Error: dsError,
+		})
+		rs.clientListResponse(r1, genError)
+		Eventually(func() bool { return hr.HealthReport().Connected }).Should(BeFalse())
+		Expect(hr.HealthReport().InSync).To(BeTrue())
+
+		By("Reconnecting the resource type")
+		rs.clientListResponse(r1, emptyList)
+		rs.clientWatchResponse(r1, nil)
+		Eventually(func() bool { return hr.HealthReport().Connected }, 2*watchersyncer.ListRetryInterval).Should(BeTrue())
+		rs.ExpectStatusUnchanged()
+	})
+
+	It("should replay the full state to a listener added after the initial sync", func() {
+		eventL1Added1 := addEvent(l1Key1)
+		eventL1Added2 := addEvent(l1Key2)
+		eventL2Added1 := addEvent(l2Key1)
+		rs := newWatcherSyncerTesterWithListeners([]watchersyncer.ResourceType{r1, r2})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		rs.clientListResponse(r1, &model.KVPairList{
+			Revision: "abcdef",
+			KVPairs:  []*model.KVPair{eventL1Added1.New, eventL1Added2.New},
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.clientListResponse(r2, &model.KVPairList{
+			Revision: "abcdef",
+			KVPairs:  []*model.KVPair{eventL2Added1.New},
+		})
+		rs.ExpectStatusUpdate(api.InSync)
+		rs.clientWatchResponse(r1, nil)
+		rs.clientWatchResponse(r2, nil)
+		rs.ExpectCacheSize(3)
+		rs.sendEvent(r1, deleteEvent(l1Key2))
+		rs.ExpectCacheSize(2)
+		rs.ExpectUpdates([]api.Update{
+			{KVPair: *eventL1Added1.New, UpdateType: api.UpdateTypeKVNew},
+			{KVPair: *eventL1Added2.New, UpdateType: api.UpdateTypeKVNew},
+			{KVPair: *eventL2Added1.New, UpdateType: api.UpdateTypeKVNew},
+			{KVPair: model.KVPair{Key: l1Key2}, UpdateType: api.UpdateTypeKVDeleted},
+		}, false)
+
+		By("Adding a listener and expecting a replay of the current state")
+		listener := testutils.NewSyncerTester()
+		rs.watcherSyncer.(watchersyncer.ListenerRegistry).AddListener(listener)
+		listener.ExpectStatusUpdate(api.WaitForDatastore)
+		listener.ExpectStatusUpdate(api.ResyncInProgress)
+		listener.ExpectStatusUpdate(api.InSync)
+		listener.ExpectCacheSize(2)
+		listener.ExpectData(*eventL1Added1.New)
+		listener.ExpectData(*eventL2Added1.New)
+		Expect(listener.GetCacheEntries()).To(HaveLen(2))
+		listener.ExpectUpdates([]api.Update{
+			{KVPair: *eventL1Added1.New, UpdateType: api.UpdateTypeKVNew},
+			{KVPair: *eventL2Added1.New, UpdateType: api.UpdateTypeKVNew},
+		}, false)
+		rs.ExpectStatusUnchanged()
+
+		By("Sending an update and expecting both listeners to receive it")
+		eventL2Modified1 := modifiedEvent(l2Key1)
+		rs.sendEvent(r2, eventL2Modified1)
+		expected := []api.Update{{KVPair: *eventL2Modified1.New, UpdateType: api.UpdateTypeKVUpdated}}
+		rs.ExpectUpdates(expected, false)
+		listener.ExpectUpdates(expected, false)
+	})
+
+	It("should not replay any state to a listener added before the initial sync", func() {
+		eventL1Added1 := addEvent(l1Key1)
+		rs := newWatcherSyncerTesterWithListeners([]watchersyncer.ResourceType{r1})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+
+		listener := testutils.NewSyncerTester()
+		rs.watcherSyncer.(watchersyncer.ListenerRegistry).AddListener(listener)
+		listener.ExpectStatusUpdate(api.WaitForDatastore)
+
+		By("Completing the sync and expecting both listeners to receive it")
+		rs.clientListResponse(r1, &model.KVPairList{
+			Revision: "abcdef",
+			KVPairs:  []*model.KVPair{eventL1Added1.New},
+		})
+		rs.ExpectStatusUpdate(api.ResyncInProgress)
+		listener.ExpectStatusUpdate(api.ResyncInProgress)
+		rs.ExpectStatusUpdate(api.InSync)
+		listener.ExpectStatusUpdate(api.InSync)
+		expected := []api.Update{{KVPair: *eventL1Added1.New, UpdateType: api.UpdateTypeKVNew}}
+		rs.ExpectUpdates(expected, false)
+		listener.ExpectUpdates(expected, false)
+	})
+
+	It("should not maintain the state or allow listeners unless listeners are enabled", func() {
+		rs := newWatcherSyncerTester([]watchersyncer.ResourceType{r1})
+		rs.ExpectStatusUpdate(api.WaitForDatastore)
+		Expect(func() {
+			rs.watcherSyncer.(watchersyncer.ListenerRegistry).AddListener(testutils.NewSyncerTester())
+		}).To(Panic())
+	})
+
 	It("Should invoke the supplied converter to alter the update", func() {
 		rc1 := watchersyncer.ResourceType{
 			UpdateProcessor: &fakeConverter{},
@@ -769,6 +1384,24 @@ func (fc *fakeConverter) Process(kvp *model.KVPair) ([]*model.KVPair, error) {
 func (fc *fakeConverter) OnSyncerStarting() {
 }
 
+// countingProcessor is an update processor that passes updates through unchanged and counts
+// the number of updates processed.
+type countingProcessor struct {
+	calls int32
+}
+
+func (cp *countingProcessor) Process(kvp *model.KVPair) ([]*model.KVPair, error) {
+	atomic.AddInt32(&cp.calls, 1)
+	return []*model.KVPair{kvp}, nil
+}
+
+func (cp *countingProcessor) OnSyncerStarting() {
+}
+
+func (cp *countingProcessor) numCalls() int {
+	return int(atomic.LoadInt32(&cp.calls))
+}
+
 // Create a delete event from a Key. The value types don't need to match the
 // Key types since we aren't unmarshaling/marshaling them in this package.
 func deleteEvent(key model.Key) api.WatchEvent {
@@ -784,6 +1417,17 @@ func deleteEvent(key model.Key) api.WatchEvent {
 
 // Create an add event from a Key. The value types don't need to match the
 // Key types since we aren't unmarshaling/marshaling them in this package.
+// caseInsensitiveValue is a value that defines its own equality, in which the case of the name
+// is not significant.
+type caseInsensitiveValue struct {
+	Name string
+}
+
+func (v *caseInsensitiveValue) EqualValue(other interface{}) bool {
+	o, ok := other.(*caseInsensitiveValue)
+	return ok && strings.EqualFold(v.Name, o.Name)
+}
+
 func addEvent(key model.Key) api.WatchEvent {
 	return api.WatchEvent{
 		Type: api.WatchAdded,
@@ -811,6 +1455,44 @@ func modifiedEvent(key model.Key) api.WatchEvent {
 // Create a new watcherSyncerTester - this creates and starts a WatcherSyncer with
 // client and sync consumer interfaces implemented and controlled by the test.
 func newWatcherSyncerTester(l []watchersyncer.ResourceType) *watcherSyncerTester {
+	return newWatcherSyncerTesterFromRevisions(l, nil)
+}
+
+// Create a new watcherSyncerTester that resumes from the supplied revisions.
+func newWatcherSyncerTesterFromRevisions(l []watchersyncer.ResourceType, revisions map[string]string) *watcherSyncerTester {
+	return newWatcherSyncerTesterWithOptions(l, watcherSyncerTesterOptions{revisions: revisions})
+}
+
+// Create a new watcherSyncerTester that resumes from the supplied revisions, sending its updates
+// to an existing SyncerTester.  This simulates a warm restart where the consumer retains the data
+// received from the previous syncer.
+func newWatcherSyncerTesterResuming(l []watchersyncer.ResourceType, revisions map[string]string, st *testutils.SyncerTester) *watcherSyncerTester {
+	return newWatcherSyncerTesterWithOptions(l, watcherSyncerTesterOptions{revisions: revisions, st: st})
+}
+
+// Create a new watcherSyncerTester whose callbacks record the per-type in-sync notifications.
+func newWatcherSyncerTesterWithTypeSynced(l []watchersyncer.ResourceType) *watcherSyncerTester {
+	return newWatcherSyncerTesterWithOptions(l, watcherSyncerTesterOptions{recordTypeSynced: true})
+}
+
+// Create a new watcherSyncerTester with listeners enabled.
+func newWatcherSyncerTesterWithListeners(l []watchersyncer.ResourceType) *watcherSyncerTester {
+	return newWatcherSyncerTesterWithOptions(l, watcherSyncerTesterOptions{enableListeners: true})
+}
+
+// watcherSyncerTesterOptions contains the options used to create a watcherSyncerTester.
+type watcherSyncerTesterOptions struct {
+	// The revisions to resume from, if any.
+	revisions map[string]string
+	// Whether the callbacks record the per-type in-sync notifications.
+	recordTypeSynced bool
+	// Whether listeners are enabled on the syncer.
+	enableListeners bool
+	// An existing SyncerTester to send the updates to, if any.
+	st *testutils.SyncerTester
+}
+
+func newWatcherSyncerTesterWithOptions(l []watchersyncer.ResourceType, opts watcherSyncerTesterOptions) *watcherSyncerTester {
 	// Create the required watchers.  This hs methods that we use to drive
 	// responses.
 	lws := map[string]*listWatchSource{}
@@ -831,13 +1513,27 @@ func newWatcherSyncerTester(l []watchersyncer.ResourceType) *watcherSyncerTester
 		lws: lws,
 	}
 
-	// Create the syncer tester.
-	st := testutils.NewSyncerTester()
+	// Create the syncer tester, unless we are reusing an existing one.
+	st := opts.st
+	if st == nil {
+		st = testutils.NewSyncerTester()
+	}
 	rst := &watcherSyncerTester{
-		SyncerTester:  st,
-		fc:            fc,
-		watcherSyncer: watchersyncer.New(fc, l, st),
-		lws:           lws,
+		SyncerTester: st,
+		fc:           fc,
+		lws:          lws,
+	}
+	if opts.recordTypeSynced {
+		rst.typeSynced = &typeSyncedRecorder{SyncerTester: st}
+		rst.watcherSyncer = watchersyncer.New(fc, l, rst.typeSynced)
+	} else {
+		rst.watcherSyncer = watchersyncer.New(fc, l, st)
+	}
+	if opts.revisions != nil {
+		rst.watcherSyncer.(watchersyncer.RevisionTracker).StartFromRevisions(opts.revisions)
+	}
+	if opts.enableListeners {
+		rst.watcherSyncer.(watchersyncer.ListenerRegistry).EnableListeners()
 	}
 	rst.watcherSyncer.Start()
 	return rst
@@ -852,6 +1548,42 @@ type watcherSyncerTester struct {
 	fc            *fakeClient
 	lws           map[string]*listWatchSource
 	watcherSyncer api.Syncer
+	typeSynced    *typeSyncedRecorder
+}
+
+// typeSyncedRecorder extends the SyncerTester to record the per-type in-sync and resync
+// notifications.
+type typeSyncedRecorder struct {
+	*testutils.SyncerTester
+	lock        sync.Mutex
+	kinds       []string
+	resyncKinds []string
+}
+
+func (r *typeSyncedRecorder) OnTypeSynced(kind string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.kinds = append(r.kinds, kind)
+}
+
+func (r *typeSyncedRecorder) OnTypeResync(kind string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.resyncKinds = append(r.resyncKinds, kind)
+}
+
+// resyncedKinds returns the kinds that have been notified as resyncing, in order.
+func (r *typeSyncedRecorder) resyncedKinds() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.resyncKinds...)
+}
+
+// syncedKinds returns the kinds that have been notified as in-sync, in order.
+func (r *typeSyncedRecorder) syncedKinds() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.kinds...)
 }
 
 // Call to test that all of the client and watcher events have been processed.
@@ -935,7 +1667,7 @@ func (rst *watcherSyncerTester) clientListResponse(r watchersyncer.ResourceType,
 		"Response": response,
 	}).Info("Setting client List response")
 	switch response.(type) {
-	case error, *model.KVPairList:
+	case error, *model.KVPairList, listPage:
 		rst.lws[name].listCallResults <- response
 	default:
 		panic("Error in test, wrong type specified")
@@ -1012,6 +1744,17 @@ func (c *fakeClient) List(ctx context.Context, list model.ListInterface, revisio
 	}
 }
 
+func (c *fakeClient) ListPaged(ctx context.Context, list model.ListInterface, pageSize int64, token string) (*model.KVPairList, string, error) {
+	// Paging is controlled by the list results, the page size and token are not used.
+	name := model.ListOptionsToDefaultPathRoot(list)
+	log.WithFields(log.Fields{"Name": name, "Token": token}).Info("ListPaged request")
+	if l, ok := c.lws[name]; !ok || l == nil {
+		panic("List for unhandled resource type")
+	} else {
+		return l.listPaged()
+	}
+}
+
 func (c *fakeClient) Watch(ctx context.Context, list model.ListInterface, revision string) (api.WatchInterface, error) {
 	// Create a fake watcher keyed off the ListOptions (root path).
 	name := model.ListOptionsToDefaultPathRoot(list)
@@ -1019,6 +1762,7 @@ func (c *fakeClient) Watch(ctx context.Context, list model.ListInterface, revisi
 	if l, ok := c.lws[name]; !ok || l == nil {
 		panic("Watch for unhandled resource type")
 	} else {
+		l.setWatchRevision(revision)
 		return l.watch()
 	}
 }
@@ -1051,6 +1795,50 @@ type listWatchSource struct {
 	// has terminated.  This is required for this test harness due to the sharing of the results
 	// channel.
 	termWg sync.WaitGroup
+
+	// The revision supplied on the most recent Watch invocation.
+	watchRevision     string
+	watchRevisionLock sync.Mutex
+}
+
+// setWatchRevision stores the revision supplied on a Watch invocation.
+func (fw *listWatchSource) setWatchRevision(revision string) {
+	fw.watchRevisionLock.Lock()
+	defer fw.watchRevisionLock.Unlock()
+	fw.watchRevision = revision
+}
+
+// getWatchRevision returns the revision supplied on the most recent Watch invocation.
+func (fw *listWatchSource) getWatchRevision() string {
+	fw.watchRevisionLock.Lock()
+	defer fw.watchRevisionLock.Unlock()
+	return fw.watchRevision
+}
+
+// listPage is a list result that is a page of a list, with the continue token for the next page.
+type listPage struct {
+	list *model.KVPairList
+	next string
+}
+
+// listPaged returns the list results specified on the listCallResults channel.  A *model.KVPairList
+// is returned as the last page of a list.
+func (fw *listWatchSource) listPaged() (*model.KVPairList, string, error) {
+	result := <-fw.listCallResults
+	switch r := result.(type) {
+	case error:
+		log.WithField("Name", fw.name).WithError(r).Info("Returning error from ListPaged invocation")
+		return nil, "", r
+	case *model.KVPairList:
+		log.WithField("Name", fw.name).Info("Returning last page from ListPaged invocation")
+		return r, "", nil
+	case listPage:
+		log.WithField("Name", fw.name).Info("Returning page from ListPaged invocation")
+		return r.list, r.next, nil
+	default:
+		log.WithField("Result", r).Panic("Unexpected result on list result channel")
+		return nil, "", nil
+	}
 }
 
 // List returns the list results specified on the listCallError or listCallResults channel.
@@ -0,0 +1,303 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+	"github.com/projectcalico/libcalico-go/lib/options"
+)
+
+// CachedInterface is a client Interface that serves Get and List requests for a set of resource
+// kinds from a local in-memory cache.  The cache is kept up to date by a syncer watching the
+// datastore.  All other requests, and requests for kinds that are not cached, are passed
+// through to the datastore.
+type CachedInterface interface {
+	Interface
+
+	// WaitForCacheSync blocks until the cache has synced with the datastore, or until the
+	// context is done.  An error is returned if the context is done before the cache syncs.
+	WaitForCacheSync(ctx context.Context) error
+
+	// Stop stops the syncer that maintains the cache.  Once stopped, all requests are passed
+	// through to the datastore.
+	Stop()
+}
+
+// NewCached returns a connected client that caches resources of the requested kinds.  The
+// ClientConfig can either be created explicitly, or can be loaded from a config file or
+// environment variables using the LoadClientConfig() function.
+func NewCached(config apiconfig.CalicoAPIConfig, kinds ...string) (CachedInterface, error) {
+	be, err := backend.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := newResourceCache(&resources{backend: be}, kinds)
+	resourceTypes := make([]watchersyncer.ResourceType, 0, len(kinds))
+	for _, kind := range kinds {
+		resourceTypes = append(resourceTypes, watchersyncer.ResourceType{
+			ListInterface: model.ResourceListOptions{Kind: kind},
+		})
+	}
+	rc.syncer = watchersyncer.New(be, resourceTypes, rc)
+	rc.syncer.Start()
+
+	return cachedClient{
+		client: client{
+			config:    config,
+			backend:   be,
+			resources: rc,
+		},
+		cache: rc,
+	}, nil
+}
+
+// cachedClient implements the CachedInterface.
+type cachedClient struct {
+	client
+	cache *resourceCache
+}
+
+// WaitForCacheSync implements the CachedInterface.
+func (c cachedClient) WaitForCacheSync(ctx context.Context) error {
+	return c.cache.waitForSync(ctx)
+}
+
+// Stop implements the CachedInterface.
+func (c cachedClient) Stop() {
+	c.cache.stop()
+}
+
+// resourceCache is a read-through caching decorator for the resources client.  It implements
+// the resourceInterface, serving Get and List from the cache for the configured kinds, and the
+// SyncerCallbacks interface through which the cache is populated.
+//
+// Before the cache has synced, a Get that misses the cache is read from the datastore and the
+// result is stored in the cache.  Once synced, the syncer is authoritative and any entries that
+// were filled from a Get but not reported by the syncer are discarded.
+type resourceCache struct {
+	*resources
+	syncer bapi.Syncer
+
+	// The kinds that are cached.
+	kinds map[string]bool
+
+	// Lock protects the following fields.
+	lock      sync.RWMutex
+	synced    bool
+	stopped   bool
+	syncedC   chan struct{}
+	entries   map[string]map[string]resource
+	filled    map[string]bool
+	revisions map[string]string
+}
+
+// newResourceCache returns a new resourceCache wrapping the supplied resources client.
+func newResourceCache(r *resources, kinds []string) *resourceCache {
+	rc := &resourceCache{
+		resources: r,
+		kinds:     make(map[string]bool, len(kinds)),
+		syncedC:   make(chan struct{}),
+		entries:   make(map[string]map[string]resource, len(kinds)),
+		filled:    make(map[string]bool),
+		revisions: make(map[string]string, len(kinds)),
+	}
+	for _, kind := range kinds {
+		rc.kinds[kind] = true
+		rc.entries[kind] = make(map[string]resource)
+	}
+	return rc
+}
+
+// Get gets a resource, serving the request from the cache if possible.
+func (c *resourceCache) Get(ctx context.Context, opts options.GetOptions, kind, ns, name string) (resource, error) {
+	// Requests for a specific revision, or for a consistent read, cannot be served from the
+	// cache.
+	if !c.kinds[kind] || opts.ResourceVersion != "" || opts.ConsistentRead {
+		return c.resources.Get(ctx, opts, kind, ns, name)
+	}
+	if err := c.checkNamespace(ns, kind); err != nil {
+		return nil, err
+	}
+	key := model.ResourceKey{Kind: kind, Name: name, Namespace: ns}.String()
+
+	c.lock.RLock()
+	res, ok := c.entries[kind][key]
+	synced, stopped := c.synced, c.stopped
+	c.lock.RUnlock()
+	if ok {
+		log.WithField("Key", key).Debug("Cache hit")
+		return res.DeepCopyObject().(resource), nil
+	}
+
+	// Cache miss, read the resource from the datastore.  Once synced the cache will be updated
+	// by the syncer, otherwise fill the cache with the result.
+	log.WithField("Key", key).Debug("Cache miss")
+	res, err := c.resources.Get(ctx, opts, kind, ns, name)
+	if err != nil || synced || stopped {
+		return res, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.entries[kind][key]; !ok && !c.synced && !c.stopped {
+		log.WithField("Key", key).Debug("Filling cache entry")
+		c.entries[kind][key] = res.DeepCopyObject().(resource)
+		c.filled[key] = true
+	}
+	return res, nil
+}
+
+// List lists resources, serving the request from the cache if the cache has synced.
+func (c *resourceCache) List(ctx context.Context, opts options.ListOptions, kind, listKind string, listObj resourceList) error {
+	if !c.kinds[kind] || opts.ResourceVersion != "" {
+		return c.resources.List(ctx, opts, kind, listKind, listObj)
+	}
+
+	c.lock.RLock()
+	if !c.synced || c.stopped {
+		c.lock.RUnlock()
+		return c.resources.List(ctx, opts, kind, listKind, listObj)
+	}
+	defer c.lock.RUnlock()
+
+	// Filter the cached entries.  Sort by key so that the ordering is deterministic.
+	keys := make([]string, 0, len(c.entries[kind]))
+	for key, res := range c.entries[kind] {
+		om := res.GetObjectMeta()
+		if opts.Namespace != "" && om.GetNamespace() != opts.Namespace {
+			continue
+		}
+		if opts.Name != "" {
+			if opts.Prefix && !strings.HasPrefix(om.GetName(), opts.Name) {
+				continue
+			} else if !opts.Prefix && om.GetName() != opts.Name {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resources := make([]runtime.Object, 0, len(keys))
+	for _, key := range keys {
+		resources = append(resources, c.entries[kind][key].DeepCopyObject())
+	}
+	if err := meta.SetList(listObj, resources); err != nil {
+		return err
+	}
+
+	listObj.GetListMeta().SetResourceVersion(c.revisions[kind])
+	listObj.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   apiv3.Group,
+		Version: apiv3.VersionCurrent,
+		Kind:    listKind,
+	})
+	return nil
+}
+
+// OnStatusUpdated implements the SyncerCallbacks interface.
+func (c *resourceCache) OnStatusUpdated(status bapi.SyncStatus) {
+	if status != bapi.InSync {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.synced || c.stopped {
+		return
+	}
+
+	// Remove any entries filled from a Get that the syncer has not confirmed - these must have
+	// since been deleted.
+	for _, entries := range c.entries {
+		for key := range entries {
+			if c.filled[key] {
+				log.WithField("Key", key).Debug("Removing unconfirmed cache entry")
+				delete(entries, key)
+			}
+		}
+	}
+	c.filled = nil
+
+	log.Info("Resource cache is in-sync")
+	c.synced = true
+	close(c.syncedC)
+}
+
+// OnUpdates implements the SyncerCallbacks interface.  It applies the updates for the cached
+// kinds to the cache.
+func (c *resourceCache) OnUpdates(updates []bapi.Update) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, u := range updates {
+		rk, ok := u.Key.(model.ResourceKey)
+		if !ok || !c.kinds[rk.Kind] {
+			continue
+		}
+		key := rk.String()
+		delete(c.filled, key)
+		if u.UpdateType == bapi.UpdateTypeKVDeleted {
+			delete(c.entries[rk.Kind], key)
+			continue
+		}
+		res, ok := u.Value.(resource)
+		if !ok {
+			log.WithField("Key", key).Warn("Unexpected value type in cache update")
+			delete(c.entries[rk.Kind], key)
+			continue
+		}
+		// Normalize the resource in the same way as a Get from the datastore, so that cached
+		// and uncached Gets return the same resource.
+		res = c.kvPairToResource(&model.KVPair{Value: res.DeepCopyObject(), Revision: u.Revision})
+		c.entries[rk.Kind][key] = res
+		c.revisions[rk.Kind] = u.Revision
+	}
+}
+
+// waitForSync blocks until the cache has synced or the context is done.
+func (c *resourceCache) waitForSync(ctx context.Context) error {
+	select {
+	case <-c.syncedC:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop stops the syncer.  All subsequent requests are passed through to the datastore.
+func (c *resourceCache) stop() {
+	c.lock.Lock()
+	c.stopped = true
+	c.lock.Unlock()
+	if c.syncer != nil {
+		c.syncer.Stop()
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/options"
+)
+
+var _ = Describe("Resource cache", func() {
+	ctx := context.Background()
+	var be *getRecordingBackend
+	var rc *resourceCache
+
+	// update returns a syncer update for the supplied resource.
+	update := func(updateType bapi.UpdateType, kind string, res resource, rev string) bapi.Update {
+		return bapi.Update{
+			UpdateType: updateType,
+			KVPair: model.KVPair{
+				Key: model.ResourceKey{
+					Kind:      kind,
+					Name:      res.GetObjectMeta().GetName(),
+					Namespace: res.GetObjectMeta().GetNamespace(),
+				},
+				Value:    res,
+				Revision: rev,
+			},
+		}
+	}
+	pool := func(name string) *apiv3.IPPool {
+		p := apiv3.NewIPPool()
+		p.Name = name
+		return p
+	}
+	policy := func(ns, name string) *apiv3.NetworkPolicy {
+		p := apiv3.NewNetworkPolicy()
+		p.Namespace = ns
+		p.Name = name
+		return p
+	}
+
+	BeforeEach(func() {
+		be = &getRecordingBackend{}
+		rc = newResourceCache(&resources{backend: be}, []string{apiv3.KindIPPool, apiv3.KindNetworkPolicy})
+	})
+
+	It("should serve Gets and Lists from the cache once synced", func() {
+		rc.OnUpdates([]bapi.Update{
+			update(bapi.UpdateTypeKVNew, apiv3.KindIPPool, pool("pool1"), "5"),
+			update(bapi.UpdateTypeKVNew, apiv3.KindIPPool, pool("pool2"), "6"),
+		})
+		rc.OnStatusUpdated(bapi.InSync)
+		Expect(rc.waitForSync(ctx)).NotTo(HaveOccurred())
+
+		out, err := rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.GetObjectMeta().GetName()).To(Equal("pool1"))
+		Expect(out.GetObjectMeta().GetResourceVersion()).To(Equal("5"))
+
+		list := &apiv3.IPPoolList{}
+		Expect(rc.List(ctx, options.ListOptions{}, apiv3.KindIPPool, apiv3.KindIPPoolList, list)).NotTo(HaveOccurred())
+		Expect(list.Items).To(HaveLen(2))
+		Expect(list.Items[0].Name).To(Equal("pool1"))
+		Expect(list.Items[1].Name).To(Equal("pool2"))
+		Expect(list.ResourceVersion).To(Equal("6"))
+		Expect(be.gets).To(BeEmpty())
+
+		By("modifying a returned resource and checking the cache is unchanged")
+		out.GetObjectMeta().SetName("modified")
+		out, err = rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.GetObjectMeta().GetName()).To(Equal("pool1"))
+
+		By("deleting a resource and checking the Get reads through to the datastore")
+		rc.OnUpdates([]bapi.Update{{
+			UpdateType: bapi.UpdateTypeKVDeleted,
+			KVPair:     model.KVPair{Key: model.ResourceKey{Kind: apiv3.KindIPPool, Name: "pool1"}},
+		}})
+		_, err = rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"get:"}))
+	})
+
+	It("should read through and fill the cache on a miss before the cache has synced", func() {
+		_, err := rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"get:"}))
+
+		By("getting the same resource and checking it is served from the cache")
+		out, err := rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.GetObjectMeta().GetResourceVersion()).To(Equal("1"))
+		Expect(be.gets).To(Equal([]string{"get:"}))
+
+		By("syncing without the filled entry and checking it is discarded")
+		rc.OnStatusUpdated(bapi.InSync)
+		_, err = rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"get:", "get:"}))
+	})
+
+	It("should normalize the resources from the syncer in the same way as a Get", func() {
+		p := pool("pool1")
+		p.SelfLink = "/apis/crd.projectcalico.org/v1/ippools/pool1"
+		p.ResourceVersion = "1"
+		rc.OnUpdates([]bapi.Update{update(bapi.UpdateTypeKVNew, apiv3.KindIPPool, p, "5")})
+		rc.OnStatusUpdated(bapi.InSync)
+
+		out, err := rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.GetObjectMeta().GetSelfLink()).To(BeEmpty())
+		Expect(out.GetObjectMeta().GetResourceVersion()).To(Equal("5"))
+
+		By("checking the update from the syncer is unchanged")
+		Expect(p.SelfLink).NotTo(BeEmpty())
+	})
+
+	It("should keep a filled entry that is confirmed by the syncer", func() {
+		_, err := rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		rc.OnUpdates([]bapi.Update{update(bapi.UpdateTypeKVNew, apiv3.KindIPPool, pool("pool1"), "7")})
+		rc.OnStatusUpdated(bapi.InSync)
+
+		out, err := rc.Get(ctx, options.GetOptions{}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.GetObjectMeta().GetResourceVersion()).To(Equal("7"))
+		Expect(be.gets).To(Equal([]string{"get:"}))
+	})
+
+	It("should respect the namespace and kind", func() {
+		rc.OnUpdates([]bapi.Update{
+			update(bapi.UpdateTypeKVNew, apiv3.KindNetworkPolicy, policy("ns1", "policy1"), "1"),
+			update(bapi.UpdateTypeKVNew, apiv3.KindNetworkPolicy, policy("ns2", "policy1"), "2"),
+			update(bapi.UpdateTypeKVNew, apiv3.KindIPPool, pool("policy1"), "3"),
+		})
+		rc.OnStatusUpdated(bapi.InSync)
+
+		out, err := rc.Get(ctx, options.GetOptions{}, apiv3.KindNetworkPolicy, "ns2", "policy1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.GetObjectMeta().GetNamespace()).To(Equal("ns2"))
+		Expect(out.GetObjectMeta().GetResourceVersion()).To(Equal("2"))
+		Expect(be.gets).To(BeEmpty())
+
+		list := &apiv3.NetworkPolicyList{}
+		err = rc.List(ctx, options.ListOptions{Namespace: "ns1"}, apiv3.KindNetworkPolicy, apiv3.KindNetworkPolicyList, list)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list.Items).To(HaveLen(1))
+		Expect(list.Items[0].Namespace).To(Equal("ns1"))
+
+		By("requiring a namespace for a namespaced kind")
+		_, err = rc.Get(ctx, options.GetOptions{}, apiv3.KindNetworkPolicy, noNamespace, "policy1")
+		Expect(err).To(HaveOccurred())
+
+		By("passing through Gets for kinds that are not cached")
+		_, err = rc.Get(ctx, options.GetOptions{}, apiv3.KindGlobalNetworkPolicy, noNamespace, "policy1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"get:"}))
+	})
+
+	It("should pass through requests for a specific revision", func() {
+		rc.OnUpdates([]bapi.Update{update(bapi.UpdateTypeKVNew, apiv3.KindIPPool, pool("pool1"), "5")})
+		rc.OnStatusUpdated(bapi.InSync)
+		_, err := rc.Get(ctx, options.GetOptions{ResourceVersion: "3"}, apiv3.KindIPPool, noNamespace, "pool1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"get:3"}))
+	})
+
+	It("should return an error if the context is done before the cache syncs", func() {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		Expect(rc.waitForSync(cctx)).To(Equal(context.Canceled))
+	})
+})
@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/namespace"
+	"github.com/projectcalico/libcalico-go/lib/options"
+)
+
+// DeleteCollectionResult is the result of a DeleteCollection.
+type DeleteCollectionResult struct {
+	// Deleted is the number of resources that were deleted.
+	Deleted int
+
+	// Failed contains an entry for each resource that could not be deleted.
+	Failed []DeleteCollectionFailure
+}
+
+// DeleteCollectionFailure identifies a resource that could not be deleted by a DeleteCollection,
+// along with the reason.
+type DeleteCollectionFailure struct {
+	Namespace string
+	Name      string
+	Err       error
+}
+
+// resourceDeleters contains, for each kind whose Delete does more than remove the resource from
+// the backend, a function that deletes a single listed resource using that Delete.
+var resourceDeleters = map[string]func(ctx context.Context, c client, name string, opts options.DeleteOptions) error{
+	apiv3.KindIPPool: func(ctx context.Context, c client, name string, opts options.DeleteOptions) error {
+		_, err := c.IPPools().Delete(ctx, name, opts)
+		return err
+	},
+	apiv3.KindNode: func(ctx context.Context, c client, name string, opts options.DeleteOptions) error {
+		_, err := c.Nodes().Delete(ctx, name, opts)
+		return err
+	},
+	apiv3.KindProfile: func(ctx context.Context, c client, name string, opts options.DeleteOptions) error {
+		_, err := c.Profiles().Delete(ctx, name, opts)
+		return err
+	},
+}
+
+// DeleteCollection deletes all resources of the given kind that match the list options.  The
+// resources are listed and each is then deleted at the listed revision, so a resource that is
+// modified concurrently is not deleted and is reported as failed.  Resources whose Delete does
+// additional processing (such as releasing the IP addresses of a Node, or the affinities of an
+// IPPool) are deleted one at a time using that Delete.  Otherwise, if the backend implements
+// bapi.BulkDeleter then the resources are deleted in a single operation.  Kubernetes network
+// policies are read-only and are never deleted.
+//
+// An error is returned if the resources cannot be listed.  If some of the resources cannot be
+// deleted, the result reports each failure and the error is an ErrorPartialFailure.
+func (c client) DeleteCollection(ctx context.Context, kind string, opts options.ListOptions) (*DeleteCollectionResult, error) {
+	if opts.Namespace != "" && !namespace.IsNamespaced(kind) {
+		return nil, cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "Namespace",
+				Value:  opts.Namespace,
+				Reason: "namespace is specified on a resource type that is not namespaced",
+			}},
+		}
+	}
+	// Policy names are stored with a tier prefix, so convert the name in the same way as the
+	// policy List.
+	isPolicy := kind == apiv3.KindNetworkPolicy || kind == apiv3.KindGlobalNetworkPolicy
+	if isPolicy && opts.Name != "" {
+		opts.Name = convertPolicyNameForStorage(opts.Name)
+	}
+	list, err := c.backend.List(ctx, model.ResourceListOptions{
+		Kind:      kind,
+		Name:      opts.Name,
+		Namespace: opts.Namespace,
+		Prefix:    opts.Prefix,
+	}, opts.ResourceVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Kubernetes network policies are listed as read-only NetworkPolicies, and cannot be deleted
+	// through Calico.
+	kvps := list.KVPairs
+	if isPolicy {
+		kvps = nil
+		for _, kvp := range list.KVPairs {
+			if !strings.HasPrefix(kvp.Key.(model.ResourceKey).Name, "knp.") {
+				kvps = append(kvps, kvp)
+			}
+		}
+	}
+
+	errs := c.deleteKVPs(ctx, kind, kvps)
+	result := &DeleteCollectionResult{}
+	for i, kvp := range kvps {
+		if errs[i] == nil {
+			result.Deleted++
+			continue
+		}
+		key := kvp.Key.(model.ResourceKey)
+		name := key.Name
+		if isPolicy {
+			name = convertPolicyNameFromStorage(name)
+		}
+		log.WithError(errs[i]).WithFields(log.Fields{"Kind": kind, "Namespace": key.Namespace, "Name": name}).Info("Failed to delete resource")
+		result.Failed = append(result.Failed, DeleteCollectionFailure{Namespace: key.Namespace, Name: name, Err: errs[i]})
+	}
+	if len(result.Failed) > 0 {
+		return result, cerrors.ErrorPartialFailure{
+			Err: fmt.Errorf("failed to delete %d of %d %s resources", len(result.Failed), len(kvps), kind),
+		}
+	}
+	return result, nil
+}
+
+// deleteKVPs deletes the KVPairs of the given kind, using the Delete for the kind if it has
+// additional processing, or else the bulk delete of the backend if supported, and returns the
+// error for each KVPair.
+func (c client) deleteKVPs(ctx context.Context, kind string, kvps []*model.KVPair) []error {
+	if del, ok := resourceDeleters[kind]; ok {
+		errs := make([]error, len(kvps))
+		for i, kvp := range kvps {
+			errs[i] = del(ctx, c, kvp.Key.(model.ResourceKey).Name, options.DeleteOptions{ResourceVersion: kvp.Revision})
+		}
+		return errs
+	}
+	if bd, ok := c.backend.(bapi.BulkDeleter); ok && len(kvps) > 0 {
+		_, errs := bd.DeleteKVPs(ctx, kvps)
+		return errs
+	}
+	errs := make([]error, len(kvps))
+	for i, kvp := range kvps {
+		_, errs[i] = c.backend.DeleteKVP(ctx, kvp)
+	}
+	return errs
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	cresources "github.com/projectcalico/libcalico-go/lib/resources"
+)
+
+// endpointBackend is a backend client that stores WorkloadEndpoints by name, and fails to delete
+// the endpoints named in failDeletes.
+type endpointBackend struct {
+	bapi.Client
+	endpoints   map[string]*apiv3.WorkloadEndpoint
+	failDeletes map[string]bool
+	deletes     int
+}
+
+func newEndpointBackend(names ...string) *endpointBackend {
+	b := &endpointBackend{endpoints: map[string]*apiv3.WorkloadEndpoint{}, failDeletes: map[string]bool{}}
+	for _, name := range names {
+		wep := apiv3.NewWorkloadEndpoint()
+		wep.Name = name
+		wep.Namespace = "default"
+		b.endpoints[name] = wep
+	}
+	return b
+}
+
+func (b *endpointBackend) List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+	opts := list.(model.ResourceListOptions)
+	kvps := &model.KVPairList{}
+	for _, name := range []string{"node1-a", "node1-b", "node1-c", "node2-a"} {
+		wep, ok := b.endpoints[name]
+		if !ok || (opts.Prefix && !strings.HasPrefix(name, opts.Name)) || (!opts.Prefix && opts.Name != "" && opts.Name != name) {
+			continue
+		}
+		kvps.KVPairs = append(kvps.KVPairs, &model.KVPair{
+			Key:      model.ResourceKey{Kind: apiv3.KindWorkloadEndpoint, Namespace: wep.Namespace, Name: name},
+			Value:    wep,
+			Revision: "1",
+		})
+	}
+	return kvps, nil
+}
+
+func (b *endpointBackend) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	b.deletes++
+	name := kvp.Key.(model.ResourceKey).Name
+	if b.failDeletes[name] {
+		return nil, cerrors.ErrorResourceUpdateConflict{Identifier: kvp.Key}
+	}
+	delete(b.endpoints, name)
+	return kvp, nil
+}
+
+// bulkEndpointBackend is an endpointBackend that supports bulk deletes.
+type bulkEndpointBackend struct {
+	*endpointBackend
+	bulkDeletes int
+}
+
+func (b *bulkEndpointBackend) DeleteKVPs(ctx context.Context, kvps []*model.KVPair) ([]*model.KVPair, []error) {
+	b.bulkDeletes++
+	out := make([]*model.KVPair, len(kvps))
+	errs := make([]error, len(kvps))
+	for i, kvp := range kvps {
+		out[i], errs[i] = b.endpointBackend.DeleteKVP(ctx, kvp)
+	}
+	return out, errs
+}
+
+// profileBackend is a backend client that lists the default-allow profile along with the named
+// profiles, and supports bulk deletes.
+type profileBackend struct {
+	bapi.Client
+	profiles    []string
+	bulkDeletes int
+}
+
+func (b *profileBackend) List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+	kvps := &model.KVPairList{KVPairs: []*model.KVPair{cresources.DefaultAllowProfile()}}
+	for _, name := range b.profiles {
+		kvps.KVPairs = append(kvps.KVPairs, &model.KVPair{
+			Key:      model.ResourceKey{Kind: apiv3.KindProfile, Name: name},
+			Value:    apiv3.NewProfile(),
+			Revision: "1",
+		})
+	}
+	return kvps, nil
+}
+
+func (b *profileBackend) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	name := kvp.Key.(model.ResourceKey).Name
+	for i, p := range b.profiles {
+		if p == name {
+			b.profiles = append(b.profiles[:i], b.profiles[i+1:]...)
+			profile := apiv3.NewProfile()
+			profile.Name = name
+			return &model.KVPair{Key: kvp.Key, Value: profile}, nil
+		}
+	}
+	return nil, cerrors.ErrorResourceDoesNotExist{Identifier: kvp.Key}
+}
+
+func (b *profileBackend) DeleteKVPs(ctx context.Context, kvps []*model.KVPair) ([]*model.KVPair, []error) {
+	b.bulkDeletes++
+	return nil, make([]error, len(kvps))
+}
+
+// policyBackend is a backend client that stores NetworkPolicies by their storage name, and fails
+// to delete the policies named in failDeletes.
+type policyBackend struct {
+	bapi.Client
+	policies    map[string]bool
+	failDeletes map[string]bool
+}
+
+func (b *policyBackend) List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+	opts := list.(model.ResourceListOptions)
+	kvps := &model.KVPairList{}
+	for _, name := range []string{"default.foo", "default.bar", "knp.default.baz"} {
+		if !b.policies[name] || (opts.Name != "" && opts.Name != name) {
+			continue
+		}
+		kvps.KVPairs = append(kvps.KVPairs, &model.KVPair{
+			Key:      model.ResourceKey{Kind: apiv3.KindNetworkPolicy, Namespace: "default", Name: name},
+			Value:    apiv3.NewNetworkPolicy(),
+			Revision: "1",
+		})
+	}
+	return kvps, nil
+}
+
+func (b *policyBackend) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	name := kvp.Key.(model.ResourceKey).Name
+	if b.failDeletes[name] {
+		return nil, cerrors.ErrorResourceUpdateConflict{Identifier: kvp.Key}
+	}
+	delete(b.policies, name)
+	return kvp, nil
+}
+
+var _ = Describe("DeleteCollection", func() {
+	ctx := context.Background()
+	node1 := options.ListOptions{Namespace: "default", Name: "node1-", Prefix: true}
+
+	It("should delete the matching resources", func() {
+		be := newEndpointBackend("node1-a", "node1-b", "node2-a")
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		res, err := c.DeleteCollection(ctx, apiv3.KindWorkloadEndpoint, node1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&DeleteCollectionResult{Deleted: 2}))
+		Expect(be.endpoints).To(HaveLen(1))
+		Expect(be.endpoints).To(HaveKey("node2-a"))
+	})
+
+	It("should report the resources that could not be deleted", func() {
+		be := newEndpointBackend("node1-a", "node1-b", "node1-c", "node2-a")
+		be.failDeletes["node1-b"] = true
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		res, err := c.DeleteCollection(ctx, apiv3.KindWorkloadEndpoint, node1)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorPartialFailure{}))
+		Expect(err.Error()).To(ContainSubstring("failed to delete 1 of 3"))
+		Expect(res.Deleted).To(Equal(2))
+		Expect(res.Failed).To(HaveLen(1))
+		Expect(res.Failed[0].Namespace).To(Equal("default"))
+		Expect(res.Failed[0].Name).To(Equal("node1-b"))
+		Expect(res.Failed[0].Err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+		Expect(be.endpoints).To(HaveLen(2))
+		Expect(be.endpoints).To(HaveKey("node1-b"))
+	})
+
+	It("should use the bulk delete of the backend", func() {
+		be := &bulkEndpointBackend{endpointBackend: newEndpointBackend("node1-a", "node1-b", "node2-a")}
+		be.failDeletes["node1-a"] = true
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		res, err := c.DeleteCollection(ctx, apiv3.KindWorkloadEndpoint, node1)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorPartialFailure{}))
+		Expect(res.Deleted).To(Equal(1))
+		Expect(res.Failed).To(HaveLen(1))
+		Expect(res.Failed[0].Name).To(Equal("node1-a"))
+		Expect(be.bulkDeletes).To(Equal(1))
+	})
+
+	It("should delete resources using the Delete of the kind", func() {
+		be := &profileBackend{profiles: []string{"profile-a", "profile-b"}}
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		res, err := c.DeleteCollection(ctx, apiv3.KindProfile, options.ListOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorPartialFailure{}))
+		Expect(res.Deleted).To(Equal(2))
+		Expect(res.Failed).To(HaveLen(1))
+		Expect(res.Failed[0].Name).To(Equal(cresources.DefaultAllowProfileName))
+		Expect(res.Failed[0].Err).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+		Expect(be.profiles).To(BeEmpty())
+		Expect(be.bulkDeletes).To(BeZero())
+	})
+
+	It("should delete a named policy using the policy name", func() {
+		be := &policyBackend{policies: map[string]bool{"default.foo": true, "default.bar": true}}
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		res, err := c.DeleteCollection(ctx, apiv3.KindNetworkPolicy, options.ListOptions{Namespace: "default", Name: "foo"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&DeleteCollectionResult{Deleted: 1}))
+		Expect(be.policies).To(Equal(map[string]bool{"default.bar": true}))
+	})
+
+	It("should not delete Kubernetes network policies and should report failures by policy name", func() {
+		be := &policyBackend{
+			policies:    map[string]bool{"default.foo": true, "default.bar": true, "knp.default.baz": true},
+			failDeletes: map[string]bool{"default.bar": true},
+		}
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		res, err := c.DeleteCollection(ctx, apiv3.KindNetworkPolicy, options.ListOptions{Namespace: "default"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorPartialFailure{}))
+		Expect(err.Error()).To(ContainSubstring("failed to delete 1 of 2"))
+		Expect(res.Deleted).To(Equal(1))
+		Expect(res.Failed).To(HaveLen(1))
+		Expect(res.Failed[0].Name).To(Equal("bar"))
+		Expect(be.policies).To(Equal(map[string]bool{"default.bar": true, "knp.default.baz": true}))
+	})
+
+	It("should do nothing if no resources match", func() {
+		be := newEndpointBackend("node2-a")
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		res, err := c.DeleteCollection(ctx, apiv3.KindWorkloadEndpoint, node1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&DeleteCollectionResult{}))
+		Expect(be.deletes).To(BeZero())
+	})
+
+	It("should reject a namespace for a resource type that is not namespaced", func() {
+		be := newEndpointBackend()
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		_, err := c.DeleteCollection(ctx, apiv3.KindIPPool, options.ListOptions{Namespace: "default"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+	})
+})
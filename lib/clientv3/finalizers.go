@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/namespace"
+	"github.com/projectcalico/libcalico-go/lib/options"
+)
+
+// AddFinalizer adds the finalizer to the cluster-scoped resource of the given kind and name.  This
+// is a no-op if the resource already has the finalizer.  The resource is updated using a
+// read-modify-write that is retried if the update conflicts with a concurrent update.
+func (c client) AddFinalizer(ctx context.Context, kind, name, finalizer string) error {
+	name, err := finalizerResourceName(kind, name)
+	if err != nil {
+		return err
+	}
+	_, err = updateFinalizers(ctx, c.resources, kind, noNamespace, name, func(finalizers []string) ([]string, bool) {
+		for _, f := range finalizers {
+			if f == finalizer {
+				return finalizers, false
+			}
+		}
+		return append(finalizers, finalizer), true
+	})
+	return err
+}
+
+// RemoveFinalizer removes the finalizer from the cluster-scoped resource of the given kind and
+// name.  This is a no-op if the resource does not have the finalizer.  The resource is updated
+// using a read-modify-write that is retried if the update conflicts with a concurrent update.
+func (c client) RemoveFinalizer(ctx context.Context, kind, name, finalizer string) error {
+	name, err := finalizerResourceName(kind, name)
+	if err != nil {
+		return err
+	}
+	_, err = updateFinalizers(ctx, c.resources, kind, noNamespace, name, func(finalizers []string) ([]string, bool) {
+		out := make([]string, 0, len(finalizers))
+		for _, f := range finalizers {
+			if f != finalizer {
+				out = append(out, f)
+			}
+		}
+		return out, len(out) != len(finalizers)
+	})
+	return err
+}
+
+// finalizerResourceName validates that the kind is cluster-scoped, and returns the name of the
+// resource as stored, which for a GlobalNetworkPolicy includes the tier prefix.
+func finalizerResourceName(kind, name string) (string, error) {
+	if namespace.IsNamespaced(kind) {
+		return "", cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "Kind",
+				Value:  kind,
+				Reason: "finalizers can only be updated on a resource type that is not namespaced",
+			}},
+		}
+	}
+	if kind == apiv3.KindGlobalNetworkPolicy {
+		return convertPolicyNameForStorage(name), nil
+	}
+	return name, nil
+}
+
+// updateFinalizers applies the update function to the finalizers of a resource, and updates the
+// resource if the function reports that the finalizers have changed.  If the update conflicts
+// with a concurrent update then the resource is re-read and the update retried, up to
+// maxApplyRetries times.  Returns the resource, as updated if an update was needed.
+func updateFinalizers(
+	ctx context.Context, r resourceInterface, kind, ns, name string, update func([]string) ([]string, bool),
+) (resource, error) {
+	logCxt := log.WithFields(log.Fields{"Kind": kind, "Namespace": ns, "Name": name})
+	for i := 0; i < maxApplyRetries; i++ {
+		res, err := r.Get(ctx, options.GetOptions{}, kind, ns, name)
+		if err != nil {
+			return nil, err
+		}
+
+		finalizers, changed := update(res.GetObjectMeta().GetFinalizers())
+		if !changed {
+			logCxt.Debug("Finalizers already up to date")
+			return res, nil
+		}
+		res.GetObjectMeta().SetFinalizers(finalizers)
+
+		out, err := r.Update(ctx, options.SetOptions{}, kind, res)
+		if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok {
+			logCxt.WithError(err).Debug("Conflict updating finalizers, retrying")
+			continue
+		}
+		return out, err
+	}
+	logCxt.Warning("Too many conflicts updating finalizers")
+	return nil, cerrors.ErrorResourceUpdateConflict{Identifier: name}
+}
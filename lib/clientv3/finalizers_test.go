@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+// poolBackend is a backend client that stores a single IP pool, and fails the first updates with
+// an update conflict.
+type poolBackend struct {
+	bapi.Client
+	pool      *apiv3.IPPool
+	revision  int
+	conflicts int
+	updates   int
+}
+
+func newPoolBackend(finalizers ...string) *poolBackend {
+	pool := apiv3.NewIPPool()
+	pool.Name = "pool"
+	pool.UID = "uid"
+	pool.CreationTimestamp = metav1.Now()
+	pool.Finalizers = finalizers
+	return &poolBackend{pool: pool, revision: 1}
+}
+
+func (b *poolBackend) Get(ctx context.Context, key model.Key, revision string) (*model.KVPair, error) {
+	if key.(model.ResourceKey).Name != b.pool.Name {
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: key}
+	}
+	return &model.KVPair{Key: key, Value: b.pool.DeepCopy(), Revision: strconv.Itoa(b.revision)}, nil
+}
+
+func (b *poolBackend) Update(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	if b.conflicts > 0 {
+		// Simulate a concurrent update.
+		b.conflicts--
+		b.revision++
+		return nil, cerrors.ErrorResourceUpdateConflict{Identifier: kvp.Key}
+	}
+	if kvp.Revision != strconv.Itoa(b.revision) {
+		return nil, cerrors.ErrorResourceUpdateConflict{Identifier: kvp.Key}
+	}
+	b.updates++
+	b.revision++
+	b.pool = kvp.Value.(*apiv3.IPPool).DeepCopy()
+	return &model.KVPair{Key: kvp.Key, Value: b.pool.DeepCopy(), Revision: strconv.Itoa(b.revision)}, nil
+}
+
+// globalPolicyBackend is a backend client that stores a single GlobalNetworkPolicy.
+type globalPolicyBackend struct {
+	bapi.Client
+	policy *apiv3.GlobalNetworkPolicy
+}
+
+func (b *globalPolicyBackend) Get(ctx context.Context, key model.Key, revision string) (*model.KVPair, error) {
+	if key.(model.ResourceKey).Name != b.policy.Name {
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: key}
+	}
+	return &model.KVPair{Key: key, Value: b.policy.DeepCopy(), Revision: "1"}, nil
+}
+
+func (b *globalPolicyBackend) Update(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	b.policy = kvp.Value.(*apiv3.GlobalNetworkPolicy).DeepCopy()
+	return &model.KVPair{Key: kvp.Key, Value: b.policy.DeepCopy(), Revision: "2"}, nil
+}
+
+var _ = Describe("Resource finalizers", func() {
+	ctx := context.Background()
+
+	It("should add a finalizer once", func() {
+		be := newPoolBackend("other")
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		Expect(c.AddFinalizer(ctx, apiv3.KindIPPool, "pool", "test/finalizer")).To(Succeed())
+		Expect(be.pool.Finalizers).To(Equal([]string{"other", "test/finalizer"}))
+		Expect(be.updates).To(Equal(1))
+
+		Expect(c.AddFinalizer(ctx, apiv3.KindIPPool, "pool", "test/finalizer")).To(Succeed())
+		Expect(be.pool.Finalizers).To(Equal([]string{"other", "test/finalizer"}))
+		Expect(be.updates).To(Equal(1))
+	})
+
+	It("should remove a finalizer once", func() {
+		be := newPoolBackend("other", "test/finalizer")
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		Expect(c.RemoveFinalizer(ctx, apiv3.KindIPPool, "pool", "test/finalizer")).To(Succeed())
+		Expect(be.pool.Finalizers).To(Equal([]string{"other"}))
+		Expect(be.updates).To(Equal(1))
+
+		Expect(c.RemoveFinalizer(ctx, apiv3.KindIPPool, "pool", "test/finalizer")).To(Succeed())
+		Expect(be.pool.Finalizers).To(Equal([]string{"other"}))
+		Expect(be.updates).To(Equal(1))
+	})
+
+	It("should retry on an update conflict", func() {
+		be := newPoolBackend()
+		be.conflicts = 2
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		Expect(c.AddFinalizer(ctx, apiv3.KindIPPool, "pool", "test/finalizer")).To(Succeed())
+		Expect(be.pool.Finalizers).To(Equal([]string{"test/finalizer"}))
+		Expect(be.updates).To(Equal(1))
+	})
+
+	It("should give up after too many update conflicts", func() {
+		be := newPoolBackend()
+		be.conflicts = maxApplyRetries
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		err := c.AddFinalizer(ctx, apiv3.KindIPPool, "pool", "test/finalizer")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+		Expect(be.updates).To(BeZero())
+	})
+
+	It("should return an error if the resource does not exist", func() {
+		be := newPoolBackend()
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		err := c.RemoveFinalizer(ctx, apiv3.KindIPPool, "missing", "test/finalizer")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+
+	It("should update the finalizers of a GlobalNetworkPolicy using the policy name", func() {
+		policy := apiv3.NewGlobalNetworkPolicy()
+		policy.Name = "default.foo"
+		policy.UID = "uid"
+		policy.CreationTimestamp = metav1.Now()
+		be := &globalPolicyBackend{policy: policy}
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		Expect(c.AddFinalizer(ctx, apiv3.KindGlobalNetworkPolicy, "foo", "test/finalizer")).To(Succeed())
+		Expect(be.policy.Name).To(Equal("default.foo"))
+		Expect(be.policy.Finalizers).To(Equal([]string{"test/finalizer"}))
+
+		Expect(c.RemoveFinalizer(ctx, apiv3.KindGlobalNetworkPolicy, "foo", "test/finalizer")).To(Succeed())
+		Expect(be.policy.Finalizers).To(BeEmpty())
+	})
+
+	It("should reject a resource type that is namespaced", func() {
+		be := newPoolBackend()
+		c := client{backend: be, resources: &resources{backend: be}}
+
+		err := c.AddFinalizer(ctx, apiv3.KindNetworkPolicy, "foo", "test/finalizer")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+		err = c.RemoveFinalizer(ctx, apiv3.KindWorkloadEndpoint, "foo", "test/finalizer")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+	})
+})
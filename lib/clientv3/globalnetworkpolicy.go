@@ -20,6 +20,7 @@ import (
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
+	cresources "github.com/projectcalico/libcalico-go/lib/resources"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
 	"github.com/projectcalico/libcalico-go/lib/watch"
 )
@@ -48,7 +49,7 @@ func (r globalNetworkPolicies) Create(ctx context.Context, res *apiv3.GlobalNetw
 		resCopy := *res
 		res = &resCopy
 	}
-	defaultPolicyTypesField(res.Spec.Ingress, res.Spec.Egress, &res.Spec.Types)
+	cresources.Default(res)
 
 	if err := validator.Validate(res); err != nil {
 		return nil, err
@@ -77,7 +78,7 @@ func (r globalNetworkPolicies) Update(ctx context.Context, res *apiv3.GlobalNetw
 		resCopy := *res
 		res = &resCopy
 	}
-	defaultPolicyTypesField(res.Spec.Ingress, res.Spec.Egress, &res.Spec.Types)
+	cresources.Default(res)
 
 	if err := validator.Validate(res); err != nil {
 		return nil, err
@@ -152,27 +153,6 @@ func (r globalNetworkPolicies) Watch(ctx context.Context, opts options.ListOptio
 	return r.client.resources.Watch(ctx, opts, apiv3.KindGlobalNetworkPolicy, &policyConverter{})
 }
 
-func defaultPolicyTypesField(ingressRules, egressRules []apiv3.Rule, types *[]apiv3.PolicyType) {
-	if len(*types) == 0 {
-		// Default the Types field according to what inbound and outbound rules are present
-		// in the policy.
-		if len(egressRules) == 0 {
-			// Policy has no egress rules, so apply this policy to ingress only.  (Note:
-			// intentionally including the case where the policy also has no ingress
-			// rules.)
-			*types = []apiv3.PolicyType{apiv3.PolicyTypeIngress}
-		} else if len(ingressRules) == 0 {
-			// Policy has egress rules but no ingress rules, so apply this policy to
-			// egress only.
-			*types = []apiv3.PolicyType{apiv3.PolicyTypeEgress}
-		} else {
-			// Policy has both ingress and egress rules, so apply this policy to both
-			// ingress and egress.
-			*types = []apiv3.PolicyType{apiv3.PolicyTypeIngress, apiv3.PolicyTypeEgress}
-		}
-	}
-}
-
 func convertPolicyNameForStorage(name string) string {
 	// Do nothing on names prefixed with "knp."
 	if strings.HasPrefix(name, "knp.") {
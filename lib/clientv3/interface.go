@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"github.com/projectcalico/libcalico-go/lib/ipam"
+	"github.com/projectcalico/libcalico-go/lib/options"
 )
 
 type Interface interface {
@@ -53,6 +54,20 @@ type Interface interface {
 	// KubeControllersConfiguration resource.
 	KubeControllersConfiguration() KubeControllersConfigurationInterface
 
+	// AddFinalizer adds the finalizer to the cluster-scoped resource of the given kind and
+	// name, retrying on update conflicts.  This is a no-op if the finalizer is already present.
+	// An ErrorValidation is returned if the kind is namespaced.
+	AddFinalizer(ctx context.Context, kind, name, finalizer string) error
+	// RemoveFinalizer removes the finalizer from the cluster-scoped resource of the given kind
+	// and name, retrying on update conflicts.  This is a no-op if the finalizer is not present.
+	// An ErrorValidation is returned if the kind is namespaced.
+	RemoveFinalizer(ctx context.Context, kind, name, finalizer string) error
+
+	// DeleteCollection deletes all resources of the given kind that match the list options.
+	// The result reports the number of resources deleted and the error for each resource that
+	// could not be deleted.  See DeleteCollectionResult.
+	DeleteCollection(ctx context.Context, kind string, opts options.ListOptions) (*DeleteCollectionResult, error)
+
 	// EnsureInitialized is used to ensure the backend datastore is correctly
 	// initialized for use by Calico.  This method may be called multiple times, and
 	// will have no effect if the datastore is already correctly initialized.
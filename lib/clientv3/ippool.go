@@ -27,6 +27,7 @@ import (
 	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
 	"github.com/projectcalico/libcalico-go/lib/options"
+	cresources "github.com/projectcalico/libcalico-go/lib/resources"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
 	"github.com/projectcalico/libcalico-go/lib/watch"
 )
@@ -327,10 +328,8 @@ func (r ipPools) validateAndSetDefaults(ctx context.Context, new, old *apiv3.IPP
 	// Normalize the CIDR before persisting.
 	new.Spec.CIDR = cidr.String()
 
-	// If a nodeSelector is not specified, then this IP pool selects all nodes.
-	if new.Spec.NodeSelector == "" {
-		new.Spec.NodeSelector = "all()"
-	}
+	// Default the node selector, block size and encapsulation modes.
+	cresources.Default(new)
 
 	// If there was a previous pool then this must be an Update, validate that the
 	// CIDR has not changed.  Since we are using normalized CIDRs we can just do a
@@ -343,15 +342,6 @@ func (r ipPools) validateAndSetDefaults(ctx context.Context, new, old *apiv3.IPP
 		})
 	}
 
-	// Default the blockSize
-	if new.Spec.BlockSize == 0 {
-		if ipAddr.Version() == 4 {
-			new.Spec.BlockSize = 26
-		} else {
-			new.Spec.BlockSize = 122
-		}
-	}
-
 	// Check that the blockSize hasn't changed since updates are not supported.
 	if old != nil && old.Spec.BlockSize != new.Spec.BlockSize {
 		errFields = append(errFields, cerrors.ErroredField{
@@ -423,16 +413,6 @@ func (r ipPools) validateAndSetDefaults(ctx context.Context, new, old *apiv3.IPP
 		}
 	}
 
-	// Make sure IPIPMode is defaulted to "Never".
-	if len(new.Spec.IPIPMode) == 0 {
-		new.Spec.IPIPMode = apiv3.IPIPModeNever
-	}
-
-	// Make sure VXLANMode is defaulted to "Never".
-	if len(new.Spec.VXLANMode) == 0 {
-		new.Spec.VXLANMode = apiv3.VXLANModeNever
-	}
-
 	// Make sure only one of VXLAN and IPIP is enabled.
 	if new.Spec.VXLANMode != apiv3.VXLANModeNever && new.Spec.IPIPMode != apiv3.IPIPModeNever {
 		errFields = append(errFields, cerrors.ErroredField{
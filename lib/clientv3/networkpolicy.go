@@ -19,6 +19,7 @@ import (
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
+	cresources "github.com/projectcalico/libcalico-go/lib/resources"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
 	"github.com/projectcalico/libcalico-go/lib/watch"
 )
@@ -47,7 +48,7 @@ func (r networkPolicies) Create(ctx context.Context, res *apiv3.NetworkPolicy, o
 		resCopy := *res
 		res = &resCopy
 	}
-	defaultPolicyTypesField(res.Spec.Ingress, res.Spec.Egress, &res.Spec.Types)
+	cresources.Default(res)
 
 	if err := validator.Validate(res); err != nil {
 		return nil, err
@@ -76,7 +77,7 @@ func (r networkPolicies) Update(ctx context.Context, res *apiv3.NetworkPolicy, o
 		resCopy := *res
 		res = &resCopy
 	}
-	defaultPolicyTypesField(res.Spec.Ingress, res.Spec.Egress, &res.Spec.Types)
+	cresources.Default(res)
 
 	if err := validator.Validate(res); err != nil {
 		return nil, err
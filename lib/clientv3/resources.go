@@ -179,13 +179,29 @@ func (c *resources) Delete(ctx context.Context, opts options.DeleteOptions, kind
 		Revision: opts.ResourceVersion,
 		UID:      opts.UID,
 	}
-	kvp, err := c.backend.DeleteKVP(ctx, &kvpIn)
+	kvp, err := c.deleteKVP(ctx, opts, &kvpIn)
 	if kvp != nil {
 		return c.kvPairToResource(kvp), err
 	}
 	return nil, err
 }
 
+// deleteKVP performs the backend delete for the supplied KVPair.  If a propagation policy is
+// requested and the backend supports it then the policy is passed to the backend, otherwise
+// this falls back to a standard delete.
+func (c *resources) deleteKVP(ctx context.Context, opts options.DeleteOptions, kvp *model.KVPair) (*model.KVPair, error) {
+	if opts.PropagationPolicy != nil {
+		if pd, ok := c.backend.(bapi.PropagatingDeleter); ok {
+			out, err := pd.DeleteKVPWithPropagation(ctx, kvp, *opts.PropagationPolicy)
+			if _, ok := err.(cerrors.ErrorOperationNotSupported); !ok {
+				return out, err
+			}
+		}
+		log.WithField("Key", kvp.Key).Debug("Propagation policy not supported by backend, performing standard delete")
+	}
+	return c.backend.DeleteKVP(ctx, kvp)
+}
+
 // Get gets a resource from the backend datastore.
 func (c *resources) Get(ctx context.Context, opts options.GetOptions, kind, ns, name string) (resource, error) {
 	if err := c.checkNamespace(ns, kind); err != nil {
@@ -196,7 +212,7 @@ func (c *resources) Get(ctx context.Context, opts options.GetOptions, kind, ns,
 		Name:      name,
 		Namespace: ns,
 	}
-	kvp, err := c.backend.Get(ctx, key, opts.ResourceVersion)
+	kvp, err := c.get(ctx, opts, key)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +220,23 @@ func (c *resources) Get(ctx context.Context, opts options.GetOptions, kind, ns,
 	return out, nil
 }
 
+// get performs the backend Get for the supplied key.  If a consistent read is requested and
+// the backend supports it then a consistent read is performed, otherwise this falls back to a
+// standard Get.
+func (c *resources) get(ctx context.Context, opts options.GetOptions, key model.Key) (*model.KVPair, error) {
+	if opts.ConsistentRead {
+		if cr, ok := c.backend.(bapi.ConsistentReader); ok {
+			kvp, err := cr.GetConsistent(ctx, key)
+			if _, ok := err.(cerrors.ErrorOperationNotSupported); !ok {
+				return kvp, err
+			}
+		}
+		log.WithField("Key", key).Debug("Consistent read not supported by backend, performing standard read")
+		return c.backend.Get(ctx, key, "")
+	}
+	return c.backend.Get(ctx, key, opts.ResourceVersion)
+}
+
 // List lists a resource from the backend datastore.
 func (c *resources) List(ctx context.Context, opts options.ListOptions, kind, listKind string, listObj resourceList) error {
 	list := model.ResourceListOptions{
@@ -248,10 +281,25 @@ func (c *resources) Watch(ctx context.Context, opts options.ListOptions, kind st
 		Namespace: opts.Namespace,
 	}
 
+	// If we are filtering events and watching from the current state, the backend watcher
+	// would send the current state as Added events which may be filtered out.  Instead, list
+	// the current state and watch from the revision of the list.
+	revision := opts.ResourceVersion
+	var initial []*model.KVPair
+	if len(opts.EventTypes) > 0 && revision == "" {
+		kvps, err := c.backend.List(ctx, list, "")
+		if err != nil {
+			return nil, err
+		}
+		initial = kvps.KVPairs
+		revision = kvps.Revision
+	}
+
 	// Create the backend watcher.  We need to process the results to add revision data etc.
 	ctx, cancel := context.WithCancel(ctx)
-	backend, err := c.backend.Watch(ctx, list, opts.ResourceVersion)
+	backend, err := c.backend.Watch(ctx, list, revision)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 	w := &watcher{
@@ -261,6 +309,8 @@ func (c *resources) Watch(ctx context.Context, opts options.ListOptions, kind st
 		context:   ctx,
 		backend:   backend,
 		converter: converter,
+		opts:      opts,
+		initial:   initial,
 	}
 	go w.run()
 	return w, nil
@@ -333,6 +383,11 @@ type watcher struct {
 	client     *resources
 	terminated uint32
 	converter  watcherConverter
+	opts       options.ListOptions
+
+	// The current state of the watched resources, sent as Added events before any backend
+	// events.  Only set when filtering events.
+	initial []*model.KVPair
 }
 
 func (w *watcher) Stop() {
@@ -351,6 +406,16 @@ func (w *watcher) run() {
 	// Make sure we terminate resources if we exit.
 	defer w.terminate()
 
+	for _, kvp := range w.initial {
+		select {
+		case w.results <- w.convertEvent(bapi.WatchEvent{Type: bapi.WatchAdded, New: kvp}):
+		case <-w.context.Done():
+			log.Info("Process backend watcher done event during initial state in main client")
+			return
+		}
+	}
+	w.initial = nil
+
 	for {
 		select {
 		case event, ok := <-w.backend.ResultChan():
@@ -358,7 +423,15 @@ func (w *watcher) run() {
 				log.Debug("Watcher results channel closed by remote")
 				return
 			}
+			if event.Type == bapi.WatchBookmark {
+				// Bookmarks only advance the backend watch revision, there is nothing to
+				// send to the client.
+				continue
+			}
 			e := w.convertEvent(event)
+			if !w.opts.WantsEvent(e.Type) {
+				continue
+			}
 			select {
 			case w.results <- e:
 			case <-w.context.Done():
@@ -0,0 +1,271 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+// getRecordingBackend is a backend client that records the Get calls made to it.
+type getRecordingBackend struct {
+	bapi.Client
+	gets []string
+}
+
+func (b *getRecordingBackend) Get(ctx context.Context, key model.Key, revision string) (*model.KVPair, error) {
+	b.gets = append(b.gets, "get:"+revision)
+	return &model.KVPair{Key: key, Value: apiv3.NewIPPool(), Revision: "1"}, nil
+}
+
+// consistentReadBackend is a backend client that also supports consistent reads.
+type consistentReadBackend struct {
+	getRecordingBackend
+	err error
+}
+
+func (b *consistentReadBackend) GetConsistent(ctx context.Context, key model.Key) (*model.KVPair, error) {
+	b.gets = append(b.gets, "consistent")
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &model.KVPair{Key: key, Value: apiv3.NewIPPool(), Revision: "2"}, nil
+}
+
+// deleteRecordingBackend is a backend client that records the Delete calls made to it.
+type deleteRecordingBackend struct {
+	bapi.Client
+	deletes []string
+}
+
+func (b *deleteRecordingBackend) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	b.deletes = append(b.deletes, "delete")
+	return &model.KVPair{Key: kvp.Key, Value: apiv3.NewIPPool(), Revision: "1"}, nil
+}
+
+// propagatingDeleteBackend is a backend client that also supports deletion propagation.
+type propagatingDeleteBackend struct {
+	deleteRecordingBackend
+	err error
+}
+
+func (b *propagatingDeleteBackend) DeleteKVPWithPropagation(
+	ctx context.Context, kvp *model.KVPair, policy metav1.DeletionPropagation,
+) (*model.KVPair, error) {
+	b.deletes = append(b.deletes, "delete:"+string(policy))
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &model.KVPair{Key: kvp.Key, Value: apiv3.NewIPPool(), Revision: "2"}, nil
+}
+
+// watchBackend is a backend client that lists a fixed set of resources and returns a watcher
+// that delivers the supplied events.
+type watchBackend struct {
+	bapi.Client
+	kvps      []*model.KVPair
+	events    chan bapi.WatchEvent
+	revisions []string
+}
+
+func (b *watchBackend) List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+	return &model.KVPairList{KVPairs: b.kvps, Revision: "10"}, nil
+}
+
+func (b *watchBackend) Watch(ctx context.Context, list model.ListInterface, revision string) (bapi.WatchInterface, error) {
+	b.revisions = append(b.revisions, revision)
+	return &chanWatcher{events: b.events}, nil
+}
+
+// chanWatcher is a backend watcher that delivers events from a channel.
+type chanWatcher struct {
+	events chan bapi.WatchEvent
+}
+
+func (w *chanWatcher) Stop()                              {}
+func (w *chanWatcher) ResultChan() <-chan bapi.WatchEvent { return w.events }
+func (w *chanWatcher) HasTerminated() bool                { return false }
+
+var _ = Describe("Resources Get", func() {
+	ctx := context.Background()
+
+	It("should pass the resource version through for a standard read", func() {
+		be := &consistentReadBackend{}
+		r := &resources{backend: be}
+		_, err := r.Get(ctx, options.GetOptions{ResourceVersion: "10"}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"get:10"}))
+	})
+
+	It("should perform a consistent read when requested and supported", func() {
+		be := &consistentReadBackend{}
+		r := &resources{backend: be}
+		out, err := r.Get(ctx, options.GetOptions{ResourceVersion: "10", ConsistentRead: true}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.(*apiv3.IPPool).ResourceVersion).To(Equal("2"))
+		Expect(be.gets).To(Equal([]string{"consistent"}))
+	})
+
+	It("should fall back to a standard read when consistent reads are not implemented", func() {
+		be := &getRecordingBackend{}
+		r := &resources{backend: be}
+		_, err := r.Get(ctx, options.GetOptions{ResourceVersion: "10", ConsistentRead: true}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"get:"}))
+	})
+
+	It("should fall back to a standard read when consistent reads are not supported for the key", func() {
+		be := &consistentReadBackend{err: cerrors.ErrorOperationNotSupported{Operation: "Get"}}
+		r := &resources{backend: be}
+		_, err := r.Get(ctx, options.GetOptions{ConsistentRead: true}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.gets).To(Equal([]string{"consistent", "get:"}))
+	})
+
+	It("should return errors from a consistent read", func() {
+		be := &consistentReadBackend{err: cerrors.ErrorResourceDoesNotExist{}}
+		r := &resources{backend: be}
+		_, err := r.Get(ctx, options.GetOptions{ConsistentRead: true}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		Expect(be.gets).To(Equal([]string{"consistent"}))
+	})
+})
+
+var _ = Describe("Resources Delete", func() {
+	ctx := context.Background()
+	foreground := metav1.DeletePropagationForeground
+
+	It("should perform a standard delete when no propagation policy is requested", func() {
+		be := &propagatingDeleteBackend{}
+		r := &resources{backend: be}
+		_, err := r.Delete(ctx, options.DeleteOptions{}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.deletes).To(Equal([]string{"delete"}))
+	})
+
+	It("should pass the propagation policy through when supported", func() {
+		be := &propagatingDeleteBackend{}
+		r := &resources{backend: be}
+		out, err := r.Delete(ctx, options.DeleteOptions{PropagationPolicy: &foreground}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.(*apiv3.IPPool).ResourceVersion).To(Equal("2"))
+		Expect(be.deletes).To(Equal([]string{"delete:Foreground"}))
+	})
+
+	It("should fall back to a standard delete when propagation is not implemented", func() {
+		be := &deleteRecordingBackend{}
+		r := &resources{backend: be}
+		_, err := r.Delete(ctx, options.DeleteOptions{PropagationPolicy: &foreground}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.deletes).To(Equal([]string{"delete"}))
+	})
+
+	It("should fall back to a standard delete when propagation is not supported for the key", func() {
+		be := &propagatingDeleteBackend{err: cerrors.ErrorOperationNotSupported{Operation: "DeleteWithPropagation"}}
+		r := &resources{backend: be}
+		_, err := r.Delete(ctx, options.DeleteOptions{PropagationPolicy: &foreground}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.deletes).To(Equal([]string{"delete:Foreground", "delete"}))
+	})
+
+	It("should return errors from a propagating delete", func() {
+		be := &propagatingDeleteBackend{err: cerrors.ErrorResourceDoesNotExist{}}
+		r := &resources{backend: be}
+		_, err := r.Delete(ctx, options.DeleteOptions{PropagationPolicy: &foreground}, apiv3.KindIPPool, noNamespace, "pool")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		Expect(be.deletes).To(Equal([]string{"delete:Foreground"}))
+	})
+})
+
+var _ = Describe("Resources Watch", func() {
+	ctx := context.Background()
+
+	pool := func(name, revision string) *model.KVPair {
+		p := apiv3.NewIPPool()
+		p.Name = name
+		return &model.KVPair{
+			Key:      model.ResourceKey{Kind: apiv3.KindIPPool, Name: name},
+			Value:    p,
+			Revision: revision,
+		}
+	}
+
+	It("should only deliver delete events after the current state when filtering deletes", func() {
+		be := &watchBackend{
+			kvps:   []*model.KVPair{pool("existing", "5")},
+			events: make(chan bapi.WatchEvent, 10),
+		}
+		r := &resources{backend: be}
+		w, err := r.Watch(ctx, options.ListOptions{EventTypes: []watch.EventType{watch.Deleted}}, apiv3.KindIPPool, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Stop()
+		Expect(be.revisions).To(Equal([]string{"10"}))
+
+		be.events <- bapi.WatchEvent{Type: bapi.WatchAdded, New: pool("added", "11")}
+		be.events <- bapi.WatchEvent{Type: bapi.WatchModified, Old: pool("existing", "5"), New: pool("existing", "12")}
+		be.events <- bapi.WatchEvent{Type: bapi.WatchDeleted, Old: pool("existing", "12")}
+		be.events <- bapi.WatchEvent{Type: bapi.WatchError, Error: cerrors.ErrorDatastoreError{Err: context.Canceled}}
+
+		var events []watch.Event
+		for i := 0; i < 3; i++ {
+			var e watch.Event
+			Eventually(w.ResultChan()).Should(Receive(&e))
+			events = append(events, e)
+		}
+		Expect(events[0].Type).To(Equal(watch.Added))
+		Expect(events[0].Object.(*apiv3.IPPool).Name).To(Equal("existing"))
+		Expect(events[1].Type).To(Equal(watch.Deleted))
+		Expect(events[1].Previous.(*apiv3.IPPool).ResourceVersion).To(Equal("12"))
+		Expect(events[2].Type).To(Equal(watch.Error))
+		Consistently(w.ResultChan()).ShouldNot(Receive())
+	})
+
+	It("should not list the current state when watching from a revision", func() {
+		be := &watchBackend{
+			kvps:   []*model.KVPair{pool("existing", "5")},
+			events: make(chan bapi.WatchEvent, 10),
+		}
+		r := &resources{backend: be}
+		w, err := r.Watch(ctx, options.ListOptions{
+			ResourceVersion: "7",
+			EventTypes:      []watch.EventType{watch.Deleted},
+		}, apiv3.KindIPPool, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Stop()
+		Expect(be.revisions).To(Equal([]string{"7"}))
+
+		be.events <- bapi.WatchEvent{Type: bapi.WatchAdded, New: pool("added", "8")}
+		be.events <- bapi.WatchEvent{Type: bapi.WatchDeleted, Old: pool("added", "9")}
+		var e watch.Event
+		Eventually(w.ResultChan()).Should(Receive(&e))
+		Expect(e.Type).To(Equal(watch.Deleted))
+	})
+
+	It("should deliver all events when not filtering", func() {
+		Expect(options.ListOptions{}.WantsEvent(watch.Modified)).To(BeTrue())
+		Expect(options.ListOptions{EventTypes: []watch.EventType{watch.Deleted}}.WantsEvent(watch.Modified)).To(BeFalse())
+		Expect(options.ListOptions{EventTypes: []watch.EventType{watch.Deleted}}.WantsEvent(watch.Error)).To(BeTrue())
+	})
+})
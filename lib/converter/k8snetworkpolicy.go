@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converter
+
+import (
+	"errors"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
+)
+
+// K8sNetworkPolicyToCalico converts a Kubernetes NetworkPolicy to the equivalent Calico
+// NetworkPolicy.  The conversion is the same as is performed by the Kubernetes datastore
+// driver, so the resulting policy is named with the "knp.default." prefix and has an order of
+// 1000.
+//
+// Rules are generated in the order of the Kubernetes rules.  Each Kubernetes rule is expanded
+// into one Calico rule per protocol (in sorted protocol order) and peer (in the order of the
+// peers), so the same NetworkPolicy always results in the same Calico rules.  A Kubernetes
+// rule that cannot be converted (for example, due to an invalid port) is dropped with a
+// warning, matching the behavior of the datastore driver.
+func K8sNetworkPolicyToCalico(np *networkingv1.NetworkPolicy) (*apiv3.NetworkPolicy, error) {
+	if np == nil {
+		return nil, errors.New("no NetworkPolicy to convert")
+	}
+	kvp, err := conversion.NewConverter().K8sNetworkPolicyToCalico(np)
+	if err != nil {
+		return nil, err
+	}
+	return kvp.Value.(*apiv3.NetworkPolicy), nil
+}
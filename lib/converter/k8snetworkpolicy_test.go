@@ -0,0 +1,190 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	kapiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	. "github.com/projectcalico/libcalico-go/lib/converter"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+var _ = Describe("K8sNetworkPolicyToCalico", func() {
+	k8sSelector := "projectcalico.org/orchestrator == 'k8s'"
+	tcp := numorstring.ProtocolFromString("TCP")
+	udp := numorstring.ProtocolFromString("UDP")
+	protoTCP := kapiv1.ProtocolTCP
+	protoUDP := kapiv1.ProtocolUDP
+	port80 := intstr.FromInt(80)
+	port53 := intstr.FromInt(53)
+	portHTTP := intstr.FromString("http")
+
+	// k8sPolicy returns a Kubernetes NetworkPolicy in the default namespace with the given spec.
+	k8sPolicy := func(spec networkingv1.NetworkPolicySpec) *networkingv1.NetworkPolicy {
+		return &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-policy", Namespace: "default"},
+			Spec:       spec,
+		}
+	}
+
+	// convert converts the policy and checks the metadata of the converted policy.
+	convert := func(np *networkingv1.NetworkPolicy) apiv3.NetworkPolicySpec {
+		p, err := K8sNetworkPolicyToCalico(np)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Name).To(Equal("knp.default.test-policy"))
+		Expect(p.Namespace).To(Equal("default"))
+		Expect(p.Spec.Order).NotTo(BeNil())
+		Expect(*p.Spec.Order).To(Equal(1000.0))
+		return p.Spec
+	}
+
+	It("should convert an empty policy to a default deny for all pods", func() {
+		spec := convert(k8sPolicy(networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		}))
+		Expect(spec.Selector).To(Equal(k8sSelector))
+		Expect(spec.Ingress).To(BeEmpty())
+		Expect(spec.Egress).To(BeEmpty())
+		Expect(spec.Types).To(Equal([]apiv3.PolicyType{apiv3.PolicyTypeIngress}))
+	})
+
+	It("should convert an empty ingress rule to allow all", func() {
+		spec := convert(k8sPolicy(networkingv1.NetworkPolicySpec{
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		}))
+		Expect(spec.Ingress).To(Equal([]apiv3.Rule{{Action: apiv3.Allow}}))
+	})
+
+	It("should convert an empty egress rule to allow all", func() {
+		spec := convert(k8sPolicy(networkingv1.NetworkPolicySpec{
+			Egress:      []networkingv1.NetworkPolicyEgressRule{{}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		}))
+		Expect(spec.Egress).To(Equal([]apiv3.Rule{{Action: apiv3.Allow}}))
+		Expect(spec.Types).To(Equal([]apiv3.PolicyType{apiv3.PolicyTypeEgress}))
+	})
+
+	It("should default the policy types to ingress", func() {
+		spec := convert(k8sPolicy(networkingv1.NetworkPolicySpec{}))
+		Expect(spec.Types).To(Equal([]apiv3.PolicyType{apiv3.PolicyTypeIngress}))
+	})
+
+	It("should convert pod and namespace selectors with numeric and named ports", func() {
+		spec := convert(k8sPolicy(networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "db", "tier": "backend"},
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{
+					{
+						PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"role": "frontend"}},
+						NamespaceSelector: &metav1.LabelSelector{},
+					},
+					{
+						NamespaceSelector: &metav1.LabelSelector{
+							MatchExpressions: []metav1.LabelSelectorRequirement{{
+								Key:      "env",
+								Operator: metav1.LabelSelectorOpIn,
+								Values:   []string{"prod", "staging"},
+							}},
+						},
+					},
+				},
+				Ports: []networkingv1.NetworkPolicyPort{
+					{Port: &port80},
+					{Protocol: &protoTCP, Port: &portHTTP},
+				},
+			}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		}))
+		Expect(spec.Selector).To(Equal(k8sSelector + " && app == 'db' && tier == 'backend'"))
+
+		ports := []numorstring.Port{numorstring.SinglePort(80), numorstring.NamedPort("http")}
+		Expect(spec.Ingress).To(Equal([]apiv3.Rule{
+			{
+				Action:   apiv3.Allow,
+				Protocol: &tcp,
+				Source: apiv3.EntityRule{
+					Selector:          k8sSelector + " && role == 'frontend'",
+					NamespaceSelector: "all()",
+				},
+				Destination: apiv3.EntityRule{Ports: ports},
+			},
+			{
+				Action:   apiv3.Allow,
+				Protocol: &tcp,
+				Source: apiv3.EntityRule{
+					Selector:          k8sSelector,
+					NamespaceSelector: "env in { 'prod', 'staging' }",
+				},
+				Destination: apiv3.EntityRule{Ports: ports},
+			},
+		}))
+	})
+
+	It("should convert egress rules with IP blocks", func() {
+		spec := convert(k8sPolicy(networkingv1.NetworkPolicySpec{
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To: []networkingv1.NetworkPolicyPeer{{
+					IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}},
+				}},
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &protoUDP, Port: &port53}},
+			}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		}))
+		Expect(spec.Ingress).To(BeEmpty())
+		Expect(spec.Egress).To(Equal([]apiv3.Rule{{
+			Action:   apiv3.Allow,
+			Protocol: &udp,
+			Destination: apiv3.EntityRule{
+				Ports:   []numorstring.Port{numorstring.SinglePort(53)},
+				Nets:    []string{"10.0.0.0/8"},
+				NotNets: []string{"10.1.0.0/16"},
+			},
+		}}))
+	})
+
+	It("should order rules deterministically by protocol", func() {
+		np := k8sPolicy(networkingv1.NetworkPolicySpec{
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				Ports: []networkingv1.NetworkPolicyPort{
+					{Protocol: &protoUDP, Port: &port53},
+					{Protocol: &protoTCP, Port: &port53},
+				},
+			}},
+		})
+		spec := convert(np)
+		Expect(spec.Ingress).To(HaveLen(2))
+		Expect(spec.Ingress[0].Protocol).To(Equal(&tcp))
+		Expect(spec.Ingress[1].Protocol).To(Equal(&udp))
+
+		for i := 0; i < 5; i++ {
+			Expect(convert(np)).To(Equal(spec))
+		}
+	})
+
+	It("should return an error for a nil policy", func() {
+		_, err := K8sNetworkPolicyToCalico(nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,152 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converter
+
+import (
+	"fmt"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+// NodeV1ToV3 reconstructs a partial v3 Node from the v1 model KVPairs produced for the Node by
+// the Felix Node update processor.  The BGP addresses, tunnel addresses, AS number, Wireguard
+// configuration and (from any Block updates) PodCIDRs are reassembled.  Fields that are not
+// represented in the v1 model are left unset, and keys that are missing or have a nil value
+// (i.e. a delete) leave the corresponding fields unset.  Keys that do not correspond to a
+// Node field are ignored.
+//
+// The reconstructed Node is not necessarily identical to the original Node, for example the
+// IPv4 address prefix length is not preserved, but converting it with the Felix Node update
+// processor produces the same v1 model.
+//
+// An error is returned if there are no keys identifying the Node, if the keys are for more than
+// one Node, or if a value has an unexpected type.
+func NodeV1ToV3(keys []*model.KVPair) (*apiv3.Node, error) {
+	node := apiv3.NewNode()
+	var wg *model.Wireguard
+	bgp := &apiv3.NodeBGPSpec{}
+
+	setName := func(name string) error {
+		if node.Name != "" && node.Name != name {
+			return fmt.Errorf("keys are for more than one node: %s and %s", node.Name, name)
+		}
+		node.Name = name
+		return nil
+	}
+	for _, kvp := range keys {
+		if kvp == nil {
+			continue
+		}
+		switch k := kvp.Key.(type) {
+		case model.HostIPKey:
+			if err := setName(k.Hostname); err != nil {
+				return nil, err
+			}
+			if kvp.Value == nil {
+				continue
+			}
+			ip, ok := kvp.Value.(*cnet.IP)
+			if !ok {
+				return nil, fmt.Errorf("unexpected value type %T for %s", kvp.Value, k)
+			}
+			if ip != nil && ip.Version() == 6 {
+				bgp.IPv6Address = ip.Network().String()
+			} else if ip != nil {
+				bgp.IPv4Address = ip.Network().String()
+			}
+		case model.HostConfigKey:
+			if err := setName(k.Hostname); err != nil {
+				return nil, err
+			}
+			if kvp.Value == nil {
+				continue
+			}
+			v, ok := kvp.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected value type %T for %s", kvp.Value, k)
+			}
+			switch k.Name {
+			case updateprocessors.DefaultIPIPTunnelAddrKeyName:
+				bgp.IPv4IPIPTunnelAddr = v
+			case updateprocessors.IPv4VXLANTunnelAddrKeyName:
+				node.Spec.IPv4VXLANTunnelAddr = v
+			case updateprocessors.VXLANTunnelMACV4AddrKeyName:
+				node.Spec.VXLANTunnelMACV4Addr = v
+			case updateprocessors.IPv6VXLANTunnelAddrKeyName:
+				node.Spec.IPv6VXLANTunnelAddr = v
+			case updateprocessors.VXLANTunnelMACV6AddrKeyName:
+				node.Spec.VXLANTunnelMACV6Addr = v
+			case updateprocessors.ASNumberKeyName:
+				asNumber, err := numorstring.ASNumberFromString(v)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse %s: %v", k, err)
+				}
+				bgp.ASNumber = &asNumber
+			}
+		case model.WireguardKey:
+			if err := setName(k.NodeName); err != nil {
+				return nil, err
+			}
+			if kvp.Value == nil {
+				continue
+			}
+			if wg, _ = kvp.Value.(*model.Wireguard); wg == nil {
+				return nil, fmt.Errorf("unexpected value type %T for %s", kvp.Value, k)
+			}
+		case model.BlockKey:
+			// Block updates are only produced for the Node PodCIDRs, and have the CIDR of the
+			// PodCIDR.
+			if kvp.Value != nil {
+				node.Status.PodCIDRs = append(node.Status.PodCIDRs, k.CIDR.String())
+			}
+		}
+	}
+	if node.Name == "" {
+		return nil, fmt.Errorf("no keys identifying the node")
+	}
+
+	if wg != nil {
+		if wg.InterfaceIPv4Addr != nil {
+			node.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: wg.InterfaceIPv4Addr.String()}
+		}
+		if wg.PublicKey != nil {
+			node.Status.WireguardPublicKey = wg.PublicKey.String()
+		}
+
+		// The allowed IPs are the PodCIDRs and the tunnel addresses, so if there were no Block
+		// updates the PodCIDRs are the remaining allowed IPs.
+		if len(node.Status.PodCIDRs) == 0 {
+			tunnelAddrs := map[string]bool{}
+			for _, addr := range []string{bgp.IPv4IPIPTunnelAddr, node.Spec.IPv4VXLANTunnelAddr, node.Spec.IPv6VXLANTunnelAddr} {
+				if ip := cnet.ParseIP(addr); ip != nil {
+					tunnelAddrs[ip.Network().String()] = true
+				}
+			}
+			for _, cidr := range wg.AllowedIPs {
+				if !tunnelAddrs[cidr.String()] {
+					node.Status.PodCIDRs = append(node.Status.PodCIDRs, cidr.String())
+				}
+			}
+		}
+	}
+	if bgp.IPv4Address != "" || bgp.IPv6Address != "" || bgp.IPv4IPIPTunnelAddr != "" || bgp.ASNumber != nil {
+		node.Spec.BGP = bgp
+	}
+	return node, nil
+}
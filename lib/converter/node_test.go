@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	. "github.com/projectcalico/libcalico-go/lib/converter"
+	"github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+var _ = Describe("NodeV1ToV3", func() {
+	nodeKey := model.ResourceKey{Kind: apiv3.KindNode, Name: "mynode"}
+
+	// v1Updates returns the v1 model updates produced for the Node, excluding the Node resource
+	// itself.
+	v1Updates := func(usePodCIDR bool, node *apiv3.Node) []*model.KVPair {
+		up := updateprocessors.NewFelixNodeUpdateProcessor(usePodCIDR)
+		kvps, err := up.Process(&model.KVPair{Key: nodeKey, Value: node, Revision: "1"})
+		Expect(err).NotTo(HaveOccurred())
+		var updates []*model.KVPair
+		for _, kvp := range kvps {
+			if _, ok := kvp.Key.(model.ResourceKey); !ok {
+				updates = append(updates, kvp)
+			}
+		}
+		return updates
+	}
+
+	fullNode := func() *apiv3.Node {
+		asNumber := numorstring.ASNumber(64512)
+		node := apiv3.NewNode()
+		node.Name = "mynode"
+		node.Spec.BGP = &apiv3.NodeBGPSpec{
+			ASNumber:           &asNumber,
+			IPv4Address:        "172.0.0.1/24",
+			IPv4IPIPTunnelAddr: "192.168.0.1",
+		}
+		node.Spec.IPv4VXLANTunnelAddr = "192.168.1.1"
+		node.Spec.VXLANTunnelMACV4Addr = "00:11:22:33:44:55"
+		node.Spec.IPv6VXLANTunnelAddr = "fd00::1"
+		node.Spec.VXLANTunnelMACV6Addr = "00:11:22:33:44:66"
+		node.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "192.168.2.1"}
+		node.Status.WireguardPublicKey = "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="
+		node.Status.PodCIDRs = []string{"10.0.0.0/24", "fd10::/120"}
+		return node
+	}
+
+	It("should round-trip a node through the node processor", func() {
+		original := v1Updates(false, fullNode())
+		node, err := NodeV1ToV3(original)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Name).To(Equal("mynode"))
+		Expect(node.Spec.BGP.IPv4Address).To(Equal("172.0.0.1/32"))
+		Expect(node.Spec.BGP.ASNumber.String()).To(Equal("64512"))
+		Expect(node.Spec.Wireguard.InterfaceIPv4Address).To(Equal("192.168.2.1"))
+		Expect(node.Status.PodCIDRs).To(Equal([]string{"10.0.0.0/24", "fd10::/120"}))
+		Expect(v1Updates(false, node)).To(Equal(original))
+	})
+
+	It("should round-trip a node's PodCIDRs from the block updates", func() {
+		original := v1Updates(true, fullNode())
+		node, err := NodeV1ToV3(original)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Status.PodCIDRs).To(Equal([]string{"10.0.0.0/24", "fd10::/120"}))
+		Expect(v1Updates(true, node)).To(Equal(original))
+	})
+
+	It("should round-trip a node with only some fields set", func() {
+		original := apiv3.NewNode()
+		original.Name = "mynode"
+		original.Spec.IPv4VXLANTunnelAddr = "192.168.1.1"
+		updates := v1Updates(false, original)
+		node, err := NodeV1ToV3(updates)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Spec.BGP).To(BeNil())
+		Expect(node.Spec.Wireguard).To(BeNil())
+		Expect(node.Spec.IPv4VXLANTunnelAddr).To(Equal("192.168.1.1"))
+		Expect(v1Updates(false, node)).To(Equal(updates))
+	})
+
+	It("should handle missing keys", func() {
+		ip := net.MustParseIP("172.0.0.1")
+		node, err := NodeV1ToV3([]*model.KVPair{
+			{Key: model.HostIPKey{Hostname: "mynode"}, Value: &ip},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Name).To(Equal("mynode"))
+		Expect(node.Spec.BGP).To(Equal(&apiv3.NodeBGPSpec{IPv4Address: "172.0.0.1/32"}))
+		Expect(node.Spec.Wireguard).To(BeNil())
+		Expect(node.Status.PodCIDRs).To(BeEmpty())
+	})
+
+	It("should return an error if there are no keys for a node", func() {
+		_, err := NodeV1ToV3(nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if the keys are for more than one node", func() {
+		_, err := NodeV1ToV3([]*model.KVPair{
+			{Key: model.HostIPKey{Hostname: "node1"}},
+			{Key: model.HostConfigKey{Hostname: "node2", Name: "IpInIpTunnelAddr"}},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error for an unexpected value type", func() {
+		_, err := NodeV1ToV3([]*model.KVPair{
+			{Key: model.HostConfigKey{Hostname: "mynode", Name: "IpInIpTunnelAddr"}, Value: 1},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
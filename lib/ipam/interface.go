@@ -50,6 +50,12 @@ type Interface interface {
 	// assigned using the provided handle.
 	IPsByHandle(ctx context.Context, handleID string) ([]cnet.IP, error)
 
+	// IPsByAttribute returns a record of each allocated IP address whose attributes map the
+	// given key to the given value, for example all of the addresses allocated to pods in a
+	// namespace (using the AttributeNamespace key).  Note that this reads every allocation
+	// block, so it is expensive on large datastores and should not be called frequently.
+	IPsByAttribute(ctx context.Context, key, value string) ([]AllocationRecord, error)
+
 	// ReleaseByHandle releases all IP addresses that have been assigned
 	// using the provided handle.  Returns an error if no addresses
 	// are assigned with the given handle.
@@ -101,4 +107,28 @@ type Interface interface {
 	// Otherwise, return the CIDR of the IPAM block allocated for this host.
 	// It returns IPv4, IPv6 block CIDR and any error encountered.
 	EnsureBlock(ctx context.Context, args BlockArgs) (*cnet.IPNet, *cnet.IPNet, error)
+
+}
+
+// BlockSizeMigrator is implemented by the ipam.Interface returned by NewIPAMClient.  It is kept
+// separate from ipam.Interface so that existing implementations of that interface are unaffected.
+type BlockSizeMigrator interface {
+	// MigrateBlockSize plans the migration of the allocation blocks in the given pool (specified
+	// by name or CIDR) to the new block size, moving each allocated address and its handle into a
+	// block of the new size.  If opts.DryRun is set, the plan is returned without modifying the
+	// datastore.  Otherwise the plan is executed and, if any step fails, the steps already taken
+	// are rolled back.  The pool must be disabled to execute the migration; once complete the pool
+	// should be recreated with the new block size.
+	MigrateBlockSize(ctx context.Context, pool string, newSize int, opts MigrateBlockSizeOptions) (*BlockSizeMigrationPlan, error)
+
+	// SplitBlocks reduces the block size of the given pool (specified by name or CIDR) without
+	// moving any allocated addresses.  Each empty block is released and, if it had an affinity,
+	// replaced by a block of the new size with the same affinity.  Blocks with allocated addresses
+	// are left in place, and are split by a later call once they have been drained.  If
+	// opts.DryRun is set, the plan is returned without modifying the datastore.  The pool must be
+	// disabled to execute the split, and must stay disabled until every block has been split; it
+	// may be recreated, still disabled, with the new block size in the meantime.  If some blocks
+	// cannot be split, the others are still split, the plan reports each failure and the error is
+	// an ErrorPartialFailure.
+	SplitBlocks(ctx context.Context, pool string, newBlockSize int, opts SplitBlocksOptions) (*BlockSplitPlan, error)
 }
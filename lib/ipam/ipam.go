@@ -62,15 +62,20 @@ var (
 // NewIPAMClient returns a new ipamClient, which implements Interface.
 // Consumers of the Calico API should not create this directly, but should
 // access IPAM through the main client IPAM accessor (e.g. clientv3.IPAM())
-func NewIPAMClient(client bapi.Client, pools PoolAccessorInterface) Interface {
-	return &ipamClient{
+func NewIPAMClient(client bapi.Client, pools PoolAccessorInterface, opts ...Option) Interface {
+	c := &ipamClient{
 		client: client,
 		pools:  pools,
 		blockReaderWriter: blockReaderWriter{
 			client: client,
 			pools:  pools,
 		},
+		selectors: newPoolSelectorCache(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // ipamClient implements Interface
@@ -78,6 +83,18 @@ type ipamClient struct {
 	client            bapi.Client
 	pools             PoolAccessorInterface
 	blockReaderWriter blockReaderWriter
+
+	// Provides the reserved addresses, or nil if there are no reservations.
+	reservations ReservationAccessorInterface
+
+	// Dispatches assignment and release events, or nil if there is no event callback.
+	events *eventDispatcher
+
+	// Tracks the pool utilization thresholds, or nil if there is no threshold callback.
+	thresholds *poolThresholdMonitor
+
+	// Caches the compiled IP pool node selectors, or nil to parse the selectors on each use.
+	selectors *poolSelectorCache
 }
 
 // AutoAssign automatically assigns one or more IP addresses as specified by the
@@ -86,6 +103,11 @@ type ipamClient struct {
 //
 // In case of error, returns the IPs allocated so far along with the error.
 func (c ipamClient) AutoAssign(ctx context.Context, args AutoAssignArgs) ([]net.IPNet, []net.IPNet, error) {
+	// Don't start any datastore operations if the caller has already given up.
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	// Determine the hostname to use - prefer the provided hostname if
 	// non-nil, otherwise use the hostname reported by os.
 	hostname, err := decideHostname(args.Hostname)
@@ -96,6 +118,16 @@ func (c ipamClient) AutoAssign(ctx context.Context, args AutoAssignArgs) ([]net.
 
 	var v4list, v6list []net.IPNet
 
+	// Send events for any addresses that were assigned, including those assigned before an error.
+	defer func() {
+		assigned := make([]net.IP, 0, len(v4list)+len(v6list))
+		for _, ipnet := range append(v4list, v6list...) {
+			assigned = append(assigned, net.IP{IP: ipnet.IP})
+		}
+		c.notify(EventTypeAssigned, assigned, func(net.IP) *string { return args.HandleID })
+		c.checkPoolThresholds(ctx, assigned, true)
+	}()
+
 	if args.Num4 != 0 {
 		// Assign IPv4 addresses.
 		log.Debugf("Assigning IPv4 addresses")
@@ -296,7 +328,7 @@ func (c ipamClient) determinePools(ctx context.Context, requestedPoolNets []net.
 	// selector.
 	for _, pool := range enabledPools {
 		var matches bool
-		matches, err = pool.SelectsNode(node)
+		matches, err = c.selectors.selectsNode(pool, node)
 		if err != nil {
 			log.WithError(err).WithField("pool", pool).Error("failed to determine if node matches pool")
 			return
@@ -351,6 +383,12 @@ func (c ipamClient) prepareAffinityBlocksForHost(
 		return nil, nil, fmt.Errorf("no configured Calico pools for node %v", host)
 	}
 
+	// Prefer pools in the same zone as the node, and then any pools hinted by the node, both
+	// when claiming new blocks and when using the existing affine blocks.  The hint takes
+	// precedence over the zone.
+	pools, zoned := sortPoolsByZone(pools, *v3n)
+	pools, hinted := sortPoolsByAffinityHint(pools, *v3n)
+
 	logCtx := log.WithFields(log.Fields{"host": host})
 
 	logCtx.Info("Looking up existing affinities for host")
@@ -358,6 +396,9 @@ func (c ipamClient) prepareAffinityBlocksForHost(
 	if err != nil {
 		return nil, nil, err
 	}
+	if zoned || hinted {
+		affBlocks = sortBlocksByPools(affBlocks, pools)
+	}
 
 	// Release any emptied blocks still affine to this host but no longer part of an IP Pool which selects this node.
 	for _, block := range affBlocksToRelease {
@@ -373,7 +414,7 @@ func (c ipamClient) prepareAffinityBlocksForHost(
 		}
 
 		// Determine if the pool selects the current node, refusing to release this particular block affinity if so.
-		blockSelectsNode, err := pool.SelectsNode(*v3n)
+		blockSelectsNode, err := c.selectors.selectsNode(*pool, *v3n)
 		if err != nil {
 			logCtx.WithError(err).WithField("pool", pool).Error("Failed to determine if node matches pool, skipping")
 			continue
@@ -571,6 +612,12 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 		return nil, err
 	}
 
+	// Get the reserved addresses, which must not be assigned.
+	reserved, err := c.getReservedCIDRs(version)
+	if err != nil {
+		return nil, err
+	}
+
 	// Merge in any global config, if it exists. We use the more restrictive value between
 	// the global max block limit, and the limit provided on this particular request.
 	if config.MaxBlocksPerHost > 0 && maxNumBlocks > 0 && maxNumBlocks > config.MaxBlocksPerHost {
@@ -604,6 +651,9 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 	// Allocate the IPs.
 	for len(ips) < num {
 		var b *model.KVPair
+		if err := ctx.Err(); err != nil {
+			return ips, err
+		}
 
 		rem := num - len(ips)
 		if maxNumBlocks > 0 && numBlocksOwned >= maxNumBlocks {
@@ -630,7 +680,10 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 
 		// We have got a block b.
 		for i := 0; i < datastoreRetries; i++ {
-			newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, config.StrictAffinity)
+			if err := ctx.Err(); err != nil {
+				return ips, err
+			}
+			newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, config.StrictAffinity, reserved)
 			if err != nil {
 				if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok {
 					log.WithError(err).Debug("CAS Error assigning from new block - retry")
@@ -682,6 +735,12 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 			logCtx.Debugf("Assigning from non-affine blocks in pool %s", p.Spec.CIDR)
 			newBlockCIDR := randomBlockGenerator(p, host)
 			for rem > 0 {
+				// Stop hunting as soon as the context is done, otherwise each remaining
+				// block in the pool would be tried (and fail) in turn.
+				if err := ctx.Err(); err != nil {
+					return ips, err
+				}
+
 				// Grab a new random block.
 				blockCIDR := newBlockCIDR()
 				if blockCIDR == nil {
@@ -698,7 +757,7 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 
 					// Attempt to assign from the block.
 					logCtx.Infof("Attempting to assign IPs from non-affine block %s", blockCIDR.String())
-					newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, false)
+					newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, false, reserved)
 					if err != nil {
 						if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok {
 							logCtx.WithError(err).Debug("CAS error assigning from non-affine block - retry")
@@ -820,6 +879,8 @@ func (c ipamClient) AssignIP(ctx context.Context, args AssignIPArgs) error {
 			}
 			return err
 		}
+		c.notify(EventTypeAssigned, []net.IP{args.IP}, func(net.IP) *string { return args.HandleID })
+		c.checkPoolThresholds(ctx, []net.IP{args.IP}, true)
 		return nil
 	}
 	return errors.New("Max retries hit - excessive concurrent IPAM requests")
@@ -968,8 +1029,17 @@ func (c ipamClient) releaseIPsFromBlock(ctx context.Context, handleMap map[strin
 			}
 		}
 
-		// Release the IPs.
+		// Release the IPs, noting the handle of each address first so that it can be included
+		// in the release events.
 		b := allocationBlock{obj.Value.(*model.AllocationBlock)}
+		handleByIP := map[string]*string{}
+		if c.events != nil {
+			for _, ip := range ips {
+				if handleID, err := b.handleForIP(ip); err == nil {
+					handleByIP[ip.String()] = handleID
+				}
+			}
+		}
 		unallocated, handles, err2 := b.release(ips)
 		if err2 != nil {
 			return nil, err2
@@ -1006,7 +1076,10 @@ func (c ipamClient) releaseIPsFromBlock(ctx context.Context, handleMap map[strin
 			}
 		}
 
-		// Success - decrement handles.
+		// Success - send events for the released addresses and decrement handles.
+		released := releasedIPs(ips, unallocated)
+		c.notify(EventTypeReleased, released, func(ip net.IP) *string { return handleByIP[ip.String()] })
+		c.checkPoolThresholds(ctx, released, false)
 		logCtx.Debugf("Decrementing handles: %v", handles)
 		for handleID, amount := range handles {
 			if err := c.decrementHandle(ctx, handleID, blockCIDR, amount, handleMap[handleID]); err != nil {
@@ -1023,7 +1096,23 @@ func (c ipamClient) releaseIPsFromBlock(ctx context.Context, handleMap map[strin
 	return nil, errors.New("Max retries hit - excessive concurrent IPAM requests")
 }
 
-func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KVPair, num int, handleID *string, attrs map[string]string, host string, affCheck bool) ([]net.IPNet, error) {
+// releasedIPs returns the unique addresses in ips that are not in unallocated.
+func releasedIPs(ips, unallocated []net.IP) []net.IP {
+	skip := map[string]bool{}
+	for _, ip := range unallocated {
+		skip[ip.String()] = true
+	}
+	released := []net.IP{}
+	for _, ip := range ips {
+		if !skip[ip.String()] {
+			skip[ip.String()] = true
+			released = append(released, ip)
+		}
+	}
+	return released
+}
+
+func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KVPair, num int, handleID *string, attrs map[string]string, host string, affCheck bool, reserved reservedCIDRs) ([]net.IPNet, error) {
 	blockCIDR := block.Key.(model.BlockKey).CIDR
 	logCtx := log.WithFields(log.Fields{"host": host, "block": blockCIDR})
 	if handleID != nil {
@@ -1034,7 +1123,7 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 	// Pull out the block.
 	b := allocationBlock{block.Value.(*model.AllocationBlock)}
 
-	ips, err := b.autoAssign(num, handleID, host, attrs, affCheck)
+	ips, err := b.autoAssign(num, handleID, host, attrs, affCheck, reserved)
 	if err != nil {
 		logCtx.WithError(err).Errorf("Error in auto assign")
 		return nil, err
@@ -1044,10 +1133,11 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 		return []net.IPNet{}, nil
 	}
 
-	// Increment handle count.
+	// Increment handle count.  Fewer addresses than requested may have been assigned if the
+	// block is nearly full or contains reserved addresses.
 	if handleID != nil {
 		logCtx.Debug("Incrementing handle")
-		c.incrementHandle(ctx, *handleID, blockCIDR, num)
+		c.incrementHandle(ctx, *handleID, blockCIDR, len(ips))
 	}
 
 	// Update the block using CAS by passing back the original
@@ -1059,7 +1149,7 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 		logCtx.WithError(err).Infof("Failed to update block")
 		if handleID != nil {
 			logCtx.Debug("Decrementing handle since we failed to allocate IP(s)")
-			if err := c.decrementHandle(ctx, *handleID, blockCIDR, num, nil); err != nil {
+			if err := c.decrementHandle(ctx, *handleID, blockCIDR, len(ips), nil); err != nil {
 				logCtx.WithError(err).Warnf("Failed to decrement handle")
 			}
 		}
@@ -1076,6 +1166,9 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 // If an empty string is passed as the host, then the hostname is automatically detected.
 func (c ipamClient) ClaimAffinity(ctx context.Context, cidr net.IPNet, host string) ([]net.IPNet, []net.IPNet, error) {
 	logCtx := log.WithFields(log.Fields{"host": host, "cidr": cidr})
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 
 	// Verify the requested CIDR falls within a configured pool.
 	pool, err := c.blockReaderWriter.getPoolForIP(net.IP{IP: cidr.IP}, nil)
@@ -1113,6 +1206,10 @@ func (c ipamClient) ClaimAffinity(ctx context.Context, cidr net.IPNet, host stri
 	blocks := blockGenerator(pool, cidr)
 	for blockCIDR := blocks(); blockCIDR != nil; blockCIDR = blocks() {
 		for i := 0; i < datastoreRetries; i++ {
+			if err := ctx.Err(); err != nil {
+				return claimed, failed, err
+			}
+
 			// First, claim a pending affinity.
 			pa, err := c.blockReaderWriter.getPendingAffinity(ctx, hostname, *blockCIDR)
 			if err != nil {
@@ -1403,9 +1500,28 @@ func (c ipamClient) IPsByHandle(ctx context.Context, handleID string) ([]net.IP,
 	return assignments, nil
 }
 
+// IPsByAttribute returns a record of each allocated IP address whose attributes map the key to
+// the value.  The attributes are stored in the allocation blocks rather than the handles, so
+// this reads every allocation block from the datastore.
+func (c ipamClient) IPsByAttribute(ctx context.Context, key, value string) ([]AllocationRecord, error) {
+	blocks, err := c.client.List(ctx, model.BlockListOptions{}, "")
+	if err != nil {
+		return nil, err
+	}
+	records := []AllocationRecord{}
+	for _, kvp := range blocks.KVPairs {
+		b := allocationBlock{kvp.Value.(*model.AllocationBlock)}
+		records = append(records, b.allocationsByAttribute(key, value)...)
+	}
+	return records, nil
+}
+
 // ReleaseByHandle releases all IP addresses that have been assigned
 // using the provided handle.
 func (c ipamClient) ReleaseByHandle(ctx context.Context, handleID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	handleID = sanitizeHandle(handleID)
 	log.Infof("Releasing all IPs with handle '%s'", handleID)
 	obj, err := c.blockReaderWriter.queryHandle(ctx, handleID, "")
@@ -1426,6 +1542,9 @@ func (c ipamClient) ReleaseByHandle(ctx context.Context, handleID string) error
 func (c ipamClient) releaseByHandle(ctx context.Context, handleID string, blockCIDR net.IPNet) error {
 	logCtx := log.WithFields(log.Fields{"handle": handleID, "cidr": blockCIDR})
 	for i := 0; i < datastoreRetries; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		logCtx.Debug("Querying block so we can release IPs by handle")
 		obj, err := c.blockReaderWriter.queryBlock(ctx, blockCIDR, "")
 		if err != nil {
@@ -1439,8 +1558,13 @@ func (c ipamClient) releaseByHandle(ctx context.Context, handleID string, blockC
 			}
 		}
 
-		// Release the IP by handle.
+		// Release the IP by handle, noting the addresses first so that they can be included in
+		// the release events and the pool utilization checks.
 		block := allocationBlock{obj.Value.(*model.AllocationBlock)}
+		var ips []net.IP
+		if c.events != nil || c.thresholds != nil {
+			ips = block.ipsByHandle(handleID)
+		}
 		num := block.releaseByHandle(handleID)
 		if num == 0 {
 			// Block has no addresses with this handle, so
@@ -1485,6 +1609,8 @@ func (c ipamClient) releaseByHandle(ctx context.Context, handleID string, blockC
 			}
 			logCtx.Debug("Successfully released IPs from block")
 		}
+		c.notify(EventTypeReleased, ips, func(net.IP) *string { return &handleID })
+		c.checkPoolThresholds(ctx, ips, false)
 		if err = c.decrementHandle(ctx, handleID, blockCIDR, num, nil); err != nil {
 			logCtx.WithError(err).Warn("Failed to decrement handle")
 		}
@@ -1779,7 +1905,7 @@ func (c ipamClient) ensureConsistentAffinity(ctx context.Context, b *model.Alloc
 	if pool == nil {
 		logCtx.Debug("No pools own this block")
 		return nil
-	} else if sel, err := pool.SelectsNode(*v3n); err != nil {
+	} else if sel, err := c.selectors.selectsNode(*pool, *v3n); err != nil {
 		logCtx.WithField("selector", pool.Spec.NodeSelector).WithError(err).Error("Failed to determine node selection")
 		return err
 	} else if sel {
@@ -84,7 +84,7 @@ func newBlock(cidr cnet.IPNet, rsvdAttr *HostReservedAttr) allocationBlock {
 }
 
 func (b *allocationBlock) autoAssign(
-	num int, handleID *string, host string, attrs map[string]string, affinityCheck bool) ([]cnet.IPNet, error) {
+	num int, handleID *string, host string, attrs map[string]string, affinityCheck bool, reserved reservedCIDRs) ([]cnet.IPNet, error) {
 
 	// Determine if we need to check for affinity.
 	if affinityCheck && b.Affinity != nil && !hostAffinityMatches(host, b.AllocationBlock) {
@@ -99,11 +99,24 @@ func (b *allocationBlock) autoAssign(
 		}
 	}
 
-	// Walk the allocations until we find enough addresses.
+	// Walk the allocations until we find enough addresses.  Reserved addresses are skipped but
+	// remain unallocated.
 	ordinals := []int{}
-	for len(b.Unallocated) > 0 && len(ordinals) < num {
-		ordinals = append(ordinals, b.Unallocated[0])
-		b.Unallocated = b.Unallocated[1:]
+	if reserved = reserved.forBlock(b.CIDR); len(reserved) == 0 {
+		for len(b.Unallocated) > 0 && len(ordinals) < num {
+			ordinals = append(ordinals, b.Unallocated[0])
+			b.Unallocated = b.Unallocated[1:]
+		}
+	} else {
+		unallocated := make([]int, 0, len(b.Unallocated))
+		for _, o := range b.Unallocated {
+			if len(ordinals) < num && !reserved.contains(b.OrdinalToIP(o)) {
+				ordinals = append(ordinals, o)
+				continue
+			}
+			unallocated = append(unallocated, o)
+		}
+		b.Unallocated = unallocated
 	}
 
 	// Create slice of IPs and perform the allocations.
@@ -395,6 +408,32 @@ func (b allocationBlock) ipsByHandle(handleID string) []cnet.IP {
 	return ips
 }
 
+// allocationsByAttribute returns a record of each allocation in the block whose attributes map
+// the key to the value.
+func (b allocationBlock) allocationsByAttribute(key, value string) []AllocationRecord {
+	var records []AllocationRecord
+	for o, attrIndex := range b.Allocations {
+		if attrIndex == nil {
+			continue
+		}
+		attr := b.Attributes[*attrIndex]
+		if v, ok := attr.AttrSecondary[key]; !ok || v != value {
+			continue
+		}
+		attrs := make(map[string]string, len(attr.AttrSecondary))
+		for k, v := range attr.AttrSecondary {
+			attrs[k] = v
+		}
+		records = append(records, AllocationRecord{
+			IP:       b.OrdinalToIP(o),
+			Block:    b.CIDR,
+			HandleID: attr.AttrPrimary,
+			Attrs:    attrs,
+		})
+	}
+	return records
+}
+
 func (b allocationBlock) attributesForIP(ip cnet.IP) (map[string]string, error) {
 	// Convert to an ordinal.
 	ordinal, err := b.IPToOrdinal(ip)
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("IPAM context handling", func() {
+	var (
+		fc *fakeClient
+		ic *ipamClient
+	)
+
+	BeforeEach(func() {
+		// The fake client panics on any call that has no handler, so a test that
+		// registers no handlers also asserts that no datastore operation was started.
+		fc = newFakeClient()
+		pls := &ipPoolAccessor{pools: map[string]pool{"10.0.0.0/16": {enabled: true}}}
+		ic = &ipamClient{
+			client:            fc,
+			pools:             pls,
+			blockReaderWriter: blockReaderWriter{client: fc, pools: pls},
+		}
+	})
+
+	Context("with a cancelled context", func() {
+		var ctx context.Context
+
+		BeforeEach(func() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+		})
+
+		It("should fail AutoAssign with the context error", func() {
+			v4, v6, err := ic.AutoAssign(ctx, AutoAssignArgs{Num4: 1, Hostname: "host-a"})
+			Expect(err).To(Equal(context.Canceled))
+			Expect(v4).To(BeEmpty())
+			Expect(v6).To(BeEmpty())
+		})
+
+		It("should fail ClaimAffinity with the context error", func() {
+			claimed, failed, err := ic.ClaimAffinity(ctx, cnet.MustParseCIDR("10.0.0.0/24"), "host-a")
+			Expect(err).To(Equal(context.Canceled))
+			Expect(claimed).To(BeEmpty())
+			Expect(failed).To(BeEmpty())
+		})
+
+		It("should fail ReleaseByHandle with the context error", func() {
+			Expect(ic.ReleaseByHandle(ctx, "handle-a")).To(Equal(context.Canceled))
+		})
+	})
+
+	Context("with a context that expires during an operation", func() {
+		It("should stop retrying ReleaseByHandle once the context is done", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			handle := "handle-a"
+			host := "host:host-a"
+			blockCIDR := cnet.MustParseCIDR("10.0.0.0/26")
+
+			fc.getFuncs[model.IPAMHandleKey{HandleID: handle}.String()] = func(ctx context.Context, k model.Key, r string) (*model.KVPair, error) {
+				return &model.KVPair{
+					Key:      k,
+					Value:    &model.IPAMHandle{HandleID: handle, Block: map[string]int{blockCIDR.String(): 1}},
+					Revision: "1",
+				}, nil
+			}
+			fc.getFuncs[model.BlockKey{CIDR: blockCIDR}.String()] = func(ctx context.Context, k model.Key, r string) (*model.KVPair, error) {
+				b := newBlock(blockCIDR, nil)
+				b.Affinity = &host
+				_, err := b.autoAssign(1, &handle, "host-a", nil, false, nil)
+				Expect(err).NotTo(HaveOccurred())
+				return &model.KVPair{Key: k, Value: b.AllocationBlock, Revision: "1"}, nil
+			}
+
+			// Every block update conflicts, which would normally be retried. The deadline
+			// expires during the first attempt, so there should be no second one.
+			updates := 0
+			fc.updateFuncs["default"] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+				updates++
+				cancel()
+				return nil, cerrors.ErrorResourceUpdateConflict{Identifier: kvp.Key}
+			}
+
+			Expect(ic.ReleaseByHandle(ctx, handle)).To(Equal(context.Canceled))
+			Expect(updates).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// The number of events that may be queued for the event callback.  Events are dropped if the
+// callback falls this far behind.
+const eventQueueLen = 1000
+
+// EventType is the type of an IPAM Event.
+type EventType string
+
+const (
+	EventTypeAssigned EventType = "Assigned"
+	EventTypeReleased EventType = "Released"
+)
+
+// Event describes the assignment or release of a single IP address.
+type Event struct {
+	Type EventType
+
+	// The address that was assigned or released.
+	IP net.IP
+
+	// The handle the address was assigned with, or nil if it was assigned without a handle.
+	HandleID *string
+
+	// The CIDR of the IP pool containing the address, or nil if the address is not within an
+	// enabled pool.
+	Pool *net.IPNet
+}
+
+// EventCallback is invoked with an Event for each address that is successfully assigned or
+// released by the IPAM client.
+type EventCallback func(Event)
+
+// Option is an optional setting for the IPAM client.
+type Option func(*ipamClient)
+
+// WithEventCallback configures the IPAM client to invoke the supplied callback for each address
+// that is assigned by AutoAssign or AssignIP, or released by ReleaseIPs or ReleaseByHandle.
+//
+// The callback is invoked from a separate goroutine, in the order the events occurred, so that a
+// slow callback does not block IPAM operations.  If the callback falls too far behind, events are
+// dropped.
+func WithEventCallback(cb EventCallback) Option {
+	return func(c *ipamClient) {
+		if cb != nil {
+			c.events = newEventDispatcher(cb)
+		}
+	}
+}
+
+// eventDispatcher queues events and passes them to the event callback.
+type eventDispatcher struct {
+	events chan Event
+}
+
+func newEventDispatcher(cb EventCallback) *eventDispatcher {
+	d := &eventDispatcher{events: make(chan Event, eventQueueLen)}
+	go func() {
+		for e := range d.events {
+			cb(e)
+		}
+	}()
+	return d
+}
+
+// send queues an event without blocking.  It is safe to call on a nil dispatcher.
+func (d *eventDispatcher) send(e Event) {
+	if d == nil {
+		return
+	}
+	select {
+	case d.events <- e:
+	default:
+		log.WithFields(log.Fields{"type": e.Type, "ip": e.IP}).Warn("IPAM event queue full, dropping event")
+	}
+}
+
+// notify sends an event of the given type for each of the given addresses, if an event callback
+// is configured.  The handleForIP function returns the handle for each address.
+func (c ipamClient) notify(t EventType, ips []net.IP, handleForIP func(net.IP) *string) {
+	if c.events == nil {
+		return
+	}
+
+	// Cache the enabled pools for each IP version so that they are only queried once.
+	pools := map[int][]v3.IPPool{}
+	for _, ip := range ips {
+		version := ip.Version()
+		if _, ok := pools[version]; !ok {
+			p, err := c.pools.GetEnabledPools(version)
+			if err != nil {
+				log.WithError(err).Warn("Failed to get enabled pools for IPAM event")
+				p = []v3.IPPool{}
+			}
+			pools[version] = p
+		}
+
+		e := Event{Type: t, IP: ip, HandleID: handleForIP(ip)}
+		if pool, err := c.blockReaderWriter.getPoolForIP(ip, pools[version]); err == nil && pool != nil {
+			_, e.Pool, _ = net.ParseCIDR(pool.Spec.CIDR)
+		}
+		c.events.send(e)
+	}
+}
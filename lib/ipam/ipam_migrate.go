@@ -0,0 +1,384 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ BlockSizeMigrator = (*ipamClient)(nil)
+
+// blockSizeMigration contains a migration plan along with the datastore state required to
+// execute it.
+type blockSizeMigration struct {
+	plan BlockSizeMigrationPlan
+
+	// The existing blocks to be removed.
+	oldBlocks []*model.KVPair
+
+	// The new blocks to be created.
+	newBlocks []*model.AllocationBlock
+
+	// The allocation counts for each handle, indexed by handle ID then new block CIDR.
+	handleCounts map[string]map[string]int
+}
+
+// undoFunc reverts a single step of a block size migration.
+type undoFunc func(ctx context.Context) error
+
+// MigrateBlockSize plans the migration of the allocation blocks in the given pool (specified
+// by name or CIDR) to the new block size, and executes it unless this is a dry run.
+func (c ipamClient) MigrateBlockSize(ctx context.Context, pool string, newSize int, opts MigrateBlockSizeOptions) (*BlockSizeMigrationPlan, error) {
+	logCtx := log.WithFields(log.Fields{"pool": pool, "newSize": newSize})
+
+	p, err := c.getPoolByNameOrCIDR(pool)
+	if err != nil {
+		return nil, err
+	}
+	_, poolCIDR, err := net.ParseCIDR(p.Spec.CIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	poolBlocks, err := c.listPoolBlocks(ctx, *poolCIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := planBlockSizeMigration(*p, newSize, poolBlocks)
+	if err != nil {
+		return nil, err
+	}
+	logCtx.Infof("Planned block size migration of %d blocks into %d blocks, moving %d addresses",
+		len(m.plan.OldBlocks), len(m.plan.NewBlocks), len(m.plan.Moves))
+	if opts.DryRun {
+		return &m.plan, nil
+	}
+
+	if !p.Spec.Disabled {
+		return nil, fmt.Errorf("pool %s must be disabled before migrating its block size", p.Name)
+	}
+	if err := c.executeBlockSizeMigration(ctx, m); err != nil {
+		return nil, err
+	}
+	logCtx.Info("Completed block size migration")
+	return &m.plan, nil
+}
+
+// getPoolByNameOrCIDR returns the pool with the given name or CIDR.
+func (c ipamClient) getPoolByNameOrCIDR(pool string) (*v3.IPPool, error) {
+	allPools, err := c.pools.GetAllPools()
+	if err != nil {
+		return nil, err
+	}
+	for i := range allPools {
+		if allPools[i].Name == pool || allPools[i].Spec.CIDR == pool {
+			return &allPools[i], nil
+		}
+	}
+	return nil, cerrors.ErrorResourceDoesNotExist{
+		Identifier: pool,
+		Err:        fmt.Errorf("IP pool %s does not exist", pool),
+	}
+}
+
+// listPoolBlocks returns the allocation blocks within the given pool CIDR.
+func (c ipamClient) listPoolBlocks(ctx context.Context, poolCIDR net.IPNet) ([]*model.KVPair, error) {
+	blocks, err := c.client.List(ctx, model.BlockListOptions{IPVersion: poolCIDR.Version()}, "")
+	if err != nil {
+		return nil, err
+	}
+	var poolBlocks []*model.KVPair
+	for _, kvp := range blocks.KVPairs {
+		if k := kvp.Key.(model.BlockKey); poolCIDR.IsNetOverlap(k.CIDR.IPNet) {
+			poolBlocks = append(poolBlocks, kvp)
+		}
+	}
+	return poolBlocks, nil
+}
+
+// planBlockSizeMigration calculates how the allocations in the supplied blocks move when the
+// pool's block size is changed to newSize.  The supplied blocks must all be within the pool.
+func planBlockSizeMigration(pool v3.IPPool, newSize int, blocks []*model.KVPair) (*blockSizeMigration, error) {
+	_, poolCIDR, err := net.ParseCIDR(pool.Spec.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNewBlockSize(pool, *poolCIDR, newSize); err != nil {
+		return nil, err
+	}
+
+	// Process the blocks in address order so that the plan is deterministic.
+	sorted := sortBlocksByAddress(blocks)
+
+	newPool := pool
+	newPool.Spec.BlockSize = newSize
+
+	m := &blockSizeMigration{
+		plan: BlockSizeMigrationPlan{
+			Pool:         pool.Name,
+			CIDR:         *poolCIDR,
+			OldBlockSize: pool.Spec.BlockSize,
+			NewBlockSize: newSize,
+		},
+		handleCounts: map[string]map[string]int{},
+	}
+	newBlocks := map[string]allocationBlock{}
+	affinities := map[string]map[string]bool{}
+	for _, kvp := range sorted {
+		old := kvp.Value.(*model.AllocationBlock)
+		m.oldBlocks = append(m.oldBlocks, kvp)
+		m.plan.OldBlocks = append(m.plan.OldBlocks, old.CIDR)
+
+		for ord, attrIdx := range old.Allocations {
+			if attrIdx == nil {
+				continue
+			}
+			if *attrIdx >= len(old.Attributes) {
+				return nil, fmt.Errorf("block %s is missing attributes for ordinal %d", old.CIDR, ord)
+			}
+			attr := old.Attributes[*attrIdx]
+			ip := old.OrdinalToIP(ord)
+			cidr := getBlockCIDRForAddress(ip, &newPool)
+
+			nb, ok := newBlocks[cidr.String()]
+			if !ok {
+				nb = newBlock(cidr, nil)
+				newBlocks[cidr.String()] = nb
+				affinities[cidr.String()] = map[string]bool{}
+				m.newBlocks = append(m.newBlocks, nb.AllocationBlock)
+			}
+			affinity := ""
+			if old.Affinity != nil {
+				affinity = *old.Affinity
+			}
+			affinities[cidr.String()][affinity] = true
+
+			newOrd, err := nb.IPToOrdinal(ip)
+			if err != nil {
+				return nil, err
+			}
+			newIdx := nb.findOrAddAttribute(attr.AttrPrimary, attr.AttrSecondary)
+			nb.Allocations[newOrd] = &newIdx
+
+			if attr.AttrPrimary != nil {
+				handleID := *attr.AttrPrimary
+				if m.handleCounts[handleID] == nil {
+					m.handleCounts[handleID] = map[string]int{}
+				}
+				m.handleCounts[handleID][cidr.String()]++
+			}
+
+			m.plan.Moves = append(m.plan.Moves, PlannedAddressMove{
+				IP:       ip,
+				HandleID: attr.AttrPrimary,
+				OldBlock: old.CIDR,
+				NewBlock: cidr,
+			})
+		}
+	}
+
+	// Fill in the unallocated ordinals and affinity of each new block now that all of the
+	// allocations are known.
+	for _, nb := range m.newBlocks {
+		unallocated := []int{}
+		allocated := 0
+		for ord, attrIdx := range nb.Allocations {
+			if attrIdx == nil {
+				unallocated = append(unallocated, ord)
+			} else {
+				allocated++
+			}
+		}
+		nb.Unallocated = unallocated
+
+		// Only keep the affinity if every address in the block came from blocks with the
+		// same affinity.
+		if affs := affinities[nb.CIDR.String()]; len(affs) == 1 {
+			for aff := range affs {
+				if aff != "" {
+					a := aff
+					nb.Affinity = &a
+				}
+			}
+		} else {
+			log.WithField("block", nb.CIDR).Warn("Addresses in new block come from blocks with different affinities")
+		}
+
+		m.plan.NewBlocks = append(m.plan.NewBlocks, PlannedBlock{
+			CIDR:      nb.CIDR,
+			Affinity:  nb.Affinity,
+			Allocated: allocated,
+		})
+	}
+	return m, nil
+}
+
+// validateNewBlockSize checks that newSize is a valid block size for the given pool, and that
+// it differs from the pool's current block size.
+func validateNewBlockSize(pool v3.IPPool, poolCIDR net.IPNet, newSize int) error {
+	if err := validateBlockSize(pool, poolCIDR, newSize); err != nil {
+		return err
+	}
+	if newSize == pool.Spec.BlockSize {
+		return invalidSizeError(fmt.Sprintf("pool %s already has block size %d", pool.Spec.CIDR, newSize))
+	}
+	return nil
+}
+
+// validateBlockSize checks that newSize is a valid block size for the given pool.
+func validateBlockSize(pool v3.IPPool, poolCIDR net.IPNet, newSize int) error {
+	poolOnes, _ := poolCIDR.Mask.Size()
+	switch {
+	case poolCIDR.Version() == 4 && (newSize < 20 || newSize > 32):
+		return invalidSizeError(fmt.Sprintf("IPv4 block size must be between 20 and 32, not %d", newSize))
+	case poolCIDR.Version() == 6 && (newSize < 116 || newSize > 128):
+		return invalidSizeError(fmt.Sprintf("IPv6 block size must be between 116 and 128, not %d", newSize))
+	case newSize < poolOnes:
+		return invalidSizeError(fmt.Sprintf("block size %d is larger than the pool %s", newSize, pool.Spec.CIDR))
+	}
+	return nil
+}
+
+// sortBlocksByAddress returns a copy of the given blocks, sorted by the first address of
+// each block.
+func sortBlocksByAddress(blocks []*model.KVPair) []*model.KVPair {
+	sorted := make([]*model.KVPair, len(blocks))
+	copy(sorted, blocks)
+	sort.Slice(sorted, func(i, j int) bool {
+		a := net.IPToBigInt(net.IP{IP: sorted[i].Key.(model.BlockKey).CIDR.IP})
+		b := net.IPToBigInt(net.IP{IP: sorted[j].Key.(model.BlockKey).CIDR.IP})
+		return a.Cmp(b) < 0
+	})
+	return sorted
+}
+
+// executeBlockSizeMigration performs the migration, rolling back the steps that have been
+// completed if any step fails.
+func (c ipamClient) executeBlockSizeMigration(ctx context.Context, m *blockSizeMigration) error {
+	var undos []undoFunc
+	rollback := func(err error) error {
+		log.WithError(err).Warnf("Block size migration failed, rolling back %d steps", len(undos))
+		for i := len(undos) - 1; i >= 0; i-- {
+			if uerr := undos[i](ctx); uerr != nil {
+				log.WithError(uerr).Error("Failed to roll back block size migration step")
+			}
+		}
+		return err
+	}
+
+	// Remove the old blocks and their affinities.
+	for _, kvp := range m.oldBlocks {
+		old := kvp.Value.(*model.AllocationBlock)
+		if host := getHostAffinity(old); host != "" {
+			aff, err := c.blockReaderWriter.queryAffinity(ctx, host, old.CIDR, "")
+			if err == nil {
+				if err := c.blockReaderWriter.deleteAffinity(ctx, aff); err != nil {
+					return rollback(err)
+				}
+				undos = append(undos, c.recreateFunc(aff))
+			} else if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok {
+				return rollback(err)
+			}
+		}
+		if err := c.blockReaderWriter.deleteBlock(ctx, kvp); err != nil {
+			return rollback(err)
+		}
+		undos = append(undos, c.recreateFunc(kvp))
+	}
+
+	// Create the new blocks and their affinities.
+	for _, nb := range m.newBlocks {
+		created, err := c.client.Create(ctx, &model.KVPair{
+			Key:   model.BlockKey{CIDR: nb.CIDR},
+			Value: nb,
+		})
+		if err != nil {
+			return rollback(err)
+		}
+		undos = append(undos, c.deleteFunc(created))
+
+		if host := getHostAffinity(nb); host != "" {
+			created, err := c.client.Create(ctx, &model.KVPair{
+				Key:   model.BlockAffinityKey{CIDR: nb.CIDR, Host: host},
+				Value: &model.BlockAffinity{State: model.StateConfirmed},
+			})
+			if err != nil {
+				return rollback(err)
+			}
+			undos = append(undos, c.deleteFunc(created))
+		}
+	}
+
+	// Update the handles to reference the new blocks.
+	oldCIDRs := map[string]bool{}
+	for _, cidr := range m.plan.OldBlocks {
+		oldCIDRs[cidr.String()] = true
+	}
+	for handleID, counts := range m.handleCounts {
+		kvp, err := c.blockReaderWriter.queryHandle(ctx, handleID, "")
+		if err != nil {
+			return rollback(err)
+		}
+		handle := kvp.Value.(*model.IPAMHandle)
+		original := map[string]int{}
+		updated := map[string]int{}
+		for cidr, num := range handle.Block {
+			original[cidr] = num
+			if !oldCIDRs[cidr] {
+				updated[cidr] = num
+			}
+		}
+		for cidr, num := range counts {
+			updated[cidr] += num
+		}
+		handle.Block = updated
+		kvp, err = c.blockReaderWriter.updateHandle(ctx, kvp)
+		if err != nil {
+			return rollback(err)
+		}
+		undos = append(undos, func(ctx context.Context) error {
+			kvp.Value.(*model.IPAMHandle).Block = original
+			_, err := c.blockReaderWriter.updateHandle(ctx, kvp)
+			return err
+		})
+	}
+	return nil
+}
+
+// recreateFunc returns an undoFunc that recreates the given (deleted) KVPair.
+func (c ipamClient) recreateFunc(kvp *model.KVPair) undoFunc {
+	return func(ctx context.Context) error {
+		_, err := c.client.Create(ctx, &model.KVPair{Key: kvp.Key, Value: kvp.Value})
+		return err
+	}
+}
+
+// deleteFunc returns an undoFunc that deletes the given (created) KVPair.
+func (c ipamClient) deleteFunc(kvp *model.KVPair) undoFunc {
+	return func(ctx context.Context) error {
+		_, err := c.client.DeleteKVP(ctx, kvp)
+		return err
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// newStoreClient returns a fakeClient backed by the given in-memory store of KVPairs, keyed by
+// the string form of each key.  Individual keys can be made to fail by adding entries to the
+// per-key function maps, which take precedence over the store.
+func newStoreClient(store map[string]*model.KVPair) *fakeClient {
+	c := newFakeClient()
+	c.createFuncs["default"] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+		if _, ok := store[kvp.Key.String()]; ok {
+			return nil, cerrors.ErrorResourceAlreadyExists{Identifier: kvp.Key}
+		}
+		store[kvp.Key.String()] = copyStoreKVP(kvp)
+		return copyStoreKVP(kvp), nil
+	}
+	c.updateFuncs["default"] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+		if _, ok := store[kvp.Key.String()]; !ok {
+			return nil, cerrors.ErrorResourceDoesNotExist{Identifier: kvp.Key}
+		}
+		store[kvp.Key.String()] = copyStoreKVP(kvp)
+		return copyStoreKVP(kvp), nil
+	}
+	c.getFuncs["default"] = func(ctx context.Context, key model.Key, revision string) (*model.KVPair, error) {
+		kvp, ok := store[key.String()]
+		if !ok {
+			return nil, cerrors.ErrorResourceDoesNotExist{Identifier: key}
+		}
+		return copyStoreKVP(kvp), nil
+	}
+	c.deleteKVPFuncs["default"] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+		existing, ok := store[kvp.Key.String()]
+		if !ok {
+			return nil, cerrors.ErrorResourceDoesNotExist{Identifier: kvp.Key}
+		}
+		delete(store, kvp.Key.String())
+		return existing, nil
+	}
+	c.listFuncs["default"] = func(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+		kvps := &model.KVPairList{}
+		for _, kvp := range store {
+			if _, ok := kvp.Key.(model.BlockKey); ok {
+				kvps.KVPairs = append(kvps.KVPairs, copyStoreKVP(kvp))
+			}
+		}
+		return kvps, nil
+	}
+	return c
+}
+
+// copyStoreKVP copies a KVPair held by the in-memory store so that callers modifying the
+// returned handles do not modify the store.
+func copyStoreKVP(kvp *model.KVPair) *model.KVPair {
+	cp := *kvp
+	if h, ok := kvp.Value.(*model.IPAMHandle); ok {
+		handle := *h
+		handle.Block = map[string]int{}
+		for cidr, num := range h.Block {
+			handle.Block[cidr] = num
+		}
+		cp.Value = &handle
+	}
+	return &cp
+}
+
+// storeKeys returns the keys held by the in-memory store.
+func storeKeys(store map[string]*model.KVPair) []string {
+	keys := []string{}
+	for k := range store {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+var _ = Describe("Block size migration planning", func() {
+	var pool v3.IPPool
+	var blocks []*model.KVPair
+	handleA := "handle-a"
+	handleB := "handle-b"
+
+	// assignedBlock creates a block for the given host with the given addresses assigned.
+	assignedBlock := func(cidr, host string, handle *string, ips ...string) *model.KVPair {
+		b := newBlock(cnet.MustParseCIDR(cidr), nil)
+		aff := "host:" + host
+		b.Affinity = &aff
+		for _, ip := range ips {
+			err := b.assign(false, cnet.MustParseIP(ip), handle, map[string]string{AttributeNode: host}, host)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		return &model.KVPair{Key: model.BlockKey{CIDR: b.CIDR}, Value: b.AllocationBlock}
+	}
+
+	BeforeEach(func() {
+		pool = v3.IPPool{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/24", BlockSize: 26}}
+		pool.Name = "pool1"
+
+		// A partially allocated pool - two of the four /26 blocks exist and each is only
+		// partially allocated.
+		blocks = []*model.KVPair{
+			assignedBlock("10.0.0.64/26", "host2", &handleB, "10.0.0.100"),
+			assignedBlock("10.0.0.0/26", "host1", &handleA, "10.0.0.1", "10.0.0.2", "10.0.0.17"),
+		}
+	})
+
+	It("should plan a migration to a smaller block size", func() {
+		m, err := planBlockSizeMigration(pool, 28, blocks)
+		Expect(err).NotTo(HaveOccurred())
+
+		plan := m.plan
+		Expect(plan.Pool).To(Equal("pool1"))
+		Expect(plan.OldBlockSize).To(Equal(26))
+		Expect(plan.NewBlockSize).To(Equal(28))
+		Expect(plan.OldBlocks).To(Equal([]cnet.IPNet{
+			cnet.MustParseCIDR("10.0.0.0/26"),
+			cnet.MustParseCIDR("10.0.0.64/26"),
+		}))
+
+		host1 := "host:host1"
+		host2 := "host:host2"
+		Expect(plan.NewBlocks).To(Equal([]PlannedBlock{
+			{CIDR: cnet.MustParseCIDR("10.0.0.0/28"), Affinity: &host1, Allocated: 2},
+			{CIDR: cnet.MustParseCIDR("10.0.0.16/28"), Affinity: &host1, Allocated: 1},
+			{CIDR: cnet.MustParseCIDR("10.0.0.96/28"), Affinity: &host2, Allocated: 1},
+		}))
+
+		Expect(plan.Moves).To(Equal([]PlannedAddressMove{
+			{
+				IP:       cnet.MustParseIP("10.0.0.1"),
+				HandleID: &handleA,
+				OldBlock: cnet.MustParseCIDR("10.0.0.0/26"),
+				NewBlock: cnet.MustParseCIDR("10.0.0.0/28"),
+			},
+			{
+				IP:       cnet.MustParseIP("10.0.0.2"),
+				HandleID: &handleA,
+				OldBlock: cnet.MustParseCIDR("10.0.0.0/26"),
+				NewBlock: cnet.MustParseCIDR("10.0.0.0/28"),
+			},
+			{
+				IP:       cnet.MustParseIP("10.0.0.17"),
+				HandleID: &handleA,
+				OldBlock: cnet.MustParseCIDR("10.0.0.0/26"),
+				NewBlock: cnet.MustParseCIDR("10.0.0.16/28"),
+			},
+			{
+				IP:       cnet.MustParseIP("10.0.0.100"),
+				HandleID: &handleB,
+				OldBlock: cnet.MustParseCIDR("10.0.0.64/26"),
+				NewBlock: cnet.MustParseCIDR("10.0.0.96/28"),
+			},
+		}))
+
+		By("checking the handle counts reference the new blocks")
+		Expect(m.handleCounts).To(Equal(map[string]map[string]int{
+			handleA: {"10.0.0.0/28": 2, "10.0.0.16/28": 1},
+			handleB: {"10.0.0.96/28": 1},
+		}))
+
+		By("checking the new blocks contain the moved allocations")
+		Expect(m.newBlocks).To(HaveLen(3))
+		b := allocationBlock{m.newBlocks[0]}
+		Expect(b.NumFreeAddresses()).To(Equal(14))
+		attrs, err := b.attributesForIP(cnet.MustParseIP("10.0.0.2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attrs).To(Equal(map[string]string{AttributeNode: "host1"}))
+		Expect(b.ipsByHandle(handleA)).To(ConsistOf(cnet.MustParseIP("10.0.0.1"), cnet.MustParseIP("10.0.0.2")))
+	})
+
+	It("should plan a migration to a larger block size", func() {
+		m, err := planBlockSizeMigration(pool, 24, blocks)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Both blocks merge into a single block.  Since they had different affinities the
+		// merged block has none.
+		Expect(m.plan.NewBlocks).To(Equal([]PlannedBlock{
+			{CIDR: cnet.MustParseCIDR("10.0.0.0/24"), Allocated: 4},
+		}))
+		Expect(m.plan.Moves).To(HaveLen(4))
+		for _, move := range m.plan.Moves {
+			Expect(move.NewBlock).To(Equal(cnet.MustParseCIDR("10.0.0.0/24")))
+		}
+		b := allocationBlock{m.newBlocks[0]}
+		Expect(b.NumFreeAddresses()).To(Equal(252))
+		Expect(b.Attributes).To(HaveLen(2))
+	})
+
+	It("should plan an empty migration for a pool with no blocks", func() {
+		m, err := planBlockSizeMigration(pool, 28, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.plan.OldBlocks).To(BeEmpty())
+		Expect(m.plan.NewBlocks).To(BeEmpty())
+		Expect(m.plan.Moves).To(BeEmpty())
+	})
+
+	It("should drop blocks that have no allocations", func() {
+		blocks = append(blocks, assignedBlock("10.0.0.128/26", "host3", nil))
+		m, err := planBlockSizeMigration(pool, 28, blocks)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.plan.OldBlocks).To(HaveLen(3))
+		Expect(m.plan.NewBlocks).To(HaveLen(3))
+	})
+
+	It("should reject invalid block sizes", func() {
+		_, err := planBlockSizeMigration(pool, 26, blocks)
+		Expect(err).To(BeAssignableToTypeOf(invalidSizeError("")))
+		_, err = planBlockSizeMigration(pool, 33, blocks)
+		Expect(err).To(BeAssignableToTypeOf(invalidSizeError("")))
+		_, err = planBlockSizeMigration(pool, 22, blocks)
+		Expect(err).To(BeAssignableToTypeOf(invalidSizeError("")))
+	})
+})
+
+var _ = Describe("Block size migration execution", func() {
+	var store map[string]*model.KVPair
+	var fc *fakeClient
+	var ic *ipamClient
+	var pools *ipPoolAccessor
+	var originalKeys []string
+	ctx := context.Background()
+	handleA := "handle-a"
+	handleB := "handle-b"
+
+	blockKey := func(cidr string) string {
+		return model.BlockKey{CIDR: cnet.MustParseCIDR(cidr)}.String()
+	}
+	affinityKey := func(cidr, host string) string {
+		return model.BlockAffinityKey{CIDR: cnet.MustParseCIDR(cidr), Host: host}.String()
+	}
+	handleKey := func(handleID string) string {
+		return model.IPAMHandleKey{HandleID: handleID}.String()
+	}
+	add := func(kvp *model.KVPair) {
+		store[kvp.Key.String()] = kvp
+	}
+
+	// addAssignedBlock stores a block for the given host with the given addresses assigned,
+	// along with the host's affinity for the block.
+	addAssignedBlock := func(cidr, host string, handle *string, ips ...string) {
+		b := newBlock(cnet.MustParseCIDR(cidr), nil)
+		aff := "host:" + host
+		b.Affinity = &aff
+		for _, ip := range ips {
+			err := b.assign(false, cnet.MustParseIP(ip), handle, map[string]string{AttributeNode: host}, host)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		add(&model.KVPair{Key: model.BlockKey{CIDR: b.CIDR}, Value: b.AllocationBlock})
+		add(&model.KVPair{
+			Key:   model.BlockAffinityKey{CIDR: b.CIDR, Host: host},
+			Value: &model.BlockAffinity{State: model.StateConfirmed},
+		})
+	}
+
+	BeforeEach(func() {
+		store = map[string]*model.KVPair{}
+		addAssignedBlock("10.0.0.0/26", "host1", &handleA, "10.0.0.1", "10.0.0.2", "10.0.0.17")
+		addAssignedBlock("10.0.0.64/26", "host2", &handleB, "10.0.0.100")
+		add(&model.KVPair{
+			Key:   model.IPAMHandleKey{HandleID: handleA},
+			Value: &model.IPAMHandle{HandleID: handleA, Block: map[string]int{"10.0.0.0/26": 3}},
+		})
+		add(&model.KVPair{
+			Key:   model.IPAMHandleKey{HandleID: handleB},
+			Value: &model.IPAMHandle{HandleID: handleB, Block: map[string]int{"10.0.0.64/26": 1}},
+		})
+		originalKeys = storeKeys(store)
+
+		fc = newStoreClient(store)
+		pools = &ipPoolAccessor{pools: map[string]pool{"10.0.0.0/24": {cidr: "10.0.0.0/24", blockSize: 26}}}
+		ic = NewIPAMClient(fc, pools).(*ipamClient)
+	})
+
+	// expectOriginalState checks that the store holds the blocks, affinities and handles
+	// from before the migration.
+	expectOriginalState := func() {
+		Expect(storeKeys(store)).To(ConsistOf(originalKeys))
+		b := store[blockKey("10.0.0.0/26")].Value.(*model.AllocationBlock)
+		Expect(allocationBlock{b}.ipsByHandle(handleA)).To(ConsistOf(
+			cnet.MustParseIP("10.0.0.1"), cnet.MustParseIP("10.0.0.2"), cnet.MustParseIP("10.0.0.17"),
+		))
+		Expect(store[handleKey(handleA)].Value.(*model.IPAMHandle).Block).To(Equal(map[string]int{"10.0.0.0/26": 3}))
+		Expect(store[handleKey(handleB)].Value.(*model.IPAMHandle).Block).To(Equal(map[string]int{"10.0.0.64/26": 1}))
+	}
+
+	It("should migrate the blocks, affinities and handles", func() {
+		plan, err := ic.MigrateBlockSize(ctx, "10.0.0.0/24", 28, MigrateBlockSizeOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.NewBlocks).To(HaveLen(3))
+
+		Expect(storeKeys(store)).To(ConsistOf(
+			blockKey("10.0.0.0/28"), affinityKey("10.0.0.0/28", "host1"),
+			blockKey("10.0.0.16/28"), affinityKey("10.0.0.16/28", "host1"),
+			blockKey("10.0.0.96/28"), affinityKey("10.0.0.96/28", "host2"),
+			handleKey(handleA), handleKey(handleB),
+		))
+		b := store[blockKey("10.0.0.16/28")].Value.(*model.AllocationBlock)
+		Expect(allocationBlock{b}.ipsByHandle(handleA)).To(ConsistOf(cnet.MustParseIP("10.0.0.17")))
+		Expect(store[handleKey(handleA)].Value.(*model.IPAMHandle).Block).To(Equal(map[string]int{"10.0.0.0/28": 2, "10.0.0.16/28": 1}))
+		Expect(store[handleKey(handleB)].Value.(*model.IPAMHandle).Block).To(Equal(map[string]int{"10.0.0.96/28": 1}))
+	})
+
+	It("should not modify the datastore for a dry run", func() {
+		_, err := ic.MigrateBlockSize(ctx, "10.0.0.0/24", 28, MigrateBlockSizeOptions{DryRun: true})
+		Expect(err).NotTo(HaveOccurred())
+		expectOriginalState()
+	})
+
+	It("should refuse to migrate an enabled pool", func() {
+		pools.pools["10.0.0.0/24"] = pool{cidr: "10.0.0.0/24", blockSize: 26, enabled: true}
+		_, err := ic.MigrateBlockSize(ctx, "10.0.0.0/24", 28, MigrateBlockSizeOptions{})
+		Expect(err).To(HaveOccurred())
+		expectOriginalState()
+	})
+
+	It("should roll back if creating a new affinity fails", func() {
+		fc.createFuncs[affinityKey("10.0.0.96/28", "host2")] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+			return nil, errors.New("create failed")
+		}
+		_, err := ic.MigrateBlockSize(ctx, "10.0.0.0/24", 28, MigrateBlockSizeOptions{})
+		Expect(err).To(MatchError("create failed"))
+		expectOriginalState()
+	})
+
+	It("should roll back if deleting an old block fails", func() {
+		fc.deleteKVPFuncs[blockKey("10.0.0.64/26")] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+			return nil, errors.New("delete failed")
+		}
+		_, err := ic.MigrateBlockSize(ctx, "10.0.0.0/24", 28, MigrateBlockSizeOptions{})
+		Expect(err).To(MatchError("delete failed"))
+		expectOriginalState()
+	})
+
+	It("should roll back if updating a handle fails", func() {
+		fc.updateFuncs[handleKey(handleB)] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+			return nil, errors.New("update failed")
+		}
+		_, err := ic.MigrateBlockSize(ctx, "10.0.0.0/24", 28, MigrateBlockSizeOptions{})
+		Expect(err).To(MatchError("update failed"))
+		expectOriginalState()
+	})
+})
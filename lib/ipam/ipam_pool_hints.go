@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// AnnotationIPPoolAffinityHint is a node annotation that hints which IP pools should be preferred
+// when auto-assigning addresses to the node.  The value is a comma separated list of IP pool names
+// or CIDRs, in order of preference.  Unlike an IP pool node selector, the hint does not prevent
+// the use of other pools - once the hinted pools are exhausted, addresses are assigned from any
+// other pool that selects the node.
+const AnnotationIPPoolAffinityHint = "projectcalico.org/IPPoolAffinityHint"
+
+// LabelZone is the label used to assign nodes and IP pools to a zone (failure domain).  When a node
+// is in a zone, pools in the same zone are preferred when auto-assigning addresses to the node,
+// followed by pools that are not in any zone, and finally pools in other zones.  The zone of a node
+// may also be given by the deprecated LabelZoneBeta label.
+const (
+	LabelZone     = "topology.kubernetes.io/zone"
+	LabelZoneBeta = "failure-domain.beta.kubernetes.io/zone"
+)
+
+// nodeZone returns the zone of the node, or an empty string if the node is not in a zone.
+func nodeZone(node v3.Node) string {
+	if z := node.Labels[LabelZone]; z != "" {
+		return z
+	}
+	return node.Labels[LabelZoneBeta]
+}
+
+// sortPoolsByZone returns the pools ordered so that pools in the same zone as the node come first,
+// followed by pools without a zone and then pools in other zones.  The order of pools with the same
+// rank is unchanged.  Returns false if the node is not in a zone, in which case the pools are
+// returned unchanged.
+func sortPoolsByZone(pools []v3.IPPool, node v3.Node) ([]v3.IPPool, bool) {
+	zone := nodeZone(node)
+	if zone == "" {
+		return pools, false
+	}
+	rank := func(p v3.IPPool) int {
+		switch p.Labels[LabelZone] {
+		case zone:
+			return 0
+		case "":
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	sorted := make([]v3.IPPool, len(pools))
+	copy(sorted, pools)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	log.WithFields(log.Fields{"node": node.Name, "zone": zone}).Debug("Ordered IP pools by zone")
+	return sorted, true
+}
+
+// poolAffinityHints returns the pool names and CIDRs hinted by the node, in order of preference.
+func poolAffinityHints(node v3.Node) []string {
+	hint := node.Annotations[AnnotationIPPoolAffinityHint]
+	if hint == "" {
+		return nil
+	}
+	var hints []string
+	for _, h := range strings.Split(hint, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(h); err == nil {
+			// Normalize the CIDR so that it can be compared with the pool CIDRs.
+			h = cidr.String()
+		}
+		hints = append(hints, h)
+	}
+	return hints
+}
+
+// sortPoolsByAffinityHint returns the pools ordered so that pools hinted by the node come first,
+// in the order of the hint.  The order of the remaining pools is unchanged.  Returns false if the
+// node has no hint, in which case the pools are returned unchanged.
+func sortPoolsByAffinityHint(pools []v3.IPPool, node v3.Node) ([]v3.IPPool, bool) {
+	hints := poolAffinityHints(node)
+	if len(hints) == 0 {
+		return pools, false
+	}
+	rank := func(p v3.IPPool) int {
+		cidr := p.Spec.CIDR
+		if _, n, err := net.ParseCIDR(p.Spec.CIDR); err == nil {
+			cidr = n.String()
+		}
+		for i, h := range hints {
+			if h == p.Name || h == cidr {
+				return i
+			}
+		}
+		return len(hints)
+	}
+
+	sorted := make([]v3.IPPool, len(pools))
+	copy(sorted, pools)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	log.WithFields(log.Fields{"node": node.Name, "hint": hints}).Debug("Ordered IP pools by affinity hint")
+	return sorted, true
+}
+
+// sortBlocksByPools returns the blocks ordered by the position of their pool in the supplied list.
+// The order of blocks within the same pool is unchanged.
+func sortBlocksByPools(blocks []net.IPNet, pools []v3.IPPool) []net.IPNet {
+	poolNets := make([]*net.IPNet, len(pools))
+	for i, p := range pools {
+		_, poolNets[i], _ = net.ParseCIDR(p.Spec.CIDR)
+	}
+	rank := func(b net.IPNet) int {
+		for i, n := range poolNets {
+			if n != nil && n.Contains(b.IP) {
+				return i
+			}
+		}
+		return len(poolNets)
+	}
+
+	sorted := make([]net.IPNet, len(blocks))
+	copy(sorted, blocks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// ReservationAccessorInterface provides the IP addresses that are reserved and must never be
+// handed out by AutoAssign.
+type ReservationAccessorInterface interface {
+	// Returns the reserved CIDRs.  A single reserved address is returned as a /32 (or /128)
+	// CIDR.
+	GetReservedCIDRs() ([]net.IPNet, error)
+}
+
+// WithReservations configures the IPAM client to consult the supplied reservations when
+// automatically assigning addresses.  AutoAssign skips any reserved address, even if it is
+// free within its block.  Reservations do not affect AssignIP, which assigns the address it is
+// asked to assign.
+func WithReservations(r ReservationAccessorInterface) Option {
+	return func(c *ipamClient) {
+		c.reservations = r
+	}
+}
+
+// reservedCIDRs is a set of reserved CIDRs.
+type reservedCIDRs []net.IPNet
+
+// getReservedCIDRs returns the reserved CIDRs of the given IP version, or nil if the client
+// has no reservations.
+func (c ipamClient) getReservedCIDRs(version int) (reservedCIDRs, error) {
+	if c.reservations == nil {
+		return nil, nil
+	}
+	cidrs, err := c.reservations.GetReservedCIDRs()
+	if err != nil {
+		return nil, err
+	}
+	var reserved reservedCIDRs
+	for _, cidr := range cidrs {
+		if cidr.Version() == version {
+			reserved = append(reserved, cidr)
+		}
+	}
+	return reserved, nil
+}
+
+// forBlock returns the reserved CIDRs that overlap the supplied block CIDR.  A reservation may
+// cover part of a block, all of a block, or a range of blocks.
+func (r reservedCIDRs) forBlock(block net.IPNet) reservedCIDRs {
+	var overlapping reservedCIDRs
+	for _, cidr := range r {
+		if cidr.Contains(block.IP) || block.Contains(cidr.IP) {
+			overlapping = append(overlapping, cidr)
+		}
+	}
+	return overlapping
+}
+
+// contains returns true if the address is reserved.
+func (r reservedCIDRs) contains(ip net.IP) bool {
+	for _, cidr := range r {
+		if cidr.Contains(ip.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"sync"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+// poolSelectorCache caches the compiled node selectors of the IP pools so that the selectors
+// do not need to be parsed on every allocation.  Entries are keyed on the pool name and record
+// the selector string they were compiled from, so a change to a pool's selector invalidates the
+// cached entry for that pool.
+type poolSelectorCache struct {
+	lock    sync.Mutex
+	entries map[string]poolSelectorEntry
+}
+
+type poolSelectorEntry struct {
+	nodeSelector string
+	compiled     selector.Selector
+}
+
+func newPoolSelectorCache() *poolSelectorCache {
+	return &poolSelectorCache{entries: map[string]poolSelectorEntry{}}
+}
+
+// selectsNode determines whether or not the pool's node selector matches the labels on the
+// given node, in the same way as IPPool.SelectsNode.  A nil cache parses the selector each time.
+func (c *poolSelectorCache) selectsNode(pool v3.IPPool, node v3.Node) (bool, error) {
+	if c == nil {
+		return pool.SelectsNode(node)
+	}
+
+	// No node selector means that the pool matches the node.
+	if len(pool.Spec.NodeSelector) == 0 {
+		c.invalidate(pool.Name)
+		return true, nil
+	}
+	sel, err := c.compiled(pool)
+	if err != nil {
+		return false, err
+	}
+	return sel.Evaluate(node.Labels), nil
+}
+
+// compiled returns the compiled node selector for the pool, parsing and caching it if the
+// cache does not hold the pool's current selector.  Selectors that fail to parse are not cached.
+func (c *poolSelectorCache) compiled(pool v3.IPPool) (selector.Selector, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.entries[pool.Name]; ok && e.nodeSelector == pool.Spec.NodeSelector {
+		return e.compiled, nil
+	}
+	sel, err := selector.Parse(pool.Spec.NodeSelector)
+	if err != nil {
+		delete(c.entries, pool.Name)
+		return nil, err
+	}
+	c.entries[pool.Name] = poolSelectorEntry{nodeSelector: pool.Spec.NodeSelector, compiled: sel}
+	return sel, nil
+}
+
+// invalidate removes any cached selector for the named pool.
+func (c *poolSelectorCache) invalidate(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, name)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+)
+
+var _ = Describe("IP pool selector cache", func() {
+	var cache *poolSelectorCache
+	var pool v3.IPPool
+	node := v3.Node{}
+	node.Labels = map[string]string{"zone": "a"}
+
+	BeforeEach(func() {
+		cache = newPoolSelectorCache()
+		pool = v3.IPPool{}
+		pool.Name = "pool1"
+		pool.Spec.NodeSelector = "zone == 'a'"
+	})
+
+	It("should reuse the compiled selector for an unchanged pool", func() {
+		sel1, err := cache.compiled(pool)
+		Expect(err).NotTo(HaveOccurred())
+		sel2, err := cache.compiled(pool)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sel2).To(BeIdenticalTo(sel1))
+	})
+
+	It("should recompile the selector when the pool's selector changes", func() {
+		matches, err := cache.selectsNode(pool, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeTrue())
+		sel1, _ := cache.compiled(pool)
+
+		pool.Spec.NodeSelector = "zone == 'b'"
+		matches, err = cache.selectsNode(pool, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeFalse())
+		sel2, _ := cache.compiled(pool)
+		Expect(sel2).NotTo(BeIdenticalTo(sel1))
+		Expect(sel2.String()).To(Equal(`zone == "b"`))
+	})
+
+	It("should drop the cached selector when the pool's selector is removed or invalid", func() {
+		_, err := cache.selectsNode(pool, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.entries).To(HaveKey("pool1"))
+
+		pool.Spec.NodeSelector = ""
+		matches, err := cache.selectsNode(pool, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeTrue())
+		Expect(cache.entries).NotTo(HaveKey("pool1"))
+
+		pool.Spec.NodeSelector = "zone =="
+		_, err = cache.selectsNode(pool, node)
+		Expect(err).To(HaveOccurred())
+		Expect(cache.entries).NotTo(HaveKey("pool1"))
+	})
+
+	It("should evaluate the selector when there is no cache", func() {
+		var nilCache *poolSelectorCache
+		matches, err := nilCache.selectsNode(pool, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeTrue())
+	})
+})
+
+var selectsResult bool
+
+func benchmarkSelectsNode(b *testing.B, cache *poolSelectorCache) {
+	pool := v3.IPPool{}
+	pool.Name = "pool1"
+	pool.Spec.NodeSelector = "zone in {'a', 'b', 'c'} && has(rack) && !has(excluded)"
+	node := v3.Node{}
+	node.Labels = map[string]string{"zone": "b", "rack": "r1"}
+
+	var r bool
+	for n := 0; n < b.N; n++ {
+		r, _ = cache.selectsNode(pool, node)
+	}
+	selectsResult = r
+}
+
+func BenchmarkSelectsNodeUncached(b *testing.B) {
+	benchmarkSelectsNode(b, nil)
+}
+
+func BenchmarkSelectsNodeCached(b *testing.B) {
+	benchmarkSelectsNode(b, newPoolSelectorCache())
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// blockSplit contains a split plan along with the datastore state required to execute it.
+type blockSplit struct {
+	plan BlockSplitPlan
+
+	// The empty blocks to be released.
+	oldBlocks []*model.KVPair
+
+	// The blocks to be created in place of each released block, indexed as oldBlocks.
+	// An entry is nil if no block replaces the released block.
+	newBlocks []*model.AllocationBlock
+}
+
+// SplitBlocks plans the split of the empty allocation blocks in the given pool (specified by
+// name or CIDR) into blocks of the new size, and executes it unless this is a dry run.
+func (c ipamClient) SplitBlocks(ctx context.Context, pool string, newBlockSize int, opts SplitBlocksOptions) (*BlockSplitPlan, error) {
+	logCtx := log.WithFields(log.Fields{"pool": pool, "newBlockSize": newBlockSize})
+
+	p, err := c.getPoolByNameOrCIDR(pool)
+	if err != nil {
+		return nil, err
+	}
+	_, poolCIDR, err := net.ParseCIDR(p.Spec.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	poolBlocks, err := c.listPoolBlocks(ctx, *poolCIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := planBlockSplit(*p, newBlockSize, poolBlocks)
+	if err != nil {
+		return nil, err
+	}
+	logCtx.Infof("Planned split of %d empty blocks, leaving %d blocks to drain",
+		len(s.plan.Splits), len(s.plan.Draining))
+	if opts.DryRun {
+		return &s.plan, nil
+	}
+
+	// New blocks of either size could overlap the blocks being split if the pool were used for
+	// allocations, so it must be disabled until every block has been split.
+	if !p.Spec.Disabled {
+		return nil, fmt.Errorf("pool %s must be disabled before splitting its blocks", p.Name)
+	}
+
+	// Each block is split independently, so that an allocation racing with the split
+	// only prevents that one block from being split.
+	for i, kvp := range s.oldBlocks {
+		if err := c.executeBlockSplit(ctx, kvp, s.newBlocks[i]); err != nil {
+			old := kvp.Value.(*model.AllocationBlock)
+			s.plan.Failed = append(s.plan.Failed, BlockSplitFailure{OldBlock: old.CIDR, Err: err})
+		}
+	}
+	if len(s.plan.Failed) > 0 {
+		return &s.plan, cerrors.ErrorPartialFailure{
+			Err: fmt.Errorf("failed to split %d of %d blocks", len(s.plan.Failed), len(s.oldBlocks)),
+		}
+	}
+	logCtx.Info("Completed block split")
+	return &s.plan, nil
+}
+
+// planBlockSplit calculates which of the supplied blocks can be split to the new block size,
+// which must be no larger than the pool's block size.  The pool may already have the new block
+// size, if it has been recreated while its blocks are drained.  The supplied blocks must all be
+// within the pool.
+func planBlockSplit(pool v3.IPPool, newSize int, blocks []*model.KVPair) (*blockSplit, error) {
+	_, poolCIDR, err := net.ParseCIDR(pool.Spec.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBlockSize(pool, *poolCIDR, newSize); err != nil {
+		return nil, err
+	}
+	if newSize < pool.Spec.BlockSize {
+		return nil, invalidSizeError(fmt.Sprintf("/%d blocks are larger than the current /%d blocks", newSize, pool.Spec.BlockSize))
+	}
+
+	newPool := pool
+	newPool.Spec.BlockSize = newSize
+
+	s := &blockSplit{
+		plan: BlockSplitPlan{
+			Pool:         pool.Name,
+			CIDR:         *poolCIDR,
+			OldBlockSize: pool.Spec.BlockSize,
+			NewBlockSize: newSize,
+		},
+	}
+	for _, kvp := range sortBlocksByAddress(blocks) {
+		old := kvp.Value.(*model.AllocationBlock)
+		if ones, _ := old.CIDR.Mask.Size(); ones >= newSize {
+			// This block has already been split.
+			continue
+		}
+
+		allocated := 0
+		for _, attrIdx := range old.Allocations {
+			if attrIdx != nil {
+				allocated++
+			}
+		}
+		if allocated > 0 {
+			s.plan.Draining = append(s.plan.Draining, PlannedBlock{
+				CIDR:      old.CIDR,
+				Affinity:  old.Affinity,
+				Allocated: allocated,
+			})
+			continue
+		}
+
+		split := PlannedBlockSplit{OldBlock: old.CIDR, Affinity: old.Affinity}
+		var nb *model.AllocationBlock
+		if getHostAffinity(old) != "" {
+			// Replace the block with the first block of the new size in its range, so that
+			// the host keeps an affine block.
+			cidr := getBlockCIDRForAddress(net.IP{IP: old.CIDR.IP}, &newPool)
+			b := newBlock(cidr, nil)
+			b.Affinity = old.Affinity
+			nb = b.AllocationBlock
+			split.NewBlock = &cidr
+		}
+		s.oldBlocks = append(s.oldBlocks, kvp)
+		s.newBlocks = append(s.newBlocks, nb)
+		s.plan.Splits = append(s.plan.Splits, split)
+	}
+	return s, nil
+}
+
+// executeBlockSplit releases a single empty block, and creates its replacement (if any),
+// rolling back the steps that have been completed if any step fails.
+func (c ipamClient) executeBlockSplit(ctx context.Context, kvp *model.KVPair, nb *model.AllocationBlock) error {
+	old := kvp.Value.(*model.AllocationBlock)
+	logCtx := log.WithField("block", old.CIDR)
+
+	var undos []undoFunc
+	rollback := func(err error) error {
+		logCtx.WithError(err).Warnf("Block split failed, rolling back %d steps", len(undos))
+		for i := len(undos) - 1; i >= 0; i-- {
+			if uerr := undos[i](ctx); uerr != nil {
+				logCtx.WithError(uerr).Error("Failed to roll back block split step")
+			}
+		}
+		return err
+	}
+
+	host := getHostAffinity(old)
+	if host != "" {
+		aff, err := c.blockReaderWriter.queryAffinity(ctx, host, old.CIDR, "")
+		if err == nil {
+			if err := c.blockReaderWriter.deleteAffinity(ctx, aff); err != nil {
+				return rollback(err)
+			}
+			undos = append(undos, c.recreateFunc(aff))
+		} else if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok {
+			return rollback(err)
+		}
+	}
+
+	// The block is deleted at the revision it was listed at, so this fails if an address
+	// has been allocated from it since the split was planned.
+	if err := c.blockReaderWriter.deleteBlock(ctx, kvp); err != nil {
+		return rollback(err)
+	}
+	undos = append(undos, c.recreateFunc(kvp))
+
+	if nb == nil {
+		logCtx.Info("Released empty block")
+		return nil
+	}
+	created, err := c.client.Create(ctx, &model.KVPair{
+		Key:   model.BlockKey{CIDR: nb.CIDR},
+		Value: nb,
+	})
+	if err != nil {
+		return rollback(err)
+	}
+	undos = append(undos, c.deleteFunc(created))
+
+	if _, err := c.client.Create(ctx, &model.KVPair{
+		Key:   model.BlockAffinityKey{CIDR: nb.CIDR, Host: host},
+		Value: &model.BlockAffinity{State: model.StateConfirmed},
+	}); err != nil {
+		return rollback(err)
+	}
+	logCtx.WithField("newBlock", nb.CIDR).Info("Split empty block")
+	return nil
+}
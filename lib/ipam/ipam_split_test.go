@@ -0,0 +1,230 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("Block split planning", func() {
+	var pool v3.IPPool
+	handle := "handle-a"
+
+	// affineBlock creates a block for the given host with the given addresses assigned.
+	affineBlock := func(cidr, host string, ips ...string) *model.KVPair {
+		b := newBlock(cnet.MustParseCIDR(cidr), nil)
+		if host != "" {
+			aff := "host:" + host
+			b.Affinity = &aff
+		}
+		for _, ip := range ips {
+			err := b.assign(false, cnet.MustParseIP(ip), &handle, map[string]string{AttributeNode: host}, host)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		return &model.KVPair{Key: model.BlockKey{CIDR: b.CIDR}, Value: b.AllocationBlock}
+	}
+
+	BeforeEach(func() {
+		pool = v3.IPPool{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/24", BlockSize: 26}}
+		pool.Name = "pool1"
+	})
+
+	It("should split empty blocks, keeping their affinity", func() {
+		s, err := planBlockSplit(pool, 28, []*model.KVPair{
+			affineBlock("10.0.0.64/26", "host2"),
+			affineBlock("10.0.0.0/26", "host1"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		host1 := "host:host1"
+		host2 := "host:host2"
+		new1 := cnet.MustParseCIDR("10.0.0.0/28")
+		new2 := cnet.MustParseCIDR("10.0.0.64/28")
+		Expect(s.plan.Pool).To(Equal("pool1"))
+		Expect(s.plan.OldBlockSize).To(Equal(26))
+		Expect(s.plan.NewBlockSize).To(Equal(28))
+		Expect(s.plan.Splits).To(Equal([]PlannedBlockSplit{
+			{OldBlock: cnet.MustParseCIDR("10.0.0.0/26"), NewBlock: &new1, Affinity: &host1},
+			{OldBlock: cnet.MustParseCIDR("10.0.0.64/26"), NewBlock: &new2, Affinity: &host2},
+		}))
+		Expect(s.plan.Draining).To(BeEmpty())
+
+		By("checking the new blocks are empty and keep the affinity")
+		Expect(s.oldBlocks).To(HaveLen(2))
+		Expect(s.newBlocks).To(HaveLen(2))
+		b := allocationBlock{s.newBlocks[0]}
+		Expect(b.CIDR).To(Equal(new1))
+		Expect(b.Affinity).To(Equal(&host1))
+		Expect(b.NumFreeAddresses()).To(Equal(16))
+	})
+
+	It("should release empty blocks without an affinity", func() {
+		s, err := planBlockSplit(pool, 28, []*model.KVPair{affineBlock("10.0.0.128/26", "")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.plan.Splits).To(Equal([]PlannedBlockSplit{
+			{OldBlock: cnet.MustParseCIDR("10.0.0.128/26")},
+		}))
+		Expect(s.newBlocks).To(Equal([]*model.AllocationBlock{nil}))
+	})
+
+	It("should leave partially allocated blocks to drain", func() {
+		s, err := planBlockSplit(pool, 28, []*model.KVPair{
+			affineBlock("10.0.0.0/26", "host1", "10.0.0.1", "10.0.0.17"),
+			affineBlock("10.0.0.64/26", "host2"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		host1 := "host:host1"
+		Expect(s.plan.Draining).To(Equal([]PlannedBlock{
+			{CIDR: cnet.MustParseCIDR("10.0.0.0/26"), Affinity: &host1, Allocated: 2},
+		}))
+		Expect(s.plan.Splits).To(HaveLen(1))
+		Expect(s.plan.Splits[0].OldBlock).To(Equal(cnet.MustParseCIDR("10.0.0.64/26")))
+		Expect(s.oldBlocks).To(HaveLen(1))
+	})
+
+	It("should skip blocks that are already the new size", func() {
+		s, err := planBlockSplit(pool, 28, []*model.KVPair{
+			affineBlock("10.0.0.0/28", "host1"),
+			affineBlock("10.0.0.16/28", "host1", "10.0.0.17"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.plan.Splits).To(BeEmpty())
+		Expect(s.plan.Draining).To(BeEmpty())
+	})
+
+	It("should split blocks once the pool has been recreated with the new size", func() {
+		pool.Spec.BlockSize = 28
+		s, err := planBlockSplit(pool, 28, []*model.KVPair{
+			affineBlock("10.0.0.0/28", "host1", "10.0.0.1"),
+			affineBlock("10.0.0.64/26", "host2"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.plan.Splits).To(HaveLen(1))
+		Expect(s.plan.Splits[0].OldBlock).To(Equal(cnet.MustParseCIDR("10.0.0.64/26")))
+	})
+
+	It("should reject block sizes that are larger", func() {
+		_, err := planBlockSplit(pool, 25, nil)
+		Expect(err).To(BeAssignableToTypeOf(invalidSizeError("")))
+		_, err = planBlockSplit(pool, 33, nil)
+		Expect(err).To(BeAssignableToTypeOf(invalidSizeError("")))
+	})
+})
+
+var _ = Describe("Block split execution", func() {
+	var store map[string]*model.KVPair
+	var fc *fakeClient
+	var ic *ipamClient
+	var pools *ipPoolAccessor
+	var originalKeys []string
+	ctx := context.Background()
+	handle := "handle-a"
+
+	blockKey := func(cidr string) string {
+		return model.BlockKey{CIDR: cnet.MustParseCIDR(cidr)}.String()
+	}
+	affinityKey := func(cidr, host string) string {
+		return model.BlockAffinityKey{CIDR: cnet.MustParseCIDR(cidr), Host: host}.String()
+	}
+
+	// addAffineBlock stores a block for the given host with the given addresses assigned,
+	// along with the host's affinity for the block.
+	addAffineBlock := func(cidr, host string, ips ...string) {
+		b := newBlock(cnet.MustParseCIDR(cidr), nil)
+		aff := "host:" + host
+		b.Affinity = &aff
+		for _, ip := range ips {
+			err := b.assign(false, cnet.MustParseIP(ip), &handle, map[string]string{AttributeNode: host}, host)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		store[blockKey(cidr)] = &model.KVPair{Key: model.BlockKey{CIDR: b.CIDR}, Value: b.AllocationBlock}
+		store[affinityKey(cidr, host)] = &model.KVPair{
+			Key:   model.BlockAffinityKey{CIDR: b.CIDR, Host: host},
+			Value: &model.BlockAffinity{State: model.StateConfirmed},
+		}
+	}
+
+	BeforeEach(func() {
+		store = map[string]*model.KVPair{}
+		addAffineBlock("10.0.0.0/26", "host1", "10.0.0.1")
+		addAffineBlock("10.0.0.64/26", "host2")
+		addAffineBlock("10.0.0.128/26", "host3")
+		originalKeys = storeKeys(store)
+
+		fc = newStoreClient(store)
+		pools = &ipPoolAccessor{pools: map[string]pool{"10.0.0.0/24": {cidr: "10.0.0.0/24", blockSize: 26}}}
+		ic = NewIPAMClient(fc, pools).(*ipamClient)
+	})
+
+	It("should split the empty blocks", func() {
+		plan, err := ic.SplitBlocks(ctx, "10.0.0.0/24", 28, SplitBlocksOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Splits).To(HaveLen(2))
+		Expect(plan.Failed).To(BeEmpty())
+		Expect(storeKeys(store)).To(ConsistOf(
+			blockKey("10.0.0.0/26"), affinityKey("10.0.0.0/26", "host1"),
+			blockKey("10.0.0.64/28"), affinityKey("10.0.0.64/28", "host2"),
+			blockKey("10.0.0.128/28"), affinityKey("10.0.0.128/28", "host3"),
+		))
+	})
+
+	It("should not modify the datastore for a dry run", func() {
+		_, err := ic.SplitBlocks(ctx, "10.0.0.0/24", 28, SplitBlocksOptions{DryRun: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(storeKeys(store)).To(ConsistOf(originalKeys))
+	})
+
+	It("should refuse to split the blocks of an enabled pool", func() {
+		pools.pools["10.0.0.0/24"] = pool{cidr: "10.0.0.0/24", blockSize: 26, enabled: true}
+		_, err := ic.SplitBlocks(ctx, "10.0.0.0/24", 28, SplitBlocksOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(storeKeys(store)).To(ConsistOf(originalKeys))
+	})
+
+	It("should split the remaining blocks once the pool has been recreated with the new size", func() {
+		pools.pools["10.0.0.0/24"] = pool{cidr: "10.0.0.0/24", blockSize: 28}
+		plan, err := ic.SplitBlocks(ctx, "10.0.0.0/24", 28, SplitBlocksOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Splits).To(HaveLen(2))
+	})
+
+	It("should roll back a failed block and continue with the others", func() {
+		fc.createFuncs[affinityKey("10.0.0.64/28", "host2")] = func(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+			return nil, errors.New("create failed")
+		}
+		plan, err := ic.SplitBlocks(ctx, "10.0.0.0/24", 28, SplitBlocksOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorPartialFailure{}))
+		Expect(plan.Failed).To(Equal([]BlockSplitFailure{
+			{OldBlock: cnet.MustParseCIDR("10.0.0.64/26"), Err: errors.New("create failed")},
+		}))
+
+		By("checking the failed block and its affinity are restored")
+		Expect(storeKeys(store)).To(ConsistOf(
+			blockKey("10.0.0.0/26"), affinityKey("10.0.0.0/26", "host1"),
+			blockKey("10.0.0.64/26"), affinityKey("10.0.0.64/26", "host2"),
+			blockKey("10.0.0.128/28"), affinityKey("10.0.0.128/28", "host3"),
+		))
+	})
+})
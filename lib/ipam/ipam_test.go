@@ -49,6 +49,9 @@ import (
 // data to be persisted in etcd.
 type ipPoolAccessor struct {
 	pools map[string]pool
+
+	// labels holds the labels of the pools, keyed by CIDR.
+	labels map[string]map[string]string
 }
 
 type pool struct {
@@ -79,7 +82,8 @@ func (i *ipPoolAccessor) getPools(sorted []string, ipVersion int, caller string)
 	for _, p := range sorted {
 		c := cnet.MustParseCIDR(p)
 		if (ipVersion == 0) || (c.Version() == ipVersion) {
-			pool := v3.IPPool{Spec: v3.IPPoolSpec{CIDR: p, NodeSelector: i.pools[p].nodeSelector}}
+			pool := v3.IPPool{Spec: v3.IPPoolSpec{CIDR: p, NodeSelector: i.pools[p].nodeSelector, Disabled: !i.pools[p].enabled}}
+			pool.Labels = i.labels[p]
 			if i.pools[p].blockSize == 0 {
 				if ipVersion == 4 {
 					pool.Spec.BlockSize = 26
@@ -112,6 +116,15 @@ var (
 	ipPools = &ipPoolAccessor{pools: map[string]pool{}}
 )
 
+// reservationAccessor returns the reserved CIDRs referenced by cidrs.
+type reservationAccessor struct {
+	cidrs *[]cnet.IPNet
+}
+
+func (r *reservationAccessor) GetReservedCIDRs() ([]cnet.IPNet, error) {
+	return *r.cidrs, nil
+}
+
 type testArgsClaimAff struct {
 	inNet, host                 string
 	cleanEnv                    bool
@@ -333,6 +346,303 @@ var _ = testutils.E2eDatastoreDescribe("IPAM tests", testutils.DatastoreAll, fun
 		})
 	})
 
+	Describe("IPAM event tests", func() {
+		var events chan Event
+		var hostname string
+		pool := cnet.MustParseCIDR("10.0.0.0/24")
+
+		BeforeEach(func() {
+			bc.Clean()
+			applyPoolWithBlockSize(pool.String(), true, "all()", 30)
+			hostname = "host-events"
+			applyNode(bc, kc, hostname, nil)
+
+			events = make(chan Event, 10)
+			ic = NewIPAMClient(bc, ipPools, WithEventCallback(func(e Event) {
+				events <- e
+			}))
+		})
+
+		// expectEvent asserts that the next event has the given type, address and handle.
+		expectEvent := func(t EventType, ip cnet.IP, handle *string) {
+			var e Event
+			Eventually(events).Should(Receive(&e))
+			Expect(e.Type).To(Equal(t))
+			Expect(e.IP.String()).To(Equal(ip.String()))
+			Expect(e.HandleID).To(Equal(handle))
+			Expect(e.Pool).NotTo(BeNil())
+			Expect(e.Pool.String()).To(Equal(pool.String()))
+		}
+
+		It("should send events for assigned and released addresses", func() {
+			handle := "test-handle"
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname, HandleID: &handle})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(1))
+			autoIP := cnet.IP{IP: v4[0].IP}
+			expectEvent(EventTypeAssigned, autoIP, &handle)
+
+			assignedIP := cnet.MustParseIP("10.0.0.200")
+			err = ic.AssignIP(context.Background(), AssignIPArgs{IP: assignedIP, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			expectEvent(EventTypeAssigned, assignedIP, nil)
+
+			By("releasing the addresses")
+			unallocated, err := ic.ReleaseIPs(context.Background(), []cnet.IP{assignedIP, cnet.MustParseIP("10.0.0.100")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(unallocated).To(HaveLen(1))
+			expectEvent(EventTypeReleased, assignedIP, nil)
+
+			err = ic.ReleaseByHandle(context.Background(), handle)
+			Expect(err).NotTo(HaveOccurred())
+			expectEvent(EventTypeReleased, autoIP, &handle)
+			Consistently(events).ShouldNot(Receive())
+		})
+
+		It("should not send events for failed assignments", func() {
+			ip := cnet.MustParseIP("10.0.0.1")
+			err := ic.AssignIP(context.Background(), AssignIPArgs{IP: ip, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(events).Should(Receive())
+
+			err = ic.AssignIP(context.Background(), AssignIPArgs{IP: ip, Hostname: hostname})
+			Expect(err).To(HaveOccurred())
+			Consistently(events).ShouldNot(Receive())
+		})
+	})
+
+	Describe("IPAM pool utilization threshold tests", func() {
+		var crossings []float64
+		var hostname string
+		pool := cnet.MustParseCIDR("10.0.0.0/28")
+
+		BeforeEach(func() {
+			bc.Clean()
+			applyPoolWithBlockSize(pool.String(), true, "all()", 30)
+			hostname = "host-threshold"
+			applyNode(bc, kc, hostname, nil)
+
+			crossings = nil
+			ic = NewIPAMClient(bc, ipPools, WithPoolUtilizationThreshold(0.5, func(p cnet.IPNet, utilization float64) {
+				Expect(p.String()).To(Equal(pool.String()))
+				crossings = append(crossings, utilization)
+			}))
+		})
+
+		AfterEach(func() {
+			deletePool(pool.String())
+		})
+
+		It("should invoke the callback once per crossing of the threshold", func() {
+			handle := "threshold-handle"
+			for i := 0; i < 7; i++ {
+				_, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname, HandleID: &handle})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(crossings).To(BeEmpty())
+
+			By("assigning the address that takes the pool to the threshold")
+			_, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname, HandleID: &handle})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crossings).To(Equal([]float64{0.5}))
+
+			By("assigning more addresses above the threshold")
+			_, _, err = ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 2, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crossings).To(HaveLen(1))
+
+			By("releasing addresses to below the threshold and crossing it again")
+			Expect(ic.ReleaseByHandle(context.Background(), handle)).To(Succeed())
+			Expect(crossings).To(HaveLen(1))
+			_, _, err = ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 6, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crossings).To(Equal([]float64{0.5, 0.5}))
+		})
+	})
+
+	Describe("IPAM reservation tests", func() {
+		var hostname string
+		var reserved []cnet.IPNet
+
+		BeforeEach(func() {
+			bc.Clean()
+			deleteAllPools()
+			hostname = "host-reservations"
+			applyNode(bc, kc, hostname, nil)
+			ic = NewIPAMClient(bc, ipPools, WithReservations(&reservationAccessor{cidrs: &reserved}))
+		})
+
+		// expectNotReserved asserts that none of the addresses are reserved.
+		expectNotReserved := func(ips []cnet.IPNet) {
+			for _, ip := range ips {
+				for _, r := range reserved {
+					Expect(r.Contains(ip.IP)).To(BeFalse(), fmt.Sprintf("%s is reserved by %s", ip.IP, r))
+				}
+			}
+		}
+
+		It("should skip reserved addresses within an otherwise empty block", func() {
+			applyPoolWithBlockSize("10.0.0.0/28", true, "all()", 28)
+			reserved = []cnet.IPNet{cnet.MustParseCIDR("10.0.0.0/30"), cnet.MustParseCIDR("10.0.0.8/32")}
+
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 4, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(4))
+			for i, ip := range v4 {
+				Expect(ip.IP.String()).To(Equal(fmt.Sprintf("10.0.0.%d", i+4)))
+			}
+
+			By("exhausting the block")
+			more, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 16, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(more).To(HaveLen(7))
+			expectNotReserved(more)
+
+			By("checking the reserved addresses remain unallocated")
+			for _, ip := range []string{"10.0.0.0", "10.0.0.3", "10.0.0.8"} {
+				_, _, err = ic.GetAssignmentAttributes(context.Background(), cnet.MustParseIP(ip))
+				Expect(err).To(HaveOccurred())
+			}
+		})
+
+		It("should skip blocks that are entirely reserved", func() {
+			applyPoolWithBlockSize("10.0.0.0/26", true, "all()", 28)
+			reserved = []cnet.IPNet{cnet.MustParseCIDR("10.0.0.0/27"), cnet.MustParseCIDR("10.0.0.40/29")}
+
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 20, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(20))
+			expectNotReserved(v4)
+		})
+
+		It("should not affect addresses assigned by AssignIP", func() {
+			applyPoolWithBlockSize("10.0.0.0/28", true, "all()", 28)
+			reserved = []cnet.IPNet{cnet.MustParseCIDR("10.0.0.0/30")}
+
+			err := ic.AssignIP(context.Background(), AssignIPArgs{IP: cnet.MustParseIP("10.0.0.1"), Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("IPAM pool affinity hint tests", func() {
+		var hostname string
+		hintedPool := cnet.MustParseNetwork("10.1.0.0/30")
+		otherPool := cnet.MustParseNetwork("10.0.0.0/30")
+
+		BeforeEach(func() {
+			bc.Clean()
+			deleteAllPools()
+			applyPoolWithBlockSize(otherPool.String(), true, "all()", 30)
+			applyPoolWithBlockSize(hintedPool.String(), true, "all()", 30)
+			hostname = "host-hint"
+			applyNode(bc, kc, hostname, nil)
+
+			// Annotate the node with the pool hint.
+			kvp, err := bc.Get(context.Background(), model.ResourceKey{Kind: v3.KindNode, Name: hostname}, "")
+			Expect(err).NotTo(HaveOccurred())
+			node := kvp.Value.(*v3.Node)
+			node.Annotations = map[string]string{AnnotationIPPoolAffinityHint: " " + hintedPool.String() + " "}
+			_, err = bc.Update(context.Background(), kvp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			deletePool(otherPool.String())
+			deletePool(hintedPool.String())
+		})
+
+		It("should prefer the hinted pool until it is exhausted", func() {
+			for i := 0; i < 4; i++ {
+				v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(v4).To(HaveLen(1))
+				Expect(hintedPool.Contains(v4[0].IP)).To(BeTrue(), "expected %s in hinted pool", v4[0].IP)
+			}
+
+			By("exhausting the hinted pool and checking the other pool is used")
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 2, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(2))
+			for _, ip := range v4 {
+				Expect(otherPool.Contains(ip.IP)).To(BeTrue(), "expected %s in other pool", ip.IP)
+			}
+		})
+
+		It("should use the pools in their usual order without a hint", func() {
+			kvp, err := bc.Get(context.Background(), model.ResourceKey{Kind: v3.KindNode, Name: hostname}, "")
+			Expect(err).NotTo(HaveOccurred())
+			kvp.Value.(*v3.Node).Annotations = nil
+			_, err = bc.Update(context.Background(), kvp)
+			Expect(err).NotTo(HaveOccurred())
+
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(1))
+			Expect(otherPool.Contains(v4[0].IP)).To(BeTrue())
+		})
+	})
+
+	Describe("IPAM pool zone tests", func() {
+		var hostname string
+		zoneAPool := cnet.MustParseNetwork("10.1.0.0/30")
+		zoneBPool := cnet.MustParseNetwork("10.0.0.0/30")
+
+		BeforeEach(func() {
+			bc.Clean()
+			deleteAllPools()
+			applyPoolWithBlockSize(zoneBPool.String(), true, "all()", 30)
+			applyPoolWithBlockSize(zoneAPool.String(), true, "all()", 30)
+			ipPools.labels = map[string]map[string]string{
+				zoneAPool.String(): {LabelZone: "zone-a"},
+				zoneBPool.String(): {LabelZone: "zone-b"},
+			}
+			hostname = "host-zone"
+		})
+
+		AfterEach(func() {
+			deletePool(zoneAPool.String())
+			deletePool(zoneBPool.String())
+			ipPools.labels = nil
+		})
+
+		It("should prefer the pool in the node's zone until it is exhausted", func() {
+			applyNode(bc, kc, hostname, map[string]string{LabelZone: "zone-a"})
+
+			for i := 0; i < 4; i++ {
+				v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(v4).To(HaveLen(1))
+				Expect(zoneAPool.Contains(v4[0].IP)).To(BeTrue(), "expected %s in zone-a pool", v4[0].IP)
+			}
+
+			By("exhausting the same-zone pool and checking the cross-zone pool is used")
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 2, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(2))
+			for _, ip := range v4 {
+				Expect(zoneBPool.Contains(ip.IP)).To(BeTrue(), "expected %s in zone-b pool", ip.IP)
+			}
+		})
+
+		It("should derive the zone from the deprecated zone label", func() {
+			applyNode(bc, kc, hostname, map[string]string{LabelZoneBeta: "zone-a"})
+
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(1))
+			Expect(zoneAPool.Contains(v4[0].IP)).To(BeTrue())
+		})
+
+		It("should use the pools in their usual order for a node without a zone", func() {
+			applyNode(bc, kc, hostname, nil)
+
+			v4, _, err := ic.AutoAssign(context.Background(), AutoAssignArgs{Num4: 1, Hostname: hostname})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v4).To(HaveLen(1))
+			Expect(zoneBPool.Contains(v4[0].IP)).To(BeTrue())
+		})
+	})
+
 	Describe("Allocation attributes tests", func() {
 		var hostname string
 
@@ -730,6 +1040,68 @@ var _ = testutils.E2eDatastoreDescribe("IPAM tests", testutils.DatastoreAll, fun
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		It("should support querying IP addresses by attribute", func() {
+			By("creating a node", func() {
+				applyNode(bc, kc, "test-host", nil)
+			})
+
+			By("setting up an IP pool", func() {
+				deleteAllPools()
+				applyPool("10.0.0.0/24", true, "")
+			})
+
+			ctx := context.Background()
+			assign := func(handle, namespace string, num int) []cnet.IP {
+				args := AutoAssignArgs{
+					Num4:     num,
+					HandleID: &handle,
+					Attrs:    map[string]string{AttributePod: handle, AttributeNamespace: namespace},
+					Hostname: "test-host",
+				}
+				v4, _, err := ic.AutoAssign(ctx, args)
+				Expect(err).NotTo(HaveOccurred())
+				var ips []cnet.IP
+				for _, ipnet := range v4 {
+					ips = append(ips, cnet.IP{IP: ipnet.IP})
+				}
+				return ips
+			}
+
+			By("assigning IP addresses to pods in two namespaces")
+			nsA1 := assign("pod-a1", "ns-a", 2)
+			nsA2 := assign("pod-a2", "ns-a", 1)
+			assign("pod-b1", "ns-b", 1)
+
+			By("querying the IP addresses in the first namespace")
+			records, err := ic.IPsByAttribute(ctx, AttributeNamespace, "ns-a")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(HaveLen(3))
+			byIP := map[string]AllocationRecord{}
+			for _, r := range records {
+				byIP[r.IP.String()] = r
+				Expect(r.Block.Contains(r.IP.IP)).To(BeTrue())
+				Expect(r.Attrs[AttributeNamespace]).To(Equal("ns-a"))
+			}
+			for _, ip := range nsA1 {
+				Expect(byIP).To(HaveKey(ip.String()))
+				Expect(*byIP[ip.String()].HandleID).To(Equal("pod-a1"))
+				Expect(byIP[ip.String()].Attrs[AttributePod]).To(Equal("pod-a1"))
+			}
+			Expect(*byIP[nsA2[0].String()].HandleID).To(Equal("pod-a2"))
+
+			By("querying a namespace with no IP addresses")
+			records, err = ic.IPsByAttribute(ctx, AttributeNamespace, "ns-c")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(BeEmpty())
+
+			By("releasing the IP addresses of a pod and querying again")
+			Expect(ic.ReleaseByHandle(ctx, "pod-a1")).To(Succeed())
+			records, err = ic.IPsByAttribute(ctx, AttributeNamespace, "ns-a")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].IP.String()).To(Equal(nsA2[0].String()))
+		})
 	})
 
 	Describe("IPAM IP borrowing", func() {
@@ -2252,6 +2624,35 @@ var (
 	v4Pool2CIDR = "20.0.0.0/24"
 )
 
+// Tests for ordering pools by the node affinity hint.
+var _ = DescribeTable("sortPoolsByAffinityHint tests",
+	func(hint string, expectation []string, expectHinted bool) {
+		pools := []v3.IPPool{}
+		for _, name := range []string{"pool1", "pool2", "pool3"} {
+			p := v3.IPPool{Spec: v3.IPPoolSpec{CIDR: fmt.Sprintf("10.%s.0.0/16", name[4:])}}
+			p.Name = name
+			pools = append(pools, p)
+		}
+		node := v3.Node{}
+		if hint != "" {
+			node.Annotations = map[string]string{AnnotationIPPoolAffinityHint: hint}
+		}
+
+		sorted, hinted := sortPoolsByAffinityHint(pools, node)
+		Expect(hinted).To(Equal(expectHinted))
+		names := []string{}
+		for _, p := range sorted {
+			names = append(names, p.Name)
+		}
+		Expect(names).To(Equal(expectation))
+	},
+	Entry("no hint", "", []string{"pool1", "pool2", "pool3"}, false),
+	Entry("hint by name", "pool3", []string{"pool3", "pool1", "pool2"}, true),
+	Entry("hint by CIDR", "10.2.0.1/16", []string{"pool2", "pool1", "pool3"}, true),
+	Entry("multiple hints", "pool3, 10.2.0.0/16", []string{"pool3", "pool2", "pool1"}, true),
+	Entry("unknown pool", "pool4", []string{"pool1", "pool2", "pool3"}, true),
+)
+
 // Tests for determining IPV4 pools to use.
 var _ = DescribeTable("determinePools tests IPV4",
 	func(pool1Enabled, pool2Enabled bool, pool1Selector, pool2Selector string, requestPool1, requestPool2 bool, expectation []string, expectErr bool) {
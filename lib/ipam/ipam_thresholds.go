@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// PoolThresholdCallback is invoked when the utilization of an IP pool crosses the configured
+// threshold.  The utilization is the fraction of the addresses in the pool that are assigned.
+type PoolThresholdCallback func(pool net.IPNet, utilization float64)
+
+// WithPoolUtilizationThreshold configures the IPAM client to invoke the supplied callback when an
+// allocation by AutoAssign or AssignIP takes the utilization of an IP pool to or above the
+// threshold, which is a fraction between 0 and 1.
+//
+// The callback is invoked at most once per crossing: it is not invoked again for the pool until
+// releases by ReleaseIPs or ReleaseByHandle have taken the utilization of the pool back below the
+// threshold.  Since the utilization is determined by listing the allocation blocks, this adds a
+// datastore list to each allocation, and to each release from a pool that is above the threshold.
+// The callback is invoked synchronously from the allocating call.
+func WithPoolUtilizationThreshold(threshold float64, cb PoolThresholdCallback) Option {
+	return func(c *ipamClient) {
+		if cb != nil && threshold > 0 && threshold <= 1 {
+			c.thresholds = &poolThresholdMonitor{
+				threshold: threshold,
+				callback:  cb,
+				above:     map[string]bool{},
+			}
+		}
+	}
+}
+
+// poolThresholdMonitor tracks which pools are above the utilization threshold.
+type poolThresholdMonitor struct {
+	threshold float64
+	callback  PoolThresholdCallback
+
+	// Whether each pool, keyed by CIDR, was above the threshold when it was last checked.
+	lock  sync.Mutex
+	above map[string]bool
+}
+
+// update records the utilization of the pool and returns true if the pool has crossed the
+// threshold from below.  Only an allocation may cross the threshold from below.
+func (m *poolThresholdMonitor) update(cidr string, utilization float64, allocated bool) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if utilization < m.threshold {
+		delete(m.above, cidr)
+		return false
+	}
+	if m.above[cidr] || !allocated {
+		return false
+	}
+	m.above[cidr] = true
+	return true
+}
+
+// isAbove returns whether the pool was above the threshold when it was last checked.
+func (m *poolThresholdMonitor) isAbove(cidr string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.above[cidr]
+}
+
+// checkPoolThresholds checks the utilization of the pools containing the given addresses, which
+// have just been allocated or released, and invokes the threshold callback for any pool that has
+// crossed the threshold.  Releases are only checked for pools that are above the threshold, since
+// they can only re-arm the callback.
+func (c ipamClient) checkPoolThresholds(ctx context.Context, ips []net.IP, allocated bool) {
+	if c.thresholds == nil || len(ips) == 0 {
+		return
+	}
+
+	// Determine the pools containing the addresses.
+	var cidrs []string
+	seen := map[string]bool{}
+	pools := map[int][]v3.IPPool{}
+	for _, ip := range ips {
+		version := ip.Version()
+		if _, ok := pools[version]; !ok {
+			p, err := c.pools.GetEnabledPools(version)
+			if err != nil {
+				log.WithError(err).Warn("Failed to get enabled pools to check utilization")
+				return
+			}
+			pools[version] = p
+		}
+		pool, err := c.blockReaderWriter.getPoolForIP(ip, pools[version])
+		if err != nil || pool == nil || seen[pool.Spec.CIDR] {
+			continue
+		}
+		seen[pool.Spec.CIDR] = true
+		_, poolNet, err := net.ParseCIDR(pool.Spec.CIDR)
+		if err != nil {
+			continue
+		}
+		if allocated || c.thresholds.isAbove(poolNet.String()) {
+			cidrs = append(cidrs, pool.Spec.CIDR)
+		}
+	}
+	if len(cidrs) == 0 {
+		return
+	}
+
+	usage, err := c.GetUtilization(ctx, GetUtilizationArgs{Pools: cidrs})
+	if err != nil {
+		log.WithError(err).Warn("Failed to get pool utilization")
+		return
+	}
+	for _, u := range usage {
+		ones, bits := u.CIDR.Mask.Size()
+		capacity := math.Pow(2, float64(bits-ones))
+		inUse := 0
+		for _, b := range u.Blocks {
+			inUse += b.Capacity - b.Available
+		}
+		utilization := float64(inUse) / capacity
+		cidr := net.IPNet{IPNet: u.CIDR}
+		if c.thresholds.update(cidr.String(), utilization, allocated) {
+			log.WithFields(log.Fields{"pool": cidr, "utilization": utilization}).Warn("IP pool utilization has crossed the threshold")
+			c.thresholds.callback(cidr, utilization)
+		}
+	}
+}
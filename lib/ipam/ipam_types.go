@@ -166,3 +166,137 @@ type BlockArgs struct {
 	// If specified, the attributes of reserved IPv6 addresses in this block.
 	HostReservedAttrIPv6s *HostReservedAttr
 }
+
+// MigrateBlockSizeOptions defines the options for migrating the allocation blocks of an
+// IP pool to a new block size.
+type MigrateBlockSizeOptions struct {
+	// If true, only calculate and return the migration plan.  No changes are made to
+	// the datastore.
+	DryRun bool
+}
+
+// BlockSizeMigrationPlan describes how the allocation blocks and allocated addresses
+// within an IP pool are rearranged when the pool's block size is changed.
+type BlockSizeMigrationPlan struct {
+	// The name of the IP pool being migrated.
+	Pool string
+
+	// The CIDR of the IP pool being migrated.
+	CIDR cnet.IPNet
+
+	// The current and requested block sizes.
+	OldBlockSize int
+	NewBlockSize int
+
+	// The existing allocation blocks that are removed by the migration.
+	OldBlocks []cnet.IPNet
+
+	// The allocation blocks that are created by the migration.  Only blocks that
+	// contain at least one allocated address are created.
+	NewBlocks []PlannedBlock
+
+	// The allocated addresses, and the blocks they move between.
+	Moves []PlannedAddressMove
+}
+
+// PlannedBlock describes an allocation block that is created by a block size migration.
+type PlannedBlock struct {
+	// The block's CIDR.
+	CIDR cnet.IPNet
+
+	// The affinity of the block, in the same format as the allocation block affinity
+	// (e.g. "host:node1").  This is nil if the addresses in the block were previously
+	// in blocks with different affinities.
+	Affinity *string
+
+	// The number of allocated addresses in the block.
+	Allocated int
+}
+
+// SplitBlocksOptions defines the options for splitting the allocation blocks of an IP pool
+// into blocks of a smaller size.
+type SplitBlocksOptions struct {
+	// If true, only calculate and return the split plan.  No changes are made to the
+	// datastore.
+	DryRun bool
+}
+
+// BlockSplitPlan describes how the allocation blocks within an IP pool are split when
+// reducing the pool's block size.  Only empty blocks are split; blocks with allocated
+// addresses are left at their current size until they are drained.
+type BlockSplitPlan struct {
+	// The name of the IP pool being split.
+	Pool string
+
+	// The CIDR of the IP pool being split.
+	CIDR cnet.IPNet
+
+	// The current and requested block sizes.
+	OldBlockSize int
+	NewBlockSize int
+
+	// The empty blocks that are released, and the blocks that replace them.
+	Splits []PlannedBlockSplit
+
+	// The blocks that still contain allocated addresses, and so are left until they
+	// are drained.
+	Draining []PlannedBlock
+
+	// The empty blocks that could not be split, for example because an address was
+	// allocated from the block while it was being split.
+	Failed []BlockSplitFailure
+}
+
+// BlockSplitFailure describes an empty allocation block that could not be split.
+type BlockSplitFailure struct {
+	// The CIDR of the block.
+	OldBlock cnet.IPNet
+
+	// The error splitting the block.
+	Err error
+}
+
+// PlannedBlockSplit describes an empty allocation block that is released by a block split.
+type PlannedBlockSplit struct {
+	// The CIDR of the released block.
+	OldBlock cnet.IPNet
+
+	// The block of the new size that is created in its place.  This is nil if the released
+	// block had no affinity, in which case the whole of its range is freed.
+	NewBlock *cnet.IPNet
+
+	// The affinity of the released block, which is kept by the new block.
+	Affinity *string
+}
+
+// PlannedAddressMove describes how a single allocated address moves from its current
+// block to a new block.
+type PlannedAddressMove struct {
+	// The allocated address.
+	IP cnet.IP
+
+	// The handle used to allocate the address, if any.
+	HandleID *string
+
+	// The CIDR of the block currently containing the address.
+	OldBlock cnet.IPNet
+
+	// The CIDR of the block that will contain the address.
+	NewBlock cnet.IPNet
+}
+
+// AllocationRecord describes an allocated IP address, along with the handle and attributes
+// stored with the allocation.
+type AllocationRecord struct {
+	// The allocated IP address.
+	IP cnet.IP
+
+	// The CIDR of the allocation block containing the IP address.
+	Block cnet.IPNet
+
+	// The handle used for the allocation, if any.
+	HandleID *string
+
+	// The key/value mapping of metadata stored with the allocation.
+	Attrs map[string]string
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"math/big"
+	"net"
+	"sort"
+)
+
+// AggregateCIDRs returns the minimal set of CIDRs that covers exactly the same addresses as the
+// supplied CIDRs, merging CIDRs that are adjacent, overlapping or contained within one another.
+// IPv4 and IPv6 CIDRs are aggregated separately.  The returned CIDRs are masked and are sorted
+// with IPv4 CIDRs first, in ascending address order.  Invalid CIDRs are ignored.
+func AggregateCIDRs(cidrs []IPNet) []IPNet {
+	var v4, v6 []addrRange
+	for _, c := range cidrs {
+		switch c.Version() {
+		case 4:
+			v4 = append(v4, newAddrRange(c, net.IPv4len))
+		case 6:
+			v6 = append(v6, newAddrRange(c, net.IPv6len))
+		}
+	}
+
+	aggregated := []IPNet{}
+	for _, r := range mergeAddrRanges(v4) {
+		aggregated = append(aggregated, r.cidrs(net.IPv4len)...)
+	}
+	for _, r := range mergeAddrRanges(v6) {
+		aggregated = append(aggregated, r.cidrs(net.IPv6len)...)
+	}
+	return aggregated
+}
+
+// addrRange is an inclusive range of addresses.
+type addrRange struct {
+	first, last *big.Int
+}
+
+// newAddrRange returns the range of addresses covered by a CIDR.  The length is the number of
+// bytes in an address of the CIDR's family.
+func newAddrRange(c IPNet, length int) addrRange {
+	ones, _ := c.Mask.Size()
+	if len(c.Mask) != length {
+		// An IPv4 CIDR with a 16-byte mask.
+		ones -= 8 * (len(c.Mask) - length)
+	}
+	first := IPToBigInt(IP{c.IP.Mask(net.CIDRMask(ones, 8*length))})
+	last := new(big.Int).Lsh(big.NewInt(1), uint(8*length-ones))
+	last.Add(last, first).Sub(last, big.NewInt(1))
+	return addrRange{first: first, last: last}
+}
+
+// mergeAddrRanges returns the ranges sorted by address, with overlapping and adjacent ranges
+// merged.
+func mergeAddrRanges(ranges []addrRange) []addrRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].first.Cmp(ranges[j].first) < 0
+	})
+	merged := []addrRange{}
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			next := new(big.Int).Add(prev.last, big.NewInt(1))
+			if r.first.Cmp(next) <= 0 {
+				if r.last.Cmp(prev.last) > 0 {
+					prev.last = r.last
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// cidrs returns the minimal set of CIDRs that covers the range.
+func (r addrRange) cidrs(length int) []IPNet {
+	bits := 8 * length
+	cidrs := []IPNet{}
+	first := new(big.Int).Set(r.first)
+	for first.Cmp(r.last) <= 0 {
+		// The block size is limited both by the alignment of the first address, and by the
+		// number of addresses remaining in the range.
+		hostBits := int(first.TrailingZeroBits())
+		if first.Sign() == 0 {
+			hostBits = bits
+		}
+		remaining := new(big.Int).Sub(r.last, first)
+		if n := remaining.Add(remaining, big.NewInt(1)).BitLen() - 1; n < hostBits {
+			hostBits = n
+		}
+
+		ip := net.IP(first.FillBytes(make([]byte, length)))
+		cidrs = append(cidrs, IPNet{net.IPNet{IP: ip, Mask: net.CIDRMask(bits-hostBits, bits)}})
+		first.Add(first, new(big.Int).Lsh(big.NewInt(1), uint(hostBits)))
+	}
+	return cidrs
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = DescribeTable("AggregateCIDRs",
+	func(cidrs []string, expected []string) {
+		in := []cnet.IPNet{}
+		for _, c := range cidrs {
+			in = append(in, cnet.MustParseCIDR(c))
+		}
+		out := []string{}
+		for _, c := range cnet.AggregateCIDRs(in) {
+			out = append(out, c.String())
+		}
+		Expect(out).To(Equal(expected))
+	},
+	Entry("no CIDRs", []string{}, []string{}),
+	Entry("adjacent halves", []string{"10.0.0.128/25", "10.0.0.0/25"}, []string{"10.0.0.0/24"}),
+	Entry("contained CIDR", []string{"10.0.0.0/16", "10.0.5.0/24"}, []string{"10.0.0.0/16"}),
+	Entry("duplicate CIDRs", []string{"10.0.0.0/24", "10.0.0.0/24"}, []string{"10.0.0.0/24"}),
+	Entry("disjoint CIDRs", []string{"192.168.0.0/24", "10.0.0.0/24"}, []string{"10.0.0.0/24", "192.168.0.0/24"}),
+	Entry("adjacent but unaligned CIDRs",
+		[]string{"10.0.1.0/24", "10.0.2.0/24"},
+		[]string{"10.0.1.0/24", "10.0.2.0/24"},
+	),
+	Entry("adjacent run of CIDRs",
+		[]string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24"},
+		[]string{"10.0.0.0/23", "10.0.2.0/24"},
+	),
+	Entry("unmasked CIDR", []string{"10.0.0.1/25", "10.0.0.200/25"}, []string{"10.0.0.0/24"}),
+	Entry("IPv6 adjacent halves", []string{"fd00::/65", "fd00::8000:0:0:0/65"}, []string{"fd00::/64"}),
+	Entry("mixed families",
+		[]string{"fd00::/65", "10.0.0.0/25", "fd00::8000:0:0:0/65", "10.0.0.128/25", "fd01::/64"},
+		[]string{"10.0.0.0/24", "fd00::/64", "fd01::/64"},
+	),
+	Entry("default routes", []string{"::/0", "fd00::/8", "0.0.0.0/0", "10.0.0.0/8"}, []string{"0.0.0.0/0", "::/0"}),
+)
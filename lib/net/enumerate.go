@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"fmt"
+	"net"
+)
+
+// IPsInCIDROption is an option that may be supplied to IPsInCIDR.
+type IPsInCIDROption func(*ipsInCIDROptions)
+
+type ipsInCIDROptions struct {
+	excludeNetworkAndBroadcast bool
+}
+
+// ExcludeNetworkAndBroadcast excludes the network and broadcast addresses of an IPv4 CIDR from
+// the addresses returned by IPsInCIDR.  A /31 or /32 IPv4 CIDR has no network or broadcast
+// address, so all of its addresses are returned.  This option has no effect on IPv6 CIDRs.
+func ExcludeNetworkAndBroadcast() IPsInCIDROption {
+	return func(o *ipsInCIDROptions) {
+		o.excludeNetworkAndBroadcast = true
+	}
+}
+
+// IPsInCIDR returns the addresses in the CIDR in ascending order.  The IP address in the
+// CIDR does not need to be masked.  As a safety check, an error is returned if the CIDR
+// contains more than limit addresses.
+func IPsInCIDR(n IPNet, limit int, opts ...IPsInCIDROption) ([]IP, error) {
+	var o ipsInCIDROptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	version := n.Version()
+	if version == 0 {
+		return nil, fmt.Errorf("invalid CIDR %s", n)
+	}
+	ones, bits := n.Mask.Size()
+	if bits == 0 {
+		return nil, fmt.Errorf("invalid mask in CIDR %s", n)
+	}
+	hostBits := uint(bits - ones)
+	if hostBits >= 63 || limit < 0 || uint64(1)<<hostBits > uint64(limit) {
+		return nil, fmt.Errorf("CIDR %s contains more than %d addresses", n, limit)
+	}
+
+	base := n.IP.Mask(n.Mask)
+	if version == 4 {
+		base = base.To4()
+	}
+	first, last := uint64(0), uint64(1)<<hostBits-1
+	if o.excludeNetworkAndBroadcast && version == 4 && hostBits > 1 {
+		first, last = first+1, last-1
+	}
+
+	ips := make([]IP, 0, last-first+1)
+	for offset := first; offset <= last; offset++ {
+		ip := make(net.IP, len(base))
+		copy(ip, base)
+
+		// The host bits of the base address are zero, so the offset can simply be OR'd in.
+		for i, off := len(ip)-1, offset; off > 0; i, off = i-1, off>>8 {
+			ip[i] |= byte(off)
+		}
+		ips = append(ips, IP{ip})
+	}
+	return ips, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("IPsInCIDR", func() {
+	DescribeTable("should enumerate the addresses in a CIDR",
+		func(cidr string, exclude bool, expected []string) {
+			var opts []cnet.IPsInCIDROption
+			if exclude {
+				opts = append(opts, cnet.ExcludeNetworkAndBroadcast())
+			}
+			ips, err := cnet.IPsInCIDR(cnet.MustParseCIDR(cidr), 256, opts...)
+			Expect(err).NotTo(HaveOccurred())
+			out := []string{}
+			for _, ip := range ips {
+				out = append(out, ip.String())
+			}
+			Expect(out).To(Equal(expected))
+		},
+		Entry("IPv4 /30", "10.0.0.0/30", false,
+			[]string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"}),
+		Entry("IPv4 /30 excluding network and broadcast", "10.0.0.0/30", true,
+			[]string{"10.0.0.1", "10.0.0.2"}),
+		Entry("unmasked IPv4 /30", "10.0.0.6/30", false,
+			[]string{"10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"}),
+		Entry("IPv4 /30 crossing an octet", "10.0.0.252/30", false,
+			[]string{"10.0.0.252", "10.0.0.253", "10.0.0.254", "10.0.0.255"}),
+		Entry("IPv4 /31 excluding network and broadcast", "10.0.0.0/31", true,
+			[]string{"10.0.0.0", "10.0.0.1"}),
+		Entry("IPv4 /32 excluding network and broadcast", "10.0.0.1/32", true,
+			[]string{"10.0.0.1"}),
+		Entry("IPv6 /126 excluding network and broadcast", "fd00::/126", true,
+			[]string{"fd00::", "fd00::1", "fd00::2", "fd00::3"}),
+	)
+
+	It("should return IPv4 addresses in 4-byte form", func() {
+		ips, err := cnet.IPsInCIDR(cnet.MustParseCIDR("10.0.0.0/30"), 4)
+		Expect(err).NotTo(HaveOccurred())
+		for _, ip := range ips {
+			Expect(ip.IP).To(HaveLen(4))
+		}
+	})
+
+	It("should error if the CIDR exceeds the limit", func() {
+		_, err := cnet.IPsInCIDR(cnet.MustParseCIDR("10.0.0.0/24"), 255)
+		Expect(err).To(HaveOccurred())
+		_, err = cnet.IPsInCIDR(cnet.MustParseCIDR("10.0.0.0/24"), 256)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = cnet.IPsInCIDR(cnet.MustParseCIDR("fd00::/64"), 1<<20)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"math/big"
 	"net"
+	"strings"
 )
 
 // Sub class net.IP so that we can add JSON marshalling and unmarshalling.
@@ -66,6 +67,27 @@ func ParseIP(ip string) *IP {
 	return &IP{addr}
 }
 
+// ParseIPStrict returns an IP from a string, rejecting ambiguous forms that ParseIP may
+// accept.  In particular, dotted-decimal octets (including those embedded in an IPv6 address)
+// with leading zeros are rejected since these may be interpreted as octal by other software.
+// Returns nil if the string is not a valid, unambiguous IP address.
+func ParseIPStrict(ip string) *IP {
+	// Extract any dotted-decimal part of the address - this is either the whole address, or
+	// the trailing part of an IPv6 address.
+	dotted := ip
+	if i := strings.LastIndex(ip, ":"); i >= 0 {
+		dotted = ip[i+1:]
+	}
+	if strings.Contains(dotted, ".") {
+		for _, octet := range strings.Split(dotted, ".") {
+			if len(octet) > 1 && octet[0] == '0' {
+				return nil
+			}
+		}
+	}
+	return ParseIP(ip)
+}
+
 // Version returns the IP version for an IP, or 0 if the IP is not valid.
 func (i IP) Version() int {
 	if i.To4() != nil {
@@ -91,6 +113,13 @@ func (i *IP) Network() *IPNet {
 	return n
 }
 
+// AsCIDR returns the IP address as a full-length CIDR for its address family, that is a /32 for
+// an IPv4 address or a /128 for an IPv6 address.  An IPv4-mapped IPv6 address is treated as an
+// IPv4 address.
+func (i IP) AsCIDR() IPNet {
+	return *i.Network()
+}
+
 // MustParseIP parses the string into an IP.
 func MustParseIP(i string) IP {
 	var ip IP
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = DescribeTable("ParseIPStrict",
+	func(ip string, expected string) {
+		parsed := cnet.ParseIPStrict(ip)
+		if expected == "" {
+			Expect(parsed).To(BeNil())
+		} else {
+			Expect(parsed).NotTo(BeNil())
+			Expect(parsed.String()).To(Equal(expected))
+		}
+	},
+	Entry("IPv4 address", "10.0.0.1", "10.0.0.1"),
+	Entry("IPv4 address with zero octets", "10.0.0.0", "10.0.0.0"),
+	Entry("IPv6 address", "fd00::10", "fd00::10"),
+	Entry("IPv4-mapped IPv6 address", "::ffff:10.0.0.1", "10.0.0.1"),
+	Entry("leading zero in first octet", "010.0.0.1", ""),
+	Entry("leading zero in last octet", "10.0.0.01", ""),
+	Entry("multiple zeros", "10.00.0.1", ""),
+	Entry("leading zero in IPv4-mapped IPv6 address", "::ffff:010.0.0.1", ""),
+	Entry("hex octet", "0x0a.0.0.1", ""),
+	Entry("short form", "10.1", ""),
+	Entry("zone", "fe80::1%eth0", ""),
+	Entry("invalid", "foo", ""),
+)
+
+var _ = DescribeTable("ParseCIDROrIPStrict",
+	func(c string, expectErr bool) {
+		_, _, err := cnet.ParseCIDROrIPStrict(c)
+		if expectErr {
+			Expect(err).To(HaveOccurred())
+		} else {
+			Expect(err).NotTo(HaveOccurred())
+		}
+	},
+	Entry("IP", "10.0.0.1", false),
+	Entry("CIDR", "10.0.0.1/24", false),
+	Entry("IPv6 CIDR", "fd00::1/64", false),
+	Entry("IP with leading zero", "010.0.0.1", true),
+	Entry("CIDR with leading zero", "010.0.0.1/24", true),
+	Entry("invalid mask", "10.0.0.1/33", true),
+)
+
+var _ = DescribeTable("AsCIDR",
+	func(ip string, expected string) {
+		cidr := cnet.MustParseIP(ip).AsCIDR()
+		Expect(cidr.String()).To(Equal(expected))
+		Expect(cidr.Contains(cnet.MustParseIP(ip).IP)).To(BeTrue())
+	},
+	Entry("IPv4 address", "10.0.0.1", "10.0.0.1/32"),
+	Entry("IPv6 address", "fd00::10", "fd00::10/128"),
+	Entry("IPv4-mapped IPv6 address", "::ffff:10.0.0.1", "10.0.0.1/32"),
+)
+
+var _ = Describe("AsCIDR", func() {
+	It("should treat a 16-byte IPv4 address as IPv4", func() {
+		ip := cnet.IP{IP: net.ParseIP("10.0.0.1")}
+		Expect(ip.IP).To(HaveLen(net.IPv6len))
+		cidr := ip.AsCIDR()
+		Expect(cidr.String()).To(Equal("10.0.0.1/32"))
+		Expect(cidr.Version()).To(Equal(4))
+	})
+})
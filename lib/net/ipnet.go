@@ -16,7 +16,10 @@ package net
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
+	"strings"
+	"unicode"
 )
 
 // Sub class net.IPNet so that we can add JSON marshalling and unmarshalling.
@@ -110,6 +113,53 @@ func ParseCIDROrIP(c string) (*IP, *IPNet, error) {
 	return nil, nil, err
 }
 
+// ParseHostIP parses a host address given either as an IP address or as a CIDR with a full
+// length prefix (i.e. /32 or /128), and returns the IP address.  This allows host addresses to
+// be given in either form.  A CIDR with a shorter prefix length is rejected since it does not
+// identify a single host.
+func ParseHostIP(s string) (*IP, error) {
+	ip, cidr, err := ParseCIDROrIP(s)
+	if err != nil {
+		return nil, err
+	}
+	if ones, bits := cidr.Mask.Size(); ones != bits {
+		return nil, fmt.Errorf("%s is not a host address, the prefix length must be /%d", s, bits)
+	}
+	return ip, nil
+}
+
+// ParseCIDROrIPStrict is the same as ParseCIDROrIP, but parses the address using the strict
+// rules of ParseIPStrict.
+func ParseCIDROrIPStrict(c string) (*IP, *IPNet, error) {
+	addr := c
+	if i := strings.Index(c, "/"); i >= 0 {
+		addr = c[:i]
+	}
+	if ParseIPStrict(addr) == nil {
+		return nil, nil, &net.ParseError{Type: "IP address", Text: c}
+	}
+	return ParseCIDROrIP(c)
+}
+
+// ParseCIDRs parses a list of CIDRs separated by commas and/or whitespace, as used by some
+// legacy configuration.  Empty entries are ignored.  The returned networks are masked.  If any
+// entry fails to parse, the error for the first invalid entry is returned, identifying the
+// entry by its index in the list of non-empty entries.
+func ParseCIDRs(s string) ([]IPNet, error) {
+	entries := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	var cidrs []IPNet
+	for i, e := range entries {
+		_, cidr, err := ParseCIDR(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR at index %d: %v", i, err)
+		}
+		cidrs = append(cidrs, *cidr)
+	}
+	return cidrs, nil
+}
+
 // String returns a friendly name for the network.  The standard net package
 // implements String() on the pointer, which means it will not be invoked on a
 // struct type, so we re-implement on the struct type.
@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = DescribeTable("ParseCIDRs",
+	func(s string, expected []string, expectedErr string) {
+		cidrs, err := cnet.ParseCIDRs(s)
+		if expectedErr != "" {
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			Expect(cidrs).To(BeNil())
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		var actual []string
+		for _, c := range cidrs {
+			actual = append(actual, c.String())
+		}
+		Expect(actual).To(Equal(expected))
+	},
+	Entry("empty string", "", nil, ""),
+	Entry("only separators", " , ,\t", nil, ""),
+	Entry("single CIDR", "10.0.0.0/8", []string{"10.0.0.0/8"}, ""),
+	Entry("comma-separated", "10.0.0.0/8,fd00::/64", []string{"10.0.0.0/8", "fd00::/64"}, ""),
+	Entry("whitespace-separated", "10.0.0.0/8 192.168.0.0/16\nfd00::/64", []string{"10.0.0.0/8", "192.168.0.0/16", "fd00::/64"}, ""),
+	Entry("surrounding whitespace", "  10.0.0.0/8 ,  192.168.0.0/16  ", []string{"10.0.0.0/8", "192.168.0.0/16"}, ""),
+	Entry("empty entries", ",10.0.0.0/8,,192.168.0.0/16,", []string{"10.0.0.0/8", "192.168.0.0/16"}, ""),
+	Entry("unmasked CIDR", "10.1.2.3/8", []string{"10.0.0.0/8"}, ""),
+	Entry("invalid first entry", "foo,10.0.0.0/8", nil, "invalid CIDR at index 0"),
+	Entry("invalid entry after valid entries", "10.0.0.0/8, 192.168.0.0/16, 10.0.0.0/33", nil, "invalid CIDR at index 2"),
+	Entry("index ignores empty entries", "10.0.0.0/8,,10.0.0.1", nil, "invalid CIDR at index 1"),
+	Entry("first of several invalid entries", "10.0.0.0/8,bar,baz", nil, "invalid CIDR at index 1"),
+)
+
+var _ = DescribeTable("ParseHostIP",
+	func(s string, expected string, expectedErr string) {
+		ip, err := cnet.ParseHostIP(s)
+		if expectedErr != "" {
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			Expect(ip).To(BeNil())
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ip.String()).To(Equal(expected))
+	},
+	Entry("IPv4 address", "10.0.0.1", "10.0.0.1", ""),
+	Entry("IPv4 host CIDR", "10.0.0.1/32", "10.0.0.1", ""),
+	Entry("IPv6 address", "fd00::1", "fd00::1", ""),
+	Entry("IPv6 host CIDR", "fd00::1/128", "fd00::1", ""),
+	Entry("IPv4 network CIDR", "10.0.0.1/24", "", "prefix length must be /32"),
+	Entry("IPv6 network CIDR", "fd00::1/64", "", "prefix length must be /128"),
+	Entry("invalid address", "10.0.0", "", "invalid"),
+)
@@ -170,6 +170,30 @@ func init() {
 		Entry("unknown protocol xxxXXX", "xxxXXX", "xxxXXX"),
 	)
 
+	// Perform tests of Protocols ParseProtocol method.
+	DescribeTable("NumOrStringProtocols ParseProtocol",
+		func(input string, expected numorstring.Protocol, expectedErr string) {
+			p, err := numorstring.ParseProtocol(input)
+			if expectedErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+				Expect(numorstring.ProtocolFromString(input).Validate()).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p).To(Equal(expected))
+			Expect(p.Validate()).NotTo(HaveOccurred())
+		},
+		Entry("protocol tcp -> TCP", "tcp", numorstring.ProtocolFromString("TCP"), ""),
+		Entry("protocol ICMPv6", "ICMPv6", numorstring.ProtocolFromString("ICMPv6"), ""),
+		Entry("protocol udplite -> UDPLite", "udplite", numorstring.ProtocolFromString("UDPLite"), ""),
+		Entry("protocol 0", "0", numorstring.ProtocolFromInt(0), ""),
+		Entry("protocol 255", "255", numorstring.ProtocolFromInt(255), ""),
+		Entry("protocol 256", "256", numorstring.Protocol{}, "invalid protocol number (256)"),
+		Entry("protocol -1", "-1", numorstring.Protocol{}, "invalid protocol number (-1)"),
+		Entry("unknown protocol xxxXXX", "xxxXXX", numorstring.Protocol{}, "invalid protocol name (xxxXXX)"),
+		Entry("empty protocol", "", numorstring.Protocol{}, "invalid protocol name ()"),
+	)
+
 	// Perform tests of Protocols FromStringV1 method.
 	DescribeTable("NumOrStringProtocols FromStringV1 is lowercase",
 		func(input, expected string) {
@@ -14,7 +14,11 @@
 
 package numorstring
 
-import "strings"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 const (
 	ProtocolUDP     = "UDP"
@@ -77,12 +81,43 @@ func ProtocolFromString(p string) Protocol {
 		}
 	}
 
-	// Unknown protocol - return the value unchanged.  Validation should catch this.
+	// Unknown protocol - return the value unchanged.  Validation should catch this, or use
+	// ParseProtocol to check the value.
 	return Protocol(
 		Uint8OrString{Type: NumOrStringString, StrVal: p},
 	)
 }
 
+// ParseProtocol creates a Protocol struct from a string value, returning an error if the value
+// is not a valid protocol.  The string value may be an IP protocol number in the range 0-255, or
+// one of the well-known protocol names (which are not case sensitive).
+func ParseProtocol(p string) (Protocol, error) {
+	if num, err := strconv.ParseInt(p, 10, 64); err == nil {
+		if num < 0 || num > 255 {
+			return Protocol{}, fmt.Errorf("invalid protocol number (%s): must be in the range 0-255", p)
+		}
+		return ProtocolFromInt(uint8(num)), nil
+	}
+	for _, n := range allProtocolNames {
+		if strings.EqualFold(n, p) {
+			return Protocol(
+				Uint8OrString{Type: NumOrStringString, StrVal: n},
+			), nil
+		}
+	}
+	return Protocol{}, fmt.Errorf("invalid protocol name (%s): must be one of %s", p, strings.Join(allProtocolNames, ", "))
+}
+
+// Validate returns an error if the protocol is not a valid protocol number or one of the
+// well-known protocol names.  See ParseProtocol.
+func (p Protocol) Validate() error {
+	if p.Type == NumOrStringNum {
+		return nil
+	}
+	_, err := ParseProtocol(p.StrVal)
+	return err
+}
+
 // ProtocolFromStringV1 creates a Protocol struct from a string value (for the v1 API)
 func ProtocolFromStringV1(p string) Protocol {
 	return Protocol(
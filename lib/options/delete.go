@@ -15,6 +15,7 @@
 package options
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -29,4 +30,9 @@ type DeleteOptions struct {
 	// If non-nil and supported by the backend (only KDD WorkloadEndpoints at the time of writing),
 	// only delete the resource if its UID matches.
 	UID *types.UID
+
+	// If non-nil and supported by the backend (only the Kubernetes datastore at the time of
+	// writing), determines whether and how garbage collection is performed for the dependents
+	// of the resource.  If nil, the datastore default policy is used.
+	PropagationPolicy *metav1.DeletionPropagation
 }
@@ -21,4 +21,10 @@ type GetOptions struct {
 	// - if set to non zero, then the result is at least as fresh as given rv.
 	// +optional
 	ResourceVersion string
+
+	// ConsistentRead requests that the result reflects the most recently committed state
+	// of the datastore.  When set, ResourceVersion is ignored.  If the datastore does not
+	// support strongly consistent reads then a standard read is performed instead.
+	// +optional
+	ConsistentRead bool
 }
@@ -14,6 +14,8 @@
 
 package options
 
+import "github.com/projectcalico/libcalico-go/lib/watch"
+
 // ListOptions is the query options a List or Watch operation in the Calico API.
 type ListOptions struct {
 	// The namespace of the resource to List or Watch.  If blank, the list or watch wildcards
@@ -36,4 +38,25 @@ type ListOptions struct {
 	// as a mechanism for enumerating endpoints within a Pod (since the name construction for a
 	// Workload endpoint is hierarchically constructed).
 	Prefix bool
+
+	// The types of event to deliver from a Watch.  If empty, all events are delivered.  Error
+	// events are always delivered.  The current state of the matching resources is still
+	// delivered as Added events when the Watch starts without a ResourceVersion, even if Added
+	// events are filtered out, so that the watcher starts from a complete view.  Only used for
+	// Watch.
+	// +optional
+	EventTypes []watch.EventType
+}
+
+// WantsEvent returns true if events of the supplied type should be delivered from a Watch.
+func (o ListOptions) WantsEvent(t watch.EventType) bool {
+	if len(o.EventTypes) == 0 || t == watch.Error {
+		return true
+	}
+	for _, et := range o.EventTypes {
+		if et == t {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"sort"
+	"strings"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+// LabelSet is the set of labels attached to an endpoint.
+type LabelSet map[string]string
+
+// TraceResult describes the outcome of tracing a packet through a set of policies.
+type TraceResult struct {
+	// Matched is true if a rule matched the packet.  If false, none of the remaining fields
+	// are set.
+	Matched bool
+
+	// Namespace and Policy identify the policy containing the matching rule.
+	Namespace string
+	Policy    string
+
+	// RuleIndex is the index of the matching rule within the ingress rules of the policy.
+	RuleIndex int
+
+	// Action is the action of the matching rule.
+	Action apiv3.Action
+}
+
+// Trace determines which ingress rule would match a packet sent from an endpoint with the src
+// labels to an endpoint with the dst labels using the supplied protocol and destination port.
+//
+// Policies are evaluated in order (policies without an order last, ties broken by namespace and
+// then name), considering only those policies whose selector matches the dst labels and that
+// apply to ingress traffic.  The first matching rule with an Allow, Deny or Pass action is
+// returned; Log rules are skipped.  Namespace scoping of policies is not applied, so the caller
+// should only supply policies that are relevant to the destination endpoint.
+//
+// Only the protocol, selector and port match criteria of a rule are evaluated.  Rules that use
+// any other match criteria (nets, named ports, service accounts, namespace selectors, ICMP,
+// HTTP, IP version or source ports) never match.
+//
+// An error is returned if a policy or rule contains a selector that cannot be parsed.
+func Trace(policies []apiv3.NetworkPolicy, src, dst LabelSet, proto numorstring.Protocol, port uint16) (*TraceResult, error) {
+	t := &tracer{
+		src:       src,
+		dst:       dst,
+		proto:     proto,
+		port:      port,
+		selectors: map[string]selector.Selector{},
+	}
+	for _, p := range orderPolicies(policies) {
+		if !appliesToIngress(p) {
+			continue
+		}
+		if selected, err := t.matchesSelector(p.Spec.Selector, dst); err != nil {
+			return nil, err
+		} else if !selected {
+			continue
+		}
+		for i, r := range p.Spec.Ingress {
+			matched, err := t.matchesRule(r)
+			if err != nil {
+				return nil, err
+			}
+			if !matched || r.Action == apiv3.Log {
+				continue
+			}
+			return &TraceResult{
+				Matched:   true,
+				Namespace: p.Namespace,
+				Policy:    p.Name,
+				RuleIndex: i,
+				Action:    r.Action,
+			}, nil
+		}
+	}
+	return &TraceResult{}, nil
+}
+
+// tracer holds the packet being traced along with a cache of the selectors compiled so far.
+type tracer struct {
+	src, dst  LabelSet
+	proto     numorstring.Protocol
+	port      uint16
+	selectors map[string]selector.Selector
+}
+
+// matchesSelector returns whether the labels match the selector.  An empty selector matches
+// all labels.
+func (t *tracer) matchesSelector(sel string, labels LabelSet) (bool, error) {
+	if strings.TrimSpace(sel) == "" {
+		return true, nil
+	}
+	s, ok := t.selectors[sel]
+	if !ok {
+		var err error
+		if s, err = selector.Parse(sel); err != nil {
+			return false, err
+		}
+		t.selectors[sel] = s
+	}
+	return s.Evaluate(labels), nil
+}
+
+// matchesRule returns whether the packet matches the rule.
+func (t *tracer) matchesRule(r apiv3.Rule) (bool, error) {
+	if r.IPVersion != nil || r.ICMP != nil || r.NotICMP != nil || r.HTTP != nil {
+		return false, nil
+	}
+	if r.Protocol != nil && !protocolsEqual(*r.Protocol, t.proto) {
+		return false, nil
+	}
+	if r.NotProtocol != nil && protocolsEqual(*r.NotProtocol, t.proto) {
+		return false, nil
+	}
+	if len(r.Source.Ports) > 0 || len(r.Source.NotPorts) > 0 {
+		return false, nil
+	}
+	if matched, err := t.matchesEntity(r.Source, t.src); err != nil || !matched {
+		return false, err
+	}
+	if matched, err := t.matchesEntity(r.Destination, t.dst); err != nil || !matched {
+		return false, err
+	}
+	if len(r.Destination.Ports) > 0 && !portsContain(r.Destination.Ports, t.port) {
+		return false, nil
+	}
+	if portsContain(r.Destination.NotPorts, t.port) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// matchesEntity returns whether the labels match the selectors of the entity rule.  Ports are
+// checked separately since they only apply to the destination of the packet.
+func (t *tracer) matchesEntity(e apiv3.EntityRule, labels LabelSet) (bool, error) {
+	if len(e.Nets) > 0 || len(e.NotNets) > 0 || e.NamespaceSelector != "" || e.ServiceAccounts != nil {
+		return false, nil
+	}
+	if matched, err := t.matchesSelector(e.Selector, labels); err != nil || !matched {
+		return false, err
+	}
+	if e.NotSelector == "" {
+		return true, nil
+	}
+	matched, err := t.matchesSelector(e.NotSelector, labels)
+	return !matched, err
+}
+
+// portsContain returns whether the port lies within any of the numeric port ranges.  Named ports
+// never contain the port.
+func portsContain(ports []numorstring.Port, port uint16) bool {
+	for _, p := range ports {
+		if p.PortName == "" && p.MinPort <= port && port <= p.MaxPort {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolNumbers maps the well-known protocol names to their IP protocol numbers.
+var protocolNumbers = map[string]uint8{
+	numorstring.ProtocolICMP:    1,
+	numorstring.ProtocolTCP:     6,
+	numorstring.ProtocolUDP:     17,
+	numorstring.ProtocolICMPv6:  58,
+	numorstring.ProtocolSCTP:    132,
+	numorstring.ProtocolUDPLite: 136,
+}
+
+// protocolsEqual returns whether the protocols are the same, allowing for one being specified by
+// name and the other by number.
+func protocolsEqual(a, b numorstring.Protocol) bool {
+	na, oka := protocolNumber(a)
+	nb, okb := protocolNumber(b)
+	if oka && okb {
+		return na == nb
+	}
+	return strings.EqualFold(a.String(), b.String())
+}
+
+// protocolNumber returns the IP protocol number of the protocol, if known.
+func protocolNumber(p numorstring.Protocol) (uint8, bool) {
+	if num, err := p.NumValue(); err == nil {
+		return num, true
+	}
+	num, ok := protocolNumbers[numorstring.ProtocolV3FromProtocolV1(p).StrVal]
+	return num, ok
+}
+
+// appliesToIngress returns whether the policy applies to ingress traffic.  A policy without any
+// types applies to ingress.
+func appliesToIngress(p *apiv3.NetworkPolicy) bool {
+	if len(p.Spec.Types) == 0 {
+		return true
+	}
+	for _, t := range p.Spec.Types {
+		if t == apiv3.PolicyTypeIngress {
+			return true
+		}
+	}
+	return false
+}
+
+// orderPolicies returns the policies in the order in which they are applied.  The supplied
+// slice is not modified.
+func orderPolicies(policies []apiv3.NetworkPolicy) []*apiv3.NetworkPolicy {
+	ordered := make([]*apiv3.NetworkPolicy, len(policies))
+	for i := range policies {
+		ordered[i] = &policies[i]
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		oi, oj := ordered[i].Spec.Order, ordered[j].Spec.Order
+		if (oi == nil) != (oj == nil) {
+			return oj == nil
+		}
+		if oi != nil && *oi != *oj {
+			return *oi < *oj
+		}
+		if ordered[i].Namespace != ordered[j].Namespace {
+			return ordered[i].Namespace < ordered[j].Namespace
+		}
+		return ordered[i].Name < ordered[j].Name
+	})
+	return ordered
+}
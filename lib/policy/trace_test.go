@@ -0,0 +1,123 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	. "github.com/projectcalico/libcalico-go/lib/policy"
+)
+
+var _ = Describe("Trace", func() {
+	tcp := numorstring.ProtocolFromString("TCP")
+	udp := numorstring.ProtocolFromString("UDP")
+	frontend := LabelSet{"app": "frontend"}
+	backend := LabelSet{"app": "backend"}
+
+	order := func(o float64) *float64 {
+		return &o
+	}
+	policy := func(name string, o *float64, rules ...apiv3.Rule) apiv3.NetworkPolicy {
+		p := apiv3.NewNetworkPolicy()
+		p.Name = name
+		p.Namespace = "default"
+		p.Spec.Order = o
+		p.Spec.Selector = "app == 'backend'"
+		p.Spec.Ingress = rules
+		return *p
+	}
+	allowFrontendTCP := apiv3.Rule{
+		Action:      apiv3.Allow,
+		Protocol:    &tcp,
+		Source:      apiv3.EntityRule{Selector: "app == 'frontend'"},
+		Destination: apiv3.EntityRule{Ports: []numorstring.Port{numorstring.SinglePort(8080)}},
+	}
+	denyAll := apiv3.Rule{Action: apiv3.Deny}
+
+	It("should report the first matching allow rule", func() {
+		policies := []apiv3.NetworkPolicy{
+			policy("deny", order(200), denyAll),
+			policy("allow", order(100), apiv3.Rule{Action: apiv3.Log}, allowFrontendTCP),
+		}
+		res, err := Trace(policies, frontend, backend, tcp, 8080)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&TraceResult{
+			Matched:   true,
+			Namespace: "default",
+			Policy:    "allow",
+			RuleIndex: 1,
+			Action:    apiv3.Allow,
+		}))
+	})
+
+	It("should match a protocol specified by number against one specified by name", func() {
+		res, err := Trace([]apiv3.NetworkPolicy{policy("allow", nil, allowFrontendTCP)}, frontend, backend, numorstring.ProtocolFromInt(6), 8080)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Matched).To(BeTrue())
+		Expect(res.Action).To(Equal(apiv3.Allow))
+	})
+
+	It("should fall through to a later deny rule when earlier rules do not match", func() {
+		policies := []apiv3.NetworkPolicy{
+			policy("deny", nil, denyAll),
+			policy("allow", order(100), allowFrontendTCP),
+		}
+		res, err := Trace(policies, frontend, backend, udp, 8080)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&TraceResult{
+			Matched:   true,
+			Namespace: "default",
+			Policy:    "deny",
+			RuleIndex: 0,
+			Action:    apiv3.Deny,
+		}))
+
+		res, err = Trace(policies, frontend, backend, tcp, 9090)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Policy).To(Equal("deny"))
+	})
+
+	It("should report no match when no rule matches", func() {
+		policies := []apiv3.NetworkPolicy{policy("allow", order(100), allowFrontendTCP)}
+		res, err := Trace(policies, backend, backend, tcp, 8080)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(&TraceResult{}))
+	})
+
+	It("should ignore policies that do not select the destination", func() {
+		policies := []apiv3.NetworkPolicy{policy("deny", order(100), denyAll)}
+		res, err := Trace(policies, backend, frontend, tcp, 8080)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Matched).To(BeFalse())
+	})
+
+	It("should ignore egress-only policies", func() {
+		p := policy("deny", order(100), denyAll)
+		p.Spec.Types = []apiv3.PolicyType{apiv3.PolicyTypeEgress}
+		res, err := Trace([]apiv3.NetworkPolicy{p}, frontend, backend, tcp, 8080)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Matched).To(BeFalse())
+	})
+
+	It("should return an error for an invalid selector", func() {
+		p := policy("bad", nil, denyAll)
+		p.Spec.Selector = "app == "
+		_, err := Trace([]apiv3.NetworkPolicy{p}, frontend, backend, tcp, 8080)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"reflect"
+	"sync"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// Defaulter sets the default values of the unset fields of a resource.  The resource is always of
+// the type the Defaulter was registered for.  A Defaulter must be idempotent - defaulting an
+// already defaulted resource must not modify it.
+type Defaulter func(obj interface{})
+
+var (
+	defaultersLock sync.RWMutex
+	defaulters     = map[reflect.Type]Defaulter{}
+)
+
+func init() {
+	RegisterDefaulter(&apiv3.IPPool{}, func(obj interface{}) {
+		defaultIPPool(obj.(*apiv3.IPPool))
+	})
+	RegisterDefaulter(&apiv3.GlobalNetworkPolicy{}, func(obj interface{}) {
+		p := obj.(*apiv3.GlobalNetworkPolicy)
+		defaultPolicyTypes(p.Spec.Ingress, p.Spec.Egress, &p.Spec.Types)
+	})
+	RegisterDefaulter(&apiv3.NetworkPolicy{}, func(obj interface{}) {
+		p := obj.(*apiv3.NetworkPolicy)
+		defaultPolicyTypes(p.Spec.Ingress, p.Spec.Egress, &p.Spec.Types)
+	})
+}
+
+// RegisterDefaulter registers the Defaulter for resources of the same type as obj, which should be
+// a pointer to the resource struct.  Any previously registered Defaulter for the type is replaced.
+func RegisterDefaulter(obj interface{}, d Defaulter) {
+	defaultersLock.Lock()
+	defer defaultersLock.Unlock()
+	defaulters[reflect.TypeOf(obj)] = d
+}
+
+// Default sets the default values of the unset fields of the resource, which should be a pointer
+// to the resource struct.  The resource is modified in place.  Resources for which no Defaulter
+// is registered are not modified.  Defaulting is idempotent.
+func Default(obj interface{}) {
+	defaultersLock.RLock()
+	d := defaulters[reflect.TypeOf(obj)]
+	defaultersLock.RUnlock()
+	if v := reflect.ValueOf(obj); d != nil && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		d(obj)
+	}
+}
+
+// defaultIPPool defaults the node selector, block size and encapsulation modes of an IPPool.  The
+// block size is only defaulted if the CIDR is valid.
+func defaultIPPool(p *apiv3.IPPool) {
+	// If a nodeSelector is not specified, then this IP pool selects all nodes.
+	if p.Spec.NodeSelector == "" {
+		p.Spec.NodeSelector = "all()"
+	}
+
+	// Default the blockSize according to the IP version of the pool.
+	if p.Spec.BlockSize == 0 {
+		if _, cidr, err := cnet.ParseCIDR(p.Spec.CIDR); err == nil {
+			if cidr.Version() == 4 {
+				p.Spec.BlockSize = 26
+			} else {
+				p.Spec.BlockSize = 122
+			}
+		}
+	}
+
+	// Make sure IPIPMode and VXLANMode are defaulted to "Never".
+	if len(p.Spec.IPIPMode) == 0 {
+		p.Spec.IPIPMode = apiv3.IPIPModeNever
+	}
+	if len(p.Spec.VXLANMode) == 0 {
+		p.Spec.VXLANMode = apiv3.VXLANModeNever
+	}
+}
+
+// defaultPolicyTypes defaults the Types field of a policy according to the rules that are
+// present in the policy.
+func defaultPolicyTypes(ingressRules, egressRules []apiv3.Rule, types *[]apiv3.PolicyType) {
+	if len(*types) == 0 {
+		if len(egressRules) == 0 {
+			// Policy has no egress rules, so apply this policy to ingress only.  (Note:
+			// intentionally including the case where the policy also has no ingress
+			// rules.)
+			*types = []apiv3.PolicyType{apiv3.PolicyTypeIngress}
+		} else if len(ingressRules) == 0 {
+			// Policy has egress rules but no ingress rules, so apply this policy to
+			// egress only.
+			*types = []apiv3.PolicyType{apiv3.PolicyTypeEgress}
+		} else {
+			// Policy has both ingress and egress rules, so apply this policy to both
+			// ingress and egress.
+			*types = []apiv3.PolicyType{apiv3.PolicyTypeIngress, apiv3.PolicyTypeEgress}
+		}
+	}
+}
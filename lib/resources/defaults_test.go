@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/resources"
+)
+
+var _ = Describe("Resource defaulting", func() {
+	It("should default an IPPool with unset encapsulation", func() {
+		pool := apiv3.NewIPPool()
+		pool.Name = "pool1"
+		pool.Spec.CIDR = "10.0.0.0/16"
+		resources.Default(pool)
+		Expect(pool.Spec.IPIPMode).To(Equal(apiv3.IPIPModeNever))
+		Expect(pool.Spec.VXLANMode).To(Equal(apiv3.VXLANModeNever))
+		Expect(pool.Spec.NodeSelector).To(Equal("all()"))
+		Expect(pool.Spec.BlockSize).To(Equal(26))
+
+		By("defaulting a second time and checking the pool is unchanged")
+		defaulted := pool.DeepCopy()
+		resources.Default(pool)
+		Expect(pool).To(Equal(defaulted))
+
+		By("defaulting an identical pool and checking the result is the same")
+		other := apiv3.NewIPPool()
+		other.Name = "pool1"
+		other.Spec.CIDR = "10.0.0.0/16"
+		resources.Default(other)
+		Expect(other).To(Equal(defaulted))
+	})
+
+	It("should not override the fields of an IPPool that are set", func() {
+		pool := apiv3.NewIPPool()
+		pool.Spec.CIDR = "fd00::/64"
+		pool.Spec.IPIPMode = apiv3.IPIPModeCrossSubnet
+		pool.Spec.NodeSelector = "has(foo)"
+		resources.Default(pool)
+		Expect(pool.Spec.IPIPMode).To(Equal(apiv3.IPIPMode(apiv3.IPIPModeCrossSubnet)))
+		Expect(pool.Spec.VXLANMode).To(Equal(apiv3.VXLANModeNever))
+		Expect(pool.Spec.NodeSelector).To(Equal("has(foo)"))
+		Expect(pool.Spec.BlockSize).To(Equal(122))
+	})
+
+	It("should not default the block size of an IPPool with an invalid CIDR", func() {
+		pool := apiv3.NewIPPool()
+		pool.Spec.CIDR = "not-a-cidr"
+		resources.Default(pool)
+		Expect(pool.Spec.BlockSize).To(BeZero())
+	})
+
+	It("should default the policy types", func() {
+		gnp := apiv3.NewGlobalNetworkPolicy()
+		gnp.Spec.Egress = []apiv3.Rule{{Action: apiv3.Allow}}
+		resources.Default(gnp)
+		Expect(gnp.Spec.Types).To(Equal([]apiv3.PolicyType{apiv3.PolicyTypeEgress}))
+
+		np := apiv3.NewNetworkPolicy()
+		np.Spec.Ingress = []apiv3.Rule{{Action: apiv3.Allow}}
+		np.Spec.Egress = []apiv3.Rule{{Action: apiv3.Allow}}
+		resources.Default(np)
+		Expect(np.Spec.Types).To(Equal([]apiv3.PolicyType{apiv3.PolicyTypeIngress, apiv3.PolicyTypeEgress}))
+	})
+
+	It("should not modify resources with no registered defaulter", func() {
+		profile := apiv3.NewProfile()
+		resources.Default(profile)
+		Expect(profile).To(Equal(apiv3.NewProfile()))
+
+		var pool *apiv3.IPPool
+		Expect(func() { resources.Default(pool) }).NotTo(Panic())
+	})
+})
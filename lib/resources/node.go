@@ -15,20 +15,59 @@
 package resources
 
 import (
+	"encoding/hex"
+	"net"
+	"strings"
+
 	log "github.com/sirupsen/logrus"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
 )
 
-// FindNodeAddress returns node address of the specified type. Type can be one of
-// CalicoNodeIP, InternalIP or ExternalIP
+// FindNodeAddress returns the first IPv6 node address of the specified type. Type can be one of
+// CalicoNodeIP, InternalIP or ExternalIP.
+//
+// Deprecated: use FindNodeIPv6Address, which makes the address family explicit.
 func FindNodeAddress(node *apiv3.Node, ipType string) (*cnet.IP, *cnet.IPNet) {
+	return FindNodeIPv6Address(node, ipType)
+}
+
+// The readiness of a Node, as returned by FindNodeReadiness.
+const (
+	NodeReady            = "Ready"
+	NodeNotReady         = "NotReady"
+	NodeReadinessUnknown = "Unknown"
+)
+
+// FindNodeReadiness returns the readiness of the Node from the status of its Ready condition:
+// NodeReady if the status is True, NodeNotReady if it is False, and NodeReadinessUnknown if it is
+// Unknown or is not a recognized status.  Returns "" if the Node has no Ready condition.
+func FindNodeReadiness(node *apiv3.Node) string {
+	for _, c := range node.Status.Conditions {
+		if c.Type != apiv3.NodeConditionReady {
+			continue
+		}
+		switch c.Status {
+		case apiv3.ConditionTrue:
+			return NodeReady
+		case apiv3.ConditionFalse:
+			return NodeNotReady
+		}
+		return NodeReadinessUnknown
+	}
+	return ""
+}
+
+// FindNodeIPv6Address returns the first IPv6 address of the given type in the Node spec
+// addresses, and its CIDR if the address was specified with a prefix length.  Addresses of other
+// families are skipped.  Returns nil if there is no such address.
+func FindNodeIPv6Address(node *apiv3.Node, ipType string) (*cnet.IP, *cnet.IPNet) {
 	for _, addr := range node.Spec.Addresses {
 		if addr.Type == ipType {
 			ip, cidr, err := cnet.ParseCIDROrIP(addr.Address)
 			if err == nil {
-				if ip.Version() == 4 {
+				if ip.Version() != 6 {
 					continue
 				}
 				log.WithFields(log.Fields{"ip": ip, "cidr": cidr}).Debug("Parsed IPv6 address")
@@ -58,3 +97,63 @@ func FindNodeIPv4Address(node *apiv3.Node, ipType string) (*cnet.IP, *cnet.IPNet
 	}
 	return nil, nil
 }
+
+// CanonicalizeMAC parses a MAC address and returns it in the canonical lowercase colon-separated
+// form, for example "00:11:22:33:44:55".  In addition to the forms accepted by net.ParseMAC
+// (colon-separated, dash-separated and dotted), a 48-bit MAC address may be given as 12
+// hexadecimal digits without separators.
+func CanonicalizeMAC(s string) (string, error) {
+	if len(s) == 12 && !strings.ContainsAny(s, ":-.") {
+		if b, err := hex.DecodeString(s); err == nil {
+			return net.HardwareAddr(b).String(), nil
+		}
+	}
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		return "", err
+	}
+	return mac.String(), nil
+}
+
+// The per-packet overhead, in bytes, of each encapsulation that may be in use on a Node.
+const (
+	IPIPOverhead      = 20
+	VXLANOverhead     = 50
+	VXLANIPv6Overhead = 70
+	WireguardOverhead = 60
+)
+
+// EffectiveMTU returns the MTU available to workloads on the node, given the MTU of the node's
+// network.  The overhead of each active tunnel is subtracted from the base MTU.  A tunnel is
+// active if the node has a valid tunnel address for it (or, for Wireguard, a public key).
+//
+// The IPIP and VXLAN tunnels are alternatives for any single route, so only the larger of their
+// overheads is subtracted.  Wireguard-encrypted traffic may itself be carried over a tunnel, so its
+// overhead is subtracted in addition to the tunnel overhead.  The returned MTU is never negative.
+func EffectiveMTU(node *apiv3.Node, baseMTU int) int {
+	tunnelOverhead := 0
+	if bgp := node.Spec.BGP; bgp != nil && isIPVersion(bgp.IPv4IPIPTunnelAddr, 4) {
+		tunnelOverhead = IPIPOverhead
+	}
+	if isIPVersion(node.Spec.IPv4VXLANTunnelAddr, 4) && tunnelOverhead < VXLANOverhead {
+		tunnelOverhead = VXLANOverhead
+	}
+	if isIPVersion(node.Spec.IPv6VXLANTunnelAddr, 6) && tunnelOverhead < VXLANIPv6Overhead {
+		tunnelOverhead = VXLANIPv6Overhead
+	}
+
+	mtu := baseMTU - tunnelOverhead
+	if wg := node.Spec.Wireguard; (wg != nil && isIPVersion(wg.InterfaceIPv4Address, 4)) || node.Status.WireguardPublicKey != "" {
+		mtu -= WireguardOverhead
+	}
+	if mtu < 0 {
+		return 0
+	}
+	return mtu
+}
+
+// isIPVersion returns true if the address is a valid IP address of the given version.
+func isIPVersion(addr string, version int) bool {
+	ip := cnet.ParseIP(addr)
+	return ip != nil && ip.Version() == version
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/resources"
+)
+
+var _ = DescribeTable("CanonicalizeMAC",
+	func(input, expected string, expectErr bool) {
+		mac, err := resources.CanonicalizeMAC(input)
+		if expectErr {
+			Expect(err).To(HaveOccurred())
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mac).To(Equal(expected))
+	},
+	Entry("colon form", "00:11:22:aa:bb:cc", "00:11:22:aa:bb:cc", false),
+	Entry("uppercase colon form", "00:11:22:AA:BB:CC", "00:11:22:aa:bb:cc", false),
+	Entry("dash form", "00-11-22-AA-BB-CC", "00:11:22:aa:bb:cc", false),
+	Entry("dotted form", "0011.22aa.bbcc", "00:11:22:aa:bb:cc", false),
+	Entry("form without separators", "001122AABBCC", "00:11:22:aa:bb:cc", false),
+	Entry("empty", "", "", true),
+	Entry("invalid characters", "00:11:22:aa:bb:zz", "", true),
+	Entry("too short", "00:11:22:aa:bb", "", true),
+	Entry("invalid form without separators", "00112233445g", "", true),
+)
+
+var _ = DescribeTable("FindNodeIPv6Address",
+	func(addresses []apiv3.NodeAddress, ipType, expected string) {
+		node := apiv3.NewNode()
+		node.Spec.Addresses = addresses
+		ip, _ := resources.FindNodeIPv6Address(node, ipType)
+		if expected == "" {
+			Expect(ip).To(BeNil())
+			return
+		}
+		Expect(ip).NotTo(BeNil())
+		Expect(ip.String()).To(Equal(expected))
+	},
+	Entry("no addresses", nil, apiv3.InternalIP, ""),
+	Entry("IPv4 address only", []apiv3.NodeAddress{
+		{Address: "10.0.0.1", Type: apiv3.InternalIP},
+	}, apiv3.InternalIP, ""),
+	Entry("IPv4 and IPv6 addresses", []apiv3.NodeAddress{
+		{Address: "10.0.0.1", Type: apiv3.InternalIP},
+		{Address: "fd00::1/64", Type: apiv3.InternalIP},
+	}, apiv3.InternalIP, "fd00::1"),
+	Entry("IPv6 address of another type", []apiv3.NodeAddress{
+		{Address: "fd00::1", Type: apiv3.ExternalIP},
+	}, apiv3.InternalIP, ""),
+	Entry("unparseable address", []apiv3.NodeAddress{
+		{Address: "not-an-ip", Type: apiv3.InternalIP},
+		{Address: "fd00::2", Type: apiv3.InternalIP},
+	}, apiv3.InternalIP, "fd00::2"),
+)
+
+var _ = DescribeTable("FindNodeReadiness",
+	func(conditions []apiv3.NodeCondition, expected string) {
+		node := apiv3.NewNode()
+		node.Status.Conditions = conditions
+		Expect(resources.FindNodeReadiness(node)).To(Equal(expected))
+	},
+	Entry("no conditions", nil, ""),
+	Entry("no Ready condition", []apiv3.NodeCondition{
+		{Type: "MemoryPressure", Status: apiv3.ConditionFalse},
+	}, ""),
+	Entry("ready", []apiv3.NodeCondition{
+		{Type: "MemoryPressure", Status: apiv3.ConditionFalse},
+		{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionTrue},
+	}, resources.NodeReady),
+	Entry("not ready", []apiv3.NodeCondition{
+		{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionFalse, Reason: "KubeletNotReady"},
+	}, resources.NodeNotReady),
+	Entry("unknown", []apiv3.NodeCondition{
+		{Type: apiv3.NodeConditionReady, Status: apiv3.ConditionUnknown, Reason: "NodeStatusUnknown"},
+	}, resources.NodeReadinessUnknown),
+	Entry("an unrecognized status", []apiv3.NodeCondition{
+		{Type: apiv3.NodeConditionReady, Status: "Maybe"},
+	}, resources.NodeReadinessUnknown),
+)
+
+var _ = Describe("EffectiveMTU", func() {
+	DescribeTable("should subtract the overhead of the active encapsulation",
+		func(setNode func(n *apiv3.Node), expected int) {
+			node := apiv3.NewNode()
+			node.Name = "node1"
+			setNode(node)
+			Expect(resources.EffectiveMTU(node, 1500)).To(Equal(expected))
+		},
+		Entry("no encapsulation", func(n *apiv3.Node) {}, 1500),
+		Entry("IPIP", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "192.168.0.1"}
+		}, 1480),
+		Entry("IPv4 VXLAN", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "192.168.0.1"
+		}, 1450),
+		Entry("IPv6 VXLAN", func(n *apiv3.Node) {
+			n.Spec.IPv6VXLANTunnelAddr = "fd00::1"
+		}, 1430),
+		Entry("Wireguard interface address", func(n *apiv3.Node) {
+			n.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "192.168.0.1"}
+		}, 1440),
+		Entry("Wireguard public key", func(n *apiv3.Node) {
+			n.Status.WireguardPublicKey = "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="
+		}, 1440),
+		Entry("IPIP and IPv4 VXLAN", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "192.168.0.1"}
+			n.Spec.IPv4VXLANTunnelAddr = "192.168.1.1"
+		}, 1450),
+		Entry("IPv4 and IPv6 VXLAN", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "192.168.1.1"
+			n.Spec.IPv6VXLANTunnelAddr = "fd00::1"
+		}, 1430),
+		Entry("IPIP and Wireguard", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "192.168.0.1"}
+			n.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "192.168.2.1"}
+		}, 1420),
+		Entry("VXLAN and Wireguard", func(n *apiv3.Node) {
+			n.Spec.IPv4VXLANTunnelAddr = "192.168.1.1"
+			n.Status.WireguardPublicKey = "jlkVyQYooZYzI2wFfNhSZez5eWh44yfq1wKVjLvSXgY="
+		}, 1390),
+		Entry("invalid tunnel addresses", func(n *apiv3.Node) {
+			n.Spec.BGP = &apiv3.NodeBGPSpec{IPv4IPIPTunnelAddr: "fd00::1"}
+			n.Spec.IPv4VXLANTunnelAddr = "not-an-ip"
+			n.Spec.Wireguard = &apiv3.NodeWireguardSpec{InterfaceIPv4Address: "not-an-ip"}
+		}, 1500),
+	)
+
+	It("should not return a negative MTU", func() {
+		node := apiv3.NewNode()
+		node.Spec.IPv4VXLANTunnelAddr = "192.168.1.1"
+		Expect(resources.EffectiveMTU(node, 40)).To(Equal(0))
+	})
+})
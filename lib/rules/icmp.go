@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"fmt"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+const (
+	// AnyCode may be passed as the code to the ICMP rule constructors to match all codes of
+	// the specified type.
+	AnyCode = -1
+
+	maxICMPType = 254
+	maxICMPCode = 255
+)
+
+// ICMPv4 returns an Allow rule matching ICMP traffic of the specified type and code.  Pass
+// AnyCode as the code to match all codes of the specified type.
+func ICMPv4(typeNum, code int) (apiv3.Rule, error) {
+	return icmpRule(4, numorstring.ProtocolICMP, typeNum, code)
+}
+
+// ICMPv6 returns an Allow rule matching ICMPv6 traffic of the specified type and code.  Pass
+// AnyCode as the code to match all codes of the specified type.
+func ICMPv6(typeNum, code int) (apiv3.Rule, error) {
+	return icmpRule(6, numorstring.ProtocolICMPv6, typeNum, code)
+}
+
+// icmpRule returns a rule matching the ICMP protocol and type/code.
+func icmpRule(ipVersion int, protocol string, typeNum, code int) (apiv3.Rule, error) {
+	if typeNum < 0 || typeNum > maxICMPType {
+		return apiv3.Rule{}, fmt.Errorf("invalid %s type %d: must be in the range 0-%d", protocol, typeNum, maxICMPType)
+	}
+	if code != AnyCode && (code < 0 || code > maxICMPCode) {
+		return apiv3.Rule{}, fmt.Errorf("invalid %s code %d: must be in the range 0-%d", protocol, code, maxICMPCode)
+	}
+
+	p := numorstring.ProtocolFromString(protocol)
+	icmp := &apiv3.ICMPFields{Type: &typeNum}
+	if code != AnyCode {
+		icmp.Code = &code
+	}
+	return apiv3.Rule{
+		Action:    apiv3.Allow,
+		IPVersion: &ipVersion,
+		Protocol:  &p,
+		ICMP:      icmp,
+	}, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	"github.com/projectcalico/libcalico-go/lib/rules"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func protocolPtr(p string) *numorstring.Protocol {
+	proto := numorstring.ProtocolFromString(p)
+	return &proto
+}
+
+var _ = Describe("ICMP rule constructors", func() {
+	It("should produce an ICMP echo-request rule", func() {
+		r, err := rules.ICMPv4(8, rules.AnyCode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r).To(Equal(apiv3.Rule{
+			Action:    apiv3.Allow,
+			IPVersion: intPtr(4),
+			Protocol:  protocolPtr(numorstring.ProtocolICMP),
+			ICMP:      &apiv3.ICMPFields{Type: intPtr(8)},
+		}))
+	})
+
+	It("should produce an ICMP destination-unreachable rule", func() {
+		r, err := rules.ICMPv4(3, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r).To(Equal(apiv3.Rule{
+			Action:    apiv3.Allow,
+			IPVersion: intPtr(4),
+			Protocol:  protocolPtr(numorstring.ProtocolICMP),
+			ICMP:      &apiv3.ICMPFields{Type: intPtr(3), Code: intPtr(1)},
+		}))
+	})
+
+	It("should produce an ICMPv6 echo-request rule", func() {
+		r, err := rules.ICMPv6(128, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r).To(Equal(apiv3.Rule{
+			Action:    apiv3.Allow,
+			IPVersion: intPtr(6),
+			Protocol:  protocolPtr(numorstring.ProtocolICMPv6),
+			ICMP:      &apiv3.ICMPFields{Type: intPtr(128), Code: intPtr(0)},
+		}))
+	})
+
+	It("should produce an ICMPv6 destination-unreachable rule", func() {
+		r, err := rules.ICMPv6(1, rules.AnyCode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.ICMP).To(Equal(&apiv3.ICMPFields{Type: intPtr(1)}))
+	})
+
+	DescribeTable("should reject out of range types and codes",
+		func(typeNum, code int) {
+			_, err := rules.ICMPv4(typeNum, code)
+			Expect(err).To(HaveOccurred())
+			_, err = rules.ICMPv6(typeNum, code)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("negative type", -1, 0),
+		Entry("type too large", 255, 0),
+		Entry("negative code", 8, -2),
+		Entry("code too large", 8, 256),
+	)
+})
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"sort"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+)
+
+// OrderStep is the gap between consecutive orders assigned when normalizing policy orders.  The
+// gap leaves room to insert further policies without renumbering.
+const OrderStep = 100
+
+// NormalizeGlobalNetworkPolicyOrders renumbers the orders of the supplied policies to a
+// monotonic sequence in steps of OrderStep, preserving their relative ordering.  Policies with
+// equal orders are ordered by name.  Policies without an order are applied after all other
+// policies and are left without an order.  The policies are updated in place.
+func NormalizeGlobalNetworkPolicyOrders(policies []apiv3.GlobalNetworkPolicy) {
+	entries := make([]orderEntry, len(policies))
+	for i := range policies {
+		entries[i] = orderEntry{order: &policies[i].Spec.Order, name: policies[i].Name}
+	}
+	normalizeOrders(entries)
+}
+
+// NormalizeNetworkPolicyOrders renumbers the orders of the supplied namespaced policies to a
+// monotonic sequence in steps of OrderStep, preserving their relative ordering.  Policies with
+// equal orders are ordered by namespace and then name.  Policies without an order are applied
+// after all other policies and are left without an order.  The policies are updated in place.
+func NormalizeNetworkPolicyOrders(policies []apiv3.NetworkPolicy) {
+	entries := make([]orderEntry, len(policies))
+	for i := range policies {
+		entries[i] = orderEntry{order: &policies[i].Spec.Order, namespace: policies[i].Namespace, name: policies[i].Name}
+	}
+	normalizeOrders(entries)
+}
+
+// orderEntry references the order field of a policy along with the fields used to break ties.
+type orderEntry struct {
+	order     **float64
+	namespace string
+	name      string
+}
+
+// normalizeOrders renumbers the referenced orders.
+func normalizeOrders(entries []orderEntry) {
+	ordered := make([]orderEntry, 0, len(entries))
+	for _, e := range entries {
+		if *e.order != nil {
+			ordered = append(ordered, e)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		oi, oj := **ordered[i].order, **ordered[j].order
+		if oi != oj {
+			return oi < oj
+		}
+		if ordered[i].namespace != ordered[j].namespace {
+			return ordered[i].namespace < ordered[j].namespace
+		}
+		return ordered[i].name < ordered[j].name
+	})
+	for i, e := range ordered {
+		order := float64((i + 1) * OrderStep)
+		*e.order = &order
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/rules"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func gnp(name string, order *float64) apiv3.GlobalNetworkPolicy {
+	p := apiv3.NewGlobalNetworkPolicy()
+	p.Name = name
+	p.Spec.Order = order
+	return *p
+}
+
+func np(namespace, name string, order *float64) apiv3.NetworkPolicy {
+	p := apiv3.NewNetworkPolicy()
+	p.Namespace = namespace
+	p.Name = name
+	p.Spec.Order = order
+	return *p
+}
+
+var _ = Describe("Policy order normalization", func() {
+	It("should renumber duplicate and fractional orders", func() {
+		policies := []apiv3.GlobalNetworkPolicy{
+			gnp("d", floatPtr(10)),
+			gnp("c", floatPtr(2.5)),
+			gnp("b", floatPtr(10)),
+			gnp("e", nil),
+			gnp("a", floatPtr(2.25)),
+			gnp("f", floatPtr(-1)),
+		}
+		rules.NormalizeGlobalNetworkPolicyOrders(policies)
+
+		// The slice order is unchanged, only the orders are updated.
+		orders := map[string]*float64{}
+		for _, p := range policies {
+			orders[p.Name] = p.Spec.Order
+		}
+		Expect(orders).To(Equal(map[string]*float64{
+			"f": floatPtr(100),
+			"a": floatPtr(200),
+			"c": floatPtr(300),
+			"b": floatPtr(400),
+			"d": floatPtr(500),
+			"e": nil,
+		}))
+	})
+
+	It("should produce the same result when renormalizing", func() {
+		policies := []apiv3.GlobalNetworkPolicy{
+			gnp("b", floatPtr(1)),
+			gnp("a", floatPtr(1)),
+		}
+		rules.NormalizeGlobalNetworkPolicyOrders(policies)
+		Expect(policies[0].Spec.Order).To(Equal(floatPtr(200)))
+		Expect(policies[1].Spec.Order).To(Equal(floatPtr(100)))
+		rules.NormalizeGlobalNetworkPolicyOrders(policies)
+		Expect(policies[0].Spec.Order).To(Equal(floatPtr(200)))
+		Expect(policies[1].Spec.Order).To(Equal(floatPtr(100)))
+	})
+
+	It("should break ties on namespace and then name for namespaced policies", func() {
+		policies := []apiv3.NetworkPolicy{
+			np("ns2", "a", floatPtr(5)),
+			np("ns1", "b", floatPtr(5)),
+			np("ns1", "a", floatPtr(5)),
+			np("ns1", "z", floatPtr(0.5)),
+		}
+		rules.NormalizeNetworkPolicyOrders(policies)
+		Expect(policies[0].Spec.Order).To(Equal(floatPtr(400)))
+		Expect(policies[1].Spec.Order).To(Equal(floatPtr(300)))
+		Expect(policies[2].Spec.Order).To(Equal(floatPtr(200)))
+		Expect(policies[3].Spec.Order).To(Equal(floatPtr(100)))
+	})
+
+	It("should handle an empty set of policies", func() {
+		rules.NormalizeGlobalNetworkPolicyOrders(nil)
+	})
+})
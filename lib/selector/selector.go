@@ -14,7 +14,41 @@
 
 package selector
 
-import "github.com/projectcalico/libcalico-go/lib/selector/parser"
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+)
+
+// DefaultMaxLength is the default maximum length of a selector accepted by Parse.  This is
+// far larger than any reasonable selector, but protects against compiling selectors that are
+// large enough to cause excessive memory usage.
+const DefaultMaxLength = 64 * 1024
+
+// maxLength is the maximum length of a selector accepted by Parse.  Accessed atomically.
+var maxLength int64 = DefaultMaxLength
+
+// SetMaxLength sets the maximum length of a selector accepted by Parse.  A value of zero or less
+// removes the limit.
+func SetMaxLength(n int) {
+	atomic.StoreInt64(&maxLength, int64(n))
+}
+
+// MaxLength returns the maximum length of a selector accepted by Parse, or zero or less if
+// there is no limit.
+func MaxLength() int {
+	return int(atomic.LoadInt64(&maxLength))
+}
+
+// The prefixes under which the labels of the namespace and the service account of an endpoint are
+// projected into the labels of the endpoint, so that a selector may match against them.  For
+// example, the namespace label "env" is projected as "pcns.env".
+const (
+	NamespaceLabelPrefix      = "pcns."
+	ServiceAccountLabelPrefix = "pcsa."
+)
 
 // Selector represents a label selector.
 type Selector interface {
@@ -32,7 +66,40 @@ type Selector interface {
 	UniqueID() string
 }
 
-// Parse a string representation of a selector expression into a Selector.
+// Parse a string representation of a selector expression into a Selector.  An error is returned
+// if the selector is longer than the configured maximum length (see SetMaxLength).
 func Parse(selector string) (sel Selector, err error) {
+	if max := MaxLength(); max > 0 && len(selector) > max {
+		return nil, fmt.Errorf("selector is too long: %d characters exceeds the maximum of %d", len(selector), max)
+	}
 	return parser.Parse(selector)
 }
+
+// MatchProjected evaluates the selector against the labels of an endpoint combined with the labels
+// of its namespace and service account, projected under NamespaceLabelPrefix and
+// ServiceAccountLabelPrefix respectively.  The labels of the endpoint take precedence over a
+// projected label with the same name.  Any of the label maps may be nil.
+func MatchProjected(sel Selector, labels, nsLabels, saLabels map[string]string) bool {
+	return sel.EvaluateLabels(projectedLabels{labels: labels, nsLabels: nsLabels, saLabels: saLabels})
+}
+
+// projectedLabels implements parser.Labels for the combined labels of an endpoint, its namespace
+// and its service account, without copying the labels into a single map.
+type projectedLabels struct {
+	labels, nsLabels, saLabels map[string]string
+}
+
+func (l projectedLabels) Get(labelName string) (string, bool) {
+	if value, ok := l.labels[labelName]; ok {
+		return value, true
+	}
+	if strings.HasPrefix(labelName, NamespaceLabelPrefix) {
+		value, ok := l.nsLabels[strings.TrimPrefix(labelName, NamespaceLabelPrefix)]
+		return value, ok
+	}
+	if strings.HasPrefix(labelName, ServiceAccountLabelPrefix) {
+		value, ok := l.saLabels[strings.TrimPrefix(labelName, ServiceAccountLabelPrefix)]
+		return value, ok
+	}
+	return "", false
+}
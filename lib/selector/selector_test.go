@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selector_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+var _ = Describe("Selector length limit", func() {
+	// longSelector returns a valid selector of exactly the requested length.
+	longSelector := func(length int) string {
+		prefix := "a == '"
+		return prefix + strings.Repeat("x", length-len(prefix)-1) + "'"
+	}
+
+	AfterEach(func() {
+		selector.SetMaxLength(selector.DefaultMaxLength)
+	})
+
+	It("should default to DefaultMaxLength", func() {
+		Expect(selector.MaxLength()).To(Equal(selector.DefaultMaxLength))
+		_, err := selector.Parse(longSelector(selector.DefaultMaxLength))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = selector.Parse(longSelector(selector.DefaultMaxLength + 1))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should accept a selector at the configured limit", func() {
+		selector.SetMaxLength(100)
+		sel, err := selector.Parse(longSelector(100))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sel.Evaluate(map[string]string{"a": strings.Repeat("x", 93)})).To(BeTrue())
+	})
+
+	It("should reject a selector over the configured limit", func() {
+		selector.SetMaxLength(100)
+		_, err := selector.Parse(longSelector(101))
+		Expect(err).To(MatchError(ContainSubstring("exceeds the maximum of 100")))
+	})
+
+	It("should not limit the length if the limit is disabled", func() {
+		selector.SetMaxLength(0)
+		_, err := selector.Parse(longSelector(selector.DefaultMaxLength + 1))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = DescribeTable("MatchProjected",
+	func(sel string, expected bool) {
+		labels := map[string]string{"app": "frontend", "pcns.shadowed": "endpoint"}
+		nsLabels := map[string]string{"env": "prod", "shadowed": "namespace", "team": "a"}
+		saLabels := map[string]string{"role": "reader"}
+		s, err := selector.Parse(sel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.MatchProjected(s, labels, nsLabels, saLabels)).To(Equal(expected))
+	},
+	Entry("an endpoint label", "app == 'frontend'", true),
+	Entry("a namespace label", "pcns.env == 'prod'", true),
+	Entry("a mismatched namespace label", "pcns.env == 'dev'", false),
+	Entry("a service account label", "pcsa.role == 'reader'", true),
+	Entry("a combination of labels", "app == 'frontend' && pcns.team == 'a' && has(pcsa.role)", true),
+	Entry("an unprefixed namespace label", "env == 'prod'", false),
+	Entry("a namespace label under the service account prefix", "has(pcsa.env)", false),
+	Entry("a missing projected label", "!has(pcns.missing)", true),
+	Entry("an endpoint label shadowing a projected label", "pcns.shadowed == 'endpoint'", true),
+)
+
+var _ = Describe("MatchProjected with nil labels", func() {
+	It("should match only selectors that require no labels", func() {
+		s, err := selector.Parse("!has(pcns.env)")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.MatchProjected(s, nil, nil, nil)).To(BeTrue())
+		s, err = selector.Parse("pcsa.role == 'reader'")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.MatchProjected(s, nil, nil, nil)).To(BeFalse())
+	})
+})
@@ -0,0 +1,172 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	api "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+// anyNode is the name used for an unlabelled node when checking BGPPeers without a set of nodes.
+const anyNode = ""
+
+// ValidateBGPPeers checks a set of BGPPeer resources for conflicting definitions.  Two BGPPeers
+// conflict if they both configure a peering from the same node to the same peer, where the peer
+// is either identified by its PeerIP, or is a node selected by a PeerSelector.
+//
+// The supplied nodes are used to evaluate the NodeSelector and PeerSelector of each BGPPeer.  A
+// node that is named by a BGPPeer but not included in the nodes is treated as having no labels.
+// If no nodes are supplied, BGPPeers that apply to all nodes are checked against an unlabelled
+// node, so exact duplicates are detected even without the set of nodes.
+//
+// The returned error is an ErrorValidation with a field for each conflict.
+func ValidateBGPPeers(peers []api.BGPPeer, nodes []api.Node) error {
+	// Sort the peers and nodes by name so that conflicts are reported deterministically.
+	peers = append([]api.BGPPeer(nil), peers...)
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	nodeLabels := map[string]map[string]string{}
+	if len(nodes) == 0 {
+		nodeLabels[anyNode] = nil
+	}
+	for _, n := range nodes {
+		nodeLabels[n.Name] = n.Labels
+	}
+	for _, p := range peers {
+		if _, ok := nodeLabels[p.Spec.Node]; p.Spec.Node != "" && !ok {
+			nodeLabels[p.Spec.Node] = nil
+		}
+	}
+	nodeNames := make([]string, 0, len(nodeLabels))
+	for name := range nodeLabels {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	// Parse the selectors up front.
+	nodeSelectors := make([]selector.Selector, len(peers))
+	peerSelectors := make([]selector.Selector, len(peers))
+	for i, p := range peers {
+		var err error
+		if p.Spec.NodeSelector != "" {
+			if nodeSelectors[i], err = selector.Parse(p.Spec.NodeSelector); err != nil {
+				return invalidBGPPeer(p, "NodeSelector", p.Spec.NodeSelector, err.Error())
+			}
+		}
+		if p.Spec.PeerSelector != "" {
+			if peerSelectors[i], err = selector.Parse(p.Spec.PeerSelector); err != nil {
+				return invalidBGPPeer(p, "PeerSelector", p.Spec.PeerSelector, err.Error())
+			}
+		}
+	}
+
+	// For each node, determine the peerings configured by each BGPPeer and check that no two
+	// BGPPeers configure the same peering.  Each pair of BGPPeers is only reported once.
+	verr := errors.ErrorValidation{}
+	reported := map[[2]string]bool{}
+	for _, node := range nodeNames {
+		owners := map[string]int{}
+		for i, p := range peers {
+			if !selectsNode(p, nodeSelectors[i], node, nodeLabels[node]) {
+				continue
+			}
+			for _, target := range peerTargets(p, peerSelectors[i], node, nodeNames, nodeLabels) {
+				j, ok := owners[target]
+				if !ok {
+					owners[target] = i
+					continue
+				}
+				pair := [2]string{peers[j].Name, p.Name}
+				if reported[pair] {
+					continue
+				}
+				reported[pair] = true
+				field := "PeerIP"
+				value := p.Spec.PeerIP
+				if p.Spec.PeerSelector != "" {
+					field, value = "PeerSelector", p.Spec.PeerSelector
+				}
+				where := "on node " + node
+				if node == anyNode {
+					where = "on all nodes"
+				}
+				verr.ErroredFields = append(verr.ErroredFields, errors.ErroredField{
+					Name:   fmt.Sprintf("BGPPeer(%s).Spec.%s", p.Name, field),
+					Value:  value,
+					Reason: fmt.Sprintf("conflicts with BGPPeer(%s) %s", peers[j].Name, where),
+				})
+			}
+		}
+	}
+	if len(verr.ErroredFields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// selectsNode returns true if the BGPPeer configures peerings on the named node.
+func selectsNode(p api.BGPPeer, nodeSelector selector.Selector, node string, labels map[string]string) bool {
+	if p.Spec.Node != "" {
+		return p.Spec.Node == node
+	}
+	if nodeSelector != nil {
+		return nodeSelector.Evaluate(labels)
+	}
+	// No node or node selector means the peer applies to all nodes.
+	return true
+}
+
+// peerTargets returns identifiers for the peers that the BGPPeer configures on the named node.
+func peerTargets(p api.BGPPeer, peerSelector selector.Selector, node string, nodeNames []string, nodeLabels map[string]map[string]string) []string {
+	if peerSelector != nil {
+		var targets []string
+		for _, remote := range nodeNames {
+			if remote != node && remote != anyNode && peerSelector.Evaluate(nodeLabels[remote]) {
+				targets = append(targets, "node:"+remote)
+			}
+		}
+		return targets
+	}
+	if p.Spec.PeerIP == "" {
+		return nil
+	}
+	return []string{"ip:" + normalizePeerIP(p.Spec.PeerIP)}
+}
+
+// normalizePeerIP returns the peer IP, with optional port, in a canonical form so that
+// equivalent addresses compare equal.
+func normalizePeerIP(peerIP string) string {
+	if ip := net.ParseIP(peerIP); ip != nil {
+		return ip.String()
+	}
+	if host, port, err := net.SplitHostPort(peerIP); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			return net.JoinHostPort(ip.String(), port)
+		}
+	}
+	return peerIP
+}
+
+func invalidBGPPeer(p api.BGPPeer, field, value, reason string) error {
+	return errors.ErrorValidation{ErroredFields: []errors.ErroredField{{
+		Name:   fmt.Sprintf("BGPPeer(%s).Spec.%s", p.Name, field),
+		Value:  value,
+		Reason: reason,
+	}}}
+}
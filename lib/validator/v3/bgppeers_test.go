@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	api "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/errors"
+	v3 "github.com/projectcalico/libcalico-go/lib/validator/v3"
+)
+
+var _ = Describe("BGPPeer conflict validation", func() {
+	peer := func(name string, spec api.BGPPeerSpec) api.BGPPeer {
+		p := api.NewBGPPeer()
+		p.Name = name
+		p.Spec = spec
+		return *p
+	}
+	node := func(name string, labels map[string]string) api.Node {
+		n := api.NewNode()
+		n.Name = name
+		n.Labels = labels
+		return *n
+	}
+	// conflicts returns the names of the errored fields.
+	conflicts := func(err error) []string {
+		Expect(err).To(BeAssignableToTypeOf(errors.ErrorValidation{}))
+		names := []string{}
+		for _, f := range err.(errors.ErrorValidation).ErroredFields {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+	nodes := []api.Node{
+		node("node1", map[string]string{"rack": "a", "rr": "true"}),
+		node("node2", map[string]string{"rack": "a"}),
+		node("node3", map[string]string{"rack": "b"}),
+	}
+
+	It("should accept peers that do not conflict", func() {
+		err := v3.ValidateBGPPeers([]api.BGPPeer{
+			peer("peer1", api.BGPPeerSpec{Node: "node1", PeerIP: "10.0.0.1"}),
+			peer("peer2", api.BGPPeerSpec{Node: "node2", PeerIP: "10.0.0.1"}),
+			peer("peer3", api.BGPPeerSpec{Node: "node1", PeerIP: "10.0.0.2"}),
+			peer("peer4", api.BGPPeerSpec{NodeSelector: "rack == 'a'", PeerIP: "10.0.0.3"}),
+			peer("peer5", api.BGPPeerSpec{NodeSelector: "rack == 'b'", PeerIP: "10.0.0.3"}),
+			peer("peer6", api.BGPPeerSpec{NodeSelector: "rack == 'a'", PeerIP: "10.0.0.4:179"}),
+			peer("peer7", api.BGPPeerSpec{NodeSelector: "rack == 'a'", PeerIP: "10.0.0.4:1790"}),
+		}, nodes)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should detect exact duplicates", func() {
+		err := v3.ValidateBGPPeers([]api.BGPPeer{
+			peer("peer2", api.BGPPeerSpec{Node: "node1", PeerIP: "fd00::0001"}),
+			peer("peer1", api.BGPPeerSpec{Node: "node1", PeerIP: "fd00::1"}),
+		}, nodes)
+		Expect(err).To(HaveOccurred())
+		Expect(conflicts(err)).To(Equal([]string{"BGPPeer(peer2).Spec.PeerIP"}))
+		Expect(err.Error()).To(ContainSubstring("conflicts with BGPPeer(peer1) on node node1"))
+	})
+
+	It("should detect exact duplicates without any nodes", func() {
+		err := v3.ValidateBGPPeers([]api.BGPPeer{
+			peer("peer1", api.BGPPeerSpec{PeerIP: "10.0.0.1", ASNumber: 64512}),
+			peer("peer2", api.BGPPeerSpec{PeerIP: "10.0.0.1", ASNumber: 64513}),
+			peer("peer3", api.BGPPeerSpec{Node: "node1", PeerIP: "10.0.0.2"}),
+			peer("peer4", api.BGPPeerSpec{Node: "node1", PeerIP: "10.0.0.2"}),
+		}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(conflicts(err)).To(Equal([]string{
+			"BGPPeer(peer2).Spec.PeerIP",
+			"BGPPeer(peer4).Spec.PeerIP",
+		}))
+		Expect(err.Error()).To(ContainSubstring("conflicts with BGPPeer(peer1) on all nodes"))
+	})
+
+	It("should detect overlapping node selectors", func() {
+		err := v3.ValidateBGPPeers([]api.BGPPeer{
+			peer("peer1", api.BGPPeerSpec{NodeSelector: "rack == 'a'", PeerIP: "10.0.0.1"}),
+			peer("peer2", api.BGPPeerSpec{NodeSelector: "rr == 'true'", PeerIP: "10.0.0.1"}),
+		}, nodes)
+		Expect(err).To(HaveOccurred())
+		Expect(conflicts(err)).To(Equal([]string{"BGPPeer(peer2).Spec.PeerIP"}))
+		Expect(err.Error()).To(ContainSubstring("on node node1"))
+	})
+
+	It("should detect a node-specific peer that overlaps a global peer", func() {
+		err := v3.ValidateBGPPeers([]api.BGPPeer{
+			peer("global", api.BGPPeerSpec{PeerIP: "10.0.0.1"}),
+			peer("specific", api.BGPPeerSpec{Node: "node3", PeerIP: "10.0.0.1"}),
+		}, nodes)
+		Expect(err).To(HaveOccurred())
+		Expect(conflicts(err)).To(Equal([]string{"BGPPeer(specific).Spec.PeerIP"}))
+	})
+
+	It("should detect overlapping peer selectors", func() {
+		err := v3.ValidateBGPPeers([]api.BGPPeer{
+			peer("peer1", api.BGPPeerSpec{NodeSelector: "rack == 'b'", PeerSelector: "rr == 'true'"}),
+			peer("peer2", api.BGPPeerSpec{NodeSelector: "rack == 'b'", PeerSelector: "rack == 'a'"}),
+			peer("peer3", api.BGPPeerSpec{NodeSelector: "rack == 'a'", PeerSelector: "rack == 'b'"}),
+		}, nodes)
+		Expect(err).To(HaveOccurred())
+		Expect(conflicts(err)).To(Equal([]string{"BGPPeer(peer2).Spec.PeerSelector"}))
+		Expect(err.Error()).To(ContainSubstring("on node node3"))
+	})
+
+	It("should reject invalid selectors", func() {
+		err := v3.ValidateBGPPeers([]api.BGPPeer{
+			peer("peer1", api.BGPPeerSpec{NodeSelector: "rack ==", PeerIP: "10.0.0.1"}),
+		}, nodes)
+		Expect(err).To(HaveOccurred())
+		Expect(conflicts(err)).To(Equal([]string{"BGPPeer(peer1).Spec.NodeSelector"}))
+	})
+})
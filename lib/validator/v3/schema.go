@@ -0,0 +1,246 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/go-openapi/spec"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/kube-openapi/pkg/common"
+
+	api "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+// intOrStringDefinitions are the definitions whose generated schema describes the Go structure
+// of the type rather than its JSON form, which is either an integer or a string.
+var intOrStringDefinitions = map[string]bool{
+	"github.com/projectcalico/libcalico-go/lib/numorstring.Port":          true,
+	"github.com/projectcalico/libcalico-go/lib/numorstring.Protocol":      true,
+	"github.com/projectcalico/libcalico-go/lib/numorstring.Uint8OrString": true,
+}
+
+var (
+	definitionsOnce sync.Once
+	definitions     map[string]common.OpenAPIDefinition
+)
+
+// openAPIDefinitions returns the generated OpenAPI definitions, indexed by the full name of
+// the Go type.
+func openAPIDefinitions() map[string]common.OpenAPIDefinition {
+	definitionsOnce.Do(func() {
+		definitions = api.GetOpenAPIDefinitions(func(path string) spec.Ref {
+			return spec.MustCreateRef(path)
+		})
+	})
+	return definitions
+}
+
+// ValidateAgainstSchema validates the JSON form of the supplied resource against its generated
+// OpenAPI schema, returning an ErrorValidation listing the path of each field that has the
+// wrong type or is missing.  It checks the form of the resource only, and is intended to be
+// called before Validate, which performs the semantic validation.
+//
+// The resource may be a typed resource (e.g. *api.NetworkPolicy), or the unstructured form of
+// a resource (a *unstructured.Unstructured or a map[string]interface{}) in which case the
+// schema is chosen from its kind.
+func ValidateAgainstSchema(obj interface{}) error {
+	var name string
+	var value interface{}
+	switch o := obj.(type) {
+	case *unstructured.Unstructured:
+		return ValidateAgainstSchema(o.Object)
+	case map[string]interface{}:
+		kind, _ := o["kind"].(string)
+		if kind == "" {
+			return errors.ErrorValidation{
+				ErroredFields: []errors.ErroredField{{Name: "kind", Reason: "kind must be specified"}},
+			}
+		}
+		name = reflect.TypeOf(api.NetworkPolicy{}).PkgPath() + "." + kind
+		value = o
+	default:
+		t := reflect.TypeOf(obj)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil {
+			return fmt.Errorf("cannot validate a nil resource against its schema")
+		}
+		name = t.PkgPath() + "." + t.Name()
+
+		// Convert the resource to its JSON form, which is what the schema describes.
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &value); err != nil {
+			return err
+		}
+	}
+
+	def, ok := openAPIDefinitions()[name]
+	if !ok {
+		return fmt.Errorf("no OpenAPI schema for %s", name)
+	}
+	var verr errors.ErrorValidation
+	validateSchemaValue(&verr, "", def.Schema, value)
+	if len(verr.ErroredFields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// validateSchemaValue validates a single value against its schema, appending an ErroredField
+// to verr for each field that does not conform.
+func validateSchemaValue(verr *errors.ErrorValidation, path string, s spec.Schema, value interface{}) {
+	if value == nil {
+		return
+	}
+	if ref := s.Ref.String(); ref != "" {
+		if intOrStringDefinitions[ref] {
+			if _, ok := value.(string); !ok && !isInteger(value) {
+				addSchemaError(verr, path, value, "integer or string")
+			}
+			return
+		}
+		def, ok := openAPIDefinitions()[ref]
+		if !ok {
+			// Schemas that aren't generated here (for example, the Kubernetes metadata)
+			// are not validated.
+			return
+		}
+		s = def.Schema
+	}
+	if len(s.Type) == 0 {
+		return
+	}
+
+	switch s.Type[0] {
+	case "object":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			addSchemaError(verr, path, value, "object")
+			return
+		}
+		for _, req := range s.Required {
+			if _, ok := m[req]; !ok {
+				verr.ErroredFields = append(verr.ErroredFields, errors.ErroredField{
+					Name:   fieldPath(path, req),
+					Reason: "required field is missing",
+				})
+			}
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := s.Properties[k]; ok {
+				validateSchemaValue(verr, fieldPath(path, k), prop, m[k])
+			} else if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+				validateSchemaValue(verr, fieldPath(path, k), *s.AdditionalProperties.Schema, m[k])
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			addSchemaError(verr, path, value, "array")
+			return
+		}
+		if s.Items == nil || s.Items.Schema == nil {
+			return
+		}
+		for i, item := range items {
+			validateSchemaValue(verr, fmt.Sprintf("%s[%d]", path, i), *s.Items.Schema, item)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			addSchemaError(verr, path, value, "string")
+		}
+	case "integer":
+		if !isInteger(value) {
+			addSchemaError(verr, path, value, "integer")
+			return
+		}
+		if f, _ := toFloat(value); s.Format == "int32" && (f < math.MinInt32 || f > math.MaxInt32) {
+			verr.ErroredFields = append(verr.ErroredFields, errors.ErroredField{
+				Name:   path,
+				Value:  value,
+				Reason: "value is out of range for int32",
+			})
+		}
+	case "number":
+		if _, ok := toFloat(value); !ok {
+			addSchemaError(verr, path, value, "number")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			addSchemaError(verr, path, value, "boolean")
+		}
+	}
+}
+
+// addSchemaError appends an ErroredField for a value that is not of the expected type.
+func addSchemaError(verr *errors.ErrorValidation, path string, value interface{}, expected string) {
+	verr.ErroredFields = append(verr.ErroredFields, errors.ErroredField{
+		Name:   path,
+		Value:  value,
+		Reason: fmt.Sprintf("expected %s, got %T", expected, value),
+	})
+}
+
+// fieldPath returns the path of the named field within the object at the given path.
+func fieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// toFloat returns the value of a numeric JSON value, which may either have been decoded from
+// JSON or built directly in Go.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+// isInteger returns true if the value is numeric and has no fractional part.
+func isInteger(value interface{}) bool {
+	f, ok := toFloat(value)
+	return ok && f == math.Trunc(f)
+}
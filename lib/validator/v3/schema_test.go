@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	v3 "github.com/projectcalico/libcalico-go/lib/validator/v3"
+)
+
+var _ = Describe("Schema validation", func() {
+	// policy returns the unstructured form of a NetworkPolicy with a single ingress rule.
+	policy := func(rule map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "projectcalico.org/v3",
+			"kind":       "NetworkPolicy",
+			"metadata":   map[string]interface{}{"name": "policy1", "namespace": "ns1"},
+			"spec": map[string]interface{}{
+				"order":    float64(100),
+				"selector": "app == 'web'",
+				"types":    []interface{}{"Ingress"},
+				"ingress":  []interface{}{rule},
+			},
+		}
+	}
+
+	// erroredFields returns the names of the fields in a validation error.
+	erroredFields := func(err error) []string {
+		ExpectWithOffset(1, err).To(BeAssignableToTypeOf(errors.ErrorValidation{}))
+		var names []string
+		for _, f := range err.(errors.ErrorValidation).ErroredFields {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	It("should accept a typed policy", func() {
+		order := 100.0
+		tcp := numorstring.ProtocolFromString("TCP")
+		np := api.NewNetworkPolicy()
+		np.Name = "policy1"
+		np.Namespace = "ns1"
+		np.Spec.Order = &order
+		np.Spec.Selector = "app == 'web'"
+		np.Spec.Ingress = []api.Rule{{
+			Action:      api.Allow,
+			Protocol:    &tcp,
+			Destination: api.EntityRule{Ports: []numorstring.Port{numorstring.SinglePort(80)}},
+		}}
+		Expect(v3.ValidateAgainstSchema(np)).NotTo(HaveOccurred())
+	})
+
+	It("should accept a well-formed unstructured policy", func() {
+		p := policy(map[string]interface{}{
+			"action":   "Allow",
+			"protocol": "TCP",
+			"destination": map[string]interface{}{
+				"ports": []interface{}{float64(80), "8080:8081", "http"},
+			},
+		})
+		Expect(v3.ValidateAgainstSchema(p)).NotTo(HaveOccurred())
+		Expect(v3.ValidateAgainstSchema(&unstructured.Unstructured{Object: p})).NotTo(HaveOccurred())
+	})
+
+	It("should report wrong-typed fields with their paths", func() {
+		p := policy(map[string]interface{}{
+			"action":   "Allow",
+			"protocol": true,
+			"destination": map[string]interface{}{
+				"ports": []interface{}{float64(80), 1.5},
+			},
+		})
+		p["spec"].(map[string]interface{})["order"] = "first"
+
+		err := v3.ValidateAgainstSchema(p)
+		Expect(erroredFields(err)).To(ConsistOf(
+			"spec.order",
+			"spec.ingress[0].protocol",
+			"spec.ingress[0].destination.ports[1]",
+		))
+		Expect(err.(errors.ErrorValidation).ErroredFields[0].Reason).To(ContainSubstring("expected"))
+	})
+
+	It("should report missing required fields", func() {
+		p := policy(map[string]interface{}{"protocol": "UDP"})
+		Expect(erroredFields(v3.ValidateAgainstSchema(p))).To(Equal([]string{"spec.ingress[0].action"}))
+	})
+
+	It("should report a rule list that is not an array", func() {
+		p := policy(nil)
+		p["spec"].(map[string]interface{})["ingress"] = map[string]interface{}{"action": "Allow"}
+		Expect(erroredFields(v3.ValidateAgainstSchema(p))).To(Equal([]string{"spec.ingress"}))
+	})
+
+	It("should reject resources without a schema", func() {
+		Expect(v3.ValidateAgainstSchema(map[string]interface{}{"kind": "NoSuchKind"})).To(HaveOccurred())
+		Expect(v3.ValidateAgainstSchema(map[string]interface{}{})).To(HaveOccurred())
+	})
+})
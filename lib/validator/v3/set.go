@@ -0,0 +1,172 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/resources"
+)
+
+// Profiles with these prefixes are generated from Kubernetes namespaces and service accounts
+// and so are not expected to appear in a resource set.
+var generatedProfilePrefixes = []string{"kns.", "ksa."}
+
+// FieldErrorList is a list of the fields that failed validation.
+type FieldErrorList []errors.ErroredField
+
+// ToError returns an ErrorValidation containing the errored fields, or nil if the list is
+// empty.
+func (l FieldErrorList) ToError() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return errors.ErrorValidation{ErroredFields: l}
+}
+
+// ValidateSet performs cross-reference checks across a snapshot of resources, for example
+// as part of admission of a set of manifests.  Each resource is expected to have been
+// validated individually with Validate; ValidateSet checks that:
+//
+//   - No two resources have the same kind, namespace and name.
+//   - The nodes named by BGPPeers, HostEndpoints, WorkloadEndpoints and BlockAffinities exist.
+//   - The profiles named by HostEndpoints and WorkloadEndpoints exist.  Profiles that are
+//     generated by Calico are always treated as existing.
+//   - The BGPPeers do not conflict with each other (see ValidateBGPPeers).
+//
+// Resources of other kinds are checked for duplicates only.
+func ValidateSet(objs []runtime.Object) FieldErrorList {
+	var errs FieldErrorList
+	nodes := map[string]bool{}
+	profiles := map[string]bool{resources.DefaultAllowProfileName: true}
+	seen := map[string]bool{}
+	var nodeList []api.Node
+	var peerList []api.BGPPeer
+
+	// Index the resources that may be referenced and check for duplicates.
+	for _, obj := range objs {
+		id := resourceID(obj)
+		if id == "" {
+			continue
+		}
+		if seen[id] {
+			errs = append(errs, errors.ErroredField{
+				Name:   id,
+				Reason: "resource is defined more than once",
+			})
+		}
+		seen[id] = true
+
+		switch r := obj.(type) {
+		case *api.Node:
+			nodes[r.Name] = true
+			nodeList = append(nodeList, *r)
+		case *api.Profile:
+			profiles[r.Name] = true
+		case *api.BGPPeer:
+			peerList = append(peerList, *r)
+		}
+	}
+
+	checkNode := func(id, field, node string) {
+		if node != "" && !nodes[node] {
+			errs = append(errs, errors.ErroredField{
+				Name:   id + ".Spec." + field,
+				Value:  node,
+				Reason: fmt.Sprintf("node %s does not exist", node),
+			})
+		}
+	}
+	checkProfiles := func(id string, names []string) {
+		for i, p := range names {
+			if profiles[p] || isGeneratedProfile(p) {
+				continue
+			}
+			errs = append(errs, errors.ErroredField{
+				Name:   fmt.Sprintf("%s.Spec.Profiles[%d]", id, i),
+				Value:  p,
+				Reason: fmt.Sprintf("profile %s does not exist", p),
+			})
+		}
+	}
+
+	// Check the references of each resource.
+	for _, obj := range objs {
+		id := resourceID(obj)
+		switch r := obj.(type) {
+		case *api.BGPPeer:
+			checkNode(id, "Node", r.Spec.Node)
+		case *api.HostEndpoint:
+			checkNode(id, "Node", r.Spec.Node)
+			checkProfiles(id, r.Spec.Profiles)
+		case *api.WorkloadEndpoint:
+			checkNode(id, "Node", r.Spec.Node)
+			checkProfiles(id, r.Spec.Profiles)
+		case *api.BlockAffinity:
+			checkNode(id, "Node", r.Spec.Node)
+		}
+	}
+
+	// Check the BGPPeers for conflicting peerings.  A peer that names a missing node has
+	// already been reported above, so only the conflicts are added here.
+	if len(peerList) > 0 {
+		if err := ValidateBGPPeers(peerList, nodeList); err != nil {
+			if ev, ok := err.(errors.ErrorValidation); ok {
+				errs = append(errs, ev.ErroredFields...)
+			} else {
+				errs = append(errs, errors.ErroredField{Name: "BGPPeers", Reason: err.Error()})
+			}
+		}
+	}
+	return errs
+}
+
+// resourceID returns an identifier for the resource of the form Kind(name) or
+// Kind(namespace/name), or an empty string if the object has no metadata.
+func resourceID(obj runtime.Object) string {
+	if obj == nil {
+		return ""
+	}
+	accessor, ok := obj.(interface {
+		GetName() string
+		GetNamespace() string
+	})
+	if !ok {
+		return ""
+	}
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		// The TypeMeta is not always filled in, so fall back to the Go type name.
+		kind = strings.TrimPrefix(fmt.Sprintf("%T", obj), "*v3.")
+	}
+	if ns := accessor.GetNamespace(); ns != "" {
+		return fmt.Sprintf("%s(%s/%s)", kind, ns, accessor.GetName())
+	}
+	return fmt.Sprintf("%s(%s)", kind, accessor.GetName())
+}
+
+func isGeneratedProfile(name string) bool {
+	for _, p := range generatedProfilePrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
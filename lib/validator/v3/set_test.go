@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	api "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/errors"
+	v3 "github.com/projectcalico/libcalico-go/lib/validator/v3"
+)
+
+var _ = Describe("Resource set validation", func() {
+	node := func(name string) *api.Node {
+		n := api.NewNode()
+		n.Name = name
+		return n
+	}
+	profile := func(name string) *api.Profile {
+		p := api.NewProfile()
+		p.Name = name
+		return p
+	}
+	peer := func(name string, spec api.BGPPeerSpec) *api.BGPPeer {
+		p := api.NewBGPPeer()
+		p.Name = name
+		p.Spec = spec
+		return p
+	}
+	hep := func(name, node string, profiles ...string) *api.HostEndpoint {
+		h := api.NewHostEndpoint()
+		h.Name = name
+		h.Spec.Node = node
+		h.Spec.InterfaceName = "eth0"
+		h.Spec.Profiles = profiles
+		return h
+	}
+	// names returns the names of the errored fields.
+	names := func(errs v3.FieldErrorList) []string {
+		names := []string{}
+		for _, f := range errs {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	It("should accept a valid set", func() {
+		errs := v3.ValidateSet([]runtime.Object{
+			node("node1"),
+			profile("prof1"),
+			peer("peer1", api.BGPPeerSpec{Node: "node1", PeerIP: "10.0.0.1"}),
+			hep("hep1", "node1", "prof1", "projectcalico-default-allow", "kns.default"),
+		})
+		Expect(errs).To(BeEmpty())
+		Expect(errs.ToError()).NotTo(HaveOccurred())
+	})
+
+	It("should report dangling node and profile references", func() {
+		errs := v3.ValidateSet([]runtime.Object{
+			node("node1"),
+			peer("peer1", api.BGPPeerSpec{Node: "node2", PeerIP: "10.0.0.1"}),
+			hep("hep1", "node1", "prof1"),
+		})
+		Expect(names(errs)).To(Equal([]string{
+			"BGPPeer(peer1).Spec.Node",
+			"HostEndpoint(hep1).Spec.Profiles[0]",
+		}))
+		Expect(errs[0].Reason).To(Equal("node node2 does not exist"))
+		Expect(errs[1].Reason).To(Equal("profile prof1 does not exist"))
+		Expect(errs.ToError()).To(BeAssignableToTypeOf(errors.ErrorValidation{}))
+	})
+
+	It("should report duplicate resources", func() {
+		errs := v3.ValidateSet([]runtime.Object{node("node1"), node("node1")})
+		Expect(names(errs)).To(Equal([]string{"Node(node1)"}))
+	})
+
+	It("should report conflicting BGPPeers", func() {
+		errs := v3.ValidateSet([]runtime.Object{
+			node("node1"),
+			peer("peer1", api.BGPPeerSpec{Node: "node1", PeerIP: "10.0.0.1"}),
+			peer("peer2", api.BGPPeerSpec{Node: "node1", PeerIP: "10.0.0.1"}),
+		})
+		Expect(names(errs)).To(Equal([]string{"BGPPeer(peer2).Spec.PeerIP"}))
+	})
+})
@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/go-playground/validator.v9"
@@ -85,6 +86,9 @@ var (
 	dropAcceptReturnRegex = regexp.MustCompile("^(Drop|Accept|Return)$")
 	acceptReturnRegex     = regexp.MustCompile("^(Accept|Return)$")
 	ipTypeRegex           = regexp.MustCompile("^(CalicoNodeIP|InternalIP|ExternalIP)$")
+	taintEffectRegex      = regexp.MustCompile("^(NoSchedule|PreferNoSchedule|NoExecute)$")
+	clusterTypeRegex      = regexp.MustCompile("^[a-zA-Z0-9_-]+(,[a-zA-Z0-9_-]+)*$")
+	stagedActionRegex     = regexp.MustCompile("^(Set|Delete)$")
 	standardCommunity     = regexp.MustCompile(`^(\d+):(\d+)$`)
 	largeCommunity        = regexp.MustCompile(`^(\d+):(\d+):(\d+)$`)
 	number                = regexp.MustCompile(`(\d+)`)
@@ -167,13 +171,20 @@ func init() {
 	registerFieldValidator("keyValueList", validateKeyValueList)
 	registerFieldValidator("prometheusHost", validatePrometheusHost)
 	registerFieldValidator("ipType", validateIPType)
+	registerFieldValidator("taintEffect", validateTaintEffect)
 
 	registerFieldValidator("sourceAddress", RegexValidator("SourceAddress", SourceAddressRegex))
+	registerFieldValidator("clusterType", RegexValidator("ClusterType", clusterTypeRegex))
+	registerFieldValidator("stagedAction", RegexValidator("StagedAction", stagedActionRegex))
 	registerFieldValidator("regexp", validateRegexp)
 	registerFieldValidator("routeSource", validateRouteSource)
 	registerFieldValidator("wireguardPublicKey", validateWireguardPublicKey)
 	registerFieldValidator("IP:port", validateIPPort)
 
+	// Register a host address validator.  Accepts an IP address or a CIDR with a full length
+	// prefix.
+	registerFieldValidator("hostip", validateHostIP)
+
 	// Register network validators (i.e. validating a correctly masked CIDR).  Also
 	// accepts an IP address without a mask (assumes a full mask).
 	registerFieldValidator("netv4", validateIPv4Network)
@@ -194,6 +205,7 @@ func init() {
 	registerStructValidator(validate, validateICMPFields, api.ICMPFields{})
 	registerStructValidator(validate, validateIPPoolSpec, api.IPPoolSpec{})
 	registerStructValidator(validate, validateNodeSpec, api.NodeSpec{})
+	registerStructValidator(validate, validateNodeTaint, api.NodeTaint{})
 	registerStructValidator(validate, validateObjectMeta, metav1.ObjectMeta{})
 	registerStructValidator(validate, validateHTTPRule, api.HTTPMatch{})
 	registerStructValidator(validate, validateFelixConfigSpec, api.FelixConfigurationSpec{})
@@ -204,6 +216,8 @@ func init() {
 	registerStructValidator(validate, validateBGPPeerSpec, api.BGPPeerSpec{})
 	registerStructValidator(validate, validateNetworkPolicy, api.NetworkPolicy{})
 	registerStructValidator(validate, validateGlobalNetworkPolicy, api.GlobalNetworkPolicy{})
+	registerStructValidator(validate, validateStagedNetworkPolicy, api.StagedNetworkPolicy{})
+	registerStructValidator(validate, validateStagedGlobalNetworkPolicy, api.StagedGlobalNetworkPolicy{})
 	registerStructValidator(validate, validateGlobalNetworkSet, api.GlobalNetworkSet{})
 	registerStructValidator(validate, validateNetworkSet, api.NetworkSet{})
 	registerStructValidator(validate, validateRuleMetadata, api.RuleMetadata{})
@@ -352,6 +366,12 @@ func RegexValidator(desc string, rx *regexp.Regexp) func(fl validator.FieldLevel
 	}
 }
 
+func validateTaintEffect(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	log.Debugf("Validate taint effect: %s", s)
+	return taintEffectRegex.MatchString(s)
+}
+
 func validateMAC(fl validator.FieldLevel) bool {
 	s := fl.Field().String()
 	log.Debugf("Validate MAC Address: %s", s)
@@ -362,6 +382,13 @@ func validateMAC(fl validator.FieldLevel) bool {
 	return true
 }
 
+func validateHostIP(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	log.Debugf("Validate host IP: %s", s)
+	_, err := cnet.ParseHostIP(s)
+	return err == nil
+}
+
 func validateIptablesBackend(fl validator.FieldLevel) bool {
 	s := fl.Field().String()
 	log.Debugf("Validate Iptables Backend: %s", s)
@@ -669,20 +696,20 @@ func validateIPNAT(structLevel validator.StructLevel) {
 	i := structLevel.Current().Interface().(api.IPNAT)
 	log.Debugf("Internal IP: %s; External IP: %s", i.InternalIP, i.ExternalIP)
 
-	iip, _, err := cnet.ParseCIDROrIP(i.InternalIP)
+	iip, err := cnet.ParseHostIP(i.InternalIP)
 	if err != nil {
 		structLevel.ReportError(reflect.ValueOf(i.ExternalIP),
 			"InternalIP", "", reason("invalid IP address"), "")
 	}
 
-	eip, _, err := cnet.ParseCIDROrIP(i.ExternalIP)
+	eip, err := cnet.ParseHostIP(i.ExternalIP)
 	if err != nil {
 		structLevel.ReportError(reflect.ValueOf(i.ExternalIP),
 			"InternalIP", "", reason("invalid IP address"), "")
 	}
 
 	// An IPNAT must have both the internal and external IP versions the same.
-	if iip.Version() != eip.Version() {
+	if iip != nil && eip != nil && iip.Version() != eip.Version() {
 		structLevel.ReportError(reflect.ValueOf(i.ExternalIP),
 			"ExternalIP", "", reason("mismatched IP versions"), "")
 	}
@@ -874,6 +901,7 @@ func validateIPPoolSpec(structLevel validator.StructLevel) {
 	}
 
 	// Normalize the CIDR before persisting.
+	originalCIDR := pool.CIDR
 	pool.CIDR = cidr.String()
 
 	// IPIP cannot be enabled for IPv6.
@@ -914,11 +942,12 @@ func validateIPPoolSpec(structLevel validator.StructLevel) {
 		}
 	}
 
-	// The Calico CIDR should be strictly masked
+	// The Calico CIDR should be strictly masked, i.e. the network address must match the masked
+	// form.  Report the original CIDR along with the corrected canonical form.
 	log.Debugf("IPPool CIDR: %s, Masked IP: %d", pool.CIDR, cidr.IP)
 	if cidr.IP.String() != ipAddr.String() {
-		structLevel.ReportError(reflect.ValueOf(pool.CIDR),
-			"IPpool.CIDR", "", reason(poolUnstictCIDR), "")
+		structLevel.ReportError(reflect.ValueOf(originalCIDR),
+			"IPpool.CIDR", "", reason(fmt.Sprintf("%s, expected %s", poolUnstictCIDR, cidr.String())), "")
 	}
 
 	// IPv4 link local subnet.
@@ -1082,6 +1111,28 @@ func validateNodeSpec(structLevel validator.StructLevel) {
 				reason("Spec.BGP should not be empty"), "")
 		}
 	}
+
+	names := set.New()
+	for _, t := range ns.VXLANTunnelAddrs {
+		if names.Contains(t.Name) {
+			structLevel.ReportError(reflect.ValueOf(t.Name), "VXLANTunnelAddrs", "",
+				reason(fmt.Sprintf("duplicate VXLAN tunnel name %q", t.Name)), "")
+		}
+		names.Add(t.Name)
+	}
+}
+
+func validateNodeTaint(structLevel validator.StructLevel) {
+	t := structLevel.Current().Interface().(api.NodeTaint)
+
+	if len(k8svalidation.IsQualifiedName(t.Key)) != 0 {
+		structLevel.ReportError(reflect.ValueOf(t.Key), "Key", "",
+			reason("taint key must be a valid qualified name"), "")
+	}
+	if len(k8svalidation.IsValidLabelValue(t.Value)) != 0 {
+		structLevel.ReportError(reflect.ValueOf(t.Value), "Value", "",
+			reason("taint value must be a valid label value"), "")
+	}
 }
 
 func validateBGPPeerSpec(structLevel validator.StructLevel) {
@@ -1099,6 +1150,45 @@ func validateBGPPeerSpec(structLevel validator.StructLevel) {
 		structLevel.ReportError(reflect.ValueOf(ps.ASNumber), "ASNumber", "",
 			reason("ASNumber field must be empty when PeerSelector is specified"), "")
 	}
+	validateBGPTimers(structLevel, ps.KeepaliveTime, ps.HoldTime)
+}
+
+// The bounds of the BGP timers.  The hold time is sent in a 16-bit field of the BGP OPEN
+// message, and a non-zero hold time must be at least 3 seconds (RFC 4271).
+const (
+	minBGPHoldTime      = 3 * time.Second
+	maxBGPHoldTime      = 65535 * time.Second
+	minBGPKeepaliveTime = time.Second
+)
+
+// validateBGPTimers validates the keepalive and hold times of a BGP peering.  Both times must be
+// whole numbers of seconds within the BGP bounds, and, if both are set, the hold time must be
+// non-zero and at least three times the keepalive time.
+func validateBGPTimers(structLevel validator.StructLevel, keepalive, hold *metav1.Duration) {
+	if keepalive != nil {
+		k := keepalive.Duration
+		if k%time.Second != 0 {
+			structLevel.ReportError(reflect.ValueOf(k), "KeepaliveTime", "",
+				reason("KeepaliveTime must be a whole number of seconds"), "")
+		} else if k < minBGPKeepaliveTime || k > maxBGPHoldTime/3 {
+			structLevel.ReportError(reflect.ValueOf(k), "KeepaliveTime", "",
+				reason(fmt.Sprintf("KeepaliveTime must be in the range %v-%v", minBGPKeepaliveTime, maxBGPHoldTime/3)), "")
+		}
+	}
+	if hold != nil {
+		h := hold.Duration
+		if h%time.Second != 0 {
+			structLevel.ReportError(reflect.ValueOf(h), "HoldTime", "",
+				reason("HoldTime must be a whole number of seconds"), "")
+		} else if h != 0 && (h < minBGPHoldTime || h > maxBGPHoldTime) {
+			structLevel.ReportError(reflect.ValueOf(h), "HoldTime", "",
+				reason(fmt.Sprintf("HoldTime must be 0 or in the range %v-%v", minBGPHoldTime, maxBGPHoldTime)), "")
+		}
+	}
+	if keepalive != nil && hold != nil && hold.Duration < 3*keepalive.Duration {
+		structLevel.ReportError(reflect.ValueOf(hold.Duration), "HoldTime", "",
+			reason("HoldTime must be at least three times the KeepaliveTime"), "")
+	}
 }
 
 func validateEndpointPort(structLevel validator.StructLevel) {
@@ -1160,7 +1250,21 @@ func validateObjectMeta(structLevel validator.StructLevel) {
 }
 
 func validateNetworkPolicy(structLevel validator.StructLevel) {
-	np := structLevel.Current().Interface().(api.NetworkPolicy)
+	validateNetworkPolicyResource(structLevel, structLevel.Current().Interface().(api.NetworkPolicy))
+}
+
+// validateStagedNetworkPolicy validates a StagedNetworkPolicy as the NetworkPolicy that it would
+// enforce.  The policy fields of a staged deletion are not validated since they are ignored.
+func validateStagedNetworkPolicy(structLevel validator.StructLevel) {
+	staged := structLevel.Current().Interface().(api.StagedNetworkPolicy)
+	action, enforced := api.ConvertStagedPolicyToEnforced(&staged)
+	if action == api.StagedActionDelete {
+		enforced.Spec = api.NetworkPolicySpec{}
+	}
+	validateNetworkPolicyResource(structLevel, *enforced)
+}
+
+func validateNetworkPolicyResource(structLevel validator.StructLevel, np api.NetworkPolicy) {
 	spec := np.Spec
 
 	// Check (and disallow) any repeats in Types field.
@@ -1266,7 +1370,70 @@ func validateGlobalNetworkSet(structLevel validator.StructLevel) {
 }
 
 func validateGlobalNetworkPolicy(structLevel validator.StructLevel) {
-	gnp := structLevel.Current().Interface().(api.GlobalNetworkPolicy)
+	validateGlobalNetworkPolicyResource(structLevel, structLevel.Current().Interface().(api.GlobalNetworkPolicy))
+}
+
+// validateStagedGlobalNetworkPolicy validates a StagedGlobalNetworkPolicy as the
+// GlobalNetworkPolicy that it would enforce.  The policy fields of a staged deletion are not
+// validated since they are ignored.
+func validateStagedGlobalNetworkPolicy(structLevel validator.StructLevel) {
+	staged := structLevel.Current().Interface().(api.StagedGlobalNetworkPolicy)
+	action, enforced := api.ConvertStagedGlobalPolicyToEnforced(&staged)
+	if action == api.StagedActionDelete {
+		enforced.Spec = api.GlobalNetworkPolicySpec{}
+	}
+	validateGlobalNetworkPolicyResource(structLevel, *enforced)
+}
+
+// ValidateGlobalNetworkPolicyFlags checks that the combination of the DoNotTrack, PreDNAT and
+// ApplyOnForward flags in the GlobalNetworkPolicySpec is one that Felix is able to program:
+//   - DoNotTrack and PreDNAT are mutually exclusive.
+//   - PreDNAT policies may only contain ingress rules.
+//   - DoNotTrack and PreDNAT policies both require ApplyOnForward.
+//
+// The returned error is an ErrorValidation with a field for each invalid flag.
+func ValidateGlobalNetworkPolicyFlags(spec *api.GlobalNetworkPolicySpec) error {
+	var fields []errors.ErroredField
+	if spec.DoNotTrack && spec.PreDNAT {
+		fields = append(fields, errors.ErroredField{
+			Name:   "PolicySpec.PreDNAT",
+			Value:  spec.PreDNAT,
+			Reason: "PreDNAT and DoNotTrack cannot both be true, for a given PolicySpec",
+		})
+	}
+	if spec.PreDNAT && len(spec.Egress) > 0 {
+		fields = append(fields, errors.ErroredField{
+			Name:   "PolicySpec.Egress",
+			Value:  spec.Egress,
+			Reason: "PreDNAT PolicySpec cannot have any Egress rules",
+		})
+	}
+	if spec.PreDNAT {
+		for _, t := range spec.Types {
+			if t == api.PolicyTypeEgress {
+				fields = append(fields, errors.ErroredField{
+					Name:   "PolicySpec.Types",
+					Value:  spec.Types,
+					Reason: "PreDNAT PolicySpec cannot have 'egress' Type",
+				})
+				break
+			}
+		}
+	}
+	if !spec.ApplyOnForward && (spec.DoNotTrack || spec.PreDNAT) {
+		fields = append(fields, errors.ErroredField{
+			Name:   "PolicySpec.ApplyOnForward",
+			Value:  spec.ApplyOnForward,
+			Reason: "ApplyOnForward must be true if either PreDNAT or DoNotTrack is true, for a given PolicySpec",
+		})
+	}
+	if len(fields) > 0 {
+		return errors.ErrorValidation{ErroredFields: fields}
+	}
+	return nil
+}
+
+func validateGlobalNetworkPolicyResource(structLevel validator.StructLevel, gnp api.GlobalNetworkPolicy) {
 	spec := gnp.Spec
 
 	// Check the name is within the max length.
@@ -1295,30 +1462,12 @@ func validateGlobalNetworkPolicy(structLevel validator.StructLevel) {
 	validateObjectMetaAnnotations(structLevel, gnp.Annotations)
 	validateObjectMetaLabels(structLevel, gnp.Labels)
 
-	if spec.DoNotTrack && spec.PreDNAT {
-		structLevel.ReportError(reflect.ValueOf(spec.PreDNAT),
-			"PolicySpec.PreDNAT", "", reason("PreDNAT and DoNotTrack cannot both be true, for a given PolicySpec"), "")
-	}
-
-	if spec.PreDNAT && len(spec.Egress) > 0 {
-		structLevel.ReportError(reflect.ValueOf(spec.Egress),
-			"PolicySpec.Egress", "", reason("PreDNAT PolicySpec cannot have any Egress rules"), "")
-	}
-
-	if spec.PreDNAT && len(spec.Types) > 0 {
-		for _, t := range spec.Types {
-			if t == api.PolicyTypeEgress {
-				structLevel.ReportError(reflect.ValueOf(spec.Types),
-					"PolicySpec.Types", "", reason("PreDNAT PolicySpec cannot have 'egress' Type"), "")
-			}
+	if err := ValidateGlobalNetworkPolicyFlags(&spec); err != nil {
+		for _, f := range err.(errors.ErrorValidation).ErroredFields {
+			structLevel.ReportError(reflect.ValueOf(f.Value), f.Name, "", reason(f.Reason), "")
 		}
 	}
 
-	if !spec.ApplyOnForward && (spec.DoNotTrack || spec.PreDNAT) {
-		structLevel.ReportError(reflect.ValueOf(spec.ApplyOnForward),
-			"PolicySpec.ApplyOnForward", "", reason("ApplyOnForward must be true if either PreDNAT or DoNotTrack is true, for a given PolicySpec"), "")
-	}
-
 	// Check (and disallow) any repeats in Types field.
 	mp := map[api.PolicyType]bool{}
 	for _, t := range spec.Types {
@@ -41,6 +41,9 @@ func init() {
 	var V256 = 256
 	var Vffffffff = 0xffffffff
 	var V100000000 = 0x100000000
+	var V4096 = 4096
+	var Vffffff = 0xffffff
+	var V1000000 = 0x1000000
 
 	// Set up some values we use in various tests.
 	ipv4_1 := "1.2.3.4"
@@ -111,6 +114,30 @@ func init() {
 			}, "error with field Port = '0' (port range invalid, port number must be between 1 and 65535)"),
 	)
 
+	// Perform validation of IP pool CIDR alignment, checking the corrected CIDR is reported.
+	DescribeTable("IPPool CIDR alignment",
+		func(cidr string, expected string) {
+			// IPIP is not supported on IPv6 pools, so set the encapsulation modes explicitly.
+			err := v3.Validate(api.IPPoolSpec{
+				CIDR:      cidr,
+				IPIPMode:  api.IPIPModeNever,
+				VXLANMode: api.VXLANModeNever,
+			})
+			if expected == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("'" + cidr + "'"))
+				Expect(err.Error()).To(ContainSubstring("IP pool CIDR is not strictly masked, expected " + expected))
+			}
+		},
+		Entry("should accept an aligned IPv4 CIDR", "10.0.0.0/24", ""),
+		Entry("should accept an aligned IPv6 CIDR", "fd00:1::/64", ""),
+		Entry("should reject a misaligned IPv4 CIDR", "10.0.0.1/24", "10.0.0.0/24"),
+		Entry("should reject a misaligned IPv4 CIDR within a larger prefix", "10.1.2.0/14", "10.0.0.0/14"),
+		Entry("should reject a misaligned IPv6 CIDR", "fd00:1::1/64", "fd00:1::/64"),
+	)
+
 	// Perform basic validation of different fields and structures to test simple valid/invalid
 	// scenarios.  This does not test precise error strings - but does cover a lot of the validation
 	// code paths.
@@ -682,6 +709,16 @@ func init() {
 				InternalIP: ipv6_1,
 				ExternalIP: ipv4_1,
 			}, false),
+		Entry("should accept IPNAT with host CIDRs",
+			api.IPNAT{
+				InternalIP: ipv4_1 + "/32",
+				ExternalIP: ipv4_2 + "/32",
+			}, true),
+		Entry("should reject IPNAT with a network CIDR",
+			api.IPNAT{
+				InternalIP: "10.0.0.0/24",
+				ExternalIP: ipv4_2,
+			}, false),
 
 		// (API) WorkloadEndpointSpec
 		Entry("should accept workload endpoint with interface only",
@@ -780,6 +817,21 @@ func init() {
 				ExpectedIPs:   []string{ipv4_1, ipv6_1},
 				Node:          "node01",
 			}, true),
+		Entry("should accept host endpoint with expected IPs as host CIDRs",
+			api.HostEndpointSpec{
+				ExpectedIPs: []string{ipv4_1 + "/32", ipv6_1 + "/128"},
+				Node:        "node01",
+			}, true),
+		Entry("should reject host endpoint with an expected IP with a network prefix",
+			api.HostEndpointSpec{
+				ExpectedIPs: []string{"10.0.0.0/24"},
+				Node:        "node01",
+			}, false),
+		Entry("should reject host endpoint with an expected IPv6 IP with a network prefix",
+			api.HostEndpointSpec{
+				ExpectedIPs: []string{ipv6_1 + "/64"},
+				Node:        "node01",
+			}, false),
 		Entry("should reject host endpoint with no config", api.HostEndpointSpec{}, false),
 		Entry("should reject host endpoint with blank interface an no IPs",
 			api.HostEndpointSpec{
@@ -1412,11 +1464,82 @@ func init() {
 			SourceAddress: api.SourceAddress("rubbish"),
 		}, false),
 
+		// BGPPeer ASPathPrepend
+		Entry("BGPPeer with ASPathPrepend 0", api.BGPPeerSpec{
+			ASPathPrepend: 0,
+		}, true),
+		Entry("BGPPeer with ASPathPrepend 10", api.BGPPeerSpec{
+			ASPathPrepend: 10,
+		}, true),
+		Entry("BGPPeer with ASPathPrepend 11", api.BGPPeerSpec{
+			ASPathPrepend: 11,
+		}, false),
+		Entry("BGPPeer with negative ASPathPrepend", api.BGPPeerSpec{
+			ASPathPrepend: -1,
+		}, false),
+
+		// BGPPeer timers
+		Entry("BGPPeer with KeepaliveTime and HoldTime", api.BGPPeerSpec{
+			KeepaliveTime: &v1.Duration{Duration: 10 * time.Second},
+			HoldTime:      &v1.Duration{Duration: 30 * time.Second},
+		}, true),
+		Entry("BGPPeer with only KeepaliveTime", api.BGPPeerSpec{
+			KeepaliveTime: &v1.Duration{Duration: time.Second},
+		}, true),
+		Entry("BGPPeer with HoldTime 0", api.BGPPeerSpec{
+			HoldTime: &v1.Duration{},
+		}, true),
+		Entry("BGPPeer with maximum HoldTime", api.BGPPeerSpec{
+			HoldTime: &v1.Duration{Duration: 65535 * time.Second},
+		}, true),
+		Entry("BGPPeer with HoldTime less than three times KeepaliveTime", api.BGPPeerSpec{
+			KeepaliveTime: &v1.Duration{Duration: 10 * time.Second},
+			HoldTime:      &v1.Duration{Duration: 29 * time.Second},
+		}, false),
+		Entry("BGPPeer with KeepaliveTime and HoldTime 0", api.BGPPeerSpec{
+			KeepaliveTime: &v1.Duration{Duration: 10 * time.Second},
+			HoldTime:      &v1.Duration{},
+		}, false),
+		Entry("BGPPeer with HoldTime below 3s", api.BGPPeerSpec{
+			HoldTime: &v1.Duration{Duration: 2 * time.Second},
+		}, false),
+		Entry("BGPPeer with HoldTime above 65535s", api.BGPPeerSpec{
+			HoldTime: &v1.Duration{Duration: 65536 * time.Second},
+		}, false),
+		Entry("BGPPeer with KeepaliveTime 0", api.BGPPeerSpec{
+			KeepaliveTime: &v1.Duration{},
+		}, false),
+		Entry("BGPPeer with fractional KeepaliveTime", api.BGPPeerSpec{
+			KeepaliveTime: &v1.Duration{Duration: 1500 * time.Millisecond},
+		}, false),
+		Entry("BGPPeer with fractional HoldTime", api.BGPPeerSpec{
+			HoldTime: &v1.Duration{Duration: 3500 * time.Millisecond},
+		}, false),
+
 		// (API) NodeSpec
 		Entry("should accept node with IPv4 BGP", api.NodeSpec{BGP: &api.NodeBGPSpec{IPv4Address: netv4_1}}, true),
 		Entry("should accept node with IPv6 BGP", api.NodeSpec{BGP: &api.NodeBGPSpec{IPv6Address: netv6_1}}, true),
 		Entry("should accept node with tunnel IP in BGP", api.NodeSpec{BGP: &api.NodeBGPSpec{IPv4IPIPTunnelAddr: "10.0.0.1"}}, true),
 		Entry("should accept node with no BGP", api.NodeSpec{}, true),
+		Entry("should accept node with additional VXLAN tunnel addresses", api.NodeSpec{VXLANTunnelAddrs: []api.NodeVXLANTunnelAddr{
+			{Name: "pool1", Address: "10.0.0.1"},
+			{Name: "pool2", Address: "fd00::1"},
+		}}, true),
+		Entry("should reject node with an invalid VXLAN tunnel address", api.NodeSpec{VXLANTunnelAddrs: []api.NodeVXLANTunnelAddr{
+			{Name: "pool1", Address: "10.0.0.1/24"},
+		}}, false),
+		Entry("should reject node with an invalid VXLAN tunnel name", api.NodeSpec{VXLANTunnelAddrs: []api.NodeVXLANTunnelAddr{
+			{Name: "Pool_1", Address: "10.0.0.1"},
+		}}, false),
+		Entry("should reject node with duplicate VXLAN tunnel names", api.NodeSpec{VXLANTunnelAddrs: []api.NodeVXLANTunnelAddr{
+			{Name: "pool1", Address: "10.0.0.1"},
+			{Name: "pool1", Address: "10.0.0.2"},
+		}}, false),
+		Entry("should accept node with a VXLAN VNI", api.NodeSpec{VXLANVNI: &V4096}, true),
+		Entry("should accept node with VXLAN VNI 0", api.NodeSpec{VXLANVNI: &V0}, true),
+		Entry("should accept node with the largest VXLAN VNI", api.NodeSpec{VXLANVNI: &Vffffff}, true),
+		Entry("should reject node with a negative VXLAN VNI", api.NodeSpec{VXLANVNI: &Vneg1}, false),
+		Entry("should reject node with a VXLAN VNI larger than 24 bits", api.NodeSpec{VXLANVNI: &V1000000}, false),
 		Entry("should reject node with an empty BGP", api.NodeSpec{BGP: &api.NodeBGPSpec{}}, false),
 		Entry("should reject node with IPv6 address in IPv4 field", api.NodeSpec{BGP: &api.NodeBGPSpec{IPv4Address: netv6_1}}, false),
 		Entry("should reject node with IPv4 address in IPv6 field", api.NodeSpec{BGP: &api.NodeBGPSpec{IPv6Address: netv4_1}}, false),
@@ -1447,6 +1570,27 @@ func init() {
 			WireguardPublicKey: "foobar",
 		}, false),
 
+		// Node taints.
+		Entry("should accept node with NoSchedule and NoExecute taints", api.NodeSpec{Taints: []api.NodeTaint{
+			{Key: "node-role.kubernetes.io/master", Effect: api.TaintEffectNoSchedule},
+			{Key: "dedicated", Value: "gpu", Effect: api.TaintEffectNoExecute},
+		}}, true),
+		Entry("should accept node with a PreferNoSchedule taint", api.NodeSpec{Taints: []api.NodeTaint{
+			{Key: "dedicated", Effect: api.TaintEffectPreferNoSchedule},
+		}}, true),
+		Entry("should reject node taint with an invalid effect", api.NodeSpec{Taints: []api.NodeTaint{
+			{Key: "dedicated", Effect: "NoRun"},
+		}}, false),
+		Entry("should reject node taint with no effect", api.NodeSpec{Taints: []api.NodeTaint{
+			{Key: "dedicated"},
+		}}, false),
+		Entry("should reject node taint with an invalid key", api.NodeSpec{Taints: []api.NodeTaint{
+			{Key: "bad key", Effect: api.TaintEffectNoSchedule},
+		}}, false),
+		Entry("should reject node taint with an invalid value", api.NodeSpec{Taints: []api.NodeTaint{
+			{Key: "dedicated", Value: "bad value", Effect: api.TaintEffectNoSchedule},
+		}}, false),
+
 		// AWS source-destination-check.
 		Entry("should accept a valid AWSSrcDstCheck value 'DoNothing'", api.FelixConfigurationSpec{AWSSrcDstCheck: &awsCheckDoNothing}, true),
 		Entry("should accept a valid AWSSrcDstCheck value 'Enable'", api.FelixConfigurationSpec{AWSSrcDstCheck: &awsCheckEnable}, true),
@@ -2185,6 +2329,66 @@ func init() {
 			Communities:          []api.Community{{Name: "community-test", Value: "101:5695"}},
 			PrefixAdvertisements: []api.PrefixAdvertisement{{CIDR: "2001:4860::/128", Communities: []string{"community-test", "8988:202"}}},
 		}, true),
+
+		// ClusterInformationSpec validation.
+		Entry("should accept an empty ClusterType", api.ClusterInformationSpec{}, true),
+		Entry("should accept a single ClusterType", api.ClusterInformationSpec{ClusterType: "k8s"}, true),
+		Entry("should accept a list of ClusterTypes", api.ClusterInformationSpec{ClusterType: "k8s,bgp,kubeadm"}, true),
+		Entry("should reject a ClusterType with an empty entry", api.ClusterInformationSpec{ClusterType: "k8s,,bgp"}, false),
+		Entry("should reject a ClusterType with a trailing comma", api.ClusterInformationSpec{ClusterType: "k8s,"}, false),
+		Entry("should reject a ClusterType containing spaces", api.ClusterInformationSpec{ClusterType: "k8s, bgp"}, false),
+
+		// Staged policy validation.
+		Entry("should accept a StagedGlobalNetworkPolicy with no staged action",
+			&api.StagedGlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}}, true),
+		Entry("should accept a StagedGlobalNetworkPolicy with staged action Set",
+			&api.StagedGlobalNetworkPolicy{
+				ObjectMeta: v1.ObjectMeta{Name: "thing"},
+				Spec:       api.StagedGlobalNetworkPolicySpec{StagedAction: api.StagedActionSet},
+			}, true),
+		Entry("should reject a StagedGlobalNetworkPolicy with an invalid staged action",
+			&api.StagedGlobalNetworkPolicy{
+				ObjectMeta: v1.ObjectMeta{Name: "thing"},
+				Spec:       api.StagedGlobalNetworkPolicySpec{StagedAction: "Enforce"},
+			}, false),
+		Entry("should reject a StagedGlobalNetworkPolicy with an invalid name",
+			&api.StagedGlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "tHiNg"}}, false),
+		Entry("should reject a StagedGlobalNetworkPolicy with both PreDNAT and DoNotTrack",
+			&api.StagedGlobalNetworkPolicy{
+				ObjectMeta: v1.ObjectMeta{Name: "thing"},
+				Spec: api.StagedGlobalNetworkPolicySpec{
+					PreDNAT:        true,
+					DoNotTrack:     true,
+					ApplyOnForward: true,
+				},
+			}, false),
+		Entry("should accept a staged deletion of a StagedGlobalNetworkPolicy with both PreDNAT and DoNotTrack",
+			&api.StagedGlobalNetworkPolicy{
+				ObjectMeta: v1.ObjectMeta{Name: "thing"},
+				Spec: api.StagedGlobalNetworkPolicySpec{
+					StagedAction:   api.StagedActionDelete,
+					PreDNAT:        true,
+					DoNotTrack:     true,
+					ApplyOnForward: true,
+				},
+			}, true),
+		Entry("should accept a StagedNetworkPolicy with staged action Delete",
+			&api.StagedNetworkPolicy{
+				ObjectMeta: v1.ObjectMeta{Name: "thing", Namespace: "default"},
+				Spec:       api.StagedNetworkPolicySpec{StagedAction: api.StagedActionDelete},
+			}, true),
+		Entry("should reject a StagedNetworkPolicy with an invalid staged action",
+			&api.StagedNetworkPolicy{
+				ObjectMeta: v1.ObjectMeta{Name: "thing", Namespace: "default"},
+				Spec:       api.StagedNetworkPolicySpec{StagedAction: "delete"},
+			}, false),
+		Entry("should reject a StagedNetworkPolicy with a repeated type",
+			&api.StagedNetworkPolicy{
+				ObjectMeta: v1.ObjectMeta{Name: "thing", Namespace: "default"},
+				Spec: api.StagedNetworkPolicySpec{
+					Types: []api.PolicyType{api.PolicyTypeIngress, api.PolicyTypeIngress},
+				},
+			}, false),
 	)
 }
 